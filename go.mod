@@ -1,6 +1,6 @@
 module github.com/bazelbuild/remote-apis-sdks
 
-go 1.15
+go 1.16
 
 require (
 	cloud.google.com/go v0.65.0 // indirect
@@ -8,13 +8,15 @@ require (
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
 	github.com/golang/protobuf v1.5.2
 	github.com/google/go-cmp v0.5.5
+	github.com/hanwen/go-fuse/v2 v2.1.0
 	github.com/klauspost/compress v1.12.3
 	github.com/mostynb/zstdpool-syncpool v0.0.7
 	github.com/pborman/uuid v1.2.0
 	github.com/pkg/errors v0.9.1
 	github.com/pkg/xattr v0.4.4
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
-	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
+	golang.org/x/sys v0.0.0-20210507014357-30e306a8bba5
 	google.golang.org/api v0.30.0
 	google.golang.org/genproto v0.0.0-20210506142907-4a47615972c2
 	google.golang.org/grpc v1.37.0
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/tool"
+
+	log "github.com/golang/glog"
+)
+
+// batchOp is an operation of the --operations_file, with the line it was read from.
+type batchOp struct {
+	line int
+	args *opArgs
+	err  error
+}
+
+// readBatch reads the operations of the file at path, one JSON object per line. Empty lines and
+// lines starting with # are skipped. The arguments missing from a line default to the flags.
+func readBatch(path string) ([]*batchOp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ops []*batchOp
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 || b[0] == '#' {
+			continue
+		}
+		args := argsFromFlags()
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(args); err != nil {
+			return nil, fmt.Errorf("%v:%d: invalid operation: %v", path, line, err)
+		}
+		ops = append(ops, &batchOp{line: line, args: args})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// runBatch performs the operations of the file at path over the client's connection, at most
+// concurrency at a time, and prints a summary of their outcomes. It returns an error if any of
+// them failed.
+func runBatch(ctx context.Context, c *tool.Client, path string, concurrency int) error {
+	ops, err := readBatch(path)
+	if err != nil {
+		return err
+	}
+	return performBatch(path, ops, concurrency, func(op *batchOp) error {
+		return runOp(ctx, c, op.args, fmt.Sprintf("%v:%d", path, op.line))
+	}, os.Stdout)
+}
+
+// performBatch performs the operations read from the file at path with run, at most concurrency
+// at a time, and writes a summary of their outcomes to out.
+func performBatch(path string, ops []*batchOp, concurrency int, run func(op *batchOp) error, out io.Writer) error {
+	start := time.Now()
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, op := range ops {
+		op := op
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			log.Infof("%v:%d: performing %v", path, op.line, op.args.Operation)
+			op.err = run(op)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, op := range ops {
+		if op.err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(out, "Operations complete\n")
+	fmt.Fprintf(out, "-------------------\n")
+	fmt.Fprintf(out, "Operations: %d\n", len(ops))
+	fmt.Fprintf(out, "Succeeded: %d\n", len(ops)-failed)
+	fmt.Fprintf(out, "Failed: %d\n", failed)
+	fmt.Fprintf(out, "Elapsed: %v\n", time.Since(start).Round(time.Millisecond))
+	for _, op := range ops {
+		if op.err != nil {
+			fmt.Fprintf(out, "%v:%d: %v: %v\n", path, op.line, op.args.Operation, op.err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d operations failed", failed, len(ops))
+	}
+	return nil
+}
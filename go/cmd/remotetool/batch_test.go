@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops")
+	contents := `# Comments and empty lines are skipped.
+
+{"operation": "download_blob", "digest": "a/1", "path": "/tmp/a"}
+  {"operation": "show_action", "digest": "b/2"}
+`
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", path, err)
+	}
+	ops, err := readBatch(path)
+	if err != nil {
+		t.Fatalf("readBatch(%v) failed: %v", path, err)
+	}
+	type op struct {
+		Line      int
+		Operation OpType
+		Digest    string
+		Path      string
+	}
+	var got []op
+	for _, o := range ops {
+		got = append(got, op{o.line, o.args.Operation, o.args.Digest, o.args.Path})
+	}
+	want := []op{
+		{3, downloadBlob, "a/1", "/tmp/a"},
+		{4, showAction, "b/2", ""},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readBatch(%v) returned diff (-want +got):\n%s", path, diff)
+	}
+}
+
+func TestReadBatchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "unknown field",
+			contents: "{\"operation\": \"download_blob\"}\n{\"operation\": \"download_blob\", \"digets\": \"a/1\"}\n",
+			wantErr:  ":2: invalid operation",
+		},
+		{
+			name:     "not json",
+			contents: "download_blob a/1\n",
+			wantErr:  ":1: invalid operation",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ops")
+			if err := ioutil.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("WriteFile(%v) failed: %v", path, err)
+			}
+			if _, err := readBatch(path); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("readBatch(%v) = %v, want an error containing %q", path, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPerformBatchConcurrency(t *testing.T) {
+	const concurrency = 3
+	var ops []*batchOp
+	for i := 1; i <= 10; i++ {
+		ops = append(ops, &batchOp{line: i, args: &opArgs{Operation: downloadBlob}})
+	}
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	var runs int32
+	run := func(op *batchOp) error {
+		atomic.AddInt32(&runs, 1)
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}
+	if err := performBatch("ops", ops, concurrency, run, ioutil.Discard); err != nil {
+		t.Fatalf("performBatch() failed: %v", err)
+	}
+	if runs := atomic.LoadInt32(&runs); runs != int32(len(ops)) {
+		t.Errorf("performBatch() ran %d operations, want %d", runs, len(ops))
+	}
+	if maxInFlight > concurrency {
+		t.Errorf("performBatch() ran %d operations concurrently, want at most %d", maxInFlight, concurrency)
+	}
+}
+
+func TestPerformBatchSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    map[int]bool
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "success",
+			want: []string{
+				"Operations complete",
+				"-------------------",
+				"Operations: 3",
+				"Succeeded: 3",
+				"Failed: 0",
+			},
+		},
+		{
+			name: "failures",
+			fail: map[int]bool{1: true, 3: true},
+			want: []string{
+				"Operations complete",
+				"-------------------",
+				"Operations: 3",
+				"Succeeded: 1",
+				"Failed: 2",
+				"ops:1: download_blob: failed on line 1",
+				"ops:3: show_action: failed on line 3",
+			},
+			wantErr: "2 of 3 operations failed",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ops := []*batchOp{
+				{line: 1, args: &opArgs{Operation: downloadBlob}},
+				{line: 2, args: &opArgs{Operation: downloadBlob}},
+				{line: 3, args: &opArgs{Operation: showAction}},
+			}
+			run := func(op *batchOp) error {
+				if tc.fail[op.line] {
+					return fmt.Errorf("failed on line %d", op.line)
+				}
+				return nil
+			}
+			out := &bytes.Buffer{}
+			err := performBatch("ops", ops, 2, run, out)
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("performBatch() failed: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Errorf("performBatch() = %v, want error %q", err, tc.wantErr)
+			}
+			var got []string
+			for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				// The elapsed time varies.
+				if !strings.HasPrefix(l, "Elapsed: ") {
+					got = append(got, l)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("performBatch() printed diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
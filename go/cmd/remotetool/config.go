@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	rflags "github.com/bazelbuild/remote-apis-sdks/go/pkg/flags"
+)
+
+// defaultConfigFile is the config file used when neither --config nor $RBE_CONFIG is set, in the
+// home directory.
+const defaultConfigFile = ".remotetool.json"
+
+// loadConfig sets the flags which are not set on the command line from their RBE_ environment
+// variables and from the config file, as rflags.NewClientFromFlags does, so that the flags read
+// before connecting, e.g. --instance when set from a ByteStream URL, take them into account. The
+// config file defaults to ~/.remotetool.json if it exists.
+func loadConfig() error {
+	setDefaultConfigFile()
+	if err := rflags.LoadEnv(); err != nil {
+		return err
+	}
+	return rflags.LoadConfig()
+}
+
+// setDefaultConfigFile sets --config to ~/.remotetool.json if it exists and no config file is set.
+func setDefaultConfigFile() {
+	if *rflags.ConfigFile != "" || os.Getenv(rflags.ConfigFileEnv) != "" {
+		return
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(home, defaultConfigFile)
+	if _, err := os.Stat(path); err == nil {
+		*rflags.ConfigFile = path
+	}
+}
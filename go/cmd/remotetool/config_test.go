@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	rflags "github.com/bazelbuild/remote-apis-sdks/go/pkg/flags"
+)
+
+func TestSetDefaultConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		homeFile bool
+		flag     string
+		env      string
+		// wantHome is whether --config is set to the file in the home directory.
+		wantHome bool
+		want     string
+	}{
+		{
+			name:     "home file",
+			homeFile: true,
+			wantHome: true,
+		},
+		{
+			name: "no home file",
+		},
+		{
+			name:     "flag set",
+			homeFile: true,
+			flag:     "/etc/remotetool.json",
+			want:     "/etc/remotetool.json",
+		},
+		{
+			name:     "env set",
+			homeFile: true,
+			env:      "/etc/remotetool.json",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer resetConfigFile()
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv(rflags.ConfigFileEnv, tc.env)
+			path := filepath.Join(home, defaultConfigFile)
+			if tc.homeFile {
+				if err := ioutil.WriteFile(path, []byte("{}"), 0644); err != nil {
+					t.Fatalf("WriteFile(%v) failed: %v", path, err)
+				}
+			}
+			if tc.flag != "" {
+				flag.Set("config", tc.flag)
+			}
+			want := tc.want
+			if tc.wantHome {
+				want = path
+			}
+			setDefaultConfigFile()
+			if *rflags.ConfigFile != want {
+				t.Errorf("setDefaultConfigFile() set --config to %q, want %q", *rflags.ConfigFile, want)
+			}
+		})
+	}
+}
+
+// rflags.LoadConfig only reads the config file once per process, so this is the only test calling
+// loadConfig.
+func TestLoadConfig(t *testing.T) {
+	defer resetConfigFile()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(rflags.ConfigFileEnv, "")
+	t.Setenv(rflags.EnvPrefix+"CAS_CONCURRENCY", "9")
+	path := filepath.Join(home, defaultConfigFile)
+	if err := ioutil.WriteFile(path, []byte(`{"instance": "config", "cas_concurrency": 7}`), 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", path, err)
+	}
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if *rflags.Instance != "config" {
+		t.Errorf("loadConfig() set --instance to %q, want %q from the config file", *rflags.Instance, "config")
+	}
+	// The environment takes precedence over the config file.
+	if *rflags.CASConcurrency != 9 {
+		t.Errorf("loadConfig() set --cas_concurrency to %d, want %d from the environment", *rflags.CASConcurrency, 9)
+	}
+}
+
+// resetConfigFile restores the flags, including --config, which setDefaultConfigFile sets without
+// marking it as set.
+func resetConfigFile() {
+	resetFlags()
+	*rflags.ConfigFile = ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	rdigest "github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	rflags "github.com/bazelbuild/remote-apis-sdks/go/pkg/flags"
+)
+
+// digestRegex matches the digests in hash/size format, with the character preceding them, if
+// any, so that those in paths or already prefixed with their digest function are left alone.
+var digestRegex = regexp.MustCompile(fmt.Sprintf(`(^|[^/\w:])([0-9a-f]{%d}/[0-9]+)\b`, rdigest.HashFn.Size()*2))
+
+// prefixDigests prefixes the digests in s with the name of the digest function, in the format of
+// digest.FormatFunction, e.g. sha256:hash/size.
+func prefixDigests(s string) string {
+	return digestRegex.ReplaceAllString(s, "${1}"+rdigest.FunctionName(rdigest.GetDigestFunction())+":${2}")
+}
+
+// digestFunctionWriter is a writer prefixing the digests written to it with the name of the digest
+// function. Writes are buffered up to the end of a line, so that digests split across writes are
+// found; Flush writes the rest.
+type digestFunctionWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (w *digestFunctionWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	line := string(w.buf[:i+1])
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	if _, err := io.WriteString(w.w, prefixDigests(line)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the buffered output, which does not end with a newline.
+func (w *digestFunctionWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.w, prefixDigests(string(w.buf)))
+	w.buf = w.buf[:0]
+	return err
+}
+
+// prefixStdoutDigests redirects os.Stdout through a digestFunctionWriter, so that what the
+// operations print, including through fmt.Printf, has its digests prefixed. It returns a function
+// writing the rest of the output and restoring os.Stdout, to call before exiting.
+func prefixStdoutDigests() (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dw := &digestFunctionWriter{w: stdout}
+		if _, err := io.Copy(dw, r); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing to stdout: %v\n", err)
+		}
+		dw.Flush()
+	}()
+	return func() {
+		w.Close()
+		<-done
+		os.Stdout = stdout
+	}, nil
+}
+
+// isBlobReference returns whether s refers to a blob by its resource name or ByteStream URL, e.g.
+// instance/blobs/hash/size or bytestream://host/instance/blobs/hash/size, rather than by its digest.
+func isBlobReference(s string) bool {
+	return strings.HasPrefix(s, "bytestream://") || strings.Contains(s, "blobs/")
+}
+
+// resolveDigests replaces the digests of a given as resource names or ByteStream URLs with the
+// digests they name. It returns an error if they name an instance other than instance, the one of
+// the connection.
+func (a *opArgs) resolveDigests(instance string) error {
+	for _, s := range []*string{&a.Digest, &a.OtherDigest} {
+		if !isBlobReference(*s) {
+			continue
+		}
+		dg, inst, err := rdigest.ParseReference(*s)
+		if err != nil {
+			return err
+		}
+		if inst != "" && inst != instance {
+			return fmt.Errorf("%v names a blob of instance %q, but the instance is %q", *s, inst, instance)
+		}
+		*s = dg.String()
+	}
+	return nil
+}
+
+// connectToDigestServer sets --service and --instance, if unset, to the server and the instance
+// named by the ByteStream URL or resource name given as --digest, if any, so that a URL copied
+// from a server is enough to connect to it.
+func connectToDigestServer() {
+	if !isBlobReference(*digest) {
+		return
+	}
+	_, inst, err := rdigest.ParseReference(*digest)
+	if err != nil {
+		// Reported when performing the operation.
+		return
+	}
+	if *rflags.Instance == "" && inst != "" {
+		flag.Set("instance", inst)
+	}
+	if host, _, err := rdigest.ParseBytestreamURL(*digest); err == nil && *rflags.Service == "" {
+		flag.Set("service", host)
+	}
+}
@@ -2,14 +2,53 @@
 //
 // This tool supports common debugging operations concerning remotely executed
 // actions:
-// 1. Download a file or directory from remote cache by its digest.
-// 2. Display details of a remotely executed action.
+// 1. Download a file or directory from remote cache by its digest, or the input root of an action,
+// e.g. to inspect it or run the action locally.
+// 2. Display details of a remotely executed action, or dump the list of its inputs to a CSV file.
+// List every digest reachable from an action, e.g. for garbage collection or cache pinning tooling.
+// Compare two actions, or two trees, e.g. to explain a cache miss.
 // 3. Download action results by the action digest.
-// 4. Re-execute remote action (with optional inputs override).
+// 4. Re-execute remote action (with optional inputs, platform or arguments override), or execute
+// an ad-hoc command described by a Command spec. Clone an action with a different timeout, salt
+// or caching policy, and optionally execute the clone. Execute an action locally and compare its
+// outputs with its cached result, e.g. to isolate failures specific to the remote workers.
+// 5. Upload a local directory to the remote cache, or compute its root digest offline. Compute the
+// trees described by a JSON spec file, optionally uploading them. Upload an
+// action built from flags, e.g. a synthetic action to test a backend with, or an action result
+// built from local files to the action cache. Seed the action cache with the result of a trusted
+// local execution of a command, e.g. from CI builds.
+// 6. Verify a local directory, e.g. a download, against a tree in the remote cache. Prefetch a tree
+// into the --local_cas_dir, so that later downloads of it are served locally.
+// 7. Attach to a remote execution started elsewhere and download its results.
+// 8. Export an action with its inputs as a self-contained archive.
+// 9. Browse a tree in the remote cache interactively, without downloading it, or resolve a path in it.
+// 10. Look up many actions in the action cache, e.g. to measure the cache hit rate of a build.
+// 11. Display the capabilities of the remote execution and cache servers. Load test the remote cache
+// with synthetic blobs or trees, reporting the throughput, latencies and error rates of transfers.
+// 12. Print the RPCs an upload or an execution would issue, without issuing them, with --dry_run.
+// 13. Perform many of the above operations listed in a file, over a single connection.
+// 14. Serve the above operations over HTTP on a local port or unix socket, keeping a single
+// connection open, e.g. for IDE plugins and scripts issuing many small queries.
+//
+// Flags, e.g. those of the connection, may be set in a JSON config file given as --config or
+// $RBE_CONFIG, ~/.remotetool.json by default, mapping flag names to values, e.g.
+// {"service": "remotebuildexecution.googleapis.com:443"}. Flags set on the command line take
+// precedence.
+//
+// With --stats_file, a JSON summary of the session is written on exit: bytes and blobs
+// transferred, cache hits and misses, retries and the wall time of each operation. With
+// --log_format=jsonl, the progress and the results of operations are written to stdout as JSON
+// lines, for automation to parse. Otherwise, the progress of long transfers is rendered on stderr
+// when it is a terminal, unless --progress=false. With --print_digest_function, digests are
+// printed prefixed with their digest function, e.g. sha256:<digest/size_bytes>.
+//
+// Operations are performed by subcommands named after them, with dashes, e.g.
+// remotetool download-action-result --digest=..., or equivalently with --operation. The
+// completion subcommand prints the completions of the subcommands and flags for bash, zsh or fish,
+// e.g. source <(remotetool completion bash).
 //
 // Example (download an action result from remote action cache):
-// bazelisk run //go/cmd/remotetool -- \
-//  --operation=download_action_result \
+// bazelisk run //go/cmd/remotetool -- download-action-result \
 // 	--instance=$INSTANCE \
 // 	--service remotebuildexecution.googleapis.com:443 \
 // 	--alsologtostderr --v 1 \
@@ -19,15 +58,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
-	"path"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/moreflag"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/tool"
 
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
 	rflags "github.com/bazelbuild/remote-apis-sdks/go/pkg/flags"
 	log "github.com/golang/glog"
 )
@@ -38,123 +84,945 @@ type OpType string
 const (
 	downloadActionResult OpType = "download_action_result"
 	showAction           OpType = "show_action"
+	showCapabilities     OpType = "show_capabilities"
 	downloadAction       OpType = "download_action"
 	downloadBlob         OpType = "download_blob"
 	downloadDir          OpType = "download_dir"
+	downloadInputs       OpType = "download_inputs"
+	downloadStdio        OpType = "download_stdio"
+	browseTree           OpType = "browse_tree"
+	pathLookup           OpType = "path_lookup"
 	executeAction        OpType = "execute_action"
+	executeCommand       OpType = "execute"
+	reexecuteAction      OpType = "reexecute_action"
+	exportAction         OpType = "export_action"
+	localExecute         OpType = "local_execute"
+	prefetchTree         OpType = "prefetch_tree"
 	checkDeterminism     OpType = "check_determinism"
+	cloneAction          OpType = "clone_action"
+	checkInputs          OpType = "check_inputs"
+	dumpInputs           OpType = "dump_inputs"
+	listRefs             OpType = "list_refs"
+	checkActionCache     OpType = "check_action_cache"
+	computeRoot          OpType = "compute_root"
+	computeTree          OpType = "compute_tree"
+	diffActions          OpType = "diff_actions"
+	treeDiff             OpType = "tree_diff"
 	uploadBlob           OpType = "upload_blob"
 	uploadBlobV2         OpType = "upload_blob_v2"
+	uploadAction         OpType = "upload_action"
+	uploadActionResult   OpType = "upload_action_result"
+	uploadDir            OpType = "upload_dir"
+	seedCache            OpType = "seed_cache"
+	bench                OpType = "bench"
+	verifyDir            OpType = "verify_dir"
+	waitOperation        OpType = "wait_operation"
 )
 
 var supportedOps = []OpType{
 	downloadActionResult,
 	showAction,
+	showCapabilities,
 	downloadAction,
 	downloadBlob,
 	downloadDir,
+	downloadInputs,
+	downloadStdio,
+	browseTree,
+	pathLookup,
 	executeAction,
+	executeCommand,
+	reexecuteAction,
+	exportAction,
+	localExecute,
+	prefetchTree,
 	checkDeterminism,
+	cloneAction,
+	checkInputs,
+	dumpInputs,
+	listRefs,
+	checkActionCache,
+	computeRoot,
+	computeTree,
+	diffActions,
+	treeDiff,
 	uploadBlob,
+	uploadAction,
+	uploadActionResult,
+	uploadDir,
+	seedCache,
+	bench,
+	verifyDir,
+	waitOperation,
 }
 
 var (
-	operation    = flag.String("operation", "", fmt.Sprintf("Specifies the operation to perform. Supported values: %v", supportedOps))
-	digest       = flag.String("digest", "", "Digest in <digest/size_bytes> format.")
-	pathPrefix   = flag.String("path", "", "Path to which outputs should be downloaded to.")
-	actionRoot   = flag.String("action_root", "", "For execute_action: the root of the action spec, containing ac.textproto (Action proto), cmd.textproto (Command proto), and input/ (root of the input tree).")
-	execAttempts = flag.Int("exec_attempts", 10, "For check_determinism: the number of times to remotely execute the action and check for mismatches.")
-	_            = flag.String("input_root", "", "Deprecated. Use action root instead.")
+	operation      = flag.String("operation", "", fmt.Sprintf("Specifies the operation to perform. Supported values: %v", supportedOps))
+	digest         = flag.String("digest", "", "Digest in <digest/size_bytes> format, optionally prefixed with the digest function, e.g. sha256:<digest/size_bytes>. A ByteStream resource name or URL of the blob is also accepted, e.g. bytestream://<host>/<instance>/blobs/<digest>/<size_bytes>, in which case --service and --instance default to its host and instance.")
+	otherDigest    = flag.String("other_digest", "", "For diff_actions: the digest of the action to compare with the action of --digest, in <digest/size_bytes> format. For tree_diff: the digest of the tree to compare with the tree of --digest.")
+	pathPrefix     = flag.String("path", "", "Path to which outputs should be downloaded to. For download_blob and download_stdio, the blob or the stdout and stderr are written to the console when unset. For upload_blob, - reads the blob from stdin and prints its digest. For check_determinism, the mismatching outputs of each execution are downloaded to it when set. For dump_inputs, the CSV file to write, tab-separated if its name ends with .tsv, or the console when unset. For upload_action_result, the directory the --output_paths are relative to. For download_inputs, the directory the input root of the action is downloaded to. For clone_action with --execute_clone, the outputs of the clone are downloaded to it when set. For seed_cache, the directory the outputs of the command are read from, laid out like its exec root, which is used if unset.")
+	actionRoot     = flag.String("action_root", "", "For execute_action: the root of the action spec, containing ac.textproto (Action proto), cmd.textproto (Command proto), and input/ (root of the input tree).")
+	execAttempts   = flag.Int("exec_attempts", 10, "For check_determinism: the number of times to remotely execute the action and check for mismatches.")
+	compareLocal   = flag.Bool("compare_local", false, "For check_determinism: once the remote executions are consistent, also execute the action locally and compare its outputs with the remote ones, to tell machine-dependent actions from flaky workers.")
+	diffOutputs    = flag.Bool("diff_outputs", false, "For check_determinism: print unified diffs of the mismatching outputs which are small text files.")
+	parallel       = flag.Int("parallel", 1, "For check_determinism: the maximum number of executions of the action running concurrently. For check_action_cache: the maximum number of concurrent lookups. For prefetch_tree: the maximum number of blobs fetched concurrently. For bench: the maximum number of blobs or trees transferred concurrently.")
+	opName         = flag.String("operation_name", "", "For wait_operation: the name of the Operation of the execution to attach to.")
+	format         = flag.String("format", "text", fmt.Sprintf("For show_action and show_capabilities: the output format. Supported values: %v", tool.ShowFormats))
+	inputsDepth    = flag.Int("show_inputs_depth", 0, "For show_action in text format: if set, also list the input tree recursively with the size of every file and directory, down to this depth. Use -1 to list the full tree.")
+	copySymlinks   = flag.Bool("copy_symlinks", false, "For download_action_result: write copies of the targets of output symlinks instead of symlinks, e.g. on Windows where creating symlinks requires privileges.")
+	resume         = flag.Bool("resume", false, "For download_dir and download_inputs: keep the existing contents of --path and only download the files missing from it or whose digest does not match, e.g. to resume a download which failed midway.")
+	archive        = flag.String("archive_format", "", "For download_action_result: if set to tar or zip, write the outputs into an archive at --path instead of extracting them.")
+	digestsFile    = flag.String("digests_file", "", "For check_action_cache: the file listing the action digests to look up, one per line.")
+	treePath       = flag.String("tree_path", "", "For path_lookup: the path to resolve, relative to the root of the tree of --digest, e.g. foo/bar/baz.o.")
+	opsFile        = flag.String("operations_file", "", "Path to a file of operations to perform instead of --operation, one JSON object per line with the operation and its arguments named like the flags, e.g. {\"operation\": \"download_blob\", \"digest\": \"<digest/size_bytes>\", \"path\": \"/tmp/blob\"}. Arguments not set in the file default to the flags.")
+	serveAddr      = flag.String("serve", "", "Address to serve operations on instead of --operation, over a single connection kept open until interrupted, either a unix socket, e.g. unix:/tmp/remotetool.sock, or a loopback address, e.g. localhost:8080, in which case requests must carry the token printed on stderr as a bearer token. Operations are requested by POSTing their arguments as JSON objects in the format of --operations_file lines, with the application/json content type, and the responses are their result events, as with --log_format=jsonl. local_execute, the upload operations, download_dir and browse_tree are not served.")
+	opsConcurrency = flag.Int("operations_concurrency", 8, "For --operations_file: the maximum number of operations performed concurrently.")
+	printDigestFn  = flag.Bool("print_digest_function", false, "Print digests prefixed with their digest function, e.g. sha256:<digest/size_bytes>, so that they tell which function produced them when working across backends. Digests in that format are accepted as arguments regardless.")
+	showProgress   = flag.Bool("progress", true, "For download_dir, download_inputs and upload_dir: render the progress of the transfer on stderr, with its throughput and estimated time left, when stderr is a terminal and --log_format is text.")
+	logFormat      = flag.String("log_format", "text", "The format of the output. Supported values: text, jsonl. With jsonl, stdout only receives JSON lines: an event when each operation starts, its progress, and its result with its output or its error.")
+	_              = flag.String("input_root", "", "Deprecated. Use action root instead.")
+	commandSpec    = flag.String("command_spec", "", "For execute and seed_cache: path to the Command proto (see go/api/command) to execute, in JSON if the file name ends with .json and in text format otherwise. Its exec_root is the local input root, relative to the directory of the spec, whose entries are all inputs unless listed in the spec. For execute, outputs are downloaded to --path if set.")
+	inputSpec      = flag.String("input_spec", "", "For compute_root: path to an InputSpec text proto (see go/api/command) listing the inputs relative to --path. All the entries of --path are inputs if unset. For compute_tree: path to a JSON file listing the trees to compute, e.g. [{\"name\": \"srcs\", \"root\": \"src\", \"input_spec\": {\"inputs\": [\"lib\", \"main.c\"]}}]. Each tree has a local root directory, relative to the file unless absolute, an optional InputSpec in JSON listing its inputs relative to the root, all its entries otherwise, and an optional name, its root by default.")
+	treeProto      = flag.Bool("tree_proto", false, "For prefetch_tree and tree_diff: the digests are those of Tree protos, e.g. of output directories, rather than of root Directories.")
+	uploadTrees    = flag.Bool("upload", false, "For compute_tree: also upload the blobs of the trees missing from the CAS.")
+	argsFile       = flag.String("args_file", "", "For reexecute_action: path to a file with the arguments replacing those of the command, one per line.")
+	stdoutFile     = flag.String("stdout_file", "", "For upload_action_result and seed_cache: path to the file with the stdout of the action.")
+	stderrFile     = flag.String("stderr_file", "", "For upload_action_result and seed_cache: path to the file with the stderr of the action.")
+	exitCode       = flag.Int("exit_code", 0, "For upload_action_result and seed_cache: the exit code of the action.")
+	timeout        = flag.Duration("timeout", 0, "For clone_action: the execution timeout of the clone, e.g. 10m. The timeout of the action is kept if unset.")
+	doNotCache     = flag.Bool("do_not_cache", false, "For clone_action: whether the result of the clone must not be cached. The policy of the action is kept if unset.")
+	salt           = flag.String("salt", "", "For clone_action: the salt of the clone, which gives it a digest of its own, e.g. to bypass the action cache. The salt of the action is kept if unset.")
+	executeClone   = flag.Bool("execute_clone", false, "For clone_action: also execute the clone remotely, writing its stdout and stderr to the console.")
+	inputRootDg    = flag.String("input_root_digest", "", "For upload_action: the digest of the input root of the action, in <digest/size_bytes> format. The input root is empty if unset.")
+	uploadConc     = flag.Int("upload_concurrency", 0, "The maximum number of files, or batches of small files, uploaded concurrently, e.g. to saturate a fast link with large directory uploads, or to throttle them on a constrained machine. Defaults to --cas_concurrency.")
+	downloadConc   = flag.Int("download_concurrency", 0, "The maximum number of files, or batches of small files, downloaded concurrently, e.g. to saturate a fast link with large directory downloads, or to throttle them on a constrained machine. Defaults to --cas_concurrency.")
+	maxBatchDgs    = flag.Int("max_batch_digests", 0, "The maximum number of blobs in a batch request to the CAS. Defaults to 4000.")
+	maxBatchSize   = flag.Int64("max_batch_size", 0, "The maximum total size in bytes of the blobs of a batch request to the CAS, taking precedence over the limit advertised by the server. Defaults to that limit, or to slightly below 4MiB.")
+	chunkSize      = flag.Int("chunk_size", 0, "The size in bytes of the chunks in which large files are streamed to and from the CAS. Defaults to 1MiB.")
+	benchShape     = flag.String("bench_shape", string(tool.BenchBlobs), fmt.Sprintf("For bench: the shape of the data transferred. Supported values: %v", tool.BenchShapes))
+	benchOps       = flag.Int("bench_ops", 100, "For bench: the number of blobs, or of trees, uploaded then downloaded.")
+	benchMinSize   = flag.Int64("bench_min_size", 1024, "For bench: the minimum size in bytes of the blobs, or of the files of the trees.")
+	benchMaxSize   = flag.Int64("bench_max_size", 1024*1024, "For bench: the maximum size in bytes of the blobs, or of the files of the trees.")
+	benchFiles     = flag.Int("bench_tree_files", 100, "For bench with --bench_shape=trees: the number of files of each tree.")
+	benchFanOut    = flag.Int("bench_tree_fanout", 10, "For bench with --bench_shape=trees: the maximum number of files in each directory of a tree.")
+	benchSeed      = flag.Int64("bench_seed", 0, "For bench: the seed of the random contents of the blobs, e.g. to transfer the same blobs again. A new seed is used if unset.")
+	dryRun         = flag.Bool("dry_run", false, "For execute_action, reexecute_action and upload_dir: print the RPCs the operation would issue, i.e. the number of digests looked up with FindMissingBlobs, the blobs and bytes uploaded if missing and the digest of the action executed, without uploading or executing anything.")
+	platform       = make(map[string]string)
+	argsOverride   []string
+	appendArgs     []string
+	envOverride    = make(envValue)
+	envUnset       repeatedValue
+	cmdArgs        []string
+	env            = make(envValue)
+	outputPaths    []string
+	include        repeatedValue
+	exclude        repeatedValue
 )
 
+func init() {
+	flag.Var((*moreflag.StringMapValue)(&platform), "platform", "For reexecute_action: comma-separated key value pairs in the form key=value, overriding the platform properties of the action, e.g. to run it on another worker pool or container image. A key with an empty value removes the property. For upload_action: the platform properties of the action.")
+	flag.Var((*moreflag.StringListValue)(&argsOverride), "args_override", "For reexecute_action: comma-separated arguments replacing those of the command. Use --args_file for arguments containing commas.")
+	flag.Var((*moreflag.StringListValue)(&appendArgs), "append_args", "For reexecute_action: comma-separated arguments appended to those of the command, e.g. -v to make a compiler verbose.")
+	flag.Var(envOverride, "env_override", "For reexecute_action: an environment variable set in the command, in the form KEY=VALUE. May be repeated.")
+	flag.Var(&envUnset, "env_unset", "For reexecute_action: the name of an environment variable removed from the command. May be repeated.")
+	flag.Var((*moreflag.StringListValue)(&cmdArgs), "cmd_args", "For upload_action: comma-separated arguments of the command, the first one being the program to run.")
+	flag.Var(env, "env", "For upload_action: an environment variable of the command, in the form KEY=VALUE. May be repeated.")
+	flag.Var((*moreflag.StringListValue)(&outputPaths), "output_paths", "For upload_action: comma-separated paths of the outputs of the command, relative to its working directory. Paths ending with a slash are output directories. For upload_action_result: comma-separated paths of the output files and directories, relative to --path.")
+	flag.Var(&include, "include", "For download_dir and download_inputs: a glob pattern of the paths to download, e.g. *.h or src/*/BUILD. Patterns without a slash match names at any depth, and the contents of matching directories are included. May be repeated.")
+	flag.Var(&exclude, "exclude", "For download_dir and download_inputs: a glob pattern of the paths not to download, in the syntax of --include. Matching directories are skipped entirely. May be repeated.")
+}
+
+// envValue is a flag accumulating KEY=VALUE environment variables over repeated uses. Unlike
+// moreflag.StringMapValue, values may contain commas and equal signs.
+type envValue map[string]string
+
+func (m envValue) String() string {
+	v := moreflag.StringMapValue(m)
+	return v.String()
+}
+
+func (m envValue) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("wrong format for environment variable %q, want KEY=VALUE", s)
+	}
+	m[s[:i]] = s[i+1:]
+	return nil
+}
+
+// repeatedValue is a flag accumulating its values over repeated uses.
+type repeatedValue []string
+
+func (r *repeatedValue) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *repeatedValue) Set(s string) error {
+	*r = append(*r, s)
+	return nil
+}
+
+// opArgs are the arguments of an operation, set from the flags or from a line of the
+// --operations_file.
+type opArgs struct {
+	Operation     OpType            `json:"operation"`
+	Digest        string            `json:"digest"`
+	OtherDigest   string            `json:"other_digest"`
+	Path          string            `json:"path"`
+	DigestsFile   string            `json:"digests_file"`
+	TreePath      string            `json:"tree_path"`
+	ActionRoot    string            `json:"action_root"`
+	ExecAttempts  int               `json:"exec_attempts"`
+	Parallel      int               `json:"parallel"`
+	Diff          bool              `json:"diff_outputs"`
+	CompareLocal  bool              `json:"compare_local"`
+	OperationName string            `json:"operation_name"`
+	Format        string            `json:"format"`
+	InputsDepth   int               `json:"show_inputs_depth"`
+	ArchiveFormat string            `json:"archive_format"`
+	CopySymlinks  bool              `json:"copy_symlinks"`
+	Resume        bool              `json:"resume"`
+	Platform      map[string]string `json:"platform"`
+	ArgsOverride  []string          `json:"args_override"`
+	ArgsFile      string            `json:"args_file"`
+	InputSpec     string            `json:"input_spec"`
+	CommandSpec   string            `json:"command_spec"`
+	AppendArgs    []string          `json:"append_args"`
+	EnvOverride   map[string]string `json:"env_override"`
+	EnvUnset      []string          `json:"env_unset"`
+	Include       []string          `json:"include"`
+	Exclude       []string          `json:"exclude"`
+	CmdArgs       []string          `json:"cmd_args"`
+	Env           map[string]string `json:"env"`
+	InputRootDg   string            `json:"input_root_digest"`
+	OutputPaths   []string          `json:"output_paths"`
+	StdoutFile    string            `json:"stdout_file"`
+	StderrFile    string            `json:"stderr_file"`
+	ExitCode      int               `json:"exit_code"`
+	Timeout       string            `json:"timeout"`
+	DoNotCache    *bool             `json:"do_not_cache"`
+	Salt          *string           `json:"salt"`
+	ExecuteClone  bool              `json:"execute_clone"`
+	Upload        bool              `json:"upload"`
+	TreeProto     bool              `json:"tree_proto"`
+	DryRun        bool              `json:"dry_run"`
+	BenchShape    string            `json:"bench_shape"`
+	BenchOps      int               `json:"bench_ops"`
+	BenchMinSize  int64             `json:"bench_min_size"`
+	BenchMaxSize  int64             `json:"bench_max_size"`
+	BenchFiles    int               `json:"bench_tree_files"`
+	BenchFanOut   int               `json:"bench_tree_fanout"`
+	BenchSeed     int64             `json:"bench_seed"`
+}
+
+func argsFromFlags() *opArgs {
+	// Copied, since decoding an --operations_file line into the arguments would update them.
+	pl := make(map[string]string, len(platform))
+	for k, v := range platform {
+		pl[k] = v
+	}
+	envOv := make(map[string]string, len(envOverride))
+	for k, v := range envOverride {
+		envOv[k] = v
+	}
+	cmdEnv := make(map[string]string, len(env))
+	for k, v := range env {
+		cmdEnv[k] = v
+	}
+	// Unlike the other flags, the overrides of clone_action only apply when set explicitly.
+	var dnc *bool
+	var sl *string
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "do_not_cache":
+			dnc = doNotCache
+		case "salt":
+			sl = salt
+		}
+	})
+	to := ""
+	if *timeout != 0 {
+		to = timeout.String()
+	}
+	return &opArgs{
+		Operation:     OpType(*operation),
+		Digest:        *digest,
+		OtherDigest:   *otherDigest,
+		Path:          *pathPrefix,
+		DigestsFile:   *digestsFile,
+		TreePath:      *treePath,
+		ActionRoot:    *actionRoot,
+		ExecAttempts:  *execAttempts,
+		Parallel:      *parallel,
+		Diff:          *diffOutputs,
+		CompareLocal:  *compareLocal,
+		OperationName: *opName,
+		Format:        *format,
+		InputsDepth:   *inputsDepth,
+		ArchiveFormat: *archive,
+		CopySymlinks:  *copySymlinks,
+		Resume:        *resume,
+		Platform:      pl,
+		ArgsOverride:  argsOverride,
+		ArgsFile:      *argsFile,
+		InputSpec:     *inputSpec,
+		CommandSpec:   *commandSpec,
+		AppendArgs:    appendArgs,
+		EnvOverride:   envOv,
+		EnvUnset:      envUnset,
+		Include:       include,
+		Exclude:       exclude,
+		CmdArgs:       cmdArgs,
+		Env:           cmdEnv,
+		InputRootDg:   *inputRootDg,
+		OutputPaths:   outputPaths,
+		StdoutFile:    *stdoutFile,
+		StderrFile:    *stderrFile,
+		ExitCode:      *exitCode,
+		Timeout:       to,
+		DoNotCache:    dnc,
+		Salt:          sl,
+		ExecuteClone:  *executeClone,
+		Upload:        *uploadTrees,
+		TreeProto:     *treeProto,
+		DryRun:        *dryRun,
+		BenchShape:    *benchShape,
+		BenchOps:      *benchOps,
+		BenchMinSize:  *benchMinSize,
+		BenchMaxSize:  *benchMaxSize,
+		BenchFiles:    *benchFiles,
+		BenchFanOut:   *benchFanOut,
+		BenchSeed:     *benchSeed,
+	}
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [-flags] -- --operation <op> arguments ...\n", path.Base(os.Args[0]))
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %[1]v <subcommand> [-flags]\n   or: %[1]v [-flags] --operation <op>\n", binaryName())
+		fmt.Fprintf(out, "\nSubcommands:\n  %v\n  completion <%v>\n  help\n\nFlags:\n", strings.Join(subcommandNames(), "\n  "), strings.Join(completionShells, "|"))
 		flag.PrintDefaults()
 	}
-	flag.Parse()
-	if *operation == "" {
-		log.Exitf("--operation must be specified.")
+	if done, err := parseCommandLine(os.Args[1:], os.Stdout); err != nil {
+		log.Exitf("%v", err)
+	} else if done {
+		return
+	}
+	if err := loadConfig(); err != nil {
+		log.Exitf("error loading the config: %v", err)
+	}
+	if *operation == "" && *opsFile == "" && *serveAddr == "" {
+		log.Exitf("a subcommand, --operation, --operations_file or --serve must be specified.")
 	}
-	if *execAttempts <= 0 {
-		log.Exitf("--exec_attempts must be >= 1.")
+	if *opsConcurrency <= 0 {
+		log.Exitf("--operations_concurrency must be >= 1.")
 	}
+	var events *tool.EventLog
+	switch *logFormat {
+	case "text":
+	case "jsonl":
+		events = tool.NewEventLog(os.Stdout)
+		// Anything else printed by the operations goes to stderr, so that stdout is only events.
+		os.Stdout = os.Stderr
+	default:
+		log.Exitf("unsupported --log_format %v, supported values are text and jsonl.", *logFormat)
+	}
+	restoreStdout := func() {}
+	if *printDigestFn {
+		var err error
+		if restoreStdout, err = prefixStdoutDigests(); err != nil {
+			log.Exitf("error redirecting stdout: %v", err)
+		}
+	}
+
+	connectToDigestServer()
 
 	ctx := context.Background()
-	grpcClient, err := rflags.NewClientFromFlags(ctx)
+	var c *tool.Client
+	if op := OpType(*operation); *opsFile == "" && *serveAddr == "" && (op == computeRoot || op == computeTree && !*uploadTrees) {
+		// Computing root digests is local, there is no need to connect.
+		c = &tool.Client{GrpcClient: &rc.Client{}}
+	} else {
+		grpcClient, err := rflags.NewClientFromFlags(ctx)
+		if err != nil {
+			err = fmt.Errorf("error connecting to remote execution client: %v", err)
+			if events != nil {
+				writeEvent(events, &tool.Event{Type: tool.ErrorEvent, Error: err.Error()})
+			}
+			log.Exitf("%v", err)
+		}
+		// The session stats written to --stats_file below include the build stats the client
+		// would write when closed.
+		rc.StatsFile("").Apply(grpcClient)
+		defer grpcClient.Close()
+		c = &tool.Client{GrpcClient: grpcClient}
+		opts := &tool.TransferOptions{
+			UploadConcurrency:   *uploadConc,
+			DownloadConcurrency: *downloadConc,
+			MaxBatchDigests:     *maxBatchDgs,
+			MaxBatchSize:        *maxBatchSize,
+			ChunkSize:           *chunkSize,
+		}
+		if err := c.ApplyTransferOptions(opts); err != nil {
+			log.Exitf("invalid transfer options: %v", err)
+		}
+	}
+	c.Events = events
+	if *showProgress && events == nil && *opsFile == "" && *serveAddr == "" && isTerminal(os.Stderr) {
+		c.OnProgress = tool.ProgressWriter(os.Stderr)
+	}
+
+	var err error
+	if *serveAddr != "" {
+		err = serve(ctx, c, *serveAddr)
+	} else if *opsFile != "" {
+		err = runBatch(ctx, c, *opsFile, *opsConcurrency)
+	} else {
+		err = runOp(ctx, c, argsFromFlags(), "")
+	}
+	if *rflags.StatsFile != "" {
+		if serr := c.WriteStatsFile(*rflags.StatsFile); serr != nil {
+			log.Errorf("error writing stats to %v: %v", *rflags.StatsFile, serr)
+		}
+	}
+	restoreStdout()
+	if err != nil {
+		log.Exitf("%v", err)
+	}
+}
+
+// isTerminal returns whether f is a terminal rather than, e.g., a file or a pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runOp performs the operation with the given arguments, and records its duration and outcome in
+// the session stats of c. If c has an EventLog, the start and the result of the operation are
+// written to it as events, with what the operation prints; source identifies the operation in them.
+func runOp(ctx context.Context, c *tool.Client, a *opArgs, source string) error {
+	if c.Events != nil {
+		_, err := captureOp(ctx, c, a, source)
+		return err
+	}
+	start := time.Now()
+	err := performOp(ctx, c, a, os.Stdout, outerr.SystemOutErr)
+	c.RecordOperation(string(a.Operation), time.Since(start), err)
+	return err
+}
+
+// captureOp is like runOp, but returns the result of the operation as an event with what it
+// prints instead of printing it.
+func captureOp(ctx context.Context, c *tool.Client, a *opArgs, source string) (*tool.Event, error) {
+	start := time.Now()
+	if c.Events != nil {
+		writeEvent(c.Events, &tool.Event{Type: tool.StartEvent, Operation: string(a.Operation), Source: source})
+	}
+	var out, stderr bytes.Buffer
+	err := performOp(ctx, c, a, &out, outerr.NewStreamOutErr(&out, &stderr))
+	output := out.String()
+	if *printDigestFn {
+		output = prefixDigests(output)
+	}
+	ev := &tool.Event{
+		Type:      tool.ResultEvent,
+		Operation: string(a.Operation),
+		Source:    source,
+		Output:    output,
+		Stderr:    stderr.String(),
+		Duration:  time.Since(start),
+	}
 	if err != nil {
-		log.Exitf("error connecting to remote execution client: %v", err)
+		ev.Type = tool.ErrorEvent
+		ev.Error = err.Error()
 	}
-	defer grpcClient.Close()
-	c := &tool.Client{GrpcClient: grpcClient}
+	if c.Events != nil {
+		writeEvent(c.Events, ev)
+	}
+	c.RecordOperation(string(a.Operation), ev.Duration, err)
+	return ev, err
+}
+
+func writeEvent(l *tool.EventLog, e *tool.Event) {
+	if err := l.Write(e); err != nil {
+		log.Errorf("error writing %v event: %v", e.Type, err)
+	}
+}
 
-	switch OpType(*operation) {
+// performOp performs the operation with the given arguments, writing what it prints to out and
+// the outputs of the actions it executes to oe.
+func performOp(ctx context.Context, c *tool.Client, a *opArgs, out io.Writer, oe outerr.OutErr) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	if err := a.resolveDigests(c.GrpcClient.InstanceName); err != nil {
+		return err
+	}
+	switch a.Operation {
 	case downloadActionResult:
-		if err := c.DownloadActionResult(ctx, getDigestFlag(), getPathFlag()); err != nil {
-			log.Exitf("error downloading action result for digest %v: %v", getDigestFlag(), err)
+		if a.ArchiveFormat != "" {
+			if err := c.DownloadActionResultArchive(ctx, a.Digest, a.Path, a.ArchiveFormat); err != nil {
+				return fmt.Errorf("error archiving action result for digest %v: %v", a.Digest, err)
+			}
+			break
+		}
+		if err := c.DownloadActionResult(ctx, a.Digest, a.Path, a.CopySymlinks); err != nil {
+			return fmt.Errorf("error downloading action result for digest %v: %v", a.Digest, err)
 		}
 
 	case downloadBlob:
-		res, err := c.DownloadBlob(ctx, getDigestFlag(), getPathFlag())
+		if a.Path == "" {
+			if err := c.StreamBlob(ctx, a.Digest, out); err != nil {
+				return fmt.Errorf("error downloading blob for digest %v: %v", a.Digest, err)
+			}
+			return nil
+		}
+		res, err := c.DownloadBlob(ctx, a.Digest, a.Path)
 		if err != nil {
-			log.Exitf("error downloading blob for digest %v: %v", getDigestFlag(), err)
+			return fmt.Errorf("error downloading blob for digest %v: %v", a.Digest, err)
 		}
-		os.Stdout.Write([]byte(res))
+		out.Write([]byte(res))
 
 	case downloadDir:
-		if err := c.DownloadDirectory(ctx, getDigestFlag(), getPathFlag()); err != nil {
-			log.Exitf("error downloading directory for digest %v: %v", getDigestFlag(), err)
+		if err := c.DownloadDirectory(ctx, a.Digest, a.Path, a.Include, a.Exclude, a.Resume); err != nil {
+			return fmt.Errorf("error downloading directory for digest %v: %v", a.Digest, err)
+		}
+
+	case downloadInputs:
+		root, wd, err := c.DownloadInputs(ctx, a.Digest, a.Path, a.Include, a.Exclude, a.Resume)
+		if err != nil {
+			return fmt.Errorf("error downloading inputs of action %v: %v", a.Digest, err)
+		}
+		fmt.Fprintf(out, "Input root %v of action %v downloaded to %v, working directory %q\n", root, a.Digest, a.Path, wd)
+
+	case downloadStdio:
+		if err := c.DownloadStdErrOut(ctx, a.Digest, a.Path, oe); err != nil {
+			return fmt.Errorf("error downloading stdout/stderr for digest %v: %v", a.Digest, err)
 		}
 
+	case browseTree:
+		stat, err := os.Stdin.Stat()
+		if err != nil {
+			return err
+		}
+		interactive := stat.Mode()&os.ModeCharDevice != 0 && c.Events == nil
+		if err := c.BrowseTree(ctx, a.Digest, os.Stdin, out, interactive); err != nil {
+			return fmt.Errorf("error browsing tree %v: %v", a.Digest, err)
+		}
+
+	case pathLookup:
+		res, err := c.LookupPath(ctx, a.Digest, a.TreePath)
+		if err != nil {
+			return fmt.Errorf("error looking up %v in tree %v: %v", a.TreePath, a.Digest, err)
+		}
+		out.Write([]byte(res))
+
 	case showAction:
-		res, err := c.ShowAction(ctx, getDigestFlag())
+		res, err := c.ShowActionFormat(ctx, a.Digest, tool.ShowFormat(a.Format))
+		if err != nil {
+			return fmt.Errorf("error fetching action %v: %v", a.Digest, err)
+		}
+		if a.InputsDepth != 0 {
+			tree, err := c.ShowInputTree(ctx, a.Digest, a.InputsDepth)
+			if err != nil {
+				return fmt.Errorf("error fetching input tree of action %v: %v", a.Digest, err)
+			}
+			res += "\n" + tree
+		}
+		out.Write([]byte(res))
+
+	case showCapabilities:
+		res, err := c.ShowCapabilities(ctx, tool.ShowFormat(a.Format))
 		if err != nil {
-			log.Exitf("error fetching action %v: %v", getDigestFlag(), err)
+			return fmt.Errorf("error fetching capabilities: %v", err)
 		}
-		os.Stdout.Write([]byte(res))
+		out.Write([]byte(res))
 
 	case downloadAction:
-		err := c.DownloadAction(ctx, getDigestFlag(), getPathFlag())
+		err := c.DownloadAction(ctx, a.Digest, a.Path)
 		if err != nil {
-			log.Exitf("error fetching action %v: %v", getDigestFlag(), err)
+			return fmt.Errorf("error fetching action %v: %v", a.Digest, err)
 		}
-		fmt.Printf("Action downloaded to %v\n", getPathFlag())
+		fmt.Fprintf(out, "Action downloaded to %v\n", a.Path)
+
+	case exportAction:
+		if err := c.ExportAction(ctx, a.Digest, a.Path); err != nil {
+			return fmt.Errorf("error exporting action %v: %v", a.Digest, err)
+		}
+		fmt.Fprintf(out, "Action exported to %v\n", a.Path)
 
 	case executeAction:
-		if _, err := c.ExecuteAction(ctx, *digest, *actionRoot, getPathFlag(), outerr.SystemOutErr); err != nil {
-			log.Exitf("error executing action: %v", err)
+		if a.DryRun {
+			res, err := c.PlanReexecuteAction(ctx, a.Digest, a.ActionRoot, nil)
+			if err != nil {
+				return fmt.Errorf("error planning the execution of the action: %v", err)
+			}
+			out.Write([]byte(res))
+			break
+		}
+		if _, err := c.ExecuteAction(ctx, a.Digest, a.ActionRoot, a.Path, oe); err != nil {
+			return fmt.Errorf("error executing action: %v", err)
 		}
 
+	case executeCommand:
+		if _, err := c.ExecuteCommand(ctx, a.CommandSpec, a.Path, oe); err != nil {
+			return fmt.Errorf("error executing command: %v", err)
+		}
+
+	case reexecuteAction:
+		overrides := &tool.ExecuteOverrides{
+			Platform:  a.Platform,
+			Args:      a.ArgsOverride,
+			ExtraArgs: a.AppendArgs,
+			Env:       a.EnvOverride,
+			UnsetEnv:  a.EnvUnset,
+		}
+		if a.ArgsFile != "" {
+			args, err := readArgsFile(a.ArgsFile)
+			if err != nil {
+				return fmt.Errorf("error reading --args_file: %v", err)
+			}
+			overrides.Args = args
+		}
+		if a.DryRun {
+			res, err := c.PlanReexecuteAction(ctx, a.Digest, a.ActionRoot, overrides)
+			if err != nil {
+				return fmt.Errorf("error planning the re-execution of the action: %v", err)
+			}
+			out.Write([]byte(res))
+			break
+		}
+		if _, err := c.ReexecuteAction(ctx, a.Digest, a.ActionRoot, a.Path, overrides, oe); err != nil {
+			return fmt.Errorf("error re-executing action: %v", err)
+		}
+
+	case localExecute:
+		if err := c.LocalExecute(ctx, a.Digest, oe); err != nil {
+			return fmt.Errorf("error executing action %v locally: %v", a.Digest, err)
+		}
+
+	case prefetchTree:
+		var mu sync.Mutex
+		var last time.Time
+		res, err := c.PrefetchTree(ctx, a.Digest, a.TreeProto, a.Parallel, func(p tool.PrefetchProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if done := p.FetchedBlobs+p.Errors == p.Blobs; done || time.Since(last) >= time.Second {
+				last = time.Now()
+				oe.WriteErr([]byte(fmt.Sprintf("Prefetched %d/%d blobs (%d/%d bytes)\n", p.FetchedBlobs, p.Blobs, p.FetchedBytes, p.Bytes)))
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("error prefetching tree %v: %v", a.Digest, err)
+		}
+		fmt.Fprintf(out, "Prefetched %d blobs (%d bytes) of tree %v into %v\n", res.FetchedBlobs, res.FetchedBytes, a.Digest, c.GrpcClient.LocalCAS.Root())
+
 	case checkDeterminism:
-		if err := c.CheckDeterminism(ctx, *digest, *actionRoot, *execAttempts); err != nil {
-			log.Exitf("error checking determinism: %v", err)
+		if err := c.CheckDeterminism(ctx, a.Digest, a.ActionRoot, a.ExecAttempts, a.Parallel, a.Path, a.Diff, oe); err != nil {
+			return fmt.Errorf("error checking determinism: %v", err)
+		}
+		if a.CompareLocal {
+			if err := c.CompareLocalExecution(ctx, a.Digest, a.ActionRoot, oe); err != nil {
+				return fmt.Errorf("error comparing with a local execution: %v", err)
+			}
+		}
+
+	case checkInputs:
+		res, err := c.CheckInputs(ctx, a.Digest)
+		if err != nil {
+			return fmt.Errorf("error checking inputs of action %v: %v", a.Digest, err)
+		}
+		out.Write([]byte(res))
+
+	case listRefs:
+		res, err := c.ListRefs(ctx, a.Digest)
+		if err != nil {
+			return fmt.Errorf("error listing the digests referenced by action %v: %v", a.Digest, err)
+		}
+		out.Write([]byte(res))
+
+	case dumpInputs:
+		if err := c.DumpInputs(ctx, a.Digest, a.Path, out); err != nil {
+			return fmt.Errorf("error dumping inputs of action %v: %v", a.Digest, err)
+		}
+
+	case checkActionCache:
+		res, err := c.CheckActionCache(ctx, a.DigestsFile, a.Parallel)
+		if err != nil {
+			return fmt.Errorf("error checking the action cache: %v", err)
+		}
+		out.Write([]byte(res))
+
+	case diffActions:
+		res, err := c.DiffActions(ctx, a.Digest, a.OtherDigest)
+		if err != nil {
+			return fmt.Errorf("error comparing actions %v and %v: %v", a.Digest, a.OtherDigest, err)
 		}
+		out.Write([]byte(res))
+
+	case treeDiff:
+		res, err := c.DiffTrees(ctx, a.Digest, a.OtherDigest, a.TreeProto)
+		if err != nil {
+			return fmt.Errorf("error comparing trees %v and %v: %v", a.Digest, a.OtherDigest, err)
+		}
+		out.Write([]byte(res))
 
 	case uploadBlob:
-		if err := c.UploadBlob(ctx, getPathFlag()); err != nil {
-			log.Exitf("error uploading blob for digest %v: %v", getDigestFlag(), err)
+		if a.Path == "-" {
+			dg, err := c.UploadBlobFromReader(ctx, os.Stdin)
+			if err != nil {
+				return fmt.Errorf("error uploading blob from stdin: %v", err)
+			}
+			fmt.Fprintln(out, dg)
+			return nil
+		}
+		if err := c.UploadBlob(ctx, a.Path); err != nil {
+			return fmt.Errorf("error uploading blob for digest %v: %v", a.Digest, err)
 		}
 
 	case uploadBlobV2:
-		if err := c.UploadBlobV2(ctx, getPathFlag()); err != nil {
-			log.Exitf("error uploading blob for digest %v: %v", getDigestFlag(), err)
+		if err := c.UploadBlobV2(ctx, a.Path); err != nil {
+			return fmt.Errorf("error uploading blob for digest %v: %v", a.Digest, err)
 		}
 
-	default:
-		log.Exitf("unsupported operation %v. Supported operations:\n%v", *operation, supportedOps)
+	case uploadAction:
+		spec := &tool.ActionSpec{
+			Args:            a.CmdArgs,
+			Env:             a.Env,
+			Platform:        a.Platform,
+			InputRootDigest: a.InputRootDg,
+			OutputPaths:     a.OutputPaths,
+		}
+		dg, err := c.UploadAction(ctx, spec)
+		if err != nil {
+			return fmt.Errorf("error uploading action: %v", err)
+		}
+		fmt.Fprintln(out, dg)
+
+	case cloneAction:
+		overrides := &tool.ActionOverrides{DoNotCache: a.DoNotCache, Salt: a.Salt}
+		if a.Timeout != "" {
+			d, err := time.ParseDuration(a.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid --timeout %q: %v", a.Timeout, err)
+			}
+			overrides.Timeout = d
+		}
+		dg, err := c.CloneAction(ctx, a.Digest, overrides)
+		if err != nil {
+			return fmt.Errorf("error cloning action %v: %v", a.Digest, err)
+		}
+		fmt.Fprintf(out, "Action %v cloned with digest %v\n", a.Digest, dg)
+		if a.ExecuteClone {
+			if err := c.RunAction(ctx, dg.String(), a.Path, oe); err != nil {
+				return fmt.Errorf("error executing action %v: %v", dg, err)
+			}
+		}
+
+	case uploadActionResult:
+		spec := &tool.ActionResultSpec{
+			OutputRoot:  a.Path,
+			OutputPaths: a.OutputPaths,
+			StdoutFile:  a.StdoutFile,
+			StderrFile:  a.StderrFile,
+			ExitCode:    int32(a.ExitCode),
+		}
+		dg, err := c.UploadActionResult(ctx, a.Digest, spec)
+		if err != nil {
+			return fmt.Errorf("error uploading action result for action %v: %v", a.Digest, err)
+		}
+		fmt.Fprintf(out, "Action result %v written to the action cache for action %v\n", dg, a.Digest)
+
+	case uploadDir:
+		if a.DryRun {
+			res, err := c.PlanUploadDirectory(a.Path)
+			if err != nil {
+				return fmt.Errorf("error planning the upload of directory %v: %v", a.Path, err)
+			}
+			out.Write([]byte(res))
+			break
+		}
+		dg, err := c.UploadDirectory(ctx, a.Path)
+		if err != nil {
+			return fmt.Errorf("error uploading directory %v: %v", a.Path, err)
+		}
+		fmt.Fprintf(out, "Directory uploaded with root digest %v\n", dg)
+
+	case seedCache:
+		spec := &tool.ActionResultSpec{
+			OutputRoot: a.Path,
+			StdoutFile: a.StdoutFile,
+			StderrFile: a.StderrFile,
+			ExitCode:   int32(a.ExitCode),
+		}
+		acDg, resDg, err := c.SeedCache(ctx, a.CommandSpec, spec)
+		if err != nil {
+			return fmt.Errorf("error seeding the action cache for command %v: %v", a.CommandSpec, err)
+		}
+		fmt.Fprintf(out, "Action result %v written to the action cache for action %v\n", resDg, acDg)
+
+	case bench:
+		seed := a.BenchSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		res, err := c.Bench(ctx, &tool.BenchOptions{
+			Shape:       tool.BenchShape(a.BenchShape),
+			Ops:         a.BenchOps,
+			MinSize:     a.BenchMinSize,
+			MaxSize:     a.BenchMaxSize,
+			TreeFiles:   a.BenchFiles,
+			TreeFanOut:  a.BenchFanOut,
+			Concurrency: a.Parallel,
+			Seed:        seed,
+		})
+		if err != nil {
+			return fmt.Errorf("error benchmarking the CAS: %v", err)
+		}
+		fmt.Fprint(out, res)
+		if n := res.Upload.Errors + res.Download.Errors; n > 0 {
+			return fmt.Errorf("%d of the transfers of the benchmark failed", n)
+		}
+
+	case computeRoot:
+		res, err := c.ComputeRoot(a.Path, a.InputSpec)
+		if err != nil {
+			return fmt.Errorf("error computing the root digest of %v: %v", a.Path, err)
+		}
+		out.Write([]byte(res))
+
+	case computeTree:
+		res, err := c.ComputeTrees(ctx, a.InputSpec, a.Upload)
+		if err != nil {
+			return fmt.Errorf("error computing the trees of %v: %v", a.InputSpec, err)
+		}
+		out.Write([]byte(res))
+
+	case verifyDir:
+		ok, res, err := c.VerifyDirectory(ctx, a.Digest, a.Path)
+		if err != nil {
+			return fmt.Errorf("error verifying directory %v: %v", a.Path, err)
+		}
+		out.Write([]byte(res))
+		if !ok {
+			return fmt.Errorf("directory %v does not match tree %v", a.Path, a.Digest)
+		}
+
+	case waitOperation:
+		if err := c.WaitOperation(ctx, a.OperationName, a.Path, oe); err != nil {
+			return fmt.Errorf("error waiting for operation %v: %v", a.OperationName, err)
+		}
 	}
+	return nil
 }
 
-func getDigestFlag() string {
-	if *digest == "" {
-		log.Exitf("--digest must be specified.")
+// validate returns an error if the operation is not supported, or if an argument it requires is
+// missing.
+func (a *opArgs) validate() error {
+	if a.DryRun && a.Operation != executeAction && a.Operation != reexecuteAction && a.Operation != uploadDir {
+		return fmt.Errorf("--dry_run is only supported for %v, %v and %v.", executeAction, reexecuteAction, uploadDir)
 	}
-	return *digest
+	required := map[string]string{}
+	switch a.Operation {
+	case downloadActionResult, downloadDir, downloadInputs, downloadAction, exportAction, verifyDir:
+		required["digest"] = a.Digest
+		required["path"] = a.Path
+	case downloadBlob, downloadStdio, localExecute, checkInputs, dumpInputs, listRefs, browseTree:
+		required["digest"] = a.Digest
+	case pathLookup:
+		required["digest"] = a.Digest
+		required["tree_path"] = a.TreePath
+	case showAction:
+		required["digest"] = a.Digest
+		if a.InputsDepth != 0 && a.Format != "" && a.Format != string(tool.TextFormat) {
+			return fmt.Errorf("--show_inputs_depth is only supported with --format=%v.", tool.TextFormat)
+		}
+	case executeAction:
+		if !a.DryRun {
+			required["path"] = a.Path
+		}
+	case showCapabilities:
+	case executeCommand, seedCache:
+		required["command_spec"] = a.CommandSpec
+	case reexecuteAction:
+		if !a.DryRun {
+			required["path"] = a.Path
+		}
+		if a.ArgsOverride != nil && a.ArgsFile != "" {
+			return fmt.Errorf("at most one of --args_override and --args_file may be specified.")
+		}
+	case checkDeterminism:
+		if a.ExecAttempts <= 0 {
+			return fmt.Errorf("--exec_attempts must be >= 1.")
+		}
+		if a.Parallel <= 0 {
+			return fmt.Errorf("--parallel must be >= 1.")
+		}
+	case prefetchTree:
+		required["digest"] = a.Digest
+		if a.Parallel <= 0 {
+			return fmt.Errorf("--parallel must be >= 1.")
+		}
+	case checkActionCache:
+		required["digests_file"] = a.DigestsFile
+		if a.Parallel <= 0 {
+			return fmt.Errorf("--parallel must be >= 1.")
+		}
+	case bench:
+		if a.Parallel <= 0 {
+			return fmt.Errorf("--parallel must be >= 1.")
+		}
+		if a.BenchOps <= 0 {
+			return fmt.Errorf("--bench_ops must be >= 1.")
+		}
+		if a.BenchMinSize < 0 || a.BenchMaxSize < a.BenchMinSize {
+			return fmt.Errorf("--bench_min_size must be >= 0 and <= --bench_max_size.")
+		}
+	case cloneAction:
+		required["digest"] = a.Digest
+		if a.Timeout != "" {
+			if d, err := time.ParseDuration(a.Timeout); err != nil || d < 0 {
+				return fmt.Errorf("--timeout must be a non-negative duration, got %q.", a.Timeout)
+			}
+		}
+	case diffActions, treeDiff:
+		required["digest"] = a.Digest
+		required["other_digest"] = a.OtherDigest
+	case uploadBlob, uploadBlobV2, uploadDir, computeRoot:
+		required["path"] = a.Path
+	case computeTree:
+		required["input_spec"] = a.InputSpec
+	case uploadAction:
+		if len(a.CmdArgs) == 0 {
+			return fmt.Errorf("--cmd_args must be specified.")
+		}
+	case uploadActionResult:
+		required["digest"] = a.Digest
+		if len(a.OutputPaths) > 0 {
+			required["path"] = a.Path
+		}
+	case waitOperation:
+		required["operation_name"] = a.OperationName
+	default:
+		return fmt.Errorf("unsupported operation %v. Supported operations:\n%v", a.Operation, supportedOps)
+	}
+	for _, name := range []string{"digest", "other_digest", "path", "digests_file", "tree_path", "operation_name", "command_spec", "input_spec"} {
+		if v, ok := required[name]; ok && v == "" {
+			return fmt.Errorf("--%s must be specified.", name)
+		}
+	}
+	return nil
 }
 
-func getPathFlag() string {
-	if *pathPrefix == "" {
-		log.Exitf("--path must be specified.")
+// readArgsFile reads the command arguments in the file at path, one per line.
+func readArgsFile(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(args) == 1 && args[0] == "" {
+		return nil, fmt.Errorf("%v contains no arguments", path)
 	}
-	return *pathPrefix
+	return args, nil
 }
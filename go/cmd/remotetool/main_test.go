@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/moreflag"
+	"github.com/google/go-cmp/cmp"
+)
+
+// resetFlags restores the flags of remotetool set on the command line to their defaults.
+func resetFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "test.") {
+			return
+		}
+		switch v := f.Value.(type) {
+		case envValue:
+			for k := range v {
+				delete(v, k)
+			}
+		case *repeatedValue:
+			*v = nil
+		case *moreflag.StringListValue:
+			*v = nil
+		case *moreflag.StringMapValue:
+			*v = make(map[string]string)
+		default:
+			f.Value.Set(f.DefValue)
+		}
+	})
+}
+
+func TestArgsFromFlags(t *testing.T) {
+	defer resetFlags()
+	// The arguments not set below keep the defaults of their flags.
+	want := argsFromFlags()
+	want.Operation = uploadAction
+	want.Digest = "a/1"
+	want.OtherDigest = "b/2"
+	want.Path = "/tmp/out"
+	want.DigestsFile = "/tmp/digests"
+	want.TreePath = "foo/bar"
+	want.ActionRoot = "/tmp/root"
+	want.OperationName = "op"
+	want.ArgsFile = "/tmp/args"
+	want.InputRootDg = "c/3"
+	want.StdoutFile = "/tmp/stdout"
+	want.StderrFile = "/tmp/stderr"
+	want.Platform = map[string]string{"pool": "large"}
+	want.ArgsOverride = []string{"cc", "-c"}
+	want.AppendArgs = []string{"-v"}
+	want.EnvOverride = map[string]string{"OVERRIDE": "1"}
+	want.EnvUnset = []string{"UNSET"}
+	want.CmdArgs = []string{"gcc", "-o", "out"}
+	want.Env = map[string]string{"CMD": "2"}
+	want.OutputPaths = []string{"out"}
+	want.Include = []string{"*.h"}
+	want.Exclude = []string{"*.o"}
+
+	args := []string{
+		"--operation=upload_action",
+		"--digest=a/1",
+		"--other_digest=b/2",
+		"--path=/tmp/out",
+		"--digests_file=/tmp/digests",
+		"--tree_path=foo/bar",
+		"--action_root=/tmp/root",
+		"--operation_name=op",
+		"--args_file=/tmp/args",
+		"--input_root_digest=c/3",
+		"--stdout_file=/tmp/stdout",
+		"--stderr_file=/tmp/stderr",
+		"--platform=pool=large",
+		"--args_override=cc,-c",
+		"--append_args=-v",
+		"--env_override=OVERRIDE=1",
+		"--env_unset=UNSET",
+		"--cmd_args=gcc,-o,out",
+		"--env=CMD=2",
+		"--output_paths=out",
+		"--include=*.h",
+		"--exclude=*.o",
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) failed: %v", args, err)
+	}
+	if diff := cmp.Diff(want, argsFromFlags()); diff != "" {
+		t.Errorf("argsFromFlags() returned diff (-want +got):\n%s", diff)
+	}
+}
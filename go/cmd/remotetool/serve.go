@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/tool"
+
+	log "github.com/golang/glog"
+)
+
+// refusedOps are the operations which are not served, since they would let any local process
+// able to reach the server run or upload local files, write whole trees to local directories, or
+// wait on the standard input of the server.
+var refusedOps = map[OpType]bool{
+	localExecute:       true,
+	uploadBlob:         true,
+	uploadBlobV2:       true,
+	uploadAction:       true,
+	uploadActionResult: true,
+	uploadDir:          true,
+	downloadDir:        true,
+	browseTree:         true,
+}
+
+// serve performs the operations requested over HTTP on addr until interrupted, over the client's
+// connection. addr is either unix:<path> for a unix socket, or host:port with a loopback host,
+// e.g. localhost:8080. On a port, requests must carry the token generated for the run, which is
+// printed on stderr, in an Authorization: Bearer <token> header.
+//
+// An operation is requested by POSTing its arguments as a JSON object, in the format of the lines
+// of --operations_file, to any path, with the application/json content type. The response is its
+// result event, with what the operation prints in output and stderr, or its error. The operations
+// of refusedOps are refused.
+func serve(ctx context.Context, c *tool.Client, addr string) error {
+	network := "tcp"
+	token := ""
+	if strings.HasPrefix(addr, "unix:") {
+		network = "unix"
+		addr = strings.TrimPrefix(strings.TrimPrefix(addr, "unix:"), "//")
+		// A stale socket left by a previous run would make Listen fail.
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		if err := checkLoopback(addr); err != nil {
+			return err
+		}
+		var err error
+		if token, err = newToken(); err != nil {
+			return err
+		}
+	}
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		fmt.Fprintf(os.Stderr, "Serving operations on %v, authorize requests with the header Authorization: Bearer %v\n", lis.Addr(), token)
+	}
+	srv := &http.Server{Handler: &opHandler{ctx: ctx, c: c, token: token}}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.Infof("Received %v, shutting down.", sig)
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Errorf("error shutting down: %v", err)
+		}
+	}()
+	log.Infof("Serving operations on %v %v.", network, lis.Addr())
+	if err := srv.Serve(lis); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// checkLoopback returns an error if addr, of the form host:port, is not on a loopback interface.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("--serve must be a unix socket or a loopback address, e.g. localhost:8080, got %v", addr)
+	}
+	return nil
+}
+
+// newToken returns a random token authorizing the requests of a run.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// opHandler performs the operations requested over HTTP.
+type opHandler struct {
+	ctx context.Context
+	c   *tool.Client
+	// token, if set, must be given as the bearer token of every request.
+	token string
+}
+
+func (h *opHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeResponse(w, http.StatusMethodNotAllowed, &tool.Event{Type: tool.ErrorEvent, Error: "operations must be requested with POST"})
+		return
+	}
+	if h.token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+			writeResponse(w, http.StatusUnauthorized, &tool.Event{Type: tool.ErrorEvent, Error: "missing or invalid bearer token"})
+			return
+		}
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		writeResponse(w, http.StatusUnsupportedMediaType, &tool.Event{Type: tool.ErrorEvent, Error: "operations must be requested with the application/json content type"})
+		return
+	}
+	args := argsFromFlags()
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(args); err != nil {
+		writeResponse(w, http.StatusBadRequest, &tool.Event{Type: tool.ErrorEvent, Error: fmt.Sprintf("invalid operation: %v", err)})
+		return
+	}
+	if refusedOps[args.Operation] {
+		writeResponse(w, http.StatusForbidden, &tool.Event{Type: tool.ErrorEvent, Operation: string(args.Operation), Error: fmt.Sprintf("operation %v is not served", args.Operation)})
+		return
+	}
+	log.Infof("%v: performing %v", r.RemoteAddr, args.Operation)
+	// Operations are not cancelled with their request, e.g. downloads are completed even if the
+	// client goes away.
+	ev, _ := captureOp(h.ctx, h.c, args, r.RemoteAddr)
+	writeResponse(w, http.StatusOK, ev)
+}
+
+func writeResponse(w http.ResponseWriter, code int, ev *tool.Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(ev); err != nil {
+		log.Errorf("error writing response: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/tool"
+)
+
+func TestOpHandler(t *testing.T) {
+	const token = "secret"
+	tests := []struct {
+		name        string
+		method      string
+		auth        string
+		contentType string
+		body        string
+		wantCode    int
+		wantErr     string
+	}{
+		{
+			name:     "not a post",
+			method:   http.MethodGet,
+			auth:     "Bearer " + token,
+			wantCode: http.StatusMethodNotAllowed,
+			wantErr:  "POST",
+		},
+		{
+			name:        "missing token",
+			contentType: "application/json",
+			body:        `{"operation": "show_action", "digest": "a/1"}`,
+			wantCode:    http.StatusUnauthorized,
+			wantErr:     "bearer token",
+		},
+		{
+			name:        "wrong token",
+			auth:        "Bearer other",
+			contentType: "application/json",
+			body:        `{"operation": "show_action", "digest": "a/1"}`,
+			wantCode:    http.StatusUnauthorized,
+			wantErr:     "bearer token",
+		},
+		{
+			name:     "no content type",
+			auth:     "Bearer " + token,
+			body:     `{"operation": "show_action", "digest": "a/1"}`,
+			wantCode: http.StatusUnsupportedMediaType,
+			wantErr:  "application/json",
+		},
+		{
+			name:        "form",
+			auth:        "Bearer " + token,
+			contentType: "application/x-www-form-urlencoded",
+			body:        "operation=show_action",
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantErr:     "application/json",
+		},
+		{
+			name:        "invalid json",
+			auth:        "Bearer " + token,
+			contentType: "application/json",
+			body:        `{"operation": "show_action", "digets": "a/1"}`,
+			wantCode:    http.StatusBadRequest,
+			wantErr:     "invalid operation",
+		},
+		{
+			name:        "local execute",
+			auth:        "Bearer " + token,
+			contentType: "application/json",
+			body:        `{"operation": "local_execute", "digest": "a/1"}`,
+			wantCode:    http.StatusForbidden,
+			wantErr:     "operation local_execute is not served",
+		},
+		{
+			name:        "upload",
+			auth:        "Bearer " + token,
+			contentType: "application/json",
+			body:        `{"operation": "upload_dir", "path": "/"}`,
+			wantCode:    http.StatusForbidden,
+			wantErr:     "operation upload_dir is not served",
+		},
+		{
+			name:        "download dir",
+			auth:        "Bearer " + token,
+			contentType: "application/json",
+			body:        `{"operation": "download_dir", "digest": "a/1", "path": "/tmp/a"}`,
+			wantCode:    http.StatusForbidden,
+			wantErr:     "operation download_dir is not served",
+		},
+		{
+			name:        "browse tree",
+			auth:        "Bearer " + token,
+			contentType: "application/json",
+			body:        `{"operation": "browse_tree", "digest": "a/1"}`,
+			wantCode:    http.StatusForbidden,
+			wantErr:     "operation browse_tree is not served",
+		},
+		{
+			name:        "performed",
+			auth:        "Bearer " + token,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"operation": "show_action"}`,
+			wantCode:    http.StatusOK,
+			wantErr:     "--digest must be specified",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/", strings.NewReader(tc.body))
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h := &opHandler{ctx: context.Background(), c: &tool.Client{}, token: token}
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantCode {
+				t.Errorf("ServeHTTP() returned code %d, want %d", rec.Code, tc.wantCode)
+			}
+			ev := &tool.Event{}
+			if err := json.Unmarshal(rec.Body.Bytes(), ev); err != nil {
+				t.Fatalf("ServeHTTP() returned invalid event %q: %v", rec.Body.String(), err)
+			}
+			if ev.Type != tool.ErrorEvent || !strings.Contains(ev.Error, tc.wantErr) {
+				t.Errorf("ServeHTTP() returned event %+v, want an error containing %q", ev, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpHandlerUnixSocket(t *testing.T) {
+	// Servers on unix sockets have no token.
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"operation": "show_action"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h := &opHandler{ctx: context.Background(), c: &tool.Client{}}
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() returned code %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "localhost:8080"},
+		{addr: "127.0.0.1:8080"},
+		{addr: "127.0.0.2:0"},
+		{addr: "[::1]:8080"},
+		{addr: ":8080", wantErr: true},
+		{addr: "0.0.0.0:8080", wantErr: true},
+		{addr: "[::]:8080", wantErr: true},
+		{addr: "10.0.0.1:8080", wantErr: true},
+		{addr: "example.com:8080", wantErr: true},
+		{addr: "localhost", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			if err := checkLoopback(tc.addr); (err != nil) != tc.wantErr {
+				t.Errorf("checkLoopback(%v) = %v, want error: %v", tc.addr, err, tc.wantErr)
+			}
+		})
+	}
+}
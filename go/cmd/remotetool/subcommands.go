@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// completionShells are the shells for which the completion subcommand generates completions.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// subcommandName returns the name of the subcommand performing op, e.g. download-blob.
+func subcommandName(op OpType) string {
+	return strings.ReplaceAll(string(op), "_", "-")
+}
+
+// subcommandNames returns the names of the subcommands, in the order of supportedOps.
+func subcommandNames() []string {
+	names := make([]string, 0, len(supportedOps))
+	for _, op := range supportedOps {
+		names = append(names, subcommandName(op))
+	}
+	return names
+}
+
+// opForSubcommand returns the operation performed by the subcommand with the given name, in which
+// underscores may be used instead of dashes.
+func opForSubcommand(name string) (OpType, bool) {
+	for _, op := range supportedOps {
+		if subcommandName(op) == strings.ReplaceAll(name, "_", "-") {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// parseCommandLine parses the command line arguments args, in either of the forms
+//   remotetool <subcommand> [flags], e.g. remotetool download-blob --digest=...
+//   remotetool [flags] --operation=<op>, e.g. remotetool --operation=download_blob --digest=...
+// The subcommand sets --operation. The completion <shell> and help subcommands write the
+// completions of remotetool for the shell and the usage, respectively, to out, and return done,
+// after which remotetool should exit.
+func parseCommandLine(args []string, out io.Writer) (done bool, err error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		flag.CommandLine.Parse(args)
+		return false, checkNoArgs()
+	}
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "help":
+		flag.CommandLine.SetOutput(out)
+		flag.Usage()
+		return true, nil
+	case "completion":
+		if len(rest) != 1 {
+			return false, fmt.Errorf("usage: %v completion <shell>, with shell one of %v", binaryName(), completionShells)
+		}
+		if err := writeCompletion(out, rest[0]); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	op, ok := opForSubcommand(cmd)
+	if !ok {
+		return false, fmt.Errorf("unknown subcommand %q, supported subcommands: %v", cmd, strings.Join(subcommandNames(), ", "))
+	}
+	flag.CommandLine.Parse(rest)
+	if *operation != "" && OpType(*operation) != op {
+		return false, fmt.Errorf("subcommand %v conflicts with --operation=%v", cmd, *operation)
+	}
+	flag.Set("operation", string(op))
+	return false, checkNoArgs()
+}
+
+func checkNoArgs() error {
+	if flag.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v, arguments must be passed as flags", flag.Args())
+	}
+	return nil
+}
+
+// completionFlags returns the names of the flags with their leading dashes, sorted.
+func completionFlags() []string {
+	var res []string
+	flag.VisitAll(func(f *flag.Flag) {
+		res = append(res, "--"+f.Name)
+	})
+	sort.Strings(res)
+	return res
+}
+
+// writeCompletion writes the script completing the subcommands and the flags of remotetool in the
+// given shell to w, e.g. to be loaded with source <(remotetool completion bash).
+func writeCompletion(w io.Writer, shell string) error {
+	name := binaryName()
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	subcommands := strings.Join(append(subcommandNames(), "completion", "help"), " ")
+	flags := strings.Join(completionFlags(), " ")
+	switch shell {
+	case "bash":
+		fmt.Fprintf(w, `%[1]s() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+		COMPREPLY=($(compgen -W "%[3]s" -- "$cur"))
+	elif [[ ${COMP_WORDS[1]} == completion && $COMP_CWORD -eq 2 ]]; then
+		COMPREPLY=($(compgen -W "%[5]s" -- "$cur"))
+	else
+		COMPREPLY=($(compgen -W "%[4]s" -- "$cur"))
+	fi
+}
+complete -o default -F %[1]s %[2]s
+`, fn, name, subcommands, flags, strings.Join(completionShells, " "))
+	case "zsh":
+		fmt.Fprintf(w, `#compdef %[2]s
+%[1]s() {
+	if (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then
+		compadd -- %[3]s
+	elif (( CURRENT == 3 )) && [[ $words[2] == completion ]]; then
+		compadd -- %[5]s
+	else
+		compadd -- %[4]s
+		_files
+	fi
+}
+compdef %[1]s %[2]s
+`, fn, name, subcommands, flags, strings.Join(completionShells, " "))
+	case "fish":
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -f -a '%s'\n", name, subcommands)
+		fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from completion' -f -a '%s'\n", name, strings.Join(completionShells, " "))
+		for _, f := range completionFlags() {
+			fmt.Fprintf(w, "complete -c %s -l %s\n", name, strings.TrimPrefix(f, "--"))
+		}
+	default:
+		return fmt.Errorf("unsupported shell %q, supported shells: %v", shell, completionShells)
+	}
+	return nil
+}
+
+// binaryName returns the name remotetool is run as.
+func binaryName() string {
+	return path.Base(os.Args[0])
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOp     OpType
+		wantDigest string
+		wantErr    string
+	}{
+		{
+			name:       "subcommand",
+			args:       []string{"download-blob", "--digest=a/1"},
+			wantOp:     downloadBlob,
+			wantDigest: "a/1",
+		},
+		{
+			name:   "subcommand with underscores",
+			args:   []string{"download_action_result"},
+			wantOp: downloadActionResult,
+		},
+		{
+			name:       "operation flag",
+			args:       []string{"--operation=show_action", "--digest=a/1"},
+			wantOp:     showAction,
+			wantDigest: "a/1",
+		},
+		{
+			name: "no arguments",
+		},
+		{
+			name:   "subcommand agreeing with operation flag",
+			args:   []string{"show-action", "--operation=show_action"},
+			wantOp: showAction,
+		},
+		{
+			name:    "subcommand conflicting with operation flag",
+			args:    []string{"show-action", "--operation=download_blob"},
+			wantErr: "subcommand show-action conflicts with --operation=download_blob",
+		},
+		{
+			name:    "unknown subcommand",
+			args:    []string{"download-everything"},
+			wantErr: `unknown subcommand "download-everything"`,
+		},
+		{
+			name:    "arguments after subcommand",
+			args:    []string{"download-blob", "a/1"},
+			wantErr: "unexpected arguments [a/1]",
+		},
+		{
+			name:    "arguments after flags",
+			args:    []string{"--operation=download_blob", "a/1"},
+			wantErr: "unexpected arguments [a/1]",
+		},
+		{
+			name:    "completion without shell",
+			args:    []string{"completion"},
+			wantErr: "completion <shell>",
+		},
+		{
+			name:    "completion of unsupported shell",
+			args:    []string{"completion", "tcsh"},
+			wantErr: `unsupported shell "tcsh"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer resetFlags()
+			out := &bytes.Buffer{}
+			done, err := parseCommandLine(tc.args, out)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("parseCommandLine(%v) = %v, want an error containing %q", tc.args, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCommandLine(%v) failed: %v", tc.args, err)
+			}
+			if done {
+				t.Errorf("parseCommandLine(%v) returned done, want the operation to be performed", tc.args)
+			}
+			if OpType(*operation) != tc.wantOp || *digest != tc.wantDigest {
+				t.Errorf("parseCommandLine(%v) set --operation=%v --digest=%v, want --operation=%v --digest=%v", tc.args, *operation, *digest, tc.wantOp, tc.wantDigest)
+			}
+			if out.Len() != 0 {
+				t.Errorf("parseCommandLine(%v) printed %q, want nothing", tc.args, out.String())
+			}
+		})
+	}
+}
+
+func TestParseCommandLineHelp(t *testing.T) {
+	defer flag.CommandLine.SetOutput(nil)
+	out := &bytes.Buffer{}
+	done, err := parseCommandLine([]string{"help"}, out)
+	if err != nil || !done {
+		t.Fatalf("parseCommandLine([help]) = %v, %v, want true, nil", done, err)
+	}
+	if !strings.Contains(out.String(), "-digest") {
+		t.Errorf("parseCommandLine([help]) printed %q, want the usage of the flags", out.String())
+	}
+}
+
+func TestParseCommandLineCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			done, err := parseCommandLine([]string{"completion", shell}, out)
+			if err != nil || !done {
+				t.Fatalf("parseCommandLine([completion %v]) = %v, %v, want true, nil", shell, done, err)
+			}
+			want := &bytes.Buffer{}
+			if err := writeCompletion(want, shell); err != nil {
+				t.Fatalf("writeCompletion(%v) failed: %v", shell, err)
+			}
+			if out.String() != want.String() {
+				t.Errorf("parseCommandLine([completion %v]) printed %q, want %q", shell, out.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	// The name of the test binary, which completions are generated for.
+	name := binaryName()
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{
+			shell: "bash",
+			want: []string{
+				fn + "() {",
+				`COMPREPLY=($(compgen -W "download-action-result show-action `,
+				` completion help" -- "$cur"))`,
+				`COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))`,
+				" --digest ",
+				"complete -o default -F " + fn + " " + name + "\n",
+			},
+		},
+		{
+			shell: "zsh",
+			want: []string{
+				"#compdef " + name + "\n",
+				"compadd -- download-action-result show-action ",
+				"compadd -- bash zsh fish\n",
+				" --digest ",
+				"compdef " + fn + " " + name + "\n",
+			},
+		},
+		{
+			shell: "fish",
+			want: []string{
+				"complete -c " + name + " -n __fish_use_subcommand -f -a 'download-action-result show-action ",
+				" completion help'\n",
+				"complete -c " + name + " -n '__fish_seen_subcommand_from completion' -f -a 'bash zsh fish'\n",
+				"complete -c " + name + " -l digest\n",
+				"complete -c " + name + " -l operation\n",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.shell, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := writeCompletion(out, tc.shell); err != nil {
+				t.Fatalf("writeCompletion(%v) failed: %v", tc.shell, err)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("writeCompletion(%v) = %q, want it to contain %q", tc.shell, out.String(), w)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteCompletionUnsupportedShell(t *testing.T) {
+	if err := writeCompletion(ioutil.Discard, "tcsh"); err == nil {
+		t.Errorf("writeCompletion(tcsh) succeeded, want an error")
+	}
+}
@@ -0,0 +1,79 @@
+// Main package for the rpclog binary, which queries RPC logs written by the rpclog package.
+//
+// Example (list the failed ByteStream RPCs referencing a digest):
+//
+//	bazelisk run //go/cmd/rpclog -- \
+//		--log_path=/tmp/rpc.log \
+//		--method=ByteStream \
+//		--errors_only \
+//		--digest=52a54724e6b3dff3bc44ef5dceb3aab5892f2fc7e37fce5aa6e16a7a266fbed6/147
+//
+// With --stats, per-method aggregates of the matching records are printed instead.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/rpclog"
+
+	log "github.com/golang/glog"
+)
+
+var (
+	logPath     = flag.String("log_path", "", "Path of the RPC log to read.")
+	method      = flag.String("method", "", "Only show RPCs whose method contains this string.")
+	errorsOnly  = flag.Bool("errors_only", false, "Only show failed RPCs.")
+	minDuration = flag.Duration("min_duration", 0, "Only show RPCs that took at least this long.")
+	dg          = flag.String("digest", "", "Only show RPCs referencing this digest, in <digest/size_bytes> format.")
+	stats       = flag.Bool("stats", false, "Print per-method statistics instead of the individual RPCs.")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v --log_path <path> [-flags]\n", path.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *logPath == "" {
+		log.Exitf("--log_path must be specified.")
+	}
+	q := &rpclog.Query{Method: *method, ErrorsOnly: *errorsOnly, MinDuration: *minDuration}
+	if *dg != "" {
+		d, err := digest.NewFromString(*dg)
+		if err != nil {
+			log.Exitf("Invalid --digest: %v", err)
+		}
+		q.Digest = d
+	}
+
+	recs, err := rpclog.ReadFile(*logPath)
+	if err != nil {
+		log.Exitf("Failed to read %v: %v", *logPath, err)
+	}
+	var matching []*rpclog.Record
+	for _, r := range recs {
+		if q.Match(r) {
+			matching = append(matching, r)
+		}
+	}
+
+	if !*stats {
+		for _, r := range matching {
+			fmt.Println(r)
+		}
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tCOUNT\tERRORS\tTOTAL\tAVG\tMAX\tSENT\tRECEIVED")
+	for _, s := range rpclog.Stats(matching) {
+		avg := s.Total / time.Duration(s.Count)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%d\t%d\n", s.Method, s.Count, s.Errors, s.Total, avg, s.Max, s.RequestBytes, s.ResponseBytes)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,372 @@
+// Package asset implements a client for the Remote Asset API, which resolves URIs and qualifiers
+// to blobs and directory trees in the Content Addressable Storage.
+//
+// Fetched content can optionally be verified against the CAS, using a cas.Client.
+package asset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cas"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/retry"
+	rapb "github.com/bazelbuild/remote-apis/build/bazel/remote/asset/v1"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	dpb "github.com/golang/protobuf/ptypes/duration"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+// ErrChecksumMismatch is returned when fetched content does not match its checksum.sri qualifier.
+var ErrChecksumMismatch = errors.New("fetched content does not match checksum.sri")
+
+// deadlineMargin is added to the server-side timeout of a fetch to compute the RPC deadline, so
+// that the server has a chance to report its own timeout before the RPC is cancelled.
+const deadlineMargin = 10 * time.Second
+
+// Client is a client for the Remote Asset API.
+// Create one using NewClient.
+//
+// Goroutine-safe.
+//
+// All fields are considered immutable, and should not be changed.
+type Client struct {
+	// InstanceName is the full name of the RBE instance.
+	InstanceName string
+
+	// Config is the configuration that the client was created with.
+	Config ClientConfig
+
+	fetch rapb.FetchClient
+	push  rapb.PushClient
+	cas   *cas.Client
+}
+
+// ClientConfig is a config for Client.
+// See DefaultClientConfig() for the default values.
+type ClientConfig struct {
+	// FetchTimeout is the maximum duration of Fetch RPCs. It is extended for requests that give the
+	// server a longer FetchRequest.Timeout.
+	FetchTimeout time.Duration
+
+	// PushTimeout is the maximum duration of Push RPCs.
+	PushTimeout time.Duration
+
+	// RetryPolicy specifies how to retry requests on transient errors.
+	RetryPolicy retry.BackoffPolicy
+
+	// VerifyFetched specifies whether fetched content is verified after a fetch: the blob or root
+	// directory must be present in the CAS, and blobs must match the checksum.sri qualifier of the
+	// request, if any. Requires a CAS client.
+	VerifyFetched bool
+}
+
+// DefaultClientConfig returns the default config.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		FetchTimeout: 5 * time.Minute,
+		PushTimeout:  time.Minute,
+		RetryPolicy:  retry.ExponentialBackoff(225*time.Millisecond, 2*time.Second, retry.Attempts(6)),
+	}
+}
+
+// Validate returns a non-nil error if the config is invalid.
+func (c *ClientConfig) Validate() error {
+	switch {
+	case c.FetchTimeout <= 0:
+		return fmt.Errorf("FetchTimeout must be positive")
+	case c.PushTimeout <= 0:
+		return fmt.Errorf("PushTimeout must be positive")
+	default:
+		return nil
+	}
+}
+
+// NewClient creates a new client with the default configuration.
+// casClient is only used to verify fetched content, and may be nil if verification is disabled.
+func NewClient(conn *grpc.ClientConn, instanceName string, casClient *cas.Client) (*Client, error) {
+	return NewClientWithConfig(conn, instanceName, casClient, DefaultClientConfig())
+}
+
+// NewClientWithConfig creates a new client and accepts a configuration.
+func NewClientWithConfig(conn *grpc.ClientConn, instanceName string, casClient *cas.Client, config ClientConfig) (*Client, error) {
+	switch err := config.Validate(); {
+	case err != nil:
+		return nil, errors.Wrap(err, "invalid config")
+	case conn == nil:
+		return nil, fmt.Errorf("conn is unspecified")
+	case instanceName == "":
+		return nil, fmt.Errorf("instance name is unspecified")
+	case config.VerifyFetched && casClient == nil:
+		return nil, fmt.Errorf("VerifyFetched requires a CAS client")
+	}
+	return &Client{
+		InstanceName: instanceName,
+		Config:       config,
+		fetch:        rapb.NewFetchClient(conn),
+		push:         rapb.NewPushClient(conn),
+		cas:          casClient,
+	}, nil
+}
+
+// FetchRequest describes the asset to fetch.
+type FetchRequest struct {
+	// URIs are the URIs of the asset, in order of preference. At least one is required.
+	URIs []string
+
+	// Qualifiers further identify the asset, see e.g. VCSBranch and ChecksumSRI.
+	Qualifiers []*rapb.Qualifier
+
+	// Timeout is how long the server may spend fetching the asset from its origin. Zero lets the
+	// server decide.
+	Timeout time.Duration
+
+	// OldestContentAccepted is the oldest cached content the server may return; content cached
+	// earlier must be refetched. Zero accepts any cached content.
+	OldestContentAccepted time.Time
+}
+
+// FetchResult is the asset returned by a fetch.
+type FetchResult struct {
+	// URI is the URI the asset was resolved from.
+	URI string
+
+	// Qualifiers are the qualifiers the server took into account.
+	Qualifiers []*rapb.Qualifier
+
+	// ExpiresAt is when the server may stop serving the asset. Zero means unknown.
+	ExpiresAt time.Time
+
+	// Digest is the digest of the blob, or of the root Directory for directories.
+	Digest digest.Digest
+}
+
+// FetchBlob resolves the request to a blob in the CAS.
+// Statuses returned by the server, e.g. NOT_FOUND, are returned as gRPC status errors.
+func (c *Client) FetchBlob(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	rreq := &rapb.FetchBlobRequest{InstanceName: c.InstanceName, Uris: req.URIs, Qualifiers: req.Qualifiers}
+	var err error
+	if rreq.Timeout, rreq.OldestContentAccepted, err = req.protoTimes(); err != nil {
+		return nil, err
+	}
+	var resp *rapb.FetchBlobResponse
+	err = c.unaryRPC(ctx, c.fetchTimeout(req), func(ctx context.Context) (err error) {
+		resp, err = c.fetch.FetchBlob(ctx, rreq)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := newFetchResult(resp.Status, resp.Uri, resp.Qualifiers, resp.ExpiresAt, resp.BlobDigest)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config.VerifyFetched {
+		if err := c.verifyBlob(ctx, req, res.Digest); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// FetchDirectory resolves the request to a directory tree in the CAS.
+// Statuses returned by the server, e.g. NOT_FOUND, are returned as gRPC status errors.
+func (c *Client) FetchDirectory(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	rreq := &rapb.FetchDirectoryRequest{InstanceName: c.InstanceName, Uris: req.URIs, Qualifiers: req.Qualifiers}
+	var err error
+	if rreq.Timeout, rreq.OldestContentAccepted, err = req.protoTimes(); err != nil {
+		return nil, err
+	}
+	var resp *rapb.FetchDirectoryResponse
+	err = c.unaryRPC(ctx, c.fetchTimeout(req), func(ctx context.Context) (err error) {
+		resp, err = c.fetch.FetchDirectory(ctx, rreq)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := newFetchResult(resp.Status, resp.Uri, resp.Qualifiers, resp.ExpiresAt, resp.RootDirectoryDigest)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config.VerifyFetched {
+		if err := c.verifyPresent(ctx, res.Digest); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// PushRequest describes an asset to associate with content already in the CAS.
+type PushRequest struct {
+	// URIs are the URIs of the asset. At least one is required.
+	URIs []string
+
+	// Qualifiers further identify the asset.
+	Qualifiers []*rapb.Qualifier
+
+	// ExpireAt is when the server may stop serving the asset. Zero means no expiration.
+	ExpireAt time.Time
+
+	// ReferencesBlobs are blobs the asset depends on, which the server should keep alive with it.
+	ReferencesBlobs []digest.Digest
+
+	// ReferencesDirectories are directory trees the asset depends on, which the server should keep
+	// alive with it.
+	ReferencesDirectories []digest.Digest
+}
+
+// PushBlob associates the request's URIs and qualifiers with the blob d.
+func (c *Client) PushBlob(ctx context.Context, req *PushRequest, d digest.Digest) error {
+	rreq := &rapb.PushBlobRequest{
+		InstanceName:          c.InstanceName,
+		Uris:                  req.URIs,
+		Qualifiers:            req.Qualifiers,
+		BlobDigest:            d.ToProto(),
+		ReferencesBlobs:       toProtos(req.ReferencesBlobs),
+		ReferencesDirectories: toProtos(req.ReferencesDirectories),
+	}
+	var err error
+	if rreq.ExpireAt, err = req.protoExpireAt(); err != nil {
+		return err
+	}
+	return c.unaryRPC(ctx, c.Config.PushTimeout, func(ctx context.Context) error {
+		_, err := c.push.PushBlob(ctx, rreq)
+		return err
+	})
+}
+
+// PushDirectory associates the request's URIs and qualifiers with the directory tree whose root
+// Directory has the digest root.
+func (c *Client) PushDirectory(ctx context.Context, req *PushRequest, root digest.Digest) error {
+	rreq := &rapb.PushDirectoryRequest{
+		InstanceName:          c.InstanceName,
+		Uris:                  req.URIs,
+		Qualifiers:            req.Qualifiers,
+		RootDirectoryDigest:   root.ToProto(),
+		ReferencesBlobs:       toProtos(req.ReferencesBlobs),
+		ReferencesDirectories: toProtos(req.ReferencesDirectories),
+	}
+	var err error
+	if rreq.ExpireAt, err = req.protoExpireAt(); err != nil {
+		return err
+	}
+	return c.unaryRPC(ctx, c.Config.PushTimeout, func(ctx context.Context) error {
+		_, err := c.push.PushDirectory(ctx, rreq)
+		return err
+	})
+}
+
+// unaryRPC calls f with retries, and with per-RPC timeouts.
+func (c *Client) unaryRPC(ctx context.Context, timeout time.Duration, f func(context.Context) error) error {
+	return retry.WithPolicy(ctx, retry.TransientOnly, c.Config.RetryPolicy, func() error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx)
+	})
+}
+
+// fetchTimeout returns the RPC timeout of a fetch, which leaves the server enough time to honor
+// the timeout of the request.
+func (c *Client) fetchTimeout(req *FetchRequest) time.Duration {
+	if t := req.Timeout + deadlineMargin; req.Timeout > 0 && t > c.Config.FetchTimeout {
+		return t
+	}
+	return c.Config.FetchTimeout
+}
+
+// verifyBlob checks that the fetched blob is in the CAS and, if the request has a checksum.sri
+// qualifier, that its contents match it.
+func (c *Client) verifyBlob(ctx context.Context, req *FetchRequest, d digest.Digest) error {
+	v, ok := findQualifier(req.Qualifiers, QualifierChecksumSRI)
+	if !ok {
+		return c.verifyPresent(ctx, d)
+	}
+	sris, err := ParseSRI(v)
+	if err != nil {
+		return err
+	}
+	newHash, want := strongestSRI(sris)
+	h := newHash()
+	if err := c.cas.ReadBlob(ctx, d, h); err != nil {
+		return errors.Wrapf(err, "reading fetched blob %v", d)
+	}
+	got := h.Sum(nil)
+	for _, s := range want {
+		if bytes.Equal(s.Sum, got) {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrChecksumMismatch, "blob %v has checksum %v", d, SRI{Algorithm: want[0].Algorithm, Sum: got})
+}
+
+// verifyPresent checks that d is in the CAS.
+func (c *Client) verifyPresent(ctx context.Context, d digest.Digest) error {
+	missing, err := c.cas.MissingBlobs(ctx, []digest.Digest{d})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("fetched content %v is missing from the CAS", d)
+	}
+	return nil
+}
+
+func (req *FetchRequest) protoTimes() (timeout *dpb.Duration, oldest *tspb.Timestamp, err error) {
+	if req.Timeout > 0 {
+		timeout = ptypes.DurationProto(req.Timeout)
+	}
+	if !req.OldestContentAccepted.IsZero() {
+		if oldest, err = ptypes.TimestampProto(req.OldestContentAccepted); err != nil {
+			return nil, nil, errors.Wrap(err, "OldestContentAccepted")
+		}
+	}
+	return timeout, oldest, nil
+}
+
+func (req *PushRequest) protoExpireAt() (*tspb.Timestamp, error) {
+	if req.ExpireAt.IsZero() {
+		return nil, nil
+	}
+	ts, err := ptypes.TimestampProto(req.ExpireAt)
+	return ts, errors.Wrap(err, "ExpireAt")
+}
+
+// newFetchResult converts the fields of a fetch response to a FetchResult, or to an error if the
+// response status is not OK.
+func newFetchResult(st *spb.Status, uri string, qs []*rapb.Qualifier, expiresAt *tspb.Timestamp, dg *repb.Digest) (*FetchResult, error) {
+	if err := status.ErrorProto(st); err != nil {
+		return nil, err
+	}
+	d, err := digest.NewFromProto(dg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "server returned an invalid digest: %v", err)
+	}
+	res := &FetchResult{URI: uri, Qualifiers: qs, Digest: d}
+	if expiresAt != nil {
+		if res.ExpiresAt, err = ptypes.Timestamp(expiresAt); err != nil {
+			return nil, errors.Wrap(err, "invalid expires_at")
+		}
+	}
+	return res, nil
+}
+
+func toProtos(dgs []digest.Digest) []*repb.Digest {
+	if len(dgs) == 0 {
+		return nil
+	}
+	res := make([]*repb.Digest, len(dgs))
+	for i, d := range dgs {
+		res[i] = d.ToProto()
+	}
+	return res
+}
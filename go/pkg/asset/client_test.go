@@ -0,0 +1,286 @@
+package asset
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	bsgrpc "google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cas"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	rapb "github.com/bazelbuild/remote-apis/build/bazel/remote/asset/v1"
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// fakeAsset is a fake Remote Asset server that serves the assets pushed to it.
+type fakeAsset struct {
+	rapb.UnimplementedFetchServer
+	rapb.UnimplementedPushServer
+	blobs    map[string]*rapb.PushBlobRequest
+	dirs     map[string]*rapb.PushDirectoryRequest
+	fetchReq proto.Message
+}
+
+func (f *fakeAsset) FetchBlob(ctx context.Context, req *rapb.FetchBlobRequest) (*rapb.FetchBlobResponse, error) {
+	f.fetchReq = req
+	for _, uri := range req.Uris {
+		if p, ok := f.blobs[uri]; ok {
+			return &rapb.FetchBlobResponse{Uri: uri, Qualifiers: p.Qualifiers, ExpiresAt: p.ExpireAt, BlobDigest: p.BlobDigest}, nil
+		}
+	}
+	return &rapb.FetchBlobResponse{Status: status.New(codes.NotFound, "no such asset").Proto()}, nil
+}
+
+func (f *fakeAsset) FetchDirectory(ctx context.Context, req *rapb.FetchDirectoryRequest) (*rapb.FetchDirectoryResponse, error) {
+	f.fetchReq = req
+	for _, uri := range req.Uris {
+		if p, ok := f.dirs[uri]; ok {
+			return &rapb.FetchDirectoryResponse{Uri: uri, Qualifiers: p.Qualifiers, ExpiresAt: p.ExpireAt, RootDirectoryDigest: p.RootDirectoryDigest}, nil
+		}
+	}
+	return &rapb.FetchDirectoryResponse{Status: status.New(codes.NotFound, "no such asset").Proto()}, nil
+}
+
+func (f *fakeAsset) PushBlob(ctx context.Context, req *rapb.PushBlobRequest) (*rapb.PushBlobResponse, error) {
+	for _, uri := range req.Uris {
+		f.blobs[uri] = req
+	}
+	return &rapb.PushBlobResponse{}, nil
+}
+
+func (f *fakeAsset) PushDirectory(ctx context.Context, req *rapb.PushDirectoryRequest) (*rapb.PushDirectoryResponse, error) {
+	for _, uri := range req.Uris {
+		f.dirs[uri] = req
+	}
+	return &rapb.PushDirectoryResponse{}, nil
+}
+
+// newTestClient starts fake asset and CAS servers, and returns a client verifying fetches.
+func newTestClient(t *testing.T) (*Client, *fakeAsset, *fakes.CAS) {
+	ctx := context.Background()
+	fa := &fakeAsset{blobs: map[string]*rapb.PushBlobRequest{}, dirs: map[string]*rapb.PushDirectoryRequest{}}
+	fc := fakes.NewCAS()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	rapb.RegisterFetchServer(srv, fa)
+	rapb.RegisterPushServer(srv, fa)
+	regrpc.RegisterContentAddressableStorageServer(srv, fc)
+	bsgrpc.RegisterByteStreamServer(srv, fc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	casCfg := cas.DefaultClientConfig()
+	casCfg.IgnoreCapabilities = true
+	casClient, err := cas.NewClientWithConfig(ctx, conn, "instance", casCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultClientConfig()
+	cfg.VerifyFetched = true
+	client, err := NewClientWithConfig(conn, "instance", casClient, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fa, fc
+}
+
+func TestPushFetchBlob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, fa, fc := newTestClient(t)
+
+	blob := []byte("archive contents")
+	dg := fc.Put(blob)
+	sri, err := NewSRI("sha384", blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire := time.Unix(1600000000, 0).UTC()
+	uri := "https://example.com/archive.tar.gz"
+	push := &PushRequest{URIs: []string{uri}, Qualifiers: []*rapb.Qualifier{ChecksumSRI(sri.String())}, ExpireAt: expire}
+	if err := client.PushBlob(ctx, push, dg); err != nil {
+		t.Fatalf("PushBlob() failed: %v", err)
+	}
+
+	oldest := time.Unix(1500000000, 0).UTC()
+	req := &FetchRequest{
+		URIs:                  []string{"https://mirror.example.com/missing", uri},
+		Qualifiers:            []*rapb.Qualifier{ChecksumSRI(sri.String())},
+		Timeout:               time.Minute,
+		OldestContentAccepted: oldest,
+	}
+	got, err := client.FetchBlob(ctx, req)
+	if err != nil {
+		t.Fatalf("FetchBlob() failed: %v", err)
+	}
+	want := &FetchResult{URI: uri, Qualifiers: push.Qualifiers, ExpiresAt: expire, Digest: dg}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("FetchBlob() returned diff (-want +got):\n%s", diff)
+	}
+	wantReq := &rapb.FetchBlobRequest{
+		InstanceName:          "instance",
+		Uris:                  req.URIs,
+		Qualifiers:            req.Qualifiers,
+		Timeout:               ptypes.DurationProto(time.Minute),
+		OldestContentAccepted: mustTimestamp(t, oldest),
+	}
+	if !proto.Equal(wantReq, fa.fetchReq) {
+		t.Errorf("FetchBlob() sent %v, want %v", fa.fetchReq, wantReq)
+	}
+}
+
+func TestFetchBlobErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, _, fc := newTestClient(t)
+
+	blob := []byte("contents")
+	dg := fc.Put(blob)
+	push := &PushRequest{URIs: []string{"present"}}
+	if err := client.PushBlob(ctx, push, dg); err != nil {
+		t.Fatalf("PushBlob() failed: %v", err)
+	}
+	if err := client.PushBlob(ctx, &PushRequest{URIs: []string{"evicted"}}, digest.NewFromBlob([]byte("evicted"))); err != nil {
+		t.Fatalf("PushBlob() failed: %v", err)
+	}
+	wrong, err := NewSRI("sha256", []byte("other contents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  *FetchRequest
+		code codes.Code
+		err  error
+	}{
+		{name: "not found", req: &FetchRequest{URIs: []string{"missing"}}, code: codes.NotFound},
+		{name: "missing from CAS", req: &FetchRequest{URIs: []string{"evicted"}}, code: codes.Unknown},
+		{
+			name: "checksum mismatch",
+			req:  &FetchRequest{URIs: []string{"present"}, Qualifiers: []*rapb.Qualifier{ChecksumSRI(wrong.String())}},
+			code: codes.Unknown,
+			err:  ErrChecksumMismatch,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.FetchBlob(ctx, tc.req)
+			if err == nil {
+				t.Fatalf("FetchBlob() succeeded, want error")
+			}
+			if got := status.Code(err); got != tc.code {
+				t.Errorf("FetchBlob() returned code %v, want %v (error: %v)", got, tc.code, err)
+			}
+			if tc.err != nil && errors.Cause(err) != tc.err {
+				t.Errorf("FetchBlob() returned %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPushFetchDirectory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, _, fc := newTestClient(t)
+
+	root := fc.Put([]byte("not really a directory"))
+	push := &PushRequest{
+		URIs:            []string{"https://github.com/bazelbuild/remote-apis.git"},
+		Qualifiers:      []*rapb.Qualifier{ResourceType(ResourceTypeGit), VCSBranch("main"), Directory("build/bazel/")},
+		ReferencesBlobs: []digest.Digest{digest.NewFromBlob([]byte("ref"))},
+	}
+	if err := client.PushDirectory(ctx, push, root); err != nil {
+		t.Fatalf("PushDirectory() failed: %v", err)
+	}
+	got, err := client.FetchDirectory(ctx, &FetchRequest{URIs: push.URIs})
+	if err != nil {
+		t.Fatalf("FetchDirectory() failed: %v", err)
+	}
+	want := &FetchResult{URI: push.URIs[0], Qualifiers: push.Qualifiers, Digest: root}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("FetchDirectory() returned diff (-want +got):\n%s", diff)
+	}
+	if v, _ := findQualifier(got.Qualifiers, QualifierDirectory); v != "build/bazel" {
+		t.Errorf("FetchDirectory() returned directory qualifier %q, want %q", v, "build/bazel")
+	}
+}
+
+func TestParseSRI(t *testing.T) {
+	t.Parallel()
+	sha256sri, err := NewSRI("sha256", []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha512sri, err := NewSRI("sha512", []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value   string
+		want    []SRI
+		wantErr bool
+	}{
+		{value: sha256sri.String(), want: []SRI{sha256sri}},
+		{value: "md5-abc " + sha256sri.String() + "  " + sha512sri.String() + "?opt", want: []SRI{sha256sri, sha512sri}},
+		{value: "md5-abc", wantErr: true},
+		{value: "sha256", wantErr: true},
+		{value: "sha256-!!!", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := ParseSRI(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseSRI(%q) returned error %v, wantErr %v", tc.value, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("ParseSRI(%q) returned diff (-want +got):\n%s", tc.value, diff)
+		}
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	t.Parallel()
+	c := &Client{Config: DefaultClientConfig()}
+	if got := c.fetchTimeout(&FetchRequest{}); got != c.Config.FetchTimeout {
+		t.Errorf("fetchTimeout() with no request timeout = %v, want %v", got, c.Config.FetchTimeout)
+	}
+	if got := c.fetchTimeout(&FetchRequest{Timeout: time.Second}); got != c.Config.FetchTimeout {
+		t.Errorf("fetchTimeout() with a short request timeout = %v, want %v", got, c.Config.FetchTimeout)
+	}
+	if got, want := c.fetchTimeout(&FetchRequest{Timeout: time.Hour}), time.Hour+deadlineMargin; got != want {
+		t.Errorf("fetchTimeout() with a long request timeout = %v, want %v", got, want)
+	}
+}
+
+func mustTimestamp(t *testing.T, ts time.Time) *tspb.Timestamp {
+	t.Helper()
+	p, err := ptypes.TimestampProto(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,155 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+
+	rapb "github.com/bazelbuild/remote-apis/build/bazel/remote/asset/v1"
+)
+
+// Standard qualifier names, as defined by the qualifier lexicon of the Remote Asset API.
+const (
+	// QualifierResourceType is the type of the resource, e.g. a media type or application/x-git.
+	QualifierResourceType = "resource_type"
+	// QualifierChecksumSRI is a Subresource Integrity checksum of the content.
+	QualifierChecksumSRI = "checksum.sri"
+	// QualifierDirectory is the relative path of a subdirectory of the resource.
+	QualifierDirectory = "directory"
+	// QualifierVCSBranch is the name of a branch under source control.
+	QualifierVCSBranch = "vcs.branch"
+	// QualifierVCSCommit is the identity of a specific version of the content under source control.
+	QualifierVCSCommit = "vcs.commit"
+)
+
+// ResourceTypeGit is the resource type of git repositories.
+const ResourceTypeGit = "application/x-git"
+
+// ResourceType returns a resource_type qualifier.
+func ResourceType(t string) *rapb.Qualifier {
+	return &rapb.Qualifier{Name: QualifierResourceType, Value: t}
+}
+
+// Directory returns a directory qualifier. Trailing slashes are removed.
+func Directory(path string) *rapb.Qualifier {
+	return &rapb.Qualifier{Name: QualifierDirectory, Value: strings.TrimRight(path, "/")}
+}
+
+// VCSBranch returns a vcs.branch qualifier.
+func VCSBranch(branch string) *rapb.Qualifier {
+	return &rapb.Qualifier{Name: QualifierVCSBranch, Value: branch}
+}
+
+// VCSCommit returns a vcs.commit qualifier.
+func VCSCommit(commit string) *rapb.Qualifier {
+	return &rapb.Qualifier{Name: QualifierVCSCommit, Value: commit}
+}
+
+// ChecksumSRI returns a checksum.sri qualifier for the given SRI string, e.g.
+// "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=". Use SRI.String to build one.
+func ChecksumSRI(sri string) *rapb.Qualifier {
+	return &rapb.Qualifier{Name: QualifierChecksumSRI, Value: sri}
+}
+
+// sriHashes are the SRI hash algorithms, from the weakest to the strongest.
+var sriHashes = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+}
+
+// SRI is a single Subresource Integrity checksum.
+type SRI struct {
+	// Algorithm is one of sha256, sha384 or sha512.
+	Algorithm string
+	// Sum is the raw checksum.
+	Sum []byte
+}
+
+// NewSRI computes the SRI checksum of data with the given algorithm.
+func NewSRI(algorithm string, data []byte) (SRI, error) {
+	for _, h := range sriHashes {
+		if h.name == algorithm {
+			hh := h.new()
+			hh.Write(data)
+			return SRI{Algorithm: algorithm, Sum: hh.Sum(nil)}, nil
+		}
+	}
+	return SRI{}, fmt.Errorf("unsupported SRI algorithm %q", algorithm)
+}
+
+// String returns the SRI in its {algorithm}-{base64 checksum} form.
+func (s SRI) String() string {
+	return s.Algorithm + "-" + base64.StdEncoding.EncodeToString(s.Sum)
+}
+
+// ParseSRI parses an SRI value, which may contain several whitespace-separated checksums.
+// Checksums using unsupported algorithms are ignored, as required by the SRI specification, and
+// options following a '?' are discarded. It is an error if no supported checksum is found.
+func ParseSRI(value string) ([]SRI, error) {
+	var res []SRI
+	for _, tok := range strings.Fields(value) {
+		i := strings.IndexByte(tok, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid SRI checksum %q", tok)
+		}
+		algo, enc := tok[:i], tok[i+1:]
+		if !supportedSRI(algo) {
+			continue
+		}
+		if j := strings.IndexByte(enc, '?'); j >= 0 {
+			enc = enc[:j]
+		}
+		sum, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SRI checksum %q: %v", tok, err)
+		}
+		res = append(res, SRI{Algorithm: algo, Sum: sum})
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no supported checksum in SRI %q", value)
+	}
+	return res, nil
+}
+
+func supportedSRI(algo string) bool {
+	for _, h := range sriHashes {
+		if h.name == algo {
+			return true
+		}
+	}
+	return false
+}
+
+// strongestSRI returns the checksums that use the strongest algorithm of sris. Per the SRI
+// specification, content matches if it matches any of them.
+func strongestSRI(sris []SRI) (func() hash.Hash, []SRI) {
+	for i := len(sriHashes) - 1; i >= 0; i-- {
+		var matching []SRI
+		for _, s := range sris {
+			if s.Algorithm == sriHashes[i].name {
+				matching = append(matching, s)
+			}
+		}
+		if len(matching) > 0 {
+			return sriHashes[i].new, matching
+		}
+	}
+	return nil, nil
+}
+
+// findQualifier returns the value of the qualifier with the given name, if any.
+func findQualifier(qs []*rapb.Qualifier, name string) (string, bool) {
+	for _, q := range qs {
+		if q.GetName() == name {
+			return q.GetValue(), true
+		}
+	}
+	return "", false
+}
@@ -1,4 +1,8 @@
 // Package cas implements an efficient client for Content Addressable Storage.
+//
+// The package only depends on the CAS and ByteStream services, so it can be used by tools that
+// need content-addressed storage access without the execution and command machinery of the
+// client package.
 package cas
 
 import (
@@ -42,6 +46,8 @@ type Client struct {
 	semFindMissingBlobs *semaphore.Weighted
 	semBatchUpdateBlobs *semaphore.Weighted
 	semByteStreamWrite  *semaphore.Weighted
+	semBatchReadBlobs   *semaphore.Weighted
+	semByteStreamRead   *semaphore.Weighted
 
 	// TODO(nodir): ensure it does not hurt streaming.
 	semFileIO *semaphore.Weighted
@@ -104,6 +110,13 @@ type ClientConfig struct {
 	// ByteStreamWrite.MaxItems is ignored.
 	ByteStreamWrite RPCConfig
 
+	// BatchReadBlobs is configuration for ContentAddressableStorage.BatchReadBlobs RPCs.
+	BatchReadBlobs RPCConfig
+
+	// ByteStreamRead is configuration for ByteStream.Read RPCs.
+	// ByteStreamRead.MaxItems and ByteStreamRead.MaxSizeBytes are ignored.
+	ByteStreamRead RPCConfig
+
 	// RetryPolicy specifies how to retry requests on transient errors.
 	RetryPolicy retry.BackoffPolicy
 
@@ -176,6 +189,17 @@ func DefaultClientConfig() ClientConfig {
 			MaxSizeBytes: 4 * 1024 * 1024,
 			Timeout:      time.Minute,
 		},
+		BatchReadBlobs: RPCConfig{
+			Concurrency: 256,
+			MaxItems:    4000,
+			// 4MiB is the default gRPC response size limit.
+			MaxSizeBytes: 4 * 1024 * 1024,
+			Timeout:      time.Minute,
+		},
+		ByteStreamRead: RPCConfig{
+			Concurrency: 256,
+			Timeout:     time.Minute,
+		},
 
 		// Disable compression by default.
 		CompressedBytestreamThreshold: -1,
@@ -213,7 +237,13 @@ func (c *ClientConfig) Validate() error {
 		return errors.Wrap(err, "BatchUpdateBlobs")
 	}
 	if err := c.ByteStreamWrite.validate(); err != nil {
-		return errors.Wrap(err, "BatchUpdateBlobs")
+		return errors.Wrap(err, "ByteStreamWrite")
+	}
+	if err := c.BatchReadBlobs.validate(); err != nil {
+		return errors.Wrap(err, "BatchReadBlobs")
+	}
+	if err := c.ByteStreamRead.validate(); err != nil {
+		return errors.Wrap(err, "ByteStreamRead")
 	}
 	return nil
 }
@@ -274,6 +304,8 @@ func (c *Client) init() {
 	c.semFindMissingBlobs = semaphore.NewWeighted(int64(c.Config.FindMissingBlobs.Concurrency))
 	c.semBatchUpdateBlobs = semaphore.NewWeighted(int64(c.Config.BatchUpdateBlobs.Concurrency))
 	c.semByteStreamWrite = semaphore.NewWeighted(int64(c.Config.ByteStreamWrite.Concurrency))
+	c.semBatchReadBlobs = semaphore.NewWeighted(int64(c.Config.BatchReadBlobs.Concurrency))
+	c.semByteStreamRead = semaphore.NewWeighted(int64(c.Config.ByteStreamRead.Concurrency))
 
 	c.semFileIO = semaphore.NewWeighted(int64(c.Config.FSConcurrency))
 	c.semLargeFile = semaphore.NewWeighted(int64(c.Config.FSLargeConcurrency))
@@ -323,12 +355,28 @@ func (c *Client) checkCapabilities(ctx context.Context) error {
 	if c.Config.BatchUpdateBlobs.MaxSizeBytes > int(caps.CacheCapabilities.MaxBatchTotalSizeBytes) {
 		c.Config.BatchUpdateBlobs.MaxSizeBytes = int(caps.CacheCapabilities.MaxBatchTotalSizeBytes)
 	}
+	if c.Config.BatchReadBlobs.MaxSizeBytes > int(caps.CacheCapabilities.MaxBatchTotalSizeBytes) {
+		c.Config.BatchReadBlobs.MaxSizeBytes = int(caps.CacheCapabilities.MaxBatchTotalSizeBytes)
+	}
 
-	// TODO(nodir): check compression capabilities.
+	if c.Config.CompressedBytestreamThreshold >= 0 && !supportsZstd(caps) {
+		// The server would reject the compressed-blobs resource names.
+		c.Config.CompressedBytestreamThreshold = -1
+	}
 
 	return nil
 }
 
+// supportsZstd returns whether the CAS advertises the zstd compressor.
+func supportsZstd(caps *repb.ServerCapabilities) bool {
+	for _, comp := range caps.GetCacheCapabilities().GetSupportedCompressors() {
+		if comp == repb.Compressor_ZSTD {
+			return true
+		}
+	}
+	return false
+}
+
 // withPerCallTimeout returns a function wrapper that cancels the context if
 // fn does not return within the timeout.
 func withPerCallTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, func(fn func())) {
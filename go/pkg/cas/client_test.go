@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
 )
 
 func TestPerCallTimeout(t *testing.T) {
@@ -33,3 +35,31 @@ func TestPerCallTimeout(t *testing.T) {
 		}
 	})
 }
+
+func TestCompressionCapabilities(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	for _, supported := range []bool{true, false} {
+		e, cleanup := fakes.NewTestEnv(t)
+		defer cleanup()
+		e.Server.CAS.NoCompressors = !supported
+		conn, err := e.Server.NewClientConn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := DefaultClientConfig()
+		cfg.CompressedBytestreamThreshold = 0
+		client, err := NewClientWithConfig(ctx, conn, "instance", cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := int64(0)
+		if !supported {
+			want = -1
+		}
+		if got := client.Config.CompressedBytestreamThreshold; got != want {
+			t.Errorf("with zstd supported: %v, CompressedBytestreamThreshold = %d, want %d", supported, got, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	bspb "google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/retry"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// zstdDecoders is a pool of ZStd decoders.
+// Clients of this pool must call Reset() on the decoder after obtaining it
+// from the pool.
+var zstdDecoders = sync.Pool{
+	New: func() interface{} {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	},
+}
+
+// MissingBlobs returns the digests that are not present on the server.
+// The digests are checked in batches of at most Config.FindMissingBlobs.MaxItems.
+func (c *Client) MissingBlobs(ctx context.Context, digests []digest.Digest) ([]digest.Digest, error) {
+	batchSize := c.Config.FindMissingBlobs.MaxItems
+	if batchSize <= 0 {
+		batchSize = len(digests)
+	}
+
+	var mu sync.Mutex
+	var missing []digest.Digest
+	eg, ctx := errgroup.WithContext(ctx)
+	for start := 0; start < len(digests); start += batchSize {
+		end := start + batchSize
+		if end > len(digests) {
+			end = len(digests)
+		}
+		batch := digests[start:end]
+		eg.Go(func() error {
+			if err := c.semFindMissingBlobs.Acquire(ctx, 1); err != nil {
+				return errors.WithStack(err)
+			}
+			defer c.semFindMissingBlobs.Release(1)
+
+			req := &repb.FindMissingBlobsRequest{
+				InstanceName: c.InstanceName,
+				BlobDigests:  make([]*repb.Digest, len(batch)),
+			}
+			for i, d := range batch {
+				req.BlobDigests[i] = d.ToProto()
+			}
+			var res *repb.FindMissingBlobsResponse
+			err := c.unaryRPC(ctx, &c.Config.FindMissingBlobs, func(ctx context.Context) (err error) {
+				res, err = c.cas.FindMissingBlobs(ctx, req)
+				return
+			})
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, d := range res.MissingBlobDigests {
+				missing = append(missing, digest.NewFromProtoUnvalidated(d))
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// ReadBlobs downloads the given blobs and returns their contents keyed by digest.
+//
+// Blobs are fetched using BatchReadBlobs RPCs where they fit within
+// Config.BatchReadBlobs limits, and using ByteStream otherwise.
+// If any blob cannot be read, the whole call fails.
+func (c *Client) ReadBlobs(ctx context.Context, digests []digest.Digest) (map[digest.Digest][]byte, error) {
+	var mu sync.Mutex
+	res := make(map[digest.Digest][]byte, len(digests))
+	add := func(d digest.Digest, data []byte) {
+		mu.Lock()
+		res[d] = data
+		mu.Unlock()
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	seen := make(map[digest.Digest]bool, len(digests))
+	var batch []digest.Digest
+	batchSize := int64(0)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		b := batch
+		eg.Go(func() error {
+			return c.readBatch(ctx, b, add)
+		})
+		batch = nil
+		batchSize = 0
+	}
+
+	maxSize := int64(c.Config.BatchReadBlobs.MaxSizeBytes)
+	for _, d := range digests {
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		if d.Size == 0 {
+			add(d, nil)
+			continue
+		}
+
+		reqSize := marshalledRequestSize(d.ToProto())
+		if reqSize > maxSize {
+			// There is no way this blob can fit in a batch request.
+			d := d
+			eg.Go(func() error {
+				var buf bytes.Buffer
+				if err := c.ReadBlob(ctx, d, &buf); err != nil {
+					return err
+				}
+				add(d, buf.Bytes())
+				return nil
+			})
+			continue
+		}
+
+		if batchSize+reqSize > maxSize || len(batch) == c.Config.BatchReadBlobs.MaxItems {
+			flush()
+		}
+		batch = append(batch, d)
+		batchSize += reqSize
+	}
+	flush()
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readBatch downloads blobs using BatchReadBlobs RPC, calling add for each of
+// them.
+func (c *Client) readBatch(ctx context.Context, digests []digest.Digest, add func(digest.Digest, []byte)) error {
+	if err := c.semBatchReadBlobs.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer c.semBatchReadBlobs.Release(1)
+
+	req := &repb.BatchReadBlobsRequest{
+		InstanceName: c.InstanceName,
+		Digests:      make([]*repb.Digest, len(digests)),
+	}
+	for i, d := range digests {
+		req.Digests[i] = d.ToProto()
+	}
+	return c.unaryRPC(ctx, &c.Config.BatchReadBlobs, func(ctx context.Context) error {
+		res, err := c.cas.BatchReadBlobs(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		var retriableErr error
+		req.Digests = req.Digests[:0] // reset for the next attempt
+		for _, r := range res.Responses {
+			d := digest.NewFromProtoUnvalidated(r.Digest)
+			if err := status.FromProto(r.Status).Err(); err != nil {
+				if !retry.TransientOnly(err) {
+					return errors.Wrapf(err, "%s", d)
+				}
+				// This error is retriable. Save it to return later, and
+				// save the failed digest for the next attempt.
+				retriableErr = err
+				req.Digests = append(req.Digests, r.Digest)
+				continue
+			}
+			if got := digest.NewFromBlob(r.Data); got != d {
+				return status.Errorf(codes.DataLoss, "blob %s was corrupted in transit, got digest %s", d, got)
+			}
+			add(d, r.Data)
+		}
+		return retriableErr
+	})
+}
+
+// ReadBlob streams the contents of the blob into w using ByteStream.Read RPCs.
+//
+// The blob is transferred compressed if its size is at least
+// Config.CompressedBytestreamThreshold. On transient errors, the read is
+// resumed from the last byte written to w. The data written to w is verified
+// against the digest; if it does not match, the returned error has the
+// codes.DataLoss status code and w should be discarded.
+func (c *Client) ReadBlob(ctx context.Context, d digest.Digest, w io.Writer) error {
+	if err := c.semByteStreamRead.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer c.semByteStreamRead.Release(1)
+
+	compressed := c.Config.CompressedBytestreamThreshold >= 0 && d.Size >= c.Config.CompressedBytestreamThreshold
+	hw := &hashingWriter{w: w, h: digest.HashFn.New()}
+	err := c.withRetries(ctx, func(ctx context.Context) error {
+		return c.readStreamed(ctx, d, hw.n, compressed, hw)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "%s", d)
+	}
+
+	if got := (digest.Digest{Hash: fmt.Sprintf("%x", hw.h.Sum(nil)), Size: hw.n}); got != d {
+		return status.Errorf(codes.DataLoss, "blob %s was corrupted in transit, got digest %s", d, got)
+	}
+	return nil
+}
+
+// readStreamed reads the blob starting at offset, which refers to the
+// uncompressed contents, and writes it to w.
+func (c *Client) readStreamed(ctx context.Context, d digest.Digest, offset int64, compressed bool, w io.Writer) error {
+	ctx, cancel, withTimeout := withPerCallTimeout(ctx, c.Config.ByteStreamRead.Timeout)
+	defer cancel()
+
+	req := &bspb.ReadRequest{ReadOffset: offset}
+	if compressed {
+		req.ResourceName = digest.NewCompressedReadResourceName(c.InstanceName, repb.Compressor_ZSTD, d)
+	} else {
+		req.ResourceName = digest.NewReadResourceName(c.InstanceName, d)
+	}
+	stream, err := c.byteStream.Read(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if !compressed {
+		return copyStream(stream, w, withTimeout)
+	}
+
+	// Decompress using an in-memory pipe, because the zstd package expects a
+	// reader.
+	pr, pw := io.Pipe()
+	dec := zstdDecoders.Get().(*zstd.Decoder)
+	defer zstdDecoders.Put(dec)
+	if err := dec.Reset(pr); err != nil {
+		return errors.Wrapf(err, "failed to reset the zstd decoder")
+	}
+	defer dec.Reset(nil)
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		err := copyStream(stream, pw, withTimeout)
+		pw.CloseWithError(err)
+		return err
+	})
+	eg.Go(func() error {
+		_, err := dec.WriteTo(w)
+		pr.CloseWithError(err)
+		return errors.Wrapf(err, "failed to decompress the blob")
+	})
+	return eg.Wait()
+}
+
+// copyStream writes the data received in stream to w until the server closes
+// the stream.
+func copyStream(stream bspb.ByteStream_ReadClient, w io.Writer, withTimeout func(fn func())) error {
+	for {
+		var res *bspb.ReadResponse
+		var err error
+		withTimeout(func() {
+			res, err = stream.Recv()
+		})
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		if _, err := w.Write(res.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// hashingWriter hashes and counts the bytes written through it.
+type hashingWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func (hw *hashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	hw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,123 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestMissingBlobs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	conn, err := e.Server.NewClientConn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultClientConfig()
+	cfg.FindMissingBlobs.MaxItems = 2 // force multiple requests
+	client, err := NewClientWithConfig(ctx, conn, "instance", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	present := []digest.Digest{e.Server.CAS.Put([]byte("a")), e.Server.CAS.Put([]byte("b"))}
+	missing := []digest.Digest{digest.NewFromBlob([]byte("c")), digest.NewFromBlob([]byte("d")), digest.NewFromBlob([]byte("e"))}
+	got, err := client.MissingBlobs(ctx, append(append([]digest.Digest{}, present...), missing...))
+	if err != nil {
+		t.Fatalf("MissingBlobs() failed: %v", err)
+	}
+	sortDigests := cmpopts.SortSlices(func(a, b digest.Digest) bool { return a.Hash < b.Hash })
+	if diff := cmp.Diff(missing, got, sortDigests); diff != "" {
+		t.Errorf("MissingBlobs() returned diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadBlobs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	conn, err := e.Server.NewClientConn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultClientConfig()
+	cfg.BatchReadBlobs.MaxSizeBytes = 200 // too small for the large blob
+	cfg.BatchReadBlobs.MaxItems = 2
+	client, err := NewClientWithConfig(ctx, conn, "instance", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[digest.Digest][]byte{}
+	for _, blob := range [][]byte{[]byte("a"), []byte("b"), []byte("c"), bytes.Repeat([]byte("large"), 100)} {
+		want[e.Server.CAS.Put(blob)] = blob
+	}
+	want[digest.Empty] = nil
+	var dgs []digest.Digest
+	for d := range want {
+		dgs = append(dgs, d, d)
+	}
+
+	got, err := client.ReadBlobs(ctx, dgs)
+	if err != nil {
+		t.Fatalf("ReadBlobs() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadBlobs() returned diff (-want +got):\n%s", diff)
+	}
+	if got := e.Server.CAS.BatchReqs(); got != 2 {
+		t.Errorf("want 2 batch requests, got %d", got)
+	}
+
+	if _, err := client.ReadBlobs(ctx, []digest.Digest{digest.NewFromBlob([]byte("missing"))}); err == nil {
+		t.Errorf("ReadBlobs() of a missing blob succeeded, want error")
+	}
+}
+
+func TestReadBlob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	for _, threshold := range []int64{-1, 0} {
+		threshold := threshold
+		t.Run(map[int64]string{-1: "uncompressed", 0: "compressed"}[threshold], func(t *testing.T) {
+			t.Parallel()
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			conn, err := e.Server.NewClientConn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := DefaultClientConfig()
+			cfg.CompressedBytestreamThreshold = threshold
+			client, err := NewClientWithConfig(ctx, conn, "instance", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			blob := bytes.Repeat([]byte("blob"), 1000)
+			d := e.Server.CAS.Put(blob)
+			var buf bytes.Buffer
+			if err := client.ReadBlob(ctx, d, &buf); err != nil {
+				t.Fatalf("ReadBlob() failed: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), blob) {
+				t.Errorf("ReadBlob() wrote %d bytes, want %d", buf.Len(), len(blob))
+			}
+		})
+	}
+}
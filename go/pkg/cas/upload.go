@@ -15,7 +15,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	log "github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
 	"github.com/klauspost/compress/zstd"
 	"github.com/pborman/uuid"
@@ -26,6 +25,7 @@ import (
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cache"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/retry"
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 	bspb "google.golang.org/genproto/googleapis/bytestream"
@@ -383,11 +383,11 @@ func (c *Client) Upload(ctx context.Context, opt UploadOptions, inputC <-chan *U
 				if !ok {
 					return nil
 				}
-				log.Infof("start startProcessing %s", in.Path)
+				logger.Infof(ctx, "start startProcessing %s", in.Path)
 				if err := u.startProcessing(ctx, in); err != nil {
 					return err
 				}
-				log.Infof("finish startProcessing %s", in.Path)
+				logger.Infof(ctx, "finish startProcessing %s", in.Path)
 			}
 		}
 	})
@@ -449,7 +449,7 @@ func (u *uploader) startProcessing(ctx context.Context, in *UploadInput) error {
 		// construct a partial Merkle tree. Note that we are not visiting
 		// the entire in.cleanPath, which may be much larger than the union of the
 		// allowlisted paths.
-		log.Infof("start localEg %s", in.Path)
+		logger.Infof(ctx, "start localEg %s", in.Path)
 		localEg, ctx := errgroup.WithContext(ctx)
 		var treeMu sync.Mutex
 		for _, relPath := range in.cleanAllowlist {
@@ -481,7 +481,7 @@ func (u *uploader) startProcessing(ctx context.Context, in *UploadInput) error {
 		if err := localEg.Wait(); err != nil {
 			return errors.WithStack(err)
 		}
-		log.Infof("done localEg %s", in.Path)
+		logger.Infof(ctx, "done localEg %s", in.Path)
 		// At this point, all allowlisted paths are digest'ed, and we only need to
 		// compute a partial Merkle tree and upload the implied ancestors.
 		for _, item := range in.partialMerkleTree() {
@@ -640,7 +640,7 @@ func (u *uploader) visitRegularFile(ctx context.Context, absPath string, info os
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to compute hash")
 	}
-	log.Infof("compute digest %s: %s", info.Name(), time.Since(now))
+	logger.Infof(ctx, "compute digest %s: %s", info.Name(), time.Since(now))
 	ret.Digest = dig.ToProto()
 
 	item := &uploadItem{
@@ -658,7 +658,7 @@ func (u *uploader) visitRegularFile(ctx context.Context, absPath string, info os
 		if res, err := u.findMissingBlobs(ctx, []*uploadItem{item}); err != nil {
 			return nil, errors.Wrapf(err, "failed to check existence")
 		} else if len(res.MissingBlobDigests) == 0 {
-			log.Infof("the file already exists. do not upload %s", absPath)
+			logger.Infof(ctx, "the file already exists. do not upload %s", absPath)
 			atomic.AddInt64(&u.stats.CacheHits.Digests, 1)
 			atomic.AddInt64(&u.stats.CacheHits.Bytes, ret.Digest.SizeBytes)
 			return ret, nil
@@ -978,10 +978,10 @@ func (u *uploader) stream(ctx context.Context, item *uploadItem, updateCacheStat
 	ctx, task := trace.NewTask(ctx, "uploader.stream")
 	defer task.End()
 
-	log.Infof("start stream upload %s, size %d", item.Title, item.Digest.SizeBytes)
+	logger.Infof(ctx, "start stream upload %s, size %d", item.Title, item.Digest.SizeBytes)
 	now := time.Now()
 	defer func() {
-		log.Infof("finish stream upload %s, size %d: %s", item.Title, item.Digest.SizeBytes, time.Since(now))
+		logger.Infof(ctx, "finish stream upload %s, size %d: %s", item.Title, item.Digest.SizeBytes, time.Since(now))
 	}()
 
 	// Open the item.
@@ -1047,7 +1047,7 @@ func (u *uploader) stream(ctx context.Context, item *uploadItem, updateCacheStat
 	})
 }
 
-func (u *uploader) streamFromReader(ctx context.Context, r io.Reader, digest *repb.Digest, compressed, updateCacheStats bool) error {
+func (u *uploader) streamFromReader(ctx context.Context, r io.Reader, d *repb.Digest, compressed, updateCacheStats bool) error {
 	ctx, cancel, withTimeout := withPerCallTimeout(ctx, u.Config.ByteStreamWrite.Timeout)
 	defer cancel()
 
@@ -1058,10 +1058,11 @@ func (u *uploader) streamFromReader(ctx context.Context, r io.Reader, digest *re
 	defer stream.CloseSend()
 
 	req := &bspb.WriteRequest{}
+	dg := digest.NewFromProtoUnvalidated(d)
 	if compressed {
-		req.ResourceName = fmt.Sprintf("%s/uploads/%s/compressed-blobs/zstd/%s/%d", u.InstanceName, uuid.New(), digest.Hash, digest.SizeBytes)
+		req.ResourceName = digest.NewCompressedWriteResourceName(u.InstanceName, uuid.New(), repb.Compressor_ZSTD, dg)
 	} else {
-		req.ResourceName = fmt.Sprintf("%s/uploads/%s/blobs/%s/%d", u.InstanceName, uuid.New(), digest.Hash, digest.SizeBytes)
+		req.ResourceName = digest.NewWriteResourceName(u.InstanceName, uuid.New(), dg)
 	}
 
 	buf := u.streamBufs.Get().(*[]byte)
@@ -1113,14 +1114,14 @@ chunkLoop:
 	switch res, err := stream.CloseAndRecv(); {
 	case err != nil:
 		return err
-	case res.CommittedSize != digest.SizeBytes:
-		return fmt.Errorf("unexpected commitSize: got %d, want %d", res.CommittedSize, digest.SizeBytes)
+	case res.CommittedSize != d.SizeBytes:
+		return fmt.Errorf("unexpected commitSize: got %d, want %d", res.CommittedSize, d.SizeBytes)
 	}
 
 	// Update stats.
 	cacheHit := !req.FinishWrite
 	if !cacheHit {
-		atomic.AddInt64(&u.stats.Streamed.Bytes, digest.SizeBytes)
+		atomic.AddInt64(&u.stats.Streamed.Bytes, d.SizeBytes)
 		atomic.AddInt64(&u.stats.Streamed.Digests, 1)
 	}
 	if updateCacheStats {
@@ -1128,7 +1129,7 @@ chunkLoop:
 		if cacheHit {
 			st = &u.stats.CacheHits
 		}
-		atomic.AddInt64(&st.Bytes, digest.SizeBytes)
+		atomic.AddInt64(&st.Bytes, d.SizeBytes)
 		atomic.AddInt64(&st.Digests, 1)
 	}
 	return nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 
 	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
@@ -28,6 +29,29 @@ var ErrEOF = errors.New("ErrEOF")
 // necessary.
 var fullCompressor, _ = zstd.NewWriter(nil, zstd.WithZeroFrames(true))
 
+var fullCompressorsMu sync.Mutex
+
+// Compressors for full blobs at non-default levels, created on first use.
+var fullCompressors = make(map[zstd.EncoderLevel]*zstd.Encoder)
+
+// fullCompressorLevel returns the compressor for full blobs of the given level.
+func fullCompressorLevel(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	if level == zstd.SpeedDefault {
+		return fullCompressor, nil
+	}
+	fullCompressorsMu.Lock()
+	defer fullCompressorsMu.Unlock()
+	if enc, ok := fullCompressors[level]; ok {
+		return enc, nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithZeroFrames(true), zstd.WithEncoderLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	fullCompressors[level] = enc
+	return enc, nil
+}
+
 // Chunker can be used to chunk an input into uploadable-size byte slices.
 // A single Chunker is NOT thread-safe; it should be used by a single uploader thread.
 type Chunker struct {
@@ -41,6 +65,8 @@ type Chunker struct {
 	contents   []byte
 	offset     int64
 	reachedEOF bool
+	// Whether r compresses the data on the fly.
+	compressed bool
 
 	ue *uploadinfo.Entry
 }
@@ -48,6 +74,15 @@ type Chunker struct {
 // New creates a new chunker from an uploadinfo.Entry.
 // If compressed, the data will of the Entry will be compressed on the fly.
 func New(ue *uploadinfo.Entry, compressed bool, chunkSize int) (*Chunker, error) {
+	return NewWithLevel(ue, compressed, zstd.SpeedDefault, chunkSize)
+}
+
+// NewWithLevel is like New, compressing the data of the Entry with the given zstd level if
+// compressed. A zero level is the default level.
+func NewWithLevel(ue *uploadinfo.Entry, compressed bool, level zstd.EncoderLevel, chunkSize int) (*Chunker, error) {
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
 	if chunkSize < 1 {
 		chunkSize = DefaultChunkSize
 	}
@@ -56,22 +91,35 @@ func New(ue *uploadinfo.Entry, compressed bool, chunkSize int) (*Chunker, error)
 		contents := make([]byte, len(ue.Contents))
 		copy(contents, ue.Contents)
 		if compressed {
-			contents = fullCompressor.EncodeAll(contents, nil)
+			enc, err := fullCompressorLevel(level)
+			if err != nil {
+				return nil, err
+			}
+			contents = enc.EncodeAll(contents, nil)
 		}
 		c = &Chunker{
 			contents: contents,
 		}
-	} else if ue.IsFile() {
-		r := reader.NewFileReadSeeker(ue.Path, IOBufferSize)
+	} else if ue.IsFile() || ue.IsReaderAt() {
+		var r reader.ReadSeeker
+		switch {
+		case ue.IsReaderAt():
+			r = reader.NewReaderAtSeeker(ue.ReaderAt, ue.Offset, ue.Digest.Size, IOBufferSize)
+		case ue.IsRange():
+			r = reader.NewFileRangeReadSeeker(ue.Path, ue.Offset, ue.Digest.Size, IOBufferSize)
+		default:
+			r = reader.NewFileReadSeeker(ue.Path, IOBufferSize)
+		}
 		if compressed {
 			var err error
-			r, err = reader.NewCompressedSeeker(r)
+			r, err = reader.NewCompressedSeekerLevel(r, level)
 			if err != nil {
 				return nil, err
 			}
 		}
 		c = &Chunker{
-			r: r,
+			r:          r,
+			compressed: compressed,
 		}
 
 		if chunkSize > IOBufferSize {
@@ -92,6 +140,9 @@ func (c *Chunker) String() string {
 	if !c.ue.IsFile() {
 		return size
 	}
+	if c.ue.IsRange() {
+		return fmt.Sprintf("%s: %s@%d", size, c.ue.Path, c.ue.Offset)
+	}
 	return fmt.Sprintf("%s: %s", size, c.ue.Path)
 }
 
@@ -105,9 +156,21 @@ func (c *Chunker) ChunkSize() int {
 	return c.chunkSize
 }
 
+// SetChunkSize sets the maximum size of the next chunks, e.g. to adapt it to the connection between
+// two chunks. Like in New, it defaults to DefaultChunkSize and is capped to IOBufferSize for file
+// sources.
+func (c *Chunker) SetChunkSize(chunkSize int) {
+	if chunkSize < 1 {
+		chunkSize = DefaultChunkSize
+	}
+	if c.r != nil && chunkSize > IOBufferSize {
+		chunkSize = IOBufferSize
+	}
+	c.chunkSize = chunkSize
+}
+
 // Reset the Chunker state to when it was newly constructed.
 // Useful for upload retries.
-// TODO(olaola): implement Seek(offset) when we have resumable uploads.
 func (c *Chunker) Reset() error {
 	if c.r != nil {
 		if err := c.r.SeekOffset(0); err != nil {
@@ -119,6 +182,49 @@ func (c *Chunker) Reset() error {
 	return nil
 }
 
+// SeekOffset sets the offset of the next chunk, e.g. to resume an upload from the offset committed by
+// the server. The offset is in the data returned by the Chunker, which is compressed if it was
+// created compressed; compressed files are then compressed again up to the offset.
+func (c *Chunker) SeekOffset(offset int64) error {
+	if err := c.Reset(); err != nil {
+		return err
+	}
+	if offset == 0 {
+		return nil
+	}
+	if offset < 0 {
+		return errors.Errorf("invalid offset %d for %s", offset, c)
+	}
+	if c.contents != nil {
+		if offset > int64(len(c.contents)) {
+			return errors.Errorf("offset %d is beyond the %d bytes of %s", offset, len(c.contents), c)
+		}
+		c.offset = offset
+		return nil
+	}
+	if !c.compressed {
+		if offset > c.ue.Digest.Size {
+			return errors.Errorf("offset %d is beyond the end of %s", offset, c)
+		}
+		if err := c.r.SeekOffset(offset); err != nil {
+			return errors.Wrapf(err, "failed to call SeekOffset(%d) for %s", offset, c)
+		}
+		c.offset = offset
+		return nil
+	}
+	if !c.r.IsInitialized() {
+		if err := c.r.Initialize(); err != nil {
+			return err
+		}
+	}
+	if _, err := io.CopyN(ioutil.Discard, c.r, offset); err != nil {
+		c.r.Close() // Free the file handle in case of error.
+		return errors.Wrapf(err, "failed to skip %d compressed bytes of %s", offset, c)
+	}
+	c.offset = offset
+	return nil
+}
+
 // FullData returns the overall (non-chunked) underlying data. The Chunker is Reset.
 // It is supposed to be used for batch uploading small inputs.
 func (c *Chunker) FullData() ([]byte, error) {
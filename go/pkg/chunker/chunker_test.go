@@ -11,6 +11,7 @@ import (
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/klauspost/compress/zstd"
 )
 
 var tests = []struct {
@@ -155,6 +156,82 @@ func TestChunkerFromFile(t *testing.T) {
 	}
 }
 
+func TestChunkerFromRanges(t *testing.T) {
+	execRoot, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatalf("failed to make temp dir: %v", err)
+	}
+	defer os.RemoveAll(execRoot)
+	IOBufferSize = 10
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Surround the blob with other data, which must not be read.
+			archive := append(append([]byte("prefix"), tc.blob...), []byte("suffix")...)
+			path := filepath.Join(execRoot, tc.name)
+			if err := ioutil.WriteFile(path, archive, 0777); err != nil {
+				t.Fatalf("failed to write temp file: %v", err)
+			}
+			dg := digest.NewFromBlob(tc.blob)
+			for _, ue := range []*uploadinfo.Entry{
+				uploadinfo.EntryFromFileRange(dg, path, int64(len("prefix"))),
+				uploadinfo.EntryFromReaderAt(dg, bytes.NewReader(archive), int64(len("prefix"))),
+			} {
+				c, err := New(ue, false, tc.chunkSize)
+				if err != nil {
+					t.Fatalf("Could not make chunker from UEntry: %v", err)
+				}
+				var gotChunks []*Chunk
+				for range tc.wantChunks {
+					if !c.HasNext() {
+						t.Fatalf("%s: c.HasNext() was false, expecting more chunks", c)
+					}
+					got, err := c.Next()
+					if err != nil {
+						t.Fatalf("%s: c.Next() gave error %v", c, err)
+					}
+					gotChunks = append(gotChunks, got)
+				}
+				if diff := cmp.Diff(tc.wantChunks, gotChunks); diff != "" {
+					t.Errorf("%s: Chunker gave result diff (-want +got):\n%s", c, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkerSetChunkSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blob")
+	blob := []byte("123456789")
+	if err := ioutil.WriteFile(path, blob, 0777); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	dg := digest.NewFromBlob(blob)
+	IOBufferSize = 10
+	for _, ue := range []*uploadinfo.Entry{uploadinfo.EntryFromBlob(blob), uploadinfo.EntryFromFile(dg, path)} {
+		c, err := New(ue, false, 2)
+		if err != nil {
+			t.Fatalf("Could not make chunker from UEntry: %v", err)
+		}
+		var got []string
+		for i, size := range []int{2, 4, 0} {
+			if i > 0 {
+				c.SetChunkSize(size)
+			}
+			chunk, err := c.Next()
+			if err != nil {
+				t.Fatalf("%v: c.Next() gave error %v", c, err)
+			}
+			got = append(got, string(chunk.Data))
+		}
+		if want := []string{"12", "3456", "789"}; !cmp.Equal(want, got) {
+			t.Errorf("%v: chunks = %v, want %v", c, got, want)
+		}
+		if c.HasNext() {
+			t.Errorf("%v: c.HasNext() = true after the last chunk", c)
+		}
+	}
+}
+
 func TestChunkerFullData(t *testing.T) {
 	t.Parallel()
 	for _, tc := range tests {
@@ -397,3 +474,100 @@ func TestChunkerResetOptimization_FullData(t *testing.T) {
 		t.Errorf("c.FullData() gave result diff, want %q, got %q", string(blob), string(got))
 	}
 }
+
+func TestChunkerCompressedLevels(t *testing.T) {
+	execRoot := t.TempDir()
+	blob := bytes.Repeat([]byte("1234567890"), 1000)
+	path := filepath.Join(execRoot, "file")
+	if err := ioutil.WriteFile(path, blob, 0777); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	defer dec.Close()
+	dg := digest.NewFromBlob(blob)
+	ues := map[string]*uploadinfo.Entry{"blob": uploadinfo.EntryFromBlob(blob), "file": uploadinfo.EntryFromFile(dg, path)}
+	for name, ue := range ues {
+		for _, level := range []zstd.EncoderLevel{0, zstd.SpeedFastest, zstd.SpeedBestCompression} {
+			c, err := NewWithLevel(ue, true, level, 100)
+			if err != nil {
+				t.Fatalf("NewWithLevel(%s, %v) failed: %v", name, level, err)
+			}
+			data, err := c.FullData()
+			if err != nil {
+				t.Fatalf("FullData() of %s at level %v failed: %v", name, level, err)
+			}
+			if len(data) >= len(blob) {
+				t.Errorf("FullData() of %s at level %v returned %d bytes, want fewer than %d", name, level, len(data), len(blob))
+			}
+			got, err := dec.DecodeAll(data, nil)
+			if err != nil {
+				t.Fatalf("DecodeAll() of %s at level %v failed: %v", name, level, err)
+			}
+			if !bytes.Equal(got, blob) {
+				t.Errorf("DecodeAll() of %s at level %v did not return the original data", name, level)
+			}
+		}
+	}
+}
+
+func TestChunkerSeekOffset(t *testing.T) {
+	execRoot := t.TempDir()
+	blob := []byte("1234567890abcdefghijklmnopqrstuvwxyz!")
+	path := filepath.Join(execRoot, "file")
+	if err := ioutil.WriteFile(path, blob, 0777); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	dg := digest.NewFromBlob(blob)
+	tests := []struct {
+		name       string
+		ue         *uploadinfo.Entry
+		compressed bool
+	}{
+		{name: "blob", ue: uploadinfo.EntryFromBlob(blob)},
+		{name: "file", ue: uploadinfo.EntryFromFile(dg, path)},
+		{name: "compressed blob", ue: uploadinfo.EntryFromBlob(blob), compressed: true},
+		{name: "compressed file", ue: uploadinfo.EntryFromFile(dg, path), compressed: true},
+	}
+	IOBufferSize = 10
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := New(tc.ue, tc.compressed, 4)
+			if err != nil {
+				t.Fatalf("Could not make chunker from UEntry: %v", err)
+			}
+			var want []byte
+			for c.HasNext() {
+				chunk, err := c.Next()
+				if err != nil {
+					t.Fatalf("c.Next() gave error %v", err)
+				}
+				want = append(want, chunk.Data...)
+			}
+			for _, offset := range []int64{0, 5, int64(len(want))} {
+				if err := c.SeekOffset(offset); err != nil {
+					t.Fatalf("c.SeekOffset(%d) gave error %v", offset, err)
+				}
+				var got []byte
+				for c.HasNext() {
+					chunk, err := c.Next()
+					if err != nil {
+						t.Fatalf("c.Next() after c.SeekOffset(%d) gave error %v", offset, err)
+					}
+					if chunk.Offset != offset+int64(len(got)) {
+						t.Errorf("c.Next() after c.SeekOffset(%d) gave chunk at offset %d, want %d", offset, chunk.Offset, offset+int64(len(got)))
+					}
+					got = append(got, chunk.Data...)
+				}
+				if !bytes.Equal(got, want[offset:]) {
+					t.Errorf("c.SeekOffset(%d) then c.Next() gave %q, want %q", offset, got, want[offset:])
+				}
+			}
+			if err := c.SeekOffset(int64(len(want)) + 1); err == nil {
+				t.Errorf("c.SeekOffset(%d) beyond the data succeeded, want error", len(want)+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// archiver adds entries to an archive.
+type archiver interface {
+	addDir(name string, mode os.FileMode) error
+	addSymlink(name, target string) error
+	// addFile adds a regular file and returns the writer its contents must be written to.
+	addFile(name string, mode os.FileMode, size int64) (io.Writer, error)
+}
+
+// WriteActionOutputsTar streams the outputs of the action result into tw, without extracting them
+// to disk. Output paths are prefixed with prefix, which is typically the working directory of the
+// command. File modes follow the client's ExecutableMode, RegularMode and DirMode, and symlinks are
+// preserved. The caller is responsible for closing tw.
+func (c *Client) WriteActionOutputsTar(ctx context.Context, ar *repb.ActionResult, prefix string, tw *tar.Writer) error {
+	return c.writeActionOutputsArchive(ctx, ar, prefix, &tarArchiver{tw: tw})
+}
+
+// WriteActionOutputsZip is like WriteActionOutputsTar, but writes a zip archive. Symlinks are
+// stored using the Unix convention of a symlink mode bit with the target as the entry contents.
+// The caller is responsible for closing zw.
+func (c *Client) WriteActionOutputsZip(ctx context.Context, ar *repb.ActionResult, prefix string, zw *zip.Writer) error {
+	return c.writeActionOutputsArchive(ctx, ar, prefix, &zipArchiver{zw: zw})
+}
+
+func (c *Client) writeActionOutputsArchive(ctx context.Context, ar *repb.ActionResult, prefix string, a archiver) error {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	outs, err := c.FlattenActionOutputs(ctx, ar)
+	if err != nil {
+		return err
+	}
+	// Sort the outputs, so that archives are deterministic.
+	paths := make([]string, 0, len(outs))
+	for p := range outs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		out := outs[p]
+		name := path.Join(prefix, p)
+		switch {
+		case out.IsEmptyDirectory:
+			err = a.addDir(name, c.DirMode)
+		case out.SymlinkTarget != "":
+			err = a.addSymlink(name, out.SymlinkTarget)
+		default:
+			mode := c.RegularMode
+			if out.IsExecutable {
+				mode = c.ExecutableMode
+			}
+			var w io.Writer
+			if w, err = a.addFile(name, mode, out.Digest.Size); err == nil {
+				_, err = c.readBlobStreamed(ctx, out.Digest, 0, 0, w)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tarArchiver struct {
+	tw *tar.Writer
+}
+
+func (a *tarArchiver) addDir(name string, mode os.FileMode) error {
+	return a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: int64(mode.Perm())})
+}
+
+func (a *tarArchiver) addSymlink(name, target string) error {
+	return a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: target, Mode: 0777})
+}
+
+func (a *tarArchiver) addFile(name string, mode os.FileMode, size int64) (io.Writer, error) {
+	if err := a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: int64(mode.Perm()), Size: size}); err != nil {
+		return nil, err
+	}
+	return a.tw, nil
+}
+
+type zipArchiver struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiver) create(name string, mode os.FileMode, method uint16) (io.Writer, error) {
+	h := &zip.FileHeader{Name: name, Method: method}
+	h.SetMode(mode)
+	return a.zw.CreateHeader(h)
+}
+
+func (a *zipArchiver) addDir(name string, mode os.FileMode) error {
+	_, err := a.create(name+"/", os.ModeDir|mode.Perm(), zip.Store)
+	return err
+}
+
+func (a *zipArchiver) addSymlink(name, target string) error {
+	w, err := a.create(name, os.ModeSymlink|0777, zip.Store)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (a *zipArchiver) addFile(name string, mode os.FileMode, _ int64) (io.Writer, error) {
+	return a.create(name, mode.Perm(), zip.Deflate)
+}
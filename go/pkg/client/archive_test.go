@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// archiveEntry is the part of an archive entry compared by the tests.
+type archiveEntry struct {
+	Mode     os.FileMode
+	Contents string
+}
+
+func archiveTestResult(t *testing.T, e *fakes.TestEnv) *repb.ActionResult {
+	t.Helper()
+	cas := e.Server.CAS
+	cas.Put(fooBlob)
+	cas.Put(barBlob)
+	tree := &repb.Tree{
+		Root: &repb.Directory{
+			Files:       []*repb.FileNode{{Name: "bar", Digest: barDgPb}},
+			Directories: []*repb.DirectoryNode{{Name: "empty", Digest: digest.TestNewFromMessage(&repb.Directory{}).ToProto()}},
+		},
+		Children: []*repb.Directory{{}},
+	}
+	treeDg := cas.Put(mustMarshal(tree))
+	return &repb.ActionResult{
+		OutputFiles:        []*repb.OutputFile{{Path: "foo", Digest: fooDgPb, IsExecutable: true}},
+		OutputFileSymlinks: []*repb.OutputSymlink{{Path: "link", Target: "foo"}},
+		OutputDirectories:  []*repb.OutputDirectory{{Path: "dir", TreeDigest: treeDg.ToProto()}},
+	}
+}
+
+func TestWriteActionOutputsTar(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	ar := archiveTestResult(t, e)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := e.Client.GrpcClient.WriteActionOutputsTar(ctx, ar, "wd", tw); err != nil {
+		t.Fatalf("WriteActionOutputsTar() failed: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	got := make(map[string]archiveEntry)
+	tr := tar.NewReader(&buf)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar failed: %v", err)
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", h.Name, err)
+		}
+		if h.Typeflag == tar.TypeSymlink {
+			contents = []byte(h.Linkname)
+		}
+		got[h.Name] = archiveEntry{Mode: h.FileInfo().Mode(), Contents: string(contents)}
+	}
+	want := map[string]archiveEntry{
+		"wd/foo":        {Mode: 0777, Contents: "foo"},
+		"wd/link":       {Mode: os.ModeSymlink | 0777, Contents: "foo"},
+		"wd/dir/bar":    {Mode: 0644, Contents: "bar"},
+		"wd/dir/empty/": {Mode: os.ModeDir | 0777},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WriteActionOutputsTar() archive diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteActionOutputsZip(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	ar := archiveTestResult(t, e)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := e.Client.GrpcClient.WriteActionOutputsZip(ctx, ar, "", zw); err != nil {
+		t.Fatalf("WriteActionOutputsZip() failed: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip failed: %v", err)
+	}
+	got := make(map[string]archiveEntry)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s failed: %v", f.Name, err)
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", f.Name, err)
+		}
+		got[f.Name] = archiveEntry{Mode: f.Mode(), Contents: string(contents)}
+	}
+	want := map[string]archiveEntry{
+		"foo":        {Mode: 0777, Contents: "foo"},
+		"link":       {Mode: os.ModeSymlink | 0777, Contents: "foo"},
+		"dir/bar":    {Mode: 0644, Contents: "bar"},
+		"dir/empty/": {Mode: os.ModeDir | 0777},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WriteActionOutputsZip() archive diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+)
+
+// DefaultArchiveBufferSize is the default UploadArchiveOptions.BufferSize.
+const DefaultArchiveBufferSize = 64 * 1024 * 1024
+
+// UploadArchiveOptions configures UploadTar and UploadZip.
+type UploadArchiveOptions struct {
+	// StripComponents removes this many leading path components from the entry names, like the
+	// --strip-components flag of tar. Entries with fewer components are skipped.
+	StripComponents int
+	// BufferSize is the number of bytes of entry contents held in memory before they are uploaded,
+	// for entries that cannot be read again from the archive. Defaults to
+	// DefaultArchiveBufferSize.
+	BufferSize int64
+}
+
+// UploadTar reads a tar stream, computes the Merkle tree of its entries, uploads the blobs that
+// are missing from the CAS, and returns the digest of the root directory along with the tree
+// statistics. Nothing is extracted to disk.
+//
+// If r is also an io.ReaderAt and an io.Seeker, like an uncompressed *os.File, file contents are
+// uploaded from their offsets in the archive. Otherwise, e.g. for a decompressing reader, they are
+// buffered in memory and uploaded whenever the buffer is full.
+//
+// Regular files, directories, symlinks and hard links are supported; other entries are skipped.
+func (c *Client) UploadTar(ctx context.Context, r io.Reader, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	defer done()
+
+	u := newArchiveUploader(c, opts)
+	cr := &countingReader{r: r}
+	ra, _ := r.(io.ReaderAt)
+	if s, ok := r.(io.Seeker); ok && ra != nil {
+		if cr.n, err = s.Seek(0, io.SeekCurrent); err != nil {
+			return digest.Empty, nil, err
+		}
+	} else {
+		ra = nil
+	}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return digest.Empty, nil, err
+		}
+		name, ok := u.name(hdr.Name)
+		if !ok {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			u.addDir(name)
+		case tar.TypeSymlink:
+			u.addSymlink(name, hdr.Linkname)
+		case tar.TypeLink:
+			target, ok := u.name(hdr.Linkname)
+			if err := u.addLink(name, target, ok); err != nil {
+				return digest.Empty, nil, err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			exec := hdr.Mode&0100 != 0
+			if ra == nil {
+				if err := u.addContents(ctx, name, exec, tr); err != nil {
+					return digest.Empty, nil, err
+				}
+				continue
+			}
+			offset := cr.n
+			dg, err := digest.NewFromReader(tr)
+			if err != nil {
+				return digest.Empty, nil, err
+			}
+			// Sparse entries are expanded by the tar reader, so their contents are not a range of
+			// the archive.
+			if cr.n-offset != dg.Size {
+				return digest.Empty, nil, fmt.Errorf("unsupported sparse tar entry %q", hdr.Name)
+			}
+			u.addFile(name, uploadinfo.EntryFromReaderAt(dg, ra, offset), exec)
+		default:
+			LogContextInfof(ctx, 2, "Skipping tar entry %q of unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+	return u.finish(ctx)
+}
+
+// UploadZip is like UploadTar, but reads the zip archive of the given size from ra. Stored entries
+// are uploaded from their offsets in the archive, compressed ones are buffered in memory. Symlinks
+// are expected to follow the Unix convention of a symlink mode bit with the target as the entry
+// contents.
+func (c *Client) UploadZip(ctx context.Context, ra io.ReaderAt, size int64, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	defer done()
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	u := newArchiveUploader(c, opts)
+	for _, f := range zr.File {
+		name, ok := u.name(f.Name)
+		if !ok {
+			continue
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			u.addDir(name)
+		case mode&os.ModeSymlink != 0:
+			target, err := readZipFile(f)
+			if err != nil {
+				return digest.Empty, nil, err
+			}
+			u.addSymlink(name, string(target))
+		case mode.IsRegular():
+			exec := mode&0100 != 0
+			if f.Method != zip.Store {
+				rc, err := f.Open()
+				if err != nil {
+					return digest.Empty, nil, err
+				}
+				err = u.addContents(ctx, name, exec, rc)
+				rc.Close()
+				if err != nil {
+					return digest.Empty, nil, err
+				}
+				continue
+			}
+			offset, err := f.DataOffset()
+			if err != nil {
+				return digest.Empty, nil, err
+			}
+			// Reading through the zip reader verifies the checksum of the entry.
+			rc, err := f.Open()
+			if err != nil {
+				return digest.Empty, nil, err
+			}
+			dg, err := digest.NewFromReader(rc)
+			rc.Close()
+			if err != nil {
+				return digest.Empty, nil, err
+			}
+			u.addFile(name, uploadinfo.EntryFromReaderAt(dg, ra, offset), exec)
+		default:
+			LogContextInfof(ctx, 2, "Skipping zip entry %q of unsupported mode %v", f.Name, mode)
+		}
+	}
+	return u.finish(ctx)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// countingReader counts the bytes read from r, starting at n.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// archiveUploader collects the entries of an archive into a tree, uploading buffered contents as
+// it goes.
+type archiveUploader struct {
+	c    *Client
+	opts UploadArchiveOptions
+
+	files map[string]*fileSysNode
+	dirs  map[string]bool
+	// buffered holds the in-memory entries not uploaded yet.
+	buffered     []*uploadinfo.Entry
+	bufferedSize int64
+	// uploaded holds the digests of the blobs uploaded while reading the archive.
+	uploaded map[digest.Digest]bool
+}
+
+func newArchiveUploader(c *Client, opts *UploadArchiveOptions) *archiveUploader {
+	u := &archiveUploader{
+		c:        c,
+		files:    make(map[string]*fileSysNode),
+		dirs:     make(map[string]bool),
+		uploaded: make(map[digest.Digest]bool),
+	}
+	if opts != nil {
+		u.opts = *opts
+	}
+	if u.opts.BufferSize <= 0 {
+		u.opts.BufferSize = DefaultArchiveBufferSize
+	}
+	return u
+}
+
+// name returns the tree path of the archive entry name, and false if the entry is outside of the
+// tree.
+func (u *archiveUploader) name(name string) (string, bool) {
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		return "", false
+	}
+	segs := strings.Split(name, "/")
+	if len(segs) <= u.opts.StripComponents {
+		return "", false
+	}
+	return path.Join(segs[u.opts.StripComponents:]...), true
+}
+
+func (u *archiveUploader) addDir(name string) {
+	u.dirs[name] = true
+}
+
+func (u *archiveUploader) addSymlink(name, target string) {
+	u.files[name] = &fileSysNode{symlink: &symlinkNode{target: target}}
+}
+
+func (u *archiveUploader) addFile(name string, ue *uploadinfo.Entry, exec bool) {
+	u.files[name] = &fileSysNode{file: &fileNode{ue: ue, isExecutable: exec}}
+}
+
+// addLink adds a hard link to the previously added file target.
+func (u *archiveUploader) addLink(name, target string, ok bool) error {
+	fn := u.files[target]
+	if !ok || fn == nil || fn.file == nil {
+		return fmt.Errorf("hard link %q to unknown file %q", name, target)
+	}
+	u.files[name] = &fileSysNode{file: &fileNode{ue: fn.file.ue, isExecutable: fn.file.isExecutable}}
+	return nil
+}
+
+// addContents adds a file read from r, buffering its contents in memory.
+func (u *archiveUploader) addContents(ctx context.Context, name string, exec bool, r io.Reader) error {
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	ue := uploadinfo.EntryFromBlob(blob)
+	u.addFile(name, ue, exec)
+	u.buffered = append(u.buffered, ue)
+	u.bufferedSize += ue.Digest.Size
+	if u.bufferedSize >= u.opts.BufferSize {
+		return u.flush(ctx)
+	}
+	return nil
+}
+
+// flush uploads the buffered entries and releases their contents.
+func (u *archiveUploader) flush(ctx context.Context) error {
+	if len(u.buffered) == 0 {
+		return nil
+	}
+	if _, _, err := u.c.UploadIfMissing(ctx, u.buffered...); err != nil {
+		return err
+	}
+	for _, ue := range u.buffered {
+		u.uploaded[ue.Digest] = true
+	}
+	for _, fn := range u.files {
+		if fn.file != nil && u.uploaded[fn.file.ue.Digest] && fn.file.ue.IsBlob() {
+			// Only the digest is needed to package the tree.
+			fn.file.ue = &uploadinfo.Entry{Digest: fn.file.ue.Digest}
+		}
+	}
+	u.buffered = nil
+	u.bufferedSize = 0
+	return nil
+}
+
+// finish packages the tree and uploads the remaining blobs.
+func (u *archiveUploader) finish(ctx context.Context) (digest.Digest, *TreeStats, error) {
+	// Directory entries only matter if they are empty.
+	parents := make(map[string]bool)
+	for name := range u.files {
+		for dir := path.Dir(name); dir != "." && !parents[dir]; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+	for name := range u.dirs {
+		for dir := path.Dir(name); dir != "." && !parents[dir]; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+	fs := make(map[string]*fileSysNode, len(u.files))
+	for name, fn := range u.files {
+		if u.dirs[name] || parents[name] {
+			return digest.Empty, nil, fmt.Errorf("archive entry %q is both a directory and a file", name)
+		}
+		fs[filepath.FromSlash(name)] = fn
+	}
+	for name := range u.dirs {
+		if !parents[name] {
+			fs[filepath.FromSlash(name)] = &fileSysNode{emptyDirectoryMarker: true}
+		}
+	}
+
+	stats := &TreeStats{}
+	ft, err := buildTree(fs)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	root, blobs, err := packageTree(ft, stats)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	var ues []*uploadinfo.Entry
+	for dg, ue := range blobs {
+		if !u.uploaded[dg] {
+			ues = append(ues, ue)
+		}
+	}
+	if _, _, err := u.c.UploadIfMissing(ctx, ues...); err != nil {
+		return digest.Empty, nil, err
+	}
+	return root, stats, nil
+}
@@ -0,0 +1,172 @@
+package client_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// archiveUploadTree returns the tree expected from the test archives, and its root digest.
+func archiveUploadTree() (digest.Digest, []*repb.Directory) {
+	dir := &repb.Directory{Files: []*repb.FileNode{{Name: "foo", Digest: fooDgPb, IsExecutable: true}}}
+	empty := &repb.Directory{}
+	root := &repb.Directory{
+		Files: []*repb.FileNode{{Name: "bar", Digest: barDgPb}, {Name: "hard", Digest: barDgPb}},
+		Directories: []*repb.DirectoryNode{
+			{Name: "dir", Digest: digest.TestNewFromMessage(dir).ToProto()},
+			{Name: "empty", Digest: digest.TestNewFromMessage(empty).ToProto()},
+		},
+		Symlinks: []*repb.SymlinkNode{{Name: "link", Target: "bar"}},
+	}
+	return digest.TestNewFromMessage(root), []*repb.Directory{root, dir, empty}
+}
+
+func testTar(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range []struct {
+		hdr      *tar.Header
+		contents []byte
+	}{
+		{&tar.Header{Typeflag: tar.TypeDir, Name: "snap/", Mode: 0755}, nil},
+		{&tar.Header{Typeflag: tar.TypeDir, Name: "snap/dir/", Mode: 0755}, nil},
+		{&tar.Header{Typeflag: tar.TypeReg, Name: "snap/dir/foo", Mode: 0755, Size: int64(len(fooBlob))}, fooBlob},
+		{&tar.Header{Typeflag: tar.TypeReg, Name: "./snap/bar", Mode: 0644, Size: int64(len(barBlob))}, barBlob},
+		{&tar.Header{Typeflag: tar.TypeLink, Name: "snap/hard", Linkname: "./snap/bar"}, nil},
+		{&tar.Header{Typeflag: tar.TypeSymlink, Name: "snap/link", Linkname: "bar", Mode: 0777}, nil},
+		{&tar.Header{Typeflag: tar.TypeDir, Name: "snap/empty/", Mode: 0755}, nil},
+		{&tar.Header{Typeflag: tar.TypeFifo, Name: "snap/fifo", Mode: 0644}, nil},
+	} {
+		if err := tw.WriteHeader(e.hdr); err != nil {
+			t.Fatalf("WriteHeader(%q) failed: %v", e.hdr.Name, err)
+		}
+		if _, err := tw.Write(e.contents); err != nil {
+			t.Fatalf("Write(%q) failed: %v", e.hdr.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func checkArchiveUpload(t *testing.T, e *fakes.TestEnv, gotDg digest.Digest, gotStats *client.TreeStats) {
+	t.Helper()
+	wantDg, dirs := archiveUploadTree()
+	if gotDg != wantDg {
+		t.Errorf("root = %v, want %v", gotDg, wantDg)
+	}
+	wantStats := &client.TreeStats{InputFiles: 3, InputDirectories: 3, InputSymlinks: 1, TotalInputBytes: 2*barDg.Size + fooDg.Size}
+	for _, dir := range dirs {
+		wantStats.TotalInputBytes += digest.TestNewFromMessage(dir).Size
+	}
+	if diff := cmp.Diff(wantStats, gotStats); diff != "" {
+		t.Errorf("stats diff (-want +got):\n%s", diff)
+	}
+	for _, dg := range []digest.Digest{fooDg, barDg, wantDg} {
+		if _, ok := e.Server.CAS.Get(dg); !ok {
+			t.Errorf("blob %v was not uploaded", dg)
+		}
+	}
+}
+
+func TestUploadTar(t *testing.T) {
+	ctx := context.Background()
+	tarBlob := testTar(t)
+	opts := &client.UploadArchiveOptions{StripComponents: 1}
+	tests := []struct {
+		name string
+		r    io.Reader
+		opts *client.UploadArchiveOptions
+	}{
+		{name: "ReaderAt", r: bytes.NewReader(tarBlob), opts: opts},
+		// Hides the io.ReaderAt, so that contents are buffered.
+		{name: "Stream", r: io.MultiReader(bytes.NewReader(tarBlob)), opts: opts},
+		{name: "Flushed", r: io.MultiReader(bytes.NewReader(tarBlob)), opts: &client.UploadArchiveOptions{StripComponents: 1, BufferSize: 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			gotDg, gotStats, err := e.Client.GrpcClient.UploadTar(ctx, tc.r, tc.opts)
+			if err != nil {
+				t.Fatalf("UploadTar() failed: %v", err)
+			}
+			checkArchiveUpload(t, e, gotDg, gotStats)
+		})
+	}
+}
+
+func TestUploadTarConflict(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeSymlink, Name: "a", Linkname: "b"},
+		{Typeflag: tar.TypeReg, Name: "a/c"},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	if _, _, err := e.Client.GrpcClient.UploadTar(ctx, &buf, nil); err == nil {
+		t.Errorf("UploadTar() of a symlink with children succeeded, want error")
+	}
+}
+
+func TestUploadZip(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name     string
+		mode     os.FileMode
+		method   uint16
+		contents []byte
+	}{
+		{"snap/dir/", os.ModeDir | 0755, zip.Store, nil},
+		{"snap/dir/foo", 0755, zip.Store, fooBlob},
+		{"snap/bar", 0644, zip.Deflate, barBlob},
+		{"snap/hard", 0644, zip.Store, barBlob},
+		{"snap/link", os.ModeSymlink | 0777, zip.Store, []byte("bar")},
+		{"snap/empty/", os.ModeDir | 0755, zip.Store, nil},
+	} {
+		h := &zip.FileHeader{Name: f.name, Method: f.method}
+		h.SetMode(f.mode)
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatalf("CreateHeader(%q) failed: %v", f.name, err)
+		}
+		if _, err := w.Write(f.contents); err != nil {
+			t.Fatalf("Write(%q) failed: %v", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	gotDg, gotStats, err := e.Client.GrpcClient.UploadZip(ctx, bytes.NewReader(buf.Bytes()), int64(buf.Len()), &client.UploadArchiveOptions{StripComponents: 1})
+	if err != nil {
+		t.Fatalf("UploadZip() failed: %v", err)
+	}
+	checkArchiveUpload(t, e, gotDg, gotStats)
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"google.golang.org/grpc/status"
+)
+
+// BuildStats are the statistics of a build: the lifetime Stats of the client, along with the
+// statistics of the actions reported with RecordAction. They are written as JSON to the StatsFile
+// when the client is closed, for mining remote execution efficiency across builds.
+type BuildStats struct {
+	Stats
+	// ErrorsByCode is Stats.Errors keyed by gRPC code name.
+	ErrorsByCode map[string]int64 `json:"errors"`
+	// StartTime is when the client was created.
+	StartTime time.Time `json:"start_time"`
+	// EndTime is when the stats were collected.
+	EndTime time.Time `json:"end_time"`
+	// ActionsByStatus is the number of recorded actions per result status.
+	ActionsByStatus map[string]int64 `json:"actions_by_status"`
+	// Phases aggregates the durations of the phases of the recorded actions, by event name, see
+	// the Event constants of the command package.
+	Phases map[string]*PhaseStats `json:"phases"`
+	// Actions are the recorded actions, in the order they were recorded.
+	Actions []*ActionStats `json:"actions"`
+}
+
+// PhaseStats aggregates the durations of a phase over several actions. Durations are written to
+// stats files in nanoseconds.
+type PhaseStats struct {
+	Count int64         `json:"count"`
+	Total time.Duration `json:"total"`
+	Max   time.Duration `json:"max"`
+}
+
+// ActionStats are the statistics of one action.
+type ActionStats struct {
+	// ActionDigest is the digest of the action, in hash/size form.
+	ActionDigest string `json:"action_digest"`
+	// Status is the result status of the action.
+	Status string `json:"status"`
+	// ExitCode is the exit code of the command.
+	ExitCode int `json:"exit_code"`
+	// ErrorCode is the gRPC code name of the error of the action, if any.
+	ErrorCode string `json:"error_code,omitempty"`
+	// Error is the error of the action, if any.
+	Error                  string `json:"error,omitempty"`
+	InputFiles             int    `json:"input_files"`
+	TotalInputBytes        int64  `json:"total_input_bytes"`
+	OutputFiles            int    `json:"output_files"`
+	TotalOutputBytes       int64  `json:"total_output_bytes"`
+	LogicalBytesUploaded   int64  `json:"logical_bytes_uploaded"`
+	RealBytesUploaded      int64  `json:"real_bytes_uploaded"`
+	LogicalBytesDownloaded int64  `json:"logical_bytes_downloaded"`
+	RealBytesDownloaded    int64  `json:"real_bytes_downloaded"`
+	// Phases are the durations of the phases of the action, by event name.
+	Phases map[string]time.Duration `json:"phases,omitempty"`
+}
+
+// StatsFile is the path of a file to which the client writes its BuildStats as JSON when closed.
+type StatsFile string
+
+// Apply sets the client's StatsFile.
+func (s StatsFile) Apply(c *Client) {
+	c.StatsFile = s
+}
+
+// buildStats accumulates the recorded actions of a Client. The zero value is ready to use.
+type buildStats struct {
+	mu       sync.Mutex
+	start    time.Time
+	byStatus map[string]int64
+	phases   map[string]*PhaseStats
+	actions  []*ActionStats
+}
+
+// RecordAction adds the result and metadata of a finished action to the BuildStats of the
+// client. The rexec package records the actions it runs.
+func (c *Client) RecordAction(res *command.Result, md *command.Metadata) {
+	if res == nil || md == nil {
+		return
+	}
+	as := &ActionStats{
+		ActionDigest:           md.ActionDigest.String(),
+		Status:                 res.Status.String(),
+		ExitCode:               res.ExitCode,
+		InputFiles:             md.InputFiles,
+		TotalInputBytes:        md.TotalInputBytes,
+		OutputFiles:            md.OutputFiles,
+		TotalOutputBytes:       md.TotalOutputBytes,
+		LogicalBytesUploaded:   md.LogicalBytesUploaded,
+		RealBytesUploaded:      md.RealBytesUploaded,
+		LogicalBytesDownloaded: md.LogicalBytesDownloaded,
+		RealBytesDownloaded:    md.RealBytesDownloaded,
+	}
+	if res.Err != nil {
+		as.Error = res.Err.Error()
+		as.ErrorCode = status.Code(res.Err).String()
+	}
+	for event, ti := range md.EventTimes {
+		if ti == nil || ti.From.IsZero() || ti.To.Before(ti.From) {
+			continue
+		}
+		if as.Phases == nil {
+			as.Phases = make(map[string]time.Duration)
+		}
+		as.Phases[event] = ti.To.Sub(ti.From)
+	}
+
+	s := &c.buildStats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byStatus == nil {
+		s.byStatus = make(map[string]int64)
+		s.phases = make(map[string]*PhaseStats)
+	}
+	s.byStatus[as.Status]++
+	for event, d := range as.Phases {
+		ps := s.phases[event]
+		if ps == nil {
+			ps = &PhaseStats{}
+			s.phases[event] = ps
+		}
+		ps.Count++
+		ps.Total += d
+		if d > ps.Max {
+			ps.Max = d
+		}
+	}
+	s.actions = append(s.actions, as)
+}
+
+// BuildStats returns the statistics of the build so far.
+func (c *Client) BuildStats() *BuildStats {
+	st := c.Stats()
+	bs := &BuildStats{
+		Stats:           st,
+		ErrorsByCode:    make(map[string]int64, len(st.Errors)),
+		EndTime:         time.Now(),
+		ActionsByStatus: make(map[string]int64),
+		Phases:          make(map[string]*PhaseStats),
+	}
+	for code, n := range st.Errors {
+		bs.ErrorsByCode[code.String()] = n
+	}
+	s := &c.buildStats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bs.StartTime = s.start
+	for st, n := range s.byStatus {
+		bs.ActionsByStatus[st] = n
+	}
+	for event, ps := range s.phases {
+		cp := *ps
+		bs.Phases[event] = &cp
+	}
+	bs.Actions = append([]*ActionStats(nil), s.actions...)
+	return bs
+}
+
+// writeStatsFile writes the BuildStats to the StatsFile, through a temporary file so that readers
+// never see partial stats.
+func (c *Client) writeStatsFile() error {
+	blob, err := json.MarshalIndent(c.BuildStats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := string(c.StatsFile)
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(blob)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
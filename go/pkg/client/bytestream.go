@@ -6,36 +6,71 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
+	"time"
 
-	log "github.com/golang/glog"
 	"github.com/pkg/errors"
 	bspb "google.golang.org/genproto/googleapis/bytestream"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/chunker"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
 )
 
 // WriteBytes uploads a byte slice.
 func (c *Client) WriteBytes(ctx context.Context, name string, data []byte) error {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	ue := uploadinfo.EntryFromBlob(data)
 	ch, err := chunker.New(ue, false, int(c.ChunkMaxSize))
 	if err != nil {
 		return err
 	}
 	_, err = c.writeChunked(ctx, name, ch)
-	return err
+	return newOpError("Write", ue.Digest, name, err)
 }
 
 // writeChunked uploads chunked data with a given resource name to the CAS.
 func (c *Client) writeChunked(ctx context.Context, name string, ch *chunker.Chunker) (int64, error) {
 	var totalBytes int64
-	closure := func() error {
-		// Retry by starting the stream from the beginning.
-		if err := ch.Reset(); err != nil {
-			return errors.Wrap(err, "failed to Reset")
+	attempts := 0
+	closure := func() (err error) {
+		attempts++
+		var committed int64
+		if attempts > 1 {
+			// Resume the upload from the offset committed by the server, if any.
+			var complete bool
+			committed, complete = c.committedWriteSize(ctx, name)
+			if complete {
+				atomic.AddInt64(&c.stats.blobsUploaded, 1)
+				return nil
+			}
 		}
-		totalBytes = int64(0)
-		// TODO(olaola): implement resumable uploads.
+		if committed > 0 {
+			if err := ch.SeekOffset(committed); err != nil {
+				LogContextInfof(ctx, 2, "Failed to resume the upload of %s from offset %d, restarting it: %v", name, committed, err)
+				committed = 0
+			} else {
+				LogContextInfof(ctx, 2, "Resuming the upload of %s from offset %d", name, committed)
+			}
+		}
+		if committed == 0 {
+			// Start the stream from the beginning.
+			if err := ch.Reset(); err != nil {
+				return errors.Wrap(err, "failed to Reset")
+			}
+		}
+		totalBytes = committed
+
+		chunks := 0
+		var lastSent time.Time
+		tuned := c.tuneChunks(ctx, ch.SetChunkSize)
+		defer func() { tuned(totalBytes-committed, chunks, lastSent, err) }()
 
 		stream, err := c.Write(ctx)
 		if err != nil {
@@ -47,7 +82,7 @@ func (c *Client) writeChunked(ctx context.Context, name string, ch *chunker.Chun
 			if err != nil {
 				return err
 			}
-			if chunk.Offset == 0 {
+			if chunks == 0 {
 				req.ResourceName = name
 			}
 			req.WriteOffset = chunk.Offset
@@ -63,24 +98,50 @@ func (c *Client) writeChunked(ctx context.Context, name string, ch *chunker.Chun
 				return err
 			}
 			totalBytes += int64(len(req.Data))
+			atomic.AddInt64(&c.stats.bytesUploaded, int64(len(req.Data)))
+			chunks++
+			lastSent = time.Now()
 		}
 		if _, err := stream.CloseAndRecv(); err != nil {
 			return err
 		}
+		atomic.AddInt64(&c.stats.blobsUploaded, 1)
 		return nil
 	}
-	err := c.Retrier.Do(ctx, closure)
+	err := c.retry(ctx, closure)
 	return totalBytes, err
 }
 
+// committedWriteSize returns the number of bytes of the resource name committed by the server in
+// previous writes, and whether the write is complete. Errors are not retried, and are reported as
+// nothing being committed, for the upload to restart from the beginning.
+func (c *Client) committedWriteSize(ctx context.Context, name string) (int64, bool) {
+	var res *bspb.QueryWriteStatusResponse
+	err := c.CallWithTimeout(ctx, "QueryWriteStatus", func(ctx context.Context) (e error) {
+		res, e = c.byteStream.QueryWriteStatus(ctx, &bspb.QueryWriteStatusRequest{ResourceName: name}, c.RPCOpts()...)
+		return e
+	})
+	if err != nil {
+		LogContextInfof(ctx, 3, "QueryWriteStatus(%s) failed: %v", name, err)
+		return 0, false
+	}
+	return res.CommittedSize, res.Complete
+}
+
 // ReadBytes fetches a resource's contents into a byte slice.
 //
 // ReadBytes panics with ErrTooLarge if an attempt is made to read a resource with contents too
 // large to fit into a byte array.
 func (c *Client) ReadBytes(ctx context.Context, name string) ([]byte, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	buf := &bytes.Buffer{}
-	_, err := c.readStreamedRetried(ctx, name, 0, 0, buf)
-	return buf.Bytes(), err
+	_, err = c.readStreamedRetried(ctx, name, 0, 0, buf)
+	return buf.Bytes(), newOpError("Read", digest.Digest{}, name, err)
 }
 
 // ReadResourceToFile fetches a resource's contents, saving it into a file.
@@ -90,6 +151,12 @@ func (c *Client) ReadBytes(ctx context.Context, name string) ([]byte, error) {
 //
 // The number of bytes read is returned.
 func (c *Client) ReadResourceToFile(ctx context.Context, name, fpath string) (int64, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
 	return c.readToFile(ctx, c.InstanceName+name, fpath)
 }
 
@@ -99,7 +166,8 @@ func (c *Client) readToFile(ctx context.Context, name string, fpath string) (int
 		return 0, err
 	}
 	defer f.Close()
-	return c.readStreamedRetried(ctx, name, 0, 0, f)
+	n, err := c.readStreamedRetried(ctx, name, 0, 0, f)
+	return n, newOpError("Read", digest.Digest{}, name, err)
 }
 
 // readStreamed reads from a bytestream and copies the result to the provided Writer, starting
@@ -131,7 +199,8 @@ func (c *Client) readStreamed(ctx context.Context, name string, offset, limit in
 		if err != nil {
 			return 0, err
 		}
-		log.V(3).Infof("Read: resource:%s offset:%d len(data):%d", name, offset, len(resp.Data))
+		logger.Logf(ctx, 3, "Read: resource:%s offset:%d len(data):%d", name, offset, len(resp.Data))
+		atomic.AddInt64(&c.stats.bytesDownloaded, int64(len(resp.Data)))
 		nm, err := w.Write(resp.Data)
 		if err != nil {
 			// Wrapping the error to ensure it may never get retried.
@@ -149,6 +218,7 @@ func (c *Client) readStreamed(ctx context.Context, name string, offset, limit in
 			}
 		}
 	}
+	atomic.AddInt64(&c.stats.blobsDownloaded, 1)
 	return n, nil
 }
 
@@ -159,5 +229,5 @@ func (c *Client) readStreamedRetried(ctx context.Context, name string, offset, l
 		n += m
 		return err
 	}
-	return n, c.Retrier.Do(ctx, closure)
+	return n, c.retry(ctx, closure)
 }
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithCallOverridesMerges(t *testing.T) {
+	off := false
+	ctx := WithCallOverrides(context.Background(), &CallOverrides{
+		Headers:     map[string]string{"x-tenant": "a", "x-user": "alice"},
+		Priority:    3,
+		Compression: &off,
+	})
+	ctx = WithCallOverrides(ctx, &CallOverrides{
+		Headers: map[string]string{"x-user": "bob"},
+		Timeout: time.Second,
+	})
+	got := CallOverridesFromContext(ctx)
+	if diff := cmp.Diff(map[string]string{"x-tenant": "a", "x-user": "bob"}, got.Headers); diff != "" {
+		t.Errorf("Headers diff (-want +got):\n%s", diff)
+	}
+	if got.Priority != 3 {
+		t.Errorf("Priority = %d, want 3", got.Priority)
+	}
+	if got.Compression == nil || *got.Compression {
+		t.Errorf("Compression = %v, want false", got.Compression)
+	}
+	if got.Timeout != time.Second {
+		t.Errorf("Timeout = %v, want 1s", got.Timeout)
+	}
+	if o := CallOverridesFromContext(context.Background()); o.Headers != nil || o.Compression != nil {
+		t.Errorf("CallOverridesFromContext(Background) = %+v, want empty", o)
+	}
+}
+
+func TestOverridesInterceptorAddsHeaders(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "existing", "1")
+	ctx = WithCallOverrides(ctx, &CallOverrides{Headers: map[string]string{"x-tenant": "a"}})
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := overridesUnaryInterceptor(ctx, "/Test", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("overridesUnaryInterceptor() failed: %v", err)
+	}
+	if got := md.Get("x-tenant"); len(got) != 1 || got[0] != "a" {
+		t.Errorf("x-tenant = %v, want [a]", got)
+	}
+	if got := md.Get("existing"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("existing = %v, want [1]", got)
+	}
+}
+
+func TestCallWithTimeoutOverride(t *testing.T) {
+	c := &Client{rpcTimeouts: RPCTimeouts{"default": time.Hour}}
+	ctx := WithCallOverrides(context.Background(), &CallOverrides{Timeout: time.Minute})
+	err := c.CallWithTimeout(ctx, "Read", func(ctx context.Context) error {
+		dl, ok := ctx.Deadline()
+		if !ok {
+			t.Fatalf("context has no deadline")
+		}
+		if left := time.Until(dl); left > time.Minute {
+			t.Errorf("deadline in %v, want at most 1m", left)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("CallWithTimeout() failed: %v", err)
+	}
+}
+
+func TestCallWithTimeoutOverrideSkipsExecutions(t *testing.T) {
+	c := &Client{rpcTimeouts: RPCTimeouts{"default": time.Hour, "Execute": 0, "WaitExecution": 0}}
+	ctx := WithCallOverrides(context.Background(), &CallOverrides{Timeout: time.Minute})
+	for _, rpc := range []string{"Execute", "WaitExecution"} {
+		err := c.CallWithTimeout(ctx, rpc, func(ctx context.Context) error {
+			if dl, ok := ctx.Deadline(); ok {
+				t.Errorf("%s context has a deadline in %v, want none", rpc, time.Until(dl))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("CallWithTimeout(%s) failed: %v", rpc, err)
+		}
+	}
+}
+
+func TestShouldCompressOverride(t *testing.T) {
+	c := &Client{CompressedBytestreamThreshold: -1}
+	on, off := true, false
+	if c.shouldCompress(context.Background(), 100) {
+		t.Errorf("shouldCompress() = true with compression disabled")
+	}
+	if !c.shouldCompress(WithCallOverrides(context.Background(), &CallOverrides{Compression: &on}), 100) {
+		t.Errorf("shouldCompress() = false with compression forced on")
+	}
+	c.CompressedBytestreamThreshold = 0
+	if c.shouldCompress(WithCallOverrides(context.Background(), &CallOverrides{Compression: &off}), 100) {
+		t.Errorf("shouldCompress() = true with compression forced off")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/pkg/errors"
 
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
@@ -26,7 +27,15 @@ func (c *Client) CheckCapabilities(ctx context.Context) (err error) {
 	}
 
 	if c.serverCaps.CacheCapabilities != nil {
-		c.MaxBatchSize = MaxBatchSize(c.serverCaps.CacheCapabilities.MaxBatchTotalSizeBytes)
+		// A zero maximum means that batches are only limited by the size of the gRPC messages.
+		max := int64(c.MaxMessageSize) - maxBatchOverhead
+		if sz := c.serverCaps.CacheCapabilities.MaxBatchTotalSizeBytes; sz > 0 && sz < max {
+			max = sz
+		}
+		c.MaxBatchSize = MaxBatchSize(max)
+	}
+	if c.CompressedBytestreamThreshold >= 0 && !c.SupportsCompressor(repb.Compressor_ZSTD) {
+		logger.Warningf(ctx, "The CAS does not support zstd compressed blobs, blobs are transferred uncompressed.")
 	}
 	return nil
 }
@@ -63,6 +72,21 @@ func (c *Client) SupportsActionPlatformProperties() bool {
 	return supportsActionPlatformProperties(c.serverCaps)
 }
 
+// SupportsCompressor returns whether the CAS advertises the given compressor, i.e. whether it
+// serves ByteStream reads and writes of blobs compressed with it. It returns false if the
+// capabilities of the server have not been fetched.
+func (c *Client) SupportsCompressor(comp repb.Compressor_Value) bool {
+	if c.serverCaps == nil {
+		return false
+	}
+	for _, sc := range c.serverCaps.GetCacheCapabilities().GetSupportedCompressors() {
+		if sc == comp {
+			return true
+		}
+	}
+	return false
+}
+
 // SupportsCommandOutputPaths returns whether the server's RE API version
 // supports the `Command.action_paths` field.
 func (c *Client) SupportsCommandOutputPaths() bool {
@@ -17,6 +17,7 @@ import (
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/chunker"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
 	"github.com/golang/protobuf/proto"
 	"github.com/klauspost/compress/zstd"
@@ -28,7 +29,6 @@ import (
 	"google.golang.org/grpc/status"
 
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
-	log "github.com/golang/glog"
 )
 
 // DefaultCompressedBytestreamThreshold is the default threshold, in bytes, for
@@ -156,7 +156,7 @@ func (c *Client) uploadProcessor() {
 				}
 				st.clients = remainingClients
 				if len(st.clients) == 0 {
-					log.V(3).Infof("Cancelling Write %v", req.ue.Digest)
+					logger.Logf(context.Background(), 3, "Cancelling Write %v", req.ue.Digest)
 					if st.cancel != nil {
 						st.cancel()
 					}
@@ -262,7 +262,7 @@ func (c *Client) upload(reqs []*uploadRequest) {
 	newStates := make(map[digest.Digest]*uploadState)
 	var newUploads []digest.Digest
 	var metas []*ContextMetadata
-	log.V(2).Infof("Upload is processing %d requests", len(reqs))
+	logger.Logf(context.Background(), 2, "Upload is processing %d requests", len(reqs))
 	for _, req := range reqs {
 		dg := req.ue.Digest
 		st, ok := c.casUploads[dg]
@@ -309,14 +309,14 @@ func (c *Client) upload(reqs []*uploadRequest) {
 		updateAndNotify(newStates[dg], 0, nil, false)
 	}
 
-	LogContextInfof(ctx, log.Level(2), "%d new items to store", len(missing))
+	LogContextInfof(ctx, 2, "%d new items to store", len(missing))
 	var batches [][]digest.Digest
 	if c.useBatchOps {
 		batches = c.makeBatches(ctx, missing, true)
 	} else {
-		LogContextInfof(ctx, log.Level(2), "Uploading them individually")
+		LogContextInfof(ctx, 2, "Uploading them individually")
 		for i := range missing {
-			LogContextInfof(ctx, log.Level(3), "Creating single batch of blob %s", missing[i])
+			LogContextInfof(ctx, 3, "Creating single batch of blob %s", missing[i])
 			batches = append(batches, missing[i:i+1])
 		}
 	}
@@ -328,10 +328,10 @@ func (c *Client) upload(reqs []*uploadRequest) {
 				defer c.casUploaders.Release(1)
 			}
 			if i%logInterval == 0 {
-				LogContextInfof(ctx, log.Level(2), "%d batches left to store", len(batches)-i)
+				LogContextInfof(ctx, 2, "%d batches left to store", len(batches)-i)
 			}
 			if len(batch) > 1 {
-				LogContextInfof(ctx, log.Level(3), "Uploading batch of %d blobs", len(batch))
+				LogContextInfof(ctx, 3, "Uploading batch of %d blobs", len(batch))
 				bchMap := make(map[digest.Digest][]byte)
 				totalBytesMap := make(map[digest.Digest]int64)
 				for _, dg := range batch {
@@ -354,11 +354,11 @@ func (c *Client) upload(reqs []*uploadRequest) {
 					updateAndNotify(newStates[dg], totalBytesMap[dg], err, true)
 				}
 			} else {
-				LogContextInfof(ctx, log.Level(3), "Uploading single blob with digest %s", batch[0])
+				LogContextInfof(ctx, 3, "Uploading single blob with digest %s", batch[0])
 				st := newStates[batch[0]]
 				st.mu.Lock()
 				if len(st.clients) == 0 { // Already cancelled.
-					log.V(3).Infof("Blob upload for digest %s was canceled", batch[0])
+					logger.Logf(ctx, 3, "Blob upload for digest %s was canceled", batch[0])
 					st.mu.Unlock()
 					return
 				}
@@ -366,12 +366,15 @@ func (c *Client) upload(reqs []*uploadRequest) {
 				st.cancel = cancel
 				st.mu.Unlock()
 				dg := st.ue.Digest
-				log.V(3).Infof("Uploading single blob with digest %s", batch[0])
-				ch, err := chunker.New(st.ue, c.shouldCompress(dg.Size), int(c.ChunkMaxSize))
+				logger.Logf(ctx, 3, "Uploading single blob with digest %s", batch[0])
+				compressed := c.shouldCompressEntry(ctx, st.ue)
+				ch, err := c.newEntryChunker(st.ue, compressed)
 				if err != nil {
 					updateAndNotify(st, 0, err, true)
 				}
-				totalBytes, err := c.writeChunked(cCtx, c.writeRscName(dg), ch)
+				name := c.writeRscName(dg, compressed)
+				totalBytes, err := c.writeChunked(cCtx, name, ch)
+				err = newOpError("Write", dg, name, err)
 				updateAndNotify(st, totalBytes, err, true)
 			}
 		}()
@@ -386,7 +389,7 @@ func (c *Client) uploadNonUnified(ctx context.Context, data ...*uploadinfo.Entry
 	for _, ue := range data {
 		dg := ue.Digest
 		if dg.IsEmpty() {
-			LogContextInfof(ctx, log.Level(2), "Skipping upload of empty blob %s", dg)
+			LogContextInfof(ctx, 2, "Skipping upload of empty blob %s", dg)
 			continue
 		}
 		if _, ok := ueList[dg]; !ok {
@@ -399,14 +402,14 @@ func (c *Client) uploadNonUnified(ctx context.Context, data ...*uploadinfo.Entry
 	if err != nil {
 		return nil, 0, err
 	}
-	LogContextInfof(ctx, log.Level(2), "%d items to store", len(missing))
+	LogContextInfof(ctx, 2, "%d items to store", len(missing))
 	var batches [][]digest.Digest
 	if c.useBatchOps {
 		batches = c.makeBatches(ctx, missing, true)
 	} else {
-		LogContextInfof(ctx, log.Level(2), "Uploading them individually")
+		LogContextInfof(ctx, 2, "Uploading them individually")
 		for i := range missing {
-			LogContextInfof(ctx, log.Level(3), "Creating single batch of blob %s", missing[i])
+			LogContextInfof(ctx, 3, "Creating single batch of blob %s", missing[i])
 			batches = append(batches, missing[i:i+1])
 		}
 	}
@@ -422,10 +425,10 @@ func (c *Client) uploadNonUnified(ctx context.Context, data ...*uploadinfo.Entry
 			}
 			defer c.casUploaders.Release(1)
 			if i%logInterval == 0 {
-				LogContextInfof(ctx, log.Level(2), "%d batches left to store", len(batches)-i)
+				LogContextInfof(ctx, 2, "%d batches left to store", len(batches)-i)
 			}
 			if len(batch) > 1 {
-				LogContextInfof(ctx, log.Level(3), "Uploading batch of %d blobs", len(batch))
+				LogContextInfof(ctx, 3, "Uploading batch of %d blobs", len(batch))
 				bchMap := make(map[digest.Digest][]byte)
 				for _, dg := range batch {
 					ue := ueList[dg]
@@ -450,14 +453,17 @@ func (c *Client) uploadNonUnified(ctx context.Context, data ...*uploadinfo.Entry
 					return err
 				}
 			} else {
-				LogContextInfof(ctx, log.Level(3), "Uploading single blob with digest %s", batch[0])
+				LogContextInfof(ctx, 3, "Uploading single blob with digest %s", batch[0])
 				ue := ueList[batch[0]]
 				dg := ue.Digest
-				ch, err := chunker.New(ue, c.shouldCompress(dg.Size), int(c.ChunkMaxSize))
+				compressed := c.shouldCompressEntry(ctx, ue)
+				ch, err := c.newEntryChunker(ue, compressed)
 				if err != nil {
 					return err
 				}
-				written, err := c.writeChunked(eCtx, c.writeRscName(dg), ch)
+				name := c.writeRscName(dg, compressed)
+				written, err := c.writeChunked(eCtx, name, ch)
+				err = newOpError("Write", dg, name, err)
 				if err != nil {
 					return fmt.Errorf("failed to upload %s: %w", ue.Path, err)
 				}
@@ -470,11 +476,11 @@ func (c *Client) uploadNonUnified(ctx context.Context, data ...*uploadinfo.Entry
 		})
 	}
 
-	LogContextInfof(ctx, log.Level(2), "Waiting for remaining jobs")
+	LogContextInfof(ctx, 2, "Waiting for remaining jobs")
 	err = eg.Wait()
-	LogContextInfof(ctx, log.Level(2), "Done")
+	LogContextInfof(ctx, 2, "Done")
 	if err != nil {
-		LogContextInfof(ctx, log.Level(2), "Upload error: %v", err)
+		LogContextInfof(ctx, 2, "Upload error: %v", err)
 	}
 
 	return missing, totalBytesTransferred, err
@@ -495,11 +501,33 @@ func (c *Client) cancelPendingRequests(reqs []*uploadRequest) {
 // Returns a slice of the missing digests and the sum of total bytes moved - may be different
 // from logical bytes moved (ie sum of digest sizes) due to compression.
 func (c *Client) UploadIfMissing(ctx context.Context, data ...*uploadinfo.Entry) ([]digest.Digest, int64, error) {
-	if !c.UnifiedUploads {
-		return c.uploadNonUnified(ctx, data...)
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	var missing []digest.Digest
+	var bytesMoved int64
+	if c.UnifiedUploads {
+		missing, bytesMoved, err = c.uploadUnified(ctx, data...)
+	} else {
+		missing, bytesMoved, err = c.uploadNonUnified(ctx, data...)
+	}
+	if err == nil {
+		c.countDeduped(data, missing)
+		dgs := make([]digest.Digest, len(data))
+		for i, ue := range data {
+			dgs[i] = ue.Digest
+		}
+		c.markPresent(ctx, dgs)
 	}
+	return missing, bytesMoved, err
+}
+
+func (c *Client) uploadUnified(ctx context.Context, data ...*uploadinfo.Entry) ([]digest.Digest, int64, error) {
 	uploads := len(data)
-	LogContextInfof(ctx, log.Level(2), "Request to upload %d blobs", uploads)
+	LogContextInfof(ctx, 2, "Request to upload %d blobs", uploads)
 
 	if uploads == 0 {
 		return nil, 0, nil
@@ -514,7 +542,7 @@ func (c *Client) UploadIfMissing(ctx context.Context, data ...*uploadinfo.Entry)
 	for _, ue := range data {
 		if ue.Digest.IsEmpty() {
 			uploads--
-			LogContextInfof(ctx, log.Level(2), "Skipping upload of empty entry %s", ue.Digest)
+			LogContextInfof(ctx, 2, "Skipping upload of empty entry %s", ue.Digest)
 			continue
 		}
 		req := &uploadRequest{
@@ -525,7 +553,7 @@ func (c *Client) UploadIfMissing(ctx context.Context, data ...*uploadinfo.Entry)
 		reqs = append(reqs, req)
 		select {
 		case <-ctx.Done():
-			LogContextInfof(ctx, log.Level(2), "Upload canceled")
+			LogContextInfof(ctx, 2, "Upload canceled")
 			c.cancelPendingRequests(reqs)
 			return nil, 0, ctx.Err()
 		case c.casUploadRequests <- req:
@@ -577,20 +605,36 @@ func (c *Client) WriteProto(ctx context.Context, msg proto.Message) (digest.Dige
 	return c.WriteBlob(ctx, bytes)
 }
 
-// WriteBlob uploads a blob to the CAS.
+// WriteBlob uploads a blob to the CAS. Blobs of at least SplitSpliceThreshold bytes are uploaded
+// as chunks and spliced if the server supports it, so that only the chunks it lacks are sent.
 func (c *Client) WriteBlob(ctx context.Context, blob []byte) (digest.Digest, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+	defer done()
+
 	ue := uploadinfo.EntryFromBlob(blob)
 	dg := ue.Digest
 	if dg.IsEmpty() {
-		LogContextInfof(ctx, log.Level(2), "Skipping upload of empty blob %s", dg)
+		LogContextInfof(ctx, 2, "Skipping upload of empty blob %s", dg)
 		return dg, nil
 	}
-	ch, err := chunker.New(ue, c.shouldCompress(dg.Size), int(c.ChunkMaxSize))
+	if c.shouldSplitSplice(dg.Size) && c.SupportsSpliceBlob() {
+		err := c.writeBlobSpliced(ctx, blob, dg)
+		if err == nil {
+			return dg, nil
+		}
+		LogContextInfof(ctx, 2, "Splicing blob %s failed, falling back to a regular write: %v", dg, err)
+	}
+	compressed := c.shouldCompressEntry(ctx, ue)
+	ch, err := c.newEntryChunker(ue, compressed)
 	if err != nil {
 		return dg, err
 	}
-	_, err = c.writeChunked(ctx, c.writeRscName(dg), ch)
-	return dg, err
+	name := c.writeRscName(dg, compressed)
+	_, err = c.writeChunked(ctx, name, ch)
+	return dg, newOpError("Write", dg, name, err)
 }
 
 type writeDummyCloser struct {
@@ -601,8 +645,8 @@ func (w *writeDummyCloser) Close() error { return nil }
 
 // maybeCompressReadBlob will, depending on the client configuration, set the blobs to be
 // read compressed. It returns the appropriate resource name.
-func (c *Client) maybeCompressReadBlob(d digest.Digest, w io.Writer) (string, io.WriteCloser, chan error, error) {
-	if !c.shouldCompress(d.Size) {
+func (c *Client) maybeCompressReadBlob(ctx context.Context, d digest.Digest, w io.Writer) (string, io.WriteCloser, chan error, error) {
+	if !c.shouldCompress(ctx, d.Size) {
 		// If we aren't compressing the data, theere's nothing to wait on.
 		dummyDone := make(chan error, 1)
 		dummyDone <- nil
@@ -620,6 +664,12 @@ func (c *Client) maybeCompressReadBlob(d digest.Digest, w io.Writer) (string, io
 // computed in advance by the caller. In case multiple errors occur during the blob upload, the
 // last error will be returned.
 func (c *Client) BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]byte) error {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	var reqs []*repb.BatchUpdateBlobsRequest_Request
 	var sz int64
 	for k, b := range blobs {
@@ -646,29 +696,37 @@ func (c *Client) BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]
 			return e
 		})
 		if err != nil {
+			if errorCode(err) == codes.ResourceExhausted && len(reqs) > 1 {
+				return errBatchTooLarge
+			}
 			return err
 		}
 
-		numErrs, errDg, errMsg := 0, new(repb.Digest), ""
+		numErrs, errDg, errMsg, errCode := 0, new(repb.Digest), "", codes.OK
 		var failedReqs []*repb.BatchUpdateBlobsRequest_Request
+		var failedDgs []digest.Digest
 		var retriableError error
 		allRetriable := true
 		for _, r := range resp.Responses {
 			st := status.FromProto(r.Status)
 			if st.Code() != codes.OK {
 				e := StatusDetailedError(st)
-				if c.Retrier.ShouldRetry(e) {
+				failedDgs = append(failedDgs, digest.NewFromProtoUnvalidated(r.Digest))
+				if c.Retrier.retriable(e) {
 					failedReqs = append(failedReqs, &repb.BatchUpdateBlobsRequest_Request{
 						Digest: r.Digest,
 						Data:   blobs[digest.NewFromProtoUnvalidated(r.Digest)],
 					})
 					retriableError = e
-				} else {
+				} else if allRetriable {
+					// Report the first failure which is not retried, since it fails the batch.
 					allRetriable = false
+					errDg, errMsg, errCode = r.Digest, e.Error(), st.Code()
 				}
 				numErrs++
-				errDg = r.Digest
-				errMsg = e.Error()
+			} else {
+				atomic.AddInt64(&c.stats.bytesUploaded, int64(len(blobs[digest.NewFromProtoUnvalidated(r.Digest)])))
+				atomic.AddInt64(&c.stats.blobsUploaded, 1)
 			}
 		}
 		reqs = failedReqs
@@ -676,11 +734,37 @@ func (c *Client) BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]
 			if allRetriable {
 				return retriableError // Retriable errors only, retry the failed requests.
 			}
-			return fmt.Errorf("uploading blobs as part of a batch resulted in %d failures, including blob %s: %s", numErrs, errDg, errMsg)
+			return &OpError{
+				Method:        "BatchUpdateBlobs",
+				Digest:        digest.NewFromProtoUnvalidated(errDg),
+				FailedDigests: failedDgs,
+				Code:          errCode,
+				Err:           fmt.Errorf("uploading blobs as part of a batch resulted in %d failures, including blob %s: %s", numErrs, errDg, errMsg),
+			}
 		}
 		return nil
 	}
-	return c.Retrier.Do(ctx, closure)
+	err = c.retry(ctx, closure)
+	if err != errBatchTooLarge {
+		return newOpError("BatchUpdateBlobs", digest.Digest{}, "", err)
+	}
+	// Upload the remaining blobs in two halves, with smaller batches from now on.
+	sz = 0
+	for _, r := range reqs {
+		sz += marshalledRequestSize(digest.NewFromProtoUnvalidated(r.Digest))
+	}
+	c.shrinkBatchSize(ctx, sz)
+	half := len(reqs) / 2
+	for _, part := range [][]*repb.BatchUpdateBlobsRequest_Request{reqs[:half], reqs[half:]} {
+		partBlobs := make(map[digest.Digest][]byte)
+		for _, r := range part {
+			partBlobs[digest.NewFromProtoUnvalidated(r.Digest)] = r.Data
+		}
+		if err := c.BatchWriteBlobs(ctx, partBlobs); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // BatchDownloadBlobs downloads a number of blobs from the CAS to memory. They must collectively be below the
@@ -688,6 +772,12 @@ func (c *Client) BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]
 // computed in advance by the caller. In case multiple errors occur during the blob read, the
 // last error will be returned.
 func (c *Client) BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (map[digest.Digest][]byte, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	if len(dgs) > int(c.MaxBatchDigests) {
 		return nil, fmt.Errorf("batch read of %d total blobs exceeds maximum of %d", len(dgs), c.MaxBatchDigests)
 	}
@@ -717,28 +807,35 @@ func (c *Client) BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (m
 			return e
 		})
 		if err != nil {
+			if errorCode(err) == codes.ResourceExhausted && len(req.Digests) > 1 {
+				return errBatchTooLarge
+			}
 			return err
 		}
 
-		numErrs, errDg, errMsg := 0, &repb.Digest{}, ""
+		numErrs, errDg, errMsg, errCode := 0, &repb.Digest{}, "", codes.OK
 		var failedDgs []*repb.Digest
+		var failed []digest.Digest
 		var retriableError error
 		allRetriable := true
 		for _, r := range resp.Responses {
 			st := status.FromProto(r.Status)
 			if st.Code() != codes.OK {
 				e := st.Err()
-				if c.Retrier.ShouldRetry(e) {
+				failed = append(failed, digest.NewFromProtoUnvalidated(r.Digest))
+				if c.Retrier.retriable(e) {
 					failedDgs = append(failedDgs, r.Digest)
 					retriableError = e
-				} else {
+				} else if allRetriable {
+					// Report the first failure which is not retried, since it fails the batch.
 					allRetriable = false
+					errDg, errMsg, errCode = r.Digest, r.Status.Message, st.Code()
 				}
 				numErrs++
-				errDg = r.Digest
-				errMsg = r.Status.Message
 			} else {
 				res[digest.NewFromProtoUnvalidated(r.Digest)] = r.Data
+				atomic.AddInt64(&c.stats.bytesDownloaded, int64(len(r.Data)))
+				atomic.AddInt64(&c.stats.blobsDownloaded, 1)
 			}
 		}
 		req.Digests = failedDgs
@@ -746,11 +843,40 @@ func (c *Client) BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (m
 			if allRetriable {
 				return retriableError // Retriable errors only, retry the failed digests.
 			}
-			return fmt.Errorf("downloading blobs as part of a batch resulted in %d failures, including blob %s: %s", numErrs, errDg, errMsg)
+			return &OpError{
+				Method:        "BatchReadBlobs",
+				Digest:        digest.NewFromProtoUnvalidated(errDg),
+				FailedDigests: failed,
+				Code:          errCode,
+				Err:           fmt.Errorf("downloading blobs as part of a batch resulted in %d failures, including blob %s: %s", numErrs, errDg, errMsg),
+			}
 		}
 		return nil
 	}
-	return res, c.Retrier.Do(ctx, closure)
+	err = c.retry(ctx, closure)
+	if err != errBatchTooLarge {
+		return res, newOpError("BatchReadBlobs", digest.Digest{}, "", err)
+	}
+	// Download the remaining blobs in two halves, with smaller batches from now on.
+	var remaining []digest.Digest
+	sz = 0
+	for _, d := range req.Digests {
+		dg := digest.NewFromProtoUnvalidated(d)
+		remaining = append(remaining, dg)
+		sz += marshalledRequestSize(dg)
+	}
+	c.shrinkBatchSize(ctx, sz)
+	half := len(remaining) / 2
+	for _, part := range [][]digest.Digest{remaining[:half], remaining[half:]} {
+		partRes, err := c.BatchDownloadBlobs(ctx, part)
+		for dg, data := range partRes {
+			res[dg] = data
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
 }
 
 // makeBatches splits a list of digests into batches of size no more than the maximum.
@@ -766,7 +892,8 @@ func (c *Client) BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (m
 // operations.
 func (c *Client) makeBatches(ctx context.Context, dgs []digest.Digest, optimizeSize bool) [][]digest.Digest {
 	var batches [][]digest.Digest
-	LogContextInfof(ctx, log.Level(2), "Batching %d digests", len(dgs))
+	maxSize := c.maxBatchSize()
+	LogContextInfof(ctx, 2, "Batching %d digests", len(dgs))
 	if optimizeSize {
 		sort.Slice(dgs, func(i, j int) bool {
 			return dgs[i].Size < dgs[j].Size
@@ -787,7 +914,7 @@ func (c *Client) makeBatches(ctx context.Context, dgs []digest.Digest, optimizeS
 		if len(dgs) > 0 {
 			nextSize = marshalledRequestSize(dgs[0])
 		}
-		for len(dgs) > 0 && len(batch) < int(c.MaxBatchDigests) && nextSize <= int64(c.MaxBatchSize)-sz { // nextSize+sz possibly overflows so subtract instead.
+		for len(dgs) > 0 && len(batch) < int(c.MaxBatchDigests) && nextSize <= maxSize-sz { // nextSize+sz possibly overflows so subtract instead.
 			sz += nextSize
 			batch = append(batch, dgs[0])
 			dgs = dgs[1:]
@@ -795,13 +922,42 @@ func (c *Client) makeBatches(ctx context.Context, dgs []digest.Digest, optimizeS
 				nextSize = marshalledRequestSize(dgs[0])
 			}
 		}
-		LogContextInfof(ctx, log.Level(3), "Created batch of %d blobs with total size %d", len(batch), sz)
+		LogContextInfof(ctx, 3, "Created batch of %d blobs with total size %d", len(batch), sz)
 		batches = append(batches, batch)
 	}
-	LogContextInfof(ctx, log.Level(2), "%d batches created", len(batches))
+	LogContextInfof(ctx, 2, "%d batches created", len(batches))
 	return batches
 }
 
+// errBatchTooLarge is returned by the attempts of batch RPCs rejected with RESOURCE_EXHAUSTED, which
+// are not retried as is but split.
+var errBatchTooLarge = errors.New("batch rejected by the server with RESOURCE_EXHAUSTED")
+
+// maxBatchSize returns the maximum size in bytes of the batches made by the client: MaxBatchSize,
+// unless the server rejected smaller batches.
+func (c *Client) maxBatchSize() int64 {
+	if limit := atomic.LoadInt64(&c.batchSizeLimit); limit > 0 && limit < int64(c.MaxBatchSize) {
+		return limit
+	}
+	return int64(c.MaxBatchSize)
+}
+
+// shrinkBatchSize lowers the maximum size of the batches made by the client below sz, the size of
+// a batch rejected by the server with RESOURCE_EXHAUSTED.
+func (c *Client) shrinkBatchSize(ctx context.Context, sz int64) {
+	limit := sz / 2
+	for {
+		cur := atomic.LoadInt64(&c.batchSizeLimit)
+		if cur > 0 && cur <= limit {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&c.batchSizeLimit, cur, limit) {
+			LogContextInfof(ctx, 1, "Batch of %d bytes rejected by the server, lowering the maximum batch size to %d bytes", sz, limit)
+			return
+		}
+	}
+}
+
 func marshalledFieldSize(size int64) int64 {
 	return 1 + int64(proto.SizeVarint(uint64(size))) + size
 }
@@ -839,8 +995,51 @@ func (c *Client) ReadBlobRange(ctx context.Context, d digest.Digest, offset, lim
 	return c.readBlob(ctx, d, offset, limit)
 }
 
+// ReadBlobToWriter streams a blob from the CAS to w, without holding the whole blob in memory.
+// Returns the size of the blob and the amount of bytes moved through the wire. The digest of the
+// blob is verified once it is entirely written, so w may have received corrupted data when a
+// DataLoss error is returned.
+func (c *Client) ReadBlobToWriter(ctx context.Context, d digest.Digest, w io.Writer) (*MovedBytesMetadata, error) {
+	return c.ReadBlobRangeToWriter(ctx, d, 0, 0, w)
+}
+
+// ReadBlobRangeToWriter streams a partial blob from the CAS to w, with the same offset and limit as
+// ReadBlobRange. Partial blobs can't be verified against the digest.
+func (c *Client) ReadBlobRangeToWriter(ctx context.Context, d digest.Digest, offset, limit int64, w io.Writer) (*MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if err := checkReadRange(d, offset, limit); err != nil {
+		return nil, err
+	}
+	return c.readBlobStreamed(ctx, d, offset, limit, w)
+}
+
+// checkReadRange returns an error if offset and limit are not a valid range of the blob dg.
+func checkReadRange(dg digest.Digest, offset, limit int64) error {
+	if offset > dg.Size {
+		return fmt.Errorf("offset %d out of range for a blob of size %d", offset, dg.Size)
+	}
+	if offset < 0 {
+		return fmt.Errorf("offset %d may not be negative", offset)
+	}
+	if limit < 0 {
+		return fmt.Errorf("limit %d may not be negative", limit)
+	}
+	return nil
+}
+
 // Returns the size of the blob and the amount of bytes moved through the wire.
 func (c *Client) readBlob(ctx context.Context, dg digest.Digest, offset, limit int64) ([]byte, *MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
 	// int might be 32-bit, in which case we could have a blob whose size is representable in int64
 	// but not int32, and thus can't fit in a slice. We can check for this by casting and seeing if
 	// the result is negative, since 32 bits is big enough wrap all out-of-range values of int64 to
@@ -848,14 +1047,8 @@ func (c *Client) readBlob(ctx context.Context, dg digest.Digest, offset, limit i
 	if int(dg.Size) < 0 {
 		return nil, nil, fmt.Errorf("digest size %d is too big to fit in a byte slice", dg.Size)
 	}
-	if offset > dg.Size {
-		return nil, nil, fmt.Errorf("offset %d out of range for a blob of size %d", offset, dg.Size)
-	}
-	if offset < 0 {
-		return nil, nil, fmt.Errorf("offset %d may not be negative", offset)
-	}
-	if limit < 0 {
-		return nil, nil, fmt.Errorf("limit %d may not be negative", limit)
+	if err := checkReadRange(dg, offset, limit); err != nil {
+		return nil, nil, err
 	}
 	sz := dg.Size - offset
 	if limit > 0 && limit < sz {
@@ -868,8 +1061,26 @@ func (c *Client) readBlob(ctx context.Context, dg digest.Digest, offset, limit i
 }
 
 // ReadBlobToFile fetches a blob with a provided digest name from the CAS, saving it into a file.
-// It returns the number of bytes read.
+// It returns the number of bytes read. Blobs of at least SplitSpliceThreshold bytes are split if the
+// server supports it, and only the chunks not found in the previous contents of the file are read.
+// Otherwise, blobs large enough for the ParallelReads of the client are read as concurrent parts.
 func (c *Client) ReadBlobToFile(ctx context.Context, d digest.Digest, fpath string) (*MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if c.shouldSplitSplice(d.Size) && c.SupportsSplitBlob() && (c.LocalCAS == nil || !c.LocalCAS.Has(d)) {
+		stats, err := c.readBlobSplit(ctx, d, fpath)
+		if err == nil {
+			return stats, nil
+		}
+		LogContextInfof(ctx, 2, "Reading blob %s as chunks failed, falling back to a regular read: %v", d, err)
+	}
+	if c.shouldReadParallel(d.Size) && (c.LocalCAS == nil || !c.LocalCAS.Has(d)) {
+		return c.readBlobParallel(ctx, d, fpath)
+	}
 	f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, c.RegularMode)
 	if err != nil {
 		return nil, err
@@ -983,17 +1194,22 @@ func (c *Client) readBlobStreamed(ctx context.Context, d digest.Digest, offset,
 		// Do not download empty blobs.
 		return stats, nil
 	}
+	if stats, ok, err := c.readLocal(ctx, d, offset, limit, w); ok {
+		return stats, err
+	}
 	sz := d.Size - offset
 	if limit > 0 && limit < sz {
 		sz = limit
 	}
 	wt := newWriteTracker(w)
 	defer func() { stats.LogicalMoved = wt.n }()
+	var rscName string
 	closure := func() (err error) {
-		name, wc, done, e := c.maybeCompressReadBlob(d, wt)
+		name, wc, done, e := c.maybeCompressReadBlob(ctx, d, wt)
 		if e != nil {
 			return e
 		}
+		rscName = name
 
 		defer func() {
 			errC := wc.Close()
@@ -1016,11 +1232,11 @@ func (c *Client) readBlobStreamed(ctx context.Context, d digest.Digest, offset,
 		return nil
 	}
 	// Only retry on transient backend issues.
-	if err := c.Retrier.Do(ctx, closure); err != nil {
-		return stats, err
+	if err := c.retry(ctx, closure); err != nil {
+		return stats, newOpError("Read", d, rscName, err)
 	}
 	if wt.n != sz {
-		return stats, fmt.Errorf("partial read of digest %s returned %d bytes", wt.dg, sz)
+		return stats, newOpError("Read", d, rscName, fmt.Errorf("partial read of digest %s returned %d bytes", wt.dg, sz))
 	}
 
 	// Incomplete reads only, since we can't reliably calculate hash without the full blob
@@ -1035,7 +1251,13 @@ func (c *Client) readBlobStreamed(ctx context.Context, d digest.Digest, offset,
 		}
 		close(wt.ready)
 		if wt.dg != d {
-			return stats, fmt.Errorf("calculated digest %s != expected digest %s", wt.dg, d)
+			return stats, &OpError{
+				Method:       "Read",
+				Digest:       d,
+				ResourceName: rscName,
+				Code:         codes.DataLoss,
+				Err:          fmt.Errorf("calculated digest %s != expected digest %s", wt.dg, d),
+			}
 		}
 	}
 
@@ -1053,8 +1275,10 @@ func (c *Client) ReadProto(ctx context.Context, d digest.Digest, msg proto.Messa
 }
 
 // MissingBlobs queries the CAS to determine if it has the listed blobs. It returns a list of the
-// missing blobs.
+// missing blobs. Blobs known to be present by the PresenceCache, if any, are not queried.
 func (c *Client) MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest.Digest, error) {
+	ds = c.filterPresent(ctx, ds)
+	queried := ds
 	var batches [][]digest.Digest
 	var missing []digest.Digest
 	var resultMutex sync.Mutex
@@ -1069,10 +1293,10 @@ func (c *Client) MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest
 			batch = append(batch, ds[i])
 		}
 		ds = ds[batchSize:]
-		LogContextInfof(ctx, log.Level(3), "Created query batch of %d blobs", len(batch))
+		LogContextInfof(ctx, 3, "Created query batch of %d blobs", len(batch))
 		batches = append(batches, batch)
 	}
-	LogContextInfof(ctx, log.Level(3), "%d query batches created", len(batches))
+	LogContextInfof(ctx, 3, "%d query batches created", len(batches))
 
 	eg, eCtx := errgroup.WithContext(ctx)
 	for i, batch := range batches {
@@ -1083,7 +1307,7 @@ func (c *Client) MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest
 			}
 			defer c.casUploaders.Release(1)
 			if i%logInterval == 0 {
-				LogContextInfof(ctx, log.Level(3), "%d missing batches left to query", len(batches)-i)
+				LogContextInfof(ctx, 3, "%d missing batches left to query", len(batches)-i)
 			}
 			var batchPb []*repb.Digest
 			for _, dg := range batch {
@@ -1108,32 +1332,41 @@ func (c *Client) MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest
 			return nil
 		})
 	}
-	LogContextInfof(ctx, log.Level(3), "Waiting for remaining query jobs")
+	LogContextInfof(ctx, 3, "Waiting for remaining query jobs")
 	err := eg.Wait()
-	LogContextInfof(ctx, log.Level(3), "Done")
-	return missing, err
+	LogContextInfof(ctx, 3, "Done")
+	if err == nil && c.PresenceCache != nil {
+		isMissing := make(map[digest.Digest]bool, len(missing))
+		for _, d := range missing {
+			isMissing[d] = true
+		}
+		var present []digest.Digest
+		for _, d := range queried {
+			if !isMissing[d] {
+				present = append(present, d)
+			}
+		}
+		c.markPresent(ctx, present)
+	}
+	return missing, newOpError("FindMissingBlobs", digest.Digest{}, "", err)
 }
 
 func (c *Client) resourceNameRead(hash string, sizeBytes int64) string {
-	return fmt.Sprintf("%s/blobs/%s/%d", c.InstanceName, hash, sizeBytes)
+	return digest.NewReadResourceName(c.InstanceName, digest.Digest{Hash: hash, Size: sizeBytes})
 }
 
-// TODO(rubensf): Converge compressor to proto in https://github.com/bazelbuild/remote-apis/pull/168 once
-// that gets merged in.
 func (c *Client) resourceNameCompressedRead(hash string, sizeBytes int64) string {
-	return fmt.Sprintf("%s/compressed-blobs/zstd/%s/%d", c.InstanceName, hash, sizeBytes)
+	return digest.NewCompressedReadResourceName(c.InstanceName, repb.Compressor_ZSTD, digest.Digest{Hash: hash, Size: sizeBytes})
 }
 
 // ResourceNameWrite generates a valid write resource name.
 func (c *Client) ResourceNameWrite(hash string, sizeBytes int64) string {
-	return fmt.Sprintf("%s/uploads/%s/blobs/%s/%d", c.InstanceName, uuid.New(), hash, sizeBytes)
+	return digest.NewWriteResourceName(c.InstanceName, uuid.New(), digest.Digest{Hash: hash, Size: sizeBytes})
 }
 
 // ResourceNameCompressedWrite generates a valid write resource name.
-// TODO(rubensf): Converge compressor to proto in https://github.com/bazelbuild/remote-apis/pull/168 once
-// that gets merged in.
 func (c *Client) ResourceNameCompressedWrite(hash string, sizeBytes int64) string {
-	return fmt.Sprintf("%s/uploads/%s/compressed-blobs/zstd/%s/%d", c.InstanceName, uuid.New(), hash, sizeBytes)
+	return digest.NewCompressedWriteResourceName(c.InstanceName, uuid.New(), repb.Compressor_ZSTD, digest.Digest{Hash: hash, Size: sizeBytes})
 }
 
 // GetDirectoryTree returns the entire directory tree rooted at the given digest (which must target
@@ -1167,7 +1400,7 @@ func (c *Client) GetDirectoryTree(ctx context.Context, d *repb.Digest) (result [
 		}
 		return nil
 	}
-	if err := c.Retrier.Do(ctx, func() error { return c.CallWithTimeout(ctx, "GetTree", closure) }); err != nil {
+	if err := c.retry(ctx, func() error { return c.CallWithTimeout(ctx, "GetTree", closure) }); err != nil {
 		return nil, err
 	}
 	return result, nil
@@ -1179,21 +1412,33 @@ func (c *Client) FlattenActionOutputs(ctx context.Context, ar *repb.ActionResult
 	outs := make(map[string]*TreeOutput)
 	for _, file := range ar.OutputFiles {
 		outs[file.Path] = &TreeOutput{
-			Path:         file.Path,
-			Digest:       digest.NewFromProtoUnvalidated(file.Digest),
-			IsExecutable: file.IsExecutable,
+			Path:           file.Path,
+			Digest:         digest.NewFromProtoUnvalidated(file.Digest),
+			IsExecutable:   file.IsExecutable,
+			NodeProperties: file.NodeProperties,
 		}
 	}
 	for _, sm := range ar.OutputFileSymlinks {
 		outs[sm.Path] = &TreeOutput{
-			Path:          sm.Path,
-			SymlinkTarget: sm.Target,
+			Path:           sm.Path,
+			SymlinkTarget:  sm.Target,
+			NodeProperties: sm.NodeProperties,
 		}
 	}
 	for _, sm := range ar.OutputDirectorySymlinks {
 		outs[sm.Path] = &TreeOutput{
-			Path:          sm.Path,
-			SymlinkTarget: sm.Target,
+			Path:           sm.Path,
+			SymlinkTarget:  sm.Target,
+			NodeProperties: sm.NodeProperties,
+		}
+	}
+	// Servers of REAPI v2.1 and above report all the output symlinks in OutputSymlinks, regardless
+	// of the type of their targets.
+	for _, sm := range ar.OutputSymlinks {
+		outs[sm.Path] = &TreeOutput{
+			Path:           sm.Path,
+			SymlinkTarget:  sm.Target,
+			NodeProperties: sm.NodeProperties,
 		}
 	}
 	for _, dir := range ar.OutputDirectories {
@@ -1212,10 +1457,70 @@ func (c *Client) FlattenActionOutputs(ctx context.Context, ar *repb.ActionResult
 	return outs, nil
 }
 
+// VerifyActionResult checks that every blob referenced by the action result is in the CAS: the
+// output files, stdout and stderr, and the output directory trees along with their files. It
+// returns an *OpError with code NotFound listing the missing blobs as FailedDigests if some are
+// missing, so that callers can treat the result as a cache miss rather than fail halfway through
+// downloading its outputs.
+func (c *Client) VerifyActionResult(ctx context.Context, ar *repb.ActionResult) error {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	var dgs []digest.Digest
+	add := func(dgPb *repb.Digest) {
+		if dgPb != nil {
+			dgs = append(dgs, digest.NewFromProtoUnvalidated(dgPb))
+		}
+	}
+	for _, file := range ar.OutputFiles {
+		add(file.Digest)
+	}
+	add(ar.StdoutDigest)
+	add(ar.StderrDigest)
+	for _, dir := range ar.OutputDirectories {
+		treeDg := digest.NewFromProtoUnvalidated(dir.TreeDigest)
+		t := &repb.Tree{}
+		if _, err := c.ReadProto(ctx, treeDg, t); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return &OpError{Method: "VerifyActionResult", Digest: treeDg, FailedDigests: []digest.Digest{treeDg}, Code: codes.NotFound, Err: err}
+			}
+			return err
+		}
+		for _, d := range append([]*repb.Directory{t.Root}, t.Children...) {
+			for _, file := range d.GetFiles() {
+				add(file.Digest)
+			}
+		}
+	}
+	missing, err := c.MissingBlobs(ctx, dgs)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return &OpError{
+			Method:        "VerifyActionResult",
+			Digest:        missing[0],
+			FailedDigests: missing,
+			Code:          codes.NotFound,
+			Err:           fmt.Errorf("%d blobs referenced by the action result are missing from the CAS", len(missing)),
+		}
+	}
+	return nil
+}
+
 // DownloadDirectory downloads the entire directory of given digest.
 // It returns the number of logical and real bytes downloaded, which may be different from sum
 // of sizes of the files due to dedupping and compression.
 func (c *Client) DownloadDirectory(ctx context.Context, d digest.Digest, outDir string, cache filemetadata.Cache) (map[string]*TreeOutput, *MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
 	dir := &repb.Directory{}
 	stats := &MovedBytesMetadata{}
 
@@ -1243,14 +1548,37 @@ func (c *Client) DownloadDirectory(ctx context.Context, d digest.Digest, outDir
 	return outputs, stats, err
 }
 
+// DownloadOutputs downloads the given outputs, e.g. a subset of those returned by FlattenTree,
+// under outDir. It returns the number of logical and real bytes downloaded, which may be different
+// from sum of sizes of the files due to dedupping and compression.
+func (c *Client) DownloadOutputs(ctx context.Context, outs map[string]*TreeOutput, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.downloadOutputs(ctx, outs, outDir, cache)
+}
+
 // DownloadActionOutputs downloads the output files and directories in the given action result. It returns the amount of downloaded bytes.
 // It returns the number of logical and real bytes downloaded, which may be different from sum
 // of sizes of the files due to dedupping and compression.
+// If the client has an OutputService, the outputs are registered with it instead, and nothing is
+// downloaded.
 func (c *Client) DownloadActionOutputs(ctx context.Context, resPb *repb.ActionResult, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	outs, err := c.FlattenActionOutputs(ctx, resPb)
 	if err != nil {
 		return nil, err
 	}
+	if stats, ok, err := c.registerOutputs(ctx, outs, outDir); ok || err != nil {
+		return stats, err
+	}
 	// Remove the existing output directories before downloading.
 	for _, dir := range resPb.OutputDirectories {
 		if err := os.RemoveAll(filepath.Join(outDir, dir.Path)); err != nil {
@@ -1264,6 +1592,17 @@ func (c *Client) downloadOutputs(ctx context.Context, outs map[string]*TreeOutpu
 	var symlinks, copies []*TreeOutput
 	downloads := make(map[digest.Digest]*TreeOutput)
 	fullStats := &MovedBytesMetadata{}
+	outDir, err := c.WindowsOpts.normalizePath(outDir)
+	if err != nil {
+		return fullStats, err
+	}
+	paths := make([]string, 0, len(outs))
+	for path := range outs {
+		paths = append(paths, path)
+	}
+	if err := c.WindowsOpts.checkCaseCollisions(paths); err != nil {
+		return fullStats, err
+	}
 	for _, out := range outs {
 		path := filepath.Join(outDir, out.Path)
 		if out.IsEmptyDirectory {
@@ -1320,7 +1659,7 @@ func (c *Client) downloadOutputs(ctx context.Context, outs map[string]*TreeOutpu
 		}
 	}
 	for _, out := range symlinks {
-		if err := os.Symlink(out.SymlinkTarget, filepath.Join(outDir, out.Path)); err != nil {
+		if err := os.Symlink(filepath.FromSlash(out.SymlinkTarget), filepath.Join(outDir, out.Path)); err != nil {
 			return fullStats, err
 		}
 	}
@@ -1390,18 +1729,18 @@ func (c *Client) downloadProcessor() {
 	}
 }
 
-func afterDownload(batch []digest.Digest, reqs map[digest.Digest][]*downloadRequest, bytesMoved map[digest.Digest]*MovedBytesMetadata, err error) {
+func afterDownload(ctx context.Context, batch []digest.Digest, reqs map[digest.Digest][]*downloadRequest, bytesMoved map[digest.Digest]*MovedBytesMetadata, err error) {
 	if err != nil {
-		log.Errorf("Error downloading %v: %v", batch[0], err)
+		logger.Errorf(ctx, "Error downloading %v: %v", batch[0], err)
 	}
 	for _, dg := range batch {
 		rs, ok := reqs[dg]
 		if !ok {
-			log.Errorf("Precondition failed: download request not found in input %v.", dg)
+			logger.Errorf(ctx, "Precondition failed: download request not found in input %v.", dg)
 		}
 		stats, ok := bytesMoved[dg]
 		if !ok {
-			log.Errorf("Internal tool error - matching map entry")
+			logger.Errorf(ctx, "Internal tool error - matching map entry")
 			continue
 		}
 		// If there's no real bytes moved it likely means there was an error moving these.
@@ -1424,10 +1763,10 @@ func afterDownload(batch []digest.Digest, reqs map[digest.Digest][]*downloadRequ
 }
 
 func (c *Client) downloadBatch(ctx context.Context, batch []digest.Digest, reqs map[digest.Digest][]*downloadRequest) {
-	LogContextInfof(ctx, log.Level(3), "Downloading batch of %d files", len(batch))
+	LogContextInfof(ctx, 3, "Downloading batch of %d files", len(batch))
 	bchMap, err := c.BatchDownloadBlobs(ctx, batch)
 	if err != nil {
-		afterDownload(batch, reqs, map[digest.Digest]*MovedBytesMetadata{}, err)
+		afterDownload(ctx, batch, reqs, map[digest.Digest]*MovedBytesMetadata{}, err)
 		return
 	}
 	for _, dg := range batch {
@@ -1469,7 +1808,7 @@ func (c *Client) downloadSingle(ctx context.Context, dg digest.Digest, reqs map[
 	// We cannot release the lock after each individual file copy, because
 	// the caller might move the file, and we don't have the contents in memory.
 	bytesMoved := map[digest.Digest]*MovedBytesMetadata{}
-	defer func() { afterDownload([]digest.Digest{dg}, reqs, bytesMoved, err) }()
+	defer func() { afterDownload(ctx, []digest.Digest{dg}, reqs, bytesMoved, err) }()
 	rs := reqs[dg]
 	if len(rs) < 1 {
 		return fmt.Errorf("Failed precondition: cannot find %v in reqs map", dg)
@@ -1477,7 +1816,7 @@ func (c *Client) downloadSingle(ctx context.Context, dg digest.Digest, reqs map[
 	r := rs[0]
 	rs = rs[1:]
 	path := filepath.Join(r.outDir, r.output.Path)
-	LogContextInfof(ctx, log.Level(3), "Downloading single file with digest %s to %s", r.output.Digest, path)
+	LogContextInfof(ctx, 3, "Downloading single file with digest %s to %s", r.output.Digest, path)
 	stats, err := c.ReadBlobToFile(ctx, r.output.Digest, path)
 	if err != nil {
 		return err
@@ -1541,18 +1880,18 @@ func (c *Client) download(data []*downloadRequest) {
 		ctx, err = ContextWithMetadata(context.Background(), unifiedMeta)
 	}
 	if err != nil {
-		afterDownload(dgs, reqs, map[digest.Digest]*MovedBytesMetadata{}, err)
+		afterDownload(ctx, dgs, reqs, map[digest.Digest]*MovedBytesMetadata{}, err)
 		return
 	}
 
-	LogContextInfof(ctx, log.Level(2), "%d digests to download (%d reqs)", len(dgs), len(reqs))
+	LogContextInfof(ctx, 2, "%d digests to download (%d reqs)", len(dgs), len(reqs))
 	var batches [][]digest.Digest
 	if c.useBatchOps {
 		batches = c.makeBatches(ctx, dgs, !bool(c.UtilizeLocality))
 	} else {
-		LogContextInfof(ctx, log.Level(2), "Downloading them individually")
+		LogContextInfof(ctx, 2, "Downloading them individually")
 		for i := range dgs {
-			LogContextInfof(ctx, log.Level(3), "Creating single batch of blob %s", dgs[i])
+			LogContextInfof(ctx, 3, "Creating single batch of blob %s", dgs[i])
 			batches = append(batches, dgs[i:i+1])
 		}
 	}
@@ -1564,7 +1903,7 @@ func (c *Client) download(data []*downloadRequest) {
 				defer c.casDownloaders.Release(1)
 			}
 			if i%logInterval == 0 {
-				LogContextInfof(ctx, log.Level(2), "%d batches left to download", len(batches)-i)
+				LogContextInfof(ctx, 2, "%d batches left to download", len(batches)-i)
 			}
 			if len(batch) > 1 {
 				c.downloadBatch(ctx, batch, reqs)
@@ -1614,14 +1953,14 @@ func (c *Client) downloadNonUnified(ctx context.Context, outDir string, outputs
 		}
 	}
 
-	LogContextInfof(ctx, log.Level(2), "%d items to download", len(dgs))
+	LogContextInfof(ctx, 2, "%d items to download", len(dgs))
 	var batches [][]digest.Digest
 	if c.useBatchOps {
 		batches = c.makeBatches(ctx, dgs, !bool(c.UtilizeLocality))
 	} else {
-		LogContextInfof(ctx, log.Level(2), "Downloading them individually")
+		LogContextInfof(ctx, 2, "Downloading them individually")
 		for i := range dgs {
-			LogContextInfof(ctx, log.Level(3), "Creating single batch of blob %s", dgs[i])
+			LogContextInfof(ctx, 3, "Creating single batch of blob %s", dgs[i])
 			batches = append(batches, dgs[i:i+1])
 		}
 	}
@@ -1635,10 +1974,10 @@ func (c *Client) downloadNonUnified(ctx context.Context, outDir string, outputs
 			}
 			defer c.casDownloaders.Release(1)
 			if i%logInterval == 0 {
-				LogContextInfof(ctx, log.Level(2), "%d batches left to download", len(batches)-i)
+				LogContextInfof(ctx, 2, "%d batches left to download", len(batches)-i)
 			}
 			if len(batch) > 1 {
-				LogContextInfof(ctx, log.Level(3), "Downloading batch of %d files", len(batch))
+				LogContextInfof(ctx, 3, "Downloading batch of %d files", len(batch))
 				bchMap, err := c.BatchDownloadBlobs(eCtx, batch)
 				for _, dg := range batch {
 					data := bchMap[dg]
@@ -1661,7 +2000,7 @@ func (c *Client) downloadNonUnified(ctx context.Context, outDir string, outputs
 			} else {
 				out := outputs[batch[0]]
 				path := filepath.Join(outDir, out.Path)
-				LogContextInfof(ctx, log.Level(3), "Downloading single file with digest %s to %s", out.Digest, path)
+				LogContextInfof(ctx, 3, "Downloading single file with digest %s to %s", out.Digest, path)
 				stats, err := c.ReadBlobToFile(ctx, out.Digest, path)
 				if err != nil {
 					return err
@@ -1682,20 +2021,31 @@ func (c *Client) downloadNonUnified(ctx context.Context, outDir string, outputs
 		})
 	}
 
-	LogContextInfof(ctx, log.Level(3), "Waiting for remaining jobs")
+	LogContextInfof(ctx, 3, "Waiting for remaining jobs")
 	err := eg.Wait()
-	LogContextInfof(ctx, log.Level(3), "Done")
+	LogContextInfof(ctx, 3, "Done")
 	return fullStats, err
 }
 
 // DownloadFiles downloads the output files under |outDir|.
 // It returns the number of logical and real bytes downloaded, which may be different from sum
-// of sizes of the files due to dedupping and compression.
+// of sizes of the files due to dedupping and compression. Files found in the LocalCAS are copied
+// from it instead.
 func (c *Client) DownloadFiles(ctx context.Context, outDir string, outputs map[digest.Digest]*TreeOutput) (*MovedBytesMetadata, error) {
-	stats := &MovedBytesMetadata{}
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 
+	outputs, stats, err := c.downloadLocal(ctx, outDir, outputs)
+	if err != nil {
+		return stats, err
+	}
 	if !c.UnifiedDownloads {
-		return c.downloadNonUnified(ctx, outDir, outputs)
+		remoteStats, err := c.downloadNonUnified(ctx, outDir, outputs)
+		stats.addFrom(remoteStats)
+		return stats, err
 	}
 	count := len(outputs)
 	if count == 0 {
@@ -1717,7 +2067,7 @@ func (c *Client) DownloadFiles(ctx context.Context, outDir string, outputs map[d
 		}
 		select {
 		case <-ctx.Done():
-			LogContextInfof(ctx, log.Level(2), "Download canceled")
+			LogContextInfof(ctx, 2, "Download canceled")
 			return stats, ctx.Err()
 		case c.casDownloadRequests <- r:
 			continue
@@ -1728,7 +2078,7 @@ func (c *Client) DownloadFiles(ctx context.Context, outDir string, outputs map[d
 	for count > 0 {
 		select {
 		case <-ctx.Done():
-			LogContextInfof(ctx, log.Level(2), "Download canceled")
+			LogContextInfof(ctx, 2, "Download canceled")
 			return stats, ctx.Err()
 		case resp := <-wait:
 			if resp.err != nil {
@@ -1741,12 +2091,19 @@ func (c *Client) DownloadFiles(ctx context.Context, outDir string, outputs map[d
 	return stats, nil
 }
 
-func (c *Client) shouldCompress(sizeBytes int64) bool {
-	return int64(c.CompressedBytestreamThreshold) >= 0 && int64(c.CompressedBytestreamThreshold) <= sizeBytes
+func (c *Client) shouldCompress(ctx context.Context, sizeBytes int64) bool {
+	if o := CallOverridesFromContext(ctx); o.Compression != nil {
+		return *o.Compression
+	}
+	if int64(c.CompressedBytestreamThreshold) < 0 || int64(c.CompressedBytestreamThreshold) > sizeBytes {
+		return false
+	}
+	// Unless the capabilities were not fetched, blobs are only compressed if the CAS supports it.
+	return c.serverCaps == nil || c.SupportsCompressor(repb.Compressor_ZSTD)
 }
 
-func (c *Client) writeRscName(dg digest.Digest) string {
-	if c.shouldCompress(dg.Size) {
+func (c *Client) writeRscName(dg digest.Digest, compressed bool) string {
+	if compressed {
 		return c.ResourceNameCompressedWrite(dg.Hash, dg.Size)
 	}
 	return c.ResourceNameWrite(dg.Hash, dg.Size)
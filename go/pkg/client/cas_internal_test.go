@@ -1,7 +1,20 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 )
 
 func TestCapToLimit(t *testing.T) {
@@ -108,3 +121,134 @@ func TestCapToLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldCompressCapabilities(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		caps *repb.ServerCapabilities
+		want bool
+	}{
+		{
+			name: "capabilities not fetched",
+			want: true,
+		},
+		{
+			name: "zstd supported",
+			caps: &repb.ServerCapabilities{CacheCapabilities: &repb.CacheCapabilities{SupportedCompressors: []repb.Compressor_Value{repb.Compressor_ZSTD}}},
+			want: true,
+		},
+		{
+			name: "zstd not supported",
+			caps: &repb.ServerCapabilities{CacheCapabilities: &repb.CacheCapabilities{}},
+			want: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{CompressedBytestreamThreshold: 10, serverCaps: tc.caps}
+			if got := c.shouldCompress(ctx, 100); got != tc.want {
+				t.Errorf("shouldCompress(100) = %v, want %v", got, tc.want)
+			}
+			if c.shouldCompress(ctx, 5) {
+				t.Errorf("shouldCompress(5) = true, want false below the threshold")
+			}
+		})
+	}
+}
+
+func TestCheckCapabilitiesMaxBatchSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverSize int64
+		want       MaxBatchSize
+	}{
+		{name: "server limit", serverSize: 500, want: 500},
+		{name: "no server limit", serverSize: 0, want: 10000 - maxBatchOverhead},
+		{name: "above the message size", serverSize: 20000, want: 10000 - maxBatchOverhead},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{
+				MaxMessageSize: 10000,
+				serverCaps: &repb.ServerCapabilities{CacheCapabilities: &repb.CacheCapabilities{
+					DigestFunctions:        []repb.DigestFunction_Value{digest.GetDigestFunction()},
+					MaxBatchTotalSizeBytes: tc.serverSize,
+				}},
+			}
+			if err := c.CheckCapabilities(context.Background()); err != nil {
+				t.Fatalf("CheckCapabilities() failed: %v", err)
+			}
+			if c.MaxBatchSize != tc.want {
+				t.Errorf("CheckCapabilities() set MaxBatchSize to %d, want %d", c.MaxBatchSize, tc.want)
+			}
+		})
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	const hash = "a5b6"
+	for _, instance := range []string{"", "instance", "projects/p/instances/i"} {
+		c := &Client{InstanceName: instance}
+		if got, want := c.resourceNameRead(hash, 12), instance+"/blobs/a5b6/12"; got != want {
+			t.Errorf("resourceNameRead() with instance %q = %q, want %q", instance, got, want)
+		}
+		if got, want := c.resourceNameCompressedRead(hash, 12), instance+"/compressed-blobs/zstd/a5b6/12"; got != want {
+			t.Errorf("resourceNameCompressedRead() with instance %q = %q, want %q", instance, got, want)
+		}
+		for got, suffix := range map[string]string{
+			c.ResourceNameWrite(hash, 12):           "/blobs/a5b6/12",
+			c.ResourceNameCompressedWrite(hash, 12): "/compressed-blobs/zstd/a5b6/12",
+		} {
+			if !strings.HasPrefix(got, instance+"/uploads/") || !strings.HasSuffix(got, suffix) {
+				t.Errorf("write resource name with instance %q = %q, want %q/uploads/<uuid>%s", instance, got, instance, suffix)
+			}
+		}
+	}
+}
+
+func TestBatchMixedFailures(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	blobs := map[digest.Digest][]byte{}
+	var dgs []digest.Digest
+	for _, b := range []string{"a", "b", "c", "d"} {
+		dg := digest.NewFromBlob([]byte(b))
+		blobs[dg] = []byte(b)
+		dgs = append(dgs, dg)
+	}
+	invalid := dgs[2]
+	// All the blobs fail, only the invalid one with an error which is not retried.
+	cas := &failingCAS{codes: map[digest.Digest]codes.Code{invalid: codes.InvalidArgument}}
+	srv := grpc.NewServer()
+	regrpc.RegisterContentAddressableStorageServer(srv, cas)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	c, err := New(ctx, instance, WithService(l.Addr().String()), WithNoSecurity(), WithStartupCapabilities(false))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+	sortDgs := cmpopts.SortSlices(func(a, b digest.Digest) bool { return a.Hash < b.Hash })
+	check := func(method string, err error) {
+		t.Helper()
+		var oe *OpError
+		if !errors.As(err, &oe) {
+			t.Fatalf("%s gave %v, want *OpError", method, err)
+		}
+		if oe.Code != codes.InvalidArgument || oe.Digest != invalid {
+			t.Errorf("%s gave %+v, want the InvalidArgument failure of %v", method, oe, invalid)
+		}
+		if diff := cmp.Diff(dgs, oe.FailedDigests, sortDgs); diff != "" {
+			t.Errorf("%s returned diff in failed digests. (-want +got)\n%s", method, diff)
+		}
+	}
+	check("BatchWriteBlobs", c.BatchWriteBlobs(ctx, blobs))
+	_, err = c.BatchDownloadBlobs(ctx, dgs)
+	check("BatchDownloadBlobs", err)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"net"
@@ -279,10 +280,57 @@ func TestRead(t *testing.T) {
 			if tc.compress && len(tc.fake.Blob) > 0 && stats.LogicalMoved == stats.RealMoved {
 				t.Errorf("c.ReadBlob(ctx, digest) = %v - compression on but same real and logical bytes", stats)
 			}
+
+			buf := &bytes.Buffer{}
+			stats, err = c.ReadBlobRangeToWriter(ctx, digest.NewFromBlob(tc.fake.Blob), tc.offset, tc.limit, buf)
+			if err != nil {
+				t.Errorf("c.ReadBlobRangeToWriter(ctx, digest, %d, %d, w) gave error %s, want nil", tc.offset, tc.limit, err)
+			}
+			if !bytes.Equal(want, buf.Bytes()) {
+				t.Errorf("c.ReadBlobRangeToWriter(ctx, digest, %d, %d, w) gave diff: want %v, got %v", tc.offset, tc.limit, want, buf.Bytes())
+			}
+			if int64(buf.Len()) != stats.LogicalMoved {
+				t.Errorf("c.ReadBlobRangeToWriter(ctx, digest, %d, %d, w) = %v - logical bytes moved different than len of blob written", tc.offset, tc.limit, stats.LogicalMoved)
+			}
 		})
 	}
 }
 
+func TestReadBlobToWriter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	client.ChunkMaxSize(1000).Apply(c)
+	blob := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(blob)
+	dg := e.Server.CAS.Put(blob)
+
+	// Stream the blob through a pipe, so that it is never entirely held by the writer.
+	pr, pw := io.Pipe()
+	eg := &errgroup.Group{}
+	eg.Go(func() error {
+		_, err := c.ReadBlobToWriter(ctx, dg, pw)
+		pw.CloseWithError(err)
+		return err
+	})
+	got, err := digest.NewFromReader(pr)
+	if err != nil {
+		t.Errorf("digest.NewFromReader() gave error %v, want nil", err)
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatalf("c.ReadBlobToWriter(ctx, %v, w) gave error %v, want nil", dg, err)
+	}
+	if got != dg {
+		t.Errorf("c.ReadBlobToWriter(ctx, %v, w) wrote a blob with digest %v", dg, got)
+	}
+
+	if _, err := c.ReadBlobRangeToWriter(ctx, dg, dg.Size+1, 0, ioutil.Discard); err == nil {
+		t.Errorf("c.ReadBlobRangeToWriter(ctx, %v, %d, 0, w) succeeded, want error", dg, dg.Size+1)
+	}
+}
+
 func TestWrite(t *testing.T) {
 	t.Parallel()
 	type testcase struct {
@@ -576,6 +624,48 @@ func TestUploadConcurrentBatch(t *testing.T) {
 	}
 }
 
+func TestBatchSizeShrinksOnResourceExhausted(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	fake := e.Server.CAS
+	fake.MessageSizeLimit = 1000
+	c := e.Client.GrpcClient
+	var input []*uploadinfo.Entry
+	var dgs []digest.Digest
+	for i := 0; i < 20; i++ {
+		ue := uploadinfo.EntryFromBlob(bytes.Repeat([]byte{byte(i)}, 100))
+		input = append(input, ue)
+		dgs = append(dgs, ue.Digest)
+	}
+	if _, _, err := c.UploadIfMissing(ctx, input...); err != nil {
+		t.Fatalf("c.UploadIfMissing(ctx, input) gave error %v, expected nil", err)
+	}
+	for _, ue := range input {
+		if _, ok := fake.Get(ue.Digest); !ok {
+			t.Errorf("blob %v was not uploaded", ue.Digest)
+		}
+	}
+	got, err := c.BatchDownloadBlobs(ctx, dgs)
+	if err != nil {
+		t.Fatalf("c.BatchDownloadBlobs(ctx, dgs) gave error %v, expected nil", err)
+	}
+	if len(got) != len(dgs) {
+		t.Errorf("c.BatchDownloadBlobs(ctx, dgs) returned %d blobs, want %d", len(got), len(dgs))
+	}
+
+	// The batches are now small enough to be accepted at once.
+	fake.Clear()
+	reqs := fake.BatchReqs()
+	if _, _, err := c.UploadIfMissing(ctx, input...); err != nil {
+		t.Fatalf("c.UploadIfMissing(ctx, input) gave error %v, expected nil", err)
+	}
+	if n := fake.BatchReqs() - reqs; n > 6 {
+		t.Errorf("%d requests were made to BatchUpdateBlobs, wanted at most 6", n)
+	}
+}
+
 func TestUploadCancel(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -856,6 +946,78 @@ func TestUpload(t *testing.T) {
 	}
 }
 
+func TestWriteAdaptiveChunking(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	client.AdaptiveChunking(true).Apply(c)
+	client.ChunkMaxSize(1000).Apply(c)
+
+	for i := 0; i < 3; i++ {
+		blob := bytes.Repeat([]byte{byte(i)}, 100000)
+		dg, err := c.WriteBlob(ctx, blob)
+		if err != nil {
+			t.Fatalf("WriteBlob() failed: %v", err)
+		}
+		if got, ok := e.Server.CAS.Get(dg); !ok || !bytes.Equal(got, blob) {
+			t.Errorf("blob %v was not written", dg)
+		}
+	}
+}
+
+func TestWriteResumesAfterDisconnect(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	blob := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(blob)
+	path := filepath.Join(t.TempDir(), "blob")
+	if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile(%v) failed: %v", path, err)
+	}
+	dg := digest.NewFromBlob(blob)
+	tests := []struct {
+		name       string
+		ue         *uploadinfo.Entry
+		compressed bool
+	}{
+		{name: "blob", ue: uploadinfo.EntryFromBlob(blob)},
+		{name: "file", ue: uploadinfo.EntryFromFile(dg, path)},
+		{name: "compressed blob", ue: uploadinfo.EntryFromBlob(blob), compressed: true},
+		{name: "compressed file", ue: uploadinfo.EntryFromFile(dg, path), compressed: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			fake := e.Server.CAS
+			fake.DisconnectAfter = 3000
+			c := e.Client.GrpcClient
+			client.MaxBatchSize(1000).Apply(c)
+			client.ChunkMaxSize(1000).Apply(c)
+			if tc.compressed {
+				client.CompressedBytestreamThreshold(0).Apply(c)
+			}
+
+			if _, _, err := c.UploadIfMissing(ctx, tc.ue); err != nil {
+				t.Fatalf("c.UploadIfMissing(ctx, %v) failed: %v", dg, err)
+			}
+			if got, ok := fake.Get(dg); !ok || !bytes.Equal(got, blob) {
+				t.Errorf("blob %v was not written", dg)
+			}
+			if got := fake.ResumedWrites(); got != 1 {
+				t.Errorf("%d writes were resumed, want 1", got)
+			}
+			if got := fake.QueryWriteStatusReqs(); got != 1 {
+				t.Errorf("%d QueryWriteStatus requests were received, want 1", got)
+			}
+		})
+	}
+}
+
 func TestWriteBlobsBatching(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -995,6 +1157,9 @@ func TestFlattenActionOutputs(t *testing.T) {
 			&repb.OutputSymlink{Path: "x/bar", Target: "../dir/a/bar"}},
 		OutputDirectorySymlinks: []*repb.OutputSymlink{
 			&repb.OutputSymlink{Path: "x/a", Target: "../dir/a"}},
+		OutputSymlinks: []*repb.OutputSymlink{
+			&repb.OutputSymlink{Path: "x/a", Target: "../dir/a"},
+			&repb.OutputSymlink{Path: "x/foo", Target: "../foo"}},
 		OutputDirectories: []*repb.OutputDirectory{
 			&repb.OutputDirectory{Path: "dir", TreeDigest: treeDigest.ToProto()},
 			&repb.OutputDirectory{Path: "dir2", TreeDigest: treeADigest.ToProto()},
@@ -1013,6 +1178,7 @@ func TestFlattenActionOutputs(t *testing.T) {
 		"foo":         &client.TreeOutput{Digest: fooDigest},
 		"x/a":         &client.TreeOutput{SymlinkTarget: "../dir/a"},
 		"x/bar":       &client.TreeOutput{SymlinkTarget: "../dir/a/bar"},
+		"x/foo":       &client.TreeOutput{SymlinkTarget: "../foo"},
 	}
 	if len(outputs) != len(wantOutputs) {
 		t.Errorf("FlattenActionOutputs gave wrong number of outputs: want %d, got %d", len(wantOutputs), len(outputs))
@@ -1037,6 +1203,49 @@ func TestFlattenActionOutputs(t *testing.T) {
 	}
 }
 
+func TestVerifyActionResult(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	fake := e.Server.CAS
+	c := e.Client.GrpcClient
+
+	fooDigest := fake.Put([]byte("foo"))
+	barDigest := digest.NewFromBlob([]byte("bar"))
+	stdoutDigest := fake.Put([]byte("stdout"))
+	dir := &repb.Directory{
+		Files: []*repb.FileNode{{Name: "bar", Digest: barDigest.ToProto()}},
+	}
+	treeBlob, err := proto.Marshal(&repb.Tree{Root: dir})
+	if err != nil {
+		t.Fatalf("failed marshalling Tree: %s", err)
+	}
+	treeDigest := fake.Put(treeBlob)
+	ar := &repb.ActionResult{
+		OutputFiles:       []*repb.OutputFile{{Path: "foo", Digest: fooDigest.ToProto()}},
+		OutputDirectories: []*repb.OutputDirectory{{Path: "dir", TreeDigest: treeDigest.ToProto()}},
+		StdoutDigest:      stdoutDigest.ToProto(),
+	}
+
+	err = c.VerifyActionResult(ctx, ar)
+	var oe *client.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("VerifyActionResult() = %v, want *client.OpError", err)
+	}
+	if oe.Code != codes.NotFound {
+		t.Errorf("VerifyActionResult() code = %v, want %v", oe.Code, codes.NotFound)
+	}
+	if diff := cmp.Diff([]digest.Digest{barDigest}, oe.FailedDigests); diff != "" {
+		t.Errorf("VerifyActionResult() gave FailedDigests diff (-want +got):\n%s", diff)
+	}
+
+	fake.Put([]byte("bar"))
+	if err := c.VerifyActionResult(ctx, ar); err != nil {
+		t.Errorf("VerifyActionResult() = %v, want nil", err)
+	}
+}
+
 func TestDownloadActionOutputs(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// MinAdaptiveChunkSize is the smallest chunk size chosen by AdaptiveChunking.
+	MinAdaptiveChunkSize = 64 * 1024
+	// MaxAdaptiveChunkSize is the largest chunk size chosen by AdaptiveChunking. It leaves room for
+	// the request headers under the default 4MiB message size limit of gRPC servers.
+	MaxAdaptiveChunkSize = 3 * 1024 * 1024
+
+	// chunkTuningWeight is the weight of new samples in the moving averages of the chunkTuner.
+	chunkTuningWeight = 0.2
+	// minChunkPenalty bounds how much write failures shrink the chunks.
+	minChunkPenalty = 1.0 / 16
+)
+
+// AdaptiveChunking makes ByteStream writes tune their chunk size to the connection, rather than
+// always use ChunkMaxSize: chunks are about the bandwidth-delay product of the connection, as
+// observed from the throughput and round trip time of previous writes, so that they are large on
+// fat pipes, and they shrink when writes fail, so that they are small on lossy links. The chunk
+// size stays between MinAdaptiveChunkSize and MaxAdaptiveChunkSize, and is ChunkMaxSize until
+// enough writes were observed.
+type AdaptiveChunking bool
+
+// Apply sets the client's AdaptiveChunking.
+func (a AdaptiveChunking) Apply(c *Client) {
+	c.AdaptiveChunking = a
+}
+
+// chunkTuner estimates the chunk size suited to a connection from the writes made on it. The zero
+// value is ready to use.
+type chunkTuner struct {
+	mu sync.Mutex
+	// throughput is the moving average of the write throughput, in bytes per second.
+	throughput float64
+	// rtt is the moving average of the time the server takes to acknowledge a write.
+	rtt time.Duration
+	// penalty shrinks the chunks after write failures. It is 1 without failures.
+	penalty float64
+}
+
+// chunkSize returns the chunk size for the next write, or def if there were not enough writes to
+// estimate it.
+func (t *chunkTuner) chunkSize(def int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.throughput == 0 || t.rtt == 0 {
+		return def
+	}
+	size := int(t.throughput * t.rtt.Seconds() * t.penaltyLocked())
+	switch {
+	case size < MinAdaptiveChunkSize:
+		return MinAdaptiveChunkSize
+	case size > MaxAdaptiveChunkSize:
+		return MaxAdaptiveChunkSize
+	}
+	return size
+}
+
+func (t *chunkTuner) penaltyLocked() float64 {
+	if t.penalty == 0 {
+		return 1
+	}
+	return t.penalty
+}
+
+// recordWrite records a successful write of n bytes in chunks chunks, which took elapsed overall,
+// of which ack waiting for the server to acknowledge the last chunk.
+func (t *chunkTuner) recordWrite(n int64, chunks int, elapsed, ack time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ack > 0 {
+		t.rtt = time.Duration(movingAverage(float64(t.rtt), float64(ack)))
+	}
+	// Writes of a single chunk are dominated by latency, and would underestimate the throughput.
+	if chunks > 1 && elapsed > ack {
+		t.throughput = movingAverage(t.throughput, float64(n)/(elapsed-ack).Seconds())
+	}
+	if p := t.penaltyLocked() * 1.25; p < 1 {
+		t.penalty = p
+	} else {
+		t.penalty = 1
+	}
+}
+
+// recordFailure records a failed write, which halves the next chunks.
+func (t *chunkTuner) recordFailure() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p := t.penaltyLocked() / 2; p > minChunkPenalty {
+		t.penalty = p
+	} else {
+		t.penalty = minChunkPenalty
+	}
+}
+
+func movingAverage(avg, sample float64) float64 {
+	if avg == 0 {
+		return sample
+	}
+	return (1-chunkTuningWeight)*avg + chunkTuningWeight*sample
+}
+
+// tuneChunks starts a ByteStream write of the client, and returns the function to call with its
+// outcome. If AdaptiveChunking is enabled, the chunk size for the write is set with setSize, and
+// the outcome is recorded by the client's chunkTuner.
+func (c *Client) tuneChunks(ctx context.Context, setSize func(int)) (done func(n int64, chunks int, lastSent time.Time, err error)) {
+	if !c.AdaptiveChunking {
+		return func(int64, int, time.Time, error) {}
+	}
+	size := c.chunkTuner.chunkSize(int(c.ChunkMaxSize))
+	LogContextInfof(ctx, 3, "Writing with chunks of %d bytes", size)
+	setSize(size)
+	start := time.Now()
+	return func(n int64, chunks int, lastSent time.Time, err error) {
+		if err != nil {
+			// Cancellations say nothing about the connection.
+			if ctx.Err() == nil {
+				c.chunkTuner.recordFailure()
+			}
+			return
+		}
+		c.chunkTuner.recordWrite(n, chunks, time.Since(start), time.Since(lastSent))
+	}
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChunkTuner(t *testing.T) {
+	tr := &chunkTuner{}
+	if got := tr.chunkSize(1000); got != 1000 {
+		t.Errorf("chunkSize() without writes = %d, want the default 1000", got)
+	}
+	// A single chunk write only estimates the round trip time.
+	tr.recordWrite(100, 1, 20*time.Millisecond, 20*time.Millisecond)
+	if got := tr.chunkSize(1000); got != 1000 {
+		t.Errorf("chunkSize() after a single chunk write = %d, want the default 1000", got)
+	}
+
+	// 10MB/s with a 20ms round trip: the bandwidth-delay product is 200KB.
+	tr.recordWrite(10e6, 10, 1020*time.Millisecond, 20*time.Millisecond)
+	if got, want := tr.chunkSize(1000), 200000; got != want {
+		t.Errorf("chunkSize() = %d, want %d", got, want)
+	}
+
+	tr.recordFailure()
+	if got, want := tr.chunkSize(1000), 100000; got != want {
+		t.Errorf("chunkSize() after a failure = %d, want %d", got, want)
+	}
+	for i := 0; i < 10; i++ {
+		tr.recordFailure()
+	}
+	if got, want := tr.chunkSize(1000), MinAdaptiveChunkSize; got != want {
+		t.Errorf("chunkSize() after many failures = %d, want %d", got, want)
+	}
+
+	fat := &chunkTuner{}
+	fat.recordWrite(1e9, 100, 1100*time.Millisecond, 100*time.Millisecond)
+	if got, want := fat.chunkSize(1000), MaxAdaptiveChunkSize; got != want {
+		t.Errorf("chunkSize() on a fat pipe = %d, want %d", got, want)
+	}
+}
@@ -11,25 +11,29 @@ import (
 	"os"
 	"os/user"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/actas"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/balancer"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/chunker"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/localcas"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/retry"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	configpb "github.com/bazelbuild/remote-apis-sdks/go/pkg/balancer/proto"
 	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
-	log "github.com/golang/glog"
 	emptypb "github.com/golang/protobuf/ptypes/empty"
 	bsgrpc "google.golang.org/genproto/googleapis/bytestream"
 	bspb "google.golang.org/genproto/googleapis/bytestream"
@@ -71,15 +75,30 @@ type Client struct {
 	LegacyExecRootRelativeOutputs LegacyExecRootRelativeOutputs
 	// ChunkMaxSize is maximum chunk size to use for CAS uploads/downloads.
 	ChunkMaxSize ChunkMaxSize
+	// AdaptiveChunking makes CAS uploads tune their chunk size to the connection, starting from
+	// ChunkMaxSize.
+	AdaptiveChunking AdaptiveChunking
 	// CompressedBytestreamThreshold is the threshold in bytes for which blobs are read and written
 	// compressed. Use 0 for all writes being compressed, and a negative number for all operations being
 	// uncompressed. TODO(rubensf): Make sure this will throw an error if the server doesn't support compression,
 	// pending https://github.com/bazelbuild/remote-apis/pull/168 being submitted.
 	CompressedBytestreamThreshold CompressedBytestreamThreshold
+	// CompressionPolicy, if set, tunes the compressor, the level and the blobs compressed in writes.
+	CompressionPolicy *CompressionPolicy
+	// SplitSpliceThreshold is the size in bytes from which blobs are transferred as chunks with
+	// SplitBlob and SpliceBlob, if the server supports them. Use a negative number to disable it.
+	SplitSpliceThreshold SplitSpliceThreshold
+	// ParallelReads, if set, makes ReadBlobToFile read large blobs as concurrent ranged reads.
+	ParallelReads *ParallelReads
 	// MaxBatchDigests is maximum amount of digests to batch in batched operations.
 	MaxBatchDigests MaxBatchDigests
-	// MaxBatchSize is maximum size in bytes of a batch request for batch operations.
+	// MaxBatchSize is maximum size in bytes of a batch request for batch operations. It is set from
+	// the capabilities of the server, if fetched, and lowered for the batches made by the client
+	// when the server rejects some of them with RESOURCE_EXHAUSTED.
 	MaxBatchSize MaxBatchSize
+	// MaxMessageSize is the maximum size in bytes of the gRPC messages accepted by the server. The
+	// MaxBatchSize set from the capabilities of the server is kept below it.
+	MaxMessageSize MaxMessageSize
 	// DirMode is mode used to create directories.
 	DirMode os.FileMode
 	// ExecutableMode is mode used to create executable files.
@@ -101,7 +120,26 @@ type Client struct {
 	// UnifiedDownloadTickDuration specifies how often the unified download daemon flushes the pending requests.
 	UnifiedDownloadTickDuration UnifiedDownloadTickDuration
 	// TreeSymlinkOpts controls how symlinks are handled when constructing a tree.
-	TreeSymlinkOpts     *TreeSymlinkOpts
+	TreeSymlinkOpts *TreeSymlinkOpts
+	// WindowsOpts controls how the semantics of Windows file systems are handled when constructing
+	// trees and downloading outputs. It is nil, disabling the handling, unless running on Windows.
+	WindowsOpts *WindowsOpts
+	// LogStatsOnClose specifies whether the client logs a summary of its Stats when closed.
+	LogStatsOnClose LogStatsOnClose
+	// StatsFile, if set, is the path to which the client writes its BuildStats when closed.
+	StatsFile StatsFile
+	// ExecutionRoutes route executions to other endpoints or instances depending on the platform
+	// properties of the actions.
+	ExecutionRoutes ExecutionRoutes
+	// OutputService, if set, is used by DownloadActionOutputs to register outputs instead of
+	// downloading them.
+	OutputService OutputService
+	// PresenceCache, if set, remembers which blobs are present in the CAS across calls and
+	// processes, to avoid querying them again.
+	PresenceCache *PresenceCache
+	// LocalCAS, if set, is consulted before the remote CAS when reading blobs and downloading files.
+	// It is filled by Prefetchers.
+	LocalCAS            *localcas.Store
 	serverCaps          *repb.ServerCapabilities
 	useBatchOps         UseBatchOps
 	casConcurrency      int64
@@ -112,12 +150,25 @@ type Client struct {
 	casDownloadRequests chan *downloadRequest
 	rpcTimeouts         RPCTimeouts
 	creds               credentials.PerRPCCredentials
+	splitSpliceOff      int32 // Set atomically when the server turns out not to implement split or splice.
+	batchSizeLimit      int64 // If positive, the lowered size of the batches after the server rejected some, set atomically.
+	stats               clientStats
+	buildStats          buildStats
+	chunkTuner          chunkTuner
+	ops                 opTracker
 }
 
 const (
 	// DefaultMaxBatchSize is the maximum size of a batch to upload with BatchWriteBlobs. We set it to slightly
 	// below 4 MB, because that is the limit of a message size in gRPC
-	DefaultMaxBatchSize = 4*1024*1024 - 1024
+	DefaultMaxBatchSize = DefaultMaxMessageSize - maxBatchOverhead
+
+	// DefaultMaxMessageSize is the default maximum size of the messages received by gRPC servers.
+	DefaultMaxMessageSize = 4 * 1024 * 1024
+
+	// maxBatchOverhead is the room left in a gRPC message for the fields of a batch request other
+	// than the blobs.
+	maxBatchOverhead = 1024
 
 	// DefaultMaxBatchDigests is a suggested approximate limit based on current RBE implementation.
 	// Above that BatchUpdateBlobs calls start to exceed a typical minute timeout.
@@ -138,6 +189,14 @@ func (c *Client) Close() error {
 	// Close the channels & stop background operations.
 	UnifiedUploads(false).Apply(c)
 	UnifiedDownloads(false).Apply(c)
+	if c.LogStatsOnClose {
+		c.logStats(context.Background())
+	}
+	if c.StatsFile != "" {
+		if err := c.writeStatsFile(); err != nil {
+			logger.Warningf(context.Background(), "Failed to write stats to %v: %v", c.StatsFile, err)
+		}
+	}
 	err := c.Connection.Close()
 	if err != nil {
 		return err
@@ -170,6 +229,15 @@ func (s CompressedBytestreamThreshold) Apply(c *Client) {
 	c.CompressedBytestreamThreshold = s
 }
 
+// SplitSpliceThreshold is the threshold for transferring blobs as chunks.
+// See comment in related field on the Client struct.
+type SplitSpliceThreshold int64
+
+// Apply sets the client's SplitSpliceThreshold.
+func (s SplitSpliceThreshold) Apply(c *Client) {
+	c.SplitSpliceThreshold = s
+}
+
 // UtilizeLocality is to specify whether client downloads files utilizing disk access locality.
 type UtilizeLocality bool
 
@@ -309,6 +377,14 @@ func (s MaxBatchSize) Apply(c *Client) {
 	c.MaxBatchSize = s
 }
 
+// MaxMessageSize is the maximum size in bytes of the gRPC messages accepted by the server.
+type MaxMessageSize int64
+
+// Apply sets the client's maximum message size to s.
+func (s MaxMessageSize) Apply(c *Client) {
+	c.MaxMessageSize = s
+}
+
 // DirMode is mode used to create directories.
 type DirMode os.FileMode
 
@@ -364,6 +440,24 @@ func (cy CASConcurrency) Apply(c *Client) {
 	c.casDownloaders = semaphore.NewWeighted(c.casConcurrency)
 }
 
+// CASUploadConcurrency is the number of simultaneous requests that will be issued for CAS upload
+// operations, overriding CASConcurrency for uploads when applied after it.
+type CASUploadConcurrency int
+
+// Apply sets the concurrency of CAS uploads on a client.
+func (cy CASUploadConcurrency) Apply(c *Client) {
+	c.casUploaders = semaphore.NewWeighted(int64(cy))
+}
+
+// CASDownloadConcurrency is the number of simultaneous requests that will be issued for CAS
+// download operations, overriding CASConcurrency for downloads when applied after it.
+type CASDownloadConcurrency int
+
+// Apply sets the concurrency of CAS downloads on a client.
+func (cy CASDownloadConcurrency) Apply(c *Client) {
+	c.casDownloaders = semaphore.NewWeighted(int64(cy))
+}
+
 // StartupCapabilities controls whether the client should attempt to fetch the remote
 // server capabilities on New. If set to true, some configuration such as MaxBatchSize
 // is set according to the remote server capabilities instead of using the provided values.
@@ -476,6 +570,10 @@ type DialParams struct {
 	//
 	// If this is specified, TLSClientAuthCert must also be specified.
 	TLSClientAuthKey string
+
+	// RemoteHeaders are extra gRPC metadata headers attached to every RPC, for deployments whose
+	// proxies route or authorize requests by custom headers.
+	RemoteHeaders map[string][]string
 }
 
 func createGRPCInterceptor(p DialParams) *balancer.GCPInterceptor {
@@ -583,6 +681,16 @@ func Dial(ctx context.Context, endpoint string, params DialParams) (*grpc.Client
 	opts = append(opts, grpc.WithBalancerName(balancer.Name))
 	opts = append(opts, grpc.WithUnaryInterceptor(grpcInt.GCPUnaryClientInterceptor))
 	opts = append(opts, grpc.WithStreamInterceptor(grpcInt.GCPStreamClientInterceptor))
+	opts = append(opts, grpc.WithChainUnaryInterceptor(overridesUnaryInterceptor))
+	opts = append(opts, grpc.WithChainStreamInterceptor(overridesStreamInterceptor))
+	if len(params.RemoteHeaders) > 0 {
+		md := metadata.MD{}
+		for k, vs := range params.RemoteHeaders {
+			md.Append(k, vs...)
+		}
+		opts = append(opts, grpc.WithChainUnaryInterceptor(headersUnaryInterceptor(md)))
+		opts = append(opts, grpc.WithChainStreamInterceptor(headersStreamInterceptor(md)))
+	}
 
 	conn, err := grpc.Dial(endpoint, opts...)
 	if err != nil {
@@ -591,13 +699,36 @@ func Dial(ctx context.Context, endpoint string, params DialParams) (*grpc.Client
 	return conn, nil
 }
 
+// withHeaders returns ctx with the given headers appended to the outgoing metadata.
+func withHeaders(ctx context.Context, md metadata.MD) context.Context {
+	kv := make([]string, 0, 2*md.Len())
+	for k, vs := range md {
+		for _, v := range vs {
+			kv = append(kv, k, v)
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func headersUnaryInterceptor(md metadata.MD) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withHeaders(ctx, md), method, req, reply, cc, opts...)
+	}
+}
+
+func headersStreamInterceptor(md metadata.MD) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withHeaders(ctx, md), desc, cc, method, opts...)
+	}
+}
+
 // DialRaw dials a remote execution service and returns the grpc connection that is established.
 // TODO(olaola): remove this overload when all clients use Dial.
 func DialRaw(ctx context.Context, params DialParams) (*grpc.ClientConn, error) {
 	if params.Service == "" {
 		return nil, fmt.Errorf("service needs to be specified")
 	}
-	log.Infof("Connecting to remote execution service %s", params.Service)
+	logger.Infof(ctx, "Connecting to remote execution service %s", params.Service)
 	return Dial(ctx, params.Service, params)
 }
 
@@ -605,17 +736,17 @@ func DialRaw(ctx context.Context, params DialParams) (*grpc.ClientConn, error) {
 // functionality.
 func NewClient(ctx context.Context, instanceName string, params DialParams, opts ...Opt) (*Client, error) {
 	if instanceName == "" {
-		log.Warning("Instance name was not specified.")
+		logger.Warningf(ctx, "Instance name was not specified.")
 	}
 	if params.Service == "" {
 		return nil, fmt.Errorf("service needs to be specified")
 	}
-	log.Infof("Connecting to remote execution instance %s", instanceName)
-	log.Infof("Connecting to remote execution service %s", params.Service)
+	logger.Infof(ctx, "Connecting to remote execution instance %s", instanceName)
+	logger.Infof(ctx, "Connecting to remote execution service %s", params.Service)
 	conn, err := Dial(ctx, params.Service, params)
 	casConn := conn
 	if params.CASService != "" && params.CASService != params.Service {
-		log.Infof("Connecting to CAS service %s", params.Service)
+		logger.Infof(ctx, "Connecting to CAS service %s", params.Service)
 		casConn, err = Dial(ctx, params.CASService, params)
 	}
 	if err != nil {
@@ -643,9 +774,11 @@ func NewClientFromConnection(ctx context.Context, instanceName string, conn, cas
 		Connection:                    conn,
 		CASConnection:                 casConn,
 		CompressedBytestreamThreshold: DefaultCompressedBytestreamThreshold,
+		SplitSpliceThreshold:          DefaultSplitSpliceThreshold,
 		ChunkMaxSize:                  chunker.DefaultChunkSize,
 		MaxBatchDigests:               DefaultMaxBatchDigests,
 		MaxBatchSize:                  DefaultMaxBatchSize,
+		MaxMessageSize:                DefaultMaxMessageSize,
 		DirMode:                       DefaultDirMode,
 		ExecutableMode:                DefaultExecutableMode,
 		RegularMode:                   DefaultRegularMode,
@@ -661,7 +794,9 @@ func NewClientFromConnection(ctx context.Context, instanceName string, conn, cas
 		UnifiedDownloadTickDuration:   DefaultUnifiedDownloadTickDuration,
 		UnifiedDownloadBufferSize:     DefaultUnifiedDownloadBufferSize,
 		Retrier:                       RetryTransient(),
+		WindowsOpts:                   defaultWindowsOpts(),
 	}
+	client.buildStats.start = time.Now()
 	for _, o := range opts {
 		o.Apply(client)
 	}
@@ -673,6 +808,9 @@ func NewClientFromConnection(ctx context.Context, instanceName string, conn, cas
 	if client.casConcurrency < 1 {
 		return nil, fmt.Errorf("CASConcurrency should be at least 1")
 	}
+	if err := client.CompressionPolicy.validate(); err != nil {
+		return nil, err
+	}
 	return client, nil
 }
 
@@ -722,15 +860,21 @@ func (c *Client) CallWithTimeout(ctx context.Context, rpcName string, f func(ctx
 			timeout = 0
 		}
 	}
+	if o := CallOverridesFromContext(ctx); o.Timeout > 0 && !longRunningRPCs[rpcName] {
+		timeout = o.Timeout
+	}
 	if timeout == 0 {
-		return f(ctx)
+		err := f(ctx)
+		c.stats.addError(err)
+		return err
 	}
 	childCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	e := f(childCtx)
 	if childCtx.Err() != nil {
-		return childCtx.Err()
+		e = childCtx.Err()
 	}
+	c.stats.addError(e)
 	return e
 }
 
@@ -755,6 +899,12 @@ func (r *Retrier) Do(ctx context.Context, f func() error) error {
 	return retry.WithPolicy(ctx, r.ShouldRetry, r.Backoff, f)
 }
 
+// retriable reports whether err should be retried. It can be called with a nil receiver; in that
+// case no error is retried.
+func (r *Retrier) retriable(err error) bool {
+	return r != nil && r.ShouldRetry(err)
+}
+
 // RetryTransient is a default retry policy for transient status codes.
 func RetryTransient() *Retrier {
 	return &Retrier{
@@ -766,12 +916,18 @@ func RetryTransient() *Retrier {
 // GetActionResult wraps the underlying call with specific client options.
 func (c *Client) GetActionResult(ctx context.Context, req *repb.GetActionResultRequest) (res *repb.ActionResult, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "GetActionResult", func(ctx context.Context) (e error) {
 			res, e = c.actionCache.GetActionResult(ctx, req, opts...)
 			return e
 		})
 	})
+	switch errorCode(err) {
+	case codes.OK:
+		atomic.AddInt64(&c.stats.actionCacheHits, 1)
+	case codes.NotFound:
+		atomic.AddInt64(&c.stats.actionCacheMisses, 1)
+	}
 	if err != nil {
 		return nil, statusWrap(err)
 	}
@@ -781,13 +937,15 @@ func (c *Client) GetActionResult(ctx context.Context, req *repb.GetActionResultR
 // UpdateActionResult wraps the underlying call with specific client options.
 func (c *Client) UpdateActionResult(ctx context.Context, req *repb.UpdateActionResultRequest) (res *repb.ActionResult, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "UpdateActionResult", func(ctx context.Context) (e error) {
 			res, e = c.actionCache.UpdateActionResult(ctx, req, opts...)
 			return e
 		})
 	})
 	if err != nil {
+		// The outputs of the action result may be missing.
+		c.notePresenceErr(ctx, err)
 		return nil, statusWrap(err)
 	}
 	return res, nil
@@ -812,7 +970,7 @@ func (c *Client) Write(ctx context.Context) (res bsgrpc.ByteStream_WriteClient,
 // QueryWriteStatus wraps the underlying call with specific client options.
 func (c *Client) QueryWriteStatus(ctx context.Context, req *bspb.QueryWriteStatusRequest) (res *bspb.QueryWriteStatusResponse, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "QueryWriteStatus", func(ctx context.Context) (e error) {
 			res, e = c.byteStream.QueryWriteStatus(ctx, req, opts...)
 			return e
@@ -827,7 +985,7 @@ func (c *Client) QueryWriteStatus(ctx context.Context, req *bspb.QueryWriteStatu
 // FindMissingBlobs wraps the underlying call with specific client options.
 func (c *Client) FindMissingBlobs(ctx context.Context, req *repb.FindMissingBlobsRequest) (res *repb.FindMissingBlobsResponse, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "FindMissingBlobs", func(ctx context.Context) (e error) {
 			res, e = c.cas.FindMissingBlobs(ctx, req, opts...)
 			return e
@@ -844,7 +1002,7 @@ func (c *Client) FindMissingBlobs(ctx context.Context, req *repb.FindMissingBlob
 // to use BatchWriteBlobs() instead.
 func (c *Client) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRequest) (res *repb.BatchUpdateBlobsResponse, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "BatchUpdateBlobs", func(ctx context.Context) (e error) {
 			res, e = c.cas.BatchUpdateBlobs(ctx, req, opts...)
 			return e
@@ -861,7 +1019,7 @@ func (c *Client) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlob
 // It is recommended to use BatchDownloadBlobs instead.
 func (c *Client) BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsRequest) (res *repb.BatchReadBlobsResponse, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "BatchReadBlobs", func(ctx context.Context) (e error) {
 			res, e = c.cas.BatchReadBlobs(ctx, req, opts...)
 			return e
@@ -886,7 +1044,8 @@ func (c *Client) GetTree(ctx context.Context, req *repb.GetTreeRequest) (res reg
 // RPCs. Prefer using higher-level ExecuteAndWait instead,
 // as it includes retries/timeouts handling.
 func (c *Client) Execute(ctx context.Context, req *repb.ExecuteRequest) (res regrpc.Execution_ExecuteClient, err error) {
-	return c.execution.Execute(ctx, req, c.RPCOpts()...)
+	res, err = c.execution.Execute(ctx, req, c.RPCOpts()...)
+	return res, newOpError("Execute", actionDigest(req), "", err)
 }
 
 // WaitExecution wraps the underlying call with specific client options.
@@ -894,14 +1053,15 @@ func (c *Client) Execute(ctx context.Context, req *repb.ExecuteRequest) (res reg
 // RPCs. Prefer using higher-level ExecuteAndWait instead,
 // as it includes retries/timeouts handling.
 func (c *Client) WaitExecution(ctx context.Context, req *repb.WaitExecutionRequest) (res regrpc.Execution_ExecuteClient, err error) {
-	return c.execution.WaitExecution(ctx, req, c.RPCOpts()...)
+	res, err = c.execution.WaitExecution(ctx, req, c.RPCOpts()...)
+	return res, newOpError("WaitExecution", digest.Digest{}, req.GetName(), err)
 }
 
 // GetBackendCapabilities returns the capabilities for a specific server connection
 // (either the main connection or the CAS connection).
 func (c *Client) GetBackendCapabilities(ctx context.Context, conn *grpc.ClientConn, req *repb.GetCapabilitiesRequest) (res *repb.ServerCapabilities, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "GetCapabilities", func(ctx context.Context) (e error) {
 			res, e = regrpc.NewCapabilitiesClient(conn).GetCapabilities(ctx, req, opts...)
 			return e
@@ -916,7 +1076,7 @@ func (c *Client) GetBackendCapabilities(ctx context.Context, conn *grpc.ClientCo
 // GetOperation wraps the underlying call with specific client options.
 func (c *Client) GetOperation(ctx context.Context, req *oppb.GetOperationRequest) (res *oppb.Operation, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "GetOperation", func(ctx context.Context) (e error) {
 			res, e = c.operations.GetOperation(ctx, req, opts...)
 			return e
@@ -931,7 +1091,7 @@ func (c *Client) GetOperation(ctx context.Context, req *oppb.GetOperationRequest
 // ListOperations wraps the underlying call with specific client options.
 func (c *Client) ListOperations(ctx context.Context, req *oppb.ListOperationsRequest) (res *oppb.ListOperationsResponse, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "ListOperations", func(ctx context.Context) (e error) {
 			res, e = c.operations.ListOperations(ctx, req, opts...)
 			return e
@@ -946,7 +1106,7 @@ func (c *Client) ListOperations(ctx context.Context, req *oppb.ListOperationsReq
 // CancelOperation wraps the underlying call with specific client options.
 func (c *Client) CancelOperation(ctx context.Context, req *oppb.CancelOperationRequest) (res *emptypb.Empty, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "CancelOperation", func(ctx context.Context) (e error) {
 			res, e = c.operations.CancelOperation(ctx, req, opts...)
 			return e
@@ -961,7 +1121,7 @@ func (c *Client) CancelOperation(ctx context.Context, req *oppb.CancelOperationR
 // DeleteOperation wraps the underlying call with specific client options.
 func (c *Client) DeleteOperation(ctx context.Context, req *oppb.DeleteOperationRequest) (res *emptypb.Empty, err error) {
 	opts := c.RPCOpts()
-	err = c.Retrier.Do(ctx, func() (e error) {
+	err = c.retry(ctx, func() (e error) {
 		return c.CallWithTimeout(ctx, "DeleteOperation", func(ctx context.Context) (e error) {
 			res, e = c.operations.DeleteOperation(ctx, req, opts...)
 			return e
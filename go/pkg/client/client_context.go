@@ -6,7 +6,7 @@ import (
 	"context"
 	"fmt"
 
-	log "github.com/golang/glog"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/golang/protobuf/proto"
 	"github.com/pborman/uuid"
 	"google.golang.org/grpc/metadata"
@@ -33,16 +33,20 @@ type ContextMetadata struct {
 	ToolVersion string
 }
 
-// LogContextInfof(ctx, x, ...) is equivalent to log.V(x).Infof(...) except it
-// also logs context metadata, if available.
-func LogContextInfof(ctx context.Context, v log.Level, format string, args ...interface{}) {
-	if log.V(v) {
-		m, err := GetContextMetadata(ctx)
-		if err != nil && m.ActionID != "" {
-			format = "%s: " + format
-			args = append([]interface{}{m.ActionID}, args...)
+// LogContextInfof(ctx, x, ...) is equivalent to logger.Logf(ctx, x, ...) except it
+// also logs context metadata, if available, as structured fields.
+func LogContextInfof(ctx context.Context, v logger.Level, format string, args ...interface{}) {
+	if logger.V(v) {
+		var fields []logger.Field
+		if m, err := GetContextMetadata(ctx); err == nil {
+			if m.ActionID != "" {
+				fields = append(fields, logger.F("action_id", m.ActionID))
+			}
+			if m.InvocationID != "" {
+				fields = append(fields, logger.F("invocation_id", m.InvocationID))
+			}
 		}
-		log.InfoDepth(1, fmt.Sprintf(format, args...))
+		logger.LogDepth(ctx, 1, v, fmt.Sprintf(format, args...), fields...)
 	}
 }
 
@@ -78,12 +82,12 @@ func ContextWithMetadata(ctx context.Context, m *ContextMetadata) (context.Conte
 	actionID := m.ActionID
 	if actionID == "" {
 		actionID = uuid.New()
-		log.V(2).Infof("Generated action_id %s for %s", actionID, m.ToolName)
+		logger.Logf(ctx, 2, "Generated action_id %s for %s", actionID, m.ToolName)
 	}
 	invocationID := m.InvocationID
 	if invocationID == "" {
 		invocationID = uuid.New()
-		log.V(2).Infof("Generated invocation_id %s for %s %s", invocationID, m.ToolName, actionID)
+		logger.Logf(ctx, 2, "Generated invocation_id %s for %s %s", invocationID, m.ToolName, actionID)
 	}
 
 	meta := &repb.RequestMetadata{
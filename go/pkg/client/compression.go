@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/chunker"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/klauspost/compress/zstd"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// contentSniffLen is the number of bytes read to detect the content type of a blob, as used by
+// http.DetectContentType.
+const contentSniffLen = 512
+
+// CompressionPolicy tunes how blobs are compressed in ByteStream writes, on top of the
+// CompressedBytestreamThreshold of the Client, which must be non-negative for any blob to be
+// compressed. It trades CPU for bandwidth: e.g. a deployment on a slow network may compress more
+// aggressively, and skip blobs of formats which are already compressed.
+type CompressionPolicy struct {
+	// Compressor is the compressor of the blobs. Only repb.Compressor_ZSTD is supported, which is
+	// also used if unset.
+	Compressor repb.Compressor_Value
+	// Level is the zstd compression level, zstd.SpeedDefault if unset.
+	Level zstd.EncoderLevel
+	// SkipExtensions are the file extensions, e.g. ".zip" or ".jpg", of the files which are never
+	// compressed. They are matched case-insensitively.
+	SkipExtensions []string
+	// SkipContentTypes are the prefixes of the MIME types, e.g. "image/" or "application/zip", of
+	// the blobs which are never compressed. The MIME type of a blob is detected from its first 512
+	// bytes with http.DetectContentType.
+	SkipContentTypes []string
+}
+
+// Apply sets the client's CompressionPolicy.
+func (p *CompressionPolicy) Apply(c *Client) {
+	c.CompressionPolicy = p
+}
+
+// validate returns an error if the policy can't be used by this client.
+func (p *CompressionPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Compressor != repb.Compressor_IDENTITY && p.Compressor != repb.Compressor_ZSTD {
+		return fmt.Errorf("unsupported compressor %v, only %v is supported", p.Compressor, repb.Compressor_ZSTD)
+	}
+	if p.Level < 0 || p.Level > zstd.SpeedBestCompression {
+		return fmt.Errorf("invalid zstd compression level %d", p.Level)
+	}
+	return nil
+}
+
+// level returns the zstd compression level of the policy.
+func (p *CompressionPolicy) level() zstd.EncoderLevel {
+	if p == nil || p.Level == 0 {
+		return zstd.SpeedDefault
+	}
+	return p.Level
+}
+
+// skips returns whether the policy never compresses the contents of ue.
+func (p *CompressionPolicy) skips(ue *uploadinfo.Entry) bool {
+	if p == nil {
+		return false
+	}
+	if ue.IsFile() && len(p.SkipExtensions) > 0 {
+		ext := filepath.Ext(ue.Path)
+		for _, skip := range p.SkipExtensions {
+			if strings.EqualFold(ext, skip) {
+				return true
+			}
+		}
+	}
+	if len(p.SkipContentTypes) == 0 {
+		return false
+	}
+	head, err := entryHead(ue)
+	if err != nil {
+		// The error, if persistent, is returned by the upload itself.
+		return false
+	}
+	ct := http.DetectContentType(head)
+	for _, skip := range p.SkipContentTypes {
+		if strings.HasPrefix(ct, skip) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryHead returns the first bytes of the contents of ue, at most contentSniffLen of them.
+func entryHead(ue *uploadinfo.Entry) ([]byte, error) {
+	n := ue.Digest.Size
+	if n > contentSniffLen {
+		n = contentSniffLen
+	}
+	if ue.IsBlob() {
+		return ue.Contents[:n], nil
+	}
+	r := ue.ReaderAt
+	if ue.IsFile() {
+		f, err := os.Open(ue.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	head := make([]byte, n)
+	if _, err := r.ReadAt(head, ue.Offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return head, nil
+}
+
+// shouldCompressEntry returns whether ue is written compressed. The override of the entry takes
+// precedence over those of the call, which take precedence over the CompressionPolicy.
+func (c *Client) shouldCompressEntry(ctx context.Context, ue *uploadinfo.Entry) bool {
+	if ue.Compression != nil {
+		return *ue.Compression
+	}
+	if o := CallOverridesFromContext(ctx); o.Compression != nil {
+		return *o.Compression
+	}
+	return c.shouldCompress(ctx, ue.Digest.Size) && !c.CompressionPolicy.skips(ue)
+}
+
+// newEntryChunker returns a chunker of the contents of ue, compressed if compressed with the level
+// of the CompressionPolicy.
+func (c *Client) newEntryChunker(ue *uploadinfo.Entry, compressed bool) (*chunker.Chunker, error) {
+	return chunker.NewWithLevel(ue, compressed, c.CompressionPolicy.level(), int(c.ChunkMaxSize))
+}
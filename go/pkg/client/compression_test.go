@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/klauspost/compress/zstd"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestShouldCompressEntry(t *testing.T) {
+	dir := t.TempDir()
+	text := bytes.Repeat([]byte("text "), 100)
+	png := append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), text...)
+	for name, contents := range map[string][]byte{"a.txt": text, "a.ZIP": text, "a.bin": png} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), contents, 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", name, err)
+		}
+	}
+	file := func(name string) *uploadinfo.Entry {
+		path := filepath.Join(dir, name)
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile(%v) failed: %v", path, err)
+		}
+		return uploadinfo.EntryFromFile(digest.NewFromBlob(blob), path)
+	}
+	on, off := true, false
+	forced := uploadinfo.EntryFromBlob(png)
+	forced.Compression = &on
+	disabled := uploadinfo.EntryFromBlob(text)
+	disabled.Compression = &off
+	policy := &CompressionPolicy{SkipExtensions: []string{".zip"}, SkipContentTypes: []string{"image/"}}
+	tests := []struct {
+		name   string
+		ue     *uploadinfo.Entry
+		ctx    context.Context
+		policy *CompressionPolicy
+		want   bool
+	}{
+		{name: "no policy", ue: file("a.ZIP"), want: true},
+		{name: "text file", ue: file("a.txt"), policy: policy, want: true},
+		{name: "skipped extension", ue: file("a.ZIP"), policy: policy, want: false},
+		{name: "skipped content type of a file", ue: file("a.bin"), policy: policy, want: false},
+		{name: "skipped content type of a blob", ue: uploadinfo.EntryFromBlob(png), policy: policy, want: false},
+		{name: "below the threshold", ue: uploadinfo.EntryFromBlob([]byte("text")), policy: policy, want: false},
+		{name: "entry override on", ue: forced, policy: policy, want: true},
+		{name: "entry override off", ue: disabled, policy: policy, want: false},
+		{
+			name:   "call override",
+			ue:     uploadinfo.EntryFromBlob(png),
+			ctx:    WithCallOverrides(context.Background(), &CallOverrides{Compression: &on}),
+			policy: policy,
+			want:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			c := &Client{CompressedBytestreamThreshold: 10, CompressionPolicy: tc.policy}
+			if got := c.shouldCompressEntry(ctx, tc.ue); got != tc.want {
+				t.Errorf("shouldCompressEntry(%v) = %v, want %v", tc.ue.Digest, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompressionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *CompressionPolicy
+		wantErr bool
+	}{
+		{name: "nil"},
+		{name: "default", policy: &CompressionPolicy{}},
+		{name: "zstd", policy: &CompressionPolicy{Compressor: repb.Compressor_ZSTD, Level: zstd.SpeedBestCompression}},
+		{name: "deflate", policy: &CompressionPolicy{Compressor: repb.Compressor_DEFLATE}, wantErr: true},
+		{name: "invalid level", policy: &CompressionPolicy{Level: zstd.SpeedBestCompression + 1}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.validate(); (err != nil) != tc.wantErr {
+				t.Errorf("validate() = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
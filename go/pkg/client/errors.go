@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// OpError is the error returned by CAS and execution operations of the Client. It identifies the
+// operation that failed and what it operated on, so callers can react to specific failures using
+// errors.As rather than matching error messages. The underlying error is available through
+// errors.Unwrap.
+//
+// OpError carries the gRPC status code of the underlying error, so status.Code and
+// status.FromError keep working on it. Cancellations are not wrapped: the Client returns
+// context.Canceled and context.DeadlineExceeded as is.
+type OpError struct {
+	// Method is the name of the RPC that failed, e.g. "Read", "BatchUpdateBlobs" or "Execute".
+	Method string
+	// Digest is the digest of the blob the operation failed on, or of the action executed, if
+	// applicable. For batch operations, this is the first of the FailedDigests whose failure was
+	// not retried, and Code is its code.
+	Digest digest.Digest
+	// FailedDigests are all the digests that failed in a batch operation.
+	FailedDigests []digest.Digest
+	// ResourceName is the ByteStream resource name used, or the name of the operation waited on,
+	// if applicable.
+	ResourceName string
+	// Code is the gRPC status code of the failure; codes.Unknown if the error didn't come from
+	// the server.
+	Code codes.Code
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *OpError) Error() string {
+	target := e.ResourceName
+	if target == "" && e.Digest != (digest.Digest{}) {
+		target = e.Digest.String()
+	}
+	if target == "" {
+		return fmt.Sprintf("%s: %v", e.Method, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Method, target, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns a Status with the code and details of the underlying error and the message
+// of e.
+func (e *OpError) GRPCStatus() *status.Status {
+	if st, ok := status.FromError(e.Err); ok {
+		p := st.Proto()
+		p.Message = e.Error()
+		return status.FromProto(p)
+	}
+	return status.New(e.Code, e.Error())
+}
+
+// errorCode returns the gRPC code of err, taking context errors into account.
+func errorCode(err error) codes.Code {
+	if st, ok := status.FromError(err); ok {
+		return st.Code()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// newOpError wraps err in an *OpError, unless it is nil or already one. Bare context errors are
+// returned unchanged, since callers commonly compare them directly.
+func newOpError(method string, dg digest.Digest, resourceName string, err error) error {
+	if err == nil || err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	var oe *OpError
+	if errors.As(err, &oe) {
+		return err
+	}
+	return &OpError{
+		Method:       method,
+		Digest:       dg,
+		ResourceName: resourceName,
+		Code:         errorCode(err),
+		Err:          err,
+	}
+}
+
+// actionDigest returns the digest of the action of req, or the zero digest if it has none.
+func actionDigest(req *repb.ExecuteRequest) digest.Digest {
+	if req.GetActionDigest() == nil {
+		return digest.Digest{}
+	}
+	return digest.NewFromProtoUnvalidated(req.ActionDigest)
+}
@@ -0,0 +1,104 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestOpErrorReadBlob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	dg := digest.NewFromBlob([]byte("missing"))
+	_, _, err := c.ReadBlob(ctx, dg)
+	var oe *client.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("ReadBlob(%v) = %v, want *client.OpError", dg, err)
+	}
+	if oe.Method != "Read" || oe.Digest != dg || oe.Code != codes.NotFound || oe.ResourceName == "" {
+		t.Errorf("ReadBlob(%v) = %+v, want a NotFound Read error on the digest with a resource name", dg, oe)
+	}
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("status.Code(ReadBlob(%v)) = %v, want %v", dg, got, codes.NotFound)
+	}
+}
+
+func TestOpErrorBatchDownloadBlobs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	present := e.Server.CAS.Put([]byte("present"))
+	missing1 := digest.NewFromBlob([]byte("missing1"))
+	missing2 := digest.NewFromBlob([]byte("missing2"))
+	_, err := c.BatchDownloadBlobs(ctx, []digest.Digest{present, missing1, missing2})
+	var oe *client.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("BatchDownloadBlobs() = %v, want *client.OpError", err)
+	}
+	if oe.Method != "BatchReadBlobs" || oe.Code != codes.NotFound {
+		t.Errorf("BatchDownloadBlobs() = %+v, want a NotFound BatchReadBlobs error", oe)
+	}
+	sortDgs := cmpopts.SortSlices(func(a, b digest.Digest) bool { return a.Hash < b.Hash })
+	if diff := cmp.Diff([]digest.Digest{missing1, missing2}, oe.FailedDigests, sortDgs); diff != "" {
+		t.Errorf("BatchDownloadBlobs() returned diff in failed digests. (-want +got)\n%s", diff)
+	}
+}
+
+func TestOpErrorExecuteAndWait(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	cmd := &command.Command{Args: []string{"tool"}, ExecRoot: e.ExecRoot}
+	_, acDg := e.Set(cmd, command.DefaultExecutionOptions(), &command.Result{Status: command.SuccessResultStatus})
+	// Without the action in the CAS, the execution fails with INVALID_ARGUMENT.
+	e.Server.CAS.Clear()
+	_, err := c.ExecuteAndWait(ctx, &repb.ExecuteRequest{InstanceName: c.InstanceName, ActionDigest: acDg.ToProto(), SkipCacheLookup: true})
+	var oe *client.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("ExecuteAndWait() = %v, want *client.OpError", err)
+	}
+	if oe.Method != "Execute" || oe.Digest != acDg || oe.Code != codes.InvalidArgument {
+		t.Errorf("ExecuteAndWait() = %+v, want an InvalidArgument Execute error on the action digest", oe)
+	}
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("status.Code(ExecuteAndWait()) = %v, want %v", got, codes.InvalidArgument)
+	}
+}
+
+func TestOpErrorWaitOperation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	_, err := c.WaitOperation(ctx, "missing", nil)
+	var oe *client.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("WaitOperation(missing) = %v, want *client.OpError", err)
+	}
+	if oe.Method != "WaitExecution" || oe.ResourceName != "missing" || oe.Code != codes.NotFound {
+		t.Errorf("WaitOperation(missing) = %+v, want a NotFound WaitExecution error on the operation", oe)
+	}
+}
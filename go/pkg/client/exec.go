@@ -5,10 +5,11 @@ import (
 	"errors"
 	"io"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
-	log "github.com/golang/glog"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"google.golang.org/grpc/codes"
@@ -70,7 +71,13 @@ type Action struct {
 // ExecuteAction is a convenience method which wraps both PrepAction and ExecuteAndWait, along with
 // other steps such as uploading extra inputs and parsing Operation protos.
 func (c *Client) ExecuteAction(ctx context.Context, ac *Action) (*repb.ActionResult, error) {
-	log.V(1).Infof("Executing action: %v", ac.Args)
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	logger.Logf(ctx, 1, "Executing action: %v", ac.Args)
 
 	// Construct the action we're trying to run.
 	acDg, res, err := c.PrepAction(ctx, ac)
@@ -87,7 +94,7 @@ func (c *Client) ExecuteAction(ctx context.Context, ac *Action) (*repb.ActionRes
 		return nil, gerrors.WithMessage(err, "uploading input files to the CAS")
 	}
 
-	log.V(1).Info("Executing job")
+	logger.Logf(ctx, 1, "Executing job")
 	res, err = c.executeJob(ctx, ac.SkipCache, acDg)
 	if err != nil {
 		return res, gerrors.WithMessage(err, "executing an action")
@@ -169,7 +176,7 @@ func (c *Client) PrepAction(ctx context.Context, ac *Action) (*repb.Digest, *rep
 
 	// If the result is cacheable, check if it's already in the cache.
 	if !ac.DoNotCache || !ac.SkipCache {
-		log.V(1).Info("Checking cache")
+		logger.Logf(ctx, 1, "Checking cache")
 		res, err := c.CheckActionCache(ctx, acDg)
 		if err != nil {
 			return nil, nil, err
@@ -226,15 +233,34 @@ func (c *Client) ExecuteAndWait(ctx context.Context, req *repb.ExecuteRequest) (
 // the completed operation or an error.
 // The supplied callback function is called for each message received to update the state of
 // the remote action.
+// If the client has ExecutionRoutes, the action is executed by the endpoint and instance of the
+// first route matching its platform.
 func (c *Client) ExecuteAndWaitProgress(ctx context.Context, req *repb.ExecuteRequest, progress func(metadata *repb.ExecuteOperationMetadata)) (op *oppb.Operation, err error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if o := CallOverridesFromContext(ctx); o.Priority != 0 && req.GetExecutionPolicy().GetPriority() == 0 {
+		req = proto.Clone(req).(*repb.ExecuteRequest)
+		if req.ExecutionPolicy == nil {
+			req.ExecutionPolicy = &repb.ExecutionPolicy{}
+		}
+		req.ExecutionPolicy.Priority = o.Priority
+	}
+	exec, req, err := c.routeExecution(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 	wait := false // Should we retry by calling WaitExecution instead of Execute?
 	lastOp := &oppb.Operation{}
 	closure := func(ctx context.Context) (e error) {
 		var res regrpc.Execution_ExecuteClient
 		if wait {
-			res, e = c.WaitExecution(ctx, &repb.WaitExecutionRequest{Name: lastOp.Name})
+			res, e = exec.WaitExecution(ctx, &repb.WaitExecutionRequest{Name: lastOp.Name}, c.RPCOpts()...)
 		} else {
-			res, e = c.Execute(ctx, req)
+			res, e = exec.Execute(ctx, req, c.RPCOpts()...)
 		}
 		if e != nil {
 			return e
@@ -258,12 +284,13 @@ func (c *Client) ExecuteAndWaitProgress(ctx context.Context, req *repb.ExecuteRe
 		}
 		return nil
 	}
-	err = c.Retrier.Do(ctx, func() error { return c.CallWithTimeout(ctx, "Execute", closure) })
+	err = c.retry(ctx, func() error { return c.CallWithTimeout(ctx, "Execute", closure) })
 	if err != nil {
+		c.notePresenceErr(ctx, err)
 		if st, ok := status.FromError(err); ok {
 			err = StatusDetailedError(st)
 		}
-		return nil, err
+		return nil, newOpError("Execute", actionDigest(req), "", err)
 	}
 
 	// In the off chance that the server closes the stream immediately without returning any Operation
@@ -273,10 +300,107 @@ func (c *Client) ExecuteAndWaitProgress(ctx context.Context, req *repb.ExecuteRe
 	if proto.Equal(lastOp, &oppb.Operation{}) {
 		return nil, errors.New("unexpected server behaviour: an empty Operation was returned, or no operation was returned")
 	}
+	if lastOp.Done {
+		// Executions fail with FAILED_PRECONDITION when inputs are missing.
+		if st := OperationStatus(lastOp); st != nil {
+			c.notePresenceErr(ctx, st.Err())
+		}
+		atomic.AddInt64(&c.stats.actionsExecuted, 1)
+		res := &repb.ExecuteResponse{}
+		if r := lastOp.GetResponse(); r != nil && ptypes.UnmarshalAny(r, res) == nil && res.CachedResult {
+			atomic.AddInt64(&c.stats.actionCacheHits, 1)
+		}
+	}
 
 	return lastOp, nil
 }
 
+// OperationPollInterval is the interval at which WaitOperation polls the operations of servers
+// not implementing WaitExecution.
+var OperationPollInterval = time.Second
+
+// WaitOperation attaches to the execution of the operation with the given name, which may have been
+// started by another client or by a process that has since exited, and waits for it to complete.
+// It returns the completed operation or an error.
+// The supplied callback function is called for each message received to update the state of
+// the remote action.
+// Servers which do not implement WaitExecution are polled with GetOperation instead.
+func (c *Client) WaitOperation(ctx context.Context, name string, progress func(metadata *repb.ExecuteOperationMetadata)) (op *oppb.Operation, err error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	lastOp := &oppb.Operation{Name: name}
+	update := func(op *oppb.Operation) {
+		lastOp = op
+		if progress != nil {
+			metadata := &repb.ExecuteOperationMetadata{}
+			if err := ptypes.UnmarshalAny(op.Metadata, metadata); err == nil {
+				progress(metadata)
+			}
+		}
+	}
+	closure := func(ctx context.Context) error {
+		res, e := c.execution.WaitExecution(ctx, &repb.WaitExecutionRequest{Name: lastOp.Name}, c.RPCOpts()...)
+		if e != nil {
+			return e
+		}
+		received := false
+		for {
+			op, e := res.Recv()
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				return e
+			}
+			received = true
+			update(op)
+		}
+		if !received {
+			return errors.New("unexpected server behaviour: no operation was returned")
+		}
+		return nil
+	}
+	// Servers may close the stream before the operation completes, in which case we wait again.
+	for err == nil && !lastOp.Done {
+		err = c.retry(ctx, func() error { return c.CallWithTimeout(ctx, "WaitExecution", closure) })
+	}
+	if status.Code(err) == codes.Unimplemented {
+		LogContextInfof(ctx, 2, "WaitExecution is not supported, polling operation %s", name)
+		err = c.pollOperation(ctx, name, update)
+	}
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			err = StatusDetailedError(st)
+		}
+		return nil, newOpError("WaitExecution", digest.Digest{}, name, err)
+	}
+	return lastOp, nil
+}
+
+// pollOperation calls update with the operation with the given name every OperationPollInterval,
+// until it completes.
+func (c *Client) pollOperation(ctx context.Context, name string, update func(*oppb.Operation)) error {
+	for {
+		op, err := c.GetOperation(ctx, &oppb.GetOperationRequest{Name: name})
+		if err != nil {
+			return err
+		}
+		update(op)
+		if op.Done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(OperationPollInterval):
+		}
+	}
+}
+
 // OperationStatus returns an operation error status, if it is present, and nil otherwise.
 func OperationStatus(op *oppb.Operation) *status.Status {
 	var r *oppb.Operation_Response
@@ -1,12 +1,19 @@
 package client_test
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
 
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	opgrpc "google.golang.org/genproto/googleapis/longrunning"
 	oppb "google.golang.org/genproto/googleapis/longrunning"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
 )
@@ -67,3 +74,64 @@ func TestOperationStatus(t *testing.T) {
 		})
 	}
 }
+
+// pollingServer does not implement WaitExecution, and completes its operation on the third
+// GetOperation call.
+type pollingServer struct {
+	regrpc.UnimplementedExecutionServer
+	opgrpc.OperationsServer
+	calls int
+}
+
+func (s *pollingServer) GetOperation(ctx context.Context, req *oppb.GetOperationRequest) (*oppb.Operation, error) {
+	s.calls++
+	stage := repb.ExecutionStage_EXECUTING
+	if s.calls == 3 {
+		stage = repb.ExecutionStage_COMPLETED
+	}
+	md, err := ptypes.MarshalAny(&repb.ExecuteOperationMetadata{Stage: stage})
+	if err != nil {
+		return nil, err
+	}
+	return &oppb.Operation{Name: req.Name, Metadata: md, Done: s.calls == 3}, nil
+}
+
+func TestWaitOperationPolling(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	defer listener.Close()
+	server := grpc.NewServer()
+	fake := &pollingServer{}
+	regrpc.RegisterExecutionServer(server, fake)
+	opgrpc.RegisterOperationsServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+	ctx := context.Background()
+	c, err := client.NewClient(ctx, "instance", client.DialParams{
+		Service:    listener.Addr().String(),
+		NoSecurity: true,
+	}, client.StartupCapabilities(false))
+	if err != nil {
+		t.Fatalf("Error connecting to server: %v", err)
+	}
+	defer c.Close()
+	defer func(d time.Duration) { client.OperationPollInterval = d }(client.OperationPollInterval)
+	client.OperationPollInterval = time.Millisecond
+
+	var stages []repb.ExecutionStage_Value
+	op, err := c.WaitOperation(ctx, "op", func(md *repb.ExecuteOperationMetadata) {
+		stages = append(stages, md.Stage)
+	})
+	if err != nil {
+		t.Fatalf("c.WaitOperation(ctx, op) = %v", err)
+	}
+	if !op.Done || op.Name != "op" {
+		t.Errorf("c.WaitOperation(ctx, op) = %v, want the completed operation op", op)
+	}
+	wantStages := []repb.ExecutionStage_Value{repb.ExecutionStage_EXECUTING, repb.ExecutionStage_EXECUTING, repb.ExecutionStage_COMPLETED}
+	if diff := cmp.Diff(wantStages, stages); diff != "" {
+		t.Errorf("c.WaitOperation(ctx, op) reported unexpected stages (-want +got):\n%s", diff)
+	}
+}
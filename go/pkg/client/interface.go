@@ -0,0 +1,516 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	emptypb "github.com/golang/protobuf/ptypes/empty"
+	bsgrpc "google.golang.org/genproto/googleapis/bytestream"
+	bspb "google.golang.org/genproto/googleapis/bytestream"
+	oppb "google.golang.org/genproto/googleapis/longrunning"
+)
+
+// Interface is the public surface of *Client. Code that only needs to call the client should
+// depend on Interface rather than *Client, so that wrappers adding caching, auditing or routing
+// can be substituted for it. Embed Base in such wrappers to only override the methods of interest.
+type Interface interface {
+	// Capabilities.
+	CheckCapabilities(ctx context.Context) error
+	GetCapabilities(ctx context.Context) (*repb.ServerCapabilities, error)
+	GetCapabilitiesForInstance(ctx context.Context, instance string) (*repb.ServerCapabilities, error)
+	SupportsCompressor(comp repb.Compressor_Value) bool
+	SupportsActionPlatformProperties() bool
+	SupportsCommandOutputPaths() bool
+	SupportsSplitBlob() bool
+	SupportsSpliceBlob() bool
+
+	// CAS and ByteStream operations.
+	WriteBytes(ctx context.Context, name string, data []byte) error
+	ReadBytes(ctx context.Context, name string) ([]byte, error)
+	ReadResourceToFile(ctx context.Context, name, fpath string) (int64, error)
+	UploadIfMissing(ctx context.Context, data ...*uploadinfo.Entry) ([]digest.Digest, int64, error)
+	WriteBlobs(ctx context.Context, blobs map[digest.Digest][]byte) error
+	WriteProto(ctx context.Context, msg proto.Message) (digest.Digest, error)
+	WriteBlob(ctx context.Context, blob []byte) (digest.Digest, error)
+	BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]byte) error
+	BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (map[digest.Digest][]byte, error)
+	ReadBlob(ctx context.Context, d digest.Digest) ([]byte, *MovedBytesMetadata, error)
+	ReadBlobRange(ctx context.Context, d digest.Digest, offset, limit int64) ([]byte, *MovedBytesMetadata, error)
+	ReadBlobToFile(ctx context.Context, d digest.Digest, fpath string) (*MovedBytesMetadata, error)
+	ReadBlobToWriter(ctx context.Context, d digest.Digest, w io.Writer) (*MovedBytesMetadata, error)
+	ReadBlobRangeToWriter(ctx context.Context, d digest.Digest, offset, limit int64, w io.Writer) (*MovedBytesMetadata, error)
+	ReadProto(ctx context.Context, d digest.Digest, msg proto.Message) (*MovedBytesMetadata, error)
+	MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest.Digest, error)
+	ResourceNameWrite(hash string, sizeBytes int64) string
+	ResourceNameCompressedWrite(hash string, sizeBytes int64) string
+	SplitBlob(ctx context.Context, d digest.Digest) ([]digest.Digest, error)
+	SpliceBlob(ctx context.Context, d digest.Digest, chunks []digest.Digest) error
+
+	// Trees and action outputs.
+	GetDirectoryTree(ctx context.Context, d *repb.Digest) ([]*repb.Directory, error)
+	FlattenActionOutputs(ctx context.Context, ar *repb.ActionResult) (map[string]*TreeOutput, error)
+	VerifyActionResult(ctx context.Context, ar *repb.ActionResult) error
+	DownloadDirectory(ctx context.Context, d digest.Digest, outDir string, cache filemetadata.Cache) (map[string]*TreeOutput, *MovedBytesMetadata, error)
+	DownloadActionOutputs(ctx context.Context, resPb *repb.ActionResult, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error)
+	DownloadFiles(ctx context.Context, outDir string, outputs map[digest.Digest]*TreeOutput) (*MovedBytesMetadata, error)
+	DownloadOutputs(ctx context.Context, outs map[string]*TreeOutput, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error)
+	ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir string, is *command.InputSpec, cache filemetadata.Cache) (digest.Digest, []*uploadinfo.Entry, *TreeStats, error)
+	UploadDirectory(ctx context.Context, path string, opts *UploadDirectoryOptions) (digest.Digest, *TreeStats, error)
+	DiffTree(ctx context.Context, oldRoot, newRoot digest.Digest, newBlobs []*uploadinfo.Entry) (*TreeDiff, error)
+	TreeFS(ctx context.Context, root digest.Digest) (fs.FS, error)
+	WriteActionOutputsTar(ctx context.Context, ar *repb.ActionResult, prefix string, tw *tar.Writer) error
+	WriteActionOutputsZip(ctx context.Context, ar *repb.ActionResult, prefix string, zw *zip.Writer) error
+	UploadTar(ctx context.Context, r io.Reader, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error)
+	UploadZip(ctx context.Context, ra io.ReaderAt, size int64, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error)
+	NewPrefetcher(opts *PrefetchOptions) (*Prefetcher, error)
+	FlattenTree(tree *repb.Tree, rootPath string) (map[string]*TreeOutput, error)
+	ComputeOutputsToUpload(execRoot, workingDir string, paths []string, cache filemetadata.Cache, sb command.SymlinkBehaviorType) (map[digest.Digest]*uploadinfo.Entry, *repb.ActionResult, error)
+
+	// Execution and the action cache.
+	ExecuteAction(ctx context.Context, ac *Action) (*repb.ActionResult, error)
+	CheckActionCache(ctx context.Context, acDg *repb.Digest) (*repb.ActionResult, error)
+	PrepAction(ctx context.Context, ac *Action) (*repb.Digest, *repb.ActionResult, error)
+	ExecuteAndWait(ctx context.Context, req *repb.ExecuteRequest) (*oppb.Operation, error)
+	ExecuteAndWaitProgress(ctx context.Context, req *repb.ExecuteRequest, progress func(metadata *repb.ExecuteOperationMetadata)) (*oppb.Operation, error)
+	WaitOperation(ctx context.Context, name string, progress func(metadata *repb.ExecuteOperationMetadata)) (*oppb.Operation, error)
+
+	// Retried, timed out wrappers of the raw RPCs.
+	GetActionResult(ctx context.Context, req *repb.GetActionResultRequest) (*repb.ActionResult, error)
+	UpdateActionResult(ctx context.Context, req *repb.UpdateActionResultRequest) (*repb.ActionResult, error)
+	Read(ctx context.Context, req *bspb.ReadRequest) (bsgrpc.ByteStream_ReadClient, error)
+	Write(ctx context.Context) (bsgrpc.ByteStream_WriteClient, error)
+	QueryWriteStatus(ctx context.Context, req *bspb.QueryWriteStatusRequest) (*bspb.QueryWriteStatusResponse, error)
+	FindMissingBlobs(ctx context.Context, req *repb.FindMissingBlobsRequest) (*repb.FindMissingBlobsResponse, error)
+	BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRequest) (*repb.BatchUpdateBlobsResponse, error)
+	BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsRequest) (*repb.BatchReadBlobsResponse, error)
+	GetTree(ctx context.Context, req *repb.GetTreeRequest) (regrpc.ContentAddressableStorage_GetTreeClient, error)
+	Execute(ctx context.Context, req *repb.ExecuteRequest) (regrpc.Execution_ExecuteClient, error)
+	WaitExecution(ctx context.Context, req *repb.WaitExecutionRequest) (regrpc.Execution_ExecuteClient, error)
+	GetBackendCapabilities(ctx context.Context, conn *grpc.ClientConn, req *repb.GetCapabilitiesRequest) (*repb.ServerCapabilities, error)
+	GetOperation(ctx context.Context, req *oppb.GetOperationRequest) (*oppb.Operation, error)
+	ListOperations(ctx context.Context, req *oppb.ListOperationsRequest) (*oppb.ListOperationsResponse, error)
+	CancelOperation(ctx context.Context, req *oppb.CancelOperationRequest) (*emptypb.Empty, error)
+	DeleteOperation(ctx context.Context, req *oppb.DeleteOperationRequest) (*emptypb.Empty, error)
+
+	// Connection management and helpers for extensions.
+	Close() error
+	Shutdown(ctx context.Context) error
+	RPCOpts() []grpc.CallOption
+	Stats() Stats
+	BuildStats() *BuildStats
+	RecordAction(res *command.Result, md *command.Metadata)
+	CallWithTimeout(ctx context.Context, rpcName string, f func(ctx context.Context) error) error
+}
+
+var (
+	_ Interface = (*Client)(nil)
+	_ Interface = (*Base)(nil)
+)
+
+// Base is an Interface that forwards every call to Next. It is meant to be embedded in wrappers
+// of a Client, which then only need to implement the methods whose behavior they change:
+//
+//	type auditingClient struct {
+//		*client.Base
+//	}
+//
+//	func (c *auditingClient) WriteBlob(ctx context.Context, blob []byte) (digest.Digest, error) {
+//		log.Printf("writing %d bytes", len(blob))
+//		return c.Next.WriteBlob(ctx, blob)
+//	}
+type Base struct {
+	// Next is the Interface calls are forwarded to, usually a *Client or another wrapper.
+	Next Interface
+}
+
+// NewBase returns a Base forwarding all calls to next.
+func NewBase(next Interface) *Base {
+	return &Base{Next: next}
+}
+
+// CheckCapabilities calls the same method of Next.
+func (b *Base) CheckCapabilities(ctx context.Context) error {
+	return b.Next.CheckCapabilities(ctx)
+}
+
+// GetCapabilities calls the same method of Next.
+func (b *Base) GetCapabilities(ctx context.Context) (*repb.ServerCapabilities, error) {
+	return b.Next.GetCapabilities(ctx)
+}
+
+// GetCapabilitiesForInstance calls the same method of Next.
+func (b *Base) GetCapabilitiesForInstance(ctx context.Context, instance string) (*repb.ServerCapabilities, error) {
+	return b.Next.GetCapabilitiesForInstance(ctx, instance)
+}
+
+// SupportsCompressor calls the same method of Next.
+func (b *Base) SupportsCompressor(comp repb.Compressor_Value) bool {
+	return b.Next.SupportsCompressor(comp)
+}
+
+// SupportsActionPlatformProperties calls the same method of Next.
+func (b *Base) SupportsActionPlatformProperties() bool {
+	return b.Next.SupportsActionPlatformProperties()
+}
+
+// SupportsCommandOutputPaths calls the same method of Next.
+func (b *Base) SupportsCommandOutputPaths() bool {
+	return b.Next.SupportsCommandOutputPaths()
+}
+
+// SupportsSplitBlob calls the same method of Next.
+func (b *Base) SupportsSplitBlob() bool {
+	return b.Next.SupportsSplitBlob()
+}
+
+// SupportsSpliceBlob calls the same method of Next.
+func (b *Base) SupportsSpliceBlob() bool {
+	return b.Next.SupportsSpliceBlob()
+}
+
+// WriteBytes calls the same method of Next.
+func (b *Base) WriteBytes(ctx context.Context, name string, data []byte) error {
+	return b.Next.WriteBytes(ctx, name, data)
+}
+
+// ReadBytes calls the same method of Next.
+func (b *Base) ReadBytes(ctx context.Context, name string) ([]byte, error) {
+	return b.Next.ReadBytes(ctx, name)
+}
+
+// ReadResourceToFile calls the same method of Next.
+func (b *Base) ReadResourceToFile(ctx context.Context, name, fpath string) (int64, error) {
+	return b.Next.ReadResourceToFile(ctx, name, fpath)
+}
+
+// UploadIfMissing calls the same method of Next.
+func (b *Base) UploadIfMissing(ctx context.Context, data ...*uploadinfo.Entry) ([]digest.Digest, int64, error) {
+	return b.Next.UploadIfMissing(ctx, data...)
+}
+
+// WriteBlobs calls the same method of Next.
+func (b *Base) WriteBlobs(ctx context.Context, blobs map[digest.Digest][]byte) error {
+	return b.Next.WriteBlobs(ctx, blobs)
+}
+
+// WriteProto calls the same method of Next.
+func (b *Base) WriteProto(ctx context.Context, msg proto.Message) (digest.Digest, error) {
+	return b.Next.WriteProto(ctx, msg)
+}
+
+// WriteBlob calls the same method of Next.
+func (b *Base) WriteBlob(ctx context.Context, blob []byte) (digest.Digest, error) {
+	return b.Next.WriteBlob(ctx, blob)
+}
+
+// BatchWriteBlobs calls the same method of Next.
+func (b *Base) BatchWriteBlobs(ctx context.Context, blobs map[digest.Digest][]byte) error {
+	return b.Next.BatchWriteBlobs(ctx, blobs)
+}
+
+// BatchDownloadBlobs calls the same method of Next.
+func (b *Base) BatchDownloadBlobs(ctx context.Context, dgs []digest.Digest) (map[digest.Digest][]byte, error) {
+	return b.Next.BatchDownloadBlobs(ctx, dgs)
+}
+
+// ReadBlob calls the same method of Next.
+func (b *Base) ReadBlob(ctx context.Context, d digest.Digest) ([]byte, *MovedBytesMetadata, error) {
+	return b.Next.ReadBlob(ctx, d)
+}
+
+// ReadBlobRange calls the same method of Next.
+func (b *Base) ReadBlobRange(ctx context.Context, d digest.Digest, offset, limit int64) ([]byte, *MovedBytesMetadata, error) {
+	return b.Next.ReadBlobRange(ctx, d, offset, limit)
+}
+
+// ReadBlobToFile calls the same method of Next.
+func (b *Base) ReadBlobToFile(ctx context.Context, d digest.Digest, fpath string) (*MovedBytesMetadata, error) {
+	return b.Next.ReadBlobToFile(ctx, d, fpath)
+}
+
+// ReadBlobToWriter calls the same method of Next.
+func (b *Base) ReadBlobToWriter(ctx context.Context, d digest.Digest, w io.Writer) (*MovedBytesMetadata, error) {
+	return b.Next.ReadBlobToWriter(ctx, d, w)
+}
+
+// ReadBlobRangeToWriter calls the same method of Next.
+func (b *Base) ReadBlobRangeToWriter(ctx context.Context, d digest.Digest, offset, limit int64, w io.Writer) (*MovedBytesMetadata, error) {
+	return b.Next.ReadBlobRangeToWriter(ctx, d, offset, limit, w)
+}
+
+// ReadProto calls the same method of Next.
+func (b *Base) ReadProto(ctx context.Context, d digest.Digest, msg proto.Message) (*MovedBytesMetadata, error) {
+	return b.Next.ReadProto(ctx, d, msg)
+}
+
+// MissingBlobs calls the same method of Next.
+func (b *Base) MissingBlobs(ctx context.Context, ds []digest.Digest) ([]digest.Digest, error) {
+	return b.Next.MissingBlobs(ctx, ds)
+}
+
+// ResourceNameWrite calls the same method of Next.
+func (b *Base) ResourceNameWrite(hash string, sizeBytes int64) string {
+	return b.Next.ResourceNameWrite(hash, sizeBytes)
+}
+
+// ResourceNameCompressedWrite calls the same method of Next.
+func (b *Base) ResourceNameCompressedWrite(hash string, sizeBytes int64) string {
+	return b.Next.ResourceNameCompressedWrite(hash, sizeBytes)
+}
+
+// SplitBlob calls the same method of Next.
+func (b *Base) SplitBlob(ctx context.Context, d digest.Digest) ([]digest.Digest, error) {
+	return b.Next.SplitBlob(ctx, d)
+}
+
+// SpliceBlob calls the same method of Next.
+func (b *Base) SpliceBlob(ctx context.Context, d digest.Digest, chunks []digest.Digest) error {
+	return b.Next.SpliceBlob(ctx, d, chunks)
+}
+
+// GetDirectoryTree calls the same method of Next.
+func (b *Base) GetDirectoryTree(ctx context.Context, d *repb.Digest) ([]*repb.Directory, error) {
+	return b.Next.GetDirectoryTree(ctx, d)
+}
+
+// FlattenActionOutputs calls the same method of Next.
+func (b *Base) FlattenActionOutputs(ctx context.Context, ar *repb.ActionResult) (map[string]*TreeOutput, error) {
+	return b.Next.FlattenActionOutputs(ctx, ar)
+}
+
+// VerifyActionResult calls the same method of Next.
+func (b *Base) VerifyActionResult(ctx context.Context, ar *repb.ActionResult) error {
+	return b.Next.VerifyActionResult(ctx, ar)
+}
+
+// DownloadDirectory calls the same method of Next.
+func (b *Base) DownloadDirectory(ctx context.Context, d digest.Digest, outDir string, cache filemetadata.Cache) (map[string]*TreeOutput, *MovedBytesMetadata, error) {
+	return b.Next.DownloadDirectory(ctx, d, outDir, cache)
+}
+
+// DownloadActionOutputs calls the same method of Next.
+func (b *Base) DownloadActionOutputs(ctx context.Context, resPb *repb.ActionResult, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error) {
+	return b.Next.DownloadActionOutputs(ctx, resPb, outDir, cache)
+}
+
+// DownloadFiles calls the same method of Next.
+func (b *Base) DownloadFiles(ctx context.Context, outDir string, outputs map[digest.Digest]*TreeOutput) (*MovedBytesMetadata, error) {
+	return b.Next.DownloadFiles(ctx, outDir, outputs)
+}
+
+// DownloadOutputs calls the same method of Next.
+func (b *Base) DownloadOutputs(ctx context.Context, outs map[string]*TreeOutput, outDir string, cache filemetadata.Cache) (*MovedBytesMetadata, error) {
+	return b.Next.DownloadOutputs(ctx, outs, outDir, cache)
+}
+
+// ComputeMerkleTree calls the same method of Next.
+func (b *Base) ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir string, is *command.InputSpec, cache filemetadata.Cache) (digest.Digest, []*uploadinfo.Entry, *TreeStats, error) {
+	return b.Next.ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir, is, cache)
+}
+
+// UploadDirectory calls the same method of Next.
+func (b *Base) UploadDirectory(ctx context.Context, path string, opts *UploadDirectoryOptions) (digest.Digest, *TreeStats, error) {
+	return b.Next.UploadDirectory(ctx, path, opts)
+}
+
+// DiffTree calls the same method of Next.
+func (b *Base) DiffTree(ctx context.Context, oldRoot, newRoot digest.Digest, newBlobs []*uploadinfo.Entry) (*TreeDiff, error) {
+	return b.Next.DiffTree(ctx, oldRoot, newRoot, newBlobs)
+}
+
+// TreeFS calls the same method of Next.
+func (b *Base) TreeFS(ctx context.Context, root digest.Digest) (fs.FS, error) {
+	return b.Next.TreeFS(ctx, root)
+}
+
+// WriteActionOutputsTar calls the same method of Next.
+func (b *Base) WriteActionOutputsTar(ctx context.Context, ar *repb.ActionResult, prefix string, tw *tar.Writer) error {
+	return b.Next.WriteActionOutputsTar(ctx, ar, prefix, tw)
+}
+
+// WriteActionOutputsZip calls the same method of Next.
+func (b *Base) WriteActionOutputsZip(ctx context.Context, ar *repb.ActionResult, prefix string, zw *zip.Writer) error {
+	return b.Next.WriteActionOutputsZip(ctx, ar, prefix, zw)
+}
+
+// UploadTar calls the same method of Next.
+func (b *Base) UploadTar(ctx context.Context, r io.Reader, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error) {
+	return b.Next.UploadTar(ctx, r, opts)
+}
+
+// UploadZip calls the same method of Next.
+func (b *Base) UploadZip(ctx context.Context, ra io.ReaderAt, size int64, opts *UploadArchiveOptions) (digest.Digest, *TreeStats, error) {
+	return b.Next.UploadZip(ctx, ra, size, opts)
+}
+
+// NewPrefetcher calls the same method of Next.
+func (b *Base) NewPrefetcher(opts *PrefetchOptions) (*Prefetcher, error) {
+	return b.Next.NewPrefetcher(opts)
+}
+
+// FlattenTree calls the same method of Next.
+func (b *Base) FlattenTree(tree *repb.Tree, rootPath string) (map[string]*TreeOutput, error) {
+	return b.Next.FlattenTree(tree, rootPath)
+}
+
+// ComputeOutputsToUpload calls the same method of Next.
+func (b *Base) ComputeOutputsToUpload(execRoot, workingDir string, paths []string, cache filemetadata.Cache, sb command.SymlinkBehaviorType) (map[digest.Digest]*uploadinfo.Entry, *repb.ActionResult, error) {
+	return b.Next.ComputeOutputsToUpload(execRoot, workingDir, paths, cache, sb)
+}
+
+// ExecuteAction calls the same method of Next.
+func (b *Base) ExecuteAction(ctx context.Context, ac *Action) (*repb.ActionResult, error) {
+	return b.Next.ExecuteAction(ctx, ac)
+}
+
+// CheckActionCache calls the same method of Next.
+func (b *Base) CheckActionCache(ctx context.Context, acDg *repb.Digest) (*repb.ActionResult, error) {
+	return b.Next.CheckActionCache(ctx, acDg)
+}
+
+// PrepAction calls the same method of Next.
+func (b *Base) PrepAction(ctx context.Context, ac *Action) (*repb.Digest, *repb.ActionResult, error) {
+	return b.Next.PrepAction(ctx, ac)
+}
+
+// ExecuteAndWait calls the same method of Next.
+func (b *Base) ExecuteAndWait(ctx context.Context, req *repb.ExecuteRequest) (*oppb.Operation, error) {
+	return b.Next.ExecuteAndWait(ctx, req)
+}
+
+// ExecuteAndWaitProgress calls the same method of Next.
+func (b *Base) ExecuteAndWaitProgress(ctx context.Context, req *repb.ExecuteRequest, progress func(metadata *repb.ExecuteOperationMetadata)) (*oppb.Operation, error) {
+	return b.Next.ExecuteAndWaitProgress(ctx, req, progress)
+}
+
+// WaitOperation calls the same method of Next.
+func (b *Base) WaitOperation(ctx context.Context, name string, progress func(metadata *repb.ExecuteOperationMetadata)) (*oppb.Operation, error) {
+	return b.Next.WaitOperation(ctx, name, progress)
+}
+
+// GetActionResult calls the same method of Next.
+func (b *Base) GetActionResult(ctx context.Context, req *repb.GetActionResultRequest) (*repb.ActionResult, error) {
+	return b.Next.GetActionResult(ctx, req)
+}
+
+// UpdateActionResult calls the same method of Next.
+func (b *Base) UpdateActionResult(ctx context.Context, req *repb.UpdateActionResultRequest) (*repb.ActionResult, error) {
+	return b.Next.UpdateActionResult(ctx, req)
+}
+
+// Read calls the same method of Next.
+func (b *Base) Read(ctx context.Context, req *bspb.ReadRequest) (bsgrpc.ByteStream_ReadClient, error) {
+	return b.Next.Read(ctx, req)
+}
+
+// Write calls the same method of Next.
+func (b *Base) Write(ctx context.Context) (bsgrpc.ByteStream_WriteClient, error) {
+	return b.Next.Write(ctx)
+}
+
+// QueryWriteStatus calls the same method of Next.
+func (b *Base) QueryWriteStatus(ctx context.Context, req *bspb.QueryWriteStatusRequest) (*bspb.QueryWriteStatusResponse, error) {
+	return b.Next.QueryWriteStatus(ctx, req)
+}
+
+// FindMissingBlobs calls the same method of Next.
+func (b *Base) FindMissingBlobs(ctx context.Context, req *repb.FindMissingBlobsRequest) (*repb.FindMissingBlobsResponse, error) {
+	return b.Next.FindMissingBlobs(ctx, req)
+}
+
+// BatchUpdateBlobs calls the same method of Next.
+func (b *Base) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRequest) (*repb.BatchUpdateBlobsResponse, error) {
+	return b.Next.BatchUpdateBlobs(ctx, req)
+}
+
+// BatchReadBlobs calls the same method of Next.
+func (b *Base) BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsRequest) (*repb.BatchReadBlobsResponse, error) {
+	return b.Next.BatchReadBlobs(ctx, req)
+}
+
+// GetTree calls the same method of Next.
+func (b *Base) GetTree(ctx context.Context, req *repb.GetTreeRequest) (regrpc.ContentAddressableStorage_GetTreeClient, error) {
+	return b.Next.GetTree(ctx, req)
+}
+
+// Execute calls the same method of Next.
+func (b *Base) Execute(ctx context.Context, req *repb.ExecuteRequest) (regrpc.Execution_ExecuteClient, error) {
+	return b.Next.Execute(ctx, req)
+}
+
+// WaitExecution calls the same method of Next.
+func (b *Base) WaitExecution(ctx context.Context, req *repb.WaitExecutionRequest) (regrpc.Execution_ExecuteClient, error) {
+	return b.Next.WaitExecution(ctx, req)
+}
+
+// GetBackendCapabilities calls the same method of Next.
+func (b *Base) GetBackendCapabilities(ctx context.Context, conn *grpc.ClientConn, req *repb.GetCapabilitiesRequest) (*repb.ServerCapabilities, error) {
+	return b.Next.GetBackendCapabilities(ctx, conn, req)
+}
+
+// GetOperation calls the same method of Next.
+func (b *Base) GetOperation(ctx context.Context, req *oppb.GetOperationRequest) (*oppb.Operation, error) {
+	return b.Next.GetOperation(ctx, req)
+}
+
+// ListOperations calls the same method of Next.
+func (b *Base) ListOperations(ctx context.Context, req *oppb.ListOperationsRequest) (*oppb.ListOperationsResponse, error) {
+	return b.Next.ListOperations(ctx, req)
+}
+
+// CancelOperation calls the same method of Next.
+func (b *Base) CancelOperation(ctx context.Context, req *oppb.CancelOperationRequest) (*emptypb.Empty, error) {
+	return b.Next.CancelOperation(ctx, req)
+}
+
+// DeleteOperation calls the same method of Next.
+func (b *Base) DeleteOperation(ctx context.Context, req *oppb.DeleteOperationRequest) (*emptypb.Empty, error) {
+	return b.Next.DeleteOperation(ctx, req)
+}
+
+// Close calls the same method of Next.
+func (b *Base) Close() error {
+	return b.Next.Close()
+}
+
+// Shutdown calls the same method of Next.
+func (b *Base) Shutdown(ctx context.Context) error {
+	return b.Next.Shutdown(ctx)
+}
+
+// RPCOpts calls the same method of Next.
+func (b *Base) RPCOpts() []grpc.CallOption {
+	return b.Next.RPCOpts()
+}
+
+// Stats calls the same method of Next.
+func (b *Base) Stats() Stats {
+	return b.Next.Stats()
+}
+
+// BuildStats calls the same method of Next.
+func (b *Base) BuildStats() *BuildStats {
+	return b.Next.BuildStats()
+}
+
+// RecordAction calls the same method of Next.
+func (b *Base) RecordAction(res *command.Result, md *command.Metadata) {
+	b.Next.RecordAction(res, md)
+}
+
+// CallWithTimeout calls the same method of Next.
+func (b *Base) CallWithTimeout(ctx context.Context, rpcName string, f func(ctx context.Context) error) error {
+	return b.Next.CallWithTimeout(ctx, rpcName, f)
+}
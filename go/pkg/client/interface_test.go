@@ -0,0 +1,49 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+// countingClient counts the blobs written through it and forwards everything else.
+type countingClient struct {
+	*client.Base
+	writes int
+}
+
+func (c *countingClient) WriteBlob(ctx context.Context, blob []byte) (digest.Digest, error) {
+	c.writes++
+	return c.Next.WriteBlob(ctx, blob)
+}
+
+func TestBaseWrapper(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	var c client.Interface = &countingClient{Base: client.NewBase(e.Client.GrpcClient)}
+	blob := []byte("wrapped")
+	dg, err := c.WriteBlob(ctx, blob)
+	if err != nil {
+		t.Fatalf("WriteBlob() failed: %v", err)
+	}
+	if want := digest.NewFromBlob(blob); dg != want {
+		t.Errorf("WriteBlob() = %v, want %v", dg, want)
+	}
+	// ReadBlob is not overridden, so it is forwarded to the wrapped client.
+	got, _, err := c.ReadBlob(ctx, dg)
+	if err != nil {
+		t.Fatalf("ReadBlob() failed: %v", err)
+	}
+	if string(got) != string(blob) {
+		t.Errorf("ReadBlob() = %q, want %q", got, blob)
+	}
+	if n := c.(*countingClient).writes; n != 1 {
+		t.Errorf("WriteBlob called %d times on the wrapper, want 1", n)
+	}
+}
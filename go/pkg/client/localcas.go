@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/localcas"
+)
+
+// WithLocalCAS sets the local CAS from which blobs are read before falling back to the remote CAS.
+// It is filled by Prefetchers.
+func WithLocalCAS(s *localcas.Store) Option {
+	return WithOpts(localCASOpt{s})
+}
+
+// localCASOpt is needed because *localcas.Store has no Apply method.
+type localCASOpt struct {
+	s *localcas.Store
+}
+
+func (o localCASOpt) Apply(c *Client) {
+	c.LocalCAS = o.s
+}
+
+// readLocal writes the requested range of the blob d from the LocalCAS to w. It returns false if
+// the blob is not in the LocalCAS.
+func (c *Client) readLocal(ctx context.Context, d digest.Digest, offset, limit int64, w io.Writer) (*MovedBytesMetadata, bool, error) {
+	if c.LocalCAS == nil {
+		return nil, false, nil
+	}
+	r, err := c.LocalCAS.Open(d)
+	if errors.Is(err, localcas.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		LogContextInfof(ctx, 2, "Failed to read %v from the local CAS, reading it remotely: %v", d, err)
+		return nil, false, nil
+	}
+	defer r.Close()
+	sz := d.Size - offset
+	if limit > 0 && limit < sz {
+		sz = limit
+	}
+	if _, err := r.(io.Seeker).Seek(offset, io.SeekStart); err != nil {
+		return nil, true, err
+	}
+	if _, err := io.CopyN(w, r, sz); err != nil {
+		return nil, true, err
+	}
+	LogContextInfof(ctx, 3, "Read %v from the local CAS", d)
+	return &MovedBytesMetadata{Requested: d.Size, Cached: sz}, true, nil
+}
+
+// downloadLocal copies the outputs found in the LocalCAS to outDir, and returns the remaining ones.
+func (c *Client) downloadLocal(ctx context.Context, outDir string, outputs map[digest.Digest]*TreeOutput) (map[digest.Digest]*TreeOutput, *MovedBytesMetadata, error) {
+	stats := &MovedBytesMetadata{}
+	if c.LocalCAS == nil {
+		return outputs, stats, nil
+	}
+	remaining := make(map[digest.Digest]*TreeOutput, len(outputs))
+	for dg, out := range outputs {
+		if !c.LocalCAS.Has(dg) {
+			remaining[dg] = out
+			continue
+		}
+		perm := c.RegularMode
+		if out.IsExecutable {
+			perm = c.ExecutableMode
+		}
+		if err := c.copyLocal(dg, filepath.Join(outDir, out.Path), perm); err != nil {
+			if errors.Is(err, localcas.ErrNotFound) {
+				// Collected in the meantime.
+				remaining[dg] = out
+				continue
+			}
+			return nil, stats, err
+		}
+		stats.Requested += dg.Size
+		stats.Cached += dg.Size
+	}
+	if n := len(outputs) - len(remaining); n > 0 {
+		LogContextInfof(ctx, 2, "%d items copied from the local CAS", n)
+	}
+	return remaining, stats, nil
+}
+
+// copyLocal materializes the blob dg from the LocalCAS at path, as a copy-on-write clone if the
+// file systems support them, and as a copy otherwise. The blob is not linked, because outputs are
+// writable and their modes vary.
+func (c *Client) copyLocal(dg digest.Digest, path string, perm os.FileMode) error {
+	if err := c.LocalCAS.Clone(dg, path, perm); !errors.Is(err, localcas.ErrCloneUnsupported) {
+		return err
+	}
+	r, err := c.LocalCAS.Open(dg)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// newConfig accumulates the settings given to New.
+type newConfig struct {
+	params DialParams
+	opts   []Opt
+}
+
+// Option configures a Client created by New. Unlike Opt, an Option can configure both how the
+// connection is dialed and how the resulting Client behaves.
+type Option func(*newConfig)
+
+// New connects to a remote execution service and returns a client suitable for higher-level
+// functionality, configured by the given options. It is equivalent to NewClient, but does not
+// require splitting the configuration between DialParams and Opts.
+func New(ctx context.Context, instanceName string, options ...Option) (*Client, error) {
+	cfg := &newConfig{}
+	for _, o := range options {
+		o(cfg)
+	}
+	if cfg.params.Service == "" {
+		return nil, fmt.Errorf("service needs to be specified, use WithService")
+	}
+	return NewClient(ctx, instanceName, cfg.params, cfg.opts...)
+}
+
+// WithOpts applies the given Opts to the Client, for settings that have no dedicated Option.
+func WithOpts(opts ...Opt) Option {
+	return func(c *newConfig) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// WithDialParams replaces all the dial parameters. Options given after it refine them.
+func WithDialParams(p DialParams) Option {
+	return func(c *newConfig) {
+		dialOpts := c.params.DialOpts
+		c.params = p
+		c.params.DialOpts = append(dialOpts, p.DialOpts...)
+	}
+}
+
+// WithService sets the address of the remote execution service.
+func WithService(address string) Option {
+	return func(c *newConfig) {
+		c.params.Service = address
+	}
+}
+
+// WithCASService sets the address of the CAS service, if it is separate from the remote execution
+// service.
+func WithCASService(address string) Option {
+	return func(c *newConfig) {
+		c.params.CASService = address
+	}
+}
+
+// WithRemoteHeaders adds gRPC metadata headers attached to every RPC. See DialParams.RemoteHeaders.
+func WithRemoteHeaders(headers map[string][]string) Option {
+	return func(c *newConfig) {
+		if c.params.RemoteHeaders == nil {
+			c.params.RemoteHeaders = make(map[string][]string)
+		}
+		for k, vs := range headers {
+			c.params.RemoteHeaders[k] = append(c.params.RemoteHeaders[k], vs...)
+		}
+	}
+}
+
+// WithNoSecurity disables TLS and authentication. Should only be used in test code.
+func WithNoSecurity() Option {
+	return func(c *newConfig) {
+		c.params.NoSecurity = true
+	}
+}
+
+// WithNoAuth keeps TLS but disables client authentication.
+func WithNoAuth() Option {
+	return func(c *newConfig) {
+		c.params.NoAuth = true
+	}
+}
+
+// WithCredentialsFile authenticates using the service account credentials in the given file.
+func WithCredentialsFile(path string) Option {
+	return func(c *newConfig) {
+		c.params.CredFile = path
+	}
+}
+
+// WithApplicationDefaultCredentials authenticates using the application default credentials.
+func WithApplicationDefaultCredentials() Option {
+	return func(c *newConfig) {
+		c.params.UseApplicationDefault = true
+	}
+}
+
+// WithCASConcurrency sets the number of simultaneous CAS upload and download requests.
+func WithCASConcurrency(n int) Option {
+	return WithOpts(CASConcurrency(n))
+}
+
+// WithCompression sets the size in bytes above which blobs are compressed in ByteStream reads and
+// writes. Use 0 to compress every blob, and a negative number to disable compression.
+func WithCompression(threshold int64) Option {
+	return WithOpts(CompressedBytestreamThreshold(threshold))
+}
+
+// WithCompressionPolicy sets the compressor, the level and the blobs compressed in ByteStream
+// writes, when compression is enabled with WithCompression.
+func WithCompressionPolicy(p *CompressionPolicy) Option {
+	return WithOpts(p)
+}
+
+// WithSplitSplice sets the size in bytes from which blobs are transferred as chunks with SplitBlob
+// and SpliceBlob, when the server supports them. Use a negative number to disable it.
+func WithSplitSplice(threshold int64) Option {
+	return WithOpts(SplitSpliceThreshold(threshold))
+}
+
+// WithParallelReads makes ReadBlobToFile, and so output downloads, read large blobs with several
+// concurrent ByteStream reads of their parts.
+func WithParallelReads(p *ParallelReads) Option {
+	return WithOpts(p)
+}
+
+// WithStatsFile makes the client write its BuildStats as JSON to path when it is closed.
+func WithStatsFile(path string) Option {
+	return WithOpts(StatsFile(path))
+}
+
+// WithRetryPolicy sets the retrier used for all RPCs. Use a nil retrier to disable retries.
+func WithRetryPolicy(r *Retrier) Option {
+	return func(c *newConfig) {
+		c.opts = append(c.opts, retrierOpt{r})
+	}
+}
+
+// retrierOpt is needed because a nil *Retrier cannot be applied as an Opt.
+type retrierOpt struct {
+	r *Retrier
+}
+
+func (o retrierOpt) Apply(c *Client) {
+	c.Retrier = o.r
+}
+
+// WithRPCTimeouts sets the per-RPC timeouts. See RPCTimeouts.
+func WithRPCTimeouts(timeouts map[string]time.Duration) Option {
+	return WithOpts(RPCTimeouts(timeouts))
+}
+
+// WithStartupCapabilities sets whether server capabilities are loaded when the client is created.
+func WithStartupCapabilities(load bool) Option {
+	return WithOpts(StartupCapabilities(load))
+}
+
+// WithMetrics installs a gRPC stats handler on the connections, which is notified of every RPC
+// and can be used to export metrics, e.g. with OpenCensus or OpenTelemetry.
+func WithMetrics(h stats.Handler) Option {
+	return WithGRPCDialOptions(grpc.WithStatsHandler(h))
+}
+
+// WithInterceptors adds gRPC client interceptors to the connections. Each list may be nil.
+func WithInterceptors(unary []grpc.UnaryClientInterceptor, stream []grpc.StreamClientInterceptor) Option {
+	var opts []grpc.DialOption
+	if len(unary) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(unary...))
+	}
+	if len(stream) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(stream...))
+	}
+	return WithGRPCDialOptions(opts...)
+}
+
+// WithGRPCDialOptions adds arbitrary gRPC dial options to the connections.
+func WithGRPCDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *newConfig) {
+		c.params.DialOpts = append(c.params.DialOpts, opts...)
+	}
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	var calls []string
+	interceptor := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		calls = append(calls, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	c, err := New(ctx, instance,
+		WithService(l.Addr().String()),
+		WithNoSecurity(),
+		WithStartupCapabilities(false),
+		WithCASConcurrency(7),
+		WithCompression(1024),
+		WithRetryPolicy(nil),
+		WithInterceptors([]grpc.UnaryClientInterceptor{interceptor}, nil),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+	if c.casConcurrency != 7 {
+		t.Errorf("casConcurrency = %v, want 7", c.casConcurrency)
+	}
+	if c.CompressedBytestreamThreshold != 1024 {
+		t.Errorf("CompressedBytestreamThreshold = %v, want 1024", c.CompressedBytestreamThreshold)
+	}
+	if c.Retrier != nil {
+		t.Errorf("Retrier = %v, want nil", c.Retrier)
+	}
+	// The server implements no services, but the interceptor must still see the call.
+	c.FindMissingBlobs(ctx, &repb.FindMissingBlobsRequest{InstanceName: instance})
+	if len(calls) != 1 || calls[0] != "/build.bazel.remote.execution.v2.ContentAddressableStorage/FindMissingBlobs" {
+		t.Errorf("interceptor saw calls %v, want exactly FindMissingBlobs", calls)
+	}
+}
+
+// failingCAS fails every blob of its batch RPCs, with the code given for its digest or with
+// UNAVAILABLE.
+type failingCAS struct {
+	regrpc.UnimplementedContentAddressableStorageServer
+	codes map[digest.Digest]codes.Code
+	calls int32
+}
+
+func (f *failingCAS) status(dg *repb.Digest) *status.Status {
+	code, ok := f.codes[digest.NewFromProtoUnvalidated(dg)]
+	if !ok {
+		code = codes.Unavailable
+	}
+	return status.New(code, "failed")
+}
+
+func (f *failingCAS) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRequest) (*repb.BatchUpdateBlobsResponse, error) {
+	atomic.AddInt32(&f.calls, 1)
+	resp := &repb.BatchUpdateBlobsResponse{}
+	for _, r := range req.Requests {
+		resp.Responses = append(resp.Responses, &repb.BatchUpdateBlobsResponse_Response{
+			Digest: r.Digest,
+			Status: f.status(r.Digest).Proto(),
+		})
+	}
+	return resp, nil
+}
+
+func (f *failingCAS) BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsRequest) (*repb.BatchReadBlobsResponse, error) {
+	atomic.AddInt32(&f.calls, 1)
+	resp := &repb.BatchReadBlobsResponse{}
+	for _, dg := range req.Digests {
+		resp.Responses = append(resp.Responses, &repb.BatchReadBlobsResponse_Response{
+			Digest: dg,
+			Status: f.status(dg).Proto(),
+		})
+	}
+	return resp, nil
+}
+
+func TestNewWithoutRetries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	cas := &failingCAS{}
+	srv := grpc.NewServer()
+	regrpc.RegisterContentAddressableStorageServer(srv, cas)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	c, err := New(ctx, instance,
+		WithService(l.Addr().String()),
+		WithNoSecurity(),
+		WithStartupCapabilities(false),
+		WithRetryPolicy(nil),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+	blob := []byte("blob")
+	dg := digest.NewFromBlob(blob)
+	if err := c.BatchWriteBlobs(ctx, map[digest.Digest][]byte{dg: blob}); status.Code(err) != codes.Unavailable {
+		t.Errorf("BatchWriteBlobs() = %v, want an UNAVAILABLE error", err)
+	}
+	if _, err := c.BatchDownloadBlobs(ctx, []digest.Digest{dg}); status.Code(err) != codes.Unavailable {
+		t.Errorf("BatchDownloadBlobs() = %v, want an UNAVAILABLE error", err)
+	}
+	if n := atomic.LoadInt32(&cas.calls); n != 2 {
+		t.Errorf("CAS received %d batch calls, want 2 without retries", n)
+	}
+}
+
+func TestNewRequiresService(t *testing.T) {
+	t.Parallel()
+	if _, err := New(context.Background(), instance, WithNoSecurity()); err == nil {
+		t.Errorf("New() without WithService succeeded, want error")
+	}
+}
+
+func TestNewWithRemoteHeaders(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	mds := make(chan metadata.MD, 1)
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		mds <- md
+		return status.Error(codes.Unimplemented, "not implemented")
+	}))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	c, err := New(ctx, instance,
+		WithService(l.Addr().String()),
+		WithNoSecurity(),
+		WithStartupCapabilities(false),
+		WithRetryPolicy(nil),
+		WithRemoteHeaders(map[string][]string{"x-route": {"a", "b"}}),
+		WithRemoteHeaders(map[string][]string{"x-tenant": {"t"}}),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+	// The server implements no services, but its fallback handler still sees the headers.
+	c.FindMissingBlobs(ctx, &repb.FindMissingBlobsRequest{InstanceName: instance})
+	got := <-mds
+	if v := got.Get("x-route"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("x-route = %v, want [a b]", v)
+	}
+	if v := got.Get("x-tenant"); len(v) != 1 || v[0] != "t" {
+		t.Errorf("x-tenant = %v, want [t]", v)
+	}
+}
+
+func TestCASUploadDownloadConcurrency(t *testing.T) {
+	t.Parallel()
+	c := &Client{}
+	for _, o := range []Opt{CASConcurrency(5), CASUploadConcurrency(2), CASDownloadConcurrency(3)} {
+		o.Apply(c)
+	}
+	if !c.casUploaders.TryAcquire(2) || c.casUploaders.TryAcquire(1) {
+		t.Errorf("CASUploadConcurrency(2) did not allow exactly 2 concurrent uploads")
+	}
+	if !c.casDownloaders.TryAcquire(3) || c.casDownloaders.TryAcquire(1) {
+		t.Errorf("CASDownloadConcurrency(3) did not allow exactly 3 concurrent downloads")
+	}
+	if c.casConcurrency != 5 {
+		t.Errorf("casConcurrency = %v, want 5", c.casConcurrency)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOutputServiceUnavailable is returned, possibly wrapped, by OutputService implementations that
+// cannot serve a registration, for example because the output directory cannot be managed by them.
+// The outputs are then downloaded instead.
+var ErrOutputServiceUnavailable = errors.New("output service is unavailable")
+
+// OutputService materializes action outputs on demand instead of downloading them: registered
+// outputs appear in the file system immediately, and their contents are only fetched from the CAS
+// when they are read. This is the model of Bazel's remote output service, and implementations may
+// forward registrations to such a daemon; the lazyfs package provides a built-in one.
+type OutputService interface {
+	// RegisterOutputs makes outs available at their paths relative to outDir, replacing previous
+	// outputs at the same paths.
+	RegisterOutputs(ctx context.Context, outDir string, outs map[string]*TreeOutput) error
+}
+
+// WithOutputService sets the OutputService with which DownloadActionOutputs registers outputs
+// instead of downloading them.
+func WithOutputService(s OutputService) Option {
+	return WithOpts(outputServiceOpt{s})
+}
+
+// outputServiceOpt is needed because interfaces cannot have an Apply method.
+type outputServiceOpt struct {
+	s OutputService
+}
+
+func (o outputServiceOpt) Apply(c *Client) {
+	c.OutputService = o.s
+}
+
+// registerOutputs registers outs with the OutputService, if any. It returns false if the outputs
+// need to be downloaded.
+func (c *Client) registerOutputs(ctx context.Context, outs map[string]*TreeOutput, outDir string) (*MovedBytesMetadata, bool, error) {
+	if c.OutputService == nil {
+		return nil, false, nil
+	}
+	if err := c.OutputService.RegisterOutputs(ctx, outDir, outs); err != nil {
+		if errors.Is(err, ErrOutputServiceUnavailable) {
+			LogContextInfof(ctx, 2, "Downloading outputs to %v: %v", outDir, err)
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	stats := &MovedBytesMetadata{}
+	for _, out := range outs {
+		stats.Requested += out.Digest.Size
+	}
+	// Nothing was downloaded yet, and what will be is up to the readers.
+	stats.Cached = stats.Requested
+	return stats, true, nil
+}
@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// recordingOutputService records registered output digests, or fails with err.
+type recordingOutputService struct {
+	registered map[string]digest.Digest
+	err        error
+}
+
+func (s *recordingOutputService) RegisterOutputs(ctx context.Context, outDir string, outs map[string]*client.TreeOutput) error {
+	if s.err != nil {
+		return s.err
+	}
+	for p, out := range outs {
+		s.registered[filepath.Join(outDir, p)] = out.Digest
+	}
+	return nil
+}
+
+func TestDownloadActionOutputsOutputService(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	fooDg := e.Server.CAS.Put(fooBlob)
+	ar := &repb.ActionResult{OutputFiles: []*repb.OutputFile{{Path: "foo", Digest: fooDg.ToProto()}}}
+	outDir := t.TempDir()
+
+	s := &recordingOutputService{registered: make(map[string]digest.Digest)}
+	c.OutputService = s
+	defer func() { c.OutputService = nil }()
+	stats, err := c.DownloadActionOutputs(ctx, ar, outDir, filemetadata.NewNoopCache())
+	if err != nil {
+		t.Fatalf("DownloadActionOutputs() failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]digest.Digest{filepath.Join(outDir, "foo"): fooDg}, s.registered); diff != "" {
+		t.Errorf("registered outputs diff (-want +got):\n%s", diff)
+	}
+	if stats.LogicalMoved != 0 || stats.Requested != fooDg.Size {
+		t.Errorf("DownloadActionOutputs() stats = %+v, want %d bytes requested and none moved", stats, fooDg.Size)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "foo")); !os.IsNotExist(err) {
+		t.Errorf("foo was downloaded despite being registered: %v", err)
+	}
+	if e.Server.CAS.BlobReads(fooDg) != 0 {
+		t.Errorf("foo was read %d times, want 0", e.Server.CAS.BlobReads(fooDg))
+	}
+
+	// Outputs the service cannot take are downloaded.
+	s.err = fmt.Errorf("%w: busy", client.ErrOutputServiceUnavailable)
+	if _, err := c.DownloadActionOutputs(ctx, ar, outDir, filemetadata.NewNoopCache()); err != nil {
+		t.Fatalf("DownloadActionOutputs() failed: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(outDir, "foo")); err != nil || string(got) != string(fooBlob) {
+		t.Errorf("foo = %q, %v, want %q", got, err, fooBlob)
+	}
+
+	// Other errors are returned.
+	s.err = fmt.Errorf("registration failed")
+	if _, err := c.DownloadActionOutputs(ctx, ar, outDir, filemetadata.NewNoopCache()); err == nil {
+		t.Errorf("DownloadActionOutputs() succeeded, want the registration error")
+	}
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// DefaultParallelReadPartSize is the default size in bytes of the parts of the blobs read in
+	// parallel.
+	DefaultParallelReadPartSize = 64 * 1024 * 1024
+
+	// DefaultParallelReadParallelism is the default number of parts of a blob read at the same time.
+	DefaultParallelReadParallelism = 8
+)
+
+// ParallelReads makes ReadBlobToFile download the blobs of at least Threshold bytes with several
+// concurrent ByteStream reads of parts of them, each written at its offset in the file, because a
+// single stream may not saturate a link with a high bandwidth-delay product. The parts are read
+// uncompressed.
+type ParallelReads struct {
+	// Threshold is the size in bytes from which blobs are read in parallel, twice PartSize if unset.
+	Threshold int64
+	// PartSize is the size in bytes of the parts, DefaultParallelReadPartSize if unset.
+	PartSize int64
+	// Parallelism is the maximum number of parts of a blob read at the same time,
+	// DefaultParallelReadParallelism if unset.
+	Parallelism int
+}
+
+// Apply sets the client's ParallelReads.
+func (p *ParallelReads) Apply(c *Client) {
+	c.ParallelReads = p
+}
+
+func (p *ParallelReads) partSize() int64 {
+	if p.PartSize <= 0 {
+		return DefaultParallelReadPartSize
+	}
+	return p.PartSize
+}
+
+func (p *ParallelReads) parallelism() int {
+	if p.Parallelism <= 0 {
+		return DefaultParallelReadParallelism
+	}
+	return p.Parallelism
+}
+
+// shouldReadParallel returns whether a blob of the given size is read in parallel.
+func (c *Client) shouldReadParallel(size int64) bool {
+	p := c.ParallelReads
+	if p == nil {
+		return false
+	}
+	threshold := p.Threshold
+	if threshold <= 0 {
+		threshold = 2 * p.partSize()
+	}
+	return size >= threshold
+}
+
+// offsetWriter writes sequentially to a file from an offset, with pwrite.
+type offsetWriter struct {
+	f   *os.File
+	off int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// readBlobParallel downloads the blob d to fpath with concurrent reads of its parts, then verifies
+// the digest of the file.
+func (c *Client) readBlobParallel(ctx context.Context, d digest.Digest, fpath string) (*MovedBytesMetadata, error) {
+	f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, c.RegularMode)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := f.Truncate(d.Size); err != nil {
+		return nil, err
+	}
+
+	name := c.resourceNameRead(d.Hash, d.Size)
+	partSize := c.ParallelReads.partSize()
+	sem := make(chan struct{}, c.ParallelReads.parallelism())
+	stats := &MovedBytesMetadata{Requested: d.Size}
+	eg, eCtx := errgroup.WithContext(ctx)
+	for off := int64(0); off < d.Size; off += partSize {
+		off := off
+		limit := partSize
+		if off+limit > d.Size {
+			limit = d.Size - off
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-eCtx.Done():
+		}
+		if eCtx.Err() != nil {
+			break
+		}
+		eg.Go(func() error {
+			defer func() { <-sem }()
+			LogContextInfof(ctx, 3, "Reading part of %d bytes at offset %d of blob %s", limit, off, d)
+			n, err := c.readStreamedRetried(eCtx, name, off, limit, &offsetWriter{f: f, off: off})
+			atomic.AddInt64(&stats.LogicalMoved, n)
+			atomic.AddInt64(&stats.RealMoved, n)
+			if err != nil {
+				return err
+			}
+			if n != limit {
+				return fmt.Errorf("partial read of %d bytes at offset %d returned %d bytes", limit, off, n)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return stats, newOpError("Read", d, name, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	if err := f.Close(); err != nil {
+		return stats, err
+	}
+	got, err := digest.NewFromFile(fpath)
+	if err != nil {
+		return stats, err
+	}
+	if got != d {
+		return stats, &OpError{
+			Method:       "Read",
+			Digest:       d,
+			ResourceName: name,
+			Code:         codes.DataLoss,
+			Err:          fmt.Errorf("calculated digest %s != expected digest %s", got, d),
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestReadBlobToFileParallel(t *testing.T) {
+	t.Parallel()
+	blob := make([]byte, 1050)
+	rand.New(rand.NewSource(1)).Read(blob)
+	tests := []struct {
+		name      string
+		reads     *client.ParallelReads
+		wantReads int
+	}{
+		{name: "disabled", wantReads: 1},
+		{name: "parts", reads: &client.ParallelReads{PartSize: 100, Parallelism: 3}, wantReads: 11},
+		{name: "default parallelism", reads: &client.ParallelReads{PartSize: 500}, wantReads: 3},
+		{name: "below the threshold", reads: &client.ParallelReads{Threshold: 2000, PartSize: 100}, wantReads: 1},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			c := e.Client.GrpcClient
+			if tc.reads != nil {
+				tc.reads.Apply(c)
+			}
+			dg := e.Server.CAS.Put(blob)
+			path := filepath.Join(t.TempDir(), "blob")
+			stats, err := c.ReadBlobToFile(context.Background(), dg, path)
+			if err != nil {
+				t.Fatalf("c.ReadBlobToFile(ctx, %v) gave error %v, want nil", dg, err)
+			}
+			if stats.LogicalMoved != dg.Size {
+				t.Errorf("c.ReadBlobToFile(ctx, %v) moved %d bytes, want %d", dg, stats.LogicalMoved, dg.Size)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("os.ReadFile(%v) failed: %v", path, err)
+			}
+			if !bytes.Equal(got, blob) {
+				t.Errorf("c.ReadBlobToFile(ctx, %v) wrote different contents", dg)
+			}
+			if n := e.Server.CAS.BlobReads(dg); n != tc.wantReads {
+				t.Errorf("c.ReadBlobToFile(ctx, %v) made %d reads, want %d", dg, n, tc.wantReads)
+			}
+		})
+	}
+}
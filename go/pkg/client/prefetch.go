@@ -0,0 +1,313 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// prefetchBackoff is how long prefetch workers wait for the CAS downloaders to be idle.
+const prefetchBackoff = 50 * time.Millisecond
+
+// PrefetchOptions configures a Prefetcher.
+type PrefetchOptions struct {
+	// Concurrency is the number of blobs fetched at the same time. Defaults to 1.
+	Concurrency int
+	// BytesPerSecond limits the rate at which blobs are fetched. Zero means no limit.
+	BytesPerSecond int64
+	// Progress, if set, is called with the stats after each blob is fetched or fails to be. It is
+	// called concurrently by the fetching goroutines.
+	Progress func(PrefetchStats)
+}
+
+// PrefetchStats describes the work done by a Prefetcher.
+type PrefetchStats struct {
+	// Blobs is the number of blobs fetched into the local CAS.
+	Blobs int64
+	// Bytes is the total size of the blobs fetched.
+	Bytes int64
+	// Errors is the number of hints that failed. Failures are otherwise only logged.
+	Errors int64
+}
+
+// Prefetcher fetches blobs that are likely to be needed soon, e.g. the toolchain of the next build
+// target, into the client's LocalCAS in the background, so that later reads and downloads are
+// served locally.
+//
+// Prefetches run at low priority: they only use a CAS download slot when no other download is
+// waiting for one, and are subject to the BytesPerSecond limit.
+type Prefetcher struct {
+	c        *Client
+	ctx      context.Context
+	cancel   func()
+	limiter  *byteRateLimiter
+	progress func(PrefetchStats)
+
+	mu     sync.Mutex
+	idle   *sync.Cond
+	queue  []prefetchJob
+	queued map[prefetchJob]bool
+	// pending counts the queued and running jobs.
+	pending int
+	wake    chan struct{}
+	wg      sync.WaitGroup
+
+	blobs, bytes, errs int64
+}
+
+type prefetchJob struct {
+	d    digest.Digest
+	tree bool
+}
+
+// NewPrefetcher starts a Prefetcher filling the client's LocalCAS, which must be set.
+func (c *Client) NewPrefetcher(opts *PrefetchOptions) (*Prefetcher, error) {
+	if c.LocalCAS == nil {
+		return nil, errors.New("prefetching requires a LocalCAS")
+	}
+	if opts == nil {
+		opts = &PrefetchOptions{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Prefetcher{
+		c:        c,
+		ctx:      ctx,
+		cancel:   cancel,
+		limiter:  &byteRateLimiter{rate: opts.BytesPerSecond},
+		progress: opts.Progress,
+		queued:   make(map[prefetchJob]bool),
+		wake:     make(chan struct{}, 1),
+	}
+	p.idle = sync.NewCond(&p.mu)
+	n := opts.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go p.worker()
+	}
+	return p, nil
+}
+
+// Prefetch hints that the blobs ds are likely to be needed soon. It returns immediately.
+func (p *Prefetcher) Prefetch(ds ...digest.Digest) {
+	for _, d := range ds {
+		p.enqueue(prefetchJob{d: d})
+	}
+}
+
+// PrefetchTree hints that the files of the directory tree rooted at root are likely to be needed
+// soon. It returns immediately; the tree itself is fetched in the background.
+func (p *Prefetcher) PrefetchTree(root digest.Digest) {
+	p.enqueue(prefetchJob{d: root, tree: true})
+}
+
+// Wait blocks until all the hints given so far are done, or ctx is done.
+func (p *Prefetcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		for p.pending > 0 && p.ctx.Err() == nil {
+			p.idle.Wait()
+		}
+		p.mu.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the work done so far.
+func (p *Prefetcher) Stats() PrefetchStats {
+	return PrefetchStats{
+		Blobs:  atomic.LoadInt64(&p.blobs),
+		Bytes:  atomic.LoadInt64(&p.bytes),
+		Errors: atomic.LoadInt64(&p.errs),
+	}
+}
+
+// Close cancels the pending hints and waits for the workers to stop.
+func (p *Prefetcher) Close() {
+	p.cancel()
+	p.mu.Lock()
+	p.idle.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Prefetcher) enqueue(j prefetchJob) {
+	if !j.tree && (j.d.Size == 0 || p.c.LocalCAS.Has(j.d)) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.queued[j] || p.ctx.Err() != nil {
+		return
+	}
+	p.queued[j] = true
+	p.queue = append(p.queue, j)
+	p.pending++
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next job, waiting for one if needed. It returns false once the Prefetcher is
+// closed.
+func (p *Prefetcher) next() (prefetchJob, bool) {
+	for {
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			j := p.queue[0]
+			p.queue = p.queue[1:]
+			// Let other workers take the following jobs.
+			if len(p.queue) > 0 {
+				select {
+				case p.wake <- struct{}{}:
+				default:
+				}
+			}
+			p.mu.Unlock()
+			return j, true
+		}
+		p.mu.Unlock()
+		select {
+		case <-p.wake:
+		case <-p.ctx.Done():
+			return prefetchJob{}, false
+		}
+	}
+}
+
+func (p *Prefetcher) done(j prefetchJob) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.queued, j)
+	p.pending--
+	if p.pending == 0 {
+		p.idle.Broadcast()
+	}
+}
+
+func (p *Prefetcher) worker() {
+	defer p.wg.Done()
+	for {
+		j, ok := p.next()
+		if !ok {
+			return
+		}
+		var err error
+		if j.tree {
+			err = p.fetchTree(j.d)
+		} else {
+			err = p.fetch(j.d)
+		}
+		if err != nil && p.ctx.Err() == nil {
+			atomic.AddInt64(&p.errs, 1)
+			LogContextInfof(p.ctx, 2, "Failed to prefetch %v: %v", j.d, err)
+		}
+		if p.progress != nil && !j.tree {
+			p.progress(p.Stats())
+		}
+		p.done(j)
+	}
+}
+
+// fetchTree queues the files of the tree rooted at root.
+func (p *Prefetcher) fetchTree(root digest.Digest) error {
+	dirs, err := p.c.GetDirectoryTree(p.ctx, root.ToProto())
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		for _, f := range dir.Files {
+			d, err := digest.NewFromProto(f.Digest)
+			if err != nil {
+				return err
+			}
+			p.enqueue(prefetchJob{d: d})
+		}
+	}
+	return nil
+}
+
+// fetch reads the blob d into the LocalCAS, once a download slot is free and the rate allows it.
+func (p *Prefetcher) fetch(d digest.Digest) error {
+	if p.c.LocalCAS.Has(d) {
+		return nil
+	}
+	if err := p.limiter.wait(p.ctx, d.Size); err != nil {
+		return err
+	}
+	for !p.c.casDownloaders.TryAcquire(1) {
+		select {
+		case <-time.After(prefetchBackoff):
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+	defer p.c.casDownloaders.Release(1)
+
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := p.c.LocalCAS.PutReader(d, pr)
+		// Unblock the download if the blob could not be stored.
+		pr.CloseWithError(err)
+		errc <- err
+	}()
+	_, err := p.c.readBlobStreamed(p.ctx, d, 0, 0, pw)
+	pw.CloseWithError(err)
+	if perr := <-errc; err == nil {
+		err = perr
+	}
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&p.blobs, 1)
+	atomic.AddInt64(&p.bytes, d.Size)
+	return nil
+}
+
+// byteRateLimiter spaces out transfers so that they do not exceed rate bytes per second on average.
+type byteRateLimiter struct {
+	rate int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until n bytes may be transferred.
+func (l *byteRateLimiter) wait(ctx context.Context, n int64) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	l.mu.Unlock()
+
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
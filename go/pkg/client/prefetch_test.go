@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/localcas"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestPrefetch(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	if _, err := c.NewPrefetcher(nil); err == nil {
+		t.Errorf("NewPrefetcher() without a LocalCAS succeeded, want error")
+	}
+	store, err := localcas.Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("localcas.Open() failed: %v", err)
+	}
+	c.LocalCAS = store
+	defer func() { c.LocalCAS = nil }()
+
+	fooDg := e.Server.CAS.Put(fooBlob)
+	barDg := e.Server.CAS.Put(barBlob)
+	bazDg := e.Server.CAS.Put([]byte("baz"))
+	dir := &repb.Directory{Files: []*repb.FileNode{{Name: "bar", Digest: barDg.ToProto()}, {Name: "baz", Digest: bazDg.ToProto()}}}
+	dirDg := e.Server.CAS.Put(mustMarshal(dir))
+	missingDg := digest.NewFromBlob([]byte("missing"))
+
+	var progressCalls int64
+	p, err := c.NewPrefetcher(&client.PrefetchOptions{
+		Concurrency: 2,
+		Progress:    func(client.PrefetchStats) { atomic.AddInt64(&progressCalls, 1) },
+	})
+	if err != nil {
+		t.Fatalf("NewPrefetcher() failed: %v", err)
+	}
+	defer p.Close()
+	p.Prefetch(fooDg, fooDg, missingDg)
+	p.PrefetchTree(dirDg)
+	if err := p.Wait(ctx); err != nil {
+		t.Fatalf("Wait() failed: %v", err)
+	}
+	for _, dg := range []digest.Digest{fooDg, barDg, bazDg} {
+		if !store.Has(dg) {
+			t.Errorf("%v was not prefetched", dg)
+		}
+	}
+	want := client.PrefetchStats{Blobs: 3, Bytes: fooDg.Size + barDg.Size + bazDg.Size, Errors: 1}
+	if got := p.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	// Progress is reported for each blob fetched or failing, but not for the tree.
+	if got := atomic.LoadInt64(&progressCalls); got != 4 {
+		t.Errorf("Progress was called %d times, want 4", got)
+	}
+
+	// Prefetched blobs are no longer read remotely.
+	reads := e.Server.CAS.BlobReads(fooDg)
+	got, stats, err := c.ReadBlob(ctx, fooDg)
+	if err != nil || string(got) != string(fooBlob) {
+		t.Errorf("ReadBlob() = %q, %v, want %q", got, err, fooBlob)
+	}
+	if stats.Cached != fooDg.Size || stats.LogicalMoved != 0 {
+		t.Errorf("ReadBlob() stats = %+v, want %d bytes cached", stats, fooDg.Size)
+	}
+	outDir := t.TempDir()
+	outputs := map[digest.Digest]*client.TreeOutput{
+		fooDg: {Digest: fooDg, Path: "foo", IsExecutable: true},
+		barDg: {Digest: barDg, Path: "bar"},
+	}
+	if _, err := c.DownloadFiles(ctx, outDir, outputs); err != nil {
+		t.Fatalf("DownloadFiles() failed: %v", err)
+	}
+	if e.Server.CAS.BlobReads(fooDg) != reads {
+		t.Errorf("foo was read %d times after being prefetched, want %d", e.Server.CAS.BlobReads(fooDg), reads)
+	}
+	got, err = os.ReadFile(filepath.Join(outDir, "foo"))
+	if err != nil || string(got) != string(fooBlob) {
+		t.Errorf("foo = %q, %v, want %q", got, err, fooBlob)
+	}
+	if info, err := os.Stat(filepath.Join(outDir, "foo")); err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("foo is not executable: %v, %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PresenceCache remembers on disk which blobs are known to be present in remote CASes, so that
+// MissingBlobs and uploads skip querying them again for a while. The directory may be shared by
+// concurrent processes and by clients of different servers and instances.
+//
+// Blobs are trusted to remain present for the TTL after a server last confirmed them. Whenever a
+// server reports missing blobs anyway, for example by failing an execution with
+// FAILED_PRECONDITION, every blob of that server and instance is forgotten, because the server
+// likely evicted more than the ones it reported.
+type PresenceCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// presenceEpochFile is the name of the file whose modification time invalidates the blobs
+// confirmed before it.
+const presenceEpochFile = "epoch"
+
+// NewPresenceCache returns a presence cache stored in dir, trusting blobs for ttl.
+func NewPresenceCache(dir string, ttl time.Duration) (*PresenceCache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("presence cache TTL must be positive, got %v", ttl)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PresenceCache{dir: dir, ttl: ttl}, nil
+}
+
+// Apply sets the client's PresenceCache.
+func (p *PresenceCache) Apply(c *Client) {
+	c.PresenceCache = p
+}
+
+// presenceNamespace returns the directory of the presence cache entries of the client's CAS and
+// instance.
+func (c *Client) presenceNamespace() string {
+	key := sha256.Sum256([]byte(c.CASConnection.Target() + "\x00" + c.InstanceName))
+	return filepath.Join(c.PresenceCache.dir, fmt.Sprintf("%x", key[:8]))
+}
+
+func presencePath(ns string, d digest.Digest) string {
+	return filepath.Join(ns, d.Hash[:2], fmt.Sprintf("%s_%d", d.Hash, d.Size))
+}
+
+// filterPresent returns the digests of ds not known to be present in the CAS.
+func (c *Client) filterPresent(ctx context.Context, ds []digest.Digest) []digest.Digest {
+	if c.PresenceCache == nil {
+		return ds
+	}
+	ns := c.presenceNamespace()
+	oldest := time.Now().Add(-c.PresenceCache.ttl)
+	if info, err := os.Stat(filepath.Join(ns, presenceEpochFile)); err == nil && info.ModTime().After(oldest) {
+		oldest = info.ModTime()
+	}
+	var unknown []digest.Digest
+	for _, d := range ds {
+		if info, err := os.Stat(presencePath(ns, d)); err != nil || !info.ModTime().After(oldest) {
+			unknown = append(unknown, d)
+		}
+	}
+	if skipped := len(ds) - len(unknown); skipped > 0 {
+		LogContextInfof(ctx, 3, "%d blobs known to be present in the CAS", skipped)
+	}
+	return unknown
+}
+
+// markPresent records that the blobs ds are present in the CAS. Failures only cost future queries,
+// so they are logged and ignored.
+func (c *Client) markPresent(ctx context.Context, ds []digest.Digest) {
+	if c.PresenceCache == nil || len(ds) == 0 {
+		return
+	}
+	ns := c.presenceNamespace()
+	now := time.Now()
+	for _, d := range ds {
+		path := presencePath(ns, d)
+		if err := os.Chtimes(path, now, now); err == nil || !os.IsNotExist(err) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			LogContextInfof(ctx, 2, "Failed to record the presence of %v: %v", d, err)
+			return
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			LogContextInfof(ctx, 2, "Failed to record the presence of %v: %v", d, err)
+			return
+		}
+	}
+}
+
+// notePresenceErr forgets all the blobs known to be present in the CAS if err shows that the
+// server is missing some of them.
+func (c *Client) notePresenceErr(ctx context.Context, err error) {
+	if c.PresenceCache == nil || err == nil {
+		return
+	}
+	if code := status.Code(err); code != codes.FailedPrecondition && code != codes.NotFound {
+		return
+	}
+	ns := c.presenceNamespace()
+	epoch := filepath.Join(ns, presenceEpochFile)
+	now := time.Now()
+	werr := os.MkdirAll(ns, 0755)
+	if werr == nil {
+		werr = os.WriteFile(epoch, nil, 0644)
+	}
+	if werr == nil {
+		werr = os.Chtimes(epoch, now, now)
+	}
+	if werr != nil {
+		LogContextInfof(ctx, 2, "Failed to invalidate the presence cache: %v", werr)
+		return
+	}
+	LogContextInfof(ctx, 2, "Server reported missing blobs, invalidated the presence cache")
+}
@@ -0,0 +1,131 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestPresenceCache(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	pc, err := client.NewPresenceCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPresenceCache() failed: %v", err)
+	}
+	// Two clients sharing the cache, as two processes would.
+	c1, err := e.Server.NewTestClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	c2, err := e.Server.NewTestClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	pc.Apply(c1)
+	pc.Apply(c2)
+
+	fooDg := e.Server.CAS.Put(fooBlob)
+	missingDg := digest.NewFromBlob([]byte("missing"))
+	dgs := []digest.Digest{fooDg, missingDg}
+	for i, c := range []*client.Client{c1, c2, c1} {
+		missing, err := c.MissingBlobs(ctx, dgs)
+		if err != nil {
+			t.Fatalf("MissingBlobs() #%d failed: %v", i, err)
+		}
+		if len(missing) != 1 || missing[0] != missingDg {
+			t.Errorf("MissingBlobs() #%d = %v, want [%v]", i, missing, missingDg)
+		}
+	}
+	if got := e.Server.CAS.BlobMissingReqs(fooDg); got != 1 {
+		t.Errorf("foo was queried %d times, want 1", got)
+	}
+	if got := e.Server.CAS.BlobMissingReqs(missingDg); got != 3 {
+		t.Errorf("the missing blob was queried %d times, want 3", got)
+	}
+
+	// Uploaded blobs are known to be present.
+	if _, _, err := c1.UploadIfMissing(ctx, uploadinfo.EntryFromBlob([]byte("missing"))); err != nil {
+		t.Fatalf("UploadIfMissing() failed: %v", err)
+	}
+	if missing, err := c2.MissingBlobs(ctx, dgs); err != nil || len(missing) != 0 {
+		t.Errorf("MissingBlobs() after upload = %v, %v, want none", missing, err)
+	}
+	if got := e.Server.CAS.BlobMissingReqs(missingDg); got != 4 {
+		t.Errorf("the uploaded blob was queried %d times, want 4", got)
+	}
+}
+
+func TestPresenceCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	pc, err := client.NewPresenceCache(t.TempDir(), time.Nanosecond)
+	if err != nil {
+		t.Fatalf("NewPresenceCache() failed: %v", err)
+	}
+	pc.Apply(c)
+	defer func() { c.PresenceCache = nil }()
+
+	fooDg := e.Server.CAS.Put(fooBlob)
+	for i := 0; i < 2; i++ {
+		if _, err := c.MissingBlobs(ctx, []digest.Digest{fooDg}); err != nil {
+			t.Fatalf("MissingBlobs() failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := e.Server.CAS.BlobMissingReqs(fooDg); got != 2 {
+		t.Errorf("foo was queried %d times, want 2 as its presence expired", got)
+	}
+}
+
+func TestPresenceCacheInvalidatedByExecute(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	pc, err := client.NewPresenceCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPresenceCache() failed: %v", err)
+	}
+	pc.Apply(c)
+	defer func() { c.PresenceCache = nil }()
+
+	fooDg := e.Server.CAS.Put(fooBlob)
+	if _, err := c.MissingBlobs(ctx, []digest.Digest{fooDg}); err != nil {
+		t.Fatalf("MissingBlobs() failed: %v", err)
+	}
+	// The invalidation must be newer than the presence of foo on coarse clocks.
+	time.Sleep(10 * time.Millisecond)
+
+	cmd := &command.Command{Args: []string{"tool"}, ExecRoot: e.ExecRoot}
+	res := &command.Result{Status: command.RemoteErrorResultStatus, Err: status.Error(codes.FailedPrecondition, "missing inputs")}
+	_, acDg := e.Set(cmd, command.DefaultExecutionOptions(), res)
+	op, err := c.ExecuteAndWait(ctx, &repb.ExecuteRequest{InstanceName: c.InstanceName, ActionDigest: acDg.ToProto()})
+	if err != nil {
+		t.Fatalf("ExecuteAndWait() failed: %v", err)
+	}
+	if st := client.OperationStatus(op); st.Code() != codes.FailedPrecondition {
+		t.Fatalf("ExecuteAndWait() status = %v, want FAILED_PRECONDITION", st)
+	}
+	if _, err := c.MissingBlobs(ctx, []digest.Digest{fooDg}); err != nil {
+		t.Fatalf("MissingBlobs() failed: %v", err)
+	}
+	if got := e.Server.CAS.BlobMissingReqs(fooDg); got != 2 {
+		t.Errorf("foo was queried %d times, want 2 as the cache was invalidated", got)
+	}
+}
@@ -525,3 +525,24 @@ func TestNonStreamingRpcRetriesSleep(t *testing.T) {
 		t.Errorf("QueryWriteStatus(ctx, {}) = %v; expected Unimplemented error (status.FromError failed)", err)
 	}
 }
+
+func TestWaitOperationRetries(t *testing.T) {
+	t.Parallel()
+	f := setup(t)
+	defer f.shutDown()
+
+	op, err := f.client.WaitOperation(f.ctx, "dummy", nil)
+	if err != nil {
+		t.Fatalf("client.WaitOperation(ctx, dummy) = %v", err)
+	}
+	if !op.Done {
+		t.Errorf("client.WaitOperation(ctx, dummy) returned an operation not done")
+	}
+	if st := client.OperationStatus(op); st == nil || st.Code() != codes.Aborted {
+		t.Errorf("client.WaitOperation(ctx, dummy) returned status %v, expected Aborted", st)
+	}
+	// 3 separate transient WaitExecution errors + the final successful call.
+	if f.fake.numCalls["WaitExecution"] != 4 {
+		t.Errorf("Expected 4 WaitExecution calls, got %v", f.fake.numCalls["WaitExecution"])
+	}
+}
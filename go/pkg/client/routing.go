@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ExecutionRoute sends the executions of the actions whose platform matches Properties to another
+// execution endpoint or instance, for example a fleet of Windows workers. The CAS and action cache
+// remain those of the client, so the routed instance must share them.
+type ExecutionRoute struct {
+	// Properties are the platform properties that an action must all have to match the route. An
+	// empty map matches every action.
+	Properties map[string]string
+	// InstanceName is the instance executing the matching actions. If empty, the client's
+	// InstanceName is used.
+	InstanceName string
+	// Connection is the connection to the execution service of the matching actions. If nil, the
+	// client's Connection is used. The caller remains responsible for closing it.
+	Connection *grpc.ClientConn
+}
+
+// ExecutionRoutes are the routing rules of executions. The first matching route is used, and
+// actions matching none are executed by the client's instance.
+type ExecutionRoutes []*ExecutionRoute
+
+// Apply sets the client's ExecutionRoutes.
+func (r ExecutionRoutes) Apply(c *Client) {
+	c.ExecutionRoutes = r
+}
+
+// WithExecutionRoutes sets the rules routing executions to other endpoints or instances depending
+// on the platform properties of the actions.
+func WithExecutionRoutes(routes ...*ExecutionRoute) Option {
+	return WithOpts(ExecutionRoutes(routes))
+}
+
+func (r *ExecutionRoute) matches(platform map[string]string) bool {
+	for name, value := range r.Properties {
+		if v, ok := platform[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// routeExecution returns the execution client to send req to, and req with the instance name of
+// the matching route, if any.
+func (c *Client) routeExecution(ctx context.Context, req *repb.ExecuteRequest) (regrpc.ExecutionClient, *repb.ExecuteRequest, error) {
+	if len(c.ExecutionRoutes) == 0 {
+		return c.execution, req, nil
+	}
+	platform, err := c.actionPlatform(ctx, req.ActionDigest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the platform of action %v for routing: %w", req.ActionDigest, err)
+	}
+	for i, r := range c.ExecutionRoutes {
+		if !r.matches(platform) {
+			continue
+		}
+		LogContextInfof(ctx, 2, "Routing the execution of action %v with route %d", req.ActionDigest, i)
+		exec := c.execution
+		if r.Connection != nil {
+			exec = regrpc.NewExecutionClient(r.Connection)
+		}
+		if r.InstanceName != "" && r.InstanceName != req.InstanceName {
+			req = proto.Clone(req).(*repb.ExecuteRequest)
+			req.InstanceName = r.InstanceName
+		}
+		return exec, req, nil
+	}
+	return c.execution, req, nil
+}
+
+// actionPlatform reads the platform properties of the action from the CAS. They are taken from the
+// Action, or from its Command for actions built for servers not supporting Action platforms.
+func (c *Client) actionPlatform(ctx context.Context, acDg *repb.Digest) (map[string]string, error) {
+	dg, err := digest.NewFromProto(acDg)
+	if err != nil {
+		return nil, err
+	}
+	ac := &repb.Action{}
+	if _, err := c.ReadProto(ctx, dg, ac); err != nil {
+		return nil, err
+	}
+	pl := ac.Platform
+	if pl == nil {
+		cmdDg, err := digest.NewFromProto(ac.CommandDigest)
+		if err != nil {
+			return nil, err
+		}
+		cmd := &repb.Command{}
+		if _, err := c.ReadProto(ctx, cmdDg, cmd); err != nil {
+			return nil, err
+		}
+		pl = cmd.Platform
+	}
+	props := make(map[string]string, len(pl.GetProperties()))
+	for _, p := range pl.GetProperties() {
+		props[p.Name] = p.Value
+	}
+	return props, nil
+}
@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/ptypes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestExecutionRoutes(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	// The Windows fleet, sharing the action with the main one.
+	win, winCleanup := fakes.NewTestEnv(t)
+	defer winCleanup()
+	conn, err := win.Server.NewClientConn(ctx)
+	if err != nil {
+		t.Fatalf("NewClientConn() failed: %v", err)
+	}
+	defer conn.Close()
+
+	cmd := &command.Command{Args: []string{"tool"}, ExecRoot: e.ExecRoot, Platform: map[string]string{"OSFamily": "windows", "Pool": "default"}}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.NonZeroExitResultStatus, ExitCode: 1})
+	if _, winAcDg := win.Set(cmd, opt, &command.Result{Status: command.NonZeroExitResultStatus, ExitCode: 2}); winAcDg != acDg {
+		t.Fatalf("action digests differ between fleets: %v != %v", winAcDg, acDg)
+	}
+
+	c := e.Client.GrpcClient
+	defer func() { c.ExecutionRoutes = nil }()
+	tests := []struct {
+		name     string
+		routes   client.ExecutionRoutes
+		wantExit int32
+	}{
+		{name: "NoRoutes", wantExit: 1},
+		{
+			name:     "NoMatch",
+			routes:   client.ExecutionRoutes{{Properties: map[string]string{"OSFamily": "linux"}, Connection: conn}},
+			wantExit: 1,
+		},
+		{
+			name: "Match",
+			routes: client.ExecutionRoutes{
+				{Properties: map[string]string{"OSFamily": "windows", "Pool": "large"}},
+				{Properties: map[string]string{"OSFamily": "windows"}, Connection: conn, InstanceName: "windows"},
+			},
+			wantExit: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e.Server.ActionCache.Clear()
+			win.Server.ActionCache.Clear()
+			c.ExecutionRoutes = tc.routes
+			op, err := c.ExecuteAndWait(ctx, &repb.ExecuteRequest{InstanceName: c.InstanceName, ActionDigest: acDg.ToProto(), SkipCacheLookup: true})
+			if err != nil {
+				t.Fatalf("ExecuteAndWait() failed: %v", err)
+			}
+			resp := &repb.ExecuteResponse{}
+			if err := ptypes.UnmarshalAny(op.GetResponse(), resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if got := resp.Result.GetExitCode(); got != tc.wantExit {
+				t.Errorf("ExecuteAndWait() exit code = %d, want %d", got, tc.wantExit)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
+)
+
+// ErrShuttingDown is returned by transfers and executions started after Shutdown was called.
+var ErrShuttingDown = errors.New("client is shutting down")
+
+// opTracker counts the transfers and executions in flight, so that Shutdown can wait for them.
+type opTracker struct {
+	mu       sync.Mutex
+	inFlight int
+	closing  bool
+	// idle is closed once closing is set and there are no operations in flight.
+	idle chan struct{}
+}
+
+// inFlightKey marks contexts of operations that are already tracked, so that nested calls, e.g.
+// DownloadActionOutputs calling DownloadFiles, keep working while the client shuts down.
+type inFlightKey struct{}
+
+// startOp registers an operation with the client. The returned function must be called when the
+// operation is complete. It returns ErrShuttingDown if Shutdown has been called, unless ctx belongs
+// to an operation that is already in flight.
+func (c *Client) startOp(ctx context.Context) (context.Context, func(), error) {
+	if ctx.Value(inFlightKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+	t := &c.ops
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return ctx, nil, ErrShuttingDown
+	}
+	t.inFlight++
+	return context.WithValue(ctx, inFlightKey{}, true), func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.inFlight--
+		if t.closing && t.inFlight == 0 {
+			close(t.idle)
+		}
+	}, nil
+}
+
+// Shutdown gracefully closes the client. It stops accepting new uploads, downloads and
+// executions, waits for the ones in flight to complete, and then closes the underlying gRPC
+// connection(s) with Close.
+//
+// If ctx is done before the in-flight operations complete, the connections are closed anyway,
+// which aborts the remaining operations, and an error wrapping ctx.Err() is returned.
+// Shutdown must be called at most once, and Close must not be called after it.
+func (c *Client) Shutdown(ctx context.Context) error {
+	t := &c.ops
+	t.mu.Lock()
+	t.closing = true
+	t.idle = make(chan struct{})
+	if t.inFlight == 0 {
+		close(t.idle)
+	}
+	t.mu.Unlock()
+
+	var waitErr error
+	select {
+	case <-t.idle:
+	case <-ctx.Done():
+		t.mu.Lock()
+		n := t.inFlight
+		t.mu.Unlock()
+		logger.Warningf(ctx, "Shutdown: closing connections with %d operation(s) still in flight", n)
+		waitErr = fmt.Errorf("%d operation(s) still in flight: %w", n, ctx.Err())
+	}
+	if err := c.Close(); err != nil {
+		return err
+	}
+	return waitErr
+}
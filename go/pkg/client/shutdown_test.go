@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestShutdownWaitsForInFlight(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c, err := e.Server.NewTestClient(ctx)
+	if err != nil {
+		t.Fatalf("NewTestClient() failed: %v", err)
+	}
+
+	blob := []byte("in flight")
+	dg := e.Server.CAS.Put(blob)
+	started := make(chan bool)
+	release := make(chan bool)
+	e.Server.CAS.PerDigestBlockFn[dg] = func() {
+		started <- true
+		<-release
+	}
+
+	readErr := make(chan error)
+	go func() {
+		_, _, err := c.ReadBlob(ctx, dg)
+		readErr <- err
+	}()
+	<-started
+
+	shutdownErr := make(chan error)
+	go func() {
+		shutdownErr <- c.Shutdown(ctx)
+	}()
+	// Wait for Shutdown to start rejecting new operations.
+	for {
+		if _, _, err := c.UploadIfMissing(ctx); errors.Is(err, client.ErrShuttingDown) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown() = %v before the in-flight read completed", err)
+	default:
+	}
+
+	close(release)
+	if err := <-readErr; err != nil {
+		t.Errorf("ReadBlob() failed: %v", err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown() failed: %v", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c, err := e.Server.NewTestClient(ctx)
+	if err != nil {
+		t.Fatalf("NewTestClient() failed: %v", err)
+	}
+
+	dg := e.Server.CAS.Put([]byte("stuck"))
+	started := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+	e.Server.CAS.PerDigestBlockFn[dg] = func() {
+		started <- true
+		<-release
+	}
+	go c.ReadBlob(ctx, dg)
+	<-started
+
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(sctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/splice"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultSplitSpliceThreshold is the default SplitSpliceThreshold. It is well above
+// splice.MaxChunkSize, so that chunks are never split themselves.
+const DefaultSplitSpliceThreshold = 16 * 1024 * 1024
+
+// SupportsSplitBlob returns whether the CAS advertises the SplitBlob RPC.
+func (c *Client) SupportsSplitBlob() bool {
+	return c.serverCaps != nil && splice.SplitBlobSupport(c.serverCaps.CacheCapabilities)
+}
+
+// SupportsSpliceBlob returns whether the CAS advertises the SpliceBlob RPC.
+func (c *Client) SupportsSpliceBlob() bool {
+	return c.serverCaps != nil && splice.SpliceBlobSupport(c.serverCaps.CacheCapabilities)
+}
+
+// SplitBlob asks the CAS to split the blob d into chunks, and returns their digests in order. The
+// chunks are then available in the CAS.
+func (c *Client) SplitBlob(ctx context.Context, d digest.Digest) ([]digest.Digest, error) {
+	req := (&splice.SplitBlobRequest{InstanceName: c.InstanceName, BlobDigest: d}).Message()
+	resp := (&splice.SplitBlobResponse{}).Message()
+	opts := c.RPCOpts()
+	err := c.retry(ctx, func() error {
+		return c.CallWithTimeout(ctx, "SplitBlob", func(ctx context.Context) error {
+			return c.CASConnection.Invoke(ctx, splice.SplitBlobMethod, req, resp, opts...)
+		})
+	})
+	if err != nil {
+		return nil, statusWrap(err)
+	}
+	res := &splice.SplitBlobResponse{}
+	if err := res.FromMessage(resp); err != nil {
+		return nil, err
+	}
+	var sz int64
+	for _, ch := range res.ChunkDigests {
+		sz += ch.Size
+	}
+	if sz != d.Size {
+		return nil, fmt.Errorf("chunks of %v add up to %d bytes", d, sz)
+	}
+	return res.ChunkDigests, nil
+}
+
+// SpliceBlob asks the CAS to concatenate the chunks, which must already be in the CAS, into the
+// blob d.
+func (c *Client) SpliceBlob(ctx context.Context, d digest.Digest, chunks []digest.Digest) error {
+	req := (&splice.SpliceBlobRequest{InstanceName: c.InstanceName, BlobDigest: d, ChunkDigests: chunks}).Message()
+	resp := (&splice.SpliceBlobResponse{}).Message()
+	opts := c.RPCOpts()
+	err := c.retry(ctx, func() error {
+		return c.CallWithTimeout(ctx, "SpliceBlob", func(ctx context.Context) error {
+			return c.CASConnection.Invoke(ctx, splice.SpliceBlobMethod, req, resp, opts...)
+		})
+	})
+	if err != nil {
+		// Chunks skipped because they were known to be present may have been evicted.
+		c.notePresenceErr(ctx, err)
+		return statusWrap(err)
+	}
+	return nil
+}
+
+// shouldSplitSplice returns whether a blob of the given size should be transferred as chunks, if
+// the server supports it.
+func (c *Client) shouldSplitSplice(size int64) bool {
+	return c.SplitSpliceThreshold >= 0 && size >= int64(c.SplitSpliceThreshold) && atomic.LoadInt32(&c.splitSpliceOff) == 0
+}
+
+// noteSplitSpliceErr stops further split and splice attempts if err shows the server does not
+// implement them, despite its capabilities.
+func (c *Client) noteSplitSpliceErr(err error) {
+	if status.Code(err) == codes.Unimplemented {
+		atomic.StoreInt32(&c.splitSpliceOff, 1)
+	}
+}
+
+// writeBlobSpliced uploads the chunks of blob that are missing from the CAS, and splices them into
+// blob, whose digest is dg.
+func (c *Client) writeBlobSpliced(ctx context.Context, blob []byte, dg digest.Digest) error {
+	chunks := splice.Chunks(blob)
+	entries := make([]*uploadinfo.Entry, len(chunks))
+	dgs := make([]digest.Digest, len(chunks))
+	for i, ch := range chunks {
+		entries[i] = uploadinfo.EntryFromBlob(ch)
+		dgs[i] = entries[i].Digest
+	}
+	if _, _, err := c.UploadIfMissing(ctx, entries...); err != nil {
+		return err
+	}
+	err := c.SpliceBlob(ctx, dg, dgs)
+	c.noteSplitSpliceErr(err)
+	return err
+}
+
+// readBlobSplit downloads the blob d to fpath by fetching only the chunks that are not already in
+// the previous contents of fpath. Previous contents are only reused if the server cuts chunks the
+// same way splice.Chunks does.
+func (c *Client) readBlobSplit(ctx context.Context, d digest.Digest, fpath string) (*MovedBytesMetadata, error) {
+	dgs, err := c.SplitBlob(ctx, d)
+	if err != nil {
+		c.noteSplitSpliceErr(err)
+		return nil, err
+	}
+	chunks := make(map[digest.Digest][]byte)
+	if old, err := os.ReadFile(fpath); err == nil {
+		for _, ch := range splice.Chunks(old) {
+			chunks[digest.NewFromBlob(ch)] = ch
+		}
+	}
+	stats := &MovedBytesMetadata{Requested: d.Size}
+	var missing []digest.Digest
+	for _, dg := range dgs {
+		if _, ok := chunks[dg]; !ok {
+			chunks[dg] = nil
+			missing = append(missing, dg)
+		}
+	}
+	for _, batch := range c.makeBatches(ctx, missing, true) {
+		if len(batch) == 1 && batch[0].Size > c.maxBatchSize() {
+			data, m, err := c.ReadBlob(ctx, batch[0])
+			if err != nil {
+				return nil, err
+			}
+			chunks[batch[0]] = data
+			stats.LogicalMoved += m.LogicalMoved
+			stats.RealMoved += m.RealMoved
+			continue
+		}
+		got, err := c.BatchDownloadBlobs(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for dg, data := range got {
+			chunks[dg] = data
+			stats.LogicalMoved += dg.Size
+			stats.RealMoved += dg.Size
+		}
+	}
+	stats.Cached = stats.Requested - stats.LogicalMoved
+
+	f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, c.RegularMode)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := digest.HashFn.New()
+	w := io.MultiWriter(f, h)
+	for _, dg := range dgs {
+		if _, err := w.Write(chunks[dg]); err != nil {
+			return nil, err
+		}
+	}
+	if got := (digest.Digest{Hash: fmt.Sprintf("%x", h.Sum(nil)), Size: d.Size}); got != d {
+		return nil, fmt.Errorf("spliced chunks of %v have digest %v", d, got)
+	}
+	return stats, nil
+}
@@ -0,0 +1,140 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/splice"
+)
+
+// newSpliceEnv returns a fake server advertising split and splice as requested, and a client
+// transferring blobs of at least 1MiB as chunks.
+func newSpliceEnv(t *testing.T, supported bool) (*fakes.Server, *client.Client) {
+	t.Helper()
+	s, err := fakes.NewServer(t)
+	if err != nil {
+		t.Fatalf("fakes.NewServer() failed: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	s.CAS.SplitSplice = supported
+	c, err := s.NewTestClient(context.Background())
+	if err != nil {
+		t.Fatalf("NewTestClient() failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	client.SplitSpliceThreshold(1024 * 1024).Apply(c)
+	return s, c
+}
+
+// editedBlobs returns a random blob, and a copy with a few bytes inserted in the middle.
+func editedBlobs() ([]byte, []byte) {
+	orig := make([]byte, 6*1024*1024)
+	rand.New(rand.NewSource(42)).Read(orig)
+	edited := append(append(append([]byte{}, orig[:len(orig)/2]...), []byte("edit")...), orig[len(orig)/2:]...)
+	return orig, edited
+}
+
+func TestWriteBlobSpliced(t *testing.T) {
+	ctx := context.Background()
+	s, c := newSpliceEnv(t, true)
+	if !c.SupportsSplitBlob() || !c.SupportsSpliceBlob() {
+		t.Fatalf("SupportsSplitBlob() = %v, SupportsSpliceBlob() = %v, want true", c.SupportsSplitBlob(), c.SupportsSpliceBlob())
+	}
+	orig, edited := editedBlobs()
+	writes := make(map[digest.Digest]int)
+	for _, blob := range [][]byte{orig, edited} {
+		for _, ch := range splice.Chunks(edited) {
+			d := digest.NewFromBlob(ch)
+			writes[d] = s.CAS.BlobWrites(d)
+		}
+		dg, err := c.WriteBlob(ctx, blob)
+		if err != nil {
+			t.Fatalf("WriteBlob() failed: %v", err)
+		}
+		if got, ok := s.CAS.Get(dg); !ok || !bytes.Equal(got, blob) {
+			t.Errorf("CAS does not contain the spliced blob %v", dg)
+		}
+	}
+	if s.CAS.SpliceReqs() != 2 {
+		t.Errorf("SpliceReqs() = %d, want 2", s.CAS.SpliceReqs())
+	}
+	// Only the chunks around the edit are uploaded for the edited blob.
+	uploaded := 0
+	for d, n := range writes {
+		if s.CAS.BlobWrites(d) > n {
+			uploaded++
+		}
+	}
+	if uploaded == 0 || uploaded > 2 {
+		t.Errorf("%d chunks of %d were uploaded for the edited blob, want 1 or 2", uploaded, len(writes))
+	}
+}
+
+func TestReadBlobToFileSplit(t *testing.T) {
+	ctx := context.Background()
+	s, c := newSpliceEnv(t, true)
+	orig, edited := editedBlobs()
+	dg := s.CAS.Put(edited)
+	path := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(path, orig, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := c.ReadBlobToFile(ctx, dg, path)
+	if err != nil {
+		t.Fatalf("ReadBlobToFile() failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, edited) {
+		t.Errorf("ReadBlobToFile() wrote %d bytes different from the blob", len(got))
+	}
+	if s.CAS.SplitReqs() != 1 {
+		t.Errorf("SplitReqs() = %d, want 1", s.CAS.SplitReqs())
+	}
+	if stats.Requested != dg.Size || stats.Cached == 0 || stats.LogicalMoved+stats.Cached != dg.Size {
+		t.Errorf("ReadBlobToFile() stats = %+v, want some of the %d bytes cached", stats, dg.Size)
+	}
+}
+
+func TestSplitSpliceFallback(t *testing.T) {
+	ctx := context.Background()
+	_, edited := editedBlobs()
+	tests := []struct {
+		name string
+		// advertised is whether the capabilities advertise split and splice, which are never served.
+		advertised bool
+	}{
+		{"NotAdvertised", false},
+		{"Unimplemented", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, c := newSpliceEnv(t, tc.advertised)
+			s.CAS.SplitSplice = false
+			dg, err := c.WriteBlob(ctx, edited)
+			if err != nil {
+				t.Fatalf("WriteBlob() failed: %v", err)
+			}
+			if got, ok := s.CAS.Get(dg); !ok || !bytes.Equal(got, edited) {
+				t.Errorf("CAS does not contain the written blob %v", dg)
+			}
+			path := filepath.Join(t.TempDir(), "blob")
+			if _, err := c.ReadBlobToFile(ctx, dg, path); err != nil {
+				t.Fatalf("ReadBlobToFile() failed: %v", err)
+			}
+			if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, edited) {
+				t.Errorf("ReadBlobToFile() wrote %d bytes different from the blob, err %v", len(got), err)
+			}
+		})
+	}
+}
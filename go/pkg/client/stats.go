@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"google.golang.org/grpc/codes"
+)
+
+// Stats are the lifetime statistics of a Client, for reporting the remote execution footprint of
+// a build.
+type Stats struct {
+	// ActionsExecuted is the number of Execute calls that completed.
+	ActionsExecuted int64 `json:"actions_executed"`
+	// ActionCacheHits is the number of action results found in the action cache, either through
+	// GetActionResult or as cached results of Execute.
+	ActionCacheHits int64 `json:"action_cache_hits"`
+	// ActionCacheMisses is the number of GetActionResult calls that found no result.
+	ActionCacheMisses int64 `json:"action_cache_misses"`
+	// BytesUploaded is the number of blob bytes sent to the CAS, after compression.
+	BytesUploaded int64 `json:"bytes_uploaded"`
+	// BytesDownloaded is the number of blob bytes received from the CAS, before decompression.
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	// BlobsUploaded is the number of blobs written to the CAS.
+	BlobsUploaded int64 `json:"blobs_uploaded"`
+	// BlobsDownloaded is the number of blobs, or ranges of blobs, read from the CAS.
+	BlobsDownloaded int64 `json:"blobs_downloaded"`
+	// DedupedBlobs is the number of blobs that did not need uploading because they were already in
+	// the CAS.
+	DedupedBlobs int64 `json:"deduped_blobs"`
+	// DedupedBytes is the total size of the DedupedBlobs.
+	DedupedBytes int64 `json:"deduped_bytes"`
+	// Retries is the number of RPC attempts made after failed ones.
+	Retries int64 `json:"retries"`
+	// Errors is the number of failed RPC attempts per gRPC code, including attempts that were
+	// retried. It is written to stats files by code name, see BuildStats.
+	Errors map[codes.Code]int64 `json:"-"`
+}
+
+// String returns a one-line summary of the stats.
+func (s Stats) String() string {
+	var errs []string
+	for code, n := range s.Errors {
+		errs = append(errs, fmt.Sprintf("%v=%d", code, n))
+	}
+	sort.Strings(errs)
+	return fmt.Sprintf("actions executed: %d, action cache hits: %d, misses: %d, bytes uploaded: %d, downloaded: %d, blobs uploaded: %d, downloaded: %d, deduped blobs: %d (%d bytes), retries: %d, errors: [%s]",
+		s.ActionsExecuted, s.ActionCacheHits, s.ActionCacheMisses, s.BytesUploaded, s.BytesDownloaded,
+		s.BlobsUploaded, s.BlobsDownloaded, s.DedupedBlobs, s.DedupedBytes, s.Retries, strings.Join(errs, " "))
+}
+
+// LogStatsOnClose makes the client log a summary of its Stats when it is closed.
+type LogStatsOnClose bool
+
+// Apply sets the LogStatsOnClose flag on a client.
+func (l LogStatsOnClose) Apply(c *Client) {
+	c.LogStatsOnClose = l
+}
+
+// clientStats accumulates the Stats of a Client. The zero value is ready to use.
+type clientStats struct {
+	actionsExecuted   int64
+	actionCacheHits   int64
+	actionCacheMisses int64
+	bytesUploaded     int64
+	bytesDownloaded   int64
+	blobsUploaded     int64
+	blobsDownloaded   int64
+	dedupedBlobs      int64
+	dedupedBytes      int64
+	retries           int64
+
+	mu     sync.Mutex
+	errors map[codes.Code]int64
+}
+
+func (s *clientStats) addError(err error) {
+	if err == nil || err == io.EOF {
+		return
+	}
+	code := errorCode(err)
+	s.mu.Lock()
+	if s.errors == nil {
+		s.errors = make(map[codes.Code]int64)
+	}
+	s.errors[code]++
+	s.mu.Unlock()
+}
+
+// Stats returns the statistics accumulated by the client since it was created.
+func (c *Client) Stats() Stats {
+	s := &c.stats
+	res := Stats{
+		ActionsExecuted:   atomic.LoadInt64(&s.actionsExecuted),
+		ActionCacheHits:   atomic.LoadInt64(&s.actionCacheHits),
+		ActionCacheMisses: atomic.LoadInt64(&s.actionCacheMisses),
+		BytesUploaded:     atomic.LoadInt64(&s.bytesUploaded),
+		BytesDownloaded:   atomic.LoadInt64(&s.bytesDownloaded),
+		BlobsUploaded:     atomic.LoadInt64(&s.blobsUploaded),
+		BlobsDownloaded:   atomic.LoadInt64(&s.blobsDownloaded),
+		DedupedBlobs:      atomic.LoadInt64(&s.dedupedBlobs),
+		DedupedBytes:      atomic.LoadInt64(&s.dedupedBytes),
+		Retries:           atomic.LoadInt64(&s.retries),
+		Errors:            make(map[codes.Code]int64),
+	}
+	s.mu.Lock()
+	for code, n := range s.errors {
+		res.Errors[code] = n
+	}
+	s.mu.Unlock()
+	return res
+}
+
+// retry calls f using the client's Retrier, counting the retried attempts.
+func (c *Client) retry(ctx context.Context, f func() error) error {
+	attempts := 0
+	return c.Retrier.Do(ctx, func() error {
+		if attempts > 0 {
+			atomic.AddInt64(&c.stats.retries, 1)
+		}
+		attempts++
+		return f()
+	})
+}
+
+func (c *Client) logStats(ctx context.Context) {
+	logger.Infof(ctx, "Remote execution client stats: %v", c.Stats())
+}
+
+// countDeduped records the entries that were not uploaded because they were already present.
+func (c *Client) countDeduped(data []*uploadinfo.Entry, missing []digest.Digest) {
+	isMissing := make(map[digest.Digest]bool, len(missing))
+	for _, dg := range missing {
+		isMissing[dg] = true
+	}
+	seen := make(map[digest.Digest]bool, len(data))
+	for _, ue := range data {
+		dg := ue.Digest
+		if dg.IsEmpty() || seen[dg] || isMissing[dg] {
+			continue
+		}
+		seen[dg] = true
+		atomic.AddInt64(&c.stats.dedupedBlobs, 1)
+		atomic.AddInt64(&c.stats.dedupedBytes, dg.Size)
+	}
+}
@@ -0,0 +1,136 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	present := []byte("already there")
+	e.Server.CAS.Put(present)
+	fresh := []byte("new blob")
+	if _, _, err := c.UploadIfMissing(ctx, uploadinfo.EntryFromBlob(present), uploadinfo.EntryFromBlob(fresh)); err != nil {
+		t.Fatalf("UploadIfMissing() failed: %v", err)
+	}
+	if _, _, err := c.ReadBlob(ctx, digest.NewFromBlob(present)); err != nil {
+		t.Fatalf("ReadBlob() failed: %v", err)
+	}
+	acDg := digest.NewFromBlob([]byte("action"))
+	if _, err := c.CheckActionCache(ctx, acDg.ToProto()); err != nil {
+		t.Fatalf("CheckActionCache() failed: %v", err)
+	}
+	e.Server.ActionCache.Put(acDg, &repb.ActionResult{ExitCode: 0})
+	if _, err := c.CheckActionCache(ctx, acDg.ToProto()); err != nil {
+		t.Fatalf("CheckActionCache() failed: %v", err)
+	}
+
+	got := c.Stats()
+	if got.DedupedBlobs != 1 || got.DedupedBytes != int64(len(present)) {
+		t.Errorf("Stats() deduped %d blobs (%d bytes), want 1 (%d bytes)", got.DedupedBlobs, got.DedupedBytes, len(present))
+	}
+	if got.BytesUploaded != int64(len(fresh)) {
+		t.Errorf("Stats().BytesUploaded = %d, want %d", got.BytesUploaded, len(fresh))
+	}
+	if got.BytesDownloaded != int64(len(present)) {
+		t.Errorf("Stats().BytesDownloaded = %d, want %d", got.BytesDownloaded, len(present))
+	}
+	if got.BlobsUploaded != 1 || got.BlobsDownloaded != 1 {
+		t.Errorf("Stats() blobs uploaded, downloaded = %d, %d, want 1, 1", got.BlobsUploaded, got.BlobsDownloaded)
+	}
+	if got.ActionCacheHits != 1 || got.ActionCacheMisses != 1 {
+		t.Errorf("Stats() action cache hits, misses = %d, %d, want 1, 1", got.ActionCacheHits, got.ActionCacheMisses)
+	}
+	if got.Errors[codes.NotFound] != 1 {
+		t.Errorf("Stats().Errors = %v, want one NotFound", got.Errors)
+	}
+	if got.Retries != 0 {
+		t.Errorf("Stats().Retries = %d, want 0", got.Retries)
+	}
+}
+
+func TestBuildStats(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	cmd := &command.Command{Args: []string{"tool"}, ExecRoot: e.ExecRoot}
+	opt := &command.ExecutionOptions{AcceptCached: true, DownloadOutputs: true, DownloadOutErr: true}
+	e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+	for i := 0; i < 2; i++ {
+		if res, _ := e.Client.Run(ctx, cmd, opt, outerr.NewRecordingOutErr()); res.Err != nil {
+			t.Fatalf("Run() #%d failed: %v", i, res.Err)
+		}
+	}
+
+	bs := e.Client.GrpcClient.BuildStats()
+	if diff := cmp.Diff(map[string]int64{"SuccessResultStatus": 1, "CacheHitResultStatus": 1}, bs.ActionsByStatus); diff != "" {
+		t.Errorf("BuildStats().ActionsByStatus diff (-want +got):\n%s", diff)
+	}
+	if len(bs.Actions) != 2 || bs.Actions[0].ActionDigest != bs.Actions[1].ActionDigest {
+		t.Errorf("BuildStats().Actions = %+v, want the same action twice", bs.Actions)
+	}
+	if ps := bs.Phases[command.EventCheckActionCache]; ps == nil || ps.Count != 2 || ps.Max > ps.Total {
+		t.Errorf("BuildStats().Phases[%q] = %+v, want 2 consistent durations", command.EventCheckActionCache, ps)
+	}
+	if ps := bs.Phases[command.EventExecuteRemotely]; ps == nil || ps.Count != 1 {
+		t.Errorf("BuildStats().Phases[%q] = %+v, want 1 duration", command.EventExecuteRemotely, ps)
+	}
+	if bs.ActionCacheHits != 1 || bs.ErrorsByCode["NotFound"] != 1 {
+		t.Errorf("BuildStats() = %+v, want 1 action cache hit and 1 NotFound error", bs)
+	}
+	if bs.StartTime.IsZero() || bs.EndTime.Before(bs.StartTime) {
+		t.Errorf("BuildStats() time range = [%v, %v], want a valid range", bs.StartTime, bs.EndTime)
+	}
+}
+
+func TestStatsFile(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	path := filepath.Join(t.TempDir(), "stats.json")
+	c, err := e.Server.NewTestClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.StatsFile(path).Apply(c)
+	c.RecordAction(command.NewRemoteErrorResult(status.Error(codes.Unavailable, "down")), &command.Metadata{ActionDigest: digest.NewFromBlob([]byte("action"))})
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("stats file was not written: %v", err)
+	}
+	var got struct {
+		Actions         []*client.ActionStats `json:"actions"`
+		ActionsByStatus map[string]int64      `json:"actions_by_status"`
+	}
+	if err := json.Unmarshal(blob, &got); err != nil {
+		t.Fatalf("stats file is not valid JSON: %v\n%s", err, blob)
+	}
+	if len(got.Actions) != 1 || got.Actions[0].ErrorCode != "Unavailable" || got.ActionsByStatus["RemoteErrorResultStatus"] != 1 {
+		t.Errorf("stats file = %s, want one Unavailable remote error", blob)
+	}
+}
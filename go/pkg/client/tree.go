@@ -2,6 +2,7 @@ package client
 
 // This module provides functionality for constructing a Merkle tree of uploadable inputs.
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,10 +14,10 @@ import (
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
 
 	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
-	log "github.com/golang/glog"
 )
 
 // treeNode represents a file tree, which is an intermediate representation used to encode a Merkle
@@ -66,6 +67,12 @@ type TreeSymlinkOpts struct {
 	Preserved bool
 	// If true, the symlink target (if not dangling) is followed.
 	FollowsTarget bool
+	// Chains, if set, caches the resolution chains of the symlinks which are converted into their
+	// targets, whose metadata is then looked up by the path of the final target, so that the many
+	// symlinks to a file, e.g. in node_modules, share a single cache entry and it is hashed once.
+	// The metadata cache should be wrapped with filemetadata.NewSymlinkAwareCache for its
+	// invalidations to reach the chains. It is not used with WindowsOpts.
+	Chains *filemetadata.SymlinkCache
 }
 
 // DefaultTreeSymlinkOpts returns a default DefaultTreeSymlinkOpts object.
@@ -154,13 +161,13 @@ func getExecRootRelPaths(absPath, execRoot, workingDir, remoteWorkingDir string)
 	if remoteRelPath, err = getRemotePath(relPath, workingDir, remoteWorkingDir); err != nil {
 		return relPath, "", err
 	}
-	log.V(3).Infof("getExecRootRelPaths(%q, %q, %q, %q)=(%q, %q)", absPath, execRoot, workingDir, remoteWorkingDir, relPath, remoteRelPath)
+	logger.Logf(context.Background(), 3, "getExecRootRelPaths(%q, %q, %q, %q)=(%q, %q)", absPath, execRoot, workingDir, remoteWorkingDir, relPath, remoteRelPath)
 	return relPath, remoteRelPath, nil
 }
 
 // loadFiles reads all files specified by the given InputSpec (descending into subdirectories
 // recursively), and loads their contents into the provided map.
-func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*command.InputExclusion, filesToProcess []string, fs map[string]*fileSysNode, cache filemetadata.Cache, opts *TreeSymlinkOpts) error {
+func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*command.InputExclusion, filesToProcess []string, fs map[string]*fileSysNode, cache filemetadata.Cache, opts *TreeSymlinkOpts, win *WindowsOpts) error {
 	if opts == nil {
 		opts = DefaultTreeSymlinkOpts()
 	}
@@ -177,20 +184,38 @@ func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*comma
 		if err != nil {
 			return err
 		}
-		meta := cache.Get(absPath)
+		var meta *filemetadata.Metadata
+		if opts.Chains != nil && !opts.Preserved && win == nil {
+			meta = resolvedMetadata(absPath, cache, opts.Chains)
+		} else {
+			meta = cache.Get(absPath)
+		}
+		preserved := opts.Preserved
+		if meta.Symlink != nil && meta.Symlink.IsJunction && win != nil {
+			switch win.Junctions {
+			case FollowJunctions:
+				preserved = false
+			case RejectJunctions:
+				return fmt.Errorf("%v is a directory junction, which are rejected by the WindowsOpts", absPath)
+			}
+		}
 		switch {
 		// An implication of this is that, if a path is a symlink to a
 		// directory, then the symlink attribute takes precedence.
-		case meta.Symlink != nil && meta.Symlink.IsDangling && !opts.Preserved:
+		case meta.Symlink != nil && meta.Symlink.IsDangling && !preserved:
 			// For now, we do not treat a dangling symlink as an error. In the case
 			// where the symlink is not preserved (i.e. needs to be converted to a
 			// file), we simply ignore this path in the finalized tree.
 			continue
-		case meta.Symlink != nil && opts.Preserved:
+		case meta.Symlink != nil && preserved:
 			if shouldIgnore(absPath, command.SymlinkInputType, excl) {
 				continue
 			}
-			targetExecRoot, targetSymDir, err := getTargetRelPath(execRoot, normPath, meta.Symlink)
+			symMeta := meta.Symlink
+			if win != nil {
+				symMeta = &filemetadata.SymlinkMetadata{Target: stripLongPathPrefix(symMeta.Target), IsDangling: symMeta.IsDangling}
+			}
+			targetExecRoot, targetSymDir, err := getTargetRelPath(execRoot, normPath, symMeta)
 			if err != nil {
 				return err
 			}
@@ -200,7 +225,8 @@ func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*comma
 				// an absolute path. Since the remote worker will map the exec root
 				// to a different directory, we must strip away the local exec root.
 				// See https://github.com/bazelbuild/remote-apis-sdks/pull/229#discussion_r524830458
-				symlink: &symlinkNode{target: targetSymDir},
+				// Symlink targets are always slash-separated remotely.
+				symlink: &symlinkNode{target: filepath.ToSlash(targetSymDir)},
 			}
 
 			if !meta.Symlink.IsDangling && opts.FollowsTarget {
@@ -245,7 +271,7 @@ func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*comma
 			fs[remoteNormPath] = &fileSysNode{
 				file: &fileNode{
 					ue:           uploadinfo.EntryFromFile(meta.Digest, absPath),
-					isExecutable: meta.IsExecutable,
+					isExecutable: win.isExecutable(absPath, meta.IsExecutable),
 				},
 			}
 		}
@@ -253,9 +279,26 @@ func loadFiles(execRoot, localWorkingDir, remoteWorkingDir string, excl []*comma
 	return nil
 }
 
+// resolvedMetadata returns the metadata of the final target of path, resolving the symlinks with
+// chains, so that all the symlinks to a file share its cache entry.
+func resolvedMetadata(path string, cache filemetadata.Cache, chains *filemetadata.SymlinkCache) *filemetadata.Metadata {
+	ch, err := chains.Resolve(path)
+	if err != nil || len(ch.Links) == 0 {
+		// Not a symlink, or an error which Get reports.
+		return cache.Get(path)
+	}
+	if ch.IsDangling {
+		return &filemetadata.Metadata{Digest: digest.Empty, Symlink: &filemetadata.SymlinkMetadata{Target: ch.Target, IsDangling: true}}
+	}
+	return cache.Get(ch.Target)
+}
+
 // ComputeMerkleTree packages an InputSpec into uploadable inputs, returned as uploadinfo.Entrys
 func (c *Client) ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir string, is *command.InputSpec, cache filemetadata.Cache) (root digest.Digest, inputs []*uploadinfo.Entry, stats *TreeStats, err error) {
 	stats = &TreeStats{}
+	if execRoot, err = c.WindowsOpts.normalizePath(execRoot); err != nil {
+		return digest.Empty, nil, nil, err
+	}
 	fs := make(map[string]*fileSysNode)
 	for _, i := range is.VirtualInputs {
 		if i.Path == "" {
@@ -279,7 +322,10 @@ func (c *Client) ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir string
 			},
 		}
 	}
-	if err := loadFiles(execRoot, workingDir, remoteWorkingDir, is.InputExclusions, is.Inputs, fs, cache, treeSymlinkOpts(c.TreeSymlinkOpts, is.SymlinkBehavior)); err != nil {
+	if err := loadFiles(execRoot, workingDir, remoteWorkingDir, is.InputExclusions, is.Inputs, fs, cache, treeSymlinkOpts(c.TreeSymlinkOpts, is.SymlinkBehavior), c.WindowsOpts); err != nil {
+		return digest.Empty, nil, nil, err
+	}
+	if err := c.WindowsOpts.checkCaseCollisions(fsPaths(fs)); err != nil {
 		return digest.Empty, nil, nil, err
 	}
 	ft, err := buildTree(fs)
@@ -297,6 +343,56 @@ func (c *Client) ComputeMerkleTree(execRoot, workingDir, remoteWorkingDir string
 	return root, inputs, stats, nil
 }
 
+// UploadDirectoryOptions configures UploadDirectory.
+type UploadDirectoryOptions struct {
+	// InputExclusions excludes matching files and directories from the upload. The regular
+	// expressions are matched against absolute paths.
+	InputExclusions []*command.InputExclusion
+	// SymlinkBehavior specifies how symlinks under the directory are handled.
+	SymlinkBehavior command.SymlinkBehaviorType
+	// Cache is used to look up file digests. If nil, files are digested without caching.
+	Cache filemetadata.Cache
+}
+
+// UploadDirectory computes the Merkle tree of the local directory at path, uploads the blobs that
+// are missing from the CAS, and returns the digest of the root directory along with the tree
+// statistics.
+func (c *Client) UploadDirectory(ctx context.Context, path string, opts *UploadDirectoryOptions) (digest.Digest, *TreeStats, error) {
+	if opts == nil {
+		opts = &UploadDirectoryOptions{}
+	}
+	cache := opts.Cache
+	if cache == nil {
+		cache = filemetadata.NewNoopCache()
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	is := &command.InputSpec{
+		Inputs:          []string{"."},
+		InputExclusions: opts.InputExclusions,
+		SymlinkBehavior: opts.SymlinkBehavior,
+	}
+	root, inputs, stats, err := c.ComputeMerkleTree(absPath, "", "", is, cache)
+	if err != nil {
+		return digest.Empty, nil, err
+	}
+	if _, _, err := c.UploadIfMissing(ctx, inputs...); err != nil {
+		return digest.Empty, nil, err
+	}
+	return root, stats, nil
+}
+
+// fsPaths returns the paths of the nodes of fs.
+func fsPaths(fs map[string]*fileSysNode) []string {
+	paths := make([]string, 0, len(fs))
+	for p := range fs {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
 func buildTree(files map[string]*fileSysNode) (*treeNode, error) {
 	root := &treeNode{}
 	for name, fn := range files {
@@ -391,6 +487,8 @@ type TreeOutput struct {
 	IsExecutable     bool
 	IsEmptyDirectory bool
 	SymlinkTarget    string
+	// NodeProperties are the properties of the node, e.g. its modification time, if any.
+	NodeProperties *repb.NodeProperties
 }
 
 // FlattenTree takes a Tree message and calculates the relative paths of all the files to
@@ -440,6 +538,7 @@ func flattenTree(root digest.Digest, rootPath string, dirs map[digest.Digest]*re
 				Path:             flatDir.p,
 				Digest:           digest.Empty,
 				IsEmptyDirectory: true,
+				NodeProperties:   dir.NodeProperties,
 			}
 			continue
 		}
@@ -447,8 +546,9 @@ func flattenTree(root digest.Digest, rootPath string, dirs map[digest.Digest]*re
 		for _, file := range dir.Files {
 			out := &TreeOutput{
 				Path:         filepath.Join(flatDir.p, file.Name),
-				Digest:       digest.NewFromProtoUnvalidated(file.Digest),
-				IsExecutable: file.IsExecutable,
+				Digest:         digest.NewFromProtoUnvalidated(file.Digest),
+				IsExecutable:   file.IsExecutable,
+				NodeProperties: file.NodeProperties,
 			}
 			flatFiles[out.Path] = out
 		}
@@ -456,8 +556,9 @@ func flattenTree(root digest.Digest, rootPath string, dirs map[digest.Digest]*re
 		// Add symlinks to the set to return
 		for _, sm := range dir.Symlinks {
 			out := &TreeOutput{
-				Path:          filepath.Join(flatDir.p, sm.Name),
-				SymlinkTarget: sm.Target,
+				Path:           filepath.Join(flatDir.p, sm.Name),
+				SymlinkTarget:  sm.Target,
+				NodeProperties: sm.NodeProperties,
 			}
 			flatFiles[out.Path] = out
 		}
@@ -513,6 +614,10 @@ func packageDirectories(t *treeNode) (root *repb.Directory, children map[digest.
 func (c *Client) ComputeOutputsToUpload(execRoot, workingDir string, paths []string, cache filemetadata.Cache, sb command.SymlinkBehaviorType) (map[digest.Digest]*uploadinfo.Entry, *repb.ActionResult, error) {
 	outs := make(map[digest.Digest]*uploadinfo.Entry)
 	resPb := &repb.ActionResult{}
+	execRoot, err := c.WindowsOpts.normalizePath(execRoot)
+	if err != nil {
+		return nil, nil, err
+	}
 	for _, path := range paths {
 		absPath := filepath.Join(execRoot, workingDir, path)
 		if _, err := getRelPath(execRoot, absPath); err != nil {
@@ -533,12 +638,15 @@ func (c *Client) ComputeOutputsToUpload(execRoot, workingDir string, paths []str
 			// A regular file.
 			ue := uploadinfo.EntryFromFile(meta.Digest, absPath)
 			outs[meta.Digest] = ue
-			resPb.OutputFiles = append(resPb.OutputFiles, &repb.OutputFile{Path: normPath, Digest: meta.Digest.ToProto(), IsExecutable: meta.IsExecutable})
+			resPb.OutputFiles = append(resPb.OutputFiles, &repb.OutputFile{Path: normPath, Digest: meta.Digest.ToProto(), IsExecutable: c.WindowsOpts.isExecutable(absPath, meta.IsExecutable)})
 			continue
 		}
 		// A directory.
 		fs := make(map[string]*fileSysNode)
-		if e := loadFiles(absPath, "", "", nil, []string{"."}, fs, cache, treeSymlinkOpts(c.TreeSymlinkOpts, sb)); e != nil {
+		if e := loadFiles(absPath, "", "", nil, []string{"."}, fs, cache, treeSymlinkOpts(c.TreeSymlinkOpts, sb), c.WindowsOpts); e != nil {
+			return nil, nil, e
+		}
+		if e := c.WindowsOpts.checkCaseCollisions(fsPaths(fs)); e != nil {
 			return nil, nil, e
 		}
 		ft, err := buildTree(fs)
@@ -1,10 +1,12 @@
 package client_test
 
 import (
+	"context"
 	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/chunker"
@@ -144,6 +146,62 @@ func (c *callCountingMetadataCache) GetCacheMisses() uint64 {
 	return 0
 }
 
+func (c *callCountingMetadataCache) Warm(ctx context.Context, root string, excludes []*regexp.Regexp) *filemetadata.Warming {
+	return c.cache.Warm(ctx, root, excludes)
+}
+
+func TestComputeMerkleTreeSymlinkChains(t *testing.T) {
+	root := t.TempDir()
+	ips := []*inputPath{
+		{path: "node_modules/pkg/index.js", fileContents: []byte("index")},
+		{path: "a/index.js", isSymlink: true, symlinkTarget: "../node_modules/pkg/index.js"},
+		{path: "b/index.js", isSymlink: true, symlinkTarget: "../a/index.js"},
+		{path: "dangling", isSymlink: true, symlinkTarget: "missing"},
+	}
+	if err := construct(root, ips); err != nil {
+		t.Fatalf("failed to construct input dir structure: %v", err)
+	}
+	inputSpec := &command.InputSpec{Inputs: []string{"a", "b", "node_modules", "dangling"}}
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	computeMerkleTree := func(opts *client.TreeSymlinkOpts) (digest.Digest, map[string]int) {
+		opts.Apply(c)
+		cache := &callCountingMetadataCache{
+			execRoot: root,
+			cache:    filemetadata.NewNoopCache(),
+			t:        t,
+			calls:    make(map[string]int),
+		}
+		rootDg, _, _, err := c.ComputeMerkleTree(root, "", "", inputSpec, cache)
+		if err != nil {
+			t.Fatalf("ComputeMerkleTree(...) = gave error %v, want success", err)
+		}
+		return rootDg, cache.calls
+	}
+	wantDg, calls := computeMerkleTree(&client.TreeSymlinkOpts{})
+	for _, p := range []string{"a/index.js", "b/index.js", "node_modules/pkg/index.js"} {
+		if calls[p] != 1 {
+			t.Errorf("ComputeMerkleTree without chains got metadata of %v %d times, want 1", p, calls[p])
+		}
+	}
+
+	chains := filemetadata.NewSymlinkCache()
+	gotDg, calls := computeMerkleTree(&client.TreeSymlinkOpts{Chains: chains})
+	if gotDg != wantDg {
+		t.Errorf("ComputeMerkleTree with chains = %v, want %v", gotDg, wantDg)
+	}
+	for p, want := range map[string]int{"a/index.js": 0, "b/index.js": 0, "node_modules/pkg/index.js": 3} {
+		if calls[p] != want {
+			t.Errorf("ComputeMerkleTree with chains got metadata of %v %d times, want %d", p, calls[p], want)
+		}
+	}
+	if chains.GetCacheMisses() == 0 {
+		t.Errorf("ComputeMerkleTree with chains did not resolve any chain")
+	}
+}
+
 func TestComputeMerkleTreeRemoteWorkingDir(t *testing.T) {
 	callComputeMerkleTree := func(files, inputs []string, virtualInputs []*command.VirtualInput, localWorkingDir, remoteWorkingDir string) (digest.Digest, map[string]int) {
 		root, err := ioutil.TempDir("", "")
@@ -1333,6 +1391,40 @@ func TestComputeMerkleTreeErrors(t *testing.T) {
 	}
 }
 
+func TestComputeMerkleTreeWindowsOpts(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	client.DefaultWindowsOpts().Apply(c)
+
+	root := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(root, "tool.EXE"), fooBlob, 0666); err != nil {
+		t.Fatalf("failed to write tool.EXE: %v", err)
+	}
+	gotDg, inputs, _, err := c.ComputeMerkleTree(root, "", "", &command.InputSpec{Inputs: []string{"."}}, filemetadata.NewNoopCache())
+	if err != nil {
+		t.Fatalf("ComputeMerkleTree() failed: %v", err)
+	}
+	wantDir := &repb.Directory{Files: []*repb.FileNode{{Name: "tool.EXE", Digest: fooDgPb, IsExecutable: true}}}
+	if wantDg := digest.TestNewFromMessage(wantDir); gotDg != wantDg {
+		t.Errorf("ComputeMerkleTree() = %v, want %v with tool.EXE executable, got inputs %v", gotDg, wantDg, inputs)
+	}
+
+	spec := &command.InputSpec{
+		VirtualInputs: []*command.VirtualInput{
+			{Path: "dir/foo", Contents: fooBlob},
+			{Path: "Dir/bar", Contents: barBlob},
+		},
+	}
+	if _, _, _, err := c.ComputeMerkleTree(root, "", "", spec, filemetadata.NewNoopCache()); err == nil {
+		t.Errorf("ComputeMerkleTree(%v) succeeded, want a case collision error", spec)
+	}
+	c.WindowsOpts.CaseInsensitive = false
+	if _, _, _, err := c.ComputeMerkleTree(root, "", "", spec, filemetadata.NewNoopCache()); err != nil {
+		t.Errorf("ComputeMerkleTree(%v) without CaseInsensitive failed: %v", spec, err)
+	}
+}
+
 func TestFlattenTreeRepeated(t *testing.T) {
 	// Directory structure:
 	// <root>
@@ -1757,3 +1849,39 @@ func BenchmarkComputeMerkleTree(b *testing.B) {
 		}
 	}
 }
+
+func TestUploadDirectory(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	root := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(root, "foo"), fooBlob, 0777); err != nil {
+		t.Fatalf("failed to write foo: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "bar"), barBlob, 0666); err != nil {
+		t.Fatalf("failed to write bar: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "baz.tmp"), []byte("excluded"), 0666); err != nil {
+		t.Fatalf("failed to write baz.tmp: %v", err)
+	}
+
+	gotDg, gotStats, err := e.Client.GrpcClient.UploadDirectory(ctx, root, &client.UploadDirectoryOptions{
+		InputExclusions: []*command.InputExclusion{{Regex: `\.tmp$`, Type: command.FileInputType}},
+	})
+	if err != nil {
+		t.Fatalf("UploadDirectory(%q) failed: %v", root, err)
+	}
+	if gotDg != foobarDirDg {
+		t.Errorf("UploadDirectory(%q) root = %v, want %v", root, gotDg, foobarDirDg)
+	}
+	wantStats := &client.TreeStats{InputFiles: 2, InputDirectories: 1, TotalInputBytes: fooDg.Size + barDg.Size + foobarDirDg.Size}
+	if diff := cmp.Diff(wantStats, gotStats); diff != "" {
+		t.Errorf("UploadDirectory(%q) stats diff (-want +got):\n%s", root, diff)
+	}
+	for _, dg := range []digest.Digest{fooDg, barDg, foobarDirDg} {
+		if _, ok := e.Server.CAS.Get(dg); !ok {
+			t.Errorf("blob %v was not uploaded", dg)
+		}
+	}
+}
@@ -84,3 +84,42 @@ func TestGetTargetRelPath(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckCaseCollisions(t *testing.T) {
+	opts := &WindowsOpts{CaseInsensitive: true}
+	tests := []struct {
+		desc    string
+		paths   []string
+		wantErr bool
+	}{
+		{desc: "distinct", paths: []string{"a/foo", "a/bar", "b/foo"}},
+		{desc: "same path", paths: []string{"a/foo", "a/foo"}},
+		{desc: "files", paths: []string{"a/foo", "a/FOO"}, wantErr: true},
+		{desc: "parents", paths: []string{"a/foo", "A/bar"}, wantErr: true},
+		{desc: "file and parent", paths: []string{"a/b/foo", "a/B"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := opts.checkCaseCollisions(tc.paths); (err != nil) != tc.wantErr {
+				t.Errorf("checkCaseCollisions(%v) = %v, want error: %v", tc.paths, err, tc.wantErr)
+			}
+		})
+	}
+	if err := (&WindowsOpts{}).checkCaseCollisions([]string{"foo", "FOO"}); err != nil {
+		t.Errorf("checkCaseCollisions() without CaseInsensitive = %v, want nil", err)
+	}
+}
+
+func TestStripLongPathPrefix(t *testing.T) {
+	tests := map[string]string{
+		`\\?\C:\foo\bar`:         `C:\foo\bar`,
+		`\\?\UNC\server\share\f`: `\\server\share\f`,
+		`C:\foo`:                 `C:\foo`,
+		`/foo/bar`:               `/foo/bar`,
+	}
+	for p, want := range tests {
+		if got := stripLongPathPrefix(p); got != want {
+			t.Errorf("stripLongPathPrefix(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// TreeDiff is the difference between a previously uploaded Merkle tree and a new one.
+type TreeDiff struct {
+	// Changed are the blobs of the new tree that are not at the same path in the old tree: the
+	// contents of changed and added files, and the directories containing them. Each blob appears
+	// once.
+	Changed []*uploadinfo.Entry
+	// UnchangedFiles is the number of files of the new tree found at the same path in the old tree.
+	UnchangedFiles int
+	// UnchangedDirectories is the number of directories of the new tree found at the same path in
+	// the old tree.
+	UnchangedDirectories int
+	// UnchangedBytes is the total size of the UnchangedFiles.
+	UnchangedBytes int64
+}
+
+// DiffTree compares the new Merkle tree rooted at newRoot, made of the blobs newBlobs as returned
+// by ComputeMerkleTree, with the tree rooted at oldRoot, previously uploaded to the CAS. Only the
+// Changed blobs need uploading, e.g. with UploadIfMissing, so that the unchanged majority of an
+// incremental build is not even queried with FindMissingBlobs. The blobs of the old tree are
+// assumed to still be in the CAS.
+//
+// The directories of the old tree are read from the CAS, only along the paths that changed.
+func (c *Client) DiffTree(ctx context.Context, oldRoot, newRoot digest.Digest, newBlobs []*uploadinfo.Entry) (*TreeDiff, error) {
+	ctx, done, err := c.startOp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	d := &treeDiffer{
+		c:     c,
+		blobs: make(map[digest.Digest]*uploadinfo.Entry, len(newBlobs)),
+		added: make(map[digest.Digest]bool),
+		diff:  &TreeDiff{},
+	}
+	for _, ue := range newBlobs {
+		d.blobs[ue.Digest] = ue
+	}
+	if err := d.diffDir(ctx, oldRoot, newRoot, true); err != nil {
+		return nil, err
+	}
+	return d.diff, nil
+}
+
+type treeDiffer struct {
+	c *Client
+	// blobs are the blobs of the new tree.
+	blobs map[digest.Digest]*uploadinfo.Entry
+	added map[digest.Digest]bool
+	diff  *TreeDiff
+}
+
+// diffDir diffs the new directory nd with the old directory od at the same path, if hasOld.
+func (d *treeDiffer) diffDir(ctx context.Context, od, nd digest.Digest, hasOld bool) error {
+	newDir, err := d.newDir(nd)
+	if err != nil {
+		return err
+	}
+	if hasOld && od == nd {
+		d.countUnchanged(newDir)
+		return nil
+	}
+	if err := d.add(nd); err != nil {
+		return err
+	}
+	oldFiles := make(map[string]digest.Digest)
+	oldDirs := make(map[string]digest.Digest)
+	if hasOld {
+		oldDir := &repb.Directory{}
+		if _, err := d.c.ReadProto(ctx, od, oldDir); err != nil {
+			return fmt.Errorf("reading directory %v of the old tree: %w", od, err)
+		}
+		for _, f := range oldDir.Files {
+			oldFiles[f.Name] = digest.NewFromProtoUnvalidated(f.Digest)
+		}
+		for _, dir := range oldDir.Directories {
+			oldDirs[dir.Name] = digest.NewFromProtoUnvalidated(dir.Digest)
+		}
+	}
+	for _, f := range newDir.Files {
+		dg, err := digest.NewFromProto(f.Digest)
+		if err != nil {
+			return err
+		}
+		if old, ok := oldFiles[f.Name]; ok && old == dg {
+			d.diff.UnchangedFiles++
+			d.diff.UnchangedBytes += dg.Size
+			continue
+		}
+		if err := d.add(dg); err != nil {
+			return err
+		}
+	}
+	for _, dir := range newDir.Directories {
+		dg, err := digest.NewFromProto(dir.Digest)
+		if err != nil {
+			return err
+		}
+		old, ok := oldDirs[dir.Name]
+		if err := d.diffDir(ctx, old, dg, ok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countUnchanged counts the directory dir and its contents as unchanged.
+func (d *treeDiffer) countUnchanged(dir *repb.Directory) {
+	d.diff.UnchangedDirectories++
+	for _, f := range dir.Files {
+		d.diff.UnchangedFiles++
+		d.diff.UnchangedBytes += f.GetDigest().GetSizeBytes()
+	}
+	for _, child := range dir.Directories {
+		// The subtrees of trees built by ComputeMerkleTree are complete.
+		if cd, err := d.newDir(digest.NewFromProtoUnvalidated(child.Digest)); err == nil {
+			d.countUnchanged(cd)
+		}
+	}
+}
+
+// newDir returns the directory dg of the new tree.
+func (d *treeDiffer) newDir(dg digest.Digest) (*repb.Directory, error) {
+	if dg.IsEmpty() {
+		return &repb.Directory{}, nil
+	}
+	ue, ok := d.blobs[dg]
+	if !ok || !ue.IsBlob() {
+		return nil, fmt.Errorf("directory %v is not among the blobs of the new tree", dg)
+	}
+	dir := &repb.Directory{}
+	if err := proto.Unmarshal(ue.Contents, dir); err != nil {
+		return nil, fmt.Errorf("directory %v of the new tree: %w", dg, err)
+	}
+	return dir, nil
+}
+
+// add records the blob dg of the new tree as changed.
+func (d *treeDiffer) add(dg digest.Digest) error {
+	if d.added[dg] || dg.IsEmpty() {
+		return nil
+	}
+	ue, ok := d.blobs[dg]
+	if !ok {
+		return fmt.Errorf("blob %v is not among the blobs of the new tree", dg)
+	}
+	d.added[dg] = true
+	d.diff.Changed = append(d.diff.Changed, ue)
+	return nil
+}
@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func writeTreeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for p, contents := range files {
+		path := filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiffTree(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+
+	root := t.TempDir()
+	writeTreeFiles(t, root, map[string]string{"a/x": "x", "a/y": "y", "b/z": "z", "top": "top"})
+	oldRoot, _, err := c.UploadDirectory(ctx, root, nil)
+	if err != nil {
+		t.Fatalf("UploadDirectory() failed: %v", err)
+	}
+
+	writeTreeFiles(t, root, map[string]string{"b/z": "changed", "c/w": "added"})
+	newRoot, blobs, _, err := c.ComputeMerkleTree(root, "", "", &command.InputSpec{Inputs: []string{"."}}, filemetadata.NewNoopCache())
+	if err != nil {
+		t.Fatalf("ComputeMerkleTree() failed: %v", err)
+	}
+	aDg := digest.TestNewFromMessage(&repb.Directory{Files: []*repb.FileNode{
+		{Name: "x", Digest: digest.NewFromBlob([]byte("x")).ToProto()},
+		{Name: "y", Digest: digest.NewFromBlob([]byte("y")).ToProto()},
+	}})
+	bDg := digest.TestNewFromMessage(&repb.Directory{Files: []*repb.FileNode{{Name: "z", Digest: digest.NewFromBlob([]byte("changed")).ToProto()}}})
+	cDg := digest.TestNewFromMessage(&repb.Directory{Files: []*repb.FileNode{{Name: "w", Digest: digest.NewFromBlob([]byte("added")).ToProto()}}})
+
+	diff, err := c.DiffTree(ctx, oldRoot, newRoot, blobs)
+	if err != nil {
+		t.Fatalf("DiffTree() failed: %v", err)
+	}
+	var got []string
+	for _, ue := range diff.Changed {
+		got = append(got, ue.Digest.String())
+	}
+	want := []string{newRoot.String(), bDg.String(), cDg.String(), digest.NewFromBlob([]byte("changed")).String(), digest.NewFromBlob([]byte("added")).String()}
+	sort.Strings(got)
+	sort.Strings(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffTree() changed blobs diff (-want +got):\n%s", diff)
+	}
+	if diff.UnchangedFiles != 3 || diff.UnchangedDirectories != 1 || diff.UnchangedBytes != 5 {
+		t.Errorf("DiffTree() = %+v, want 3 unchanged files of 5 bytes in 1 unchanged directory", diff)
+	}
+	if n := e.Server.CAS.BlobReads(aDg); n != 0 {
+		t.Errorf("the unchanged directory was read %d times, want 0", n)
+	}
+
+	// Uploading the changed blobs completes the new tree.
+	if _, _, err := c.UploadIfMissing(ctx, diff.Changed...); err != nil {
+		t.Fatalf("UploadIfMissing() failed: %v", err)
+	}
+	for _, ue := range blobs {
+		if _, ok := e.Server.CAS.Get(ue.Digest); !ok {
+			t.Errorf("blob %v of the new tree is missing", ue.Digest)
+		}
+	}
+
+	if _, err := c.DiffTree(ctx, oldRoot, newRoot, nil); err == nil {
+		t.Errorf("DiffTree() without the new blobs succeeded, want error")
+	}
+}
@@ -0,0 +1,325 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// maxSymlinkHops bounds the number of symlinks followed when resolving a path in a TreeFS.
+const maxSymlinkHops = 40
+
+// TreeFS returns a read-only fs.FS of the directory tree rooted at the given digest.
+//
+// The directory structure is fetched eagerly with GetTree, but file contents are only read from
+// the CAS when a file is first read, so remote trees can be inspected without materializing them
+// on local disk. All the CAS reads made by the file system use ctx.
+//
+// Symlinks are followed as long as their targets are relative and stay inside the tree; other
+// symlinks can be listed and stat'ed, but opening them fails with fs.ErrNotExist.
+func (c *Client) TreeFS(ctx context.Context, root digest.Digest) (fs.FS, error) {
+	dirs, err := c.GetDirectoryTree(ctx, root.ToProto())
+	if err != nil {
+		return nil, err
+	}
+	t := &treeFS{ctx: ctx, c: c, dirs: make(map[digest.Digest]*repb.Directory, len(dirs))}
+	for _, dir := range dirs {
+		dg, err := digest.NewFromMessage(dir)
+		if err != nil {
+			return nil, err
+		}
+		t.dirs[dg] = dir
+	}
+	if t.root = t.dirs[root]; t.root == nil {
+		return nil, fmt.Errorf("root directory %v is missing from the tree", root)
+	}
+	return t, nil
+}
+
+type treeFS struct {
+	ctx  context.Context
+	c    *Client
+	root *repb.Directory
+	dirs map[digest.Digest]*repb.Directory
+}
+
+var (
+	_ fs.ReadDirFS = (*treeFS)(nil)
+	_ fs.StatFS    = (*treeFS)(nil)
+)
+
+// treeFSNode is a resolved entry of a treeFS. Exactly one of dir, file and link is set.
+type treeFSNode struct {
+	name string
+	dir  *repb.Directory
+	file *repb.FileNode
+	link *repb.SymlinkNode
+}
+
+// Open implements fs.FS.
+func (t *treeFS) Open(name string) (fs.File, error) {
+	n, err := t.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case n.dir != nil:
+		entries, err := t.entries(n.dir)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &treeFSDir{info: n.info(), entries: entries}, nil
+	case n.file != nil:
+		return &treeFSFile{t: t, name: name, node: n.file, info: n.info()}, nil
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+}
+
+// Stat implements fs.StatFS.
+func (t *treeFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := t.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := t.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if n.dir == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	entries, err := t.entries(n.dir)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// lookup resolves name to a node. The final path element is only dereferenced if it is a symlink
+// and follow is set.
+func (t *treeFS) lookup(op, name string, follow bool) (*treeFSNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	for hops := 0; ; hops++ {
+		if hops > maxSymlinkHops {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("too many levels of symbolic links")}
+		}
+		n, target, err := t.walk(name, follow)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if n != nil {
+			return n, nil
+		}
+		name = target
+	}
+}
+
+// walk walks the path elements of name. If it reaches a symlink that must be followed, it returns
+// the path that the symlink resolves name to instead of a node.
+func (t *treeFS) walk(name string, follow bool) (*treeFSNode, string, error) {
+	n := &treeFSNode{name: ".", dir: t.root}
+	if name == "." {
+		return n, "", nil
+	}
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		if n.dir == nil {
+			return nil, "", fs.ErrNotExist
+		}
+		next, err := t.child(n.dir, elem)
+		if err != nil {
+			return nil, "", err
+		}
+		last := i == len(elems)-1
+		if next.link != nil && (!last || follow) {
+			target := next.link.Target
+			if path.IsAbs(target) {
+				return nil, "", fs.ErrNotExist
+			}
+			resolved := path.Join(append([]string{path.Join(elems[:i]...), target}, elems[i+1:]...)...)
+			if resolved == ".." || strings.HasPrefix(resolved, "../") {
+				return nil, "", fs.ErrNotExist
+			}
+			return nil, resolved, nil
+		}
+		n = next
+	}
+	return n, "", nil
+}
+
+// child returns the entry of dir with the given name.
+func (t *treeFS) child(dir *repb.Directory, name string) (*treeFSNode, error) {
+	for _, d := range dir.Directories {
+		if d.Name == name {
+			sub, ok := t.dirs[digest.NewFromProtoUnvalidated(d.Digest)]
+			if !ok {
+				return nil, fmt.Errorf("directory %v is missing from the tree", d.Digest)
+			}
+			return &treeFSNode{name: name, dir: sub}, nil
+		}
+	}
+	for _, f := range dir.Files {
+		if f.Name == name {
+			return &treeFSNode{name: name, file: f}, nil
+		}
+	}
+	for _, l := range dir.Symlinks {
+		if l.Name == name {
+			return &treeFSNode{name: name, link: l}, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// entries returns the entries of dir, sorted by name.
+func (t *treeFS) entries(dir *repb.Directory) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	for _, d := range dir.Directories {
+		n, err := t.child(dir, d.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(n.info()))
+	}
+	for _, f := range dir.Files {
+		entries = append(entries, fs.FileInfoToDirEntry((&treeFSNode{name: f.Name, file: f}).info()))
+	}
+	for _, l := range dir.Symlinks {
+		entries = append(entries, fs.FileInfoToDirEntry((&treeFSNode{name: l.Name, link: l}).info()))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (n *treeFSNode) info() *treeFSInfo {
+	switch {
+	case n.dir != nil:
+		return &treeFSInfo{name: path.Base(n.name), mode: fs.ModeDir | 0555, modTime: mtime(n.dir.GetNodeProperties())}
+	case n.file != nil:
+		mode := fs.FileMode(0444)
+		if n.file.IsExecutable {
+			mode = 0555
+		}
+		return &treeFSInfo{name: n.name, size: n.file.GetDigest().GetSizeBytes(), mode: mode, modTime: mtime(n.file.GetNodeProperties())}
+	default:
+		return &treeFSInfo{name: n.name, size: int64(len(n.link.Target)), mode: fs.ModeSymlink | 0777, modTime: mtime(n.link.GetNodeProperties())}
+	}
+}
+
+func mtime(p *repb.NodeProperties) time.Time {
+	if p.GetMtime() == nil {
+		return time.Time{}
+	}
+	return p.GetMtime().AsTime()
+}
+
+// treeFSInfo implements fs.FileInfo.
+type treeFSInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *treeFSInfo) Name() string       { return i.name }
+func (i *treeFSInfo) Size() int64        { return i.size }
+func (i *treeFSInfo) Mode() fs.FileMode  { return i.mode }
+func (i *treeFSInfo) ModTime() time.Time { return i.modTime }
+func (i *treeFSInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *treeFSInfo) Sys() interface{}   { return nil }
+
+// treeFSFile is a regular file of a treeFS. Its contents are fetched on the first read.
+type treeFSFile struct {
+	t    *treeFS
+	name string
+	node *repb.FileNode
+	info *treeFSInfo
+	r    *bytes.Reader
+}
+
+func (f *treeFSFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *treeFSFile) Close() error { return nil }
+
+func (f *treeFSFile) load() error {
+	if f.r != nil {
+		return nil
+	}
+	data, _, err := f.t.c.ReadBlob(f.t.ctx, digest.NewFromProtoUnvalidated(f.node.Digest))
+	if err != nil {
+		return &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	f.r = bytes.NewReader(data)
+	return nil
+}
+
+func (f *treeFSFile) Read(p []byte) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.r.Read(p)
+}
+
+func (f *treeFSFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func (f *treeFSFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.r.Seek(offset, whence)
+}
+
+// treeFSDir is an open directory of a treeFS.
+type treeFSDir struct {
+	info    *treeFSInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *treeFSDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *treeFSDir) Close() error { return nil }
+
+func (d *treeFSDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fmt.Errorf("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *treeFSDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.off += n
+	return rest[:n], nil
+}
@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTreeFS(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+
+	cas.Put(fooBlob)
+	cas.Put(barBlob)
+	sub := &repb.Directory{
+		Files:    []*repb.FileNode{{Name: "bar", Digest: barDgPb}},
+		Symlinks: []*repb.SymlinkNode{{Name: "up", Target: "../foo"}},
+	}
+	subDg := cas.Put(mustMarshal(sub))
+	root := &repb.Directory{
+		Files:       []*repb.FileNode{{Name: "foo", Digest: fooDgPb, IsExecutable: true}},
+		Directories: []*repb.DirectoryNode{{Name: "sub", Digest: subDg.ToProto()}},
+		Symlinks: []*repb.SymlinkNode{
+			{Name: "link", Target: "sub"},
+			{Name: "escape", Target: "../outside"},
+		},
+	}
+	rootDg := cas.Put(mustMarshal(root))
+
+	fsys, err := e.Client.GrpcClient.TreeFS(ctx, rootDg)
+	if err != nil {
+		t.Fatalf("TreeFS(%v) failed: %v", rootDg, err)
+	}
+	if reads := cas.BlobReads(fooDg); reads != 0 {
+		t.Errorf("TreeFS() read foo %d times before it was opened, want 0", reads)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want []byte
+	}{
+		{"foo", fooBlob},
+		{"sub/bar", barBlob},
+		{"link/bar", barBlob},
+		{"sub/up", fooBlob},
+	} {
+		got, err := fs.ReadFile(fsys, tc.path)
+		if err != nil {
+			t.Errorf("ReadFile(%q) failed: %v", tc.path, err)
+			continue
+		}
+		if string(got) != string(tc.want) {
+			t.Errorf("ReadFile(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+	info, err := fs.Stat(fsys, "foo")
+	if err != nil {
+		t.Fatalf("Stat(foo) failed: %v", err)
+	}
+	if info.Mode() != 0555 || info.Size() != fooDg.Size {
+		t.Errorf("Stat(foo) = mode %v size %d, want mode %v size %d", info.Mode(), info.Size(), fs.FileMode(0555), fooDg.Size)
+	}
+	if _, err := fsys.Open("escape"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(escape) = %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing) = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	// Check the fs.FS contract on a tree without symlinks.
+	plainDg := cas.Put(mustMarshal(&repb.Directory{
+		Files:       []*repb.FileNode{{Name: "foo", Digest: fooDgPb, IsExecutable: true}},
+		Directories: []*repb.DirectoryNode{{Name: "sub", Digest: cas.Put(mustMarshal(&repb.Directory{Files: sub.Files})).ToProto()}},
+	}))
+	plain, err := e.Client.GrpcClient.TreeFS(ctx, plainDg)
+	if err != nil {
+		t.Fatalf("TreeFS(%v) failed: %v", plainDg, err)
+	}
+	if err := fstest.TestFS(plain, "foo", "sub/bar"); err != nil {
+		t.Errorf("fstest.TestFS() failed: %v", err)
+	}
+}
+
+func TestTreeFSMissingRoot(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	if _, err := e.Client.GrpcClient.TreeFS(ctx, digest.NewFromBlob([]byte("missing"))); err == nil {
+		t.Errorf("TreeFS() of a missing root succeeded, want error")
+	}
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// JunctionPolicy is how NTFS directory junctions are handled when constructing a tree.
+type JunctionPolicy int
+
+const (
+	// JunctionsAsSymlinks handles junctions like symlinks, according to the TreeSymlinkOpts and
+	// the SymlinkBehavior of the inputs.
+	JunctionsAsSymlinks JunctionPolicy = iota
+	// FollowJunctions replaces junctions with the directories they point to, even when symlinks
+	// are preserved. Junctions are always absolute, so they rarely make sense remotely.
+	FollowJunctions
+	// RejectJunctions fails the construction of trees containing junctions.
+	RejectJunctions
+)
+
+// WindowsOpts controls how the semantics of Windows file systems are handled when constructing
+// trees and downloading outputs. They are set by default on Windows.
+type WindowsOpts struct {
+	// CaseInsensitive rejects trees and outputs with paths differing only by case, which would
+	// silently overwrite each other on a case-insensitive file system.
+	CaseInsensitive bool
+	// Junctions is how directory junctions are handled when constructing trees.
+	Junctions JunctionPolicy
+	// ExecutableExtensions are the file name extensions, such as ".exe", of the files marked as
+	// executable when constructing trees. Windows has no executable bit, so files are otherwise
+	// never executable. Extensions are matched case-insensitively.
+	ExecutableExtensions []string
+}
+
+// DefaultWindowsOpts returns the default WindowsOpts of clients running on Windows.
+func DefaultWindowsOpts() *WindowsOpts {
+	return &WindowsOpts{
+		CaseInsensitive:      true,
+		Junctions:            JunctionsAsSymlinks,
+		ExecutableExtensions: []string{".bat", ".cmd", ".com", ".exe", ".ps1"},
+	}
+}
+
+// Apply sets the client's WindowsOpts.
+func (o *WindowsOpts) Apply(c *Client) {
+	c.WindowsOpts = o
+}
+
+// defaultWindowsOpts returns the WindowsOpts of new clients, nil unless running on Windows.
+func defaultWindowsOpts() *WindowsOpts {
+	if runtime.GOOS == "windows" {
+		return DefaultWindowsOpts()
+	}
+	return nil
+}
+
+// isExecutable returns whether the file at path, with the given executable bit, is executable.
+func (o *WindowsOpts) isExecutable(path string, executableBit bool) bool {
+	if executableBit || o == nil {
+		return executableBit
+	}
+	ext := filepath.Ext(path)
+	for _, e := range o.ExecutableExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCaseCollisions returns an error if two of the paths, or of their parent directories,
+// differ only by case.
+func (o *WindowsOpts) checkCaseCollisions(paths []string) error {
+	if o == nil || !o.CaseInsensitive {
+		return nil
+	}
+	seen := make(map[string]string)
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		for {
+			folded := strings.ToLower(p)
+			if prev, ok := seen[folded]; ok {
+				if prev != p {
+					return fmt.Errorf("paths %q and %q differ only by case, and would collide on a case-insensitive file system", prev, p)
+				}
+				// The parents were checked with prev.
+				break
+			}
+			seen[folded] = p
+			parent := filepath.Dir(p)
+			if parent == p || parent == "." {
+				break
+			}
+			p = parent
+		}
+	}
+	return nil
+}
+
+// normalizePath returns the absolute form of the local path p, without the \\?\ prefix of long
+// Windows paths, which breaks the path arithmetic of the filepath package. The os package adds the
+// prefix back to the absolute paths exceeding MAX_PATH.
+func (o *WindowsOpts) normalizePath(p string) (string, error) {
+	if o == nil {
+		return p, nil
+	}
+	return filepath.Abs(stripLongPathPrefix(p))
+}
+
+// stripLongPathPrefix removes the \\?\ prefix of the Windows path p, if any.
+func stripLongPathPrefix(p string) string {
+	switch {
+	case strings.HasPrefix(p, `\\?\UNC\`):
+		return `\\` + p[len(`\\?\UNC\`):]
+	case strings.HasPrefix(p, `\\?\`):
+		return p[len(`\\?\`):]
+	}
+	return p
+}
@@ -0,0 +1,231 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ValidationError is returned by Builder.Build and lists every problem found in the command.
+type ValidationError struct {
+	Errs []error
+}
+
+// Error returns all the problems found, separated by semicolons.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid command: %s", strings.Join(msgs, "; "))
+}
+
+// Builder accumulates the fields of a Command and validates them all at once when built, so that
+// malformed commands are rejected up front with a complete error rather than failing, or
+// panicking, during execution. The setters can be chained:
+//
+//	cmd, err := command.NewBuilder("/exec/root", "gcc", "-c", "foo.c").
+//		Identifiers(&command.Identifiers{ToolName: "mytool"}).
+//		Inputs("foo.c", "include").
+//		OutputFiles("foo.o").
+//		Build()
+type Builder struct {
+	cmd  *Command
+	errs []error
+}
+
+// NewBuilder returns a Builder for a command running args in the given absolute exec root.
+func NewBuilder(execRoot string, args ...string) *Builder {
+	return &Builder{cmd: &Command{
+		Args:      args,
+		ExecRoot:  execRoot,
+		InputSpec: &InputSpec{},
+	}}
+}
+
+// Identifiers sets the identifiers of the command. Empty identifiers are filled with defaults by
+// Build, but the identifiers themselves are required.
+func (b *Builder) Identifiers(ids *Identifiers) *Builder {
+	b.cmd.Identifiers = ids
+	return b
+}
+
+// WorkingDir sets the working directory, relative to the exec root.
+func (b *Builder) WorkingDir(dir string) *Builder {
+	b.cmd.WorkingDir = dir
+	return b
+}
+
+// RemoteWorkingDir sets the working directory used remotely, relative to the exec root.
+func (b *Builder) RemoteWorkingDir(dir string) *Builder {
+	b.cmd.RemoteWorkingDir = dir
+	return b
+}
+
+// Inputs adds input files or directories, relative to the exec root.
+func (b *Builder) Inputs(paths ...string) *Builder {
+	b.cmd.InputSpec.Inputs = append(b.cmd.InputSpec.Inputs, paths...)
+	return b
+}
+
+// VirtualInputs adds inputs that are not on the local file system.
+func (b *Builder) VirtualInputs(inputs ...*VirtualInput) *Builder {
+	b.cmd.InputSpec.VirtualInputs = append(b.cmd.InputSpec.VirtualInputs, inputs...)
+	return b
+}
+
+// Exclude excludes the inputs of the given type matching the regular expression.
+func (b *Builder) Exclude(regex string, t InputType) *Builder {
+	b.cmd.InputSpec.InputExclusions = append(b.cmd.InputSpec.InputExclusions, &InputExclusion{Regex: regex, Type: t})
+	return b
+}
+
+// Env sets an environment variable of the command.
+func (b *Builder) Env(name, value string) *Builder {
+	if b.cmd.InputSpec.EnvironmentVariables == nil {
+		b.cmd.InputSpec.EnvironmentVariables = make(map[string]string)
+	}
+	if _, ok := b.cmd.InputSpec.EnvironmentVariables[name]; ok {
+		b.errs = append(b.errs, fmt.Errorf("environment variable %q is set more than once", name))
+	}
+	b.cmd.InputSpec.EnvironmentVariables[name] = value
+	return b
+}
+
+// SymlinkBehavior sets how symlinks in the inputs are handled.
+func (b *Builder) SymlinkBehavior(s SymlinkBehaviorType) *Builder {
+	b.cmd.InputSpec.SymlinkBehavior = s
+	return b
+}
+
+// OutputFiles adds output files, relative to the working directory.
+func (b *Builder) OutputFiles(paths ...string) *Builder {
+	b.cmd.OutputFiles = append(b.cmd.OutputFiles, paths...)
+	return b
+}
+
+// OutputDirs adds output directories, relative to the working directory.
+func (b *Builder) OutputDirs(paths ...string) *Builder {
+	b.cmd.OutputDirs = append(b.cmd.OutputDirs, paths...)
+	return b
+}
+
+// Timeout sets the execution timeout.
+func (b *Builder) Timeout(d time.Duration) *Builder {
+	b.cmd.Timeout = d
+	return b
+}
+
+// Platform sets a platform property.
+func (b *Builder) Platform(name, value string) *Builder {
+	if b.cmd.Platform == nil {
+		b.cmd.Platform = make(map[string]string)
+	}
+	b.cmd.Platform[name] = value
+	return b
+}
+
+// Build validates the command and returns it with default values filled in. If the command is
+// invalid, the error is a *ValidationError listing all the problems found.
+func (b *Builder) Build() (*Command, error) {
+	c := b.cmd
+	errs := append([]error(nil), b.errs...)
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Args) == 0 {
+		addErr("missing command arguments")
+	}
+	if c.ExecRoot == "" {
+		addErr("missing command exec root")
+	} else if !filepath.IsAbs(c.ExecRoot) {
+		addErr("exec root %q is not an absolute path", c.ExecRoot)
+	}
+	if c.Identifiers == nil {
+		addErr("missing command identifiers")
+	}
+	if err := checkRelative(c.ExecRoot, c.WorkingDir); err != nil {
+		addErr("working directory: %v", err)
+	}
+	if err := checkRelative(c.ExecRoot, c.RemoteWorkingDir); err != nil {
+		addErr("remote working directory: %v", err)
+	} else if c.RemoteWorkingDir != "" && levels(c.RemoteWorkingDir) != levels(c.WorkingDir) {
+		addErr("remote working directory %q has %d level(s), it is expected to have the same depth as working directory %q with %d level(s)",
+			c.RemoteWorkingDir, levels(c.RemoteWorkingDir), c.WorkingDir, levels(c.WorkingDir))
+	}
+
+	for _, in := range c.InputSpec.Inputs {
+		if in == "" {
+			addErr("empty input path")
+		} else if err := checkRelative(c.ExecRoot, in); err != nil {
+			addErr("input: %v", err)
+		}
+	}
+	virtual := make(map[string]bool)
+	for i, vi := range c.InputSpec.VirtualInputs {
+		switch {
+		case vi == nil:
+			addErr("virtual input #%d is nil", i)
+		case vi.Path == "":
+			addErr("virtual input #%d has an empty path", i)
+		case virtual[filepath.Clean(vi.Path)]:
+			addErr("duplicate virtual input %q", vi.Path)
+		default:
+			virtual[filepath.Clean(vi.Path)] = true
+			if err := checkRelative(c.ExecRoot, vi.Path); err != nil {
+				addErr("virtual input: %v", err)
+			}
+		}
+	}
+	for _, e := range c.InputSpec.InputExclusions {
+		if _, err := regexp.Compile(e.Regex); err != nil {
+			addErr("invalid input exclusion %q: %v", e.Regex, err)
+		}
+	}
+	for name := range c.InputSpec.EnvironmentVariables {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			addErr("invalid environment variable name %q", name)
+		}
+	}
+
+	outputs := make(map[string]bool)
+	for _, group := range [][]string{c.OutputFiles, c.OutputDirs} {
+		for _, out := range group {
+			clean := filepath.Clean(out)
+			switch {
+			case out == "":
+				addErr("empty output path")
+			case outputs[clean]:
+				addErr("duplicate output %q", out)
+			default:
+				outputs[clean] = true
+				if err := checkRelative(filepath.Join(c.ExecRoot, c.WorkingDir), out); err != nil {
+					addErr("output: %v", err)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errs: errs}
+	}
+	c.FillDefaultFieldValues()
+	return c, nil
+}
+
+// checkRelative returns an error if path is absolute or points outside of root.
+func checkRelative(root, path string) error {
+	if path == "" {
+		return nil
+	}
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("%q is an absolute path, expected a path relative to %q", path, root)
+	}
+	if clean := filepath.Clean(path); clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%q is outside of %q", path, root)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder_Success(t *testing.T) {
+	t.Parallel()
+	cmd, err := NewBuilder("/exec/root", "gcc", "-c", "foo.c").
+		Identifiers(&Identifiers{ToolName: "tool"}).
+		WorkingDir("wd").
+		Inputs("foo.c", "include").
+		Exclude(`\.bak$`, FileInputType).
+		Env("PATH", "/usr/bin").
+		OutputFiles("foo.o").
+		OutputDirs("out").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if cmd.Identifiers.ToolName != "tool" {
+		t.Errorf("Build() ToolName = %q, want %q", cmd.Identifiers.ToolName, "tool")
+	}
+	if cmd.Identifiers.CommandID == "" {
+		t.Errorf("Build() did not fill in the default CommandID")
+	}
+	if err := cmd.Validate(); err != nil {
+		t.Errorf("Validate() of built command = %v, want nil", err)
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	t.Parallel()
+	_, err := NewBuilder("exec/root").
+		Inputs("../outside", "").
+		VirtualInputs(&VirtualInput{Path: "v"}, &VirtualInput{Path: "./v"}, nil).
+		Exclude("(", FileInputType).
+		Env("A=B", "c").
+		Env("X", "1").
+		Env("X", "2").
+		OutputFiles("foo.o", "/abs").
+		OutputDirs("./foo.o", "../up").
+		Build()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Build() error = %v, want a *ValidationError", err)
+	}
+	wantErrs := []string{
+		`environment variable "X" is set more than once`,
+		"missing command arguments",
+		`exec root "exec/root" is not an absolute path`,
+		"missing command identifiers",
+		`input: "../outside" is outside of "exec/root"`,
+		"empty input path",
+		`duplicate virtual input "./v"`,
+		"virtual input #2 is nil",
+		"invalid input exclusion \"(\": error parsing regexp: missing closing ): `(`",
+		`invalid environment variable name "A=B"`,
+		`output: "/abs" is an absolute path, expected a path relative to "exec/root"`,
+		`duplicate output "./foo.o"`,
+		`output: "../up" is outside of "exec/root"`,
+	}
+	if len(verr.Errs) != len(wantErrs) {
+		t.Fatalf("Build() returned %d errors, want %d: %v", len(verr.Errs), len(wantErrs), verr)
+	}
+	for i, want := range wantErrs {
+		if got := verr.Errs[i].Error(); got != want {
+			t.Errorf("Build() error #%d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestBuilder_RemoteWorkingDirDepth(t *testing.T) {
+	t.Parallel()
+	_, err := NewBuilder("/exec/root", "a").
+		Identifiers(&Identifiers{}).
+		WorkingDir("foo").
+		RemoteWorkingDir("bar/baz").
+		Build()
+	if err == nil {
+		t.Errorf("Build() with mismatched working dir depths = nil, want error")
+	}
+}
@@ -128,9 +128,20 @@ func NewFromProtoUnvalidated(dg *repb.Digest) Digest {
 	return Digest{Hash: dg.Hash, Size: dg.SizeBytes}
 }
 
-// NewFromString returns a digest from a canonical digest string.
-// It returns an error if the hash/size are invalid.
+// NewFromString returns a digest from a canonical digest string, optionally prefixed with the
+// name of the digest function of HashFn as in FormatFunction, e.g. sha256:hash/size.
+// It returns an error if the hash/size are invalid, or if the digest is of another function.
 func NewFromString(s string) (Digest, error) {
+	if i := strings.Index(s, ":"); i >= 0 {
+		fn, ok := functionNamed(s[:i])
+		if !ok {
+			return Empty, fmt.Errorf("unknown digest function %q in digest %s", s[:i], s)
+		}
+		if want := GetDigestFunction(); fn != want {
+			return Empty, fmt.Errorf("digest %s is of digest function %v, want %v", s, fn, want)
+		}
+		s = s[i+1:]
+	}
 	pair := strings.Split(s, "/")
 	if len(pair) != 2 {
 		return Empty, fmt.Errorf("expected digest in the form hash/size, got %s", s)
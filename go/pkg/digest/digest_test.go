@@ -245,4 +245,12 @@ func TestNewFromString(t *testing.T) {
 	if _, err := NewFromString(sInvalid3); err == nil {
 		t.Errorf("FromString(%s) = (_, nil), want (_, error)", sInvalid3)
 	}
+	if dGot, err := NewFromString("sha256:" + sGood); err != nil || dGot != dSHA256 {
+		t.Errorf("FromString(sha256:%s) = (%v, %v), want (%v, nil)", sGood, dGot, err, dSHA256)
+	}
+	for _, s := range []string{"sha512:" + sGood, "foo:" + sGood, "SHA256:" + sGood} {
+		if _, err := NewFromString(s); err == nil {
+			t.Errorf("FromString(%s) = (_, nil), want (_, error)", s)
+		}
+	}
 }
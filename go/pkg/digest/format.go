@@ -0,0 +1,211 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// Format is a textual representation of a digest.
+type Format int
+
+const (
+	// FormatCanonical is hash/size, as returned by Digest.String.
+	FormatCanonical Format = iota
+	// FormatDash is hash-size, which is safe to use in file names.
+	FormatDash
+	// FormatPath is blobs/hash/size, the path used in ByteStream resource names.
+	FormatPath
+	// FormatProto is the compact text format of the repb.Digest message.
+	FormatProto
+	// FormatFunction is function:hash/size, with the lowercase name of the digest function, e.g.
+	// sha256:hash/size, which tells apart the digests of backends using different functions.
+	FormatFunction
+)
+
+var formatNames = map[Format]string{
+	FormatCanonical: "canonical",
+	FormatDash:      "dash",
+	FormatPath:      "path",
+	FormatProto:     "proto",
+	FormatFunction:  "function",
+}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	if n, ok := formatNames[f]; ok {
+		return n
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+var (
+	// ErrInvalidFormat is returned when a string doesn't match any known digest format.
+	ErrInvalidFormat = errors.New("invalid digest format")
+	// ErrInvalidHash is returned when the hash of a digest is malformed.
+	ErrInvalidHash = errors.New("invalid digest hash")
+	// ErrInvalidSize is returned when the size of a digest is malformed or negative.
+	ErrInvalidSize = errors.New("invalid digest size")
+)
+
+// ParseError is the error returned when parsing a digest fails. It wraps one of
+// ErrInvalidFormat, ErrInvalidHash or ErrInvalidSize, which can be checked with errors.Is.
+type ParseError struct {
+	Input  string
+	Err    error
+	Detail string
+}
+
+// Error returns the error message.
+func (e *ParseError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%v %q", e.Err, e.Input)
+	}
+	return fmt.Sprintf("%v %q: %s", e.Err, e.Input, e.Detail)
+}
+
+// Unwrap returns the underlying sentinel error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Format returns the digest in the given format.
+func (d Digest) Format(f Format) string {
+	switch f {
+	case FormatDash:
+		return fmt.Sprintf("%s-%d", d.Hash, d.Size)
+	case FormatPath:
+		return fmt.Sprintf("blobs/%s/%d", d.Hash, d.Size)
+	case FormatProto:
+		return proto.CompactTextString(d.ToProto())
+	case FormatFunction:
+		return FunctionName(GetDigestFunction()) + ":" + d.String()
+	default:
+		return d.String()
+	}
+}
+
+// Parse parses a digest in any of the known formats, detecting which one is used.
+func Parse(s string) (Digest, error) {
+	return ParseFormat(s, detectFormat(s))
+}
+
+// ParseFormat parses a digest in the given format. The returned error, if any, is a *ParseError.
+// Digests in FormatFunction are validated for the digest function they name, which may differ from
+// HashFn; use ParseFunction to get it.
+func ParseFormat(s string, f Format) (Digest, error) {
+	d, _, err := parseFormat(s, f)
+	return d, err
+}
+
+// ParseFunction is like Parse, but also returns the digest function of the digest: the one it
+// names if in FormatFunction, and the one of HashFn otherwise.
+func ParseFunction(s string) (Digest, repb.DigestFunction_Value, error) {
+	return parseFormat(s, detectFormat(s))
+}
+
+// FunctionName returns the name of the digest function in FormatFunction, e.g. sha256.
+func FunctionName(fn repb.DigestFunction_Value) string {
+	return strings.ToLower(fn.String())
+}
+
+// functionNamed returns the digest function with the given name in FormatFunction.
+func functionNamed(name string) (repb.DigestFunction_Value, bool) {
+	v, ok := repb.DigestFunction_Value_value[strings.ToUpper(name)]
+	if !ok || repb.DigestFunction_Value(v) == repb.DigestFunction_UNKNOWN || name != strings.ToLower(name) {
+		return repb.DigestFunction_UNKNOWN, false
+	}
+	return repb.DigestFunction_Value(v), true
+}
+
+func parseFormat(s string, f Format) (Digest, repb.DigestFunction_Value, error) {
+	fn := GetDigestFunction()
+	var hash, size string
+	switch f {
+	case FormatCanonical:
+		parts := strings.Split(s, "/")
+		if len(parts) != 2 {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: "expected hash/size"}
+		}
+		hash, size = parts[0], parts[1]
+	case FormatFunction:
+		i := strings.Index(s, ":")
+		if i < 0 {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: "expected function:hash/size"}
+		}
+		var ok bool
+		if fn, ok = functionNamed(s[:i]); !ok {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: fmt.Sprintf("unknown digest function %q", s[:i])}
+		}
+		parts := strings.Split(s[i+1:], "/")
+		if len(parts) != 2 {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: "expected function:hash/size"}
+		}
+		hash, size = parts[0], parts[1]
+	case FormatDash:
+		i := strings.LastIndex(s, "-")
+		if i < 0 {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: "expected hash-size"}
+		}
+		hash, size = s[:i], s[i+1:]
+	case FormatPath:
+		parts := strings.Split(strings.Trim(s, "/"), "/")
+		if len(parts) != 3 || parts[0] != "blobs" {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: "expected blobs/hash/size"}
+		}
+		hash, size = parts[1], parts[2]
+	case FormatProto:
+		dg := &repb.Digest{}
+		if err := proto.UnmarshalText(s, dg); err != nil {
+			return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: err.Error()}
+		}
+		d, err := newParsed(s, dg.Hash, dg.SizeBytes, fn)
+		return d, fn, err
+	default:
+		return Empty, fn, &ParseError{Input: s, Err: ErrInvalidFormat, Detail: fmt.Sprintf("unknown format %v", f)}
+	}
+	sz, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return Empty, fn, &ParseError{Input: s, Err: ErrInvalidSize, Detail: err.Error()}
+	}
+	d, err := newParsed(s, hash, sz, fn)
+	return d, fn, err
+}
+
+func newParsed(input, hash string, size int64, fn repb.DigestFunction_Value) (Digest, error) {
+	d := Digest{Hash: hash, Size: size}
+	if size < 0 {
+		return Empty, &ParseError{Input: input, Err: ErrInvalidSize, Detail: fmt.Sprintf("expected non-negative size, got %d", size)}
+	}
+	validate := d.Validate
+	if fn != GetDigestFunction() {
+		validate = func() error { return ValidateFor(d, fn) }
+	}
+	if err := validate(); err != nil {
+		return Empty, &ParseError{Input: input, Err: ErrInvalidHash, Detail: err.Error()}
+	}
+	return d, nil
+}
+
+func detectFormat(s string) Format {
+	if i := strings.Index(s, ":"); i > 0 {
+		if _, ok := functionNamed(s[:i]); ok {
+			return FormatFunction
+		}
+	}
+	switch {
+	case strings.Contains(s, ":"):
+		return FormatProto
+	case strings.HasPrefix(strings.TrimPrefix(s, "/"), "blobs/"):
+		return FormatPath
+	case strings.Contains(s, "/"):
+		return FormatCanonical
+	default:
+		return FormatDash
+	}
+}
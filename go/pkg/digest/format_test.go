@@ -0,0 +1,89 @@
+package digest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, f := range []Format{FormatCanonical, FormatDash, FormatPath, FormatProto, FormatFunction} {
+		s := dSHA256.Format(f)
+		if got, err := ParseFormat(s, f); err != nil || got != dSHA256 {
+			t.Errorf("ParseFormat(%q, %v) = (%v, %v), want (%v, nil)", s, f, got, err, dSHA256)
+		}
+		if got, err := Parse(s); err != nil || got != dSHA256 {
+			t.Errorf("Parse(%q) = (%v, %v), want (%v, nil)", s, got, err, dSHA256)
+		}
+	}
+}
+
+func TestFormatStrings(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		f    Format
+		want string
+	}{
+		{FormatCanonical, dSHA256.Hash + "/321"},
+		{FormatDash, dSHA256.Hash + "-321"},
+		{FormatPath, "blobs/" + dSHA256.Hash + "/321"},
+		{FormatFunction, "sha256:" + dSHA256.Hash + "/321"},
+	}
+	for _, tc := range tests {
+		if got := dSHA256.Format(tc.f); got != tc.want {
+			t.Errorf("%v.Format(%v) = %q, want %q", dSHA256, tc.f, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{input: "", want: ErrInvalidFormat},
+		{input: "a/b/c", want: ErrInvalidFormat},
+		{input: dSHA256.Hash + "/abc", want: ErrInvalidSize},
+		{input: dSHA256.Hash + "/-1", want: ErrInvalidSize},
+		{input: dInvalid.Hash + "-321", want: ErrInvalidHash},
+		{input: "/blobs/" + dSHA256.Hash, want: ErrInvalidFormat},
+		{input: "hash: 3", want: ErrInvalidFormat},
+		{input: "sha256:" + dSHA256.Hash, want: ErrInvalidFormat},
+		{input: "sha512:" + dSHA256.Hash + "/321", want: ErrInvalidHash},
+	}
+	for _, tc := range tests {
+		_, err := Parse(tc.input)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.input, err, tc.want)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Input != tc.input {
+			t.Errorf("Parse(%q) = %v, want *ParseError with the input", tc.input, err)
+		}
+	}
+}
+
+func TestParseFunction(t *testing.T) {
+	t.Parallel()
+	sha512 := Digest{Hash: strings.Repeat("b", 128), Size: 5}
+	tests := []struct {
+		input  string
+		want   Digest
+		wantFn repb.DigestFunction_Value
+	}{
+		{input: dSHA256.String(), want: dSHA256, wantFn: repb.DigestFunction_SHA256},
+		{input: "sha256:" + dSHA256.String(), want: dSHA256, wantFn: repb.DigestFunction_SHA256},
+		{input: "sha512:" + sha512.String(), want: sha512, wantFn: repb.DigestFunction_SHA512},
+		{input: "md5:" + strings.Repeat("c", 32) + "/1", want: Digest{Hash: strings.Repeat("c", 32), Size: 1}, wantFn: repb.DigestFunction_MD5},
+	}
+	for _, tc := range tests {
+		got, gotFn, err := ParseFunction(tc.input)
+		if err != nil || got != tc.want || gotFn != tc.wantFn {
+			t.Errorf("ParseFunction(%q) = (%v, %v, %v), want (%v, %v, nil)", tc.input, got, gotFn, err, tc.want, tc.wantFn)
+		}
+	}
+}
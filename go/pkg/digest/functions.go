@@ -0,0 +1,76 @@
+package digest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// hashLengths are the hex lengths of digest functions that have no registered implementation.
+var hashLengths = map[repb.DigestFunction_Value]int{
+	repb.DigestFunction_VSO:     66,
+	repb.DigestFunction_MURMUR3: 32,
+}
+
+// HashLength returns the length of the hex-encoded hash produced by the given digest function.
+func HashLength(fn repb.DigestFunction_Value) (int, error) {
+	if newHash, err := hasherFor(fn); err == nil {
+		return newHash().Size() * 2, nil
+	}
+	if l, ok := hashLengths[fn]; ok {
+		return l, nil
+	}
+	return 0, fmt.Errorf("unknown digest function %v", fn)
+}
+
+// EmptyFor returns the digest of the empty blob for the given digest function.
+// It returns an error if the function has no registered implementation.
+func EmptyFor(fn repb.DigestFunction_Value) (Digest, error) {
+	newHash, err := hasherFor(fn)
+	if err != nil {
+		return Empty, err
+	}
+	return Digest{Hash: hex.EncodeToString(newHash().Sum(nil)), Size: 0}, nil
+}
+
+// ValidateFor is like Validate, but checks the hash length expected for the given digest
+// function rather than the one of HashFn.
+func ValidateFor(d Digest, fn repb.DigestFunction_Value) error {
+	want, err := HashLength(fn)
+	if err != nil {
+		return err
+	}
+	if length := len(d.Hash); length != want {
+		return fmt.Errorf("valid %v hash length is %d, got length %d (%s)", fn, want, length, d.Hash)
+	}
+	if !hexStringRegex.MatchString(d.Hash) {
+		return fmt.Errorf("hash is not a lowercase hex string (%s)", d.Hash)
+	}
+	if d.Size < 0 {
+		return fmt.Errorf("expected non-negative size, got %d", d.Size)
+	}
+	return nil
+}
+
+// DetectFunctions returns the digest functions that could have produced the given hash, judging
+// by its length, in ascending enum order. Several functions share hash lengths (e.g. MD5 and
+// MURMUR3), so the result may be ambiguous; it is empty if the hash is not lowercase hex.
+func DetectFunctions(hash string) []repb.DigestFunction_Value {
+	if !hexStringRegex.MatchString(hash) {
+		return nil
+	}
+	var res []repb.DigestFunction_Value
+	for v := range repb.DigestFunction_Value_name {
+		fn := repb.DigestFunction_Value(v)
+		if fn == repb.DigestFunction_UNKNOWN {
+			continue
+		}
+		if l, err := HashLength(fn); err == nil && l == len(hash) {
+			res = append(res, fn)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestEmptyFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		fn   repb.DigestFunction_Value
+		want string
+	}{
+		{repb.DigestFunction_SHA256, Empty.Hash},
+		{repb.DigestFunction_SHA1, "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{repb.DigestFunction_MD5, "d41d8cd98f00b204e9800998ecf8427e"},
+	}
+	for _, tc := range tests {
+		got, err := EmptyFor(tc.fn)
+		if err != nil || got != (Digest{Hash: tc.want}) {
+			t.Errorf("EmptyFor(%v) = (%v, %v), want (%v/0, nil)", tc.fn, got, err, tc.want)
+		}
+	}
+	if _, err := EmptyFor(repb.DigestFunction_VSO); err == nil {
+		t.Errorf("EmptyFor(VSO) succeeded, want error")
+	}
+}
+
+func TestValidateFor(t *testing.T) {
+	t.Parallel()
+	sha1 := Digest{Hash: strings.Repeat("a", 40), Size: 3}
+	if err := ValidateFor(sha1, repb.DigestFunction_SHA1); err != nil {
+		t.Errorf("ValidateFor(%v, SHA1) = %v, want nil", sha1, err)
+	}
+	if err := ValidateFor(sha1, repb.DigestFunction_SHA256); err == nil {
+		t.Errorf("ValidateFor(%v, SHA256) = nil, want error", sha1)
+	}
+	upper := Digest{Hash: strings.Repeat("A", 40), Size: 3}
+	if err := ValidateFor(upper, repb.DigestFunction_SHA1); err == nil {
+		t.Errorf("ValidateFor(%v, SHA1) = nil, want error", upper)
+	}
+}
+
+func TestDetectFunctions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		hash string
+		want []repb.DigestFunction_Value
+	}{
+		{strings.Repeat("a", 64), []repb.DigestFunction_Value{repb.DigestFunction_SHA256}},
+		{strings.Repeat("a", 40), []repb.DigestFunction_Value{repb.DigestFunction_SHA1}},
+		{strings.Repeat("a", 32), []repb.DigestFunction_Value{repb.DigestFunction_MD5, repb.DigestFunction_MURMUR3}},
+		{strings.Repeat("a", 128), []repb.DigestFunction_Value{repb.DigestFunction_SHA512}},
+		{strings.Repeat("a", 10), nil},
+		{strings.Repeat("x", 64), nil},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, DetectFunctions(tc.hash)); diff != "" {
+			t.Errorf("DetectFunctions(%v) returned diff. (-want +got)\n%s", tc.hash, diff)
+		}
+	}
+}
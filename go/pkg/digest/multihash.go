@@ -0,0 +1,88 @@
+package digest
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+var (
+	hashersMu sync.RWMutex
+	// hashers are the digest functions that multi-hash computations support.
+	hashers = map[repb.DigestFunction_Value]func() hash.Hash{
+		repb.DigestFunction_SHA256: sha256.New,
+		repb.DigestFunction_SHA1:   sha1.New,
+		repb.DigestFunction_MD5:    md5.New,
+		repb.DigestFunction_SHA384: sha512.New384,
+		repb.DigestFunction_SHA512: sha512.New,
+	}
+)
+
+// RegisterHashFunction makes a digest function available to the multi-hash functions, or
+// replaces its implementation. This allows using functions that are not in the standard
+// library, such as BLAKE3, without this package depending on them.
+func RegisterHashFunction(fn repb.DigestFunction_Value, newHash func() hash.Hash) {
+	hashersMu.Lock()
+	defer hashersMu.Unlock()
+	hashers[fn] = newHash
+}
+
+func hasherFor(fn repb.DigestFunction_Value) (func() hash.Hash, error) {
+	hashersMu.RLock()
+	defer hashersMu.RUnlock()
+	newHash, ok := hashers[fn]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest function %v", fn)
+	}
+	return newHash, nil
+}
+
+// NewMultiFromReader computes the digests of the reader's contents for each of the given digest
+// functions, reading the contents only once.
+func NewMultiFromReader(r io.Reader, fns ...repb.DigestFunction_Value) (map[repb.DigestFunction_Value]Digest, error) {
+	hs := make(map[repb.DigestFunction_Value]hash.Hash, len(fns))
+	ws := make([]io.Writer, 0, len(fns))
+	for _, fn := range fns {
+		if _, ok := hs[fn]; ok {
+			continue
+		}
+		newHash, err := hasherFor(fn)
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		hs[fn] = h
+		ws = append(ws, h)
+	}
+	buf := copyBufs.Get().(*[]byte)
+	defer copyBufs.Put(buf)
+	size, err := io.CopyBuffer(io.MultiWriter(ws...), r, *buf)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[repb.DigestFunction_Value]Digest, len(hs))
+	for fn, h := range hs {
+		res[fn] = Digest{Hash: hex.EncodeToString(h.Sum(nil)), Size: size}
+	}
+	return res, nil
+}
+
+// NewMultiFromFile computes the digests of a file for each of the given digest functions,
+// reading the file only once.
+func NewMultiFromFile(path string, fns ...repb.DigestFunction_Value) (map[repb.DigestFunction_Value]Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewMultiFromReader(f, fns...)
+}
@@ -0,0 +1,54 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestNewMultiFromReader(t *testing.T) {
+	t.Parallel()
+	blob := []byte("multi-hash")
+	got, err := NewMultiFromReader(bytes.NewReader(blob), repb.DigestFunction_SHA256, repb.DigestFunction_SHA1)
+	if err != nil {
+		t.Fatalf("NewMultiFromReader() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("NewMultiFromReader() returned %d digests, want 2", len(got))
+	}
+	if want := NewFromBlob(blob); got[repb.DigestFunction_SHA256] != want {
+		t.Errorf("NewMultiFromReader()[SHA256] = %v, want %v", got[repb.DigestFunction_SHA256], want)
+	}
+	sum := sha1.Sum(blob)
+	if want := (Digest{Hash: hex.EncodeToString(sum[:]), Size: int64(len(blob))}); got[repb.DigestFunction_SHA1] != want {
+		t.Errorf("NewMultiFromReader()[SHA1] = %v, want %v", got[repb.DigestFunction_SHA1], want)
+	}
+}
+
+func TestNewMultiFromReaderUnsupported(t *testing.T) {
+	t.Parallel()
+	if _, err := NewMultiFromReader(bytes.NewReader(nil), repb.DigestFunction_VSO); err == nil {
+		t.Errorf("NewMultiFromReader(VSO) succeeded, want error")
+	}
+}
+
+func TestRegisterHashFunction(t *testing.T) {
+	fn := repb.DigestFunction_MURMUR3
+	RegisterHashFunction(fn, func() hash.Hash { return sha1.New() })
+	defer func() {
+		hashersMu.Lock()
+		delete(hashers, fn)
+		hashersMu.Unlock()
+	}()
+	got, err := NewMultiFromReader(bytes.NewReader([]byte("a")), fn)
+	if err != nil {
+		t.Fatalf("NewMultiFromReader(%v) failed: %v", fn, err)
+	}
+	if got[fn].Size != 1 {
+		t.Errorf("NewMultiFromReader(%v) = %v, want a digest of size 1", fn, got[fn])
+	}
+}
@@ -0,0 +1,251 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ErrInvalidResourceName is returned when a string is not a valid ByteStream resource name.
+var ErrInvalidResourceName = errors.New("invalid resource name")
+
+// reservedSegments are the path segments that the remote execution API forbids in instance
+// names, because they would make resource names ambiguous.
+var reservedSegments = map[string]bool{
+	"blobs":            true,
+	"uploads":          true,
+	"actions":          true,
+	"actionResults":    true,
+	"operations":       true,
+	"capabilities":     true,
+	"compressed-blobs": true,
+}
+
+// ResourceName identifies a blob in the ByteStream API. Read resource names have the form
+// {instance_name}/blobs/{hash}/{size}, or {instance_name}/compressed-blobs/{compressor}/{hash}/{size}
+// for compressed blobs. Write resource names have the form
+// {instance_name}/uploads/{uuid}/blobs/{hash}/{size}{/optional_metadata}, or the equivalent
+// compressed-blobs form.
+type ResourceName struct {
+	// InstanceName is the instance name, possibly empty or containing slashes.
+	InstanceName string
+	// UploadID is the client-generated UUID of a write. It is empty for read resource names.
+	UploadID string
+	// Compressor is the compressor of the transferred data; IDENTITY means uncompressed.
+	Compressor repb.Compressor_Value
+	// Digest is the digest of the uncompressed blob.
+	Digest Digest
+	// Metadata is the optional trailing part of a write resource name, without the leading slash.
+	Metadata string
+}
+
+// NewReadResourceName returns the resource name used to read the blob uncompressed.
+func NewReadResourceName(instanceName string, d Digest) string {
+	return ResourceName{InstanceName: instanceName, Digest: d}.String()
+}
+
+// NewCompressedReadResourceName returns the resource name used to read the blob compressed with c.
+func NewCompressedReadResourceName(instanceName string, c repb.Compressor_Value, d Digest) string {
+	return ResourceName{InstanceName: instanceName, Compressor: c, Digest: d}.String()
+}
+
+// NewWriteResourceName returns the resource name used to write the blob uncompressed.
+func NewWriteResourceName(instanceName, uploadID string, d Digest) string {
+	return ResourceName{InstanceName: instanceName, UploadID: uploadID, Digest: d}.String()
+}
+
+// NewCompressedWriteResourceName returns the resource name used to write the blob compressed with c.
+func NewCompressedWriteResourceName(instanceName, uploadID string, c repb.Compressor_Value, d Digest) string {
+	return ResourceName{InstanceName: instanceName, UploadID: uploadID, Compressor: c, Digest: d}.String()
+}
+
+// IsWrite returns whether r is a write resource name.
+func (r ResourceName) IsWrite() bool {
+	return r.UploadID != ""
+}
+
+// String returns the resource name, in the write form if UploadID is set and in the read form
+// otherwise. With an empty instance name, the resource name starts with a slash, e.g.
+// /blobs/{hash}/{size}, as the Client has always sent it.
+func (r ResourceName) String() string {
+	parts := []string{r.InstanceName}
+	if r.IsWrite() {
+		parts = append(parts, "uploads", r.UploadID)
+	}
+	if r.Compressor == repb.Compressor_IDENTITY {
+		parts = append(parts, "blobs")
+	} else {
+		parts = append(parts, "compressed-blobs", compressorName(r.Compressor))
+	}
+	parts = append(parts, r.Digest.Hash, strconv.FormatInt(r.Digest.Size, 10))
+	if r.IsWrite() && r.Metadata != "" {
+		parts = append(parts, r.Metadata)
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParseReadResourceName parses a read resource name. The returned error, if any, is a *ParseError
+// wrapping ErrInvalidResourceName, ErrInvalidHash or ErrInvalidSize.
+func ParseReadResourceName(name string) (*ResourceName, error) {
+	segs := strings.Split(name, "/")
+	i := indexOfAny(segs, "blobs", "compressed-blobs")
+	if i < 0 {
+		return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "expected {instance_name}/blobs/{hash}/{size}"}
+	}
+	r := &ResourceName{}
+	if err := r.parseInstanceName(name, segs[:i]); err != nil {
+		return nil, err
+	}
+	rest, err := r.parseBlob(name, segs[i:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "unexpected trailing path after the blob size"}
+	}
+	return r, nil
+}
+
+// ParseWriteResourceName parses a write resource name. The returned error, if any, is a
+// *ParseError wrapping ErrInvalidResourceName, ErrInvalidHash or ErrInvalidSize.
+func ParseWriteResourceName(name string) (*ResourceName, error) {
+	segs := strings.Split(name, "/")
+	i := indexOfAny(segs, "uploads")
+	if i < 0 || len(segs) < i+2 || segs[i+1] == "" {
+		return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "expected {instance_name}/uploads/{uuid}/blobs/{hash}/{size}"}
+	}
+	r := &ResourceName{UploadID: segs[i+1]}
+	if err := r.parseInstanceName(name, segs[:i]); err != nil {
+		return nil, err
+	}
+	rest, err := r.parseBlob(name, segs[i+2:])
+	if err != nil {
+		return nil, err
+	}
+	r.Metadata = strings.Join(rest, "/")
+	return r, nil
+}
+
+// ParseResourceName parses either a read or a write resource name.
+func ParseResourceName(name string) (*ResourceName, error) {
+	if indexOfAny(strings.Split(name, "/"), "uploads") >= 0 {
+		return ParseWriteResourceName(name)
+	}
+	return ParseReadResourceName(name)
+}
+
+// bytestreamScheme is the scheme of the ByteStream URLs of blobs handed out by some servers.
+const bytestreamScheme = "bytestream://"
+
+// ParseBytestreamURL parses the ByteStream URL of a blob, of the form
+// bytestream://{host}/{read_resource_name}, e.g. bytestream://host:443/instance/blobs/{hash}/{size}.
+// It returns the host, with its port if any, and the read resource name. The returned error, if
+// any, is a *ParseError.
+func ParseBytestreamURL(url string) (string, *ResourceName, error) {
+	if !strings.HasPrefix(url, bytestreamScheme) {
+		return "", nil, &ParseError{Input: url, Err: ErrInvalidResourceName, Detail: "expected " + bytestreamScheme + "{host}/{resource_name}"}
+	}
+	rest := strings.TrimPrefix(url, bytestreamScheme)
+	i := strings.Index(rest, "/")
+	if i <= 0 {
+		return "", nil, &ParseError{Input: url, Err: ErrInvalidResourceName, Detail: "expected " + bytestreamScheme + "{host}/{resource_name}"}
+	}
+	r, err := ParseReadResourceName(rest[i+1:])
+	if err != nil {
+		return "", nil, err
+	}
+	return rest[:i], r, nil
+}
+
+// ParseReference parses a reference to a blob, which is either a digest in the format accepted
+// by NewFromString, a read resource name, e.g. {instance_name}/blobs/{hash}/{size}, or a ByteStream
+// URL as accepted by ParseBytestreamURL, e.g. copied from the logs of a server. It returns the
+// digest of the blob with the instance name of the resource name, which is empty for digests.
+func ParseReference(s string) (Digest, string, error) {
+	if strings.HasPrefix(s, bytestreamScheme) {
+		_, r, err := ParseBytestreamURL(s)
+		if err != nil {
+			return Empty, "", err
+		}
+		return r.Digest, r.InstanceName, nil
+	}
+	if indexOfAny(strings.Split(s, "/"), "blobs", "compressed-blobs") >= 0 {
+		r, err := ParseReadResourceName(strings.TrimPrefix(s, "/"))
+		if err != nil {
+			return Empty, "", err
+		}
+		return r.Digest, r.InstanceName, nil
+	}
+	d, err := NewFromString(s)
+	return d, "", err
+}
+
+// parseInstanceName sets the instance name from the segments preceding the blob or upload. A
+// single empty segment, i.e. a leading slash, is an empty instance name.
+func (r *ResourceName) parseInstanceName(name string, segs []string) error {
+	if len(segs) == 1 && segs[0] == "" {
+		segs = nil
+	}
+	for _, s := range segs {
+		if s == "" {
+			return &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "empty instance name segment"}
+		}
+		if reservedSegments[s] {
+			return &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: fmt.Sprintf("instance name contains reserved segment %q", s)}
+		}
+	}
+	r.InstanceName = strings.Join(segs, "/")
+	return nil
+}
+
+// parseBlob parses blobs/{hash}/{size} or compressed-blobs/{compressor}/{hash}/{size} from the
+// beginning of segs, and returns the remaining segments.
+func (r *ResourceName) parseBlob(name string, segs []string) ([]string, error) {
+	if len(segs) > 0 && segs[0] == "compressed-blobs" {
+		if len(segs) < 2 {
+			return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "missing compressor"}
+		}
+		c, ok := repb.Compressor_Value_value[strings.ToUpper(segs[1])]
+		if !ok || c == int32(repb.Compressor_IDENTITY) || segs[1] != strings.ToLower(segs[1]) {
+			return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: fmt.Sprintf("unknown compressor %q", segs[1])}
+		}
+		r.Compressor = repb.Compressor_Value(c)
+		segs = segs[2:]
+	} else if len(segs) > 0 && segs[0] == "blobs" {
+		segs = segs[1:]
+	} else {
+		return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "expected blobs or compressed-blobs"}
+	}
+	if len(segs) < 2 {
+		return nil, &ParseError{Input: name, Err: ErrInvalidResourceName, Detail: "expected {hash}/{size}"}
+	}
+	sz, err := strconv.ParseInt(segs[1], 10, 64)
+	if err != nil {
+		return nil, &ParseError{Input: name, Err: ErrInvalidSize, Detail: err.Error()}
+	}
+	d, err := newParsed(name, segs[0], sz, GetDigestFunction())
+	if err != nil {
+		return nil, err
+	}
+	r.Digest = d
+	return segs[2:], nil
+}
+
+func compressorName(c repb.Compressor_Value) string {
+	return strings.ToLower(c.String())
+}
+
+// indexOfAny returns the index of the first segment equal to one of the values, or -1.
+func indexOfAny(segs []string, values ...string) int {
+	for i, s := range segs {
+		for _, v := range values {
+			if s == v {
+				return i
+			}
+		}
+	}
+	return -1
+}
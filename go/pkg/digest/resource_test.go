@@ -0,0 +1,180 @@
+package digest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestResourceNameRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		want *ResourceName
+	}{
+		{
+			name: "/blobs/" + dSHA256.Hash + "/321",
+			want: &ResourceName{Digest: dSHA256},
+		},
+		{
+			name: "/uploads/some-uuid/compressed-blobs/zstd/" + dSHA256.Hash + "/321",
+			want: &ResourceName{UploadID: "some-uuid", Compressor: repb.Compressor_ZSTD, Digest: dSHA256},
+		},
+		{
+			name: "projects/p/instances/default/blobs/" + dSHA256.Hash + "/321",
+			want: &ResourceName{InstanceName: "projects/p/instances/default", Digest: dSHA256},
+		},
+		{
+			name: "instance/compressed-blobs/zstd/" + dSHA256.Hash + "/321",
+			want: &ResourceName{InstanceName: "instance", Compressor: repb.Compressor_ZSTD, Digest: dSHA256},
+		},
+		{
+			name: "instance/uploads/some-uuid/blobs/" + dSHA256.Hash + "/321",
+			want: &ResourceName{InstanceName: "instance", UploadID: "some-uuid", Digest: dSHA256},
+		},
+		{
+			name: "a/b/uploads/some-uuid/compressed-blobs/deflate/" + dSHA256.Hash + "/321/extra/metadata",
+			want: &ResourceName{InstanceName: "a/b", UploadID: "some-uuid", Compressor: repb.Compressor_DEFLATE, Digest: dSHA256, Metadata: "extra/metadata"},
+		},
+	}
+	for _, tc := range tests {
+		got, err := ParseResourceName(tc.name)
+		if err != nil {
+			t.Errorf("ParseResourceName(%q) failed: %v", tc.name, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("ParseResourceName(%q) returned diff (-want +got):\n%s", tc.name, diff)
+		}
+		if s := got.String(); s != tc.name {
+			t.Errorf("String() = %q, want %q", s, tc.name)
+		}
+	}
+}
+
+func TestResourceNameConstructors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		got, want string
+	}{
+		{NewReadResourceName("i", dSHA256), "i/blobs/" + dSHA256.Hash + "/321"},
+		{NewReadResourceName("", dSHA256), "/blobs/" + dSHA256.Hash + "/321"},
+		{NewWriteResourceName("", "u", dSHA256), "/uploads/u/blobs/" + dSHA256.Hash + "/321"},
+		{NewCompressedReadResourceName("i", repb.Compressor_ZSTD, dSHA256), "i/compressed-blobs/zstd/" + dSHA256.Hash + "/321"},
+		{NewWriteResourceName("i", "u", dSHA256), "i/uploads/u/blobs/" + dSHA256.Hash + "/321"},
+		{NewCompressedWriteResourceName("i", "u", repb.Compressor_ZSTD, dSHA256), "i/uploads/u/compressed-blobs/zstd/" + dSHA256.Hash + "/321"},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("got resource name %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
+func TestParseResourceNameEmptyInstance(t *testing.T) {
+	t.Parallel()
+	want := &ResourceName{Digest: dSHA256}
+	for _, name := range []string{"blobs/" + dSHA256.Hash + "/321", "/blobs/" + dSHA256.Hash + "/321"} {
+		got, err := ParseReadResourceName(name)
+		if err != nil {
+			t.Errorf("ParseReadResourceName(%q) failed: %v", name, err)
+			continue
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseReadResourceName(%q) returned diff (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestParseResourceNameErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		parse   func(string) (*ResourceName, error)
+		wantErr error
+	}{
+		{"instance/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/blobs/" + dSHA256.Hash, ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/blobs/" + dSHA256.Hash + "/321/extra", ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/compressed-blobs/gzip/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/compressed-blobs/identity/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"a//b/blobs/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"/instance/blobs/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"actions/x/blobs/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/blobs/abc/321", ParseReadResourceName, ErrInvalidHash},
+		{"instance/blobs/" + dSHA256.Hash + "/-1", ParseReadResourceName, ErrInvalidSize},
+		{"instance/blobs/" + dSHA256.Hash + "/x", ParseReadResourceName, ErrInvalidSize},
+		{"instance/uploads/u/blobs/" + dSHA256.Hash + "/321", ParseReadResourceName, ErrInvalidResourceName},
+		{"instance/blobs/" + dSHA256.Hash + "/321", ParseWriteResourceName, ErrInvalidResourceName},
+		{"instance/uploads//blobs/" + dSHA256.Hash + "/321", ParseWriteResourceName, ErrInvalidResourceName},
+	}
+	for _, tc := range tests {
+		_, err := tc.parse(tc.name)
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, tc.wantErr) {
+			t.Errorf("parsing %q returned error %v, want a *ParseError wrapping %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestParseBytestreamURL(t *testing.T) {
+	t.Parallel()
+	host, got, err := ParseBytestreamURL("bytestream://remote.example.com:443/projects/p/instances/default/blobs/" + dSHA256.Hash + "/321")
+	if err != nil {
+		t.Fatalf("ParseBytestreamURL() failed: %v", err)
+	}
+	want := &ResourceName{InstanceName: "projects/p/instances/default", Digest: dSHA256}
+	if host != "remote.example.com:443" {
+		t.Errorf("ParseBytestreamURL() returned host %q, want %q", host, "remote.example.com:443")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseBytestreamURL() returned diff (-want +got):\n%s", diff)
+	}
+
+	for _, url := range []string{
+		"http://host/blobs/" + dSHA256.Hash + "/321",
+		"bytestream://host",
+		"bytestream:///blobs/" + dSHA256.Hash + "/321",
+		"bytestream://host/instance/" + dSHA256.Hash + "/321",
+	} {
+		_, _, err := ParseBytestreamURL(url)
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, ErrInvalidResourceName) {
+			t.Errorf("ParseBytestreamURL(%q) returned error %v, want a *ParseError wrapping %v", url, err, ErrInvalidResourceName)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ref          string
+		wantInstance string
+	}{
+		{dSHA256.String(), ""},
+		{"blobs/" + dSHA256.Hash + "/321", ""},
+		{"/blobs/" + dSHA256.Hash + "/321", ""},
+		{"a/b/blobs/" + dSHA256.Hash + "/321", "a/b"},
+		{"instance/compressed-blobs/zstd/" + dSHA256.Hash + "/321", "instance"},
+		{"bytestream://host/instance/blobs/" + dSHA256.Hash + "/321", "instance"},
+		{"bytestream://host/blobs/" + dSHA256.Hash + "/321", ""},
+	}
+	for _, tc := range tests {
+		got, instance, err := ParseReference(tc.ref)
+		if err != nil {
+			t.Errorf("ParseReference(%q) failed: %v", tc.ref, err)
+			continue
+		}
+		if got != dSHA256 || instance != tc.wantInstance {
+			t.Errorf("ParseReference(%q) = %v, %q, want %v, %q", tc.ref, got, instance, dSHA256, tc.wantInstance)
+		}
+	}
+	for _, ref := range []string{"", dSHA256.Hash, "instance/blobs/" + dSHA256.Hash, "bytestream://host"} {
+		if _, _, err := ParseReference(ref); err == nil {
+			t.Errorf("ParseReference(%q) succeeded, want error", ref)
+		}
+	}
+}
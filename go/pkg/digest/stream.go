@@ -0,0 +1,73 @@
+package digest
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrTooLarge is returned when the content being hashed exceeds HashOptions.MaxSize.
+var ErrTooLarge = errors.New("content exceeds the maximum size")
+
+// HashOptions configures NewFromReaderContext.
+type HashOptions struct {
+	// MaxSize, if positive, is the maximum number of bytes hashed before failing with ErrTooLarge.
+	MaxSize int64
+	// Progress, if set, is called after every chunk read with the total number of bytes hashed.
+	Progress func(hashed int64)
+}
+
+// NewFromReaderContext computes a digest from a reader like NewFromReader, but stops early if
+// ctx is done or if the content is larger than opts.MaxSize, and reports progress as it goes.
+func NewFromReaderContext(ctx context.Context, r io.Reader, opts HashOptions) (Digest, error) {
+	h := HashFn.New()
+	buf := copyBufs.Get().(*[]byte)
+	defer copyBufs.Put(buf)
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return Empty, err
+		}
+		n, err := r.Read(*buf)
+		if n > 0 {
+			size += int64(n)
+			if opts.MaxSize > 0 && size > opts.MaxSize {
+				return Empty, fmt.Errorf("%w: read more than %d bytes", ErrTooLarge, opts.MaxSize)
+			}
+			h.Write((*buf)[:n])
+			if opts.Progress != nil {
+				opts.Progress(size)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Empty, err
+		}
+	}
+	return Digest{
+		Hash: hex.EncodeToString(h.Sum(nil)),
+		Size: size,
+	}, nil
+}
+
+// NewFromFileContext computes a file digest from a path like NewFromFile, with the cancellation,
+// size guard and progress reporting of NewFromReaderContext. Files known to be larger than
+// opts.MaxSize fail without being read.
+func NewFromFileContext(ctx context.Context, path string, opts HashOptions) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Empty, err
+	}
+	defer f.Close()
+	if opts.MaxSize > 0 {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && fi.Size() > opts.MaxSize {
+			return Empty, fmt.Errorf("%w: %s is %d bytes, maximum is %d", ErrTooLarge, path, fi.Size(), opts.MaxSize)
+		}
+	}
+	return NewFromReaderContext(ctx, f, opts)
+}
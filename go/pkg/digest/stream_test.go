@@ -0,0 +1,65 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFromReaderContext(t *testing.T) {
+	t.Parallel()
+	blob := bytes.Repeat([]byte("a"), 100*1024)
+	var last int64
+	calls := 0
+	got, err := NewFromReaderContext(context.Background(), bytes.NewReader(blob), HashOptions{
+		Progress: func(hashed int64) {
+			calls++
+			last = hashed
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReaderContext() failed: %v", err)
+	}
+	if want := NewFromBlob(blob); got != want {
+		t.Errorf("NewFromReaderContext() = %v, want %v", got, want)
+	}
+	if calls < 2 || last != int64(len(blob)) {
+		t.Errorf("Progress called %d times with last value %d, want several calls ending at %d", calls, last, len(blob))
+	}
+}
+
+func TestNewFromReaderContextMaxSize(t *testing.T) {
+	t.Parallel()
+	blob := []byte("too large")
+	_, err := NewFromReaderContext(context.Background(), bytes.NewReader(blob), HashOptions{MaxSize: 3})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("NewFromReaderContext() = %v, want %v", err, ErrTooLarge)
+	}
+	got, err := NewFromReaderContext(context.Background(), bytes.NewReader(blob), HashOptions{MaxSize: int64(len(blob))})
+	if err != nil || got != NewFromBlob(blob) {
+		t.Errorf("NewFromReaderContext() = (%v, %v), want (%v, nil)", got, err, NewFromBlob(blob))
+	}
+}
+
+func TestNewFromReaderContextCanceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewFromReaderContext(ctx, bytes.NewReader([]byte("a")), HashOptions{}); err != context.Canceled {
+		t.Errorf("NewFromReaderContext() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewFromFileContextMaxSize(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(path, []byte("too large"), 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", path, err)
+	}
+	if _, err := NewFromFileContext(context.Background(), path, HashOptions{MaxSize: 3}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("NewFromFileContext(%v) = %v, want %v", path, err, ErrTooLarge)
+	}
+}
@@ -261,16 +261,25 @@ func (f *Writer) QueryWriteStatus(context.Context, *bspb.QueryWriteStatusRequest
 type CAS struct {
 	// Maximum batch byte size to verify requests against.
 	BatchSize         int
+	MessageSizeLimit  int // If positive, larger batch messages are rejected with RESOURCE_EXHAUSTED.
 	ReqSleepDuration  time.Duration
 	ReqSleepRandomize bool
 	PerDigestBlockFn  map[digest.Digest]func()
+	SplitSplice       bool  // Whether SplitBlob and SpliceBlob are advertised and served.
+	NoCompressors     bool  // Whether the zstd compressor is not advertised.
+	DisconnectAfter   int64 // If positive, the first Write stream of each upload fails with UNAVAILABLE past this many bytes.
 	blobs             map[digest.Digest][]byte
+	uploads           map[string]*upload
 	reads             map[digest.Digest]int
 	writes            map[digest.Digest]int
 	missingReqs       map[digest.Digest]int
 	mu                sync.RWMutex
 	batchReqs         int
+	splitReqs         int
+	spliceReqs        int
 	writeReqs         int
+	resumedWrites     int
+	queryWriteReqs    int
 	concReqs          int
 	maxConcReqs       int
 }
@@ -298,11 +307,23 @@ func (f *CAS) Clear() {
 	f.writes = make(map[digest.Digest]int)
 	f.missingReqs = make(map[digest.Digest]int)
 	f.batchReqs = 0
+	f.splitReqs = 0
+	f.spliceReqs = 0
 	f.writeReqs = 0
+	f.resumedWrites = 0
+	f.queryWriteReqs = 0
+	f.uploads = make(map[string]*upload)
 	f.concReqs = 0
 	f.maxConcReqs = 0
 }
 
+// upload is the state of a Write of a resource name, as reported by QueryWriteStatus.
+type upload struct {
+	committed    []byte
+	complete     bool
+	disconnected bool
+}
+
 // Put adds a given blob to the cache and returns its digest.
 func (f *CAS) Put(blob []byte) digest.Digest {
 	f.mu.Lock()
@@ -348,6 +369,21 @@ func (f *CAS) BatchReqs() int {
 	return f.batchReqs
 }
 
+// ResumedWrites returns the number of Write streams which resumed an upload from its committed
+// bytes.
+func (f *CAS) ResumedWrites() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.resumedWrites
+}
+
+// QueryWriteStatusReqs returns the number of QueryWriteStatus requests received.
+func (f *CAS) QueryWriteStatusReqs() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.queryWriteReqs
+}
+
 // WriteReqs returns the total number of Write requests to this fake.
 func (f *CAS) WriteReqs() int {
 	f.mu.RLock()
@@ -420,6 +456,9 @@ func (f *CAS) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRe
 
 	reqBlob, _ := proto.Marshal(req)
 	size := len(reqBlob)
+	if f.MessageSizeLimit > 0 && size > f.MessageSizeLimit {
+		return nil, status.Errorf(codes.ResourceExhausted, "test fake received a message larger than the maximum of %d bytes: %d bytes", f.MessageSizeLimit, size)
+	}
 	if size > f.BatchSize {
 		return nil, status.Errorf(codes.InvalidArgument, "test fake received batch update for more than the maximum of %d bytes: %d bytes", f.BatchSize, size)
 	}
@@ -470,6 +509,15 @@ func (f *CAS) BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsReques
 
 	reqBlob, _ := proto.Marshal(req)
 	size := len(reqBlob)
+	if f.MessageSizeLimit > 0 {
+		var respSize int64
+		for _, dg := range req.Digests {
+			respSize += dg.SizeBytes
+		}
+		if respSize > int64(f.MessageSizeLimit) {
+			return nil, status.Errorf(codes.ResourceExhausted, "test fake would send a message larger than the maximum of %d bytes: %d bytes", f.MessageSizeLimit, respSize)
+		}
+	}
 	if size > f.BatchSize {
 		return nil, status.Errorf(codes.InvalidArgument, "test fake received batch read for more than the maximum of %d bytes: %d bytes", f.BatchSize, size)
 	}
@@ -610,6 +658,19 @@ func (f *CAS) Write(stream bsgrpc.ByteStream_WriteServer) (err error) {
 	}
 	f.mu.Unlock()
 	res := req.ResourceName
+	f.mu.Lock()
+	u, ok := f.uploads[res]
+	if !ok {
+		u = &upload{}
+		f.uploads[res] = u
+	}
+	if req.WriteOffset > 0 && req.WriteOffset == int64(len(u.committed)) {
+		// Resume the upload from the committed bytes.
+		f.resumedWrites++
+		buf.Write(u.committed)
+		off = req.WriteOffset
+	}
+	f.mu.Unlock()
 	done := false
 	for {
 		if req.ResourceName != res && req.ResourceName != "" {
@@ -632,6 +693,18 @@ func (f *CAS) Write(stream bsgrpc.ByteStream_WriteServer) (err error) {
 		if req.FinishWrite {
 			done = true
 		}
+		if f.DisconnectAfter > 0 && off > f.DisconnectAfter && !done {
+			f.mu.Lock()
+			disconnect := !u.disconnected
+			if disconnect {
+				u.committed = append([]byte(nil), buf.Bytes()...)
+				u.disconnected = true
+			}
+			f.mu.Unlock()
+			if disconnect {
+				return status.Errorf(codes.Unavailable, "test fake disconnected the write stream after %d bytes", off)
+			}
+		}
 
 		req, err = stream.Recv()
 		if err == io.EOF {
@@ -661,6 +734,8 @@ func (f *CAS) Write(stream bsgrpc.ByteStream_WriteServer) (err error) {
 	f.mu.Lock()
 	f.blobs[dg] = uncompressedBuf
 	f.writes[dg]++
+	u.committed = buf.Bytes()
+	u.complete = true
 	f.mu.Unlock()
 	cDg := digest.NewFromBlob(uncompressedBuf)
 	if dg != cDg {
@@ -671,10 +746,6 @@ func (f *CAS) Write(stream bsgrpc.ByteStream_WriteServer) (err error) {
 
 // Read implements the corresponding RE API function.
 func (f *CAS) Read(req *bspb.ReadRequest, stream bsgrpc.ByteStream_ReadServer) error {
-	if req.ReadOffset != 0 || req.ReadLimit != 0 {
-		return status.Error(codes.Unimplemented, "test fake does not implement read_offset or limit")
-	}
-
 	path := strings.Split(req.ResourceName, "/")
 	if (len(path) != 4 && len(path) != 5) || path[0] != "instance" || (path[1] != "blobs" && path[1] != "compressed-blobs") {
 		return status.Error(codes.InvalidArgument, "test fake expected resource name of the form \"instance/blobs|compressed-blobs/<compressor?>/<hash>/<size>\"")
@@ -701,11 +772,21 @@ func (f *CAS) Read(req *bspb.ReadRequest, stream bsgrpc.ByteStream_ReadServer) e
 	}
 
 	if path[1] == "compressed-blobs" {
+		if req.ReadOffset != 0 || req.ReadLimit != 0 {
+			return status.Error(codes.Unimplemented, "test fake does not implement read_offset or limit of compressed blobs")
+		}
 		if path[2] != "zstd" {
 			return status.Error(codes.InvalidArgument, "test fake expected valid compressor, eg zstd")
 		}
 		blob = zstdEncoder.EncodeAll(blob, nil)
 	}
+	if req.ReadOffset < 0 || req.ReadOffset > int64(len(blob)) || req.ReadLimit < 0 {
+		return status.Errorf(codes.OutOfRange, "test fake received invalid read_offset %d or read_limit %d for a blob of %d bytes", req.ReadOffset, req.ReadLimit, len(blob))
+	}
+	blob = blob[req.ReadOffset:]
+	if req.ReadLimit > 0 && req.ReadLimit < int64(len(blob)) {
+		blob = blob[:req.ReadLimit]
+	}
 	ue := uploadinfo.EntryFromBlob(blob)
 	ch, err := chunker.New(ue, false, 2*1024*1024)
 	if err != nil {
@@ -728,6 +809,13 @@ func (f *CAS) Read(req *bspb.ReadRequest, stream bsgrpc.ByteStream_ReadServer) e
 }
 
 // QueryWriteStatus implements the corresponding RE API function.
-func (f *CAS) QueryWriteStatus(context.Context, *bspb.QueryWriteStatusRequest) (*bspb.QueryWriteStatusResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "test fake does not implement method")
+func (f *CAS) QueryWriteStatus(ctx context.Context, req *bspb.QueryWriteStatusRequest) (*bspb.QueryWriteStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queryWriteReqs++
+	u, ok := f.uploads[req.ResourceName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "test fake has no upload %q", req.ResourceName)
+	}
+	return &bspb.QueryWriteStatusResponse{CommittedSize: int64(len(u.committed)), Complete: u.complete}, nil
 }
@@ -3,11 +3,13 @@ package fakes
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/splice"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"google.golang.org/grpc/codes"
@@ -42,6 +44,10 @@ type Exec struct {
 	t testing.TB
 	// The digest of the fake action.
 	adg digest.Digest
+	// Protects ops.
+	mu sync.Mutex
+	// The operations of the completed executions, by name, for WaitExecution.
+	ops map[string]*oppb.Operation
 }
 
 // NewExec returns a new empty Exec.
@@ -58,6 +64,9 @@ func (s *Exec) Clear() {
 	s.Cached = false
 	s.OutputBlobs = nil
 	atomic.StoreInt32(&s.numExecCalls, 0)
+	s.mu.Lock()
+	s.ops = make(map[string]*oppb.Operation)
+	s.mu.Unlock()
 }
 
 // ExecuteCalls returns the total number of Execute calls.
@@ -102,11 +111,23 @@ func (s *Exec) fakeExecution(dg digest.Digest, skipCacheLookup bool) (*oppb.Oper
 	if err != nil {
 		return nil, err
 	}
-	return &oppb.Operation{
-		Name:   "fake",
-		Done:   true,
-		Result: &oppb.Operation_Response{Response: any},
-	}, nil
+	md, err := ptypes.MarshalAny(&repb.ExecuteOperationMetadata{
+		Stage:        repb.ExecutionStage_COMPLETED,
+		ActionDigest: dg.ToProto(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	op := &oppb.Operation{
+		Name:     "fake",
+		Metadata: md,
+		Done:     true,
+		Result:   &oppb.Operation_Response{Response: any},
+	}
+	s.mu.Lock()
+	s.ops[op.Name] = op
+	s.mu.Unlock()
+	return op, nil
 }
 
 // GetCapabilities returns the fake capabilities.
@@ -126,6 +147,12 @@ func (c *Exec) GetCapabilities(ctx context.Context, req *repb.GetCapabilitiesReq
 			SymlinkAbsolutePathStrategy: repb.SymlinkAbsolutePathStrategy_DISALLOWED,
 		},
 	}
+	if c.cas.SplitSplice {
+		splice.SetSupport(res.CacheCapabilities, true, true)
+	}
+	if !c.cas.NoCompressors {
+		res.CacheCapabilities.SupportedCompressors = []repb.Compressor_Value{repb.Compressor_ZSTD}
+	}
 	return res, nil
 }
 
@@ -149,7 +176,13 @@ func (s *Exec) Execute(req *repb.ExecuteRequest, stream regrpc.Execution_Execute
 	return nil
 }
 
-// WaitExecution is not implemented on this fake.
+// WaitExecution returns the operation of a previous execution.
 func (s *Exec) WaitExecution(req *repb.WaitExecutionRequest, stream regrpc.Execution_WaitExecutionServer) (err error) {
-	return status.Error(codes.Unimplemented, "method WaitExecution not implemented by test fake")
+	s.mu.Lock()
+	op, ok := s.ops[req.Name]
+	s.mu.Unlock()
+	if !ok {
+		return status.Error(codes.NotFound, fmt.Sprintf("operation %q not found", req.Name))
+	}
+	return stream.Send(op)
 }
@@ -49,7 +49,7 @@ func NewServer(t testing.TB) (s *Server, err error) {
 	if err != nil {
 		return nil, err
 	}
-	s.srv = grpc.NewServer()
+	s.srv = grpc.NewServer(grpc.UnknownServiceHandler(s.CAS.HandleSplitSplice))
 	bsgrpc.RegisterByteStreamServer(s.srv, s.CAS)
 	regrpc.RegisterContentAddressableStorageServer(s.srv, s.CAS)
 	regrpc.RegisterActionCacheServer(s.srv, s.ActionCache)
@@ -283,6 +283,18 @@ func (f *OutputFile) Apply(ac *repb.ActionResult, s *Server, execRoot string) er
 	return nil
 }
 
+// OutputSymlink is to be added as an output symlink of the fake action.
+type OutputSymlink struct {
+	Path   string
+	Target string
+}
+
+// Apply adds the symlink to the given ActionResult.
+func (l *OutputSymlink) Apply(ac *repb.ActionResult, s *Server, execRoot string) error {
+	ac.OutputSymlinks = append(ac.OutputSymlinks, &repb.OutputSymlink{Path: l.Path, Target: l.Target})
+	return nil
+}
+
 // OutputDir is to be added as an output of the fake action.
 type OutputDir struct {
 	Path string
@@ -0,0 +1,116 @@
+package fakes
+
+import (
+	"bytes"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/splice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// HandleSplitSplice serves SplitBlob and SpliceBlob, which are not part of the generated
+// ContentAddressableStorage service. Register it with grpc.UnknownServiceHandler. Other unknown
+// methods, and all methods if SplitSplice is false, are unimplemented.
+func (f *CAS) HandleSplitSplice(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	if !f.SplitSplice || (method != splice.SplitBlobMethod && method != splice.SpliceBlobMethod) {
+		return status.Errorf(codes.Unimplemented, "unknown method %v", method)
+	}
+	if method == splice.SplitBlobMethod {
+		req := &splice.SplitBlobRequest{}
+		if err := recvSpliceMessage(stream, req); err != nil {
+			return err
+		}
+		resp, err := f.splitBlob(req)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(resp.Message())
+	}
+	req := &splice.SpliceBlobRequest{}
+	if err := recvSpliceMessage(stream, req); err != nil {
+		return err
+	}
+	resp, err := f.spliceBlob(req)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(resp.Message())
+}
+
+// SplitReqs returns the total number of SplitBlob requests to this fake.
+func (f *CAS) SplitReqs() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.splitReqs
+}
+
+// SpliceReqs returns the total number of SpliceBlob requests to this fake.
+func (f *CAS) SpliceReqs() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.spliceReqs
+}
+
+func recvSpliceMessage(stream grpc.ServerStream, req interface {
+	Message() proto.Message
+	FromMessage(proto.Message) error
+}) error {
+	m := req.Message()
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	if err := req.FromMessage(m); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// splitBlob splits the blob with splice.Chunks and stores the chunks.
+func (f *CAS) splitBlob(req *splice.SplitBlobRequest) (*splice.SplitBlobResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.splitReqs++
+	if req.InstanceName != "instance" {
+		return nil, status.Error(codes.InvalidArgument, "test fake expected instance name \"instance\"")
+	}
+	blob, ok := f.blobs[req.BlobDigest]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "digest %s was not found in the fake CAS", req.BlobDigest)
+	}
+	resp := &splice.SplitBlobResponse{}
+	for _, ch := range splice.Chunks(blob) {
+		d := digest.NewFromBlob(ch)
+		f.blobs[d] = ch
+		resp.ChunkDigests = append(resp.ChunkDigests, d)
+	}
+	return resp, nil
+}
+
+// spliceBlob concatenates the chunks into the blob, after verifying its digest.
+func (f *CAS) spliceBlob(req *splice.SpliceBlobRequest) (*splice.SpliceBlobResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.spliceReqs++
+	if req.InstanceName != "instance" {
+		return nil, status.Error(codes.InvalidArgument, "test fake expected instance name \"instance\"")
+	}
+	var buf bytes.Buffer
+	for _, d := range req.ChunkDigests {
+		ch, ok := f.blobs[d]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "chunk %s was not found in the fake CAS", d)
+		}
+		buf.Write(ch)
+	}
+	blob := buf.Bytes()
+	if d := digest.NewFromBlob(blob); d != req.BlobDigest {
+		return nil, status.Errorf(codes.InvalidArgument, "spliced chunks have digest %s, want %s", d, req.BlobDigest)
+	}
+	f.blobs[req.BlobDigest] = blob
+	f.writes[req.BlobDigest]++
+	return &splice.SpliceBlobResponse{BlobDigest: req.BlobDigest}, nil
+}
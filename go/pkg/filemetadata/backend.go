@@ -0,0 +1,369 @@
+package filemetadata
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cache"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// Backend is a storage layer for file Metadata, keyed by absolute path.
+// Backends can be layered using NewLayeredCache.
+type Backend interface {
+	// Load returns the stored Metadata for the given path, and whether it was found.
+	Load(path string) (*Metadata, bool)
+	// Store saves the given Metadata for the given path.
+	Store(path string, md *Metadata) error
+	// Delete removes any Metadata stored for the given path.
+	Delete(path string) error
+}
+
+// MemoryBackend is a Backend that keeps entries in an in-memory map.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	entries map[string]*Metadata
+}
+
+// NewMemoryBackend returns an empty in-memory Backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]*Metadata)}
+}
+
+// Load returns the entry for the given path, if present.
+func (b *MemoryBackend) Load(path string) (*Metadata, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	md, ok := b.entries[path]
+	return md, ok
+}
+
+// Store saves the entry for the given path.
+func (b *MemoryBackend) Store(path string, md *Metadata) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[path] = md
+	return nil
+}
+
+// Delete removes the entry for the given path.
+func (b *MemoryBackend) Delete(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, path)
+	return nil
+}
+
+// SingleFlightBackend is a Backend on top of a cache.SingleFlight.
+type SingleFlightBackend struct {
+	sf *cache.SingleFlight
+}
+
+// NewSingleFlightBackend returns a Backend that stores entries in the given SingleFlight cache.
+// If sf is nil, the global cache shared with NewSingleFlightCache is used.
+func NewSingleFlightBackend(sf *cache.SingleFlight) *SingleFlightBackend {
+	if sf == nil {
+		sf = &globalCache
+	}
+	return &SingleFlightBackend{sf: sf}
+}
+
+// Load returns the entry for the given path, if present.
+func (b *SingleFlightBackend) Load(path string) (*Metadata, bool) {
+	val, err, loaded := b.sf.Load(path)
+	if !loaded || err != nil {
+		return nil, false
+	}
+	md, ok := val.(*Metadata)
+	return md, ok
+}
+
+// Store saves the entry for the given path.
+func (b *SingleFlightBackend) Store(path string, md *Metadata) error {
+	b.sf.Store(path, md)
+	return nil
+}
+
+// Delete removes the entry for the given path.
+func (b *SingleFlightBackend) Delete(path string) error {
+	b.sf.Delete(path)
+	return nil
+}
+
+// persistentEntry is the on-disk representation of a Metadata entry.
+type persistentEntry struct {
+	Hash          string    `json:"hash"`
+	Size          int64     `json:"size"`
+	IsExecutable  bool      `json:"is_executable,omitempty"`
+	IsDirectory   bool      `json:"is_directory,omitempty"`
+	MTime         time.Time `json:"mtime"`
+	SymlinkTarget string    `json:"symlink_target,omitempty"`
+	IsDangling    bool      `json:"is_dangling,omitempty"`
+}
+
+// PersistentBackend is a Backend that survives process restarts by saving entries to a file.
+// Entries are validated against the file's current modification time and size on Load, so
+// stale entries are never returned. Entries with errors are not persisted, except those of
+// dangling symlinks.
+type PersistentBackend struct {
+	file    string
+	mu      sync.RWMutex
+	entries map[string]*persistentEntry
+	dirty   bool
+}
+
+// NewPersistentBackend returns a Backend that loads entries from, and flushes entries to, the
+// given file. A missing file is treated as an empty backend.
+func NewPersistentBackend(file string) (*PersistentBackend, error) {
+	b := &PersistentBackend{file: file, entries: make(map[string]*persistentEntry)}
+	blob, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) == 0 {
+		return b, nil
+	}
+	if err := json.Unmarshal(blob, &b.entries); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Load returns the entry for the given path, if present and still valid.
+func (b *PersistentBackend) Load(path string) (*Metadata, bool) {
+	b.mu.RLock()
+	pe, ok := b.entries[path]
+	b.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if pe.SymlinkTarget != "" {
+		if dest, err := os.Readlink(path); err != nil || dest != pe.SymlinkTarget {
+			return nil, false
+		}
+	}
+	fi, err := os.Stat(path)
+	if pe.IsDangling {
+		if err == nil {
+			return nil, false
+		}
+		return &Metadata{
+			Digest:  digest.Empty,
+			Symlink: &SymlinkMetadata{Target: pe.SymlinkTarget, IsDangling: true},
+			Err:     &FileError{Err: err},
+		}, true
+	}
+	if err != nil || !fi.ModTime().Equal(pe.MTime) || fi.IsDir() != pe.IsDirectory {
+		return nil, false
+	}
+	if !pe.IsDirectory && fi.Size() != pe.Size {
+		return nil, false
+	}
+	md := &Metadata{
+		Digest:       digest.Digest{Hash: pe.Hash, Size: pe.Size},
+		IsExecutable: pe.IsExecutable,
+		IsDirectory:  pe.IsDirectory,
+		MTime:        pe.MTime,
+	}
+	if pe.SymlinkTarget != "" {
+		md.Symlink = &SymlinkMetadata{Target: pe.SymlinkTarget}
+	}
+	return md, true
+}
+
+// Store saves the entry for the given path. Entries with errors are ignored, except those of
+// dangling symlinks.
+func (b *PersistentBackend) Store(path string, md *Metadata) error {
+	if md == nil {
+		return nil
+	}
+	dangling := md.Symlink != nil && md.Symlink.IsDangling
+	if md.Err != nil && !dangling {
+		return nil
+	}
+	pe := &persistentEntry{
+		Hash:         md.Digest.Hash,
+		Size:         md.Digest.Size,
+		IsExecutable: md.IsExecutable,
+		IsDirectory:  md.IsDirectory,
+		MTime:        md.MTime,
+	}
+	if md.Symlink != nil {
+		pe.SymlinkTarget = md.Symlink.Target
+		pe.IsDangling = md.Symlink.IsDangling
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[path] = pe
+	b.dirty = true
+	return nil
+}
+
+// Delete removes the entry for the given path.
+func (b *PersistentBackend) Delete(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.entries[path]; ok {
+		delete(b.entries, path)
+		b.dirty = true
+	}
+	return nil
+}
+
+// Flush writes all entries to the backing file, if anything changed since the last Flush.
+func (b *PersistentBackend) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.dirty {
+		return nil
+	}
+	blob, err := json.Marshal(b.entries)
+	if err != nil {
+		return err
+	}
+	tmp := b.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.file); err != nil {
+		return err
+	}
+	b.dirty = false
+	return nil
+}
+
+// XattrBackend is a read-only Backend that reads file digests from an extended attribute.
+type XattrBackend struct {
+	name string
+}
+
+// NewXattrBackend returns a Backend that reads digests from the given extended attribute.
+func NewXattrBackend(name string) *XattrBackend {
+	return &XattrBackend{name: name}
+}
+
+// Load returns the metadata for the given path if the extended attribute is set on it. Symlinks
+// are never loaded, so that their metadata is computed with their target.
+func (b *XattrBackend) Load(path string) (*Metadata, bool) {
+	if !XattrAccess.isSupported() {
+		return nil, false
+	}
+	fi, err := os.Lstat(path)
+	if err != nil || fi.IsDir() || fi.Mode()&os.ModeSymlink != 0 {
+		return nil, false
+	}
+	val, err := XattrAccess.getXAttr(path, b.name)
+	if err != nil || len(val) == 0 {
+		return nil, false
+	}
+	return &Metadata{
+		Digest:       digest.Digest{Hash: string(val), Size: fi.Size()},
+		IsExecutable: (fi.Mode() & 0100) != 0,
+		MTime:        fi.ModTime(),
+	}, true
+}
+
+// Store is a noop: the extended attributes are owned by whoever produced the file.
+func (b *XattrBackend) Store(string, *Metadata) error {
+	return nil
+}
+
+// Delete is a noop for the same reason as Store.
+func (b *XattrBackend) Delete(string) error {
+	return nil
+}
+
+// layeredCache is a Cache composed of a stack of Backends.
+type layeredCache struct {
+	layers      []Backend
+	cacheHits   uint64
+	cacheMisses uint64
+}
+
+// NewLayeredCache returns a Cache that consults the given backends in order, e.g.
+// memory over persistent over xattr. A hit in a lower layer is written back to the layers
+// above it. If no layer has an entry, the metadata is computed and stored in every layer.
+func NewLayeredCache(layers ...Backend) Cache {
+	return &layeredCache{layers: layers}
+}
+
+// Get retrieves the metadata of the file with the given filename, whether from one of the
+// layers or by computing the digest.
+func (c *layeredCache) Get(filename string) *Metadata {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return &Metadata{Err: err}
+	}
+	for i, l := range c.layers {
+		md, ok := l.Load(abs)
+		if !ok {
+			continue
+		}
+		for _, above := range c.layers[:i] {
+			if err := above.Store(abs, md); err != nil {
+				return &Metadata{Err: err}
+			}
+		}
+		atomic.AddUint64(&c.cacheHits, 1)
+		return md
+	}
+	atomic.AddUint64(&c.cacheMisses, 1)
+	md := Compute(abs)
+	if err := c.Update(abs, md); err != nil {
+		return &Metadata{Err: err}
+	}
+	return md
+}
+
+// Delete deletes an entry from all layers.
+func (c *layeredCache) Delete(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for _, l := range c.layers {
+		if err := l.Delete(abs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update updates the entry for the filename in all layers.
+func (c *layeredCache) Update(filename string, cacheEntry *Metadata) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for _, l := range c.layers {
+		if err := l.Store(abs, cacheEntry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCacheHits returns the number of cache hits across all layers.
+func (c *layeredCache) GetCacheHits() uint64 {
+	return atomic.LoadUint64(&c.cacheHits)
+}
+
+// GetCacheMisses returns the number of times no layer had an entry.
+func (c *layeredCache) GetCacheMisses() uint64 {
+	return atomic.LoadUint64(&c.cacheMisses)
+}
+
+// Warm populates every layer with the metadata of the tree under root in the background.
+func (c *layeredCache) Warm(ctx context.Context, root string, excludes []*regexp.Regexp) *Warming {
+	return startWarming(ctx, c, root, excludes)
+}
@@ -0,0 +1,214 @@
+package filemetadata
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/testutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLayeredCacheBackfillsUpperLayers(t *testing.T) {
+	filename, err := testutil.CreateFile(t, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create tmp file for testing digests: %v", err)
+	}
+	if err = ioutil.WriteFile(filename, contents, os.ModeTemporary); err != nil {
+		t.Fatalf("Failed to write to tmp file for testing digests: %v", err)
+	}
+	mem := NewMemoryBackend()
+	lower := NewMemoryBackend()
+	want := &Metadata{Digest: wantDg}
+	if err := lower.Store(filename, want); err != nil {
+		t.Fatalf("Store(%v) failed: %v", filename, err)
+	}
+	c := NewLayeredCache(mem, lower)
+	got := c.Get(filename)
+	if diff := cmp.Diff(want, got, ignoreMtime); diff != "" {
+		t.Errorf("Get(%v) returned diff. (-want +got)\n%s", filename, diff)
+	}
+	if md, ok := mem.Load(filename); !ok || md != want {
+		t.Errorf("upper layer Load(%v) = %v, %v, want %v, true", filename, md, ok, want)
+	}
+	if c.GetCacheHits() != 1 || c.GetCacheMisses() != 0 {
+		t.Errorf("Cache has wrong hits/misses, want 1/0, got %v/%v", c.GetCacheHits(), c.GetCacheMisses())
+	}
+}
+
+func TestLayeredCacheComputesOnMiss(t *testing.T) {
+	filename, err := testutil.CreateFile(t, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create tmp file for testing digests: %v", err)
+	}
+	if err = ioutil.WriteFile(filename, contents, os.ModeTemporary); err != nil {
+		t.Fatalf("Failed to write to tmp file for testing digests: %v", err)
+	}
+	mem := NewMemoryBackend()
+	lower := NewMemoryBackend()
+	c := NewLayeredCache(mem, lower)
+	for i := 0; i < 2; i++ {
+		got := c.Get(filename)
+		if diff := cmp.Diff(&Metadata{Digest: wantDg}, got, ignoreMtime); diff != "" {
+			t.Errorf("Get(%v) returned diff. (-want +got)\n%s", filename, diff)
+		}
+	}
+	for _, b := range []*MemoryBackend{mem, lower} {
+		if _, ok := b.Load(filename); !ok {
+			t.Errorf("Load(%v) after Get returned false, want true", filename)
+		}
+	}
+	if c.GetCacheHits() != 1 || c.GetCacheMisses() != 1 {
+		t.Errorf("Cache has wrong hits/misses, want 1/1, got %v/%v", c.GetCacheHits(), c.GetCacheMisses())
+	}
+	if err := c.Delete(filename); err != nil {
+		t.Fatalf("Delete(%v) failed: %v", filename, err)
+	}
+	for _, b := range []*MemoryBackend{mem, lower} {
+		if _, ok := b.Load(filename); ok {
+			t.Errorf("Load(%v) after Delete returned true, want false", filename)
+		}
+	}
+}
+
+func TestPersistentBackendRoundTrip(t *testing.T) {
+	filename, err := testutil.CreateFile(t, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create tmp file for testing digests: %v", err)
+	}
+	if err = ioutil.WriteFile(filename, contents, os.ModeTemporary); err != nil {
+		t.Fatalf("Failed to write to tmp file for testing digests: %v", err)
+	}
+	dbFile := filepath.Join(t.TempDir(), "fmcache.json")
+	b, err := NewPersistentBackend(dbFile)
+	if err != nil {
+		t.Fatalf("NewPersistentBackend(%v) failed: %v", dbFile, err)
+	}
+	want := Compute(filename)
+	if err := b.Store(filename, want); err != nil {
+		t.Fatalf("Store(%v) failed: %v", filename, err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	b, err = NewPersistentBackend(dbFile)
+	if err != nil {
+		t.Fatalf("NewPersistentBackend(%v) failed: %v", dbFile, err)
+	}
+	got, ok := b.Load(filename)
+	if !ok {
+		t.Fatalf("Load(%v) after reopening returned false, want true", filename)
+	}
+	if diff := cmp.Diff(want, got, ignoreMtime); diff != "" {
+		t.Errorf("Load(%v) returned diff. (-want +got)\n%s", filename, diff)
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("changed contents"), os.ModeTemporary); err != nil {
+		t.Fatalf("Failed to write to tmp file for testing digests: %v", err)
+	}
+	if _, ok := b.Load(filename); ok {
+		t.Errorf("Load(%v) after modifying the file returned true, want false", filename)
+	}
+}
+
+func TestPersistentBackendSymlinks(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "target")
+	if err := ioutil.WriteFile(target, contents, 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", target, err)
+	}
+	link := filepath.Join(root, "link")
+	if err := createSymlinkToTarget(t, link, target); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", link, err)
+	}
+	dangling := filepath.Join(root, "dangling")
+	if err := createSymlinkToTarget(t, dangling, filepath.Join(root, "missing")); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", dangling, err)
+	}
+	dbFile := filepath.Join(root, "fmcache.json")
+	b, err := NewPersistentBackend(dbFile)
+	if err != nil {
+		t.Fatalf("NewPersistentBackend(%v) failed: %v", dbFile, err)
+	}
+	want := map[string]*Metadata{link: Compute(link), dangling: Compute(dangling)}
+	if !want[dangling].Symlink.IsDangling {
+		t.Fatalf("Compute(%v) = %+v, want a dangling symlink", dangling, want[dangling])
+	}
+	for path, md := range want {
+		if err := b.Store(path, md); err != nil {
+			t.Fatalf("Store(%v) failed: %v", path, err)
+		}
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	b, err = NewPersistentBackend(dbFile)
+	if err != nil {
+		t.Fatalf("NewPersistentBackend(%v) failed: %v", dbFile, err)
+	}
+	for path, md := range want {
+		got, ok := b.Load(path)
+		if !ok {
+			t.Fatalf("Load(%v) after reopening returned false, want true", path)
+		}
+		if diff := cmp.Diff(md, got, ignoreMtime); diff != "" {
+			t.Errorf("Load(%v) returned diff. (-want +got)\n%s", path, diff)
+		}
+	}
+
+	// Repoint the link, and resolve the dangling one.
+	if err := os.Remove(link); err != nil {
+		t.Fatalf("Remove(%v) failed: %v", link, err)
+	}
+	if err := createSymlinkToTarget(t, link, dbFile); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", link, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "missing"), contents, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	for path := range want {
+		if _, ok := b.Load(path); ok {
+			t.Errorf("Load(%v) after changing the symlink returned true, want false", path)
+		}
+	}
+}
+
+func TestXattrBackendLoad(t *testing.T) {
+	XattrAccess = xattributeAccessorMock{}
+	defer func() { XattrAccess = xattributeAccessor{} }()
+	filename, err := testutil.CreateFile(t, true, "bla")
+	if err != nil {
+		t.Fatalf("Failed to create tmp file for testing digests: %v", err)
+	}
+	fakeHash := "12345"
+	getXAttrMock = func(path string, name string) ([]byte, error) {
+		if name != "user.digest" {
+			return nil, errors.New("unknown attribute")
+		}
+		return []byte(fakeHash), nil
+	}
+	b := NewXattrBackend("user.digest")
+	got, ok := b.Load(filename)
+	if !ok {
+		t.Fatalf("Load(%v) returned false, want true", filename)
+	}
+	want := &Metadata{Digest: digest.Digest{Hash: fakeHash, Size: 3}, IsExecutable: true}
+	if diff := cmp.Diff(want, got, ignoreMtime); diff != "" {
+		t.Errorf("Load(%v) returned diff. (-want +got)\n%s", filename, diff)
+	}
+	if _, ok := NewXattrBackend("user.other").Load(filename); ok {
+		t.Errorf("Load(%v) with a missing attribute returned true, want false", filename)
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := createSymlinkToTarget(t, link, filename); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", link, err)
+	}
+	if _, ok := b.Load(link); ok {
+		t.Errorf("Load(%v) of a symlink returned true, want false", link)
+	}
+}
@@ -1,7 +1,9 @@
 package filemetadata
 
 import (
+	"context"
 	"path/filepath"
+	"regexp"
 	"sync/atomic"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cache"
@@ -72,6 +74,11 @@ func (c *fmCache) GetCacheMisses() uint64 {
 	return atomic.LoadUint64(&c.cacheMisses)
 }
 
+// Warm populates the cache with the metadata of the tree under root in the background.
+func (c *fmCache) Warm(ctx context.Context, root string, excludes []*regexp.Regexp) *Warming {
+	return startWarming(ctx, c, root, excludes)
+}
+
 func (c *fmCache) loadMetadata(filename string) (*Metadata, bool, error) {
 	cacheHit := true
 	val, err := c.Backend.LoadOrStore(filename, func() (interface{}, error) {
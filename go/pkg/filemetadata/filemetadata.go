@@ -2,8 +2,10 @@
 package filemetadata
 
 import (
+	"context"
 	"errors"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
@@ -14,6 +16,8 @@ import (
 type SymlinkMetadata struct {
 	Target     string
 	IsDangling bool
+	// IsJunction is whether the symlink is actually an NTFS directory junction.
+	IsJunction bool
 }
 
 // Metadata contains details for a particular file.
@@ -72,7 +76,7 @@ func Compute(filename string) *Metadata {
 	md := &Metadata{Digest: digest.Empty}
 	file, err := os.Stat(filename)
 	if isSym, _ := isSymlink(filename); isSym {
-		md.Symlink = &SymlinkMetadata{}
+		md.Symlink = &SymlinkMetadata{IsJunction: isJunction(filename)}
 		dest, rlErr := os.Readlink(filename)
 		if rlErr != nil {
 			md.Err = &FileError{Err: rlErr}
@@ -130,6 +134,10 @@ type Cache interface {
 	Update(path string, cacheEntry *Metadata) error
 	GetCacheHits() uint64
 	GetCacheMisses() uint64
+	// Warm walks the directory tree under root in the background and populates the cache ahead
+	// of time, skipping the paths whose absolute form matches any of the excludes. It returns
+	// immediately.
+	Warm(ctx context.Context, root string, excludes []*regexp.Regexp) *Warming
 }
 
 type noopCache struct{}
@@ -161,6 +169,13 @@ func (c *noopCache) GetCacheMisses() uint64 {
 	return 0
 }
 
+// Warm returns a complete Warming. It is a noop for Noop cache.
+func (c *noopCache) Warm(context.Context, string, []*regexp.Regexp) *Warming {
+	w := &Warming{cancel: func() {}, done: make(chan struct{})}
+	close(w.done)
+	return w
+}
+
 // NewNoopCache returns a cache that doesn't cache (evaluates on every Get).
 func NewNoopCache() Cache {
 	return &noopCache{}
@@ -0,0 +1,9 @@
+// +build !windows
+
+package filemetadata
+
+// isJunction returns whether the file at path is an NTFS directory junction. Junctions only exist
+// on Windows.
+func isJunction(string) bool {
+	return false
+}
@@ -0,0 +1,24 @@
+// +build windows
+
+package filemetadata
+
+import "syscall"
+
+// ioReparseTagMountPoint is the reparse tag of NTFS junctions, which syscall does not export.
+const ioReparseTagMountPoint = 0xA0000003
+
+// isJunction returns whether the file at path is an NTFS directory junction rather than a symlink.
+// The os package reports both as symlinks.
+func isJunction(path string) bool {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	var fd syscall.Win32finddata
+	h, err := syscall.FindFirstFile(p, &fd)
+	if err != nil {
+		return false
+	}
+	syscall.FindClose(h)
+	return fd.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 && fd.Reserved0 == ioReparseTagMountPoint
+}
@@ -0,0 +1,137 @@
+package filemetadata
+
+import (
+	"container/list"
+	"sync"
+)
+
+// entryOverhead approximates the fixed memory cost of a cache entry, in bytes.
+const entryOverhead = 128
+
+// LRUOptions bounds the size of an LRUBackend. Zero values mean no bound.
+type LRUOptions struct {
+	// MaxEntries is the maximum number of entries kept.
+	MaxEntries int
+	// MaxBytes is the maximum approximate memory used by the entries.
+	MaxBytes int64
+	// OnEvict, if set, is called for every entry evicted to make room for new ones.
+	// It is called without any lock held, so it may use the backend.
+	OnEvict func(path string, md *Metadata)
+}
+
+type lruEntry struct {
+	path string
+	md   *Metadata
+	size int64
+}
+
+// LRUBackend is an in-memory Backend bounded in entry count and approximate bytes, that evicts
+// the least recently used entries first.
+type LRUBackend struct {
+	opts LRUOptions
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+// NewLRUBackend returns an empty LRUBackend with the given bounds.
+func NewLRUBackend(opts LRUOptions) *LRUBackend {
+	return &LRUBackend{opts: opts, ll: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// NewLRUCache returns a bounded in-memory Cache. Use NewLRUBackend with NewLayeredCache to put
+// the bounded cache in front of other backends.
+func NewLRUCache(opts LRUOptions) Cache {
+	return NewLayeredCache(NewLRUBackend(opts))
+}
+
+// Load returns the entry for the given path, if present, marking it as recently used.
+func (b *LRUBackend) Load(path string) (*Metadata, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	el, ok := b.entries[path]
+	if !ok {
+		return nil, false
+	}
+	b.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).md, true
+}
+
+// Store saves the entry for the given path, evicting old entries if the bounds are exceeded.
+func (b *LRUBackend) Store(path string, md *Metadata) error {
+	e := &lruEntry{path: path, md: md, size: approxSize(path, md)}
+	b.mu.Lock()
+	if el, ok := b.entries[path]; ok {
+		b.bytes -= el.Value.(*lruEntry).size
+		el.Value = e
+		b.ll.MoveToFront(el)
+	} else {
+		b.entries[path] = b.ll.PushFront(e)
+	}
+	b.bytes += e.size
+	var evicted []*lruEntry
+	for b.ll.Len() > 1 && b.overLimit() {
+		el := b.ll.Back()
+		old := el.Value.(*lruEntry)
+		b.remove(el)
+		evicted = append(evicted, old)
+	}
+	b.mu.Unlock()
+	if b.opts.OnEvict != nil {
+		for _, old := range evicted {
+			b.opts.OnEvict(old.path, old.md)
+		}
+	}
+	return nil
+}
+
+// Delete removes the entry for the given path. The eviction callback is not called.
+func (b *LRUBackend) Delete(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if el, ok := b.entries[path]; ok {
+		b.remove(el)
+	}
+	return nil
+}
+
+// Len returns the number of entries currently stored.
+func (b *LRUBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ll.Len()
+}
+
+// Bytes returns the approximate memory used by the entries currently stored.
+func (b *LRUBackend) Bytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bytes
+}
+
+func (b *LRUBackend) overLimit() bool {
+	return (b.opts.MaxEntries > 0 && b.ll.Len() > b.opts.MaxEntries) ||
+		(b.opts.MaxBytes > 0 && b.bytes > b.opts.MaxBytes)
+}
+
+// remove must be called with mu held.
+func (b *LRUBackend) remove(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	b.ll.Remove(el)
+	delete(b.entries, e.path)
+	b.bytes -= e.size
+}
+
+func approxSize(path string, md *Metadata) int64 {
+	size := int64(entryOverhead + len(path))
+	if md == nil {
+		return size
+	}
+	size += int64(len(md.Digest.Hash))
+	if md.Symlink != nil {
+		size += int64(len(md.Symlink.Target))
+	}
+	return size
+}
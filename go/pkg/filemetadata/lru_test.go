@@ -0,0 +1,57 @@
+package filemetadata
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLRUBackendMaxEntries(t *testing.T) {
+	var evicted []string
+	b := NewLRUBackend(LRUOptions{
+		MaxEntries: 2,
+		OnEvict:    func(path string, md *Metadata) { evicted = append(evicted, path) },
+	})
+	b.Store("/a", &Metadata{Digest: wantDg})
+	b.Store("/b", &Metadata{Digest: wantDg})
+	// Touch /a so that /b becomes the least recently used entry.
+	if _, ok := b.Load("/a"); !ok {
+		t.Fatalf("Load(/a) returned false, want true")
+	}
+	b.Store("/c", &Metadata{Digest: wantDg})
+	if diff := cmp.Diff([]string{"/b"}, evicted); diff != "" {
+		t.Errorf("Evicted entries returned diff. (-want +got)\n%s", diff)
+	}
+	for path, want := range map[string]bool{"/a": true, "/b": false, "/c": true} {
+		if _, ok := b.Load(path); ok != want {
+			t.Errorf("Load(%v) returned %v, want %v", path, ok, want)
+		}
+	}
+	if b.Len() != 2 {
+		t.Errorf("Len() = %v, want 2", b.Len())
+	}
+}
+
+func TestLRUBackendMaxBytes(t *testing.T) {
+	md := &Metadata{Digest: wantDg}
+	one := approxSize("/a", md)
+	b := NewLRUBackend(LRUOptions{MaxBytes: 2 * one})
+	b.Store("/a", md)
+	b.Store("/b", md)
+	b.Store("/c", md)
+	if b.Len() != 2 {
+		t.Errorf("Len() = %v, want 2", b.Len())
+	}
+	if b.Bytes() != 2*one {
+		t.Errorf("Bytes() = %v, want %v", b.Bytes(), 2*one)
+	}
+	if _, ok := b.Load("/a"); ok {
+		t.Errorf("Load(/a) returned true, want the oldest entry evicted")
+	}
+	if err := b.Delete("/b"); err != nil {
+		t.Fatalf("Delete(/b) failed: %v", err)
+	}
+	if b.Bytes() != one {
+		t.Errorf("Bytes() after Delete = %v, want %v", b.Bytes(), one)
+	}
+}
@@ -0,0 +1,171 @@
+package filemetadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// maxSymlinkHops is the maximum number of links followed before a chain is considered cyclic.
+// It matches the MAXSYMLINKS limit on Linux.
+const maxSymlinkHops = 40
+
+// SymlinkChain is the resolution chain of a symlink.
+type SymlinkChain struct {
+	// Links are the absolute paths of every symlink in the chain, starting with the resolved path.
+	Links []string
+	// Target is the absolute path the chain finally resolves to.
+	Target string
+	// IsDangling is true if Target does not exist.
+	IsDangling bool
+}
+
+// SymlinkCache caches symlink resolution chains. Invalidating any link in a chain invalidates
+// every cached chain that goes through it.
+type SymlinkCache struct {
+	mu     sync.Mutex
+	chains map[string]*SymlinkChain
+	// users maps a link to the set of cached chains (keyed by their first link) that contain it.
+	users       map[string]map[string]bool
+	cacheHits   uint64
+	cacheMisses uint64
+}
+
+// NewSymlinkCache returns an empty SymlinkCache.
+func NewSymlinkCache() *SymlinkCache {
+	return &SymlinkCache{
+		chains: make(map[string]*SymlinkChain),
+		users:  make(map[string]map[string]bool),
+	}
+}
+
+// Resolve returns the resolution chain of the given path. If the path is not a symlink, the
+// returned chain has no Links and its Target is the absolute path itself.
+func (c *SymlinkCache) Resolve(path string) (*SymlinkChain, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	ch, ok := c.chains[abs]
+	c.mu.Unlock()
+	if ok {
+		atomic.AddUint64(&c.cacheHits, 1)
+		return ch, nil
+	}
+	atomic.AddUint64(&c.cacheMisses, 1)
+	ch, err = resolveChain(abs)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chains[abs] = ch
+	for _, l := range ch.Links {
+		if c.users[l] == nil {
+			c.users[l] = make(map[string]bool)
+		}
+		c.users[l][abs] = true
+	}
+	return ch, nil
+}
+
+// Invalidate drops the cached chain of the given path, as well as any chain going through it.
+func (c *SymlinkCache) Invalidate(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drop(abs)
+	for start := range c.users[abs] {
+		c.drop(start)
+	}
+	return nil
+}
+
+// drop removes the chain starting at the given path. Must be called with mu held.
+func (c *SymlinkCache) drop(start string) {
+	ch, ok := c.chains[start]
+	if !ok {
+		return
+	}
+	delete(c.chains, start)
+	for _, l := range ch.Links {
+		delete(c.users[l], start)
+		if len(c.users[l]) == 0 {
+			delete(c.users, l)
+		}
+	}
+}
+
+// GetCacheHits returns the number of cache hits.
+func (c *SymlinkCache) GetCacheHits() uint64 {
+	return atomic.LoadUint64(&c.cacheHits)
+}
+
+// GetCacheMisses returns the number of cache misses.
+func (c *SymlinkCache) GetCacheMisses() uint64 {
+	return atomic.LoadUint64(&c.cacheMisses)
+}
+
+func resolveChain(abs string) (*SymlinkChain, error) {
+	ch := &SymlinkChain{}
+	cur := abs
+	for i := 0; i <= maxSymlinkHops; i++ {
+		fi, err := os.Lstat(cur)
+		if os.IsNotExist(err) && len(ch.Links) > 0 {
+			ch.Target = cur
+			ch.IsDangling = true
+			return ch, nil
+		}
+		if err != nil {
+			return nil, &FileError{IsNotFound: os.IsNotExist(err), Err: err}
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			ch.Target = cur
+			return ch, nil
+		}
+		ch.Links = append(ch.Links, cur)
+		dest, err := os.Readlink(cur)
+		if err != nil {
+			return nil, &FileError{Err: err}
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(cur), dest)
+		}
+		cur = filepath.Clean(dest)
+	}
+	return nil, &FileError{Err: fmt.Errorf("too many levels of symbolic links resolving %v", abs)}
+}
+
+// symlinkAwareCache is a Cache that keeps a SymlinkCache consistent with its own invalidations.
+type symlinkAwareCache struct {
+	Cache
+	symlinks *SymlinkCache
+}
+
+// NewSymlinkAwareCache returns a Cache backed by c, where any Delete or Update of a path also
+// invalidates the symlink chains in s that go through that path.
+func NewSymlinkAwareCache(c Cache, s *SymlinkCache) Cache {
+	return &symlinkAwareCache{Cache: c, symlinks: s}
+}
+
+// Delete deletes an entry from cache and invalidates any symlink chain going through it.
+func (c *symlinkAwareCache) Delete(filename string) error {
+	if err := c.symlinks.Invalidate(filename); err != nil {
+		return err
+	}
+	return c.Cache.Delete(filename)
+}
+
+// Update updates the cache entry and invalidates any symlink chain going through it.
+func (c *symlinkAwareCache) Update(filename string, cacheEntry *Metadata) error {
+	if err := c.symlinks.Invalidate(filename); err != nil {
+		return err
+	}
+	return c.Cache.Update(filename, cacheEntry)
+}
@@ -0,0 +1,91 @@
+package filemetadata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSymlinkCacheResolveChain(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "target")
+	if err := ioutil.WriteFile(target, contents, 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", target, err)
+	}
+	mid := filepath.Join(root, "mid")
+	top := filepath.Join(root, "top")
+	if err := createSymlinkToTarget(t, mid, "target"); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", mid, err)
+	}
+	if err := createSymlinkToTarget(t, top, mid); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", top, err)
+	}
+	c := NewSymlinkCache()
+	want := &SymlinkChain{Links: []string{top, mid}, Target: target}
+	for i := 0; i < 2; i++ {
+		got, err := c.Resolve(top)
+		if err != nil {
+			t.Fatalf("Resolve(%v) failed: %v", top, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Resolve(%v) returned diff. (-want +got)\n%s", top, diff)
+		}
+	}
+	if c.GetCacheHits() != 1 || c.GetCacheMisses() != 1 {
+		t.Errorf("Cache has wrong hits/misses, want 1/1, got %v/%v", c.GetCacheHits(), c.GetCacheMisses())
+	}
+
+	// Repoint the intermediate link, and invalidate it through a symlink-aware cache.
+	other := filepath.Join(root, "other")
+	if err := os.Remove(mid); err != nil {
+		t.Fatalf("Remove(%v) failed: %v", mid, err)
+	}
+	if err := createSymlinkToTarget(t, mid, other); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", mid, err)
+	}
+	fmc := NewSymlinkAwareCache(NewNoopCache(), c)
+	if err := fmc.Delete(mid); err != nil {
+		t.Fatalf("Delete(%v) failed: %v", mid, err)
+	}
+	got, err := c.Resolve(top)
+	if err != nil {
+		t.Fatalf("Resolve(%v) failed: %v", top, err)
+	}
+	want = &SymlinkChain{Links: []string{top, mid}, Target: other, IsDangling: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Resolve(%v) after invalidation returned diff. (-want +got)\n%s", top, diff)
+	}
+}
+
+func TestSymlinkCacheResolveNonSymlink(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "target")
+	if err := ioutil.WriteFile(target, contents, 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", target, err)
+	}
+	got, err := NewSymlinkCache().Resolve(target)
+	if err != nil {
+		t.Fatalf("Resolve(%v) failed: %v", target, err)
+	}
+	if diff := cmp.Diff(&SymlinkChain{Target: target}, got); diff != "" {
+		t.Errorf("Resolve(%v) returned diff. (-want +got)\n%s", target, diff)
+	}
+}
+
+func TestSymlinkCacheResolveCycle(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a")
+	b := filepath.Join(root, "b")
+	if err := createSymlinkToTarget(t, a, b); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", a, err)
+	}
+	if err := createSymlinkToTarget(t, b, a); err != nil {
+		t.Fatalf("Failed to create symlink %v: %v", b, err)
+	}
+	if _, err := NewSymlinkCache().Resolve(a); err == nil {
+		t.Errorf("Resolve(%v) on a symlink cycle succeeded, want error", a)
+	}
+}
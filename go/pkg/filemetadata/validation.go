@@ -0,0 +1,130 @@
+package filemetadata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// timeNow is overridden in tests.
+var timeNow = time.Now
+
+// ValidationPolicy configures when cached metadata is checked against the file system.
+// The zero value trusts cached entries forever, which is the behavior of the plain caches.
+type ValidationPolicy struct {
+	// NegativeTTL is how long an entry for a missing file is trusted. Zero means forever.
+	NegativeTTL time.Duration
+	// RevalidateAfter is how long an entry is trusted before the file's mtime and size are
+	// compared against it again. Zero disables periodic revalidation.
+	RevalidateAfter time.Duration
+	// Strict re-stats the file on every Get, before trusting a cached digest.
+	Strict bool
+}
+
+// validatingCache is a Cache that revalidates the entries of an underlying Cache.
+type validatingCache struct {
+	Cache
+	policy ValidationPolicy
+
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+// NewValidatingCache returns a Cache that serves entries from c, but recomputes them according
+// to the given policy. This is useful in environments where files change outside of the build
+// system's knowledge, so that Delete and Update are not reliably called.
+func NewValidatingCache(c Cache, p ValidationPolicy) Cache {
+	return &validatingCache{Cache: c, policy: p, checked: make(map[string]time.Time)}
+}
+
+// Get retrieves the metadata of the file with the given filename, recomputing it if the cached
+// entry is stale under the configured policy.
+func (c *validatingCache) Get(filename string) *Metadata {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return &Metadata{Err: err}
+	}
+	md := c.Cache.Get(abs)
+	now := timeNow()
+	c.mu.Lock()
+	last, ok := c.checked[abs]
+	if !ok {
+		c.checked[abs] = now
+	}
+	c.mu.Unlock()
+	if (!ok && !c.policy.Strict) || (ok && !c.needsCheck(md, now.Sub(last))) {
+		return md
+	}
+	if isStale(abs, md) {
+		if err := c.Cache.Delete(abs); err != nil {
+			return &Metadata{Err: err}
+		}
+		md = c.Cache.Get(abs)
+	}
+	c.mu.Lock()
+	c.checked[abs] = now
+	c.mu.Unlock()
+	return md
+}
+
+// Warm populates the cache with the metadata of the tree under root in the background, validating
+// the entries already cached.
+func (c *validatingCache) Warm(ctx context.Context, root string, excludes []*regexp.Regexp) *Warming {
+	return startWarming(ctx, c, root, excludes)
+}
+
+// Delete deletes an entry from the cache.
+func (c *validatingCache) Delete(filename string) error {
+	if abs, err := filepath.Abs(filename); err == nil {
+		c.mu.Lock()
+		delete(c.checked, abs)
+		c.mu.Unlock()
+	}
+	return c.Cache.Delete(filename)
+}
+
+// Update updates the cache entry for the filename with the given value.
+func (c *validatingCache) Update(filename string, cacheEntry *Metadata) error {
+	if abs, err := filepath.Abs(filename); err == nil {
+		c.mu.Lock()
+		c.checked[abs] = timeNow()
+		c.mu.Unlock()
+	}
+	return c.Cache.Update(filename, cacheEntry)
+}
+
+func (c *validatingCache) needsCheck(md *Metadata, age time.Duration) bool {
+	if c.policy.Strict {
+		return true
+	}
+	if isNotFound(md) {
+		return c.policy.NegativeTTL > 0 && age >= c.policy.NegativeTTL
+	}
+	return c.policy.RevalidateAfter > 0 && age >= c.policy.RevalidateAfter
+}
+
+// isStale returns whether md no longer describes the file at abs, judging by mtime and size.
+func isStale(abs string, md *Metadata) bool {
+	fi, err := os.Stat(abs)
+	if err != nil {
+		// Keep cached errors for files that still cannot be stat-ed, since recomputing would
+		// yield the same result. Anything else that disappeared is stale.
+		return md.Err == nil || isNotFound(md) != os.IsNotExist(err)
+	}
+	if md.Err != nil {
+		return true
+	}
+	if !fi.ModTime().Equal(md.MTime) || fi.IsDir() != md.IsDirectory {
+		return true
+	}
+	return !fi.IsDir() && fi.Size() != md.Digest.Size
+}
+
+func isNotFound(md *Metadata) bool {
+	var fe *FileError
+	return md.Err != nil && errors.As(md.Err, &fe) && fe.IsNotFound
+}
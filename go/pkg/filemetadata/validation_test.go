@@ -0,0 +1,94 @@
+package filemetadata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+func TestValidatingCache(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name    string
+		policy  ValidationPolicy
+		elapsed time.Duration
+		missing bool
+		wantNew bool
+	}{
+		{
+			name:    "no policy trusts modified entry",
+			elapsed: time.Hour,
+		},
+		{
+			name:    "strict sees modified entry",
+			policy:  ValidationPolicy{Strict: true},
+			wantNew: true,
+		},
+		{
+			name:    "revalidation before period",
+			policy:  ValidationPolicy{RevalidateAfter: time.Minute},
+			elapsed: time.Second,
+		},
+		{
+			name:    "revalidation after period",
+			policy:  ValidationPolicy{RevalidateAfter: time.Minute},
+			elapsed: 2 * time.Minute,
+			wantNew: true,
+		},
+		{
+			name:    "negative entry before TTL",
+			policy:  ValidationPolicy{NegativeTTL: time.Minute},
+			elapsed: time.Second,
+			missing: true,
+		},
+		{
+			name:    "negative entry after TTL",
+			policy:  ValidationPolicy{NegativeTTL: time.Minute},
+			elapsed: 2 * time.Minute,
+			missing: true,
+			wantNew: true,
+		},
+		{
+			name:    "negative TTL does not apply to existing files",
+			policy:  ValidationPolicy{NegativeTTL: time.Minute},
+			elapsed: 2 * time.Minute,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			now := start
+			timeNow = func() time.Time { return now }
+			defer func() { timeNow = time.Now }()
+
+			filename := filepath.Join(t.TempDir(), "file")
+			if !tc.missing {
+				if err := ioutil.WriteFile(filename, contents, 0644); err != nil {
+					t.Fatalf("WriteFile(%v) failed: %v", filename, err)
+				}
+			}
+			c := NewValidatingCache(NewLayeredCache(NewMemoryBackend()), tc.policy)
+			c.Get(filename)
+
+			newContents := []byte("new contents")
+			if err := ioutil.WriteFile(filename, newContents, 0644); err != nil {
+				t.Fatalf("WriteFile(%v) failed: %v", filename, err)
+			}
+			// Make sure the mtime changes even on file systems with coarse timestamps.
+			mtime := start.Add(time.Hour)
+			if err := os.Chtimes(filename, mtime, mtime); err != nil {
+				t.Fatalf("Chtimes(%v) failed: %v", filename, err)
+			}
+			now = start.Add(tc.elapsed)
+			got := c.Get(filename)
+			gotNew := got.Err == nil && got.Digest == digest.NewFromBlob(newContents)
+			if gotNew != tc.wantNew {
+				t.Errorf("Get(%v) returned %+v, want recomputed entry: %v", filename, got, tc.wantNew)
+			}
+		})
+	}
+}
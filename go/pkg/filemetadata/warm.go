@@ -0,0 +1,90 @@
+package filemetadata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Warming is a walk of a directory tree populating a cache in the background, as started by
+// the Warm method of the cache.
+type Warming struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Wait blocks until the walk is complete or canceled, and returns its error, if any.
+func (w *Warming) Wait() error {
+	<-w.done
+	return w.err
+}
+
+// Cancel stops the walk. The entries already computed stay in the cache.
+func (w *Warming) Cancel() {
+	w.cancel()
+}
+
+// startWarming walks the directory tree under root in the background and populates c with the
+// metadata of every file and directory found, so that later lookups of those paths are cache
+// hits. Paths whose absolute form matches any of the excludes are skipped; excluded directories
+// are not descended. Files are hashed concurrently. Errors computing individual files are recorded
+// in their cache entries and do not fail the walk.
+func startWarming(ctx context.Context, c Cache, root string, excludes []*regexp.Regexp) *Warming {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Warming{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		defer cancel()
+		w.err = warm(ctx, c, root, excludes)
+	}()
+	return w
+}
+
+func warm(ctx context.Context, c Cache, root string, excludes []*regexp.Regexp) error {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	paths := make(chan string)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		eg.Go(func() error {
+			for p := range paths {
+				c.Get(p)
+			}
+			return nil
+		})
+	}
+	eg.Go(func() error {
+		defer close(paths)
+		return filepath.Walk(abs, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				// Unreadable entries are left for Get to report when they are actually used.
+				return nil
+			}
+			for _, r := range excludes {
+				if r.MatchString(path) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	})
+	return eg.Wait()
+}
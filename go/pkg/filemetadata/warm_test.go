@@ -0,0 +1,77 @@
+package filemetadata
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestWarm(t *testing.T) {
+	root := t.TempDir()
+	files := []string{"a.txt", "sub/b.txt", "sub/c.log", "skip/d.txt"}
+	for _, f := range files {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(p), err)
+		}
+		if err := ioutil.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", p, err)
+		}
+	}
+	mem := NewMemoryBackend()
+	c := NewLayeredCache(mem)
+	excl := []*regexp.Regexp{regexp.MustCompile(`\.log$`), regexp.MustCompile(`/skip$`)}
+	if err := c.Warm(context.Background(), root, excl).Wait(); err != nil {
+		t.Fatalf("Warm(%v) failed: %v", root, err)
+	}
+	tests := []struct {
+		path   string
+		cached bool
+	}{
+		{path: "a.txt", cached: true},
+		{path: "sub", cached: true},
+		{path: "sub/b.txt", cached: true},
+		{path: "sub/c.log", cached: false},
+		{path: "skip", cached: false},
+		{path: "skip/d.txt", cached: false},
+	}
+	for _, tc := range tests {
+		p := filepath.Join(root, tc.path)
+		if _, ok := mem.Load(p); ok != tc.cached {
+			t.Errorf("Load(%v) after Warm returned %v, want %v", tc.path, ok, tc.cached)
+		}
+	}
+	if c.GetCacheHits() != 0 {
+		t.Errorf("Warm should only produce cache misses, got %v hits", c.GetCacheHits())
+	}
+}
+
+func TestWarmCanceled(t *testing.T) {
+	root := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewLayeredCache(NewMemoryBackend()).Warm(ctx, root, nil).Wait(); err != context.Canceled {
+		t.Errorf("Warm() with canceled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWarmCancel(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 100; i++ {
+		if err := ioutil.WriteFile(filepath.Join(root, fmt.Sprintf("%d.txt", i)), []byte{byte(i)}, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	w := NewLayeredCache(NewMemoryBackend()).Warm(context.Background(), root, nil)
+	w.Cancel()
+	if err := w.Wait(); err != nil && err != context.Canceled {
+		t.Errorf("Warm() canceled returned %v, want nil or %v", err, context.Canceled)
+	}
+}
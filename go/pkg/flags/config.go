@@ -0,0 +1,111 @@
+package flags
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ConfigFileEnv is the environment variable holding the path of the config file, used when
+// --config is not set.
+const ConfigFileEnv = EnvPrefix + "CONFIG"
+
+var (
+	// ConfigFile is the path of a JSON file providing default values for the flags.
+	ConfigFile = flag.String("config", "", "Path to a JSON file mapping flag names to default values. Flags set on the command line take precedence. Defaults to $"+ConfigFileEnv+".")
+
+	configLoaded bool
+)
+
+// LoadConfig sets every flag that was not explicitly set to the value given in the config file,
+// if one is configured through --config or $RBE_CONFIG. It must be called after the flags are
+// parsed, and does nothing on subsequent calls. NewClientFromFlags calls it, so binaries only
+// need to call it themselves if they read the flags before creating the client.
+//
+// The file holds a JSON object whose keys are flag names and whose values are strings, numbers,
+// booleans or lists of those, which are joined with commas. Keys that are not flags of the
+// current binary are ignored, so that one file can be shared by several tools.
+func LoadConfig() error {
+	if configLoaded {
+		return nil
+	}
+	configLoaded = true
+	path := *ConfigFile
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	if path == "" {
+		return nil
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	vals, err := parseConfig(blob)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return applyConfig(flag.CommandLine, vals)
+}
+
+// applyConfig sets the flags in fs that were not explicitly set to the given values, skipping
+// unknown flags.
+func applyConfig(fs *flag.FlagSet, vals map[string]string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for name, val := range vals {
+		if fs.Lookup(name) == nil || explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, val); err != nil {
+			return fmt.Errorf("invalid value %q for flag %q in config file: %v", val, name, err)
+		}
+	}
+	return nil
+}
+
+func parseConfig(blob []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string, len(raw))
+	for k, v := range raw {
+		s, err := configValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", k, err)
+		}
+		vals[k] = s
+	}
+	return vals, nil
+}
+
+func configValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := configValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -0,0 +1,146 @@
+package flags
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		blob    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			blob: `{}`,
+			want: map[string]string{},
+		},
+		{
+			name: "scalars",
+			blob: `{"service": "remote:443", "cas_concurrency": 100, "service_no_auth": true, "rpc_timeouts": "default=20s"}`,
+			want: map[string]string{
+				"service":         "remote:443",
+				"cas_concurrency": "100",
+				"service_no_auth": "true",
+				"rpc_timeouts":    "default=20s",
+			},
+		},
+		{
+			name: "lists",
+			blob: `{"remote_header": ["a=b", "c=d"], "ints": [1, 2.5], "empty": []}`,
+			want: map[string]string{
+				"remote_header": "a=b,c=d",
+				"ints":          "1,2.5",
+				"empty":         "",
+			},
+		},
+		{
+			name:    "nested object",
+			blob:    `{"service": {"address": "remote:443"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "null",
+			blob:    `{"service": null}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			blob:    `["service"]`,
+			wantErr: true,
+		},
+		{
+			name:    "yaml",
+			blob:    "service: remote:443\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseConfig([]byte(tc.blob))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseConfig(%s) gave error %v, want error: %v", tc.blob, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseConfig(%s) gave result diff (-want +got):\n%s", tc.blob, diff)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		vals    map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "unset flags take the config values",
+			vals: map[string]string{"service": "config:443", "cas_concurrency": "7"},
+			want: map[string]string{"service": "config:443", "cas_concurrency": "7", "instance": ""},
+		},
+		{
+			name: "explicit flags win",
+			args: []string{"--service=cmd:443", "--instance="},
+			vals: map[string]string{"service": "config:443", "instance": "config", "cas_concurrency": "7"},
+			want: map[string]string{"service": "cmd:443", "cas_concurrency": "7", "instance": ""},
+		},
+		{
+			name: "unknown keys are ignored",
+			vals: map[string]string{"other_tool_flag": "x"},
+			want: map[string]string{"service": "", "cas_concurrency": "500", "instance": ""},
+		},
+		{
+			name:    "invalid value",
+			vals:    map[string]string{"cas_concurrency": "many"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := testFlagSet(t, tc.args)
+			err := applyConfig(fs, tc.vals)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applyConfig(%v) gave error %v, want error: %v", tc.vals, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, flagValues(fs, tc.want)); diff != "" {
+				t.Errorf("applyConfig(%v) gave flag diff (-want +got):\n%s", tc.vals, diff)
+			}
+		})
+	}
+}
+
+// testFlagSet returns a flag set with a few of the client flags, parsed from args.
+func testFlagSet(t *testing.T, args []string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("service", "", "")
+	fs.String("instance", "", "")
+	fs.Int("cas_concurrency", 500, "")
+	fs.Bool("service_no_auth", false, "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) failed: %v", args, err)
+	}
+	return fs
+}
+
+// flagValues returns the values of the flags in fs named by the keys of names.
+func flagValues(fs *flag.FlagSet, names map[string]string) map[string]string {
+	vals := make(map[string]string, len(names))
+	for name := range names {
+		vals[name] = fs.Lookup(name).Value.String()
+	}
+	return vals
+}
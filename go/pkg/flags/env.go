@@ -0,0 +1,73 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that provide fallback flag values.
+const EnvPrefix = "RBE_"
+
+// envFlags are the flags of this package that can be set through environment variables.
+var envFlags = []string{
+	"credential_file",
+	"use_application_default_credentials",
+	"use_gce_credentials",
+	"use_rpc_credentials",
+	"service",
+	"service_no_security",
+	"service_no_auth",
+	"cas_service",
+	"instance",
+	"cas_concurrency",
+	"max_concurrent_requests_per_conn",
+	"max_concurrent_streams_per_conn",
+	"tls_server_name",
+	"tls_ca_cert",
+	"tls_client_auth_cert",
+	"tls_client_auth_key",
+	"startup_capabilities",
+	"rpc_timeouts",
+	"remote_header",
+	"min_grpc_connections",
+}
+
+var envLoaded bool
+
+// EnvVar returns the name of the environment variable providing a fallback value for the flag,
+// e.g. RBE_SERVICE for --service.
+func EnvVar(flagName string) string {
+	return EnvPrefix + strings.ToUpper(flagName)
+}
+
+// LoadEnv sets every client flag of this package that was not explicitly set to the value of its
+// RBE_-prefixed environment variable, if present. Environment values take precedence over the
+// config file, but not over the command line. It must be called after the flags are parsed, and
+// does nothing on subsequent calls. NewClientFromFlags calls it.
+func LoadEnv() error {
+	if envLoaded {
+		return nil
+	}
+	envLoaded = true
+	return applyEnv(flag.CommandLine, os.LookupEnv)
+}
+
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, name := range envFlags {
+		if explicit[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		v, ok := lookup(EnvVar(name))
+		if !ok {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("invalid value %q for $%s: %v", v, EnvVar(name), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package flags
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			name: "unset flags take the environment values",
+			env:  map[string]string{"RBE_SERVICE": "env:443", "RBE_CAS_CONCURRENCY": "7"},
+			want: map[string]string{"service": "env:443", "cas_concurrency": "7", "instance": ""},
+		},
+		{
+			name: "explicit flags win",
+			args: []string{"--service=cmd:443"},
+			env:  map[string]string{"RBE_SERVICE": "env:443", "RBE_INSTANCE": "env"},
+			want: map[string]string{"service": "cmd:443", "instance": "env"},
+		},
+		{
+			name: "empty values are set",
+			env:  map[string]string{"RBE_INSTANCE": ""},
+			want: map[string]string{"instance": ""},
+		},
+		{
+			name: "unprefixed variables are ignored",
+			env:  map[string]string{"SERVICE": "env:443", "service": "env:443"},
+			want: map[string]string{"service": ""},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := testFlagSet(t, tc.args)
+			if err := applyEnv(fs, lookupIn(tc.env)); err != nil {
+				t.Fatalf("applyEnv(%v) failed: %v", tc.env, err)
+			}
+			if diff := cmp.Diff(tc.want, flagValues(fs, tc.want)); diff != "" {
+				t.Errorf("applyEnv(%v) gave flag diff (-want +got):\n%s", tc.env, diff)
+			}
+		})
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	fs := testFlagSet(t, nil)
+	env := map[string]string{"RBE_CAS_CONCURRENCY": "many"}
+	err := applyEnv(fs, lookupIn(env))
+	if err == nil {
+		t.Fatalf("applyEnv(%v) succeeded, want an error", env)
+	}
+	if !strings.Contains(err.Error(), "$RBE_CAS_CONCURRENCY") {
+		t.Errorf("applyEnv(%v) gave error %q, want it to name $RBE_CAS_CONCURRENCY", env, err)
+	}
+}
+
+// TestPrecedence checks that the command line takes precedence over the environment, which takes
+// precedence over the config file, when they are applied in the order of NewClientFromFlags.
+func TestPrecedence(t *testing.T) {
+	fs := testFlagSet(t, []string{"--service=cmd:443"})
+	env := map[string]string{"RBE_SERVICE": "env:443", "RBE_INSTANCE": "env"}
+	if err := applyEnv(fs, lookupIn(env)); err != nil {
+		t.Fatalf("applyEnv(%v) failed: %v", env, err)
+	}
+	vals := map[string]string{"service": "config:443", "instance": "config", "cas_concurrency": "7"}
+	if err := applyConfig(fs, vals); err != nil {
+		t.Fatalf("applyConfig(%v) failed: %v", vals, err)
+	}
+	want := map[string]string{"service": "cmd:443", "instance": "env", "cas_concurrency": "7"}
+	if diff := cmp.Diff(want, flagValues(fs, want)); diff != "" {
+		t.Errorf("Flag diff (-want +got):\n%s", diff)
+	}
+}
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
@@ -4,11 +4,14 @@ package flags
 import (
 	"context"
 	"flag"
+	"fmt"
 	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/balancer"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/localcas"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/moreflag"
+	"github.com/klauspost/compress/zstd"
 )
 
 var (
@@ -60,8 +63,31 @@ var (
 	TLSClientAuthKey = flag.String("tls_client_auth_key", "", "Key to use when using mTLS to connect to the RBE service.")
 	// StartupCapabilities specifies whether to self-configure based on remote server capabilities on startup.
 	StartupCapabilities = flag.Bool("startup_capabilities", true, "Whether to self-configure based on remote server capabilities on startup.")
+	// PresenceCacheDir is the directory remembering which blobs are present in the CAS.
+	PresenceCacheDir = flag.String("presence_cache_dir", "", "If set, a directory, which may be shared by concurrent processes, remembering which blobs are known to be present in the CAS, to avoid querying them again.")
+	// PresenceCacheTTL is how long blobs are trusted to remain in the CAS after being seen there.
+	PresenceCacheTTL = flag.Duration("presence_cache_ttl", time.Hour, "How long blobs in --presence_cache_dir are trusted to remain in the CAS after being seen there.")
+	// LocalCASDir is the directory of the local CAS from which blobs are read before the remote CAS.
+	LocalCASDir = flag.String("local_cas_dir", "", "If set, a directory storing blobs locally, e.g. prefetched ones, from which reads and downloads are served before falling back to the remote CAS. It must not be used by several processes at the same time.")
+	// StatsFile is the path to which the client writes its build stats when closed.
+	StatsFile = flag.String("stats_file", "", "If set, a file to which per-action and per-build statistics are written as JSON when the client is closed.")
+	// CompressedBytestreamThreshold is the minimum size of the blobs transferred compressed.
+	CompressedBytestreamThreshold = flag.Int64("compressed_bytestream_threshold", client.DefaultCompressedBytestreamThreshold, "If non-negative, the minimum size in bytes of the blobs read and written compressed with zstd over ByteStream, when the CAS supports it. Negative values disable compression.")
+	// CompressionLevel is the zstd level of the blobs written compressed.
+	CompressionLevel = flag.String("compression_level", "default", "The zstd level of the blobs written compressed: fastest, default, better or best.")
+	// ParallelReadPartSize is the size of the parts of the large blobs downloaded in parallel.
+	ParallelReadPartSize = flag.Int64("parallel_read_part_size", 0, "If positive, blobs of at least twice this size in bytes are downloaded to files with concurrent ByteStream reads of parts of this size.")
+	// ParallelReadParallelism is the number of parts of a blob downloaded at the same time.
+	ParallelReadParallelism = flag.Int("parallel_read_parallelism", client.DefaultParallelReadParallelism, "The maximum number of parts of a blob downloaded at the same time, with --parallel_read_part_size.")
 	// RPCTimeouts stores the per-RPC timeout values.
 	RPCTimeouts map[string]string
+	// RemoteHeaders stores the extra gRPC metadata headers attached to every RPC.
+	RemoteHeaders map[string][]string
+	// CompressionSkipExtensions are the extensions of the files never written compressed.
+	CompressionSkipExtensions []string
+	// CompressionSkipContentTypes are the prefixes of the MIME types of the blobs never written
+	// compressed.
+	CompressionSkipContentTypes []string
 )
 
 func init() {
@@ -72,11 +98,23 @@ func init() {
 	// themselves with every RPC, otherwise it is easy to accidentally enforce a timeout on
 	// WaitExecution, for example.
 	flag.Var((*moreflag.StringMapValue)(&RPCTimeouts), "rpc_timeouts", "Comma-separated key value pairs in the form rpc_name=timeout. The key for default RPC is named default. 0 indicates no timeout. Example: GetActionResult=500ms,Execute=0,default=10s.")
+	flag.Var((*moreflag.StringListMapValue)(&RemoteHeaders), "remote_header", "Extra gRPC metadata header to attach to every RPC, in the form key=value. Can be repeated.")
+	flag.Var((*moreflag.StringListValue)(&CompressionSkipExtensions), "compression_skip_extensions", "Comma-separated extensions of the files never written compressed, e.g. .zip,.jpg for data which is already compressed.")
+	flag.Var((*moreflag.StringListValue)(&CompressionSkipContentTypes), "compression_skip_content_types", "Comma-separated prefixes of the MIME types, detected from their contents, of the blobs never written compressed, e.g. image/,application/zip.")
 }
 
 // NewClientFromFlags connects to a remote execution service and returns a client suitable for higher-level
 // functionality. It uses the flags from above to configure the connection to remote execution.
+// Flags not set on the command line fall back to their RBE_ environment variables, then to the
+// config file: LoadEnv is called before LoadConfig, and the flags it sets count as set for
+// LoadConfig, which leaves them alone.
 func NewClientFromFlags(ctx context.Context, opts ...client.Opt) (*client.Client, error) {
+	if err := LoadEnv(); err != nil {
+		return nil, err
+	}
+	if err := LoadConfig(); err != nil {
+		return nil, err
+	}
 	opts = append(opts, []client.Opt{client.CASConcurrency(*CASConcurrency), client.StartupCapabilities(*StartupCapabilities)}...)
 	if len(RPCTimeouts) > 0 {
 		timeouts := make(map[string]time.Duration)
@@ -93,7 +131,39 @@ func NewClientFromFlags(ctx context.Context, opts ...client.Opt) (*client.Client
 		}
 		opts = append(opts, client.RPCTimeouts(timeouts))
 	}
-	return client.NewClient(ctx, *Instance, client.DialParams{
+	if *PresenceCacheDir != "" {
+		pc, err := client.NewPresenceCache(*PresenceCacheDir, *PresenceCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, pc)
+	}
+	if *StatsFile != "" {
+		opts = append(opts, client.StatsFile(*StatsFile))
+	}
+	if *CompressedBytestreamThreshold >= 0 {
+		opts = append(opts, client.CompressedBytestreamThreshold(*CompressedBytestreamThreshold))
+		ok, level := zstd.EncoderLevelFromString(*CompressionLevel)
+		if !ok {
+			return nil, fmt.Errorf("invalid --compression_level %q, want fastest, default, better or best", *CompressionLevel)
+		}
+		opts = append(opts, &client.CompressionPolicy{
+			Level:            level,
+			SkipExtensions:   CompressionSkipExtensions,
+			SkipContentTypes: CompressionSkipContentTypes,
+		})
+	}
+	if *ParallelReadPartSize > 0 {
+		opts = append(opts, &client.ParallelReads{PartSize: *ParallelReadPartSize, Parallelism: *ParallelReadParallelism})
+	}
+	var store *localcas.Store
+	if *LocalCASDir != "" {
+		var err error
+		if store, err = localcas.Open(*LocalCASDir, nil); err != nil {
+			return nil, err
+		}
+	}
+	c, err := client.NewClient(ctx, *Instance, client.DialParams{
 		Service:               *Service,
 		NoSecurity:            *ServiceNoSecurity,
 		NoAuth:                *ServiceNoAuth,
@@ -108,5 +178,11 @@ func NewClientFromFlags(ctx context.Context, opts ...client.Opt) (*client.Client
 		TLSClientAuthKey:      *TLSClientAuthKey,
 		MaxConcurrentRequests: uint32(*MaxConcurrentRequests),
 		MaxConcurrentStreams:  uint32(*MaxConcurrentStreams),
+		RemoteHeaders:         RemoteHeaders,
 	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.LocalCAS = store
+	return c, nil
 }
@@ -0,0 +1,192 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package lazyfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
+)
+
+// attrTimeout is how long the kernel may cache entries and attributes. Trees are immutable, so
+// it is long.
+const attrTimeout = time.Hour
+
+func mountFUSE(ctx context.Context, c *client.Client, outs map[string]*client.TreeOutput, dir, cacheDir string) (*Mount, error) {
+	if _, err := os.Stat("/dev/fuse"); err != nil && runtime.GOOS == "linux" {
+		return nil, fmt.Errorf("%w: %v", ErrFUSEUnavailable, err)
+	}
+	cacheDir, cleanup, err := newCacheDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	root := &dirNode{outs: outs, f: &fetcher{ctx: ctx, c: c, dir: cacheDir}}
+	timeout := attrTimeout
+	srv, err := fs.Mount(dir, root, &fs.Options{
+		MountOptions: fuse.MountOptions{FsName: "cas", Name: "lazyfs"},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+	})
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("%w: mounting %v: %v", ErrFUSEUnavailable, dir, err)
+	}
+	return &Mount{Dir: dir, Lazy: true, unmount: func() error {
+		defer cleanup()
+		return srv.Unmount()
+	}, add: func(ctx context.Context, outs map[string]*client.TreeOutput) {
+		root.addOutputs(ctx, outs, true)
+	}}, nil
+}
+
+// dirNode is a directory. The root directory builds the whole tree when it is added.
+type dirNode struct {
+	fs.Inode
+	outs map[string]*client.TreeOutput
+	f    *fetcher
+}
+
+var (
+	_ fs.NodeOnAdder    = (*dirNode)(nil)
+	_ fs.NodeGetattrer  = (*dirNode)(nil)
+	_ fs.NodeGetattrer  = (*fileNode)(nil)
+	_ fs.NodeOpener     = (*fileNode)(nil)
+	_ fs.NodeGetattrer  = (*fs.MemSymlink)(nil)
+	_ fs.NodeReadlinker = (*fs.MemSymlink)(nil)
+)
+
+// OnAdd implements fs.NodeOnAdder.
+func (n *dirNode) OnAdd(ctx context.Context) {
+	if n.outs == nil {
+		return
+	}
+	n.addOutputs(ctx, n.outs, false)
+	n.outs = nil
+}
+
+// addOutputs adds outs to the tree rooted at n, replacing existing entries. If notify is true, the
+// kernel is told to forget the replaced entries, which it may have cached.
+func (n *dirNode) addOutputs(ctx context.Context, outs map[string]*client.TreeOutput, notify bool) {
+	for p, out := range outs {
+		parent := n.EmbeddedInode()
+		elems := strings.Split(filepath.ToSlash(p), "/")
+		for _, elem := range elems[:len(elems)-1] {
+			parent = n.mkdir(ctx, parent, elem)
+		}
+		name := elems[len(elems)-1]
+		var ch *fs.Inode
+		switch {
+		case out.IsEmptyDirectory:
+			n.mkdir(ctx, parent, name)
+			continue
+		case out.SymlinkTarget != "":
+			ch = parent.NewPersistentInode(ctx, &fs.MemSymlink{Data: []byte(out.SymlinkTarget)}, fs.StableAttr{Mode: fuse.S_IFLNK})
+		default:
+			ch = parent.NewPersistentInode(ctx, &fileNode{d: out.Digest, exec: out.IsExecutable, f: n.f}, fs.StableAttr{Mode: fuse.S_IFREG})
+		}
+		replaced := parent.GetChild(name) != nil
+		parent.AddChild(name, ch, true)
+		if notify && replaced {
+			parent.NotifyEntry(name)
+		}
+	}
+}
+
+// mkdir returns the child directory of parent with the given name, creating it if needed.
+func (n *dirNode) mkdir(ctx context.Context, parent *fs.Inode, name string) *fs.Inode {
+	if name == "" || name == "." {
+		return parent
+	}
+	if ch := parent.GetChild(name); ch != nil {
+		return ch
+	}
+	ch := parent.NewPersistentInode(ctx, &dirNode{}, fs.StableAttr{Mode: fuse.S_IFDIR})
+	parent.AddChild(name, ch, true)
+	return ch
+}
+
+// Getattr implements fs.NodeGetattrer.
+func (n *dirNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0555
+	return 0
+}
+
+// fileNode is a regular file, fetched on open.
+type fileNode struct {
+	fs.Inode
+	d    digest.Digest
+	exec bool
+	f    *fetcher
+}
+
+// Getattr implements fs.NodeGetattrer.
+func (n *fileNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0444
+	if n.exec {
+		out.Mode |= 0111
+	}
+	out.Size = uint64(n.d.Size)
+	return 0
+}
+
+// Open implements fs.NodeOpener.
+func (n *fileNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	path, err := n.f.fetch(n.d)
+	if err != nil {
+		logger.Errorf(n.f.ctx, "lazyfs: fetching %v: %v", n.d, err)
+		return nil, 0, syscall.EIO
+	}
+	fd, err := syscall.Open(path, syscall.O_RDONLY, 0)
+	if err != nil {
+		return nil, 0, fs.ToErrno(err)
+	}
+	return fs.NewLoopbackFile(fd), fuse.FOPEN_KEEP_CACHE, 0
+}
+
+// fetcher downloads blobs to the cache directory, once per blob.
+type fetcher struct {
+	// ctx is the context of the mount. Fetches do not use the contexts of the FUSE requests,
+	// because they are shared between concurrent requests.
+	ctx   context.Context
+	c     *client.Client
+	dir   string
+	group singleflight.Group
+}
+
+// fetch returns the path of the cached copy of the blob, downloading it if needed.
+func (f *fetcher) fetch(d digest.Digest) (string, error) {
+	path := filepath.Join(f.dir, fmt.Sprintf("%s_%d", d.Hash, d.Size))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	_, err, _ := f.group.Do(path, func() (interface{}, error) {
+		if _, err := os.Stat(path); err == nil {
+			return nil, nil
+		}
+		// Other processes may share the cache directory.
+		tmp := fmt.Sprintf("%s.tmp%d", path, os.Getpid())
+		if _, err := f.c.ReadBlobToFile(f.ctx, d, tmp); err != nil {
+			os.Remove(tmp)
+			return nil, err
+		}
+		return nil, os.Rename(tmp, path)
+	})
+	return path, err
+}
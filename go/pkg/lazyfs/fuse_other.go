@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package lazyfs
+
+import (
+	"context"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+func mountFUSE(ctx context.Context, c *client.Client, outs map[string]*client.TreeOutput, dir, cacheDir string) (*Mount, error) {
+	return nil, ErrFUSEUnavailable
+}
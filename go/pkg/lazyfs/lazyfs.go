@@ -0,0 +1,150 @@
+// Package lazyfs materializes remote directory trees and action outputs on local paths lazily.
+//
+// Where FUSE is available, it mounts a read-only file system on the target directory, whose
+// structure is known upfront but whose file contents are fetched from the CAS on first access and
+// kept in a local cache directory. Elsewhere, or if the mount fails, it falls back to downloading
+// everything eagerly, so that callers do not need to handle both cases.
+//
+// OutputService plugs the same mounts into Client.DownloadActionOutputs, so that action outputs
+// are only fetched when read.
+package lazyfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ErrFUSEUnavailable is returned by mounts on platforms or machines without FUSE support.
+var ErrFUSEUnavailable = errors.New("FUSE is not available")
+
+// Options configures how trees are materialized.
+type Options struct {
+	// CacheDir is the directory where file contents are stored once fetched. If empty, a temporary
+	// directory is created, and removed by Unmount.
+	CacheDir string
+
+	// DisableFUSE forces trees to be downloaded eagerly.
+	DisableFUSE bool
+
+	// Cache is the file metadata cache updated by eager downloads. If nil, no cache is used.
+	Cache filemetadata.Cache
+}
+
+// Mount is a materialized tree.
+type Mount struct {
+	// Dir is the directory the tree was materialized in.
+	Dir string
+
+	// Lazy is whether the tree is served by FUSE and fetched on access. If false, the tree was
+	// downloaded eagerly.
+	Lazy bool
+
+	unmount func() error
+	// add adds outputs to a lazy mount.
+	add func(context.Context, map[string]*client.TreeOutput)
+}
+
+// Unmount unmounts a lazily materialized tree, which must not be in use anymore, and removes the
+// temporary cache directory, if any. It is a no-op for trees downloaded eagerly, which are left in
+// place.
+func (m *Mount) Unmount() error {
+	if m.unmount == nil {
+		return nil
+	}
+	return m.unmount()
+}
+
+// MountDirectory materializes the directory tree whose root Directory has the digest root in dir,
+// which must exist and be empty. File contents are read from the CAS using ctx, which must remain
+// valid until Unmount.
+func MountDirectory(ctx context.Context, c *client.Client, root digest.Digest, dir string, opts *Options) (*Mount, error) {
+	return mount(ctx, c, dir, opts, func() (map[string]*client.TreeOutput, error) {
+		dirs, err := c.GetDirectoryTree(ctx, root.ToProto())
+		if err != nil {
+			return nil, err
+		}
+		tree := &repb.Tree{}
+		for _, d := range dirs {
+			dg, err := digest.NewFromMessage(d)
+			if err != nil {
+				return nil, err
+			}
+			if dg == root && tree.Root == nil {
+				tree.Root = d
+			} else {
+				tree.Children = append(tree.Children, d)
+			}
+		}
+		if tree.Root == nil {
+			return nil, fmt.Errorf("root directory %v is missing from the tree", root)
+		}
+		return c.FlattenTree(tree, "")
+	}, func(cache filemetadata.Cache) error {
+		_, _, err := c.DownloadDirectory(ctx, root, dir, cache)
+		return err
+	})
+}
+
+// MountActionOutputs materializes the outputs of the action result in dir, which must exist and
+// be empty. Output paths are relative to dir. File contents are read from the CAS using ctx, which
+// must remain valid until Unmount.
+func MountActionOutputs(ctx context.Context, c *client.Client, ar *repb.ActionResult, dir string, opts *Options) (*Mount, error) {
+	return mount(ctx, c, dir, opts, func() (map[string]*client.TreeOutput, error) {
+		return c.FlattenActionOutputs(ctx, ar)
+	}, func(cache filemetadata.Cache) error {
+		_, err := c.DownloadActionOutputs(ctx, ar, dir, cache)
+		return err
+	})
+}
+
+func mount(ctx context.Context, c *client.Client, dir string, opts *Options, entries func() (map[string]*client.TreeOutput, error), download func(filemetadata.Cache) error) (*Mount, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if !opts.DisableFUSE {
+		outs, err := entries()
+		if err != nil {
+			return nil, err
+		}
+		m, err := mountFUSE(ctx, c, outs, dir, opts.CacheDir)
+		if err == nil {
+			return m, nil
+		}
+		if !errors.Is(err, ErrFUSEUnavailable) {
+			return nil, err
+		}
+		logger.Warningf(ctx, "lazyfs: %v, downloading %v eagerly", err, dir)
+	}
+	cache := opts.Cache
+	if cache == nil {
+		cache = filemetadata.NewNoopCache()
+	}
+	if err := download(cache); err != nil {
+		return nil, err
+	}
+	return &Mount{Dir: dir}, nil
+}
+
+// newCacheDir returns the cache directory to use and a function removing it if it is temporary.
+func newCacheDir(dir string) (string, func(), error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", nil, err
+		}
+		return dir, func() {}, nil
+	}
+	tmp, err := os.MkdirTemp("", "lazyfs")
+	if err != nil {
+		return "", nil, err
+	}
+	return tmp, func() { os.RemoveAll(tmp) }, nil
+}
@@ -0,0 +1,195 @@
+package lazyfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// putTree stores a tree with a file, an executable file in a subdirectory and a symlink in the
+// CAS, and returns the digest of its root.
+func putTree(t *testing.T, cas *fakes.CAS) digest.Digest {
+	t.Helper()
+	fooDg := cas.Put([]byte("foo"))
+	barDg := cas.Put([]byte("bar"))
+	sub := &repb.Directory{Files: []*repb.FileNode{{Name: "bar", Digest: barDg.ToProto(), IsExecutable: true}}}
+	subDg := putMessage(t, cas, sub)
+	root := &repb.Directory{
+		Files:       []*repb.FileNode{{Name: "foo", Digest: fooDg.ToProto()}},
+		Directories: []*repb.DirectoryNode{{Name: "sub", Digest: subDg.ToProto()}},
+		Symlinks:    []*repb.SymlinkNode{{Name: "link", Target: "foo"}},
+	}
+	return putMessage(t, cas, root)
+}
+
+func putMessage(t *testing.T, cas *fakes.CAS, m *repb.Directory) digest.Digest {
+	t.Helper()
+	blob, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cas.Put(blob)
+}
+
+// readTree returns the contents of the regular files and the targets of the symlinks in dir.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	got := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			got[rel] = "-> " + target
+			return err
+		}
+		data, err := os.ReadFile(path)
+		got[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+var wantTree = map[string]string{
+	"foo":                       "foo",
+	filepath.Join("sub", "bar"): "bar",
+	"link":                      "-> foo",
+}
+
+func TestMountDirectory(t *testing.T) {
+	for _, disableFUSE := range []bool{false, true} {
+		disableFUSE := disableFUSE
+		name := "FUSE"
+		if disableFUSE {
+			name = "Download"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			root := putTree(t, e.Server.CAS)
+
+			dir := t.TempDir()
+			m, err := MountDirectory(ctx, e.Client.GrpcClient, root, dir, &Options{DisableFUSE: disableFUSE, CacheDir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("MountDirectory() failed: %v", err)
+			}
+			defer func() {
+				if err := m.Unmount(); err != nil {
+					t.Errorf("Unmount() failed: %v", err)
+				}
+			}()
+			if !disableFUSE && !m.Lazy {
+				t.Skip("FUSE is not available, the tree was downloaded instead")
+			}
+			if disableFUSE && m.Lazy {
+				t.Errorf("MountDirectory() with FUSE disabled returned a lazy mount")
+			}
+			if diff := cmp.Diff(wantTree, readTree(t, dir)); diff != "" {
+				t.Errorf("MountDirectory() materialized diff (-want +got):\n%s", diff)
+			}
+			info, err := os.Stat(filepath.Join(dir, "sub", "bar"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode()&0100 == 0 {
+				t.Errorf("sub/bar has mode %v, want it to be executable", info.Mode())
+			}
+		})
+	}
+}
+
+func TestMountActionOutputsFallback(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	fooDg := e.Server.CAS.Put([]byte("foo"))
+	ar := &repb.ActionResult{
+		OutputFiles:        []*repb.OutputFile{{Path: "out/foo", Digest: fooDg.ToProto()}},
+		OutputFileSymlinks: []*repb.OutputSymlink{{Path: "out/link", Target: "foo"}},
+	}
+
+	dir := t.TempDir()
+	m, err := MountActionOutputs(ctx, e.Client.GrpcClient, ar, dir, &Options{DisableFUSE: true})
+	if err != nil {
+		t.Fatalf("MountActionOutputs() failed: %v", err)
+	}
+	if m.Lazy {
+		t.Errorf("MountActionOutputs() with FUSE disabled returned a lazy mount")
+	}
+	want := map[string]string{
+		filepath.Join("out", "foo"):  "foo",
+		filepath.Join("out", "link"): "-> foo",
+	}
+	if diff := cmp.Diff(want, readTree(t, dir)); diff != "" {
+		t.Errorf("MountActionOutputs() materialized diff (-want +got):\n%s", diff)
+	}
+	if err := m.Unmount(); err != nil {
+		t.Errorf("Unmount() failed: %v", err)
+	}
+}
+
+func TestOutputService(t *testing.T) {
+	ctx := context.Background()
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	c := e.Client.GrpcClient
+	fooDg := e.Server.CAS.Put([]byte("foo"))
+	ar := &repb.ActionResult{
+		OutputFiles:        []*repb.OutputFile{{Path: "out/foo", Digest: fooDg.ToProto()}},
+		OutputFileSymlinks: []*repb.OutputSymlink{{Path: "out/link", Target: "foo"}},
+	}
+	want := map[string]string{
+		filepath.Join("out", "foo"):  "foo",
+		filepath.Join("out", "link"): "-> foo",
+	}
+
+	s := NewOutputService(ctx, c, &Options{CacheDir: t.TempDir()})
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	}()
+	c.OutputService = s
+	defer func() { c.OutputService = nil }()
+
+	// Outputs are served by FUSE where available, and downloaded otherwise.
+	dir := filepath.Join(t.TempDir(), "outputs")
+	if _, err := c.DownloadActionOutputs(ctx, ar, dir, filemetadata.NewNoopCache()); err != nil {
+		t.Fatalf("DownloadActionOutputs() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, readTree(t, dir)); diff != "" {
+		t.Errorf("DownloadActionOutputs() materialized diff (-want +got):\n%s", diff)
+	}
+
+	// Non-empty directories cannot be mounted, so outputs are downloaded into them.
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input"), []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DownloadActionOutputs(ctx, ar, dir, filemetadata.NewNoopCache()); err != nil {
+		t.Fatalf("DownloadActionOutputs() failed: %v", err)
+	}
+	want["input"] = "input"
+	if diff := cmp.Diff(want, readTree(t, dir)); diff != "" {
+		t.Errorf("DownloadActionOutputs() materialized diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,83 @@
+package lazyfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+// OutputService is a client.OutputService serving registered outputs from a FUSE mount per output
+// directory. The first registration in a directory mounts it, so the directory must then be empty
+// or not exist yet; later registrations add to the mount. Registrations that cannot be served,
+// including all of them where FUSE is unavailable, fail with client.ErrOutputServiceUnavailable so
+// that the client downloads the outputs instead.
+type OutputService struct {
+	ctx  context.Context
+	c    *client.Client
+	opts *Options
+
+	mu     sync.Mutex
+	mounts map[string]*Mount
+}
+
+var _ client.OutputService = (*OutputService)(nil)
+
+// NewOutputService returns an OutputService fetching file contents from the CAS of c using ctx,
+// which must remain valid until Close. Only opts.CacheDir is used.
+func NewOutputService(ctx context.Context, c *client.Client, opts *Options) *OutputService {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &OutputService{ctx: ctx, c: c, opts: opts, mounts: make(map[string]*Mount)}
+}
+
+// RegisterOutputs implements client.OutputService.
+func (s *OutputService) RegisterOutputs(ctx context.Context, outDir string, outs map[string]*client.TreeOutput) error {
+	dir, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.mounts[dir]; ok {
+		m.add(ctx, outs)
+		return nil
+	}
+	if s.opts.DisableFUSE {
+		return fmt.Errorf("%w: FUSE is disabled", client.ErrOutputServiceUnavailable)
+	}
+	if err := os.MkdirAll(dir, s.c.DirMode); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%w: %v is not empty", client.ErrOutputServiceUnavailable, dir)
+	}
+	m, err := mountFUSE(s.ctx, s.c, outs, dir, s.opts.CacheDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", client.ErrOutputServiceUnavailable, err)
+	}
+	s.mounts[dir] = m
+	return nil
+}
+
+// Close unmounts all the output directories, which must not be in use anymore.
+func (s *OutputService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for dir, m := range s.mounts {
+		if err := m.Unmount(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("unmounting %v: %v", dir, err)
+		}
+		delete(s.mounts, dir)
+	}
+	return firstErr
+}
@@ -0,0 +1,37 @@
+// +build linux
+
+package localcas
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile clones src to dst with the FICLONE ioctl, which btrfs and XFS support.
+func cloneFile(src, dst string) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlFileClone(int(d.Fd()), int(s.Fd()))
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		return nil
+	}
+	os.Remove(dst)
+	// The errors of file systems without reflinks, or of clones across file systems.
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EXDEV) {
+		return fmt.Errorf("%w: %v", ErrCloneUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+// +build !darwin,!linux
+
+package localcas
+
+// cloneFile is not implemented on this platform.
+func cloneFile(src, dst string) error {
+	return ErrCloneUnsupported
+}
@@ -0,0 +1,416 @@
+// Package localcas implements an on-disk content-addressable store with a size cap.
+//
+// Blobs are stored as individual read-only files, sharded by the first two characters of their
+// hash, so that they can be hard linked or cloned into place by callers materializing outputs.
+// When the store grows beyond its size cap, the least recently used blobs are removed, except for
+// those currently referenced with Ref.
+//
+// A store directory must only be used by one Store at a time.
+package localcas
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+var (
+	// ErrNotFound is returned for blobs missing from the store.
+	ErrNotFound = errors.New("blob not found in the local CAS")
+	// ErrCloneUnsupported is returned by Clone when the file systems do not support copy-on-write
+	// clones.
+	ErrCloneUnsupported = errors.New("copy-on-write clones are not supported")
+)
+
+const (
+	blobsDir = "blobs"
+	tmpDir   = "tmp"
+)
+
+// Options configures a Store.
+type Options struct {
+	// MaxSize is the size in bytes above which blobs are garbage collected. Zero means no limit.
+	MaxSize int64
+
+	// GCTarget is the fraction of MaxSize that garbage collections shrink the store to, so that
+	// they do not run on every write. It defaults to 0.9.
+	GCTarget float64
+}
+
+// Stats describes the contents of a Store.
+type Stats struct {
+	// Blobs is the number of blobs in the store.
+	Blobs int
+	// Size is the total size of the blobs in bytes.
+	Size int64
+	// Referenced is the number of blobs with live references.
+	Referenced int
+	// Evicted is the number of blobs removed by garbage collection since the store was opened.
+	Evicted int
+}
+
+// Store is an on-disk content-addressable store. It is safe for concurrent use.
+type Store struct {
+	root string
+	opts Options
+
+	mu      sync.Mutex
+	entries map[digest.Digest]*entry
+	// lru holds the entries from the least to the most recently used.
+	lru     *list.List
+	size    int64
+	evicted int
+}
+
+type entry struct {
+	d    digest.Digest
+	refs int
+	elem *list.Element
+}
+
+// Open opens the store in root, creating it if needed, and indexes the blobs already in it,
+// ordered by modification time.
+func Open(root string, opts *Options) (*Store, error) {
+	s := &Store{root: root, entries: make(map[digest.Digest]*entry), lru: list.New()}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.GCTarget <= 0 || s.opts.GCTarget > 1 {
+		s.opts.GCTarget = 0.9
+	}
+	// Leftovers of interrupted writes are garbage.
+	if err := os.RemoveAll(filepath.Join(root, tmpDir)); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{blobsDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	type found struct {
+		d     digest.Digest
+		mtime time.Time
+	}
+	var blobs []found
+	err := filepath.Walk(filepath.Join(root, blobsDir), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		d, err := parseName(info.Name())
+		if err != nil || d.Size != info.Size() {
+			// Not a blob, or a corrupted one.
+			return os.Remove(path)
+		}
+		blobs = append(blobs, found{d, info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].mtime.Before(blobs[j].mtime) })
+	for _, b := range blobs {
+		s.add(b.d)
+	}
+	return s, nil
+}
+
+// Root returns the directory of the store.
+func (s *Store) Root() string {
+	return s.root
+}
+
+// Path returns the path of the file storing the blob d, which may not exist. The file must not be
+// modified; it may be removed by garbage collection unless the blob is referenced.
+func (s *Store) Path(d digest.Digest) string {
+	return filepath.Join(s.root, blobsDir, d.Hash[:2], fmt.Sprintf("%s_%d", d.Hash, d.Size))
+}
+
+// Has returns whether the blob d is in the store, and marks it as used.
+func (s *Store) Has(d digest.Digest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.touch(d) != nil
+}
+
+// Get returns the contents of the blob d.
+func (s *Store) Get(d digest.Digest) ([]byte, error) {
+	release, err := s.Ref(d)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return os.ReadFile(s.Path(d))
+}
+
+// Open opens the blob d for reading. The blob is referenced until the file is closed.
+func (s *Store) Open(d digest.Digest) (io.ReadCloser, error) {
+	release, err := s.Ref(d)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.Path(d))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &refFile{File: f, release: release}, nil
+}
+
+type refFile struct {
+	*os.File
+	release func()
+	once    sync.Once
+}
+
+func (f *refFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(f.release)
+	return err
+}
+
+// Ref marks the blob d as used and protects it from garbage collection until release is called,
+// so that its file can be read, linked or cloned. It returns ErrNotFound if the blob is missing.
+func (s *Store) Ref(d digest.Digest) (release func(), err error) {
+	s.mu.Lock()
+	e := s.touch(d)
+	if e == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, d)
+	}
+	e.refs++
+	s.mu.Unlock()
+	// Persist the recency for the next Open. This is best effort.
+	now := time.Now()
+	os.Chtimes(s.Path(d), now, now)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			e.refs--
+		})
+	}, nil
+}
+
+// Put stores blob and returns its digest.
+func (s *Store) Put(blob []byte) (digest.Digest, error) {
+	d := digest.NewFromBlob(blob)
+	if s.Has(d) {
+		return d, nil
+	}
+	return d, s.write(d, func(f *os.File) error {
+		_, err := f.Write(blob)
+		return err
+	})
+}
+
+// PutReader stores the blob d read from r, verifying its digest.
+func (s *Store) PutReader(d digest.Digest, r io.Reader) error {
+	if s.Has(d) {
+		return nil
+	}
+	return s.write(d, func(f *os.File) error {
+		h := digest.HashFn.New()
+		n, err := io.Copy(io.MultiWriter(f, h), r)
+		if err != nil {
+			return err
+		}
+		if got := (digest.Digest{Hash: fmt.Sprintf("%x", h.Sum(nil)), Size: n}); got != d {
+			return fmt.Errorf("read blob %v, want %v", got, d)
+		}
+		return nil
+	})
+}
+
+// PutFile stores the contents of the file at path, and returns their digest.
+func (s *Store) PutFile(path string) (digest.Digest, error) {
+	d, err := digest.NewFromFile(path)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+	defer f.Close()
+	return d, s.PutReader(d, f)
+}
+
+// write creates the blob d with fill, through a temporary file so that partial blobs are never
+// visible, and collects garbage if the store is over its size cap.
+func (s *Store) write(d digest.Digest, fill func(*os.File) error) error {
+	f, err := os.CreateTemp(filepath.Join(s.root, tmpDir), d.Hash)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	err = fill(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0444); err != nil {
+		return err
+	}
+	path := s.Path(d)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if s.entries[d] == nil {
+		s.add(d)
+	}
+	s.maybeGC()
+	return nil
+}
+
+// Link hard links the blob d to path, which must not exist. The linked file shares the read-only
+// permissions of the blob and remains valid after the blob is garbage collected.
+func (s *Store) Link(d digest.Digest, path string) error {
+	release, err := s.Ref(d)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return os.Link(s.Path(d), path)
+}
+
+// Clone materializes the blob d at path as a copy-on-write clone with permissions perm, replacing
+// any existing file. Unlike a linked file, the clone can be modified independently of the blob,
+// while sharing its data on disk until then. Clones are made with clonefile on APFS, and with the
+// FICLONE ioctl on Linux file systems supporting reflinks, such as btrfs and XFS. If the file
+// systems do not support clones, it returns an error wrapping ErrCloneUnsupported, and the blob
+// should be copied instead.
+func (s *Store) Clone(d digest.Digest, path string, perm os.FileMode) error {
+	release, err := s.Ref(d)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := cloneFile(s.Path(d), path); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// Delete removes the blob d, unless it is referenced.
+func (s *Store) Delete(d digest.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[d]
+	if e == nil {
+		return nil
+	}
+	if e.refs > 0 {
+		return fmt.Errorf("blob %v is referenced", d)
+	}
+	return s.remove(e)
+}
+
+// GC removes the least recently used unreferenced blobs until the store is within the target size
+// derived from its size cap. It returns the number of bytes freed.
+func (s *Store) GC() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gc()
+}
+
+// Stats returns statistics about the contents of the store.
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Stats{Blobs: len(s.entries), Size: s.size, Evicted: s.evicted}
+	for _, e := range s.entries {
+		if e.refs > 0 {
+			st.Referenced++
+		}
+	}
+	return st
+}
+
+// maybeGC collects garbage if the store is over its size cap. Failures are not fatal to the write
+// that triggered the collection, and are retried on the next one.
+func (s *Store) maybeGC() {
+	if s.opts.MaxSize > 0 && s.size > s.opts.MaxSize {
+		s.gc()
+	}
+}
+
+func (s *Store) gc() (int64, error) {
+	if s.opts.MaxSize <= 0 {
+		return 0, nil
+	}
+	target := int64(float64(s.opts.MaxSize) * s.opts.GCTarget)
+	var freed int64
+	for el := s.lru.Front(); el != nil && s.size > target; {
+		e := el.Value.(*entry)
+		el = el.Next()
+		if e.refs > 0 {
+			continue
+		}
+		if err := s.remove(e); err != nil {
+			return freed, err
+		}
+		freed += e.d.Size
+		s.evicted++
+	}
+	return freed, nil
+}
+
+// touch marks the entry of d as the most recently used, and returns it. s.mu must be held.
+func (s *Store) touch(d digest.Digest) *entry {
+	e := s.entries[d]
+	if e != nil {
+		s.lru.MoveToBack(e.elem)
+	}
+	return e
+}
+
+// add indexes the blob d as the most recently used. s.mu must be held.
+func (s *Store) add(d digest.Digest) {
+	e := &entry{d: d}
+	e.elem = s.lru.PushBack(e)
+	s.entries[d] = e
+	s.size += d.Size
+}
+
+// remove deletes the blob of e. s.mu must be held.
+func (s *Store) remove(e *entry) error {
+	if err := os.Remove(s.Path(e.d)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.lru.Remove(e.elem)
+	delete(s.entries, e.d)
+	s.size -= e.d.Size
+	return nil
+}
+
+// parseName parses the digest from a blob file name.
+func parseName(name string) (digest.Digest, error) {
+	i := strings.LastIndexByte(name, '_')
+	if i < 0 {
+		return digest.Digest{}, fmt.Errorf("invalid blob name %q", name)
+	}
+	size, err := strconv.ParseInt(name[i+1:], 10, 64)
+	if err != nil {
+		return digest.Digest{}, fmt.Errorf("invalid blob name %q: %v", name, err)
+	}
+	return digest.New(name[:i], size)
+}
@@ -0,0 +1,276 @@
+package localcas
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+func mustPut(t *testing.T, s *Store, blob string) digest.Digest {
+	t.Helper()
+	d, err := s.Put([]byte(blob))
+	if err != nil {
+		t.Fatalf("Put(%q) failed: %v", blob, err)
+	}
+	return d
+}
+
+func TestPutGet(t *testing.T) {
+	s, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	d := mustPut(t, s, "foo")
+	if d != digest.NewFromBlob([]byte("foo")) {
+		t.Errorf("Put() = %v, want the digest of foo", d)
+	}
+	if !s.Has(d) {
+		t.Errorf("Has(%v) = false, want true", d)
+	}
+	got, err := s.Get(d)
+	if err != nil || string(got) != "foo" {
+		t.Errorf("Get(%v) = %q, %v, want foo", d, got, err)
+	}
+	r, err := s.Open(d)
+	if err != nil {
+		t.Fatalf("Open(%v) failed: %v", d, err)
+	}
+	got, err = io.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != "foo" {
+		t.Errorf("reading %v = %q, %v, want foo", d, got, err)
+	}
+
+	missing := digest.NewFromBlob([]byte("bar"))
+	if s.Has(missing) {
+		t.Errorf("Has(%v) = true, want false", missing)
+	}
+	if _, err := s.Get(missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(%v) = %v, want ErrNotFound", missing, err)
+	}
+	if st := s.Stats(); st.Blobs != 1 || st.Size != 3 || st.Referenced != 0 {
+		t.Errorf("Stats() = %+v, want 1 unreferenced blob of 3 bytes", st)
+	}
+}
+
+func TestPutReader(t *testing.T) {
+	s, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	d := digest.NewFromBlob([]byte("foo"))
+	if err := s.PutReader(d, strings.NewReader("bar")); err == nil {
+		t.Errorf("PutReader() with mismatching contents succeeded, want error")
+	}
+	if s.Has(d) {
+		t.Errorf("Has(%v) = true after a failed write", d)
+	}
+	if err := s.PutReader(d, strings.NewReader("foo")); err != nil {
+		t.Errorf("PutReader() failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := s.PutFile(path)
+	if err != nil {
+		t.Fatalf("PutFile() failed: %v", err)
+	}
+	if got, err := s.Get(fd); err != nil || string(got) != "from file" {
+		t.Errorf("Get(%v) = %q, %v, want the file contents", fd, got, err)
+	}
+}
+
+func TestGC(t *testing.T) {
+	s, err := Open(t.TempDir(), &Options{MaxSize: 10, GCTarget: 0.5})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	a := mustPut(t, s, "aaa")
+	b := mustPut(t, s, "bbb")
+	c := mustPut(t, s, "ccc")
+	// a is now the most recently used, and b is pinned.
+	s.Has(a)
+	release, err := s.Ref(b)
+	if err != nil {
+		t.Fatalf("Ref() failed: %v", err)
+	}
+	// Going over 10 bytes shrinks the store to 5 bytes, as far as references allow.
+	d := mustPut(t, s, "dd")
+	for dg, want := range map[digest.Digest]bool{a: false, b: true, c: false, d: true} {
+		if got := s.Has(dg); got != want {
+			t.Errorf("Has(%v) = %v, want %v", dg, got, want)
+		}
+		if _, err := os.Stat(s.Path(dg)); (err == nil) != want {
+			t.Errorf("stat(%v) = %v, want present = %v", dg, err, want)
+		}
+	}
+	if st := s.Stats(); st.Size != 5 || st.Evicted != 2 || st.Referenced != 1 {
+		t.Errorf("Stats() = %+v, want 5 bytes with 2 blobs evicted and 1 referenced", st)
+	}
+
+	release()
+	if err := s.Delete(b); err != nil {
+		t.Errorf("Delete() failed: %v", err)
+	}
+	if s.Has(b) {
+		t.Errorf("Has(%v) = true after Delete()", b)
+	}
+}
+
+func TestDeleteReferenced(t *testing.T) {
+	s, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	d := mustPut(t, s, "foo")
+	r, err := s.Open(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(d); err == nil {
+		t.Errorf("Delete() of an open blob succeeded, want error")
+	}
+	r.Close()
+	if err := s.Delete(d); err != nil {
+		t.Errorf("Delete() after Close() failed: %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	root := t.TempDir()
+	s, err := Open(root, nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	foo := mustPut(t, s, "foo")
+	bar := mustPut(t, s, "bar")
+	// A truncated blob and an interrupted write.
+	if err := os.Chmod(s.Path(bar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.Path(bar), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, tmpDir, "partial"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(root, nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if !s.Has(foo) || s.Has(bar) {
+		t.Errorf("after reopening, Has(foo) = %v and Has(bar) = %v, want true and false", s.Has(foo), s.Has(bar))
+	}
+	if entries, err := os.ReadDir(filepath.Join(root, tmpDir)); err != nil || len(entries) != 0 {
+		t.Errorf("temporary directory has %d entries, %v, want it empty", len(entries), err)
+	}
+}
+
+func TestLink(t *testing.T) {
+	s, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	d := mustPut(t, s, "foo")
+	path := filepath.Join(t.TempDir(), "linked")
+	if err := s.Link(d, path); err != nil {
+		t.Fatalf("Link() failed: %v", err)
+	}
+	if err := s.Delete(d); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "foo" {
+		t.Errorf("linked file = %q, %v, want foo after the blob was deleted", got, err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	root := t.TempDir()
+	s, err := Open(filepath.Join(root, "cas"), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	d := mustPut(t, s, "foo")
+	path := filepath.Join(root, "cloned")
+	if err := os.WriteFile(path, []byte("previous contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = s.Clone(d, path, 0644)
+	if errors.Is(err, ErrCloneUnsupported) {
+		t.Skipf("Clone() is not supported by the file system of %v: %v", root, err)
+	}
+	if err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "foo" {
+		t.Errorf("cloned file = %q, %v, want foo", got, err)
+	}
+	if err := os.WriteFile(path, []byte("bar"), 0644); err != nil {
+		t.Fatalf("cloned file is not writable: %v", err)
+	}
+	if got, err := s.Get(d); err != nil || string(got) != "foo" {
+		t.Errorf("Get() = %q, %v, want foo after the clone was modified", got, err)
+	}
+}
+
+func TestCloneMissing(t *testing.T) {
+	s, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := s.Clone(digest.NewFromBlob([]byte("foo")), filepath.Join(t.TempDir(), "cloned"), 0644); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Clone() = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConcurrentGC(t *testing.T) {
+	s, err := Open(t.TempDir(), &Options{MaxSize: 100})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				blob := []byte(fmt.Sprintf("blob-%d-%d", i, j%10))
+				d, err := s.Put(blob)
+				if err != nil {
+					t.Errorf("Put() failed: %v", err)
+					return
+				}
+				// A blob may be collected right after being written, but never while referenced.
+				r, err := s.Open(d)
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("Open() failed: %v", err)
+					return
+				}
+				got, err := io.ReadAll(r)
+				r.Close()
+				if err != nil || !bytes.Equal(got, blob) {
+					t.Errorf("reading %v = %q, %v, want %q", d, got, err, blob)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if st := s.Stats(); st.Size > 100 || st.Referenced != 0 {
+		t.Errorf("Stats() = %+v, want at most 100 bytes and no references", st)
+	}
+}
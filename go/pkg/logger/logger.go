@@ -0,0 +1,158 @@
+// Package logger defines the logging interface used by the SDK, so that binaries and services
+// embedding it can route its logs into their own logging stack. By default, logs go to glog.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/golang/glog"
+)
+
+// Level is the severity of a log message. Levels above Info are verbose info levels, which are
+// only logged if the verbosity is at least that high, like glog's V levels.
+type Level int
+
+const (
+	// Error is the level of errors.
+	Error Level = -2
+	// Warning is the level of warnings.
+	Warning Level = -1
+	// Info is the level of info messages that are always logged.
+	Info Level = 0
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case Error:
+		return "ERROR"
+	case Warning:
+		return "WARNING"
+	case Info:
+		return "INFO"
+	default:
+		return fmt.Sprintf("V%d", int(l))
+	}
+}
+
+// Field is a key-value pair attached to a structured log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a Field with the given key and value.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger is the interface of log sinks used by the SDK.
+type Logger interface {
+	// Enabled reports whether messages at the given level are logged. It lets callers skip
+	// formatting messages that would be dropped.
+	Enabled(level Level) bool
+	// Log logs a message at the given level. The context is the one of the operation being
+	// logged, which may carry request metadata to correlate logs with. Calldepth is the number of
+	// stack frames between the call to Log and the code that the message should be attributed to.
+	Log(ctx context.Context, calldepth int, level Level, msg string, fields ...Field)
+}
+
+var (
+	mu  sync.RWMutex
+	def Logger = Glog{}
+)
+
+// Default returns the Logger used by the SDK.
+func Default() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return def
+}
+
+// SetDefault sets the Logger used by the SDK. A nil Logger restores the glog default.
+func SetDefault(l Logger) {
+	if l == nil {
+		l = Glog{}
+	}
+	mu.Lock()
+	def = l
+	mu.Unlock()
+}
+
+// V reports whether messages at the given level are logged by the default Logger.
+func V(level Level) bool {
+	return Default().Enabled(level)
+}
+
+// Logf formats and logs a message at the given level with the default Logger.
+func Logf(ctx context.Context, level Level, format string, args ...interface{}) {
+	LogDepth(ctx, 1, level, fmt.Sprintf(format, args...))
+}
+
+// LogDepth logs a message with the default Logger, attributing it to the caller calldepth frames
+// up from the caller of LogDepth.
+func LogDepth(ctx context.Context, calldepth int, level Level, msg string, fields ...Field) {
+	if l := Default(); l.Enabled(level) {
+		l.Log(ctx, calldepth+1, level, msg, fields...)
+	}
+}
+
+// Infof logs a message at Info level with the default Logger.
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	LogDepth(ctx, 1, Info, fmt.Sprintf(format, args...))
+}
+
+// Warningf logs a message at Warning level with the default Logger.
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	LogDepth(ctx, 1, Warning, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a message at Error level with the default Logger.
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	LogDepth(ctx, 1, Error, fmt.Sprintf(format, args...))
+}
+
+// Glog is a Logger that writes to glog. Fields are appended to the message as key=value pairs.
+type Glog struct{}
+
+// Enabled reports whether glog logs messages at the given level.
+func (Glog) Enabled(level Level) bool {
+	return level <= Info || bool(log.V(log.Level(level)))
+}
+
+// Log writes the message to glog.
+func (Glog) Log(_ context.Context, calldepth int, level Level, msg string, fields ...Field) {
+	msg = withFields(msg, fields)
+	switch {
+	case level <= Error:
+		log.ErrorDepth(calldepth+1, msg)
+	case level == Warning:
+		log.WarningDepth(calldepth+1, msg)
+	default:
+		log.InfoDepth(calldepth+1, msg)
+	}
+}
+
+func withFields(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
+
+// Nop is a Logger that discards all messages.
+type Nop struct{}
+
+// Enabled returns false.
+func (Nop) Enabled(Level) bool { return false }
+
+// Log does nothing.
+func (Nop) Log(context.Context, int, Level, string, ...Field) {}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingLogger struct {
+	maxLevel Level
+	msgs     []string
+}
+
+func (l *recordingLogger) Enabled(level Level) bool {
+	return level <= l.maxLevel
+}
+
+func (l *recordingLogger) Log(_ context.Context, _ int, level Level, msg string, fields ...Field) {
+	l.msgs = append(l.msgs, fmt.Sprintf("%v %s", level, withFields(msg, fields)))
+}
+
+func TestSetDefault(t *testing.T) {
+	rec := &recordingLogger{maxLevel: 1}
+	SetDefault(rec)
+	defer SetDefault(nil)
+
+	ctx := context.Background()
+	Infof(ctx, "info %d", 1)
+	Warningf(ctx, "warning")
+	Errorf(ctx, "error")
+	Logf(ctx, 1, "verbose %s", "one")
+	Logf(ctx, 2, "verbose two")
+	LogDepth(ctx, 0, Info, "with fields", F("action_id", "a"), F("n", 3))
+
+	want := []string{
+		"INFO info 1",
+		"WARNING warning",
+		"ERROR error",
+		"V1 verbose one",
+		"INFO with fields action_id=a n=3",
+	}
+	if diff := cmp.Diff(want, rec.msgs); diff != "" {
+		t.Errorf("logged messages diff (-want +got):\n%s", diff)
+	}
+	if !V(1) || V(2) {
+		t.Errorf("V(1), V(2) = %v, %v, want true, false", V(1), V(2))
+	}
+}
+
+func TestSetDefaultNilRestoresGlog(t *testing.T) {
+	SetDefault(Nop{})
+	SetDefault(nil)
+	if _, ok := Default().(Glog); !ok {
+		t.Errorf("Default() = %T, want Glog", Default())
+	}
+}
@@ -107,3 +107,50 @@ func (m *StringListValue) Set(s string) error {
 func (m *StringListValue) Get() interface{} {
 	return []string(*m)
 }
+
+// StringListMapValue is a repeatable command line flag that interprets each occurrence of a string
+// in the format key=value as a value for key. A key may be given several times, accumulating values.
+type StringListMapValue map[string][]string
+
+// String retrieves the flag's map in the format key1=value1,key1=value2,key2=value3, sorted by keys.
+func (m *StringListMapValue) String() string {
+	keys := make([]string, 0, len(*m))
+	for key := range *m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, key := range keys {
+		for _, v := range (*m)[key] {
+			if b.Len() > 0 {
+				b.WriteRune(',')
+			}
+			b.WriteString(key)
+			b.WriteRune('=')
+			b.WriteString(v)
+		}
+	}
+	return b.String()
+}
+
+// Set adds a value for a key, given in the format key=value. Only the first = separates the key
+// from the value, so the value may contain further = characters.
+func (m *StringListMapValue) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return fmt.Errorf("wrong format for key-value pair: %v", s)
+	}
+	if i == 0 {
+		return fmt.Errorf("key not provided")
+	}
+	if *m == nil {
+		*m = make(map[string][]string)
+	}
+	(*m)[s[:i]] = append((*m)[s[:i]], s[i+1:])
+	return nil
+}
+
+// Get returns the flag value as a map of string lists.
+func (m *StringListMapValue) Get() interface{} {
+	return map[string][]string(*m)
+}
@@ -225,3 +225,31 @@ func TestListValueSet(t *testing.T) {
 		})
 	}
 }
+
+func TestListMapValueSet(t *testing.T) {
+	var m map[string][]string
+	mv := (*StringListMapValue)(&m)
+	for _, s := range []string{"key1=value1", "key2=a=b", "key1=value2"} {
+		if err := mv.Set(s); err != nil {
+			t.Errorf("StringListMapValue.Set(%v) returned error: %v", s, err)
+		}
+	}
+	want := map[string][]string{"key1": {"value1", "value2"}, "key2": {"a=b"}}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("StringListMapValue.Set() produced diff in map, (-want +got): %s", diff)
+	}
+	wantStr := "key1=value1,key1=value2,key2=a=b"
+	if got := mv.String(); got != wantStr {
+		t.Errorf("StringListMapValue.String() = %q, want %q", got, wantStr)
+	}
+}
+
+func TestListMapValueSetErrors(t *testing.T) {
+	for _, s := range []string{"novalue", "=val"} {
+		var m map[string][]string
+		mv := (*StringListMapValue)(&m)
+		if err := mv.Set(s); err == nil {
+			t.Errorf("StringListMapValue.Set(%v) = nil, want error", s)
+		}
+	}
+}
@@ -146,15 +146,30 @@ func (sb *syncedBuffer) Reset() {
 }
 
 type compressedSeeker struct {
-	fs    ReadSeeker
-	encdW *syncpool.EncoderWrapper
+	fs       ReadSeeker
+	encoders *sync.Pool
+	encdW    *syncpool.EncoderWrapper
 	// This keeps the compressed data
 	buf *syncedBuffer
 	err error
 }
 
-var encoderInit sync.Once
-var encoders *sync.Pool
+var encoderPoolsMu sync.Mutex
+
+// The pools of encoders, one per compression level.
+var encoderPools = make(map[zstd.EncoderLevel]*sync.Pool)
+
+// encoderPool returns the pool of encoders of the given level.
+func encoderPool(level zstd.EncoderLevel) *sync.Pool {
+	encoderPoolsMu.Lock()
+	defer encoderPoolsMu.Unlock()
+	p, ok := encoderPools[level]
+	if !ok {
+		p = syncpool.NewEncoderPool(zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(level))
+		encoderPools[level] = p
+	}
+	return p
+}
 
 // NewCompressedFileSeeker creates a ReadSeeker based on a file path.
 func NewCompressedFileSeeker(path string, buffsize int) (ReadSeeker, error) {
@@ -163,14 +178,17 @@ func NewCompressedFileSeeker(path string, buffsize int) (ReadSeeker, error) {
 
 // NewCompressedSeeker wraps a ReadSeeker to compress its data on the fly.
 func NewCompressedSeeker(fs ReadSeeker) (ReadSeeker, error) {
+	return NewCompressedSeekerLevel(fs, zstd.SpeedDefault)
+}
+
+// NewCompressedSeekerLevel wraps a ReadSeeker to compress its data on the fly with the given
+// zstd compression level.
+func NewCompressedSeekerLevel(fs ReadSeeker, level zstd.EncoderLevel) (ReadSeeker, error) {
 	if _, ok := fs.(*compressedSeeker); ok {
 		return nil, errors.New("trying to double compress files")
 	}
 
-	encoderInit.Do(func() {
-		encoders = syncpool.NewEncoderPool(zstd.WithEncoderConcurrency(1))
-	})
-
+	encoders := encoderPool(level)
 	buf := bytes.NewBuffer(nil)
 	sb := &syncedBuffer{buf: buf}
 
@@ -182,10 +200,11 @@ func NewCompressedSeeker(fs ReadSeeker) (ReadSeeker, error) {
 
 	encdW.Encoder.Reset(sb)
 	return &compressedSeeker{
-		fs:    fs,
-		encdW: encdW,
-		buf:   sb,
-		err:   nil,
+		fs:       fs,
+		encoders: encoders,
+		encdW:    encdW,
+		buf:      sb,
+		err:      nil,
 	}, nil
 }
 
@@ -219,7 +238,7 @@ func (cfs *compressedSeeker) Read(p []byte) (int, error) {
 	var errC error
 	if cfs.err != nil || errR == io.EOF {
 		errC = cfs.encdW.Encoder.Close()
-		encoders.Put(cfs.encdW)
+		cfs.encoders.Put(cfs.encdW)
 		cfs.encdW = nil
 	}
 
@@ -237,16 +256,18 @@ func (cfs *compressedSeeker) Read(p []byte) (int, error) {
 }
 
 func (cfs *compressedSeeker) SeekOffset(offset int64) error {
+	// Also clear the io.EOF of the previous pass, if any.
+	cfs.err = nil
 	cfs.buf.Reset()
 	if cfs.encdW == nil {
-		encdIntf := encoders.Get()
+		encdIntf := cfs.encoders.Get()
 		var ok bool
 		cfs.encdW, ok = encdIntf.(*syncpool.EncoderWrapper)
 		if !ok || cfs.encdW == nil {
 			return errors.New("failed to get a new encoder")
 		}
 	} else if err := cfs.encdW.Encoder.Close(); err != nil {
-		encoders.Put(cfs.encdW)
+		cfs.encoders.Put(cfs.encdW)
 		cfs.encdW = nil
 		return err
 	}
@@ -0,0 +1,107 @@
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+type sectionSeeker struct {
+	reader *bufio.Reader
+
+	open        func() (io.ReaderAt, io.Closer, error)
+	ra          io.ReaderAt
+	closer      io.Closer
+	offset      int64
+	length      int64
+	buffSize    int
+	seekOffset  int64
+	initialized bool
+}
+
+// NewReaderAtSeeker returns a ReadSeeker over the length bytes of ra starting at offset. Closing
+// the returned reader does not close ra.
+func NewReaderAtSeeker(ra io.ReaderAt, offset, length int64, buffsize int) ReadSeeker {
+	return &sectionSeeker{
+		open:     func() (io.ReaderAt, io.Closer, error) { return ra, nil, nil },
+		offset:   offset,
+		length:   length,
+		buffSize: buffsize,
+	}
+}
+
+// NewFileRangeReadSeeker returns a ReadSeeker over the length bytes of the file at path starting
+// at offset. Like with NewFileReadSeeker, the file is only opened by Initialize.
+func NewFileRangeReadSeeker(path string, offset, length int64, buffsize int) ReadSeeker {
+	return &sectionSeeker{
+		open: func() (io.ReaderAt, io.Closer, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			return f, f, nil
+		},
+		offset:   offset,
+		length:   length,
+		buffSize: buffsize,
+	}
+}
+
+// Close closes the underlying file, if any. The reader can still be reopened with Initialize().
+func (s *sectionSeeker) Close() (err error) {
+	s.initialized = false
+	if s.closer != nil {
+		err = s.closer.Close()
+	}
+	s.ra = nil
+	s.closer = nil
+	s.reader = nil
+	return err
+}
+
+// Read implements io.Reader.
+func (s *sectionSeeker) Read(p []byte) (int, error) {
+	if !s.IsInitialized() {
+		return 0, errors.New("Not yet initialized")
+	}
+	return s.reader.Read(p)
+}
+
+// SeekOffset sets the offset of the next Read relative to the start of the range. Like for file
+// readers, it requires a call to Initialize and errors lazily.
+func (s *sectionSeeker) SeekOffset(offset int64) error {
+	s.seekOffset = offset
+	s.initialized = false
+	return nil
+}
+
+// IsInitialized indicates whether this reader is ready. If false, Read calls will fail.
+func (s *sectionSeeker) IsInitialized() bool {
+	return s.initialized
+}
+
+// Initialize does the required IO pre-work for Read calls to function.
+func (s *sectionSeeker) Initialize() error {
+	if s.initialized {
+		return errors.New("Already initialized")
+	}
+	if s.seekOffset < 0 || s.seekOffset > s.length {
+		return fmt.Errorf("seek offset %d is out of the range of length %d", s.seekOffset, s.length)
+	}
+	if s.ra == nil {
+		var err error
+		if s.ra, s.closer, err = s.open(); err != nil {
+			return err
+		}
+	}
+	sr := io.NewSectionReader(s.ra, s.offset+s.seekOffset, s.length-s.seekOffset)
+	if s.reader == nil {
+		s.reader = bufio.NewReaderSize(sr, s.buffSize)
+	} else {
+		s.reader.Reset(sr)
+	}
+	s.initialized = true
+	return nil
+}
@@ -0,0 +1,64 @@
+package reader
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/testutil"
+)
+
+func TestSectionSeekers(t *testing.T) {
+	t.Parallel()
+	blob := "0123456789"
+	path, err := testutil.CreateFile(t, false, blob)
+	if err != nil {
+		t.Fatalf("Failed to make temp file: %v", err)
+	}
+	readers := map[string]ReadSeeker{
+		"ReaderAt":  NewReaderAtSeeker(strings.NewReader(blob), 2, 5, 2),
+		"FileRange": NewFileRangeReadSeeker(path, 2, 5, 2),
+	}
+	for name, r := range readers {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer r.Close()
+			if _, err := r.Read(make([]byte, 1)); err == nil {
+				t.Errorf("Read() should have err'd on uninitialized reader")
+			}
+			if err := r.Initialize(); err != nil {
+				t.Fatalf("Initialize() failed: %v", err)
+			}
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() failed: %v", err)
+			}
+			if string(got) != "23456" {
+				t.Errorf("ReadAll() = %q, want %q", got, "23456")
+			}
+
+			if err := r.SeekOffset(3); err != nil {
+				t.Fatalf("SeekOffset(3) failed: %v", err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+			if err := r.Initialize(); err != nil {
+				t.Fatalf("Initialize() after SeekOffset failed: %v", err)
+			}
+			got, err = ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() failed: %v", err)
+			}
+			if string(got) != "56" {
+				t.Errorf("ReadAll() after SeekOffset(3) = %q, want %q", got, "56")
+			}
+
+			r.SeekOffset(6)
+			if err := r.Initialize(); err == nil {
+				t.Errorf("Initialize() after seeking past the range succeeded, want error")
+			}
+		})
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"sync"
 	"time"
 
-	log "github.com/golang/glog"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -98,9 +98,9 @@ func WithPolicy(ctx context.Context, shouldRetry ShouldRetry, bp BackoffPolicy,
 			return err
 		}
 
-		if log.V(1) {
+		if logger.V(1) {
 			// This log depth is custom-tailored to the SDK usage, which always calls the retrier from within client.CallWithTimeout.
-			log.InfoDepth(3, fmt.Sprintf("call failed with err=%v, retrying.", err))
+			logger.LogDepth(ctx, 3, 1, fmt.Sprintf("call failed with err=%v, retrying.", err))
 		}
 
 		if attempts+1 == int(bp.maxAttempts) {
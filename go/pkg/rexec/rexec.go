@@ -15,6 +15,7 @@ import (
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -73,23 +74,22 @@ func (c *Client) NewContext(ctx context.Context, cmd *command.Command, opt *comm
 	}, nil
 }
 
-func (ec *Context) downloadStream(raw []byte, dgPb *repb.Digest, write func([]byte)) error {
+func (ec *Context) downloadStream(raw []byte, dgPb *repb.Digest, write func([]byte)) (*rc.MovedBytesMetadata, error) {
 	if raw != nil {
 		write(raw)
 	} else if dgPb != nil {
 		dg, err := digest.NewFromProto(dgPb)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		bytes, stats, err := ec.client.GrpcClient.ReadBlob(ec.ctx, dg)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		ec.Metadata.LogicalBytesDownloaded += stats.LogicalMoved
-		ec.Metadata.RealBytesDownloaded += stats.RealMoved
 		write(bytes)
+		return stats, nil
 	}
-	return nil
+	return &rc.MovedBytesMetadata{}, nil
 }
 
 func (ec *Context) setOutputMetadata() {
@@ -123,16 +123,60 @@ func (ec *Context) setOutputMetadata() {
 	}
 }
 
+// downloadOutErr downloads the stdout and stderr of the action concurrently, writing each to the
+// OutErr as soon as it arrives.
 func (ec *Context) downloadOutErr() *command.Result {
-	if err := ec.downloadStream(ec.resPb.StdoutRaw, ec.resPb.StdoutDigest, ec.oe.WriteOut); err != nil {
+	var stdoutStats, stderrStats *rc.MovedBytesMetadata
+	eg := &errgroup.Group{}
+	eg.Go(func() (err error) {
+		stdoutStats, err = ec.downloadStream(ec.resPb.StdoutRaw, ec.resPb.StdoutDigest, ec.oe.WriteOut)
+		return err
+	})
+	eg.Go(func() (err error) {
+		stderrStats, err = ec.downloadStream(ec.resPb.StderrRaw, ec.resPb.StderrDigest, ec.oe.WriteErr)
+		return err
+	})
+	if err := eg.Wait(); err != nil {
 		return command.NewRemoteErrorResult(err)
 	}
-	if err := ec.downloadStream(ec.resPb.StderrRaw, ec.resPb.StderrDigest, ec.oe.WriteErr); err != nil {
-		return command.NewRemoteErrorResult(err)
+	for _, stats := range []*rc.MovedBytesMetadata{stdoutStats, stderrStats} {
+		ec.Metadata.LogicalBytesDownloaded += stats.LogicalMoved
+		ec.Metadata.RealBytesDownloaded += stats.RealMoved
 	}
 	return command.NewResultFromExitCode((int)(ec.resPb.ExitCode))
 }
 
+// downloadResults downloads the stdout and stderr and the outputs of the action, as requested by
+// the ExecutionOptions. Stdout and stderr are fetched concurrently with the outputs and delivered
+// to the OutErr without waiting for them, so that diagnostics are shown before a large output tree
+// finishes transferring. Their reads do not wait for a CAS download slot, so they are not queued
+// behind the output files.
+func (ec *Context) downloadResults() *command.Result {
+	res := command.NewResultFromExitCode((int)(ec.resPb.ExitCode))
+	oeRes := make(chan *command.Result, 1)
+	if ec.opt.DownloadOutErr {
+		go func() { oeRes <- ec.downloadOutErr() }()
+	} else {
+		oeRes <- res
+	}
+	var stats *rc.MovedBytesMetadata
+	if ec.opt.DownloadOutputs {
+		cmdID, executionID := ec.cmd.Identifiers.ExecutionID, ec.cmd.Identifiers.CommandID
+		log.V(1).Infof("%s %s> Downloading outputs...", cmdID, executionID)
+		stats, res = ec.downloadOutputs(ec.cmd.ExecRoot)
+	}
+	// The stdout and stderr errors take precedence, and their download must be done before the
+	// download stats are updated.
+	if r := <-oeRes; r.Err != nil {
+		return r
+	}
+	if stats != nil {
+		ec.Metadata.LogicalBytesDownloaded += stats.LogicalMoved
+		ec.Metadata.RealBytesDownloaded += stats.RealMoved
+	}
+	return res
+}
+
 func (ec *Context) downloadOutputs(outDir string) (*rc.MovedBytesMetadata, *command.Result) {
 	ec.Metadata.EventTimes[command.EventDownloadResults] = &command.TimeInterval{From: time.Now()}
 	defer func() { ec.Metadata.EventTimes[command.EventDownloadResults].To = time.Now() }()
@@ -223,15 +267,7 @@ func (ec *Context) GetCachedResult() {
 		ec.setOutputMetadata()
 		cmdID, executionID := ec.cmd.Identifiers.ExecutionID, ec.cmd.Identifiers.CommandID
 		log.V(1).Infof("%s %s> Found cached result, downloading outputs...", cmdID, executionID)
-		if ec.opt.DownloadOutErr {
-			ec.Result = ec.downloadOutErr()
-		}
-		if ec.Result.Err == nil && ec.opt.DownloadOutputs {
-			stats, res := ec.downloadOutputs(ec.cmd.ExecRoot)
-			ec.Metadata.LogicalBytesDownloaded += stats.LogicalMoved
-			ec.Metadata.RealBytesDownloaded += stats.RealMoved
-			ec.Result = res
-		}
+		ec.Result = ec.downloadResults()
 		if ec.Result.Err == nil {
 			ec.Result.Status = command.CacheHitResultStatus
 		}
@@ -350,17 +386,7 @@ func (ec *Context) ExecuteRemotely() {
 
 	if ec.resPb != nil {
 		ec.setOutputMetadata()
-		ec.Result = command.NewResultFromExitCode((int)(ec.resPb.ExitCode))
-		if ec.opt.DownloadOutErr {
-			ec.Result = ec.downloadOutErr()
-		}
-		if ec.Result.Err == nil && ec.opt.DownloadOutputs {
-			log.V(1).Infof("%s %s> Downloading outputs...", cmdID, executionID)
-			stats, res := ec.downloadOutputs(ec.cmd.ExecRoot)
-			ec.Metadata.LogicalBytesDownloaded += stats.LogicalMoved
-			ec.Metadata.RealBytesDownloaded += stats.RealMoved
-			ec.Result = res
-		}
+		ec.Result = ec.downloadResults()
 		if resp.CachedResult && ec.Result.Err == nil {
 			ec.Result.Status = command.CacheHitResultStatus
 		}
@@ -451,8 +477,14 @@ func setTimingMetadata(cm *command.Metadata, em *repb.ExecutedActionMetadata) {
 	setEventTimes(cm, command.EventServerWorkerOutputUpload, em.OutputUploadStartTimestamp, em.OutputUploadCompletedTimestamp)
 }
 
-// Run executes a command remotely.
+// Run executes a command remotely, and records it in the build stats of the client.
 func (c *Client) Run(ctx context.Context, cmd *command.Command, opt *command.ExecutionOptions, oe outerr.OutErr) (*command.Result, *command.Metadata) {
+	res, md := c.run(ctx, cmd, opt, oe)
+	c.GrpcClient.RecordAction(res, md)
+	return res, md
+}
+
+func (c *Client) run(ctx context.Context, cmd *command.Command, opt *command.ExecutionOptions, oe outerr.OutErr) (*command.Result, *command.Metadata) {
 	ec, err := c.NewContext(ctx, cmd, opt, oe)
 	if err != nil {
 		return command.NewLocalErrorResult(err), &command.Metadata{}
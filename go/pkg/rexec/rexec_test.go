@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
@@ -574,3 +576,59 @@ func TestDownloadResults(t *testing.T) {
 		t.Errorf("DownloadOutputs() stderr = %v, want 'stderr'", string(oe.Stderr()))
 	}
 }
+
+// signalingOutErr closes stdoutWritten on the first write to stdout.
+type signalingOutErr struct {
+	*outerr.RecordingOutErr
+	once          sync.Once
+	stdoutWritten chan struct{}
+}
+
+func (oe *signalingOutErr) WriteOut(buf []byte) {
+	oe.RecordingOutErr.WriteOut(buf)
+	oe.once.Do(func() { close(oe.stdoutWritten) })
+}
+
+func TestOutErrDeliveredBeforeOutputs(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"tool"},
+		ExecRoot:    e.ExecRoot,
+		OutputFiles: []string{"out"},
+	}
+	opt := command.DefaultExecutionOptions()
+	wantRes := &command.Result{Status: command.CacheHitResultStatus}
+	e.Set(cmd, opt, wantRes, &fakes.OutputFile{Path: "out", Contents: "output"}, fakes.StdOut("stdout"))
+	oe := &signalingOutErr{RecordingOutErr: outerr.NewRecordingOutErr(), stdoutWritten: make(chan struct{})}
+	// The stdout is only served once the output file is requested, which is only served once the
+	// stdout was delivered.
+	outputRequested := make(chan struct{})
+	e.Server.CAS.PerDigestBlockFn[digest.NewFromBlob([]byte("stdout"))] = func() {
+		select {
+		case <-outputRequested:
+		case <-time.After(10 * time.Second):
+			t.Error("stdout was requested, but the output file was not requested concurrently")
+		}
+	}
+	e.Server.CAS.PerDigestBlockFn[digest.NewFromBlob([]byte("output"))] = func() {
+		close(outputRequested)
+		select {
+		case <-oe.stdoutWritten:
+		case <-time.After(10 * time.Second):
+			t.Error("output file was requested, but stdout was not delivered")
+		}
+	}
+
+	res, _ := e.Client.Run(context.Background(), cmd, opt, oe)
+	if diff := cmp.Diff(wantRes, res); diff != "" {
+		t.Errorf("Run() gave result diff (-want +got):\n%s", diff)
+	}
+	if string(oe.Stdout()) != "stdout" {
+		t.Errorf("Run() stdout = %q, want \"stdout\"", oe.Stdout())
+	}
+	path := filepath.Join(e.ExecRoot, "out")
+	if contents, err := ioutil.ReadFile(path); err != nil || string(contents) != "output" {
+		t.Errorf("ReadFile(%v) = %q, %v, want \"output\"", path, contents, err)
+	}
+}
@@ -0,0 +1,248 @@
+package rpclog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/logger"
+)
+
+// Options configures what a Logger records.
+type Options struct {
+	// MaxSummaryLen is the maximum length of the request and response summaries.
+	MaxSummaryLen int
+	// MaxDigests is the maximum number of digests recorded per RPC.
+	MaxDigests int
+}
+
+// DefaultOptions returns the default options.
+func DefaultOptions() Options {
+	return Options{MaxSummaryLen: 256, MaxDigests: 100}
+}
+
+// Logger writes RPC records to a log. Install its interceptors on a connection, e.g. with
+// client.WithInterceptors, to record the RPCs made on it.
+//
+// Each record is written with a single Write call as soon as its RPC completes, so a log remains
+// readable up to the last completed RPC if the process crashes.
+//
+// Goroutine-safe.
+type Logger struct {
+	opts Options
+
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+	buf    []byte
+}
+
+// NewLogger returns a logger writing to w with the default options.
+func NewLogger(w io.Writer) (*Logger, error) {
+	return NewLoggerWithOptions(w, DefaultOptions())
+}
+
+// NewLoggerWithOptions returns a logger writing to w.
+func NewLoggerWithOptions(w io.Writer, opts Options) (*Logger, error) {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, err
+	}
+	return &Logger{opts: opts, w: w}, nil
+}
+
+// Create creates the file at path, truncating it if it exists, and returns a logger writing to it.
+// The file is closed by Logger.Close.
+func Create(path string, opts Options) (*Logger, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l, err := NewLoggerWithOptions(f, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.closer = f
+	return l, nil
+}
+
+// Close stops the logging. It returns the first error encountered while writing records, if any,
+// and closes the file of loggers created with Create. RPCs completing after Close are not logged.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.err
+	if l.err == nil {
+		l.err = fmt.Errorf("logger is closed")
+	}
+	if l.closer != nil {
+		if cerr := l.closer.Close(); err == nil {
+			err = cerr
+		}
+		l.closer = nil
+	}
+	return err
+}
+
+// Write writes a record to the log. It is called by the interceptors for every completed RPC, and
+// can be used to add records for RPCs made on connections without the interceptors.
+func (l *Logger) Write(r *Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	l.buf = r.marshal(l.buf[:0])
+	if _, err := l.w.Write(l.buf); err != nil {
+		logger.Warningf(context.Background(), "rpclog: failed to write record, disabling RPC logging: %v", err)
+		l.err = err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that records unary RPCs.
+func (l *Logger) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		rec := &recorder{l: l, r: &Record{Method: method, StartTime: time.Now()}}
+		rec.sent(req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			rec.received(reply)
+		}
+		rec.finish(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that records streaming RPCs. A stream is recorded
+// once its status is received, or once ctx is done.
+func (l *Logger) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		rec := &recorder{l: l, r: &Record{Method: method, Stream: true, StartTime: time.Now()}}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			rec.finish(err)
+			return nil, err
+		}
+		s := &loggedStream{ClientStream: cs, rec: rec, serverStreams: desc.ServerStreams, done: make(chan struct{})}
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.finish(ctx.Err())
+			case <-s.done:
+			}
+		}()
+		return s, nil
+	}
+}
+
+// recorder accumulates the record of an RPC in flight.
+type recorder struct {
+	l *Logger
+
+	mu      sync.Mutex
+	r       *Record
+	digests map[digest.Digest]bool
+}
+
+func (rec *recorder) sent(m interface{}) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.RequestCount++
+	if pm, ok := m.(proto.Message); ok {
+		rec.r.RequestBytes += int64(proto.Size(pm))
+		if rec.r.RequestCount == 1 {
+			rec.r.Request = summarize(pm, rec.l.opts.MaxSummaryLen)
+		}
+		rec.addDigests(pm)
+	}
+}
+
+func (rec *recorder) received(m interface{}) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.ResponseCount++
+	if pm, ok := m.(proto.Message); ok {
+		rec.r.ResponseBytes += int64(proto.Size(pm))
+		rec.r.Response = summarize(pm, rec.l.opts.MaxSummaryLen)
+		rec.addDigests(pm)
+	}
+}
+
+func (rec *recorder) addDigests(m proto.Message) {
+	collectDigests(m.ProtoReflect(), func(d digest.Digest) bool {
+		if len(rec.r.Digests) >= rec.l.opts.MaxDigests {
+			return false
+		}
+		if !rec.digests[d] {
+			if rec.digests == nil {
+				rec.digests = make(map[digest.Digest]bool)
+			}
+			rec.digests[d] = true
+			rec.r.Digests = append(rec.r.Digests, d)
+		}
+		return true
+	})
+}
+
+func (rec *recorder) finish(err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.Duration = time.Since(rec.r.StartTime)
+	st, ok := status.FromError(err)
+	if !ok {
+		st = status.FromContextError(err)
+	}
+	rec.r.Code = st.Code()
+	rec.r.Error = st.Message()
+	rec.l.Write(rec.r)
+}
+
+// loggedStream records the messages of a stream, and the stream once it is complete.
+type loggedStream struct {
+	grpc.ClientStream
+	rec           *recorder
+	serverStreams bool
+	once          sync.Once
+	done          chan struct{}
+}
+
+func (s *loggedStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.rec.sent(m)
+	}
+	return err
+}
+
+func (s *loggedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	default:
+		s.rec.received(m)
+		if !s.serverStreams {
+			// The single response of a client-streaming RPC completes the stream.
+			s.finish(nil)
+		}
+	}
+	return err
+}
+
+func (s *loggedStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.rec.finish(err)
+	})
+}
@@ -0,0 +1,176 @@
+package rpclog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// maxRecordSize bounds the size of a record, to fail fast on corrupted logs.
+const maxRecordSize = 64 * 1024 * 1024
+
+// Reader reads the records of a log.
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewReader returns a reader of the log in r. It returns an error wrapping ErrInvalidLog if r
+// does not start with a log header.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, hdr); err != nil || string(hdr) != magic {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidLog)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record. It returns io.EOF at the end of the log, and
+// io.ErrUnexpectedEOF if the last record is truncated, e.g. because the process writing the log
+// crashed.
+func (r *Reader) Next() (*Record, error) {
+	n, err := readUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxRecordSize {
+		return nil, fmt.Errorf("%w: record of %d bytes", ErrInvalidLog, n)
+	}
+	if cap(r.buf) < int(n) {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:n]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	rec := &Record{}
+	if err := rec.unmarshal(r.buf); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// readUvarint reads a varint, returning io.EOF only if there is no data left at all.
+func readUvarint(r io.ByteReader) (uint64, error) {
+	var b []byte
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF && len(b) > 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		b = append(b, c)
+		if c < 0x80 {
+			break
+		}
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidLog, protowire.ParseError(n))
+	}
+	return v, nil
+}
+
+// ReadFile reads all the records of the log file at path. A truncated last record is ignored.
+func ReadFile(path string) ([]*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var recs []*Record
+	for {
+		rec, err := r.Next()
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return recs, nil
+		case err != nil:
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// Query selects records. The zero value matches all records.
+type Query struct {
+	// Method matches records whose method contains it.
+	Method string
+	// ErrorsOnly matches records of failed RPCs only.
+	ErrorsOnly bool
+	// MinDuration matches records of RPCs that took at least that long.
+	MinDuration time.Duration
+	// Digest, if set, matches records referencing that digest.
+	Digest digest.Digest
+}
+
+// Match returns whether r is selected by q.
+func (q *Query) Match(r *Record) bool {
+	if !strings.Contains(r.Method, q.Method) || (q.ErrorsOnly && r.Code == codes.OK) || r.Duration < q.MinDuration {
+		return false
+	}
+	if q.Digest == (digest.Digest{}) {
+		return true
+	}
+	for _, d := range r.Digests {
+		if d == q.Digest {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodStats aggregates the records of a method.
+type MethodStats struct {
+	Method string
+	// Count is the number of RPCs, and Errors the number of failed ones.
+	Count, Errors int
+	// Total and Max are the total and maximum durations of the RPCs.
+	Total, Max time.Duration
+	// RequestBytes and ResponseBytes are the total sizes of the messages sent and received.
+	RequestBytes, ResponseBytes int64
+}
+
+// Stats aggregates records per method, sorted by decreasing total duration.
+func Stats(recs []*Record) []*MethodStats {
+	byMethod := make(map[string]*MethodStats)
+	var res []*MethodStats
+	for _, r := range recs {
+		s := byMethod[r.Method]
+		if s == nil {
+			s = &MethodStats{Method: r.Method}
+			byMethod[r.Method] = s
+			res = append(res, s)
+		}
+		s.Count++
+		if r.Code != codes.OK {
+			s.Errors++
+		}
+		s.Total += r.Duration
+		if r.Duration > s.Max {
+			s.Max = r.Duration
+		}
+		s.RequestBytes += r.RequestBytes
+		s.ResponseBytes += r.ResponseBytes
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Total > res[j].Total })
+	return res
+}
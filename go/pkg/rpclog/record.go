@@ -0,0 +1,181 @@
+// Package rpclog records the gRPC calls made by a client to a compact binary log, and reads such
+// logs back for postmortem debugging.
+//
+// A log starts with a magic header, followed by records. Each record is a protocol buffer
+// message (see Record for the field numbers) prefixed by its varint-encoded length, so logs can
+// also be decoded by generic protobuf tooling.
+package rpclog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// magic is the header of every log.
+const magic = "RPCLOG\x01\n"
+
+// ErrInvalidLog is returned when reading data that is not a valid RPC log.
+var ErrInvalidLog = errors.New("invalid RPC log")
+
+// Record is a single logged RPC. For streaming RPCs, a record covers the whole stream.
+type Record struct {
+	// Method is the full gRPC method name, e.g. /google.bytestream.ByteStream/Read.
+	Method string // field 1
+	// Stream is whether the RPC is a streaming RPC.
+	Stream bool // field 2
+	// StartTime is when the RPC was started.
+	StartTime time.Time // field 3, in Unix nanoseconds
+	// Duration is the duration of the RPC, until its status was received.
+	Duration time.Duration // field 4, in nanoseconds
+	// Code is the status code of the RPC.
+	Code codes.Code // field 5
+	// Error is the status message of a failed RPC.
+	Error string // field 6
+	// Request is a summary of the first request message.
+	Request string // field 7
+	// Response is a summary of the last response message.
+	Response string // field 8
+	// Digests are the digests referenced by the request and response messages, in order of first
+	// appearance, including the digests of ByteStream resource names.
+	Digests []digest.Digest // field 9, as hash/size strings
+	// RequestCount and ResponseCount are the numbers of messages sent and received.
+	RequestCount, ResponseCount int64 // fields 10 and 11
+	// RequestBytes and ResponseBytes are the total serialized sizes of the messages sent and
+	// received.
+	RequestBytes, ResponseBytes int64 // fields 12 and 13
+}
+
+// String returns a one line description of the record.
+func (r *Record) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %v %s", r.StartTime.Format(time.RFC3339Nano), r.Method, r.Duration, r.Code)
+	if r.Error != "" {
+		fmt.Fprintf(&sb, " %q", r.Error)
+	}
+	if r.Stream {
+		fmt.Fprintf(&sb, " sent=%d/%dB recv=%d/%dB", r.RequestCount, r.RequestBytes, r.ResponseCount, r.ResponseBytes)
+	} else {
+		fmt.Fprintf(&sb, " sent=%dB recv=%dB", r.RequestBytes, r.ResponseBytes)
+	}
+	if r.Request != "" {
+		fmt.Fprintf(&sb, " req=%s", r.Request)
+	}
+	if r.Response != "" {
+		fmt.Fprintf(&sb, " resp=%s", r.Response)
+	}
+	return sb.String()
+}
+
+// marshal appends the length-delimited encoding of r to b.
+func (r *Record) marshal(b []byte) []byte {
+	var m []byte
+	appendString := func(num protowire.Number, s string) {
+		if s != "" {
+			m = protowire.AppendTag(m, num, protowire.BytesType)
+			m = protowire.AppendString(m, s)
+		}
+	}
+	appendVarint := func(num protowire.Number, v int64) {
+		if v != 0 {
+			m = protowire.AppendTag(m, num, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(v))
+		}
+	}
+	appendString(1, r.Method)
+	if r.Stream {
+		appendVarint(2, 1)
+	}
+	if !r.StartTime.IsZero() {
+		appendVarint(3, r.StartTime.UnixNano())
+	}
+	appendVarint(4, int64(r.Duration))
+	appendVarint(5, int64(r.Code))
+	appendString(6, r.Error)
+	appendString(7, r.Request)
+	appendString(8, r.Response)
+	for _, d := range r.Digests {
+		m = protowire.AppendTag(m, 9, protowire.BytesType)
+		m = protowire.AppendString(m, d.String())
+	}
+	appendVarint(10, r.RequestCount)
+	appendVarint(11, r.ResponseCount)
+	appendVarint(12, r.RequestBytes)
+	appendVarint(13, r.ResponseBytes)
+
+	b = protowire.AppendVarint(b, uint64(len(m)))
+	return append(b, m...)
+}
+
+// unmarshal decodes a record message, without its length prefix. Unknown fields are skipped.
+func (r *Record) unmarshal(m []byte) error {
+	for len(m) > 0 {
+		num, typ, n := protowire.ConsumeTag(m)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalidLog, protowire.ParseError(n))
+		}
+		m = m[n:]
+		switch {
+		case typ == protowire.BytesType && num >= 1 && num <= 9:
+			s, n := protowire.ConsumeString(m)
+			if n < 0 {
+				return fmt.Errorf("%w: field %d: %v", ErrInvalidLog, num, protowire.ParseError(n))
+			}
+			m = m[n:]
+			switch num {
+			case 1:
+				r.Method = s
+			case 6:
+				r.Error = s
+			case 7:
+				r.Request = s
+			case 8:
+				r.Response = s
+			case 9:
+				d, err := digest.NewFromString(s)
+				if err != nil {
+					return fmt.Errorf("%w: %v", ErrInvalidLog, err)
+				}
+				r.Digests = append(r.Digests, d)
+			}
+		case typ == protowire.VarintType && num >= 2 && num <= 13:
+			u, n := protowire.ConsumeVarint(m)
+			if n < 0 {
+				return fmt.Errorf("%w: field %d: %v", ErrInvalidLog, num, protowire.ParseError(n))
+			}
+			m = m[n:]
+			v := int64(u)
+			switch num {
+			case 2:
+				r.Stream = v != 0
+			case 3:
+				r.StartTime = time.Unix(0, v)
+			case 4:
+				r.Duration = time.Duration(v)
+			case 5:
+				r.Code = codes.Code(v)
+			case 10:
+				r.RequestCount = v
+			case 11:
+				r.ResponseCount = v
+			case 12:
+				r.RequestBytes = v
+			case 13:
+				r.ResponseBytes = v
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, m)
+			if n < 0 {
+				return fmt.Errorf("%w: field %d: %v", ErrInvalidLog, num, protowire.ParseError(n))
+			}
+			m = m[n:]
+		}
+	}
+	return nil
+}
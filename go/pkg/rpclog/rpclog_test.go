@@ -0,0 +1,207 @@
+package rpclog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	bsgrpc "google.golang.org/genproto/googleapis/bytestream"
+	bspb "google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	regrpc "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestLogRoundTrip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cas := fakes.NewCAS()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	regrpc.RegisterContentAddressableStorageServer(srv, cas)
+	bsgrpc.RegisterByteStreamServer(srv, cas)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	buf := &bytes.Buffer{}
+	l, err := NewLogger(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(l.UnaryClientInterceptor()), grpc.WithStreamInterceptor(l.StreamClientInterceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	blob := []byte("hello")
+	dg := cas.Put(blob)
+	missing := digest.NewFromBlob([]byte("missing"))
+	if _, err := regrpc.NewContentAddressableStorageClient(conn).FindMissingBlobs(ctx, &repb.FindMissingBlobsRequest{
+		InstanceName: "instance",
+		BlobDigests:  []*repb.Digest{dg.ToProto(), missing.ToProto()},
+	}); err != nil {
+		t.Fatalf("FindMissingBlobs() failed: %v", err)
+	}
+	stream, err := bsgrpc.NewByteStreamClient(conn).Read(ctx, &bspb.ReadRequest{ResourceName: digest.NewReadResourceName("instance", dg)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err != io.EOF {
+		t.Fatalf("ByteStream.Read() failed: %v", err)
+	}
+	stream, err = bsgrpc.NewByteStreamClient(conn).Read(ctx, &bspb.ReadRequest{ResourceName: digest.NewReadResourceName("instance", missing)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("ByteStream.Read() of a missing blob succeeded, want error")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		got = append(got, rec)
+	}
+	want := []*Record{
+		{
+			Method:        "/build.bazel.remote.execution.v2.ContentAddressableStorage/FindMissingBlobs",
+			Request:       `FindMissingBlobsRequest{instance_name:"instance" blob_digests:[2]}`,
+			Response:      "FindMissingBlobsResponse{missing_blob_digests:[1]}",
+			Digests:       []digest.Digest{dg, missing},
+			RequestCount:  1,
+			ResponseCount: 1,
+		},
+		{
+			Method:        "/google.bytestream.ByteStream/Read",
+			Stream:        true,
+			Request:       `ReadRequest{resource_name:"instance/blobs/` + dg.Hash + `/5"}`,
+			Response:      "ReadResponse{data:<5 bytes>}",
+			Digests:       []digest.Digest{dg},
+			RequestCount:  1,
+			ResponseCount: 1,
+		},
+		{
+			Method:       "/google.bytestream.ByteStream/Read",
+			Stream:       true,
+			Code:         codes.NotFound,
+			Digests:      []digest.Digest{missing},
+			RequestCount: 1,
+		},
+	}
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(Record{}, "StartTime", "Duration", "Error", "Request", "RequestBytes", "ResponseBytes"),
+	}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Errorf("log returned diff (-want +got):\n%s", diff)
+	}
+	for i, rec := range got {
+		if rec.StartTime.IsZero() || rec.Duration <= 0 || rec.RequestBytes == 0 {
+			t.Errorf("record %d is missing timing or size: %v", i, rec)
+		}
+		if i < 2 && rec.Request != want[i].Request {
+			t.Errorf("record %d has request summary %q, want %q", i, rec.Request, want[i].Request)
+		}
+	}
+	if got[2].Error == "" {
+		t.Errorf("failed RPC record has no error message: %v", got[2])
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	l, err := NewLogger(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Write(&Record{Method: "/a", StartTime: time.Unix(1, 0)})
+	l.Write(&Record{Method: "/b", Code: codes.Unavailable, Error: "oops"})
+	data := buf.Bytes()
+
+	r, err := NewReader(bytes.NewReader(data[:len(data)-2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, err := r.Next(); err != nil || rec.Method != "/a" {
+		t.Fatalf("Next() = %v, %v, want /a record", rec, err)
+	}
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Next() on a truncated record returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if _, err := NewReader(strings.NewReader("not a log")); err == nil {
+		t.Errorf("NewReader() on invalid data succeeded, want error")
+	}
+}
+
+func TestQueryAndStats(t *testing.T) {
+	t.Parallel()
+	dg := digest.NewFromBlob([]byte("a"))
+	recs := []*Record{
+		{Method: "/s/Read", Duration: time.Second, Digests: []digest.Digest{dg}, ResponseBytes: 10},
+		{Method: "/s/Read", Duration: 3 * time.Second, Code: codes.NotFound, ResponseBytes: 5},
+		{Method: "/s/Write", Duration: time.Millisecond, RequestBytes: 7},
+	}
+	tests := []struct {
+		name string
+		q    Query
+		want []int
+	}{
+		{name: "all", want: []int{0, 1, 2}},
+		{name: "method", q: Query{Method: "Read"}, want: []int{0, 1}},
+		{name: "errors", q: Query{ErrorsOnly: true}, want: []int{1}},
+		{name: "slow", q: Query{MinDuration: time.Second}, want: []int{0, 1}},
+		{name: "digest", q: Query{Digest: dg}, want: []int{0}},
+	}
+	for _, tc := range tests {
+		var got []int
+		for i, r := range recs {
+			if tc.q.Match(r) {
+				got = append(got, i)
+			}
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%s: Match() returned diff (-want +got):\n%s", tc.name, diff)
+		}
+	}
+
+	want := []*MethodStats{
+		{Method: "/s/Read", Count: 2, Errors: 1, Total: 4 * time.Second, Max: 3 * time.Second, ResponseBytes: 15},
+		{Method: "/s/Write", Count: 1, Total: time.Millisecond, Max: time.Millisecond, RequestBytes: 7},
+	}
+	if diff := cmp.Diff(want, Stats(recs)); diff != "" {
+		t.Errorf("Stats() returned diff (-want +got):\n%s", diff)
+	}
+}
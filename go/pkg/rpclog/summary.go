@@ -0,0 +1,111 @@
+package rpclog
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+var digestName = (&repb.Digest{}).ProtoReflect().Descriptor().FullName()
+
+// summarize returns a short description of m: its type and top-level fields. Unlike the text
+// format, it is cheap for messages carrying blobs: bytes fields are described by their size,
+// repeated fields by their length, and nested messages other than digests are elided.
+func summarize(m proto.Message, maxLen int) string {
+	var sb strings.Builder
+	r := m.ProtoReflect()
+	sb.WriteString(string(r.Descriptor().Name()))
+	sb.WriteByte('{')
+	first := true
+	// Walk the fields in declaration order, so that summaries are deterministic.
+	fields := r.Descriptor().Fields()
+	for i := 0; i < fields.Len() && sb.Len() < maxLen; i++ {
+		fd := fields.Get(i)
+		if !r.Has(fd) {
+			continue
+		}
+		v := r.Get(fd)
+		if !first {
+			sb.WriteByte(' ')
+		}
+		first = false
+		sb.WriteString(string(fd.Name()))
+		sb.WriteByte(':')
+		switch {
+		case fd.IsList():
+			fmt.Fprintf(&sb, "[%d]", v.List().Len())
+		case fd.IsMap():
+			fmt.Fprintf(&sb, "{%d}", v.Map().Len())
+		case fd.Kind() == protoreflect.BytesKind:
+			fmt.Fprintf(&sb, "<%d bytes>", len(v.Bytes()))
+		case fd.Kind() == protoreflect.StringKind:
+			fmt.Fprintf(&sb, "%q", v.String())
+		case fd.Kind() == protoreflect.EnumKind:
+			if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+				sb.WriteString(string(ev.Name()))
+			} else {
+				fmt.Fprint(&sb, v.Enum())
+			}
+		case fd.Message() != nil:
+			if fd.Message().FullName() == digestName {
+				sb.WriteString(toDigest(v.Message()).String())
+			} else {
+				sb.WriteString("{...}")
+			}
+		default:
+			fmt.Fprint(&sb, v.Interface())
+		}
+	}
+	sb.WriteByte('}')
+	s := sb.String()
+	if len(s) > maxLen {
+		s = s[:maxLen] + "..."
+	}
+	return s
+}
+
+// collectDigests calls add for every digest referenced by m: Digest messages at any depth, and
+// ByteStream resource names. It stops when add returns false.
+func collectDigests(m protoreflect.Message, add func(digest.Digest) bool) bool {
+	if m.Descriptor().FullName() == digestName {
+		return add(toDigest(m))
+	}
+	cont := true
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() != nil && fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len() && cont; i++ {
+				cont = collectDigests(l.Get(i).Message(), add)
+			}
+		case fd.Message() != nil && fd.IsMap():
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				if fd.MapValue().Message() != nil {
+					cont = collectDigests(mv.Message(), add)
+				}
+				return cont
+			})
+		case fd.Message() != nil:
+			cont = collectDigests(v.Message(), add)
+		case fd.Name() == "resource_name" && fd.Kind() == protoreflect.StringKind:
+			if rn, err := digest.ParseResourceName(v.String()); err == nil {
+				cont = add(rn.Digest)
+			}
+		}
+		return cont
+	})
+	return cont
+}
+
+func toDigest(m protoreflect.Message) digest.Digest {
+	fields := m.Descriptor().Fields()
+	return digest.Digest{
+		Hash: m.Get(fields.ByName("hash")).String(),
+		Size: m.Get(fields.ByName("size_bytes")).Int(),
+	}
+}
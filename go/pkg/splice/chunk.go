@@ -0,0 +1,61 @@
+package splice
+
+import "math/bits"
+
+const (
+	// MinChunkSize is the size below which Chunks never cuts a chunk, except at the end of the
+	// data.
+	MinChunkSize = 128 * 1024
+
+	// AvgChunkSize is the expected size of the chunks cut by Chunks.
+	AvgChunkSize = 512 * 1024
+
+	// MaxChunkSize is the size at which Chunks always cuts a chunk.
+	MaxChunkSize = 2 * 1024 * 1024
+)
+
+// gear maps bytes to the pseudo-random values rolled into the hash.
+var gear [256]uint64
+
+func init() {
+	// splitmix64, so that the table, and thus the chunk boundaries, are stable across releases.
+	x := uint64(0x2545f4914f6cdd1d)
+	for i := range gear {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Chunks splits data into content-defined chunks using a gear rolling hash: boundaries depend on
+// the bytes around them rather than on their offsets, so an insertion or deletion only changes the
+// chunks it touches. The returned chunks share the memory of data.
+func Chunks(data []byte) [][]byte {
+	// The hash of the last 64 bytes is in the top bits, which are the ones tested.
+	shift := 64 - bits.TrailingZeros(AvgChunkSize)
+	mask := ^uint64(0) << shift
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := len(data)
+		if n > MinChunkSize {
+			limit := n
+			if limit > MaxChunkSize {
+				limit = MaxChunkSize
+			}
+			n = limit
+			var h uint64
+			for i := MinChunkSize; i < limit; i++ {
+				h = h<<1 + gear[data[i]]
+				if h&mask == 0 {
+					n = i + 1
+					break
+				}
+			}
+		}
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
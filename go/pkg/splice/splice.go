@@ -0,0 +1,355 @@
+// Package splice implements the split and splice extension of the ContentAddressableStorage
+// service, which lets clients transfer only the chunks of a large blob that the other side does
+// not already have.
+//
+// The SplitBlob and SpliceBlob RPCs are not part of the remote-apis protos this module depends on
+// yet, so their messages are built dynamically from descriptors defined here, with the field
+// numbers of the upstream proposal. The messages work with the default gRPC codec on both the
+// client and the server side.
+package splice
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+const (
+	// SplitBlobMethod is the full gRPC method name of SplitBlob.
+	SplitBlobMethod = "/build.bazel.remote.execution.v2.ContentAddressableStorage/SplitBlob"
+
+	// SpliceBlobMethod is the full gRPC method name of SpliceBlob.
+	SpliceBlobMethod = "/build.bazel.remote.execution.v2.ContentAddressableStorage/SpliceBlob"
+)
+
+// Field numbers of the split and splice support flags in CacheCapabilities.
+const (
+	splitBlobSupportField  protowire.Number = 9
+	spliceBlobSupportField protowire.Number = 10
+)
+
+var (
+	splitBlobRequestDesc   protoreflect.MessageDescriptor
+	splitBlobResponseDesc  protoreflect.MessageDescriptor
+	spliceBlobRequestDesc  protoreflect.MessageDescriptor
+	spliceBlobResponseDesc protoreflect.MessageDescriptor
+)
+
+func init() {
+	re := repb.File_build_bazel_remote_execution_v2_remote_execution_proto
+	digestType := "." + string((&repb.Digest{}).ProtoReflect().Descriptor().FullName())
+	digestFnType := "." + string(repb.DigestFunction_SHA256.Descriptor().FullName())
+	str := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false)
+	}
+	dg := func(name string, num int32, repeated bool) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, digestType, repeated)
+	}
+	fn := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_ENUM, digestFnType, false)
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("github.com/bazelbuild/remote-apis-sdks/go/pkg/splice/splice.proto"),
+		Package:    proto.String(string(re.Package())),
+		Dependency: []string{re.Path()},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("SplitBlobRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				str("instance_name", 1), dg("blob_digest", 2, false), fn("digest_function", 3),
+			}},
+			{Name: proto.String("SplitBlobResponse"), Field: []*descriptorpb.FieldDescriptorProto{
+				dg("chunk_digests", 1, true), fn("digest_function", 2),
+			}},
+			{Name: proto.String("SpliceBlobRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				str("instance_name", 1), dg("blob_digest", 2, false), dg("chunk_digests", 3, true), fn("digest_function", 4),
+			}},
+			{Name: proto.String("SpliceBlobResponse"), Field: []*descriptorpb.FieldDescriptorProto{
+				dg("blob_digest", 1, false),
+			}},
+		},
+	}
+	// The file is resolved against the global registry but not registered in it, so that it does
+	// not conflict with the generated messages once remote-apis is updated.
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Sprintf("splice: invalid descriptor: %v", err))
+	}
+	msgs := fd.Messages()
+	splitBlobRequestDesc = msgs.ByName("SplitBlobRequest")
+	splitBlobResponseDesc = msgs.ByName("SplitBlobResponse")
+	spliceBlobRequestDesc = msgs.ByName("SpliceBlobRequest")
+	spliceBlobResponseDesc = msgs.ByName("SpliceBlobResponse")
+}
+
+func field(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(num),
+		Type:   typ.Enum(),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	if repeated {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	return f
+}
+
+// SplitBlobRequest asks the server to split a blob into chunks stored in the CAS.
+type SplitBlobRequest struct {
+	InstanceName string
+	BlobDigest   digest.Digest
+}
+
+// SplitBlobResponse lists the chunks of a split blob, in order.
+type SplitBlobResponse struct {
+	ChunkDigests []digest.Digest
+}
+
+// SpliceBlobRequest asks the server to concatenate chunks present in the CAS into a blob.
+type SpliceBlobRequest struct {
+	InstanceName string
+	BlobDigest   digest.Digest
+	ChunkDigests []digest.Digest
+}
+
+// SpliceBlobResponse acknowledges a spliced blob.
+type SpliceBlobResponse struct {
+	BlobDigest digest.Digest
+}
+
+// Message returns r as a protocol buffer message. The message of a zero value can be used to
+// receive a request.
+func (r *SplitBlobRequest) Message() proto.Message {
+	m := dynamicpb.NewMessage(splitBlobRequestDesc)
+	setString(m, "instance_name", r.InstanceName)
+	setDigest(m, "blob_digest", r.BlobDigest)
+	setDigestFunction(m)
+	return m
+}
+
+// FromMessage sets r from a message returned by Message.
+func (r *SplitBlobRequest) FromMessage(m proto.Message) error {
+	mr, err := checkType(m, splitBlobRequestDesc)
+	if err != nil {
+		return err
+	}
+	r.InstanceName = getString(mr, "instance_name")
+	r.BlobDigest, err = getDigest(mr, "blob_digest")
+	return err
+}
+
+// Message returns r as a protocol buffer message. The message of a zero value can be used to
+// receive a response.
+func (r *SplitBlobResponse) Message() proto.Message {
+	m := dynamicpb.NewMessage(splitBlobResponseDesc)
+	setDigests(m, "chunk_digests", r.ChunkDigests)
+	setDigestFunction(m)
+	return m
+}
+
+// FromMessage sets r from a message returned by Message.
+func (r *SplitBlobResponse) FromMessage(m proto.Message) error {
+	mr, err := checkType(m, splitBlobResponseDesc)
+	if err != nil {
+		return err
+	}
+	r.ChunkDigests, err = getDigests(mr, "chunk_digests")
+	return err
+}
+
+// Message returns r as a protocol buffer message. The message of a zero value can be used to
+// receive a request.
+func (r *SpliceBlobRequest) Message() proto.Message {
+	m := dynamicpb.NewMessage(spliceBlobRequestDesc)
+	setString(m, "instance_name", r.InstanceName)
+	setDigest(m, "blob_digest", r.BlobDigest)
+	setDigests(m, "chunk_digests", r.ChunkDigests)
+	setDigestFunction(m)
+	return m
+}
+
+// FromMessage sets r from a message returned by Message.
+func (r *SpliceBlobRequest) FromMessage(m proto.Message) error {
+	mr, err := checkType(m, spliceBlobRequestDesc)
+	if err != nil {
+		return err
+	}
+	r.InstanceName = getString(mr, "instance_name")
+	if r.BlobDigest, err = getDigest(mr, "blob_digest"); err != nil {
+		return err
+	}
+	r.ChunkDigests, err = getDigests(mr, "chunk_digests")
+	return err
+}
+
+// Message returns r as a protocol buffer message. The message of a zero value can be used to
+// receive a response.
+func (r *SpliceBlobResponse) Message() proto.Message {
+	m := dynamicpb.NewMessage(spliceBlobResponseDesc)
+	setDigest(m, "blob_digest", r.BlobDigest)
+	return m
+}
+
+// FromMessage sets r from a message returned by Message.
+func (r *SpliceBlobResponse) FromMessage(m proto.Message) error {
+	mr, err := checkType(m, spliceBlobResponseDesc)
+	if err != nil {
+		return err
+	}
+	r.BlobDigest, err = getDigest(mr, "blob_digest")
+	return err
+}
+
+// SplitBlobSupport returns whether the server advertises SplitBlob in its cache capabilities.
+func SplitBlobSupport(caps *repb.CacheCapabilities) bool {
+	return boolField(caps, splitBlobSupportField)
+}
+
+// SpliceBlobSupport returns whether the server advertises SpliceBlob in its cache capabilities.
+func SpliceBlobSupport(caps *repb.CacheCapabilities) bool {
+	return boolField(caps, spliceBlobSupportField)
+}
+
+// SetSupport advertises SplitBlob and SpliceBlob support in caps, for servers.
+func SetSupport(caps *repb.CacheCapabilities, split, splice bool) {
+	r := caps.ProtoReflect()
+	var unknown []byte
+	// Drop previous values of the flags.
+	for b := r.GetUnknown(); len(b) > 0; {
+		num, _, n := protowire.ConsumeField(b)
+		if n < 0 {
+			break
+		}
+		if num != splitBlobSupportField && num != spliceBlobSupportField {
+			unknown = append(unknown, b[:n]...)
+		}
+		b = b[n:]
+	}
+	for _, f := range []struct {
+		num protowire.Number
+		v   bool
+	}{{splitBlobSupportField, split}, {spliceBlobSupportField, splice}} {
+		if f.v {
+			unknown = protowire.AppendTag(unknown, f.num, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, 1)
+		}
+	}
+	r.SetUnknown(unknown)
+}
+
+// boolField returns the last value of the unknown boolean field num of caps.
+func boolField(caps *repb.CacheCapabilities, num protowire.Number) bool {
+	if caps == nil {
+		return false
+	}
+	res := false
+	for b := caps.ProtoReflect().GetUnknown(); len(b) > 0; {
+		n, typ, tn := protowire.ConsumeTag(b)
+		if tn < 0 {
+			return false
+		}
+		b = b[tn:]
+		if n == num && typ == protowire.VarintType {
+			v, vn := protowire.ConsumeVarint(b)
+			if vn < 0 {
+				return false
+			}
+			res = v != 0
+			b = b[vn:]
+			continue
+		}
+		vn := protowire.ConsumeFieldValue(n, typ, b)
+		if vn < 0 {
+			return false
+		}
+		b = b[vn:]
+	}
+	return res
+}
+
+func checkType(m proto.Message, want protoreflect.MessageDescriptor) (protoreflect.Message, error) {
+	mr := m.ProtoReflect()
+	if got := mr.Descriptor().FullName(); got != want.FullName() {
+		return nil, fmt.Errorf("got a %v message, want %v", got, want.FullName())
+	}
+	return mr, nil
+}
+
+func setString(m *dynamicpb.Message, name, v string) {
+	if v != "" {
+		m.Set(m.Descriptor().Fields().ByName(protoreflect.Name(name)), protoreflect.ValueOfString(v))
+	}
+}
+
+func getString(m protoreflect.Message, name string) string {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name))).String()
+}
+
+func setDigestFunction(m *dynamicpb.Message) {
+	fd := m.Descriptor().Fields().ByName("digest_function")
+	m.Set(fd, protoreflect.ValueOfEnum(protoreflect.EnumNumber(digest.GetDigestFunction())))
+}
+
+func setDigest(m *dynamicpb.Message, name string, d digest.Digest) {
+	if d == (digest.Digest{}) {
+		return
+	}
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	fillDigest(m.Mutable(fd).Message(), d)
+}
+
+func getDigest(m protoreflect.Message, name string) (digest.Digest, error) {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if !m.Has(fd) {
+		return digest.Digest{}, fmt.Errorf("missing %v", name)
+	}
+	return toDigest(m.Get(fd).Message())
+}
+
+func setDigests(m *dynamicpb.Message, name string, ds []digest.Digest) {
+	if len(ds) == 0 {
+		return
+	}
+	l := m.Mutable(m.Descriptor().Fields().ByName(protoreflect.Name(name))).List()
+	for _, d := range ds {
+		v := l.NewElement()
+		fillDigest(v.Message(), d)
+		l.Append(v)
+	}
+}
+
+func getDigests(m protoreflect.Message, name string) ([]digest.Digest, error) {
+	l := m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name))).List()
+	ds := make([]digest.Digest, l.Len())
+	for i := range ds {
+		d, err := toDigest(l.Get(i).Message())
+		if err != nil {
+			return nil, fmt.Errorf("%v[%d]: %v", name, i, err)
+		}
+		ds[i] = d
+	}
+	return ds, nil
+}
+
+func fillDigest(m protoreflect.Message, d digest.Digest) {
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("hash"), protoreflect.ValueOfString(d.Hash))
+	m.Set(fields.ByName("size_bytes"), protoreflect.ValueOfInt64(d.Size))
+}
+
+func toDigest(m protoreflect.Message) (digest.Digest, error) {
+	fields := m.Descriptor().Fields()
+	return digest.New(m.Get(fields.ByName("hash")).String(), m.Get(fields.ByName("size_bytes")).Int())
+}
@@ -0,0 +1,128 @@
+package splice
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestMessagesRoundTrip(t *testing.T) {
+	foo := digest.NewFromBlob([]byte("foo"))
+	bar := digest.NewFromBlob([]byte("bar"))
+	foobar := digest.NewFromBlob([]byte("foobar"))
+	tests := []struct {
+		name string
+		in   interface {
+			Message() proto.Message
+		}
+		out interface {
+			Message() proto.Message
+			FromMessage(proto.Message) error
+		}
+	}{
+		{"SplitBlobRequest", &SplitBlobRequest{InstanceName: "instance", BlobDigest: foobar}, &SplitBlobRequest{}},
+		{"SplitBlobResponse", &SplitBlobResponse{ChunkDigests: []digest.Digest{foo, bar}}, &SplitBlobResponse{}},
+		{"SpliceBlobRequest", &SpliceBlobRequest{InstanceName: "instance", BlobDigest: foobar, ChunkDigests: []digest.Digest{foo, bar}}, &SpliceBlobRequest{}},
+		{"SpliceBlobResponse", &SpliceBlobResponse{BlobDigest: foobar}, &SpliceBlobResponse{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := proto.Marshal(tc.in.Message())
+			if err != nil {
+				t.Fatalf("proto.Marshal() failed: %v", err)
+			}
+			m := tc.out.Message()
+			if err := proto.Unmarshal(b, m); err != nil {
+				t.Fatalf("proto.Unmarshal() failed: %v", err)
+			}
+			if err := tc.out.FromMessage(m); err != nil {
+				t.Fatalf("FromMessage() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.in, tc.out); diff != "" {
+				t.Errorf("round trip diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromMessageWrongType(t *testing.T) {
+	if err := (&SplitBlobRequest{}).FromMessage((&SpliceBlobRequest{}).Message()); err == nil {
+		t.Errorf("FromMessage() of a SpliceBlobRequest into a SplitBlobRequest succeeded, want error")
+	}
+}
+
+func TestSupport(t *testing.T) {
+	caps := &repb.CacheCapabilities{MaxBatchTotalSizeBytes: 10}
+	if SplitBlobSupport(caps) || SpliceBlobSupport(caps) || SplitBlobSupport(nil) {
+		t.Fatalf("support reported without the capabilities being set")
+	}
+	SetSupport(caps, false, true)
+	// Survive a round trip, as the client sees the capabilities after decoding.
+	b, err := proto.Marshal(caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &repb.CacheCapabilities{}
+	if err := proto.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if SplitBlobSupport(got) || !SpliceBlobSupport(got) {
+		t.Errorf("SplitBlobSupport() = %v, SpliceBlobSupport() = %v, want false, true", SplitBlobSupport(got), SpliceBlobSupport(got))
+	}
+	if got.MaxBatchTotalSizeBytes != 10 {
+		t.Errorf("MaxBatchTotalSizeBytes = %d, want 10", got.MaxBatchTotalSizeBytes)
+	}
+	SetSupport(got, true, false)
+	if !SplitBlobSupport(got) || SpliceBlobSupport(got) {
+		t.Errorf("after SetSupport(true, false): SplitBlobSupport() = %v, SpliceBlobSupport() = %v", SplitBlobSupport(got), SpliceBlobSupport(got))
+	}
+}
+
+func TestChunks(t *testing.T) {
+	data := make([]byte, 8*1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	chunks := Chunks(data)
+	if len(chunks) < 2 {
+		t.Fatalf("Chunks() returned %d chunks for %d bytes, want several", len(chunks), len(data))
+	}
+	for i, c := range chunks {
+		if len(c) > MaxChunkSize || (len(c) < MinChunkSize && i != len(chunks)-1) {
+			t.Errorf("chunk %d has size %d, want between %d and %d", i, len(c), MinChunkSize, MaxChunkSize)
+		}
+	}
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, data) {
+		t.Fatalf("chunks do not concatenate to the data")
+	}
+
+	// An insertion in the middle only changes the chunks around it.
+	edited := append(append(append([]byte{}, data[:len(data)/2]...), []byte("inserted")...), data[len(data)/2:]...)
+	before := make(map[digest.Digest]bool)
+	for _, c := range chunks {
+		before[digest.NewFromBlob(c)] = true
+	}
+	changed := 0
+	editedChunks := Chunks(edited)
+	for _, c := range editedChunks {
+		if !before[digest.NewFromBlob(c)] {
+			changed++
+		}
+	}
+	if changed > 2 {
+		t.Errorf("%d of %d chunks changed after a small insertion, want at most 2", changed, len(editedChunks))
+	}
+}
+
+func TestChunksSmall(t *testing.T) {
+	if got := Chunks(nil); len(got) != 0 {
+		t.Errorf("Chunks(nil) = %d chunks, want none", len(got))
+	}
+	if got := Chunks([]byte("foo")); len(got) != 1 || string(got[0]) != "foo" {
+		t.Errorf("Chunks(foo) = %q, want [foo]", got)
+	}
+}
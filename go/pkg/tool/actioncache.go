@@ -0,0 +1,104 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// cacheLookup is the outcome of the action cache lookup of an action.
+type cacheLookup struct {
+	actionDg digest.Digest
+	resultDg digest.Digest
+	hit      bool
+	err      error
+}
+
+// CheckActionCache looks up the actions with the digests listed in the file at path in the action
+// cache, at most concurrency at a time, e.g. to measure the cache hit rate of a past build. The
+// file lists one digest per line; empty lines and lines starting with # are skipped. It returns
+// whether each action is a hit, with the digest of its ActionResult, or a miss, followed by the
+// totals.
+func (c *Client) CheckActionCache(ctx context.Context, path string, concurrency int) (string, error) {
+	dgs, err := readDigests(path)
+	if err != nil {
+		return "", err
+	}
+	c.infof("Looking up %d actions in the action cache..", len(dgs))
+	lookups := make([]*cacheLookup, len(dgs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, dg := range dgs {
+		i, dg := i, dg
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			l := &cacheLookup{actionDg: dg}
+			ar, err := c.GrpcClient.CheckActionCache(ctx, dg.ToProto())
+			switch {
+			case err != nil:
+				l.err = err
+			case ar != nil:
+				l.hit = true
+				l.resultDg, l.err = digest.NewFromMessage(ar)
+			}
+			lookups[i] = l
+		}()
+	}
+	wg.Wait()
+
+	var res bytes.Buffer
+	hits, errs := 0, 0
+	for _, l := range lookups {
+		switch {
+		case l.err != nil:
+			errs++
+			res.WriteString(fmt.Sprintf("%v\terror: %v\n", l.actionDg, l.err))
+		case l.hit:
+			hits++
+			res.WriteString(fmt.Sprintf("%v\thit\t%v\n", l.actionDg, l.resultDg))
+		default:
+			res.WriteString(fmt.Sprintf("%v\tmiss\n", l.actionDg))
+		}
+	}
+	rate := 0.0
+	if len(lookups) > 0 {
+		rate = 100 * float64(hits) / float64(len(lookups))
+	}
+	res.WriteString(fmt.Sprintf("Actions: %d, hits: %d (%.1f%%), misses: %d, errors: %d\n", len(lookups), hits, rate, len(lookups)-hits-errs, errs))
+	return res.String(), nil
+}
+
+// readDigests reads the digests listed in the file at path, one per line in <digest/size_bytes>
+// format. Empty lines and lines starting with # are skipped.
+func readDigests(path string) ([]digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var dgs []digest.Digest
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		dg, err := digest.NewFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+		dgs = append(dgs, dg)
+	}
+	return dgs, sc.Err()
+}
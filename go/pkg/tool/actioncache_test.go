@@ -0,0 +1,50 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_CheckActionCache(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	hitDg := digest.NewFromBlob([]byte("hit"))
+	missDg := digest.NewFromBlob([]byte("miss"))
+	ar := &repb.ActionResult{ExitCode: 1}
+	e.Server.ActionCache.Put(hitDg, ar)
+	arDg, err := digest.NewFromMessage(ar)
+	if err != nil {
+		t.Fatalf("digest.NewFromMessage(%v) failed: %v", ar, err)
+	}
+	path := filepath.Join(t.TempDir(), "digests")
+	contents := fmt.Sprintf("# actions of the build\n%v\n\n%v\n", hitDg, missDg)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed writing %v: %v", path, err)
+	}
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.CheckActionCache(context.Background(), path, 2)
+	if err != nil {
+		t.Fatalf("CheckActionCache(%v) failed: %v", path, err)
+	}
+	want := fmt.Sprintf("%v\thit\t%v\n%v\tmiss\nActions: 2, hits: 1 (50.0%%), misses: 1, errors: 0\n", hitDg, arDg, missDg)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckActionCache(%v) returned diff (-want +got):\n%s", path, diff)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not a digest\n"), 0644); err != nil {
+		t.Fatalf("failed writing %v: %v", path, err)
+	}
+	if _, err := toolClient.CheckActionCache(context.Background(), path, 2); err == nil {
+		t.Errorf("CheckActionCache(%v) with an invalid digest succeeded, want error", path)
+	}
+}
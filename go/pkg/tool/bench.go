@@ -0,0 +1,270 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+)
+
+// BenchShape is the shape of the data transferred by Bench.
+type BenchShape string
+
+const (
+	// BenchBlobs transfers independent blobs: each one is uploaded with UploadIfMissing, looking it
+	// up with FindMissingBlobs and writing it in a batch or a stream depending on its size, and
+	// downloaded with ReadBlob.
+	BenchBlobs BenchShape = "blobs"
+	// BenchTrees transfers directory trees: each one is uploaded with its Merkle tree like
+	// UploadDirectory, and downloaded like DownloadDirectory.
+	BenchTrees BenchShape = "trees"
+)
+
+// BenchShapes are the supported shapes of Bench.
+var BenchShapes = []BenchShape{BenchBlobs, BenchTrees}
+
+// BenchOptions configure a Bench run.
+type BenchOptions struct {
+	// Shape is the shape of the data transferred, BenchBlobs if unset.
+	Shape BenchShape
+	// Ops is the number of blobs, or of trees, uploaded then downloaded.
+	Ops int
+	// MinSize and MaxSize bound the sizes in bytes of the blobs, or of the files of the trees,
+	// which are uniformly distributed between them.
+	MinSize, MaxSize int64
+	// TreeFiles is the number of files of each tree.
+	TreeFiles int
+	// TreeFanOut is the maximum number of files in each directory of a tree, the files being spread
+	// over subdirectories of its root.
+	TreeFanOut int
+	// Concurrency is the maximum number of blobs, or of trees, transferred concurrently.
+	Concurrency int
+	// Seed is the seed of the random contents of the blobs. Blobs generated with the same seed are
+	// identical, and may already be in the CAS.
+	Seed int64
+}
+
+// BenchStats are the statistics of the transfers of a Bench run in one direction.
+type BenchStats struct {
+	// Ops is the number of blobs, or of trees, transferred, including the failed ones.
+	Ops int
+	// Errors is the number of failed transfers, and FirstError the error of the first of them.
+	Errors     int
+	FirstError string
+	// Bytes is the total size of the blobs, or of the files of the trees, transferred successfully.
+	Bytes int64
+	// Elapsed is the wall time of all the transfers.
+	Elapsed time.Duration
+	// Latencies are the durations of the successful transfers, sorted.
+	Latencies []time.Duration
+}
+
+// Percentile returns the p-th percentile of the latencies of the successful transfers, with p
+// between 0 and 100, or 0 if none succeeded.
+func (s *BenchStats) Percentile(p float64) time.Duration {
+	n := len(s.Latencies)
+	if n == 0 {
+		return 0
+	}
+	i := int(p/100*float64(n)+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= n {
+		i = n - 1
+	}
+	return s.Latencies[i]
+}
+
+// String returns a summary of the statistics, e.g.
+// "100 ops, 0 errors (0.0%), 10.0 MiB in 1.2s, 8.3 MiB/s, latency p50 10ms p90 21ms p99 40ms max 42ms".
+func (s *BenchStats) String() string {
+	errPct := 0.0
+	if s.Ops > 0 {
+		errPct = 100 * float64(s.Errors) / float64(s.Ops)
+	}
+	var throughput int64
+	if s.Elapsed > 0 {
+		throughput = int64(float64(s.Bytes) / s.Elapsed.Seconds())
+	}
+	res := fmt.Sprintf("%d ops, %d errors (%.1f%%), %s in %v, %s/s, latency p50 %v p90 %v p99 %v max %v",
+		s.Ops, s.Errors, errPct, formatBytes(s.Bytes), s.Elapsed.Round(time.Millisecond), formatBytes(throughput),
+		s.Percentile(50), s.Percentile(90), s.Percentile(99), s.Percentile(100))
+	if s.FirstError != "" {
+		res += ", first error: " + s.FirstError
+	}
+	return res
+}
+
+// BenchResult is the result of a Bench run.
+type BenchResult struct {
+	Upload, Download BenchStats
+}
+
+// String returns a summary of the upload and download statistics, one per line.
+func (r *BenchResult) String() string {
+	return fmt.Sprintf("Upload: %v\nDownload: %v\n", &r.Upload, &r.Download)
+}
+
+// benchOp is a blob, or a tree, transferred by Bench.
+type benchOp struct {
+	blob []byte
+	// The local root of a tree with its entries, and its digest once uploaded.
+	dir    string
+	inputs []string
+	root   digest.Digest
+	size   int64
+	// Whether the upload succeeded, in which case it is downloaded.
+	uploaded bool
+}
+
+// Bench load tests the CAS with the I/O patterns of this SDK: it generates blobs or trees of
+// random contents as configured by o, uploads them, then downloads them back with up to
+// o.Concurrency transfers at the same time, e.g. to qualify a remote cache backend. It returns the
+// throughput, latency and error statistics of the transfers. Failed transfers are counted, not
+// returned as errors. The trees are generated in a temporary directory, removed on return.
+func (c *Client) Bench(ctx context.Context, o *BenchOptions) (*BenchResult, error) {
+	shape := o.Shape
+	if shape == "" {
+		shape = BenchBlobs
+	}
+	if shape != BenchBlobs && shape != BenchTrees {
+		return nil, fmt.Errorf("unsupported shape %q, supported shapes are %v", shape, BenchShapes)
+	}
+	if o.Ops <= 0 || o.Concurrency <= 0 {
+		return nil, fmt.Errorf("the number of operations and the concurrency must be >= 1")
+	}
+	if o.MinSize < 0 || o.MaxSize < o.MinSize {
+		return nil, fmt.Errorf("invalid sizes [%d, %d]", o.MinSize, o.MaxSize)
+	}
+	if shape == BenchTrees && (o.TreeFiles <= 0 || o.TreeFanOut <= 0) {
+		return nil, fmt.Errorf("the number of files and the fan out of the trees must be >= 1")
+	}
+
+	rnd := rand.New(rand.NewSource(o.Seed))
+	blob := func() []byte {
+		b := make([]byte, o.MinSize+rnd.Int63n(o.MaxSize-o.MinSize+1))
+		rnd.Read(b)
+		return b
+	}
+	ops := make([]*benchOp, o.Ops)
+	if shape == BenchBlobs {
+		for i := range ops {
+			b := blob()
+			ops[i] = &benchOp{blob: b, size: int64(len(b))}
+		}
+	} else {
+		tmp, err := ioutil.TempDir("", "remotetool-bench")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		c.infof("Generating %d trees of %d files in %v.", o.Ops, o.TreeFiles, tmp)
+		for i := range ops {
+			op := &benchOp{dir: filepath.Join(tmp, fmt.Sprintf("tree%d", i))}
+			for j := 0; j < o.TreeFiles; j++ {
+				sub := fmt.Sprintf("dir%d", j/o.TreeFanOut)
+				if j%o.TreeFanOut == 0 {
+					if err := os.MkdirAll(filepath.Join(op.dir, sub), 0755); err != nil {
+						return nil, err
+					}
+					op.inputs = append(op.inputs, sub)
+				}
+				path := filepath.Join(op.dir, sub, fmt.Sprintf("file%d", j))
+				b := blob()
+				if err := ioutil.WriteFile(path, b, 0644); err != nil {
+					return nil, err
+				}
+				op.size += int64(len(b))
+			}
+			ops[i] = op
+		}
+	}
+
+	res := &BenchResult{}
+	c.infof("Uploading %d %s.", o.Ops, shape)
+	benchRun(ops, o.Concurrency, &res.Upload, func(op *benchOp) error {
+		if shape == BenchBlobs {
+			_, _, err := c.GrpcClient.UploadIfMissing(ctx, uploadinfo.EntryFromBlob(op.blob))
+			return err
+		}
+		root, entries, _, err := c.GrpcClient.ComputeMerkleTree(op.dir, "", "", &command.InputSpec{Inputs: op.inputs}, filemetadata.NewNoopCache())
+		if err != nil {
+			return err
+		}
+		op.root = root
+		_, _, err = c.GrpcClient.UploadIfMissing(ctx, entries...)
+		return err
+	}, func(op *benchOp) { op.uploaded = true })
+
+	var uploaded []*benchOp
+	for _, op := range ops {
+		if op.uploaded {
+			uploaded = append(uploaded, op)
+		}
+	}
+	c.infof("Downloading %d %s.", len(uploaded), shape)
+	benchRun(uploaded, o.Concurrency, &res.Download, func(op *benchOp) error {
+		if shape == BenchBlobs {
+			_, _, err := c.GrpcClient.ReadBlob(ctx, digest.NewFromBlob(op.blob))
+			return err
+		}
+		out := op.dir + ".download"
+		defer os.RemoveAll(out)
+		_, _, err := c.GrpcClient.DownloadDirectory(ctx, op.root, out, filemetadata.NewNoopCache())
+		return err
+	}, nil)
+	return res, nil
+}
+
+// benchRun performs transfer on each of ops with up to concurrency of them at the same time, and
+// records their statistics in st. done, if set, is called after each successful transfer.
+func benchRun(ops []*benchOp, concurrency int, st *BenchStats, transfer func(*benchOp) error, done func(*benchOp)) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	start := time.Now()
+	for _, op := range ops {
+		op := op
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			opStart := time.Now()
+			err := transfer(op)
+			latency := time.Since(opStart)
+			mu.Lock()
+			defer mu.Unlock()
+			st.Ops++
+			if err != nil {
+				if st.Errors == 0 {
+					st.FirstError = strings.TrimSpace(err.Error())
+				}
+				st.Errors++
+				return
+			}
+			st.Bytes += op.size
+			st.Latencies = append(st.Latencies, latency)
+			if done != nil {
+				done(op)
+			}
+		}()
+	}
+	wg.Wait()
+	st.Elapsed = time.Since(start)
+	sort.Slice(st.Latencies, func(i, j int) bool { return st.Latencies[i] < st.Latencies[j] })
+}
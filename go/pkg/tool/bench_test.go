@@ -0,0 +1,70 @@
+package tool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_Bench(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      *BenchOptions
+		wantBytes int64
+	}{
+		{
+			name:      "blobs",
+			opts:      &BenchOptions{Ops: 20, MinSize: 100, MaxSize: 100, Concurrency: 4},
+			wantBytes: 20 * 100,
+		},
+		{
+			name:      "trees",
+			opts:      &BenchOptions{Shape: BenchTrees, Ops: 3, MinSize: 10, MaxSize: 10, TreeFiles: 5, TreeFanOut: 2, Concurrency: 2, Seed: 1},
+			wantBytes: 3 * 5 * 10,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+			res, err := toolClient.Bench(context.Background(), tc.opts)
+			if err != nil {
+				t.Fatalf("Bench() failed: %v", err)
+			}
+			for name, st := range map[string]*BenchStats{"upload": &res.Upload, "download": &res.Download} {
+				if st.Ops != tc.opts.Ops || st.Errors != 0 || st.Bytes != tc.wantBytes || len(st.Latencies) != tc.opts.Ops {
+					t.Errorf("Bench() %s stats = %v, want %d ops, no errors and %d bytes", name, st, tc.opts.Ops, tc.wantBytes)
+				}
+			}
+		})
+	}
+}
+
+func TestTool_BenchInvalidOptions(t *testing.T) {
+	toolClient := &Client{}
+	for _, o := range []*BenchOptions{
+		{Ops: 0, Concurrency: 1},
+		{Ops: 1, Concurrency: 1, MinSize: 10, MaxSize: 5},
+		{Shape: "graphs", Ops: 1, Concurrency: 1},
+		{Shape: BenchTrees, Ops: 1, Concurrency: 1, TreeFiles: 1},
+	} {
+		if _, err := toolClient.Bench(context.Background(), o); err == nil {
+			t.Errorf("Bench(%+v) succeeded, want error", o)
+		}
+	}
+}
+
+func TestBenchStats_Percentile(t *testing.T) {
+	st := &BenchStats{}
+	for i := 1; i <= 100; i++ {
+		st.Latencies = append(st.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{0: time.Millisecond, 50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := st.Percentile(p); got != want {
+			t.Errorf("Percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
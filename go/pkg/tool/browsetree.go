@@ -0,0 +1,254 @@
+package tool
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// maxCatSize is the size of the largest blobs printed by the cat command of BrowseTree.
+const maxCatSize = 1024 * 1024
+
+const browseHelp = `Commands:
+	ls [path]    list the children of a directory
+	cd [path]    change the current directory, to the root if path is unset
+	pwd          print the current directory
+	stat path    print the digest, size and type of a file, directory or symlink
+	cat path     print the contents of a file of at most 1MiB
+	help         print this help
+	exit         exit
+`
+
+// treeBrowser resolves paths of a tree in the CAS, reading directories only as they are visited.
+type treeBrowser struct {
+	c      *Client
+	rootDg digest.Digest
+	cwd    string
+	dirs   map[digest.Digest]*repb.Directory
+}
+
+// treeEntry is the node at a path of a tree: either a directory, a file or a symlink.
+type treeEntry struct {
+	path    string
+	dirDg   digest.Digest
+	dir     *repb.Directory
+	file    *repb.FileNode
+	symlink *repb.SymlinkNode
+}
+
+// BrowseTree runs an interactive browser over the tree with the given root directory digest,
+// reading commands from in and writing their results to out. Commands list directories (ls), print
+// details of entries (stat) and print small files (cat), reading the blobs they need from the CAS
+// without downloading the whole tree. If interactive is set, a prompt is written before each command.
+func (c *Client) BrowseTree(ctx context.Context, rootDigest string, in io.Reader, out io.Writer, interactive bool) error {
+	rootDg, err := digest.NewFromString(rootDigest)
+	if err != nil {
+		return err
+	}
+	b := &treeBrowser{c: c, rootDg: rootDg, cwd: "/", dirs: make(map[digest.Digest]*repb.Directory)}
+	// Fail early if the root is not a directory in the CAS.
+	if _, err := b.readDir(ctx, rootDg); err != nil {
+		return err
+	}
+	if interactive {
+		fmt.Fprintf(out, "Browsing tree %v. Type help for the list of commands.\n", rootDg)
+	}
+	sc := bufio.NewScanner(in)
+	for {
+		if interactive {
+			fmt.Fprintf(out, "%s> ", b.cwd)
+		}
+		if !sc.Scan() {
+			break
+		}
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		if cmd == "exit" || cmd == "quit" {
+			return nil
+		}
+		if err := b.run(ctx, cmd, args, out); err != nil {
+			fmt.Fprintf(out, "%s: %v\n", cmd, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (b *treeBrowser) run(ctx context.Context, cmd string, args []string, out io.Writer) error {
+	arg := ""
+	switch {
+	case len(args) == 1:
+		arg = args[0]
+	case len(args) > 1:
+		return fmt.Errorf("too many arguments")
+	}
+	switch cmd {
+	case "help":
+		io.WriteString(out, browseHelp)
+	case "pwd":
+		fmt.Fprintln(out, b.cwd)
+	case "cd":
+		if arg == "" {
+			arg = "/"
+		}
+		e, err := b.resolve(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if e.dir == nil {
+			return fmt.Errorf("%v is not a directory", e.path)
+		}
+		b.cwd = e.path
+	case "ls":
+		e, err := b.resolve(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if e.dir == nil {
+			writeEntry(out, e)
+			return nil
+		}
+		for _, d := range e.dir.Directories {
+			fmt.Fprintf(out, "%s/\n", d.Name)
+		}
+		for _, f := range e.dir.Files {
+			writeEntry(out, &treeEntry{path: f.Name, file: f})
+		}
+		for _, s := range e.dir.Symlinks {
+			writeEntry(out, &treeEntry{path: s.Name, symlink: s})
+		}
+	case "stat":
+		if arg == "" {
+			return fmt.Errorf("missing path")
+		}
+		e, err := b.resolve(ctx, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Path: %s\n", e.path)
+		switch {
+		case e.dir != nil:
+			fmt.Fprintf(out, "Type: directory\nDigest: %v\nEntries: %d directories, %d files, %d symlinks\n", e.dirDg, len(e.dir.Directories), len(e.dir.Files), len(e.dir.Symlinks))
+		case e.file != nil:
+			fmt.Fprintf(out, "Type: file\nDigest: %v\nSize: %d\nExecutable: %v\n", digestString(e.file.Digest), e.file.GetDigest().GetSizeBytes(), e.file.IsExecutable)
+		default:
+			fmt.Fprintf(out, "Type: symlink\nTarget: %s\n", e.symlink.Target)
+		}
+	case "cat":
+		if arg == "" {
+			return fmt.Errorf("missing path")
+		}
+		e, err := b.resolve(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if e.file == nil {
+			return fmt.Errorf("%v is not a file", e.path)
+		}
+		dg, err := digest.NewFromProto(e.file.Digest)
+		if err != nil {
+			return err
+		}
+		if dg.Size > maxCatSize {
+			return fmt.Errorf("%v is %d bytes, larger than %d: use download_blob with digest %v instead", e.path, dg.Size, maxCatSize, dg)
+		}
+		blob, _, err := b.c.GrpcClient.ReadBlob(ctx, dg)
+		if err != nil {
+			return err
+		}
+		out.Write(blob)
+		if len(blob) > 0 && blob[len(blob)-1] != '\n' {
+			io.WriteString(out, "\n")
+		}
+	default:
+		return fmt.Errorf("unknown command, type help for the list of commands")
+	}
+	return nil
+}
+
+func writeEntry(out io.Writer, e *treeEntry) {
+	switch {
+	case e.dir != nil:
+		fmt.Fprintf(out, "%s/\n", e.path)
+	case e.file != nil:
+		exec := ""
+		if e.file.IsExecutable {
+			exec = " (executable)"
+		}
+		fmt.Fprintf(out, "%s\t%d%s\n", e.path, e.file.GetDigest().GetSizeBytes(), exec)
+	default:
+		fmt.Fprintf(out, "%s -> %s\n", e.path, e.symlink.Target)
+	}
+}
+
+// resolve returns the entry at p, relative to the current directory unless absolute. Symlinks are
+// not followed.
+func (b *treeBrowser) resolve(ctx context.Context, p string) (*treeEntry, error) {
+	if !path.IsAbs(p) {
+		p = path.Join(b.cwd, p)
+	}
+	p = path.Clean("/" + p)
+	dirDg := b.rootDg
+	dir, err := b.readDir(ctx, dirDg)
+	if err != nil {
+		return nil, err
+	}
+	if p == "/" {
+		return &treeEntry{path: p, dirDg: dirDg, dir: dir}, nil
+	}
+	parts := strings.Split(p[1:], "/")
+	for i, name := range parts {
+		last := i == len(parts)-1
+		found := false
+		for _, d := range dir.Directories {
+			if d.Name != name {
+				continue
+			}
+			if dirDg, err = digest.NewFromProto(d.Digest); err != nil {
+				return nil, err
+			}
+			if dir, err = b.readDir(ctx, dirDg); err != nil {
+				return nil, err
+			}
+			found = true
+			break
+		}
+		if found {
+			continue
+		}
+		if last {
+			for _, f := range dir.Files {
+				if f.Name == name {
+					return &treeEntry{path: p, file: f}, nil
+				}
+			}
+			for _, s := range dir.Symlinks {
+				if s.Name == name {
+					return &treeEntry{path: p, symlink: s}, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%v: no such file or directory", "/"+strings.Join(parts[:i+1], "/"))
+	}
+	return &treeEntry{path: p, dirDg: dirDg, dir: dir}, nil
+}
+
+func (b *treeBrowser) readDir(ctx context.Context, dg digest.Digest) (*repb.Directory, error) {
+	if d, ok := b.dirs[dg]; ok {
+		return d, nil
+	}
+	d := &repb.Directory{}
+	if _, err := b.c.GrpcClient.ReadProto(ctx, dg, d); err != nil {
+		return nil, err
+	}
+	b.dirs[dg] = d
+	return d, nil
+}
@@ -0,0 +1,78 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_BrowseTree(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+
+	srcDg := cas.Put([]byte("int main;"))
+	toolDg := cas.Put([]byte("#!/bin/sh\n"))
+	subDg := put(&repb.Directory{
+		Files:    []*repb.FileNode{{Name: "main.c", Digest: srcDg.ToProto()}},
+		Symlinks: []*repb.SymlinkNode{{Name: "link", Target: "main.c"}},
+	})
+	rootDg := put(&repb.Directory{
+		Files:       []*repb.FileNode{{Name: "tool", Digest: toolDg.ToProto(), IsExecutable: true}},
+		Directories: []*repb.DirectoryNode{{Name: "src", Digest: subDg}},
+	})
+
+	commands := []string{
+		"ls",
+		"cd src",
+		"pwd",
+		"ls",
+		"cat main.c",
+		"cat ../tool",
+		"stat /tool",
+		"stat link",
+		"cat missing",
+		"cd ../tool",
+		"cd",
+		"pwd",
+		"exit",
+		"ls",
+	}
+	want := "src/\n" +
+		"tool\t10 (executable)\n" +
+		"/src\n" +
+		"main.c\t9\n" +
+		"link -> main.c\n" +
+		"int main;\n" +
+		"#!/bin/sh\n" +
+		fmt.Sprintf("Path: /tool\nType: file\nDigest: %v\nSize: 10\nExecutable: true\n", toolDg) +
+		"Path: /src/link\nType: symlink\nTarget: main.c\n" +
+		"cat: /src/missing: no such file or directory\n" +
+		"cd: /tool is not a directory\n" +
+		"/\n"
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(commands, "\n"))
+	if err := toolClient.BrowseTree(context.Background(), digestString(rootDg), in, &out, false); err != nil {
+		t.Fatalf("BrowseTree(%v) failed: %v", digestString(rootDg), err)
+	}
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("BrowseTree(%v) returned diff (-want +got):\n%s", digestString(rootDg), diff)
+	}
+}
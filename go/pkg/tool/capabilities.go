@@ -0,0 +1,175 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	smpb "github.com/bazelbuild/remote-apis/build/bazel/semver"
+)
+
+// capabilitiesJSON is the JSON output of ShowCapabilities.
+type capabilitiesJSON struct {
+	LowAPIVersion        string                     `json:"low_api_version,omitempty"`
+	HighAPIVersion       string                     `json:"high_api_version,omitempty"`
+	DeprecatedAPIVersion string                     `json:"deprecated_api_version,omitempty"`
+	Cache                *cacheCapabilitiesJSON     `json:"cache,omitempty"`
+	Execution            *executionCapabilitiesJSON `json:"execution,omitempty"`
+}
+
+type cacheCapabilitiesJSON struct {
+	DigestFunctions             []string `json:"digest_functions"`
+	MaxBatchTotalSizeBytes      int64    `json:"max_batch_total_size_bytes"`
+	ActionCacheUpdateEnabled    bool     `json:"action_cache_update_enabled"`
+	SymlinkAbsolutePathStrategy string   `json:"symlink_absolute_path_strategy"`
+	SupportedCompressors        []string `json:"supported_compressors"`
+	Priorities                  []string `json:"priorities,omitempty"`
+}
+
+type executionCapabilitiesJSON struct {
+	ExecEnabled             bool     `json:"exec_enabled"`
+	DigestFunction          string   `json:"digest_function"`
+	SupportedNodeProperties []string `json:"supported_node_properties"`
+	Priorities              []string `json:"priorities,omitempty"`
+}
+
+// ShowCapabilities returns the capabilities of the remote execution and cache servers, the first
+// thing to check when debugging against a new backend, in the given format.
+func (c *Client) ShowCapabilities(ctx context.Context, format ShowFormat) (string, error) {
+	caps, err := c.GrpcClient.GetCapabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case TextFormat, "":
+		return capabilitiesText(caps), nil
+	case JSONFormat:
+		out, err := json.MarshalIndent(capabilitiesToJSON(caps), "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out) + "\n", nil
+	case TextprotoFormat:
+		return proto.MarshalTextString(caps), nil
+	}
+	return "", fmt.Errorf("unsupported format %q, supported formats: %v", format, ShowFormats)
+}
+
+func capabilitiesToJSON(caps *repb.ServerCapabilities) *capabilitiesJSON {
+	res := &capabilitiesJSON{
+		LowAPIVersion:        semverString(caps.LowApiVersion),
+		HighAPIVersion:       semverString(caps.HighApiVersion),
+		DeprecatedAPIVersion: semverString(caps.DeprecatedApiVersion),
+	}
+	if cc := caps.CacheCapabilities; cc != nil {
+		res.Cache = &cacheCapabilitiesJSON{
+			DigestFunctions:             []string{},
+			MaxBatchTotalSizeBytes:      cc.MaxBatchTotalSizeBytes,
+			ActionCacheUpdateEnabled:    cc.GetActionCacheUpdateCapabilities().GetUpdateEnabled(),
+			SymlinkAbsolutePathStrategy: cc.SymlinkAbsolutePathStrategy.String(),
+			SupportedCompressors:        []string{},
+			Priorities:                  priorityRanges(cc.CachePriorityCapabilities),
+		}
+		for _, fn := range cc.DigestFunctions {
+			res.Cache.DigestFunctions = append(res.Cache.DigestFunctions, fn.String())
+		}
+		for _, comp := range cc.SupportedCompressors {
+			res.Cache.SupportedCompressors = append(res.Cache.SupportedCompressors, comp.String())
+		}
+	}
+	if ec := caps.ExecutionCapabilities; ec != nil {
+		res.Execution = &executionCapabilitiesJSON{
+			ExecEnabled:             ec.ExecEnabled,
+			DigestFunction:          ec.DigestFunction.String(),
+			SupportedNodeProperties: append([]string{}, ec.SupportedNodeProperties...),
+			Priorities:              priorityRanges(ec.ExecutionPriorityCapabilities),
+		}
+	}
+	return res
+}
+
+func capabilitiesText(caps *repb.ServerCapabilities) string {
+	j := capabilitiesToJSON(caps)
+	var res bytes.Buffer
+	res.WriteString("Server Capabilities\n===================\n")
+	res.WriteString(fmt.Sprintf("API versions: %v to %v\n", orNone(j.LowAPIVersion), orNone(j.HighAPIVersion)))
+	if j.DeprecatedAPIVersion != "" {
+		res.WriteString(fmt.Sprintf("Deprecated API version: %v\n", j.DeprecatedAPIVersion))
+	}
+
+	var cache bytes.Buffer
+	if cc := j.Cache; cc == nil {
+		cache.WriteString("None\n")
+	} else {
+		cache.WriteString(fmt.Sprintf("Digest functions: %v\n", orNone(strings.Join(cc.DigestFunctions, ", "))))
+		if cc.MaxBatchTotalSizeBytes == 0 {
+			cache.WriteString("Max batch total size: unlimited\n")
+		} else {
+			cache.WriteString(fmt.Sprintf("Max batch total size: %d bytes\n", cc.MaxBatchTotalSizeBytes))
+		}
+		cache.WriteString(fmt.Sprintf("Action cache updates: %v\n", enabled(cc.ActionCacheUpdateEnabled)))
+		cache.WriteString(fmt.Sprintf("Symlink absolute path strategy: %v\n", cc.SymlinkAbsolutePathStrategy))
+		cache.WriteString(fmt.Sprintf("Supported compressors: %v\n", orNone(strings.Join(cc.SupportedCompressors, ", "))))
+		if len(cc.Priorities) > 0 {
+			cache.WriteString(fmt.Sprintf("Priorities: %v\n", strings.Join(cc.Priorities, ", ")))
+		}
+	}
+	writeSection(&res, "Cache Capabilities", cache.String())
+
+	var exec bytes.Buffer
+	if ec := j.Execution; ec == nil {
+		exec.WriteString("None\n")
+	} else {
+		exec.WriteString(fmt.Sprintf("Execution: %v\n", enabled(ec.ExecEnabled)))
+		exec.WriteString(fmt.Sprintf("Digest function: %v\n", ec.DigestFunction))
+		exec.WriteString(fmt.Sprintf("Supported node properties: %v\n", orNone(strings.Join(ec.SupportedNodeProperties, ", "))))
+		if len(ec.Priorities) > 0 {
+			exec.WriteString(fmt.Sprintf("Priorities: %v\n", strings.Join(ec.Priorities, ", ")))
+		}
+	}
+	writeSection(&res, "Execution Capabilities", exec.String())
+	return res.String()
+}
+
+// semverString returns v in major.minor[.patch][-prerelease] format, or an empty string if unset.
+func semverString(v *smpb.SemVer) string {
+	if v == nil {
+		return ""
+	}
+	s := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	if v.Patch != 0 {
+		s += fmt.Sprintf(".%d", v.Patch)
+	}
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// priorityRanges returns the priority ranges of pc in min..max format.
+func priorityRanges(pc *repb.PriorityCapabilities) []string {
+	var res []string
+	for _, p := range pc.GetPriorities() {
+		res = append(res, fmt.Sprintf("%d..%d", p.MinPriority, p.MaxPriority))
+	}
+	return res
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,64 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTool_ShowCapabilities(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+
+	got, err := toolClient.ShowCapabilities(context.Background(), TextFormat)
+	if err != nil {
+		t.Fatalf("ShowCapabilities(text) failed: %v", err)
+	}
+	want := "Server Capabilities\n===================\n" +
+		"API versions: none to none\n" +
+		"\nCache Capabilities\n==================\n" +
+		"Digest functions: SHA256\n" +
+		fmt.Sprintf("Max batch total size: %d bytes\n", client.DefaultMaxBatchSize) +
+		"Action cache updates: enabled\n" +
+		"Symlink absolute path strategy: DISALLOWED\n" +
+		"Supported compressors: ZSTD\n" +
+		"\nExecution Capabilities\n======================\n" +
+		"Execution: enabled\n" +
+		"Digest function: SHA256\n" +
+		"Supported node properties: none\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ShowCapabilities(text) returned diff (-want +got):\n%s", diff)
+	}
+
+	got, err = toolClient.ShowCapabilities(context.Background(), JSONFormat)
+	if err != nil {
+		t.Fatalf("ShowCapabilities(json) failed: %v", err)
+	}
+	caps := &capabilitiesJSON{}
+	if err := json.Unmarshal([]byte(got), caps); err != nil {
+		t.Fatalf("ShowCapabilities(json) returned invalid JSON %q: %v", got, err)
+	}
+	wantCaps := &capabilitiesJSON{
+		Cache: &cacheCapabilitiesJSON{
+			DigestFunctions:             []string{"SHA256"},
+			MaxBatchTotalSizeBytes:      client.DefaultMaxBatchSize,
+			ActionCacheUpdateEnabled:    true,
+			SymlinkAbsolutePathStrategy: "DISALLOWED",
+			SupportedCompressors:        []string{"ZSTD"},
+		},
+		Execution: &executionCapabilitiesJSON{
+			ExecEnabled:             true,
+			DigestFunction:          "SHA256",
+			SupportedNodeProperties: []string{},
+		},
+	}
+	if diff := cmp.Diff(wantCaps, caps); diff != "" {
+		t.Errorf("ShowCapabilities(json) returned diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,105 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// CheckInputs walks the input tree of the action with the given digest, and reports every blob of
+// the action missing from the CAS with its path in the tree: the Command, the Directory protos and
+// the input files. Missing blobs make executions fail with FAILED_PRECONDITION.
+func (c *Client) CheckInputs(ctx context.Context, actionDigest string) (string, error) {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return "", err
+	}
+	actionProto := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, acDg, actionProto); err != nil {
+		return "", err
+	}
+
+	cmdDg, err := digest.NewFromProto(actionProto.GetCommandDigest())
+	if err != nil {
+		return "", err
+	}
+	// The paths of each file in the tree, which may be referenced more than once.
+	paths := make(map[digest.Digest][]string)
+	var missing []string
+
+	c.infof("Walking the input tree of %v..", acDg)
+	type dir struct {
+		path string
+		dg   *repb.Digest
+	}
+	queue := []dir{{path: ".", dg: actionProto.GetInputRootDigest()}}
+	numDirs := 0
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		dg, err := digest.NewFromProto(d.dg)
+		if err != nil {
+			return "", err
+		}
+		numDirs++
+		dirPb := &repb.Directory{}
+		if _, err := c.GrpcClient.ReadProto(ctx, dg, dirPb); err != nil {
+			if status.Code(err) != codes.NotFound {
+				return "", err
+			}
+			missing = append(missing, fmt.Sprintf("%v: [Directory digest: %v]", d.path, dg))
+			continue
+		}
+		for _, f := range dirPb.Files {
+			fDg, err := digest.NewFromProto(f.Digest)
+			if err != nil {
+				return "", err
+			}
+			paths[fDg] = append(paths[fDg], path.Join(d.path, f.Name))
+		}
+		for _, sub := range dirPb.Directories {
+			queue = append(queue, dir{path: path.Join(d.path, sub.Name), dg: sub.Digest})
+		}
+	}
+
+	dgs := make([]digest.Digest, 0, len(paths)+1)
+	dgs = append(dgs, cmdDg)
+	for dg := range paths {
+		dgs = append(dgs, dg)
+	}
+	missingDgs, err := c.GrpcClient.MissingBlobs(ctx, dgs)
+	if err != nil {
+		return "", err
+	}
+	for _, dg := range missingDgs {
+		for _, p := range paths[dg] {
+			missing = append(missing, fmt.Sprintf("%v: [File digest: %v]", p, dg))
+		}
+	}
+	sort.Strings(missing)
+	for _, dg := range missingDgs {
+		if dg == cmdDg {
+			missing = append([]string{fmt.Sprintf("[Command digest: %v]", dg)}, missing...)
+		}
+	}
+
+	var res bytes.Buffer
+	if len(missing) == 0 {
+		res.WriteString(fmt.Sprintf("All inputs of action %v are present in the CAS (%d directories, %d files).\n", acDg, numDirs, len(paths)))
+		return res.String(), nil
+	}
+	res.WriteString(fmt.Sprintf("%d inputs of action %v are missing from the CAS:\n", len(missing), acDg))
+	for _, m := range missing {
+		res.WriteString(m)
+		res.WriteString("\n")
+	}
+	return res.String(), nil
+}
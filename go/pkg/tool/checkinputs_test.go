@@ -0,0 +1,72 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_CheckInputs(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+
+	presentDg := cas.Put([]byte("present")).ToProto()
+	missingDg := digest.NewFromBlob([]byte("missing"))
+	goneDir := &repb.Directory{Files: []*repb.FileNode{{Name: "f", Digest: presentDg}}}
+	goneDg := digest.TestNewFromMessage(goneDir)
+	subDg := put(&repb.Directory{Files: []*repb.FileNode{
+		{Name: "missing.txt", Digest: missingDg.ToProto()},
+		{Name: "present.txt", Digest: presentDg},
+	}})
+	rootDg := put(&repb.Directory{
+		Files: []*repb.FileNode{
+			{Name: "also_missing.txt", Digest: missingDg.ToProto()},
+			{Name: "present.txt", Digest: presentDg},
+		},
+		Directories: []*repb.DirectoryNode{
+			{Name: "gone", Digest: goneDg.ToProto()},
+			{Name: "sub", Digest: subDg},
+		},
+	})
+	cmdDg := put(&repb.Command{Arguments: []string{"tool"}})
+	acDg := digest.NewFromProtoUnvalidated(put(&repb.Action{CommandDigest: cmdDg, InputRootDigest: rootDg}))
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.CheckInputs(context.Background(), acDg.String())
+	if err != nil {
+		t.Fatalf("CheckInputs(%v) failed: %v", acDg, err)
+	}
+	want := "3 inputs of action " + acDg.String() + " are missing from the CAS:\n" +
+		"also_missing.txt: [File digest: " + missingDg.String() + "]\n" +
+		"gone: [Directory digest: " + goneDg.String() + "]\n" +
+		"sub/missing.txt: [File digest: " + missingDg.String() + "]\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckInputs(%v) returned diff (-want +got):\n%s", acDg, diff)
+	}
+
+	// Once the blobs are uploaded, no input is missing.
+	cas.Put([]byte("missing"))
+	put(goneDir)
+	got, err = toolClient.CheckInputs(context.Background(), acDg.String())
+	if err != nil {
+		t.Fatalf("CheckInputs(%v) failed: %v", acDg, err)
+	}
+	want = "All inputs of action " + acDg.String() + " are present in the CAS (3 directories, 2 files).\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckInputs(%v) returned diff (-want +got):\n%s", acDg, diff)
+	}
+}
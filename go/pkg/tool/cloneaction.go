@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ActionOverrides are the fields of an Action to override when cloning it. Unset fields are kept.
+type ActionOverrides struct {
+	// Timeout is the execution timeout of the action, kept if zero.
+	Timeout time.Duration
+	// DoNotCache is whether the result of the action must not be cached.
+	DoNotCache *bool
+	// Salt is the salt of the action, which makes its digest differ from the original one without
+	// changing what it does, e.g. to bypass the action cache.
+	Salt *string
+}
+
+// CloneAction copies the Action with the given digest, overriding the given fields, uploads the
+// copy to the CAS and returns its digest. The copy has the same Command and input root as the
+// original, which are expected to be in the CAS already.
+func (c *Client) CloneAction(ctx context.Context, actionDigest string, overrides *ActionOverrides) (digest.Digest, error) {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return digest.Empty, err
+	}
+	acPb := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, acDg, acPb); err != nil {
+		return digest.Empty, err
+	}
+	clone := proto.Clone(acPb).(*repb.Action)
+	if overrides.Timeout != 0 {
+		clone.Timeout = ptypes.DurationProto(overrides.Timeout)
+	}
+	if overrides.DoNotCache != nil {
+		clone.DoNotCache = *overrides.DoNotCache
+	}
+	if overrides.Salt != nil {
+		clone.Salt = []byte(*overrides.Salt)
+	}
+	ue, err := uploadinfo.EntryFromProto(clone)
+	if err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Uploading Action %v cloned from %v.", ue.Digest, acDg)
+	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, ue); err != nil {
+		return digest.Empty, err
+	}
+	return ue.Digest, nil
+}
+
+// RunAction executes the Action with the given digest as it is in the CAS, unlike ExecuteAction
+// which rebuilds it from its Command, accepting cached results. The stdout and stderr of the action
+// are written to oe, and its outputs are downloaded to outDir, if set.
+func (c *Client) RunAction(ctx context.Context, actionDigest, outDir string, oe outerr.OutErr) error {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return err
+	}
+	c.infof("Executing action %v..", acDg)
+	op, err := c.GrpcClient.ExecuteAndWait(ctx, &repb.ExecuteRequest{
+		InstanceName: c.GrpcClient.InstanceName,
+		ActionDigest: acDg.ToProto(),
+	})
+	if err != nil {
+		return err
+	}
+	return c.completeOperation(ctx, op, acDg.ToProto(), outDir, oe)
+}
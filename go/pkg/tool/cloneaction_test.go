@@ -0,0 +1,116 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_CloneAction(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"out"},
+		Timeout:     10 * time.Second,
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	_, acPb, _, err := toolClient.readAction(context.Background(), acDg.String())
+	if err != nil {
+		t.Fatalf("readAction(%v) failed: %v", acDg, err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides *ActionOverrides
+		want      func(*repb.Action)
+	}{
+		{
+			name:      "no overrides",
+			overrides: &ActionOverrides{},
+			want:      func(*repb.Action) {},
+		},
+		{
+			name:      "timeout",
+			overrides: &ActionOverrides{Timeout: time.Minute},
+			want:      func(ac *repb.Action) { ac.Timeout = ptypes.DurationProto(time.Minute) },
+		},
+		{
+			name:      "do not cache and salt",
+			overrides: &ActionOverrides{DoNotCache: proto.Bool(true), Salt: proto.String("salt")},
+			want: func(ac *repb.Action) {
+				ac.DoNotCache = true
+				ac.Salt = []byte("salt")
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotDg, err := toolClient.CloneAction(context.Background(), acDg.String(), tc.overrides)
+			if err != nil {
+				t.Fatalf("CloneAction(%v, %+v) failed: %v", acDg, tc.overrides, err)
+			}
+			got := &repb.Action{}
+			if _, err := toolClient.GrpcClient.ReadProto(context.Background(), gotDg, got); err != nil {
+				t.Fatalf("ReadProto(%v) failed: %v", gotDg, err)
+			}
+			want := proto.Clone(acPb).(*repb.Action)
+			tc.want(want)
+			if !proto.Equal(got, want) {
+				t.Errorf("CloneAction(%v, %+v) uploaded Action %v, want %v", acDg, tc.overrides, got, want)
+			}
+		})
+	}
+}
+
+func TestTool_RunAction(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"a/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus},
+		&fakes.OutputFile{Path: "a/out", Contents: "output"}, fakes.StdOut("stdout"))
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	tmpDir := t.TempDir()
+	oe := outerr.NewRecordingOutErr()
+	if err := toolClient.RunAction(context.Background(), acDg.String(), tmpDir, oe); err != nil {
+		t.Fatalf("RunAction(%v) failed: %v", acDg, err)
+	}
+	if got := string(oe.Stdout()); got != "stdout" {
+		t.Errorf("RunAction(%v) wrote stdout %q, want %q", acDg, got, "stdout")
+	}
+	fp := filepath.Join(tmpDir, "a/out")
+	got, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("Unable to read downloaded output %v: %v", fp, err)
+	}
+	if string(got) != "output" {
+		t.Errorf("RunAction(%v) downloaded %v with contents %q, want %q", acDg, fp, got, "output")
+	}
+}
@@ -0,0 +1,49 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+
+	cpb "github.com/bazelbuild/remote-apis-sdks/go/api/command"
+)
+
+// ComputeRoot computes the Merkle tree of the local directory at path, without any remote calls,
+// and returns its root digest with the stats of its inputs and blobs. If inputSpecPath is set, it is
+// the path of an InputSpec text proto (see go/api/command) listing the inputs of the tree relative
+// to path. Otherwise, the tree is that of the whole directory.
+func (c *Client) ComputeRoot(path, inputSpecPath string) (string, error) {
+	var is *command.InputSpec
+	if inputSpecPath != "" {
+		b, err := ioutil.ReadFile(inputSpecPath)
+		if err != nil {
+			return "", err
+		}
+		isPb := &cpb.InputSpec{}
+		if err := proto.UnmarshalText(string(b), isPb); err != nil {
+			return "", fmt.Errorf("error parsing input spec %v: %v", inputSpecPath, err)
+		}
+		is = command.FromProto(&cpb.Command{Input: isPb}).InputSpec
+	}
+	root, entries, stats, err := c.localTree(path, is)
+	if err != nil {
+		return "", err
+	}
+	var blobBytes int64
+	for _, ue := range entries {
+		blobBytes += ue.Digest.Size
+	}
+
+	var res bytes.Buffer
+	res.WriteString(fmt.Sprintf("Root digest: %v\n", root))
+	res.WriteString(fmt.Sprintf("Input files: %d\n", stats.InputFiles))
+	res.WriteString(fmt.Sprintf("Input directories: %d\n", stats.InputDirectories))
+	res.WriteString(fmt.Sprintf("Input symlinks: %d\n", stats.InputSymlinks))
+	res.WriteString(fmt.Sprintf("Total input bytes: %d\n", stats.TotalInputBytes))
+	res.WriteString(fmt.Sprintf("Unique blobs: %d (%d bytes)\n", len(entries), blobBytes))
+	return res.String(), nil
+}
@@ -0,0 +1,67 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+func TestTool_ComputeRoot(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	for p, contents := range map[string]string{"a": "a", "b/c": "cc"} {
+		path := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating directory for %v: %v", p, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing %v: %v", p, err)
+		}
+	}
+	uploaded, err := (&Client{GrpcClient: e.Client.GrpcClient}).UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+
+	// No connection is needed to compute the root digest.
+	toolClient := &Client{GrpcClient: &rc.Client{}}
+	got, err := toolClient.ComputeRoot(dir, "")
+	if err != nil {
+		t.Fatalf("ComputeRoot(%v) failed: %v", dir, err)
+	}
+	for _, want := range []string{
+		"Root digest: " + uploaded.String() + "\n",
+		"Input files: 2\n",
+		"Input directories: 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ComputeRoot(%v) = %q, want it to contain %q", dir, got, want)
+		}
+	}
+
+	spec := filepath.Join(t.TempDir(), "spec.textproto")
+	if err := ioutil.WriteFile(spec, []byte(`inputs: "b"`), 0644); err != nil {
+		t.Fatalf("failed writing input spec: %v", err)
+	}
+	got, err = toolClient.ComputeRoot(dir, spec)
+	if err != nil {
+		t.Fatalf("ComputeRoot(%v, %v) failed: %v", dir, spec, err)
+	}
+	for _, want := range []string{"Input files: 1\n", "Input directories: 2\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ComputeRoot(%v, %v) = %q, want it to contain %q", dir, spec, got, want)
+		}
+	}
+	if strings.Contains(got, uploaded.String()) {
+		t.Errorf("ComputeRoot(%v, %v) = %q, want a root digest other than that of the whole directory", dir, spec, got)
+	}
+}
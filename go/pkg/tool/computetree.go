@@ -0,0 +1,121 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/golang/protobuf/jsonpb"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+
+	cpb "github.com/bazelbuild/remote-apis-sdks/go/api/command"
+)
+
+// treeSpecJSON is a tree in the spec file of ComputeTrees. The file is a JSON array of trees, e.g.
+//
+//	[
+//	  {"name": "srcs", "root": "src", "input_spec": {"inputs": ["lib", "main.c"]}},
+//	  {"root": "/tmp/out", "input_spec": {"exclude_inputs": [{"regex": ".*\\.o$"}]}}
+//	]
+//
+// Root is the local directory of the tree, relative to the directory of the spec file unless
+// absolute. InputSpec is an InputSpec proto (see go/api/command) in JSON, listing the inputs of the
+// tree relative to its root, which are all the entries of the root if unset. Name identifies the
+// tree in the output, and defaults to its root.
+type treeSpecJSON struct {
+	Name      string          `json:"name"`
+	Root      string          `json:"root"`
+	InputSpec json.RawMessage `json:"input_spec"`
+}
+
+// treeSpec is a parsed treeSpecJSON.
+type treeSpec struct {
+	name string
+	root string
+	is   *command.InputSpec
+}
+
+// ComputeTrees computes the Merkle trees described by the JSON spec file at specPath (see
+// treeSpecJSON), and returns the root digest of each, one per line in name: digest format. If
+// upload is set, the blobs of the trees missing from the CAS are uploaded, e.g. to prepare the
+// inputs of actions built with upload_action. Otherwise, no remote calls are made.
+func (c *Client) ComputeTrees(ctx context.Context, specPath string, upload bool) (string, error) {
+	specs, err := readTreeSpecs(specPath)
+	if err != nil {
+		return "", err
+	}
+	var res bytes.Buffer
+	var entries []*uploadinfo.Entry
+	seen := make(map[digest.Digest]bool)
+	for _, s := range specs {
+		root, ues, _, err := c.localTree(s.root, s.is)
+		if err != nil {
+			return "", fmt.Errorf("error computing tree %v: %v", s.name, err)
+		}
+		res.WriteString(fmt.Sprintf("%v: %v\n", s.name, root))
+		for _, ue := range ues {
+			if !seen[ue.Digest] {
+				seen[ue.Digest] = true
+				entries = append(entries, ue)
+			}
+		}
+	}
+	if !upload {
+		return res.String(), nil
+	}
+	c.infof("Uploading %d blobs of %d trees.", len(entries), len(specs))
+	missing, moved, err := c.GrpcClient.UploadIfMissing(ctx, entries...)
+	if err != nil {
+		return "", err
+	}
+	res.WriteString(fmt.Sprintf("Uploaded %d of %d blobs (%d bytes)\n", len(missing), len(entries), moved))
+	return res.String(), nil
+}
+
+// readTreeSpecs reads the JSON spec file of ComputeTrees at path.
+func readTreeSpecs(path string) ([]*treeSpec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specsJSON []treeSpecJSON
+	if err := json.Unmarshal(b, &specsJSON); err != nil {
+		return nil, fmt.Errorf("error parsing tree spec file %v: %v", path, err)
+	}
+	if len(specsJSON) == 0 {
+		return nil, fmt.Errorf("tree spec file %v lists no trees", path)
+	}
+	names := make(map[string]bool)
+	var res []*treeSpec
+	for i, sj := range specsJSON {
+		if sj.Root == "" {
+			return nil, fmt.Errorf("tree %d of %v has no root", i, path)
+		}
+		s := &treeSpec{name: sj.Name, root: sj.Root}
+		if !filepath.IsAbs(s.root) {
+			s.root = filepath.Join(filepath.Dir(path), s.root)
+		}
+		if s.name == "" {
+			s.name = sj.Root
+		}
+		if names[s.name] {
+			return nil, fmt.Errorf("tree %d of %v has the same name as another tree: %v", i, path, s.name)
+		}
+		names[s.name] = true
+		if len(sj.InputSpec) > 0 {
+			isPb := &cpb.InputSpec{}
+			if err := jsonpb.Unmarshal(bytes.NewReader(sj.InputSpec), isPb); err != nil {
+				return nil, fmt.Errorf("error parsing the input spec of tree %v in %v: %v", s.name, path, err)
+			}
+			s.is = command.FromProto(&cpb.Command{Input: isPb}).InputSpec
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+func TestTool_ComputeTrees(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	for p, contents := range map[string]string{"src/a": "a", "src/b/c": "cc", "src/b/c.o": "obj"} {
+		path := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating directory for %v: %v", p, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing %v: %v", p, err)
+		}
+	}
+	spec := filepath.Join(dir, "trees.json")
+	if err := ioutil.WriteFile(spec, []byte(`[
+  {"name": "all", "root": "src"},
+  {"name": "a", "root": "src", "input_spec": {"inputs": ["a"]}},
+  {"root": "src/b", "input_spec": {"inputs": ["."], "exclude_inputs": [{"regex": ".*\\.o$"}]}}
+]`), 0644); err != nil {
+		t.Fatalf("failed writing tree spec: %v", err)
+	}
+	// The expected trees are computed from directories with the same contents.
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	wantRoots := make(map[string]string)
+	for name, files := range map[string]map[string]string{
+		"all": {"a": "a", "b/c": "cc", "b/c.o": "obj"},
+		"a":   {"a": "a"},
+		"b":   {"c": "cc"},
+	} {
+		root := t.TempDir()
+		for p, contents := range files {
+			path := filepath.Join(root, p)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed creating directory for %v: %v", p, err)
+			}
+			if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatalf("failed writing %v: %v", p, err)
+			}
+		}
+		dg, _, _, err := toolClient.localTree(root, nil)
+		if err != nil {
+			t.Fatalf("localTree(%v) failed: %v", root, err)
+		}
+		wantRoots[name] = dg.String()
+	}
+
+	// No connection is needed unless uploading.
+	got, err := (&Client{GrpcClient: &rc.Client{}}).ComputeTrees(context.Background(), spec, false)
+	if err != nil {
+		t.Fatalf("ComputeTrees(%v, false) failed: %v", spec, err)
+	}
+	want := "all: " + wantRoots["all"] + "\na: " + wantRoots["a"] + "\nsrc/b: " + wantRoots["b"] + "\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ComputeTrees(%v, false) returned diff (-want +got):\n%s", spec, diff)
+	}
+
+	got, err = toolClient.ComputeTrees(context.Background(), spec, true)
+	if err != nil {
+		t.Fatalf("ComputeTrees(%v, true) failed: %v", spec, err)
+	}
+	if !strings.HasPrefix(got, want) || !strings.Contains(got, "Uploaded ") {
+		t.Errorf("ComputeTrees(%v, true) = %q, want the root digests followed by the upload stats", spec, got)
+	}
+	for name, root := range wantRoots {
+		dg, err := digest.NewFromString(root)
+		if err != nil {
+			t.Fatalf("digest.NewFromString(%v) failed: %v", root, err)
+		}
+		if _, ok := e.Server.CAS.Get(dg); !ok {
+			t.Errorf("ComputeTrees(%v, true) did not upload the root of tree %v", spec, name)
+		}
+	}
+}
+
+func TestTool_ComputeTreesErrors(t *testing.T) {
+	dir := t.TempDir()
+	toolClient := &Client{GrpcClient: &rc.Client{}}
+	for name, contents := range map[string]string{
+		"not a list":      `{"root": "."}`,
+		"empty":           `[]`,
+		"no root":         `[{"name": "a"}]`,
+		"duplicate names": `[{"root": "."}, {"root": "."}]`,
+		"bad input spec":  `[{"root": ".", "input_spec": {"no_such_field": 1}}]`,
+		"missing root":    `[{"root": "missing"}]`,
+	} {
+		spec := filepath.Join(dir, "trees.json")
+		if err := ioutil.WriteFile(spec, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing tree spec: %v", err)
+		}
+		if got, err := toolClient.ComputeTrees(context.Background(), spec, false); err == nil {
+			t.Errorf("ComputeTrees() with %v spec = %q, want an error", name, got)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// DiffActions compares the two actions with the given digests, typically to find out why one did
+// not get a cache hit on the result of the other. It returns the differences between their
+// command arguments, environment variables, platform properties and other Action and Command
+// fields, and the input files added, removed or modified from the first action to the second.
+func (c *Client) DiffActions(ctx context.Context, actionDigestA, actionDigestB string) (string, error) {
+	acDgA, actionA, commandA, err := c.readAction(ctx, actionDigestA)
+	if err != nil {
+		return "", err
+	}
+	acDgB, actionB, commandB, err := c.readAction(ctx, actionDigestB)
+	if err != nil {
+		return "", err
+	}
+	if acDgA == acDgB {
+		return "Actions are identical.\n", nil
+	}
+
+	var res bytes.Buffer
+	res.WriteString(fmt.Sprintf("--- %v\n+++ %v\n", acDgA, acDgB))
+
+	var fields bytes.Buffer
+	diffField := func(name string, a, b interface{}) {
+		if sa, sb := fmt.Sprint(a), fmt.Sprint(b); sa != sb {
+			fields.WriteString(fmt.Sprintf("\t%s: %v -> %v\n", name, sa, sb))
+		}
+	}
+	diffField("Command digest", digestString(actionA.GetCommandDigest()), digestString(actionB.GetCommandDigest()))
+	diffField("Input root digest", digestString(actionA.GetInputRootDigest()), digestString(actionB.GetInputRootDigest()))
+	timeoutA, err := ptypes.Duration(actionA.GetTimeout())
+	if actionA.GetTimeout() != nil && err != nil {
+		return "", err
+	}
+	timeoutB, err := ptypes.Duration(actionB.GetTimeout())
+	if actionB.GetTimeout() != nil && err != nil {
+		return "", err
+	}
+	diffField("Timeout", timeoutA, timeoutB)
+	diffField("Do not cache", actionA.GetDoNotCache(), actionB.GetDoNotCache())
+	diffField("Working directory", commandA.GetWorkingDirectory(), commandB.GetWorkingDirectory())
+	diffField("Output files", commandA.GetOutputFiles(), commandB.GetOutputFiles())
+	diffField("Output directories", commandA.GetOutputDirectories(), commandB.GetOutputDirectories())
+	diffField("Output paths", commandA.GetOutputPaths(), commandB.GetOutputPaths())
+	writeSection(&res, "Action", fields.String())
+
+	writeSection(&res, "Arguments", diffArguments(commandA.GetArguments(), commandB.GetArguments()))
+
+	envA, envB := make(map[string]string), make(map[string]string)
+	for _, ev := range commandA.GetEnvironmentVariables() {
+		envA[ev.Name] = ev.Value
+	}
+	for _, ev := range commandB.GetEnvironmentVariables() {
+		envB[ev.Name] = ev.Value
+	}
+	writeSection(&res, "Environment Variables", diffMaps(envA, envB))
+
+	writeSection(&res, "Platform", diffMaps(platformMap(commandA.GetPlatform()), platformMap(commandB.GetPlatform())))
+
+	c.infof("Fetching input trees from input root digests..")
+	inputsA, err := c.flatInputs(ctx, actionA.GetInputRootDigest())
+	if err != nil {
+		return "", err
+	}
+	inputsB, err := c.flatInputs(ctx, actionB.GetInputRootDigest())
+	if err != nil {
+		return "", err
+	}
+	writeSection(&res, "Inputs", diffMaps(inputsA, inputsB))
+	return res.String(), nil
+}
+
+// writeSection writes the section with the given title and body, unless the body is empty.
+func writeSection(res *bytes.Buffer, title, body string) {
+	if body == "" {
+		return
+	}
+	res.WriteString(fmt.Sprintf("\n%s\n%s\n%s", title, strings.Repeat("=", len(title)), body))
+}
+
+// diffArguments returns the arguments differing between a and b, by position.
+func diffArguments(a, b []string) string {
+	var res bytes.Buffer
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(a):
+			res.WriteString(fmt.Sprintf("\t+ [%d] %q\n", i, b[i]))
+		case i >= len(b):
+			res.WriteString(fmt.Sprintf("\t- [%d] %q\n", i, a[i]))
+		case a[i] != b[i]:
+			res.WriteString(fmt.Sprintf("\t~ [%d] %q -> %q\n", i, a[i], b[i]))
+		}
+	}
+	return res.String()
+}
+
+// diffMaps returns the keys added, removed or modified from a to b, sorted.
+func diffMaps(a, b map[string]string) string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var res bytes.Buffer
+	for _, k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inA:
+			res.WriteString(fmt.Sprintf("\t+ %s: %s\n", k, vb))
+		case !inB:
+			res.WriteString(fmt.Sprintf("\t- %s: %s\n", k, va))
+		case va != vb:
+			res.WriteString(fmt.Sprintf("\t~ %s: %s -> %s\n", k, va, vb))
+		}
+	}
+	return res.String()
+}
+
+// platformMap returns the platform properties by name. The values of repeated properties are
+// joined by commas.
+func platformMap(p *repb.Platform) map[string]string {
+	res := make(map[string]string)
+	for _, prop := range p.GetProperties() {
+		if v, ok := res[prop.Name]; ok {
+			res[prop.Name] = v + "," + prop.Value
+		} else {
+			res[prop.Name] = prop.Value
+		}
+	}
+	return res
+}
+
+// flatInputs returns a description of each input of the tree with the given root, by path.
+func (c *Client) flatInputs(ctx context.Context, root *repb.Digest) (map[string]string, error) {
+	dirs, err := c.GrpcClient.GetDirectoryTree(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("empty directories returned by GetTree for %v", digestString(root))
+	}
+	outputs, err := c.GrpcClient.FlattenTree(&repb.Tree{Root: dirs[0], Children: dirs}, "")
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(outputs))
+	for path, o := range outputs {
+		if path == "" {
+			path = "."
+		}
+		res[path] = describeTreeOutput(o)
+	}
+	return res, nil
+}
+
+func describeTreeOutput(o *rc.TreeOutput) string {
+	switch {
+	case o.IsEmptyDirectory:
+		return fmt.Sprintf("[Directory digest: %v]", o.Digest)
+	case o.SymlinkTarget != "":
+		return fmt.Sprintf("[Symlink Target: %v]", o.SymlinkTarget)
+	case o.IsExecutable:
+		return fmt.Sprintf("[File digest: %v, executable]", o.Digest)
+	}
+	return fmt.Sprintf("[File digest: %v]", o.Digest)
+}
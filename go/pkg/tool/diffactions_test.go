@@ -0,0 +1,83 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func writeInputs(t *testing.T, execRoot string, files map[string]string) {
+	t.Helper()
+	for p, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(execRoot, p), []byte(contents), 0644); err != nil {
+			t.Fatalf("failed creating input file: %v", err)
+		}
+	}
+}
+
+func TestTool_DiffActions(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	opt := command.DefaultExecutionOptions()
+	res := &command.Result{Status: command.SuccessResultStatus}
+	cmdA := &command.Command{
+		Args:     []string{"tool", "--flag=a", "extra"},
+		ExecRoot: e.ExecRoot,
+		InputSpec: &command.InputSpec{
+			Inputs:               []string{"same.txt", "changed.txt", "removed.txt"},
+			EnvironmentVariables: map[string]string{"SAME": "1", "CHANGED": "a", "REMOVED": "1"},
+		},
+		Platform: map[string]string{"OSFamily": "Linux", "Pool": "a"},
+	}
+	writeInputs(t, e.ExecRoot, map[string]string{"same.txt": "same", "changed.txt": "a", "removed.txt": "removed"})
+	_, acDgA := e.Set(cmdA, opt, res)
+	cmdB := &command.Command{
+		Args:     []string{"tool", "--flag=b"},
+		ExecRoot: e.ExecRoot,
+		InputSpec: &command.InputSpec{
+			Inputs:               []string{"same.txt", "changed.txt", "added.txt"},
+			EnvironmentVariables: map[string]string{"SAME": "1", "CHANGED": "b", "ADDED": "1"},
+		},
+		Platform: map[string]string{"OSFamily": "Linux", "Pool": "b"},
+	}
+	writeInputs(t, e.ExecRoot, map[string]string{"changed.txt": "b", "added.txt": "added"})
+	_, acDgB := e.Set(cmdB, opt, res)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.DiffActions(context.Background(), acDgA.String(), acDgB.String())
+	if err != nil {
+		t.Fatalf("DiffActions(%v, %v) failed: %v", acDgA, acDgB, err)
+	}
+	want := []string{
+		"--- " + acDgA.String() + "\n+++ " + acDgB.String() + "\n",
+		"\n\t~ [1] \"--flag=a\" -> \"--flag=b\"\n\t- [2] \"extra\"\n",
+		"\n\t+ ADDED: 1\n\t~ CHANGED: a -> b\n\t- REMOVED: 1\n",
+		"\n\t~ Pool: a -> b\n",
+		"\n\t+ added.txt: [File digest: ",
+		"\n\t~ changed.txt: [File digest: ",
+		"\n\t- removed.txt: [File digest: ",
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("DiffActions(%v, %v) = %s, want it to contain %q", acDgA, acDgB, got, w)
+		}
+	}
+	for _, unchanged := range []string{"same.txt", "SAME", "OSFamily", "tool"} {
+		if strings.Contains(got, unchanged) {
+			t.Errorf("DiffActions(%v, %v) = %s, want it not to contain %q", acDgA, acDgB, got, unchanged)
+		}
+	}
+
+	got, err = toolClient.DiffActions(context.Background(), acDgA.String(), acDgA.String())
+	if err != nil {
+		t.Fatalf("DiffActions(%v, %v) failed: %v", acDgA, acDgA, err)
+	}
+	if got != "Actions are identical.\n" {
+		t.Errorf("DiffActions(%v, %v) = %s, want identical actions", acDgA, acDgA, got)
+	}
+}
@@ -0,0 +1,157 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// DiffTrees compares the two trees with the given digests, e.g. the input roots of two actions to
+// explain a cache miss, and returns the paths added, removed or modified from the first tree to
+// the second, with their old and new digests. Only the directories whose digests differ are
+// fetched and compared, so that comparing large, mostly identical trees is fast. The digests are
+// those of root Directory protos, or of Tree protos, e.g. of output directories, if isTree is set.
+func (c *Client) DiffTrees(ctx context.Context, digestA, digestB string, isTree bool) (string, error) {
+	dgA, err := digest.NewFromString(digestA)
+	if err != nil {
+		return "", err
+	}
+	dgB, err := digest.NewFromString(digestB)
+	if err != nil {
+		return "", err
+	}
+	if dgA == dgB {
+		return "Trees are identical.\n", nil
+	}
+	getDir := c.readDirectory
+	if isTree {
+		dirs := make(map[digest.Digest]*repb.Directory)
+		if dgA, err = c.readTreeDirs(ctx, dgA, dirs); err != nil {
+			return "", err
+		}
+		if dgB, err = c.readTreeDirs(ctx, dgB, dirs); err != nil {
+			return "", err
+		}
+		getDir = func(_ context.Context, dg digest.Digest) (*repb.Directory, error) {
+			d, ok := dirs[dg]
+			if !ok {
+				return nil, fmt.Errorf("directory %v is missing from the trees", dg)
+			}
+			return d, nil
+		}
+	}
+
+	a, b := make(map[string]string), make(map[string]string)
+	fetched := 0
+	var diffDirs func(dir string, dgA, dgB digest.Digest) error
+	diffDirs = func(dir string, dgA, dgB digest.Digest) error {
+		dirA, err := getDir(ctx, dgA)
+		if err != nil {
+			return err
+		}
+		dirB, err := getDir(ctx, dgB)
+		if err != nil {
+			return err
+		}
+		fetched += 2
+		entriesA, entriesB := dirEntries(dirA), dirEntries(dirB)
+		for name, ea := range entriesA {
+			p := path.Join(dir, name)
+			eb, ok := entriesB[name]
+			switch {
+			case !ok:
+				a[p] = ea.desc
+			case ea.desc == eb.desc:
+			case ea.isDir && eb.isDir:
+				// Only the differing subtrees are walked.
+				if err := diffDirs(p, ea.dg, eb.dg); err != nil {
+					return err
+				}
+			default:
+				a[p], b[p] = ea.desc, eb.desc
+			}
+		}
+		for name, eb := range entriesB {
+			if _, ok := entriesA[name]; !ok {
+				b[path.Join(dir, name)] = eb.desc
+			}
+		}
+		return nil
+	}
+	c.infof("Comparing trees %v and %v..", dgA, dgB)
+	if err := diffDirs("", dgA, dgB); err != nil {
+		return "", err
+	}
+	c.infof("Compared %d directories.", fetched)
+
+	diff := diffMaps(a, b)
+	if diff == "" {
+		return "Trees have identical contents.\n", nil
+	}
+	var res bytes.Buffer
+	res.WriteString(fmt.Sprintf("--- %v\n+++ %v\n", digestA, digestB))
+	res.WriteString(diff)
+	return res.String(), nil
+}
+
+// dirEntry is an entry of a Directory compared by DiffTrees.
+type dirEntry struct {
+	desc  string
+	isDir bool
+	dg    digest.Digest
+}
+
+// dirEntries returns the entries of dir by name, described in the format of describeTreeOutput.
+func dirEntries(dir *repb.Directory) map[string]dirEntry {
+	res := make(map[string]dirEntry)
+	for _, f := range dir.GetFiles() {
+		dg := digest.NewFromProtoUnvalidated(f.Digest)
+		desc := fmt.Sprintf("[File digest: %v]", dg)
+		if f.IsExecutable {
+			desc = fmt.Sprintf("[File digest: %v, executable]", dg)
+		}
+		res[f.Name] = dirEntry{desc: desc, dg: dg}
+	}
+	for _, d := range dir.GetDirectories() {
+		dg := digest.NewFromProtoUnvalidated(d.Digest)
+		res[d.Name] = dirEntry{desc: fmt.Sprintf("[Directory digest: %v]", dg), isDir: true, dg: dg}
+	}
+	for _, s := range dir.GetSymlinks() {
+		res[s.Name] = dirEntry{desc: fmt.Sprintf("[Symlink Target: %v]", s.Target)}
+	}
+	return res
+}
+
+// readDirectory reads the Directory proto with the given digest.
+func (c *Client) readDirectory(ctx context.Context, dg digest.Digest) (*repb.Directory, error) {
+	dir := &repb.Directory{}
+	if _, err := c.GrpcClient.ReadProto(ctx, dg, dir); err != nil {
+		return nil, fmt.Errorf("error reading directory %v: %v", dg, err)
+	}
+	return dir, nil
+}
+
+// readTreeDirs reads the Tree proto with the given digest, adds its directories to dirs by digest,
+// and returns the digest of its root directory.
+func (c *Client) readTreeDirs(ctx context.Context, dg digest.Digest, dirs map[digest.Digest]*repb.Directory) (digest.Digest, error) {
+	tree := &repb.Tree{}
+	if _, err := c.GrpcClient.ReadProto(ctx, dg, tree); err != nil {
+		return digest.Empty, fmt.Errorf("error reading tree %v: %v", dg, err)
+	}
+	var root digest.Digest
+	for i, d := range append([]*repb.Directory{tree.Root}, tree.Children...) {
+		dirDg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return digest.Empty, err
+		}
+		dirs[dirDg] = d
+		if i == 0 {
+			root = dirDg
+		}
+	}
+	return root, nil
+}
@@ -0,0 +1,94 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_DiffTrees(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+	str := func(dg *repb.Digest) string {
+		return digest.NewFromProtoUnvalidated(dg).String()
+	}
+
+	aDg := cas.Put([]byte("a")).ToProto()
+	bDg := cas.Put([]byte("b")).ToProto()
+	// The shared subtree is missing from the CAS, so walking it would fail.
+	sharedDg := digest.TestNewFromMessage(&repb.Directory{Files: []*repb.FileNode{{Name: "f", Digest: aDg}}}).ToProto()
+	subA := &repb.Directory{Files: []*repb.FileNode{{Name: "changed", Digest: aDg}, {Name: "removed", Digest: aDg}}}
+	subB := &repb.Directory{
+		Files:    []*repb.FileNode{{Name: "changed", Digest: bDg}},
+		Symlinks: []*repb.SymlinkNode{{Name: "added", Target: "changed"}},
+	}
+	rootA := &repb.Directory{
+		Files: []*repb.FileNode{{Name: "exec", Digest: aDg}, {Name: "same", Digest: aDg}, {Name: "type", Digest: aDg}},
+		Directories: []*repb.DirectoryNode{
+			{Name: "shared", Digest: sharedDg},
+			{Name: "sub", Digest: put(subA)},
+		},
+	}
+	newDirDg := put(&repb.Directory{Files: []*repb.FileNode{{Name: "f", Digest: bDg}}})
+	rootB := &repb.Directory{
+		Files: []*repb.FileNode{{Name: "exec", Digest: aDg, IsExecutable: true}, {Name: "same", Digest: aDg}},
+		Directories: []*repb.DirectoryNode{
+			{Name: "new", Digest: newDirDg},
+			{Name: "shared", Digest: sharedDg},
+			{Name: "sub", Digest: put(subB)},
+			{Name: "type", Digest: newDirDg},
+		},
+	}
+	rootDgA, rootDgB := put(rootA), put(rootB)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.DiffTrees(context.Background(), str(rootDgA), str(rootDgB), false)
+	if err != nil {
+		t.Fatalf("DiffTrees(%v, %v) failed: %v", str(rootDgA), str(rootDgB), err)
+	}
+	want := "--- " + str(rootDgA) + "\n+++ " + str(rootDgB) + "\n" +
+		"\t~ exec: [File digest: " + str(aDg) + "] -> [File digest: " + str(aDg) + ", executable]\n" +
+		"\t+ new: [Directory digest: " + str(newDirDg) + "]\n" +
+		"\t+ sub/added: [Symlink Target: changed]\n" +
+		"\t~ sub/changed: [File digest: " + str(aDg) + "] -> [File digest: " + str(bDg) + "]\n" +
+		"\t- sub/removed: [File digest: " + str(aDg) + "]\n" +
+		"\t~ type: [File digest: " + str(aDg) + "] -> [Directory digest: " + str(newDirDg) + "]\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffTrees(%v, %v) returned diff (-want +got):\n%s", str(rootDgA), str(rootDgB), diff)
+	}
+
+	if got, err := toolClient.DiffTrees(context.Background(), str(rootDgA), str(rootDgA), false); err != nil || got != "Trees are identical.\n" {
+		t.Errorf("DiffTrees() of identical trees = (%q, %v), want them identical", got, err)
+	}
+
+	// Tree protos hold all their directories.
+	treeDgA := put(&repb.Tree{Root: &repb.Directory{Files: []*repb.FileNode{{Name: "f", Digest: aDg}}}})
+	treeDgB := put(&repb.Tree{
+		Root:     &repb.Directory{Directories: []*repb.DirectoryNode{{Name: "d", Digest: newDirDg}}},
+		Children: []*repb.Directory{{Files: []*repb.FileNode{{Name: "f", Digest: bDg}}}},
+	})
+	got, err = toolClient.DiffTrees(context.Background(), str(treeDgA), str(treeDgB), true)
+	if err != nil {
+		t.Fatalf("DiffTrees(%v, %v) of Tree protos failed: %v", str(treeDgA), str(treeDgB), err)
+	}
+	want = "--- " + str(treeDgA) + "\n+++ " + str(treeDgB) + "\n" +
+		"\t+ d: [Directory digest: " + str(newDirDg) + "]\n" +
+		"\t- f: [File digest: " + str(aDg) + "]\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffTrees(%v, %v) of Tree protos returned diff (-want +got):\n%s", str(treeDgA), str(treeDgB), diff)
+	}
+}
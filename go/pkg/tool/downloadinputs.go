@@ -0,0 +1,27 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+// DownloadInputs downloads the input root of the action with the given digest to path, e.g. to
+// inspect the inputs of the action or to run it locally. Executable files keep their executable
+// bit and symlinks are recreated as symlinks. The include, exclude and resume arguments are those
+// of DownloadDirectory. It returns the digest of the input root and the working directory of the
+// command, relative to path.
+func (c *Client) DownloadInputs(ctx context.Context, actionDigest, path string, include, exclude []string, resume bool) (digest.Digest, string, error) {
+	_, actionProto, commandProto, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return digest.Empty, "", err
+	}
+	rootDg, err := digest.NewFromProto(actionProto.GetInputRootDigest())
+	if err != nil {
+		return digest.Empty, "", err
+	}
+	if err := c.DownloadDirectory(ctx, rootDg.String(), path, include, exclude, resume); err != nil {
+		return digest.Empty, "", err
+	}
+	return rootDg, commandProto.GetWorkingDirectory(), nil
+}
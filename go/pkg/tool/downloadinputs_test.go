@@ -0,0 +1,62 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_DownloadInputs(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+	sub := &repb.Directory{Files: []*repb.FileNode{{Name: "data", Digest: cas.Put([]byte("data")).ToProto()}}}
+	root := &repb.Directory{
+		Files:       []*repb.FileNode{{Name: "run.sh", Digest: cas.Put([]byte("#!/bin/sh")).ToProto(), IsExecutable: true}},
+		Directories: []*repb.DirectoryNode{{Name: "sub", Digest: put(sub)}},
+		Symlinks:    []*repb.SymlinkNode{{Name: "link", Target: "sub/data"}},
+	}
+	rootDg := put(root)
+	cmdDg := put(&repb.Command{Arguments: []string{"./run.sh"}, WorkingDirectory: "sub"})
+	acDg := digest.NewFromProtoUnvalidated(put(&repb.Action{CommandDigest: cmdDg, InputRootDigest: rootDg}))
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	outDir := t.TempDir()
+	gotDg, wd, err := toolClient.DownloadInputs(context.Background(), acDg.String(), outDir, nil, nil, false)
+	if err != nil {
+		t.Fatalf("DownloadInputs(%v) failed: %v", acDg, err)
+	}
+	if wantDg := digest.NewFromProtoUnvalidated(rootDg); gotDg != wantDg || wd != "sub" {
+		t.Errorf("DownloadInputs(%v) = %v, %q, want %v, %q", acDg, gotDg, wd, wantDg, "sub")
+	}
+	for path, want := range map[string]string{"run.sh": "#!/bin/sh", "sub/data": "data", "link": "data"} {
+		got, err := ioutil.ReadFile(filepath.Join(outDir, path))
+		if err != nil {
+			t.Fatalf("ReadFile(%v) failed: %v", path, err)
+		}
+		if string(got) != want {
+			t.Errorf("%v contains %q, want %q", path, got, want)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(outDir, "run.sh")); err != nil || fi.Mode()&0100 == 0 {
+		t.Errorf("Stat(run.sh) = %v, %v, want an executable file", fi, err)
+	}
+	if target, err := os.Readlink(filepath.Join(outDir, "link")); err != nil || target != "sub/data" {
+		t.Errorf("Readlink(link) = %q, %v, want %q", target, err, "sub/data")
+	}
+}
@@ -0,0 +1,143 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	"github.com/golang/protobuf/ptypes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// PlanUploadDirectory returns the RPCs UploadDirectory would issue to upload the local directory
+// at path, without issuing them: the number of digests looked up with FindMissingBlobs, and the
+// number of blobs and bytes uploaded if all of them are missing. The Merkle tree of the directory
+// is computed locally, the remote cache is not contacted.
+func (c *Client) PlanUploadDirectory(path string) (string, error) {
+	root, entries, stats, err := c.localTree(path, nil)
+	if err != nil {
+		return "", err
+	}
+	var res bytes.Buffer
+	fmt.Fprintf(&res, "Directory %v: root digest %v, %d files, %d directories, %d bytes\n", path, root, stats.InputFiles, stats.InputDirectories, stats.TotalInputBytes)
+	writeUploadPlan(&res, entryDigests(entries))
+	return res.String(), nil
+}
+
+// PlanReexecuteAction returns the RPCs ReexecuteAction would issue to execute the action with the
+// given overrides, without issuing them: the blobs looked up with FindMissingBlobs and uploaded if
+// missing, and the digest of the action executed. No blobs are uploaded and nothing is executed,
+// but unless actionRoot is set, the Action and Command protos and the Directory protos of the
+// input tree of the action are read from the CAS.
+func (c *Client) PlanReexecuteAction(ctx context.Context, actionDigest, actionRoot string, overrides *ExecuteOverrides) (string, error) {
+	var actionProto *repb.Action
+	var commandProto *repb.Command
+	var blobs []digest.Digest
+	inputRoot := ""
+	if actionRoot != "" {
+		var entries []*uploadinfo.Entry
+		var err error
+		if actionProto, commandProto, entries, err = readActionRoot(actionRoot); err != nil {
+			return "", err
+		}
+		blobs = entryDigests(entries)
+		inputRoot = filepath.Join(actionRoot, "input")
+	} else {
+		var err error
+		if _, actionProto, commandProto, err = c.readAction(ctx, actionDigest); err != nil {
+			return "", err
+		}
+	}
+	cmd := commandFromREProto(commandProto)
+	if actionProto.Timeout != nil {
+		tm, err := ptypes.Duration(actionProto.Timeout)
+		if err != nil {
+			return "", err
+		}
+		cmd.Timeout = tm
+	}
+	overrides.apply(cmd)
+	if len(cmd.Args) == 0 {
+		return "", fmt.Errorf("missing command arguments")
+	}
+
+	var root digest.Digest
+	if inputRoot != "" {
+		// As prepCommand, all the entries of the local input root are inputs.
+		contents, err := ioutil.ReadDir(inputRoot)
+		if err != nil {
+			return "", err
+		}
+		cmd.ExecRoot = inputRoot
+		for _, f := range contents {
+			cmd.InputSpec.Inputs = append(cmd.InputSpec.Inputs, f.Name())
+		}
+		var entries []*uploadinfo.Entry
+		if root, entries, _, err = c.GrpcClient.ComputeMerkleTree(cmd.ExecRoot, cmd.WorkingDir, cmd.RemoteWorkingDir, cmd.InputSpec, filemetadata.NewNoopCache()); err != nil {
+			return "", err
+		}
+		blobs = append(blobs, entryDigests(entries)...)
+	} else {
+		// The input root of the action is downloaded and uploaded back unchanged.
+		var err error
+		if root, err = digest.NewFromProto(actionProto.GetInputRootDigest()); err != nil {
+			return "", err
+		}
+		dirs, err := c.GrpcClient.GetDirectoryTree(ctx, root.ToProto())
+		if err != nil {
+			return "", err
+		}
+		for _, dir := range dirs {
+			dg, err := digest.NewFromMessage(dir)
+			if err != nil {
+				return "", err
+			}
+			blobs = append(blobs, dg)
+			for _, f := range dir.Files {
+				blobs = append(blobs, digest.NewFromProtoUnvalidated(f.Digest))
+			}
+		}
+	}
+
+	cmdUe, acUe, err := c.actionEntries(cmd, root)
+	if err != nil {
+		return "", err
+	}
+	cmdDg, acDg := cmdUe.Digest, acUe.Digest
+	blobs = append(blobs, cmdDg, acDg)
+
+	var res bytes.Buffer
+	writeUploadPlan(&res, blobs)
+	fmt.Fprintf(&res, "Execute: action %v, command %v, input root %v, skip cache lookup\n", acDg, cmdDg, root)
+	return res.String(), nil
+}
+
+// writeUploadPlan writes the FindMissingBlobs and upload RPCs issued to upload the given blobs to
+// res. Duplicate blobs are only looked up and uploaded once, and empty blobs never, as in
+// UploadIfMissing.
+func writeUploadPlan(res *bytes.Buffer, blobs []digest.Digest) {
+	seen := make(map[digest.Digest]bool, len(blobs))
+	var size int64
+	for _, dg := range blobs {
+		if !dg.IsEmpty() && !seen[dg] {
+			seen[dg] = true
+			size += dg.Size
+		}
+	}
+	fmt.Fprintf(res, "FindMissingBlobs: %d digests\n", len(seen))
+	fmt.Fprintf(res, "Upload of the missing blobs: at most %d blobs, %d bytes\n", len(seen), size)
+}
+
+func entryDigests(entries []*uploadinfo.Entry) []digest.Digest {
+	dgs := make([]digest.Digest, len(entries))
+	for i, ue := range entries {
+		dgs[i] = ue.Digest
+	}
+	return dgs
+}
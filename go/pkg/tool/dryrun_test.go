@@ -0,0 +1,85 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_PlanUploadDirectory(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("failed creating directory: %v", err)
+	}
+	for path, content := range map[string]string{"a": "aa", "sub/b": "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("failed creating input file: %v", err)
+		}
+	}
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.PlanUploadDirectory(dir)
+	if err != nil {
+		t.Fatalf("PlanUploadDirectory(%v) failed: %v", dir, err)
+	}
+	if n := e.Server.CAS.WriteReqs() + e.Server.CAS.BatchReqs(); n != 0 {
+		t.Errorf("PlanUploadDirectory(%v) issued %d write requests, want 0", dir, n)
+	}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+	// The root and sub directories, and the two files.
+	for _, want := range []string{"root digest " + root.String(), "FindMissingBlobs: 4 digests\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PlanUploadDirectory(%v) = %q, want it to contain %q", dir, got, want)
+		}
+	}
+}
+
+func TestTool_PlanReexecuteAction(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"a/b/out"},
+		Platform:    map[string]string{"pool": "default"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+	cmd.Platform = map[string]string{"pool": "large"}
+	_, wantDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+	writes := e.Server.CAS.WriteReqs()
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	overrides := &ExecuteOverrides{Platform: map[string]string{"pool": "large"}}
+	got, err := toolClient.PlanReexecuteAction(context.Background(), acDg.String(), "", overrides)
+	if err != nil {
+		t.Fatalf("PlanReexecuteAction(%v) failed: %v", acDg, err)
+	}
+	// The input root and the input file, the Command and the Action.
+	for _, want := range []string{"FindMissingBlobs: 4 digests\n", "Execute: action " + wantDg.String()} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PlanReexecuteAction(%v) = %q, want it to contain %q", acDg, got, want)
+		}
+	}
+	if n := e.Server.Exec.ExecuteCalls(); n != 0 {
+		t.Errorf("PlanReexecuteAction(%v) issued %d Execute requests, want 0", acDg, n)
+	}
+	if n := e.Server.CAS.WriteReqs(); n != writes {
+		t.Errorf("PlanReexecuteAction(%v) issued %d Write requests, want 0", acDg, n-writes)
+	}
+}
@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// dumpInputsHeader are the columns written by DumpInputs.
+var dumpInputsHeader = []string{"path", "digest", "size", "executable", "symlink_target"}
+
+// DumpInputs writes a line for every file and symlink in the input tree of the action with the
+// given digest, with its path, hash, size, whether it is executable and its target for symlinks,
+// e.g. to analyze the inputs in a spreadsheet or to diff those of two builds with standard tools.
+// The lines are sorted by path, after a header naming the columns. They are written to the file
+// at path, tab-separated if its name ends with .tsv and comma-separated otherwise, or to out as
+// CSV if path is empty.
+func (c *Client) DumpInputs(ctx context.Context, actionDigest, path string, out io.Writer) (err error) {
+	_, actionProto, _, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return err
+	}
+	c.infof("Fetching input tree from input root digest..")
+	dirs, err := c.GrpcClient.GetDirectoryTree(ctx, actionProto.GetInputRootDigest())
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("empty directories returned by GetTree for %v", digestString(actionProto.GetInputRootDigest()))
+	}
+	outputs, err := c.GrpcClient.FlattenTree(&repb.Tree{Root: dirs[0], Children: dirs}, "")
+	if err != nil {
+		return err
+	}
+	var entries [][]string
+	for p, o := range outputs {
+		switch {
+		case o.IsEmptyDirectory:
+			continue
+		case o.SymlinkTarget != "":
+			entries = append(entries, []string{p, "", "", "", o.SymlinkTarget})
+		default:
+			entries = append(entries, []string{p, o.Digest.Hash, strconv.FormatInt(o.Digest.Size, 10), strconv.FormatBool(o.IsExecutable), ""})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i][0] < entries[j][0] })
+
+	w := csv.NewWriter(out)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = csv.NewWriter(f)
+		if strings.HasSuffix(path, ".tsv") {
+			w.Comma = '\t'
+		}
+	}
+	c.infof("Writing %d inputs of action %v.", len(entries), actionDigest)
+	if err := w.Write(dumpInputsHeader); err != nil {
+		return err
+	}
+	if err := w.WriteAll(entries); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTool_DumpInputs(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"tool"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"a/b/input.txt", "run.sh"}},
+		OutputFiles: []string{"out"},
+	}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus},
+		&fakes.InputFile{Path: "a/b/input.txt", Contents: "input"}, &fakes.InputFile{Path: "run.sh", Contents: "#!/bin/sh"})
+	// The fake environment creates the inputs as empty executable files.
+	empty := digest.Empty.Hash
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	var out bytes.Buffer
+	if err := toolClient.DumpInputs(context.Background(), acDg.String(), "", &out); err != nil {
+		t.Fatalf("DumpInputs(%v) failed: %v", acDg, err)
+	}
+	want := "path,digest,size,executable,symlink_target\n" +
+		"a/b/input.txt," + empty + ",0,true,\n" +
+		"run.sh," + empty + ",0,true,\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("DumpInputs(%v) returned diff (-want +got):\n%s", acDg, diff)
+	}
+
+	path := filepath.Join(t.TempDir(), "inputs.tsv")
+	if err := toolClient.DumpInputs(context.Background(), acDg.String(), path, nil); err != nil {
+		t.Fatalf("DumpInputs(%v, %v) failed: %v", acDg, path, err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading %v: %v", path, err)
+	}
+	want = "path\tdigest\tsize\texecutable\tsymlink_target\n" +
+		"a/b/input.txt\t" + empty + "\t0\ttrue\t\n" +
+		"run.sh\t" + empty + "\t0\ttrue\t\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("DumpInputs(%v, %v) wrote diff (-want +got):\n%s", acDg, path, diff)
+	}
+}
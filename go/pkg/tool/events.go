@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	// StartEvent is written when an operation starts.
+	StartEvent EventType = "start"
+	// ProgressEvent is written for each step of an operation, with the message logged for it.
+	ProgressEvent EventType = "progress"
+	// ResultEvent is written when an operation succeeds, with its output.
+	ResultEvent EventType = "result"
+	// ErrorEvent is written when an operation fails, with its error.
+	ErrorEvent EventType = "error"
+)
+
+// Event is a machine-parsable record of the progress or the outcome of an operation.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// Operation is the name of the operation, unset for progress events.
+	Operation string `json:"operation,omitempty"`
+	// Source identifies where the operation was read from, e.g. a line of an operations file.
+	Source string `json:"source,omitempty"`
+	// Severity is the log severity of a progress event: info, warning or error.
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// Output and Stderr are what the operation would have written to stdout and stderr.
+	Output string `json:"output,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Duration is the duration of the operation, in nanoseconds.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// EventLog writes events as JSON lines. It is safe for concurrent use.
+type EventLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventLog returns an EventLog writing to w.
+func NewEventLog(w io.Writer) *EventLog {
+	return &EventLog{enc: json.NewEncoder(w)}
+}
+
+// Write writes e on a line of its own, setting its time if unset.
+func (l *EventLog) Write(e *Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(e)
+}
+
+// infof logs the progress of an operation, and writes it as a progress event if the client has
+// an EventLog.
+func (c *Client) infof(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.InfoDepth(1, msg)
+	c.progress("info", msg)
+}
+
+// warningf is like infof, with the warning severity.
+func (c *Client) warningf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.WarningDepth(1, msg)
+	c.progress("warning", msg)
+}
+
+// errorf is like infof, with the error severity.
+func (c *Client) errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.ErrorDepth(1, msg)
+	c.progress("error", msg)
+}
+
+func (c *Client) progress(severity, msg string) {
+	if c.Events == nil {
+		return
+	}
+	if err := c.Events.Write(&Event{Type: ProgressEvent, Severity: severity, Message: msg}); err != nil {
+		log.Errorf("error writing progress event: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_Events(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	tmpFile := filepath.Join(t.TempDir(), "blob")
+	if err := ioutil.WriteFile(tmpFile, []byte("Hello, World!"), 0777); err != nil {
+		t.Fatalf("Could not create temp blob: %v", err)
+	}
+
+	var buf bytes.Buffer
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient, Events: NewEventLog(&buf)}
+	if err := toolClient.UploadBlob(context.Background(), tmpFile); err != nil {
+		t.Fatalf("UploadBlob(%v) failed: %v", tmpFile, err)
+	}
+	if err := toolClient.Events.Write(&Event{Type: ResultEvent, Operation: "upload_blob"}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	var events []*Event
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		ev := &Event{}
+		if err := json.Unmarshal(sc.Bytes(), ev); err != nil {
+			t.Fatalf("line %q is not a JSON event: %v", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) < 2 {
+		t.Fatalf("got %d events, want a progress and a result event", len(events))
+	}
+	for _, ev := range events[:len(events)-1] {
+		if ev.Type != ProgressEvent || ev.Severity != "info" || ev.Message == "" || ev.Time.IsZero() {
+			t.Errorf("got event %+v, want an info progress event with a message and a time", ev)
+		}
+	}
+	if last := events[len(events)-1]; last.Type != ResultEvent || last.Operation != "upload_blob" || last.Time.IsZero() {
+		t.Errorf("got last event %+v, want the upload_blob result event with a time", last)
+	}
+}
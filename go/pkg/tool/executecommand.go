@@ -0,0 +1,88 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/rexec"
+
+	cpb "github.com/bazelbuild/remote-apis-sdks/go/api/command"
+)
+
+// ExecuteCommand executes the command described by the spec at specPath remotely, turning
+// remotetool into a client for ad-hoc commands. The spec is a Command proto (see go/api/command),
+// in JSON if its file name ends with .json and in text format otherwise, with the arguments,
+// environment variables, platform and outputs of the command. Its exec root is the local input
+// root, relative to the directory of the spec unless absolute. All the entries of the input root
+// are inputs unless the spec lists them.
+// The inputs missing from the CAS are uploaded, and the Action is built and executed, accepting
+// cached results. The stdout and stderr of the command are written to oe, and its outputs are
+// downloaded to outDir, if set.
+func (c *Client) ExecuteCommand(ctx context.Context, specPath, outDir string, oe outerr.OutErr) (*command.Metadata, error) {
+	cmd, err := readCommandSpec(specPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(cmd.InputSpec.Inputs) == 0 && len(cmd.InputSpec.VirtualInputs) == 0 {
+		contents, err := ioutil.ReadDir(cmd.ExecRoot)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range contents {
+			cmd.InputSpec.Inputs = append(cmd.InputSpec.Inputs, f.Name())
+		}
+	}
+	client := &rexec.Client{
+		FileMetadataCache: filemetadata.NewNoopCache(),
+		GrpcClient:        c.GrpcClient,
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true, DownloadOutputs: false, DownloadOutErr: true}
+	ec, err := client.NewContext(ctx, cmd, opt, oe)
+	if err != nil {
+		return nil, err
+	}
+	c.infof("Executing %v with inputs from %v.", strings.Join(cmd.Args, " "), cmd.ExecRoot)
+	ec.ExecuteRemotely()
+	printExecution(ec, cmd, oe)
+	if ec.Result.Err == nil && outDir != "" {
+		ec.DownloadOutputs(outDir)
+		fmt.Printf("Output written to %v\n", outDir)
+	}
+	return ec.Metadata, ec.Result.Err
+}
+
+// readCommandSpec reads the Command proto at path, in JSON or in text format, and resolves its
+// exec root relative to the directory of path.
+func readCommandSpec(path string) (*command.Command, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cmdPb := &cpb.Command{}
+	if strings.HasSuffix(path, ".json") {
+		err = jsonpb.Unmarshal(bytes.NewReader(b), cmdPb)
+	} else {
+		err = proto.UnmarshalText(string(b), cmdPb)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing command spec %v: %v", path, err)
+	}
+	cmd := command.FromProto(cmdPb)
+	if cmd.ExecRoot == "" {
+		return nil, fmt.Errorf("command spec %v has no exec_root", path)
+	}
+	if !filepath.IsAbs(cmd.ExecRoot) {
+		cmd.ExecRoot = filepath.Join(filepath.Dir(path), cmd.ExecRoot)
+	}
+	return cmd, nil
+}
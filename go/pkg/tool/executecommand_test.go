@@ -0,0 +1,78 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+)
+
+func TestTool_ExecuteCommand(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	specDir := t.TempDir()
+	inputRoot := filepath.Join(specDir, "input")
+	if err := os.Mkdir(inputRoot, 0755); err != nil {
+		t.Fatalf("failed creating input root: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(inputRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	cmd := &command.Command{
+		Args:        []string{"cat", "i1"},
+		ExecRoot:    inputRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}, EnvironmentVariables: map[string]string{"K": "V"}},
+		OutputFiles: []string{"a/out"},
+		Platform:    map[string]string{"OSFamily": "Linux"},
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true, DownloadOutputs: false, DownloadOutErr: true}
+	e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/out", Contents: "output"},
+		fakes.StdOut("stdout"), fakes.StdErr("stderr"))
+
+	specs := map[string]string{
+		"cmd.json": `{
+			"args": ["cat", "i1"],
+			"exec_root": "input",
+			"input": {"environment_variables": {"K": "V"}},
+			"output": {"output_files": ["a/out"]},
+			"platform": {"OSFamily": "Linux"}
+		}`,
+		"cmd.textproto": `
+			args: "cat"
+			args: "i1"
+			exec_root: "input"
+			input: {inputs: "i1" environment_variables: {key: "K" value: "V"}}
+			output: {output_files: "a/out"}
+			platform: {key: "OSFamily" value: "Linux"}
+		`,
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	for name, spec := range specs {
+		t.Run(name, func(t *testing.T) {
+			specPath := filepath.Join(specDir, name)
+			if err := ioutil.WriteFile(specPath, []byte(spec), 0644); err != nil {
+				t.Fatalf("failed writing spec: %v", err)
+			}
+			outDir := t.TempDir()
+			oe := outerr.NewRecordingOutErr()
+			if _, err := toolClient.ExecuteCommand(context.Background(), specPath, outDir, oe); err != nil {
+				t.Fatalf("ExecuteCommand(%v) failed: %v", specPath, err)
+			}
+			if string(oe.Stdout()) != "stdout" || string(oe.Stderr()) != "stderr" {
+				t.Errorf("ExecuteCommand(%v) wrote stdout %q and stderr %q, want \"stdout\" and \"stderr\"", specPath, oe.Stdout(), oe.Stderr())
+			}
+			out, err := ioutil.ReadFile(filepath.Join(outDir, "a/out"))
+			if err != nil {
+				t.Fatalf("failed reading downloaded output: %v", err)
+			}
+			if string(out) != "output" {
+				t.Errorf("downloaded output contains %q, want \"output\"", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package tool
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+const (
+	runScriptFile = "run.sh"
+	manifestFile  = "manifest.json"
+)
+
+// exportManifest is the manifest.json of an exported action. Digests are in
+// <hash>/<size_bytes> format.
+type exportManifest struct {
+	ActionDigest     string         `json:"action_digest"`
+	CommandDigest    string         `json:"command_digest"`
+	InputRootDigest  string         `json:"input_root_digest"`
+	Arguments        []string       `json:"arguments"`
+	WorkingDirectory string         `json:"working_directory,omitempty"`
+	Platform         []propertyJSON `json:"platform,omitempty"`
+	Timeout          string         `json:"timeout,omitempty"`
+	DoNotCache       bool           `json:"do_not_cache,omitempty"`
+}
+
+// ExportAction writes the action with the given digest into a self-contained gzipped tarball at
+// path, so that it can be reproduced offline or attached to a bug report. The tarball has the
+// layout of DownloadAction: ac.textproto, cmd.textproto and the input tree under input/, which
+// can be passed to ExecuteAction as the action root once extracted. It also contains a run.sh
+// script running the command locally in the input tree, and a manifest.json describing the action.
+func (c *Client) ExportAction(ctx context.Context, actionDigest, path string) error {
+	dir, err := ioutil.TempDir("", "export_action")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := c.DownloadAction(ctx, actionDigest, dir); err != nil {
+		return err
+	}
+	acDg, actionProto, commandProto, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return err
+	}
+
+	m := &exportManifest{
+		ActionDigest:     acDg.String(),
+		CommandDigest:    digestString(actionProto.GetCommandDigest()),
+		InputRootDigest:  digestString(actionProto.GetInputRootDigest()),
+		Arguments:        commandProto.GetArguments(),
+		WorkingDirectory: commandProto.GetWorkingDirectory(),
+		DoNotCache:       actionProto.GetDoNotCache(),
+	}
+	for _, p := range commandProto.GetPlatform().GetProperties() {
+		m.Platform = append(m.Platform, propertyJSON{Name: p.Name, Value: p.Value})
+	}
+	if actionProto.Timeout != nil {
+		timeout, err := ptypes.Duration(actionProto.Timeout)
+		if err != nil {
+			return err
+		}
+		m.Timeout = timeout.String()
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, manifestFile), append(manifest, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, runScriptFile), []byte(runScript(commandProto)), 0755); err != nil {
+		return err
+	}
+
+	c.infof("Writing action %v to %v.", acDg, path)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	if err := tarDirectory(dir, tw); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// runScript returns a shell script running the command in the input tree next to it.
+func runScript(cmd *repb.Command) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Runs the exported action locally, in its input tree.\n")
+	b.WriteString("set -e\n")
+	b.WriteString(fmt.Sprintf("cd \"$(dirname \"$0\")\"/%s\n", shellQuote(filepath.ToSlash(filepath.Join("input", cmd.GetWorkingDirectory())))))
+	for _, ev := range cmd.GetEnvironmentVariables() {
+		b.WriteString(fmt.Sprintf("export %s=%s\n", ev.Name, shellQuote(ev.Value)))
+	}
+	args := make([]string, len(cmd.GetArguments()))
+	for i, a := range cmd.GetArguments() {
+		args[i] = shellQuote(a)
+	}
+	b.WriteString(fmt.Sprintf("exec %s\n", strings.Join(args, " ")))
+	return b.String()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// tarDirectory writes the contents of dir to tw, with paths relative to dir.
+func tarDirectory(dir string, tw *tar.Writer) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
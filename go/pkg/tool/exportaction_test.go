@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTool_ExportAction(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:       []string{"tool", "it's"},
+		ExecRoot:   e.ExecRoot,
+		WorkingDir: "wd",
+		InputSpec: &command.InputSpec{
+			Inputs:               []string{"wd/in"},
+			EnvironmentVariables: map[string]string{"VAR": "a b"},
+		},
+		Platform: map[string]string{"OSFamily": "Linux"},
+	}
+	if err := os.MkdirAll(filepath.Join(e.ExecRoot, "wd"), 0755); err != nil {
+		t.Fatalf("failed creating input dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "wd/in"), []byte("input"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	_, acDg := e.Set(cmd, command.DefaultExecutionOptions(), &command.Result{Status: command.SuccessResultStatus})
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	path := filepath.Join(t.TempDir(), "action.tar.gz")
+	if err := toolClient.ExportAction(context.Background(), acDg.String(), path); err != nil {
+		t.Fatalf("ExportAction(%v) failed: %v", acDg, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed opening archive: %v", err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed reading gzip archive: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	got := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed reading tar archive: %v", err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed reading %v from tar archive: %v", hdr.Name, err)
+		}
+		got[hdr.Name] = string(b)
+	}
+	for _, name := range []string{"ac.textproto", "cmd.textproto", "input/", "input/wd/", "manifest.json"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("ExportAction(%v) archive has no %v, got entries %v", acDg, name, got)
+		}
+	}
+	if got["input/wd/in"] != "input" {
+		t.Errorf("ExportAction(%v) archive has input/wd/in = %q, want \"input\"", acDg, got["input/wd/in"])
+	}
+	wantScript := `#!/bin/sh
+# Runs the exported action locally, in its input tree.
+set -e
+cd "$(dirname "$0")"/'input/wd'
+export VAR='a b'
+exec 'tool' 'it'\''s'
+`
+	if diff := cmp.Diff(wantScript, got["run.sh"]); diff != "" {
+		t.Errorf("ExportAction(%v) wrote run.sh with diff (-want +got):\n%s", acDg, diff)
+	}
+	m := &exportManifest{}
+	if err := json.Unmarshal([]byte(got["manifest.json"]), m); err != nil {
+		t.Fatalf("ExportAction(%v) wrote invalid manifest.json: %v", acDg, err)
+	}
+	if m.ActionDigest != acDg.String() || m.WorkingDirectory != "wd" {
+		t.Errorf("ExportAction(%v) wrote unexpected manifest.json %+v", acDg, m)
+	}
+}
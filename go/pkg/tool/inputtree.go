@@ -0,0 +1,126 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// treeStats are the totals of an input tree.
+type treeStats struct {
+	files, dirs, symlinks int
+	bytes                 int64
+}
+
+// ShowInputTree returns the input tree of the action with the given digest, listed recursively
+// with the digest and size of every file, its executable bit and the target of every symlink. Each
+// directory is listed with the number of files and total bytes under it, to find out what dominates
+// the input footprint of the action. Directories deeper than depth are summarized rather than
+// listed, unless depth is negative.
+func (c *Client) ShowInputTree(ctx context.Context, actionDigest string, depth int) (string, error) {
+	acDg, actionProto, _, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	rootDg, err := digest.NewFromProto(actionProto.GetInputRootDigest())
+	if err != nil {
+		return "", err
+	}
+	c.infof("Fetching input tree of %v..", acDg)
+	dirPbs, err := c.GrpcClient.GetDirectoryTree(ctx, actionProto.GetInputRootDigest())
+	if err != nil {
+		return "", err
+	}
+	dirs := make(map[digest.Digest]*repb.Directory, len(dirPbs))
+	for _, d := range dirPbs {
+		dg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return "", err
+		}
+		dirs[dg] = d
+	}
+
+	stats := make(map[digest.Digest]*treeStats)
+	var statsOf func(dg digest.Digest) (*treeStats, error)
+	statsOf = func(dg digest.Digest) (*treeStats, error) {
+		if s, ok := stats[dg]; ok {
+			return s, nil
+		}
+		d, ok := dirs[dg]
+		if !ok {
+			return nil, fmt.Errorf("directory %v missing from the input tree", dg)
+		}
+		s := &treeStats{files: len(d.Files), dirs: len(d.Directories), symlinks: len(d.Symlinks)}
+		for _, f := range d.Files {
+			s.bytes += f.GetDigest().GetSizeBytes()
+		}
+		for _, sub := range d.Directories {
+			subDg, err := digest.NewFromProto(sub.Digest)
+			if err != nil {
+				return nil, err
+			}
+			subStats, err := statsOf(subDg)
+			if err != nil {
+				return nil, err
+			}
+			s.files += subStats.files
+			s.dirs += subStats.dirs
+			s.symlinks += subStats.symlinks
+			s.bytes += subStats.bytes
+		}
+		stats[dg] = s
+		return s, nil
+	}
+
+	var res bytes.Buffer
+	var list func(dg digest.Digest, level int) error
+	list = func(dg digest.Digest, level int) error {
+		d := dirs[dg]
+		indent := strings.Repeat("  ", level)
+		for _, sub := range d.Directories {
+			subDg, err := digest.NewFromProto(sub.Digest)
+			if err != nil {
+				return err
+			}
+			s, err := statsOf(subDg)
+			if err != nil {
+				return err
+			}
+			res.WriteString(fmt.Sprintf("%s%s/ [Directory digest: %v] %d files, %d bytes\n", indent, sub.Name, subDg, s.files, s.bytes))
+			if depth < 0 || level < depth {
+				if err := list(subDg, level+1); err != nil {
+					return err
+				}
+			}
+		}
+		for _, f := range d.Files {
+			exec := ""
+			if f.IsExecutable {
+				exec = ", executable"
+			}
+			res.WriteString(fmt.Sprintf("%s%s [File digest: %v] %d bytes%s\n", indent, f.Name, digestString(f.Digest), f.GetDigest().GetSizeBytes(), exec))
+		}
+		for _, sl := range d.Symlinks {
+			res.WriteString(fmt.Sprintf("%s%s -> %s\n", indent, sl.Name, sl.Target))
+		}
+		return nil
+	}
+
+	s, err := statsOf(rootDg)
+	if err != nil {
+		return "", err
+	}
+	res.WriteString("Input Tree\n==========\n")
+	res.WriteString(fmt.Sprintf("./ [Root directory digest: %v]\n", rootDg))
+	if depth != 0 {
+		if err := list(rootDg, 1); err != nil {
+			return "", err
+		}
+	}
+	res.WriteString(fmt.Sprintf("Total: %d files, %d directories, %d symlinks, %d bytes\n", s.files, s.dirs, s.symlinks, s.bytes))
+	return res.String(), nil
+}
@@ -0,0 +1,83 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_ShowInputTree(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+
+	libDg := cas.Put([]byte("library contents"))
+	srcDg := cas.Put([]byte("int main;"))
+	deepDg := put(&repb.Directory{Files: []*repb.FileNode{{Name: "lib.a", Digest: libDg.ToProto()}}})
+	libsDg := put(&repb.Directory{
+		Directories: []*repb.DirectoryNode{{Name: "deep", Digest: deepDg}},
+		Symlinks:    []*repb.SymlinkNode{{Name: "current", Target: "deep"}},
+	})
+	rootDg := put(&repb.Directory{
+		Files: []*repb.FileNode{
+			{Name: "main.c", Digest: srcDg.ToProto()},
+			{Name: "tool", Digest: libDg.ToProto(), IsExecutable: true},
+		},
+		Directories: []*repb.DirectoryNode{{Name: "libs", Digest: libsDg}},
+	})
+	cmdDg := put(&repb.Command{Arguments: []string{"tool"}})
+	acDg := digest.NewFromProtoUnvalidated(put(&repb.Action{CommandDigest: cmdDg, InputRootDigest: rootDg}))
+
+	header := fmt.Sprintf("Input Tree\n==========\n./ [Root directory digest: %v]\n", digestString(rootDg))
+	libs := fmt.Sprintf("  libs/ [Directory digest: %v] 1 files, 16 bytes\n", digestString(libsDg))
+	files := fmt.Sprintf("  main.c [File digest: %v] 9 bytes\n", srcDg) +
+		fmt.Sprintf("  tool [File digest: %v] 16 bytes, executable\n", libDg)
+	total := "Total: 3 files, 2 directories, 1 symlinks, 41 bytes\n"
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{
+			depth: 0,
+			want:  header + total,
+		},
+		{
+			depth: 1,
+			want:  header + libs + files + total,
+		},
+		{
+			depth: -1,
+			want: header + libs +
+				fmt.Sprintf("    deep/ [Directory digest: %v] 1 files, 16 bytes\n", digestString(deepDg)) +
+				fmt.Sprintf("      lib.a [File digest: %v] 16 bytes\n", libDg) +
+				"    current -> deep\n" +
+				files + total,
+		},
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("depth %d", tc.depth), func(t *testing.T) {
+			got, err := toolClient.ShowInputTree(context.Background(), acDg.String(), tc.depth)
+			if err != nil {
+				t.Fatalf("ShowInputTree(%v, %d) failed: %v", acDg, tc.depth, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ShowInputTree(%v, %d) returned diff (-want +got):\n%s", acDg, tc.depth, diff)
+			}
+		})
+	}
+}
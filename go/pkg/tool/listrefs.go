@@ -0,0 +1,139 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// refSet is a set of digests which keeps the order they were added in.
+type refSet struct {
+	seen map[digest.Digest]bool
+	dgs  []digest.Digest
+}
+
+// add adds the digest if set, and returns whether it was not in the set yet.
+func (s *refSet) add(dgPb *repb.Digest) (bool, error) {
+	if dgPb == nil {
+		return false, nil
+	}
+	dg, err := digest.NewFromProto(dgPb)
+	if err != nil {
+		return false, err
+	}
+	if s.seen[dg] {
+		return false, nil
+	}
+	s.seen[dg] = true
+	s.dgs = append(s.dgs, dg)
+	return true, nil
+}
+
+// addFiles adds the digests of the files of dir.
+func (s *refSet) addFiles(dir *repb.Directory) error {
+	for _, f := range dir.GetFiles() {
+		if _, err := s.add(f.Digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRefs returns every digest transitively reachable from the action with the given digest, one
+// per line: the action, its Command, the Directory protos and the files of its input tree and, if
+// the action has a result in the action cache, its stdout, stderr, output files and output
+// directories, with the files of the latter. The Directory protos within the Tree protos of output
+// directories are not listed, since the CAS need not store them separately. This is e.g. for
+// garbage collection or cache pinning tooling to keep everything an action needs.
+func (c *Client) ListRefs(ctx context.Context, actionDigest string) (string, error) {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return "", err
+	}
+	refs := &refSet{seen: make(map[digest.Digest]bool)}
+	refs.add(acDg.ToProto())
+	actionProto := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, acDg, actionProto); err != nil {
+		return "", err
+	}
+	if _, err := refs.add(actionProto.GetCommandDigest()); err != nil {
+		return "", err
+	}
+
+	c.infof("Walking the input tree of %v..", acDg)
+	queue := []*repb.Digest{actionProto.GetInputRootDigest()}
+	if _, err := refs.add(actionProto.GetInputRootDigest()); err != nil {
+		return "", err
+	}
+	for len(queue) > 0 {
+		dg, err := digest.NewFromProto(queue[0])
+		if err != nil {
+			return "", err
+		}
+		queue = queue[1:]
+		dirPb := &repb.Directory{}
+		if _, err := c.GrpcClient.ReadProto(ctx, dg, dirPb); err != nil {
+			return "", fmt.Errorf("error reading input directory %v: %v", dg, err)
+		}
+		for _, d := range dirPb.GetDirectories() {
+			// Directories already listed are already walked or queued.
+			added, err := refs.add(d.Digest)
+			if err != nil {
+				return "", err
+			}
+			if added {
+				queue = append(queue, d.Digest)
+			}
+		}
+		if err := refs.addFiles(dirPb); err != nil {
+			return "", err
+		}
+	}
+
+	c.infof("Looking up the result of %v..", acDg)
+	resPb, err := c.getActionResult(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	if resPb == nil {
+		c.infof("Action %v has no result in the action cache.", acDg)
+	}
+	for _, dg := range []*repb.Digest{resPb.GetStdoutDigest(), resPb.GetStderrDigest()} {
+		if _, err := refs.add(dg); err != nil {
+			return "", err
+		}
+	}
+	for _, f := range resPb.GetOutputFiles() {
+		if _, err := refs.add(f.Digest); err != nil {
+			return "", err
+		}
+	}
+	for _, d := range resPb.GetOutputDirectories() {
+		added, err := refs.add(d.TreeDigest)
+		if err != nil {
+			return "", err
+		}
+		if !added {
+			continue
+		}
+		treeDg := refs.dgs[len(refs.dgs)-1]
+		tree := &repb.Tree{}
+		if _, err := c.GrpcClient.ReadProto(ctx, treeDg, tree); err != nil {
+			return "", fmt.Errorf("error reading the tree of output directory %v: %v", d.Path, err)
+		}
+		for _, dir := range append([]*repb.Directory{tree.Root}, tree.Children...) {
+			if err := refs.addFiles(dir); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	var res strings.Builder
+	for _, dg := range refs.dgs {
+		res.WriteString(dg.String() + "\n")
+	}
+	return res.String(), nil
+}
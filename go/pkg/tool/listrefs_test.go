@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_ListRefs(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+	str := func(dg *repb.Digest) string {
+		return digest.NewFromProtoUnvalidated(dg).String()
+	}
+
+	aDg := cas.Put([]byte("a")).ToProto()
+	bDg := cas.Put([]byte("b")).ToProto()
+	// The subdirectory is referenced twice, and the file a three times.
+	subDg := put(&repb.Directory{Files: []*repb.FileNode{{Name: "a", Digest: aDg}, {Name: "b", Digest: bDg}}})
+	rootDg := put(&repb.Directory{
+		Files: []*repb.FileNode{{Name: "a", Digest: aDg}},
+		Directories: []*repb.DirectoryNode{
+			{Name: "sub1", Digest: subDg},
+			{Name: "sub2", Digest: subDg},
+		},
+	})
+	cmdDg := put(&repb.Command{Arguments: []string{"tool"}})
+	action := &repb.Action{CommandDigest: cmdDg, InputRootDigest: rootDg}
+	acDg := put(action)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.ListRefs(context.Background(), str(acDg))
+	if err != nil {
+		t.Fatalf("ListRefs(%v) failed: %v", str(acDg), err)
+	}
+	inputs := str(acDg) + "\n" + str(cmdDg) + "\n" + str(rootDg) + "\n" + str(subDg) + "\n" + str(aDg) + "\n" + str(bDg) + "\n"
+	if diff := cmp.Diff(inputs, got); diff != "" {
+		t.Errorf("ListRefs(%v) of an action without result returned diff (-want +got):\n%s", str(acDg), diff)
+	}
+
+	outDg := cas.Put([]byte("out")).ToProto()
+	treeFileDg := cas.Put([]byte("in a tree")).ToProto()
+	treeDg := put(&repb.Tree{
+		Root:     &repb.Directory{Files: []*repb.FileNode{{Name: "a", Digest: aDg}}},
+		Children: []*repb.Directory{{Files: []*repb.FileNode{{Name: "f", Digest: treeFileDg}}}},
+	})
+	stderrDg := cas.Put([]byte("stderr")).ToProto()
+	e.Server.ActionCache.PutAction(action, &repb.ActionResult{
+		OutputFiles:       []*repb.OutputFile{{Path: "out", Digest: outDg}},
+		OutputDirectories: []*repb.OutputDirectory{{Path: "dir", TreeDigest: treeDg}},
+		StdoutRaw:         []byte("inlined"),
+		StderrDigest:      stderrDg,
+	})
+	got, err = toolClient.ListRefs(context.Background(), str(acDg))
+	if err != nil {
+		t.Fatalf("ListRefs(%v) failed: %v", str(acDg), err)
+	}
+	want := inputs + str(stderrDg) + "\n" + str(outDg) + "\n" + str(treeDg) + "\n" + str(treeFileDg) + "\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListRefs(%v) returned diff (-want +got):\n%s", str(acDg), diff)
+	}
+}
@@ -0,0 +1,315 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/rexec"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// localResult is the outcome of an execution of an action on the local machine.
+type localResult struct {
+	exitCode int
+	// outputs are the digests of the output files by path, including the files of the output
+	// directories, in the format of rexec.Context.GetOutputFileDigests.
+	outputs map[string]digest.Digest
+}
+
+// CompareLocalExecution executes the action with the given digest both remotely, accepting a
+// cached result, and on the local machine, and compares their outputs, e.g. to tell whether a
+// nondeterministic action is machine-dependent or runs on flaky workers. The action is read from
+// actionRoot if set, in the layout of DownloadAction, and downloaded otherwise. The local execution
+// runs in a copy of the input tree, with the environment variables of the command only. It returns
+// an error if the exit codes or the digests of the outputs differ, after writing a report of the
+// differences to oe.
+func (c *Client) CompareLocalExecution(ctx context.Context, actionDigest, actionRoot string, oe outerr.OutErr) error {
+	if actionRoot == "" {
+		dir, err := ioutil.TempDir("", "compare_local")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := c.DownloadAction(ctx, actionDigest, dir); err != nil {
+			return err
+		}
+		actionRoot = dir
+	}
+	remote, remoteErr := c.remoteOutputDigests(ctx, actionRoot, oe)
+	if remote == nil {
+		return fmt.Errorf("error executing action remotely: %v", remoteErr)
+	}
+	local, err := c.executeLocally(ctx, actionRoot, oe)
+	if err != nil {
+		return fmt.Errorf("error executing action locally: %v", err)
+	}
+
+	var res bytes.Buffer
+	mismatches := 0
+	if remoteOK, localOK := remoteErr == nil, local.exitCode == 0; remoteOK != localOK {
+		res.WriteString(fmt.Sprintf("\tremote execution result: %v, local exit code: %d\n", remoteErr, local.exitCode))
+		mismatches++
+	}
+	diff, n, compared := diffOutputDigests(remote, local.outputs)
+	res.WriteString(diff)
+	mismatches += n
+	if mismatches == 0 {
+		oe.WriteOut([]byte(fmt.Sprintf("Local execution matches remote execution, %d outputs compared.\n", compared)))
+		return nil
+	}
+	var report bytes.Buffer
+	writeSection(&report, "Local and remote execution mismatches", res.String())
+	oe.WriteOut(report.Bytes())
+	return fmt.Errorf("local and remote executions of the action differ in %d ways, the action is likely machine-dependent", mismatches)
+}
+
+// LocalExecute executes the action with the given digest on the local machine, and compares its
+// exit code and outputs with those of its result in the action cache, e.g. to isolate failures
+// specific to the environment of the remote workers. The action and its inputs are downloaded to a
+// temporary directory, and the command runs in a copy of the input tree as in
+// CompareLocalExecution. It returns an error if the action has no cached result, or if the local
+// execution differs from it, after writing a report of the differences to oe.
+func (c *Client) LocalExecute(ctx context.Context, actionDigest string, oe outerr.OutErr) error {
+	resPb, err := c.getActionResult(ctx, actionDigest)
+	if err != nil {
+		return err
+	}
+	if resPb == nil {
+		return fmt.Errorf("action %v not found in the action cache", actionDigest)
+	}
+	remote, err := c.actionResultDigests(ctx, resPb)
+	if err != nil {
+		return fmt.Errorf("error reading the outputs of the cached result: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "local_execute")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := c.DownloadAction(ctx, actionDigest, dir); err != nil {
+		return err
+	}
+	local, err := c.executeLocally(ctx, dir, oe)
+	if err != nil {
+		return fmt.Errorf("error executing action locally: %v", err)
+	}
+
+	var res bytes.Buffer
+	mismatches := 0
+	if remoteCode := int(resPb.GetExitCode()); remoteCode != local.exitCode {
+		res.WriteString(fmt.Sprintf("\tremote exit code: %d, local exit code: %d\n", remoteCode, local.exitCode))
+		mismatches++
+	}
+	diff, n, compared := diffOutputDigests(remote, local.outputs)
+	res.WriteString(diff)
+	mismatches += n
+	if mismatches == 0 {
+		oe.WriteOut([]byte(fmt.Sprintf("Local execution matches the cached result, %d outputs compared.\n", compared)))
+		return nil
+	}
+	var report bytes.Buffer
+	writeSection(&report, "Local execution and cached result mismatches", res.String())
+	oe.WriteOut(report.Bytes())
+	return fmt.Errorf("local execution of the action differs from its cached result in %d ways", mismatches)
+}
+
+// actionResultDigests returns the digests of the outputs of resPb by path, including the files of
+// the output directories, in the format of localResult.outputs.
+func (c *Client) actionResultDigests(ctx context.Context, resPb *repb.ActionResult) (map[string]digest.Digest, error) {
+	res := make(map[string]digest.Digest)
+	for _, f := range resPb.GetOutputFiles() {
+		res[f.GetPath()] = digest.NewFromProtoUnvalidated(f.GetDigest())
+	}
+	for _, d := range resPb.GetOutputDirectories() {
+		treeDg, err := digest.NewFromProto(d.GetTreeDigest())
+		if err != nil {
+			return nil, err
+		}
+		tree := &repb.Tree{}
+		if _, err := c.GrpcClient.ReadProto(ctx, treeDg, tree); err != nil {
+			return nil, err
+		}
+		outs, err := c.GrpcClient.FlattenTree(tree, d.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		for p, o := range outs {
+			res[p] = o.Digest
+		}
+	}
+	return res, nil
+}
+
+// diffOutputDigests compares the digests of the outputs of a remote and a local execution by
+// path, and returns a line for every output missing from either or whose digests differ, with the
+// number of such outputs and the number of outputs compared.
+func diffOutputDigests(remote, local map[string]digest.Digest) (string, int, int) {
+	paths := make([]string, 0, len(remote)+len(local))
+	for p := range remote {
+		paths = append(paths, p)
+	}
+	for p := range local {
+		if _, ok := remote[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	var res bytes.Buffer
+	mismatches := 0
+	for _, p := range paths {
+		r, inRemote := remote[p]
+		l, inLocal := local[p]
+		switch {
+		case !inLocal:
+			res.WriteString(fmt.Sprintf("\tmissing locally %s: %s\n", p, r))
+		case !inRemote:
+			res.WriteString(fmt.Sprintf("\tmissing remotely %s: %s\n", p, l))
+		case r != l:
+			res.WriteString(fmt.Sprintf("\tmismatched %s: local %s, remote %s\n", p, l, r))
+		default:
+			continue
+		}
+		mismatches++
+	}
+	return res.String(), mismatches, len(paths)
+}
+
+// remoteOutputDigests executes the action at actionRoot remotely, accepting a cached result, and
+// returns the digests of its outputs by path with the error of the execution. The digests are nil
+// if the execution did not complete.
+func (c *Client) remoteOutputDigests(ctx context.Context, actionRoot string, oe outerr.OutErr) (map[string]digest.Digest, error) {
+	client := &rexec.Client{
+		FileMetadataCache: filemetadata.NewNoopCache(),
+		GrpcClient:        c.GrpcClient,
+	}
+	actionDigest, err := c.prepProtos(ctx, actionRoot)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := c.prepCommand(ctx, client, actionDigest, filepath.Join(actionRoot, "input"))
+	if err != nil {
+		return nil, err
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true, DownloadOutputs: false, DownloadOutErr: true}
+	ec, err := client.NewContext(ctx, cmd, opt, oe)
+	if err != nil {
+		return nil, err
+	}
+	ec.ExecuteRemotely()
+	printExecution(ec, cmd, oe)
+	outputs, err := ec.GetOutputFileDigests(false)
+	if err != nil {
+		return nil, err
+	}
+	return outputs, ec.Result.Err
+}
+
+// executeLocally runs the command of the action at actionRoot, in the layout of DownloadAction, in
+// a temporary copy of its input tree, and returns its exit code and the digests of its outputs. The
+// stdout and stderr of the command are written to oe.
+func (c *Client) executeLocally(ctx context.Context, actionRoot string, oe outerr.OutErr) (*localResult, error) {
+	actionProto := &repb.Action{}
+	cmdProto := &repb.Command{}
+	for name, m := range map[string]proto.Message{"ac.textproto": actionProto, "cmd.textproto": cmdProto} {
+		b, err := ioutil.ReadFile(filepath.Join(actionRoot, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := proto.UnmarshalText(string(b), m); err != nil {
+			return nil, fmt.Errorf("error parsing %v: %v", name, err)
+		}
+	}
+	args := cmdProto.GetArguments()
+	if len(args) == 0 {
+		return nil, fmt.Errorf("the command has no arguments")
+	}
+	execRoot, err := ioutil.TempDir("", "local_exec")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(execRoot)
+	if err := copyPath(filepath.Join(actionRoot, "input"), execRoot); err != nil {
+		return nil, err
+	}
+	workDir := filepath.Join(execRoot, cmdProto.GetWorkingDirectory())
+	outPaths := cmdProto.GetOutputPaths()
+	if len(outPaths) == 0 {
+		outPaths = append(append(outPaths, cmdProto.GetOutputFiles()...), cmdProto.GetOutputDirectories()...)
+	}
+	// As remote workers do, create the parent directories of the outputs.
+	for _, p := range outPaths {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(workDir, p)), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	if actionProto.Timeout != nil {
+		timeout, err := ptypes.Duration(actionProto.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = []string{}
+	for _, ev := range cmdProto.GetEnvironmentVariables() {
+		cmd.Env = append(cmd.Env, ev.Name+"="+ev.Value)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	c.infof("Executing %v locally in %v.", args, workDir)
+	res := &localResult{}
+	err = cmd.Run()
+	oe.WriteOut(stdout.Bytes())
+	oe.WriteErr(stderr.Bytes())
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		res.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries, resPb, err := c.GrpcClient.ComputeOutputsToUpload(execRoot, cmdProto.GetWorkingDirectory(), outPaths, filemetadata.NewNoopCache(), command.UnspecifiedSymlinkBehavior)
+	if err != nil {
+		return nil, err
+	}
+	res.outputs = make(map[string]digest.Digest)
+	for _, f := range resPb.OutputFiles {
+		res.outputs[f.Path] = digest.NewFromProtoUnvalidated(f.Digest)
+	}
+	for _, d := range resPb.OutputDirectories {
+		ue, ok := entries[digest.NewFromProtoUnvalidated(d.TreeDigest)]
+		if !ok {
+			return nil, fmt.Errorf("tree of output directory %v not found", d.Path)
+		}
+		tree := &repb.Tree{}
+		if err := proto.Unmarshal(ue.Contents, tree); err != nil {
+			return nil, err
+		}
+		outs, err := c.GrpcClient.FlattenTree(tree, d.Path)
+		if err != nil {
+			return nil, err
+		}
+		for p, o := range outs {
+			res.outputs[p] = o.Digest
+		}
+	}
+	return res, nil
+}
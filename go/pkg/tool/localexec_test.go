@@ -0,0 +1,87 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+)
+
+func TestTool_CompareLocalExecution(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"sh", "-c", "cat in > a/b/out"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"in"}},
+		OutputFiles: []string{"a/b/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "in"), []byte("output"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: true, DownloadOutErr: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"})
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	oe := outerr.NewRecordingOutErr()
+	if err := client.CompareLocalExecution(context.Background(), acDg.String(), "", oe); err != nil {
+		t.Errorf("CompareLocalExecution(%v) failed: %v\n%s", acDg, err, oe.Stdout())
+	}
+	if want := "Local execution matches remote execution, 1 outputs compared.\n"; !strings.Contains(string(oe.Stdout()), want) {
+		t.Errorf("CompareLocalExecution(%v) wrote %q, want it to contain %q", acDg, oe.Stdout(), want)
+	}
+
+	// A machine-dependent action, whose output differs on the remote worker.
+	cmd.Args = []string{"sh", "-c", "uname > a/b/out"}
+	_, acDg = e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "remote"})
+	oe = outerr.NewRecordingOutErr()
+	if err := client.CompareLocalExecution(context.Background(), acDg.String(), "", oe); err == nil {
+		t.Errorf("CompareLocalExecution(%v) succeeded with mismatching outputs, want error", acDg)
+	}
+	if want := "\tmismatched a/b/out: local "; !strings.Contains(string(oe.Stdout()), want) {
+		t.Errorf("CompareLocalExecution(%v) wrote %q, want it to contain %q", acDg, oe.Stdout(), want)
+	}
+}
+
+func TestTool_LocalExecute(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"sh", "-c", "cat in > a/b/out"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"in"}},
+		OutputFiles: []string{"a/b/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "in"), []byte("output"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"})
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	oe := outerr.NewRecordingOutErr()
+	if err := client.LocalExecute(context.Background(), acDg.String(), oe); err != nil {
+		t.Errorf("LocalExecute(%v) failed: %v\n%s", acDg, err, oe.Stdout())
+	}
+	if want := "Local execution matches the cached result, 1 outputs compared.\n"; !strings.Contains(string(oe.Stdout()), want) {
+		t.Errorf("LocalExecute(%v) wrote %q, want it to contain %q", acDg, oe.Stdout(), want)
+	}
+
+	// An action failing locally, e.g. for lack of a tool installed on the workers.
+	cmd.Args = []string{"sh", "-c", "exit 3"}
+	_, acDg = e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"})
+	oe = outerr.NewRecordingOutErr()
+	if err := client.LocalExecute(context.Background(), acDg.String(), oe); err == nil {
+		t.Errorf("LocalExecute(%v) succeeded with a mismatching exit code, want error", acDg)
+	}
+	for _, want := range []string{"\tremote exit code: 0, local exit code: 3\n", "\tmissing locally a/b/out: "} {
+		if !strings.Contains(string(oe.Stdout()), want) {
+			t.Errorf("LocalExecute(%v) wrote %q, want it to contain %q", acDg, oe.Stdout(), want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+)
+
+const (
+	// maxDiffSize is the size of the largest outputs diffed by mismatchReport.
+	maxDiffSize = 64 * 1024
+	// diffContext is the number of unchanged lines around the changes of a unified diff.
+	diffContext = 3
+)
+
+// outputVersion is a version of an output produced by some executions of an action.
+type outputVersion struct {
+	dg      digest.Digest
+	missing bool
+	execs   []int
+}
+
+func (v *outputVersion) String() string {
+	execs := make([]string, len(v.execs))
+	for i, e := range v.execs {
+		execs[i] = strconv.Itoa(e)
+	}
+	what := v.dg.String()
+	if v.missing {
+		what = "missing"
+	}
+	if len(execs) == 1 {
+		return fmt.Sprintf("\texecution %s: %s\n", execs[0], what)
+	}
+	return fmt.Sprintf("\texecutions %s: %s\n", strings.Join(execs, ", "), what)
+}
+
+// outputVersions returns the versions of each output path across the executions with the given
+// metadata, in order of first appearance. Executions which failed before producing metadata are
+// skipped.
+func outputVersions(mds []*command.Metadata, outputs func(*command.Metadata) map[string]digest.Digest) map[string][]*outputVersion {
+	paths := make(map[string]bool)
+	for _, md := range mds {
+		if md == nil {
+			continue
+		}
+		for p := range outputs(md) {
+			paths[p] = true
+		}
+	}
+	res := make(map[string][]*outputVersion)
+	for p := range paths {
+		var versions []*outputVersion
+		for i, md := range mds {
+			if md == nil {
+				continue
+			}
+			dg, ok := outputs(md)[p]
+			found := false
+			for _, v := range versions {
+				if v.missing == !ok && v.dg == dg {
+					v.execs = append(v.execs, i)
+					found = true
+					break
+				}
+			}
+			if !found {
+				versions = append(versions, &outputVersion{dg: dg, missing: !ok, execs: []int{i}})
+			}
+		}
+		res[p] = versions
+	}
+	return res
+}
+
+// mismatchReport returns which outputs differ between the executions of an action with the given
+// metadata, with their digests in each execution. If outDir is set, each version of a mismatching
+// output file is downloaded to outDir/<execution>/<path>, where <execution> is the first execution
+// which produced it. If diff is set, unified diffs between the versions of small text output files
+// are added to the report.
+func (c *Client) mismatchReport(ctx context.Context, mds []*command.Metadata, outDir string, diff bool) (string, error) {
+	files := outputVersions(mds, func(md *command.Metadata) map[string]digest.Digest { return md.OutputFileDigests })
+	dirs := outputVersions(mds, func(md *command.Metadata) map[string]digest.Digest { return md.OutputDirectoryDigests })
+
+	var res bytes.Buffer
+	writeVersions := func(title string, outputs map[string][]*outputVersion) []string {
+		var paths []string
+		for p, versions := range outputs {
+			if len(versions) > 1 {
+				paths = append(paths, p)
+			}
+		}
+		sort.Strings(paths)
+		var body bytes.Buffer
+		for _, p := range paths {
+			body.WriteString(fmt.Sprintf("%s:\n", p))
+			for _, v := range outputs[p] {
+				body.WriteString(v.String())
+			}
+		}
+		writeSection(&res, title, body.String())
+		return paths
+	}
+	mismatchedFiles := writeVersions("Mismatching output files", files)
+	writeVersions("Mismatching output directories", dirs)
+
+	if outDir != "" {
+		for _, p := range mismatchedFiles {
+			for _, v := range files[p] {
+				if v.missing {
+					continue
+				}
+				path := filepath.Join(outDir, strconv.Itoa(v.execs[0]), p)
+				c.infof("Downloading %v of execution %d to %v.", p, v.execs[0], path)
+				if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777)); err != nil {
+					return "", err
+				}
+				if _, err := c.GrpcClient.ReadBlobToFile(ctx, v.dg, path); err != nil {
+					return "", err
+				}
+			}
+		}
+		res.WriteString(fmt.Sprintf("\nMismatching output files written to %v\n", outDir))
+	}
+
+	if diff {
+		var diffs bytes.Buffer
+		for _, p := range mismatchedFiles {
+			versions := files[p]
+			base, err := c.readTextOutput(ctx, versions[0])
+			if err != nil {
+				return "", err
+			}
+			for _, v := range versions[1:] {
+				other, err := c.readTextOutput(ctx, v)
+				if err != nil {
+					return "", err
+				}
+				if base == nil || other == nil {
+					diffs.WriteString(fmt.Sprintf("%s: not diffed, binary or larger than %d bytes\n", p, maxDiffSize))
+					continue
+				}
+				nameA := fmt.Sprintf("execution %d/%s", versions[0].execs[0], p)
+				nameB := fmt.Sprintf("execution %d/%s", v.execs[0], p)
+				diffs.WriteString(unifiedDiff(nameA, nameB, base, other))
+			}
+		}
+		writeSection(&res, "Diffs", diffs.String())
+	}
+	return res.String(), nil
+}
+
+// readTextOutput returns the lines of the given output version, or nil if it is not a small text
+// file. Missing outputs are empty.
+func (c *Client) readTextOutput(ctx context.Context, v *outputVersion) ([]string, error) {
+	if v.missing {
+		return []string{}, nil
+	}
+	if v.dg.Size > maxDiffSize {
+		return nil, nil
+	}
+	blob, _, err := c.GrpcClient.ReadBlob(ctx, v.dg)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(blob) || bytes.IndexByte(blob, 0) >= 0 {
+		return nil, nil
+	}
+	lines := strings.SplitAfter(string(blob), "\n")
+	// The last line is empty if the output ends with a newline.
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+// unifiedDiff returns the unified diff from lines a to lines b, which keep their trailing newlines.
+func unifiedDiff(nameA, nameB string, a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	var res bytes.Buffer
+	res.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", nameA, nameB))
+	// lineA and lineB are the numbers of lines of a and b before edits[k].
+	lineA, lineB := 0, 0
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			lineA++
+			lineB++
+			k++
+			continue
+		}
+		// Extend the hunk until diffContext unchanged lines follow its last change.
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for unchanged := 0; end < len(edits) && unchanged <= 2*diffContext; end++ {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > k && edits[end-1].op == ' ' {
+			end--
+		}
+		end += diffContext
+		if end > len(edits) {
+			end = len(edits)
+		}
+		startA, startB := lineA-(k-start), lineB-(k-start)
+		var body bytes.Buffer
+		lenA, lenB := 0, 0
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				lenA++
+			}
+			if e.op != '-' {
+				lenB++
+			}
+			body.WriteByte(e.op)
+			body.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		res.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(startA, lenA), hunkRange(startB, lenB)))
+		res.Write(body.Bytes())
+		for _, e := range edits[k:end] {
+			if e.op != '+' {
+				lineA++
+			}
+			if e.op != '-' {
+				lineB++
+			}
+		}
+		k = end
+	}
+	return res.String()
+}
+
+// hunkRange returns the range of a hunk of n lines after the first start lines of a file.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
@@ -0,0 +1,120 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "modified line",
+			a:    "1\n2\n3\n",
+			b:    "1\n4\n3\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+4\n 3\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			b:    "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+		{
+			name: "merged hunks",
+			a:    "a\n1\n2\n3\n4\n5\n6\nb\n",
+			b:    "A\n1\n2\n3\n4\n5\n6\nB\n",
+			want: "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-a\n+A\n 1\n 2\n 3\n 4\n 5\n 6\n-b\n+B\n",
+		},
+		{
+			name: "added to empty",
+			a:    "",
+			b:    "1\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+1\n",
+		},
+		{
+			name: "no newline at end of file",
+			a:    "1\n2",
+			b:    "1\n2\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n 1\n-2\n\\ No newline at end of file\n+2\n",
+		},
+	}
+	lines := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		res := strings.SplitAfter(s, "\n")
+		if res[len(res)-1] == "" {
+			res = res[:len(res)-1]
+		}
+		return res
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := unifiedDiff("a", "b", lines(tc.a), lines(tc.b))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unifiedDiff(%q, %q) returned diff (-want +got):\n%s", tc.a, tc.b, diff)
+			}
+		})
+	}
+}
+
+func TestTool_MismatchReport(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	stable := cas.Put([]byte("stable"))
+	log0 := cas.Put([]byte("start\ntime: 1\nend\n"))
+	log1 := cas.Put([]byte("start\ntime: 2\nend\n"))
+	bin0 := cas.Put([]byte{0, 1})
+	bin1 := cas.Put([]byte{0, 2})
+	mds := []*command.Metadata{
+		{OutputFileDigests: map[string]digest.Digest{"stable": stable, "log": log0, "bin": bin0, "flaky": stable}},
+		{OutputFileDigests: map[string]digest.Digest{"stable": stable, "log": log1, "bin": bin1}},
+		nil,
+		{OutputFileDigests: map[string]digest.Digest{"stable": stable, "log": log0, "bin": bin0, "flaky": stable}},
+	}
+	outDir := t.TempDir()
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := client.mismatchReport(context.Background(), mds, outDir, true)
+	if err != nil {
+		t.Fatalf("mismatchReport failed: %v", err)
+	}
+	for _, want := range []string{
+		"bin:\n\texecutions 0, 3: " + bin0.String() + "\n\texecution 1: " + bin1.String() + "\n",
+		"flaky:\n\texecutions 0, 3: " + stable.String() + "\n\texecution 1: missing\n",
+		"log:\n\texecutions 0, 3: " + log0.String() + "\n\texecution 1: " + log1.String() + "\n",
+		"bin: not diffed, binary or larger than 65536 bytes\n",
+		"--- execution 0/log\n+++ execution 1/log\n@@ -1,3 +1,3 @@\n start\n-time: 1\n+time: 2\n end\n",
+		"--- execution 0/flaky\n+++ execution 1/flaky\n@@ -1 +0,0 @@\n-stable\n\\ No newline at end of file\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mismatchReport() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "stable:") {
+		t.Errorf("mismatchReport() = %q, want it to not report consistent output stable", got)
+	}
+
+	for path, want := range map[string]string{"0/log": "start\ntime: 1\nend\n", "1/log": "start\ntime: 2\nend\n", "0/flaky": "stable"} {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, path))
+		if err != nil {
+			t.Fatalf("failed reading %v: %v", path, err)
+		}
+		if string(b) != want {
+			t.Errorf("%v contains %q, want %q", path, b, want)
+		}
+	}
+}
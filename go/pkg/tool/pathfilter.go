@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// pathFilter selects the entries of a tree by glob patterns, in the syntax of filepath.Match, on
+// their slash-separated paths relative to the root. Patterns without a slash match the name of
+// entries at any depth, e.g. *.h, and the others match whole paths, e.g. src/*/testdata. An entry
+// is selected if it or one of its parent directories matches an include pattern, or if there are
+// no include patterns, unless it or one of its parent directories matches an exclude pattern.
+type pathFilter struct {
+	include, exclude []string
+}
+
+// newPathFilter returns a pathFilter for the given patterns, or nil if there are none.
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	for _, p := range append(append([]string(nil), include...), exclude...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %v", p, err)
+		}
+	}
+	return &pathFilter{include: include, exclude: exclude}, nil
+}
+
+func matchesAny(patterns []string, path string) bool {
+	path = filepath.ToSlash(path)
+	name := path[strings.LastIndex(path, "/")+1:]
+	for _, p := range patterns {
+		target := path
+		if !strings.Contains(p, "/") {
+			target = name
+		}
+		if ok, _ := filepath.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walk adds the selected entries of the directory with the given digest at path to outs, in the
+// format of FlattenTree, pruning the directories which are excluded. included is whether a parent
+// directory matches an include pattern.
+func (f *pathFilter) walk(dg digest.Digest, path string, included bool, dirs map[digest.Digest]*repb.Directory, outs map[string]*rc.TreeOutput) error {
+	dir, ok := dirs[dg]
+	if !ok {
+		return fmt.Errorf("couldn't find directory %s with digest %s", path, dg)
+	}
+	selected := func(p string) bool {
+		return !matchesAny(f.exclude, p) && (included || len(f.include) == 0 || matchesAny(f.include, p))
+	}
+	if len(dir.Files)+len(dir.Directories)+len(dir.Symlinks) == 0 {
+		if path != "" && (included || len(f.include) == 0) {
+			outs[path] = &rc.TreeOutput{Path: path, Digest: digest.Empty, IsEmptyDirectory: true}
+		}
+		return nil
+	}
+	for _, file := range dir.Files {
+		p := filepath.Join(path, file.Name)
+		if selected(p) {
+			outs[p] = &rc.TreeOutput{Path: p, Digest: digest.NewFromProtoUnvalidated(file.Digest), IsExecutable: file.IsExecutable}
+		}
+	}
+	for _, sl := range dir.Symlinks {
+		p := filepath.Join(path, sl.Name)
+		if selected(p) {
+			outs[p] = &rc.TreeOutput{Path: p, SymlinkTarget: sl.Target}
+		}
+	}
+	for _, sub := range dir.Directories {
+		p := filepath.Join(path, sub.Name)
+		if matchesAny(f.exclude, p) {
+			continue
+		}
+		subDg, err := digest.NewFromProto(sub.Digest)
+		if err != nil {
+			return err
+		}
+		if err := f.walk(subDg, p, included || matchesAny(f.include, p), dirs, outs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTool_DownloadDirectoryFiltered(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	dir := t.TempDir()
+	for _, p := range []string{"a.h", "a.c", "src/b.h", "src/b.c", "src/testdata/c.h", "lib/d.c"} {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(fp), err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(p), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", fp, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatalf("Mkdir(empty) failed: %v", err)
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{
+			name:    "include names at any depth",
+			include: []string{"*.h"},
+			want:    []string{"a.h", "src/b.h", "src/testdata/c.h"},
+		},
+		{
+			name:    "include directory",
+			include: []string{"src"},
+			want:    []string{"src/b.c", "src/b.h", "src/testdata/c.h"},
+		},
+		{
+			name:    "exclude directory",
+			exclude: []string{"src/testdata", "lib"},
+			want:    []string{"a.c", "a.h", "empty/", "src/b.c", "src/b.h"},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"*.h"},
+			exclude: []string{"testdata"},
+			want:    []string{"a.h", "src/b.h"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			if err := toolClient.DownloadDirectory(context.Background(), root.String(), outDir, tc.include, tc.exclude, false); err != nil {
+				t.Fatalf("DownloadDirectory(%v, %v, %v) failed: %v", root, tc.include, tc.exclude, err)
+			}
+			var got []string
+			err := filepath.Walk(outDir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(outDir, path)
+				if !info.IsDir() {
+					got = append(got, filepath.ToSlash(rel))
+				} else if entries, _ := ioutil.ReadDir(path); len(entries) == 0 {
+					got = append(got, filepath.ToSlash(rel)+"/")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed walking %v: %v", outDir, err)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("DownloadDirectory(%v, %v, %v) downloaded diff (-want +got):\n%s", root, tc.include, tc.exclude, diff)
+			}
+		})
+	}
+
+	if err := toolClient.DownloadDirectory(context.Background(), root.String(), t.TempDir(), []string{"[a"}, nil, false); err == nil {
+		t.Errorf("DownloadDirectory() with invalid pattern [a succeeded, want error")
+	}
+}
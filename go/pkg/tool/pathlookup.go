@@ -0,0 +1,98 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// LookupPath resolves the path p, relative to the root of the tree with the given root directory
+// digest, reading only the directories along it. It returns the chain of directories leading to p
+// with their digests, one per line, followed by the details of the node at p: its digest, size,
+// whether it is executable and its node properties for a file, its target for a symlink, and its
+// number of entries for a directory. Symlinks are not followed.
+func (c *Client) LookupPath(ctx context.Context, rootDigest, p string) (string, error) {
+	rootDg, err := digest.NewFromString(rootDigest)
+	if err != nil {
+		return "", err
+	}
+	b := &treeBrowser{c: c, rootDg: rootDg, cwd: "/", dirs: make(map[digest.Digest]*repb.Directory)}
+	dirDg := rootDg
+	dir, err := b.readDir(ctx, dirDg)
+	if err != nil {
+		return "", err
+	}
+	var res bytes.Buffer
+	p = path.Clean("/" + p)
+	if p == "/" {
+		writeLookupDir(&res, p, dirDg, dir)
+		return res.String(), nil
+	}
+	fmt.Fprintf(&res, "Directory /: %v\n", dirDg)
+	parts := strings.Split(p[1:], "/")
+	for i, name := range parts {
+		cur := "/" + strings.Join(parts[:i+1], "/")
+		last := i == len(parts)-1
+		var dn *repb.DirectoryNode
+		for _, d := range dir.Directories {
+			if d.Name == name {
+				dn = d
+				break
+			}
+		}
+		if dn != nil {
+			if dirDg, err = digest.NewFromProto(dn.Digest); err != nil {
+				return "", err
+			}
+			if dir, err = b.readDir(ctx, dirDg); err != nil {
+				return "", err
+			}
+			if last {
+				writeLookupDir(&res, cur, dirDg, dir)
+			} else {
+				fmt.Fprintf(&res, "Directory %s: %v\n", cur, dirDg)
+			}
+			continue
+		}
+		for _, s := range dir.Symlinks {
+			if s.Name != name {
+				continue
+			}
+			if !last {
+				return "", fmt.Errorf("%v is a symlink to %v, symlinks are not followed", cur, s.Target)
+			}
+			fmt.Fprintf(&res, "Symlink %s\n\tTarget: %s\n", cur, s.Target)
+			writeLookupProperties(&res, s.NodeProperties)
+			return res.String(), nil
+		}
+		for _, f := range dir.Files {
+			if f.Name != name {
+				continue
+			}
+			if !last {
+				return "", fmt.Errorf("%v is a file, not a directory", cur)
+			}
+			fmt.Fprintf(&res, "File %s\n\tDigest: %v\n\tSize: %d\n\tExecutable: %v\n", cur, digestString(f.Digest), f.GetDigest().GetSizeBytes(), f.IsExecutable)
+			writeLookupProperties(&res, f.NodeProperties)
+			return res.String(), nil
+		}
+		return "", fmt.Errorf("%v: no such file or directory in directory %v", cur, dirDg)
+	}
+	return res.String(), nil
+}
+
+func writeLookupDir(res *bytes.Buffer, p string, dg digest.Digest, dir *repb.Directory) {
+	fmt.Fprintf(res, "Directory %s: %v\n\tEntries: %d directories, %d files, %d symlinks\n", p, dg, len(dir.Directories), len(dir.Files), len(dir.Symlinks))
+	writeLookupProperties(res, dir.NodeProperties)
+}
+
+func writeLookupProperties(res *bytes.Buffer, p *repb.NodeProperties) {
+	if s := nodePropertiesString(p); s != "" {
+		fmt.Fprintf(res, "\tNode properties: %s\n", s)
+	}
+}
@@ -0,0 +1,85 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_LookupPath(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	put := func(m proto.Message) *repb.Digest {
+		blob, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return cas.Put(blob).ToProto()
+	}
+	str := func(dg *repb.Digest) string {
+		return digest.NewFromProtoUnvalidated(dg).String()
+	}
+
+	objDg := cas.Put([]byte("object")).ToProto()
+	bar := &repb.Directory{
+		Files: []*repb.FileNode{{
+			Name:           "baz.o",
+			Digest:         objDg,
+			NodeProperties: &repb.NodeProperties{UnixMode: &wrappers.UInt32Value{Value: 0644}},
+		}},
+	}
+	barDg := put(bar)
+	fooDg := put(&repb.Directory{Directories: []*repb.DirectoryNode{{Name: "bar", Digest: barDg}}})
+	rootDg := put(&repb.Directory{
+		Directories: []*repb.DirectoryNode{{Name: "foo", Digest: fooDg}},
+		Symlinks:    []*repb.SymlinkNode{{Name: "link", Target: "foo/bar"}},
+	})
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "foo/bar/baz.o",
+			want: "Directory /: " + str(rootDg) + "\n" +
+				"Directory /foo: " + str(fooDg) + "\n" +
+				"Directory /foo/bar: " + str(barDg) + "\n" +
+				"File /foo/bar/baz.o\n\tDigest: " + str(objDg) + "\n\tSize: 6\n\tExecutable: false\n\tNode properties: unix mode: 0644\n",
+		},
+		{
+			path: "foo/bar",
+			want: "Directory /: " + str(rootDg) + "\n" +
+				"Directory /foo: " + str(fooDg) + "\n" +
+				"Directory /foo/bar: " + str(barDg) + "\n\tEntries: 0 directories, 1 files, 0 symlinks\n",
+		},
+		{
+			path: "link",
+			want: "Directory /: " + str(rootDg) + "\nSymlink /link\n\tTarget: foo/bar\n",
+		},
+	}
+	for _, tc := range tests {
+		got, err := toolClient.LookupPath(context.Background(), str(rootDg), tc.path)
+		if err != nil {
+			t.Errorf("LookupPath(%v) failed: %v", tc.path, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("LookupPath(%v) returned diff (-want +got):\n%s", tc.path, diff)
+		}
+	}
+
+	for _, p := range []string{"foo/missing", "link/baz.o", "foo/bar/baz.o/x"} {
+		if _, err := toolClient.LookupPath(context.Background(), str(rootDg), p); err == nil {
+			t.Errorf("LookupPath(%v) succeeded, want error", p)
+		}
+	}
+}
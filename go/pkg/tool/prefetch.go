@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// PrefetchProgress is the progress of PrefetchTree.
+type PrefetchProgress struct {
+	// Blobs and Bytes are the number and the total size of the files of the tree which were missing
+	// from the local CAS when the prefetch started.
+	Blobs, Bytes int64
+	// FetchedBlobs and FetchedBytes are the number and the total size of those fetched so far.
+	FetchedBlobs, FetchedBytes int64
+	// Errors is the number of blobs which failed to be fetched.
+	Errors int64
+}
+
+// PrefetchTree fetches the files of the tree with the given digest into the local CAS of the
+// client, so that later downloads of the tree, e.g. with DownloadDirectory, are served locally.
+// The digest is that of the root Directory of the tree, or of a Tree proto if isTree is set, e.g.
+// of an output directory; the Tree proto is then stored in the local CAS as well. Up to
+// concurrency blobs are fetched at the same time, and progress, if set, is called concurrently
+// after each of them.
+func (c *Client) PrefetchTree(ctx context.Context, treeDigest string, isTree bool, concurrency int, progress func(PrefetchProgress)) (*PrefetchProgress, error) {
+	store := c.GrpcClient.LocalCAS
+	if store == nil {
+		return nil, errors.New("prefetching requires a local CAS")
+	}
+	dg, err := digest.NewFromString(treeDigest)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []*repb.Directory
+	if isTree {
+		blob, _, err := c.GrpcClient.ReadBlob(ctx, dg)
+		if err != nil {
+			return nil, err
+		}
+		tree := &repb.Tree{}
+		if err := proto.Unmarshal(blob, tree); err != nil {
+			return nil, fmt.Errorf("%v is not a Tree: %v", dg, err)
+		}
+		if _, err := store.Put(blob); err != nil {
+			return nil, err
+		}
+		dirs = append([]*repb.Directory{tree.Root}, tree.Children...)
+	} else {
+		c.infof("Fetching the directories of tree %v..", dg)
+		if dirs, err = c.GrpcClient.GetDirectoryTree(ctx, dg.ToProto()); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &PrefetchProgress{}
+	var missing []digest.Digest
+	seen := make(map[digest.Digest]bool)
+	for _, dir := range dirs {
+		for _, f := range dir.GetFiles() {
+			fDg, err := digest.NewFromProto(f.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if seen[fDg] || fDg.Size == 0 || store.Has(fDg) {
+				continue
+			}
+			seen[fDg] = true
+			missing = append(missing, fDg)
+			res.Blobs++
+			res.Bytes += fDg.Size
+		}
+	}
+	c.infof("Prefetching %d blobs (%d bytes) of tree %v.", res.Blobs, res.Bytes, dg)
+	if len(missing) == 0 {
+		return res, nil
+	}
+	p, err := c.GrpcClient.NewPrefetcher(&rc.PrefetchOptions{
+		Concurrency: concurrency,
+		Progress: func(st rc.PrefetchStats) {
+			if progress != nil {
+				progress(PrefetchProgress{Blobs: res.Blobs, Bytes: res.Bytes, FetchedBlobs: st.Blobs, FetchedBytes: st.Bytes, Errors: st.Errors})
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+	p.Prefetch(missing...)
+	if err := p.Wait(ctx); err != nil {
+		return nil, err
+	}
+	st := p.Stats()
+	res.FetchedBlobs, res.FetchedBytes, res.Errors = st.Blobs, st.Bytes, st.Errors
+	if res.Errors > 0 {
+		return res, fmt.Errorf("%d of %d blobs of tree %v could not be prefetched", res.Errors, res.Blobs, dg)
+	}
+	return res, nil
+}
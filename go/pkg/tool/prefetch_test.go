@@ -0,0 +1,86 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/localcas"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_PrefetchTree(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	if _, err := toolClient.PrefetchTree(context.Background(), digest.Empty.String(), false, 1, nil); err == nil {
+		t.Errorf("PrefetchTree() without a local CAS succeeded, want an error")
+	}
+	store, err := localcas.Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("localcas.Open() failed: %v", err)
+	}
+	e.Client.GrpcClient.LocalCAS = store
+	defer func() { e.Client.GrpcClient.LocalCAS = nil }()
+
+	dir := t.TempDir()
+	files := map[string]string{"a": "a", "b/c": "cc", "b/d": "a", "e": ""}
+	for p, contents := range files {
+		path := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating directory for %v: %v", p, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing %v: %v", p, err)
+		}
+	}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+
+	var calls int64
+	got, err := toolClient.PrefetchTree(context.Background(), root.String(), false, 2, func(PrefetchProgress) { atomic.AddInt64(&calls, 1) })
+	if err != nil {
+		t.Fatalf("PrefetchTree(%v) failed: %v", root, err)
+	}
+	// The duplicate and the empty files are not fetched.
+	want := &PrefetchProgress{Blobs: 2, Bytes: 3, FetchedBlobs: 2, FetchedBytes: 3}
+	if *got != *want {
+		t.Errorf("PrefetchTree(%v) = %+v, want %+v", root, got, want)
+	}
+	if calls != 2 {
+		t.Errorf("PrefetchTree(%v) reported progress %d times, want 2", root, calls)
+	}
+	for _, contents := range []string{"a", "cc"} {
+		if dg := digest.NewFromBlob([]byte(contents)); !store.Has(dg) {
+			t.Errorf("PrefetchTree(%v) did not prefetch %v", root, dg)
+		}
+	}
+	if got, err = toolClient.PrefetchTree(context.Background(), root.String(), false, 2, nil); err != nil || got.Blobs != 0 {
+		t.Errorf("PrefetchTree(%v) of a prefetched tree = (%+v, %v), want no blobs to fetch", root, got, err)
+	}
+
+	fDg := e.Server.CAS.Put([]byte("in a tree"))
+	tree := &repb.Tree{Root: &repb.Directory{Files: []*repb.FileNode{{Name: "f", Digest: fDg.ToProto()}}}}
+	treeBlob, err := proto.Marshal(tree)
+	if err != nil {
+		t.Fatalf("proto.Marshal(%v) failed: %v", tree, err)
+	}
+	treeDg := e.Server.CAS.Put(treeBlob)
+	if got, err = toolClient.PrefetchTree(context.Background(), treeDg.String(), true, 1, nil); err != nil || got.FetchedBlobs != 1 {
+		t.Errorf("PrefetchTree(%v) of a Tree = (%+v, %v), want 1 blob fetched", treeDg, got, err)
+	}
+	for _, dg := range []digest.Digest{fDg, treeDg} {
+		if !store.Has(dg) {
+			t.Errorf("PrefetchTree(%v) of a Tree did not prefetch %v", treeDg, dg)
+		}
+	}
+}
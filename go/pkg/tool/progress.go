@@ -0,0 +1,183 @@
+package tool
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+// progressInterval is the interval between reports of the progress of a transfer.
+var progressInterval = 500 * time.Millisecond
+
+// Progress is the progress of a long transfer, e.g. of DownloadDirectory or UploadDirectory.
+type Progress struct {
+	// Operation describes the transfer, e.g. "Downloading".
+	Operation string
+	// BytesDone and BytesTotal are the number of bytes transferred so far and to transfer.
+	BytesDone, BytesTotal int64
+	// FilesDone and FilesTotal are the number of blobs transferred so far and to transfer. Identical
+	// files are transferred once.
+	FilesDone, FilesTotal int64
+	// Throughput is the transfer rate since the previous report, in bytes per second. In the last
+	// report, it is the average rate of the transfer.
+	Throughput float64
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+	// ETA is the estimated time until the transfer completes, or 0 if unknown.
+	ETA time.Duration
+	// Done is set in the last report of the transfer, whether it succeeded or not.
+	Done bool
+}
+
+// progressTracker reports the progress of a transfer to the OnProgress callback of a client. The
+// progress is measured with the transfer counters of the gRPC client, so it also counts the
+// transfers of concurrent operations sharing it, and is capped at the totals.
+type progressTracker struct {
+	c        *Client
+	download bool
+	base     rc.Stats
+	start    time.Time
+	last     time.Time
+	p        Progress
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// trackProgress starts reporting the progress of a transfer of the given number of blobs and
+// bytes, and returns a function to call with the outcome of the transfer once it completes, which
+// makes the last report. It does nothing if the client has no OnProgress callback.
+func (c *Client) trackProgress(op string, download bool, files, bytes int64) func(error) {
+	if c.OnProgress == nil {
+		return func(error) {}
+	}
+	now := time.Now()
+	t := &progressTracker{
+		c:        c,
+		download: download,
+		base:     c.GrpcClient.Stats(),
+		start:    now,
+		last:     now,
+		p:        Progress{Operation: op, FilesTotal: files, BytesTotal: bytes},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t.finish
+}
+
+func (t *progressTracker) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-ticker.C:
+			t.update(now)
+			t.c.OnProgress(t.p)
+		}
+	}
+}
+
+// update updates the progress from the counters of the gRPC client.
+func (t *progressTracker) update(now time.Time) {
+	st := t.c.GrpcClient.Stats()
+	files, bytes := st.BlobsUploaded-t.base.BlobsUploaded, st.BytesUploaded-t.base.BytesUploaded
+	if t.download {
+		files, bytes = st.BlobsDownloaded-t.base.BlobsDownloaded, st.BytesDownloaded-t.base.BytesDownloaded
+	}
+	if files > t.p.FilesTotal {
+		files = t.p.FilesTotal
+	}
+	if bytes > t.p.BytesTotal {
+		bytes = t.p.BytesTotal
+	}
+	if d := now.Sub(t.last); d > 0 {
+		t.p.Throughput = float64(bytes-t.p.BytesDone) / d.Seconds()
+	}
+	t.p.FilesDone, t.p.BytesDone = files, bytes
+	t.p.Elapsed = now.Sub(t.start)
+	t.p.ETA = 0
+	if bytes > 0 && bytes < t.p.BytesTotal {
+		// Estimated from the average rate, which is steadier than the current one.
+		rate := float64(bytes) / t.p.Elapsed.Seconds()
+		t.p.ETA = time.Duration(float64(t.p.BytesTotal-bytes) / rate * float64(time.Second)).Round(time.Second)
+	}
+	t.last = now
+}
+
+func (t *progressTracker) finish(err error) {
+	close(t.stop)
+	<-t.done
+	t.update(time.Now())
+	if err == nil {
+		// Blobs served from the local CAS, if any, are not counted by the gRPC client.
+		t.p.FilesDone, t.p.BytesDone = t.p.FilesTotal, t.p.BytesTotal
+	}
+	t.p.Throughput = 0
+	if s := t.p.Elapsed.Seconds(); s > 0 {
+		t.p.Throughput = float64(t.p.BytesDone) / s
+	}
+	t.p.ETA = 0
+	t.p.Done = true
+	t.c.OnProgress(t.p)
+}
+
+// ProgressWriter returns an OnProgress callback rendering the progress of transfers to w, e.g. a
+// terminal, as a single line rewritten on each report and ended once the transfer completes.
+func ProgressWriter(w io.Writer) func(Progress) {
+	var mu sync.Mutex
+	width := 0
+	return func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		line := p.String()
+		pad := ""
+		if n := width - len(line); n > 0 {
+			// Erases the rest of the previous, longer line.
+			pad = strings.Repeat(" ", n)
+		}
+		width = len(line)
+		end := ""
+		if p.Done {
+			end, width = "\n", 0
+		}
+		fmt.Fprintf(w, "\r%s%s%s", line, pad, end)
+	}
+}
+
+// String returns a one-line summary of the progress, e.g.
+// "Downloading: 45.0% 1.2 GiB/2.6 GiB, 120/300 files, 25.3 MiB/s, ETA 1m2s".
+func (p Progress) String() string {
+	pct := 100.0
+	if p.BytesTotal > 0 {
+		pct = 100 * float64(p.BytesDone) / float64(p.BytesTotal)
+	}
+	s := fmt.Sprintf("%s: %.1f%% %s/%s, %d/%d files, %s/s", p.Operation, pct, formatBytes(p.BytesDone), formatBytes(p.BytesTotal), p.FilesDone, p.FilesTotal, formatBytes(int64(p.Throughput)))
+	switch {
+	case p.Done:
+		s += fmt.Sprintf(", in %v", p.Elapsed.Round(time.Millisecond))
+	case p.ETA > 0:
+		s += fmt.Sprintf(", ETA %v", p.ETA)
+	}
+	return s
+}
+
+// formatBytes formats a number of bytes with a binary unit, e.g. 1.5 KiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTool_Progress(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	defer func(d time.Duration) { progressInterval = d }(progressInterval)
+	progressInterval = time.Millisecond
+
+	var mu sync.Mutex
+	var reports []Progress
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient, OnProgress: func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	}}
+	last := func() Progress {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reports) == 0 {
+			t.Fatalf("no progress reported")
+		}
+		for _, p := range reports[:len(reports)-1] {
+			if p.Done || p.BytesDone > p.BytesTotal || p.FilesDone > p.FilesTotal {
+				t.Errorf("intermediate progress %+v is done or exceeds its totals", p)
+			}
+		}
+		p := reports[len(reports)-1]
+		reports = nil
+		return p
+	}
+
+	dir := t.TempDir()
+	// The duplicate and the empty files are transferred once and not at all.
+	for p, contents := range map[string]string{"a": "aaaa", "b/c": "cc", "b/d": "aaaa", "e": ""} {
+		path := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating directory for %v: %v", p, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing %v: %v", p, err)
+		}
+	}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+	got := last()
+	if got.Operation != "Uploading" || !got.Done || got.FilesDone != got.FilesTotal || got.BytesDone != got.BytesTotal || got.FilesTotal == 0 {
+		t.Errorf("UploadDirectory(%v) last reported %+v, want a completed upload", dir, got)
+	}
+	if _, err := toolClient.UploadDirectory(context.Background(), dir); err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+	if got := last(); got.FilesTotal != 0 || !got.Done {
+		t.Errorf("UploadDirectory(%v) of an uploaded directory last reported %+v, want nothing to transfer", dir, got)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	if err := toolClient.DownloadDirectory(context.Background(), root.String(), out, nil, nil, false); err != nil {
+		t.Fatalf("DownloadDirectory(%v) failed: %v", root, err)
+	}
+	want := Progress{Operation: "Downloading", FilesDone: 2, FilesTotal: 2, BytesDone: 6, BytesTotal: 6, Done: true}
+	got = last()
+	got.Throughput, got.Elapsed = 0, 0
+	if got != want {
+		t.Errorf("DownloadDirectory(%v) last reported %+v, want %+v", root, got, want)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(out, "b/d")); err != nil || string(b) != "aaaa" {
+		t.Errorf("DownloadDirectory(%v) wrote b/d = (%q, %v), want aaaa", root, b, err)
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := ProgressWriter(&buf)
+	w(Progress{Operation: "Downloading", BytesDone: 3 << 20, BytesTotal: 6 << 20, FilesDone: 100, FilesTotal: 300, Throughput: 1536, ETA: 62 * time.Second})
+	w(Progress{Operation: "Downloading", BytesDone: 6 << 20, BytesTotal: 6 << 20, FilesDone: 300, FilesTotal: 300, Throughput: 2 << 30, Elapsed: 1500 * time.Millisecond, Done: true})
+	want := "\rDownloading: 50.0% 3.0 MiB/6.0 MiB, 100/300 files, 1.5 KiB/s, ETA 1m2s" +
+		"\rDownloading: 100.0% 6.0 MiB/6.0 MiB, 300/300 files, 2.0 GiB/s, in 1.5s\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("ProgressWriter() wrote diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,55 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+// skipDownloaded removes from outs the entries already downloaded under dir, so that an
+// interrupted download can be resumed: files whose digest and executable bit match, symlinks with
+// the same target and existing directories. The local entries which do not match are removed to
+// be downloaded again. It returns the number of entries skipped.
+func (c *Client) skipDownloaded(dir string, outs map[string]*rc.TreeOutput) (int, error) {
+	paths := make([]string, 0, len(outs))
+	for p := range outs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	skipped := 0
+	for _, p := range paths {
+		out := outs[p]
+		path := filepath.Join(dir, p)
+		md := filemetadata.Compute(path)
+		if e, ok := md.Err.(*filemetadata.FileError); ok && e.IsNotFound && md.Symlink == nil {
+			continue
+		}
+		if isDownloaded(out, md) {
+			delete(outs, p)
+			skipped++
+			continue
+		}
+		c.infof("Downloading %v again, its local copy does not match.", path)
+		if err := os.RemoveAll(path); err != nil {
+			return 0, err
+		}
+	}
+	return skipped, nil
+}
+
+// isDownloaded returns whether md, the metadata of a local file, matches out.
+func isDownloaded(out *rc.TreeOutput, md *filemetadata.Metadata) bool {
+	switch {
+	case out.SymlinkTarget != "":
+		return md.Symlink != nil && md.Symlink.Target == out.SymlinkTarget
+	case md.Symlink != nil:
+		return false
+	case out.IsEmptyDirectory:
+		return md.IsDirectory
+	}
+	return md.Err == nil && !md.IsDirectory && md.Digest == out.Digest && md.IsExecutable == out.IsExecutable
+}
@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_DownloadDirectoryResume(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	dir := t.TempDir()
+	files := map[string]string{
+		"kept":      "kept",
+		"missing":   "missing",
+		"b/corrupt": "corrupt",
+		"b/extra":   "extra",
+	}
+	for p, contents := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(fp), err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(contents), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", fp, err)
+		}
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+	outDir := t.TempDir()
+	if err := toolClient.DownloadDirectory(context.Background(), root.String(), outDir, nil, nil, false); err != nil {
+		t.Fatalf("DownloadDirectory(%v) failed: %v", root, err)
+	}
+
+	// Simulate a download which failed midway, leaving a file missing and another one truncated.
+	if err := os.Remove(filepath.Join(outDir, "missing")); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outDir, "b/corrupt"), []byte("cor"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	reads := make(map[string]int)
+	for p, contents := range files {
+		reads[p] = e.Server.CAS.BlobReads(digest.NewFromBlob([]byte(contents)))
+	}
+	if err := toolClient.DownloadDirectory(context.Background(), root.String(), outDir, nil, nil, true); err != nil {
+		t.Fatalf("DownloadDirectory(%v, resume) failed: %v", root, err)
+	}
+
+	for p, contents := range files {
+		fp := filepath.Join(outDir, p)
+		got, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatalf("Unable to read downloaded file %v: %v", fp, err)
+		}
+		if string(got) != contents {
+			t.Errorf("Incorrect content in downloaded file %v, want %s, got %s", fp, contents, got)
+		}
+		wantReads := 0
+		if p == "missing" || p == "b/corrupt" {
+			wantReads = 1
+		}
+		if got := e.Server.CAS.BlobReads(digest.NewFromBlob([]byte(contents))) - reads[p]; got != wantReads {
+			t.Errorf("DownloadDirectory(%v, resume) read %v %d times, want %d", root, p, got, wantReads)
+		}
+	}
+}
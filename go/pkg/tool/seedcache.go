@@ -0,0 +1,58 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+)
+
+// SeedCache writes the result of a trusted local execution of the command described by the spec
+// at specPath to the action cache, e.g. to pre-populate a remote cache from local or CI builds.
+// The spec is that of ExecuteCommand: the digest of the action is computed from the local inputs
+// in its exec root, as ExecuteCommand would, so that executing the command later is a cache hit.
+// The outputs of the command are read from spec.OutputRoot, laid out like the exec root, or from
+// the exec root if unset; the OutputPaths of spec are ignored. The outputs, the stdout and stderr
+// and the Command and Action protos are uploaded if missing, but not the inputs.
+// It returns the digests of the Action and of the ActionResult.
+func (c *Client) SeedCache(ctx context.Context, specPath string, spec *ActionResultSpec) (acDg, resDg digest.Digest, err error) {
+	cmd, err := readCommandSpec(specPath)
+	if err != nil {
+		return digest.Empty, digest.Empty, err
+	}
+	if len(cmd.InputSpec.Inputs) == 0 && len(cmd.InputSpec.VirtualInputs) == 0 {
+		contents, err := ioutil.ReadDir(cmd.ExecRoot)
+		if err != nil {
+			return digest.Empty, digest.Empty, err
+		}
+		for _, f := range contents {
+			cmd.InputSpec.Inputs = append(cmd.InputSpec.Inputs, f.Name())
+		}
+	}
+	c.infof("Computing the Merkle tree of the inputs in %v.", cmd.ExecRoot)
+	root, _, _, err := c.GrpcClient.ComputeMerkleTree(cmd.ExecRoot, cmd.WorkingDir, cmd.RemoteWorkingDir, cmd.InputSpec, filemetadata.NewNoopCache())
+	if err != nil {
+		return digest.Empty, digest.Empty, err
+	}
+	cmdUe, acUe, err := c.actionEntries(cmd, root)
+	if err != nil {
+		return digest.Empty, digest.Empty, err
+	}
+
+	res := *spec
+	if res.OutputRoot == "" {
+		res.OutputRoot = cmd.ExecRoot
+	}
+	// As for remote executions, the outputs are relative to the working directory.
+	if !c.GrpcClient.LegacyExecRootRelativeOutputs {
+		res.OutputRoot = filepath.Join(res.OutputRoot, cmd.WorkingDir)
+	}
+	res.OutputPaths = append(append([]string(nil), cmd.OutputFiles...), cmd.OutputDirs...)
+	c.infof("Seeding the action cache for action %v.", acUe.Digest)
+	if resDg, err = c.uploadActionResult(ctx, acUe.Digest, &res, cmdUe, acUe); err != nil {
+		return digest.Empty, digest.Empty, err
+	}
+	return acUe.Digest, resDg, nil
+}
@@ -0,0 +1,83 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_SeedCache(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	specDir := t.TempDir()
+	inputRoot := filepath.Join(specDir, "input")
+	outRoot := filepath.Join(specDir, "output")
+	for path, contents := range map[string]string{"input/i1": "i1", "output/a/out": "seeded", "stderr": "warning"} {
+		fp := filepath.Join(specDir, path)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(fp), err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(contents), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", fp, err)
+		}
+	}
+	// The action executing the command remotely, whose result is replaced by the seeded one.
+	cmd := &command.Command{
+		Args:        []string{"cat", "i1"},
+		ExecRoot:    inputRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"a/out"},
+		Platform:    map[string]string{"OSFamily": "Linux"},
+	}
+	opt := &command.ExecutionOptions{AcceptCached: true, DownloadOutputs: false, DownloadOutErr: true}
+	_, wantAcDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/out", Contents: "remote"})
+
+	specPath := filepath.Join(specDir, "cmd.textproto")
+	spec := `
+		args: "cat"
+		args: "i1"
+		exec_root: "input"
+		output: {output_files: "a/out"}
+		platform: {key: "OSFamily" value: "Linux"}
+	`
+	if err := ioutil.WriteFile(specPath, []byte(spec), 0644); err != nil {
+		t.Fatalf("failed writing spec: %v", err)
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	res := &ActionResultSpec{OutputRoot: outRoot, StderrFile: filepath.Join(specDir, "stderr")}
+	acDg, resDg, err := toolClient.SeedCache(context.Background(), specPath, res)
+	if err != nil {
+		t.Fatalf("SeedCache(%v) failed: %v", specPath, err)
+	}
+	if acDg != wantAcDg {
+		t.Errorf("SeedCache(%v) seeded action %v, want %v", specPath, acDg, wantAcDg)
+	}
+	got := e.Server.ActionCache.Get(acDg)
+	if got == nil {
+		t.Fatalf("SeedCache(%v) wrote no action result", specPath)
+	}
+	if gotDg, err := digest.NewFromMessage(got); err != nil || gotDg != resDg {
+		t.Errorf("SeedCache(%v) returned result %v, want the digest of the cached result %v", specPath, resDg, gotDg)
+	}
+	want := &repb.ActionResult{
+		StderrDigest: digest.NewFromBlob([]byte("warning")).ToProto(),
+		OutputFiles:  []*repb.OutputFile{{Path: "a/out", Digest: digest.NewFromBlob([]byte("seeded")).ToProto()}},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("SeedCache(%v) wrote result %v, want %v", specPath, got, want)
+	}
+	for _, blob := range []string{"seeded", "warning"} {
+		if _, ok := e.Server.CAS.Get(digest.NewFromBlob([]byte(blob))); !ok {
+			t.Errorf("SeedCache(%v) did not upload blob %q", specPath, blob)
+		}
+	}
+}
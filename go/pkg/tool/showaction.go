@@ -0,0 +1,375 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ShowFormat is the output format of ShowActionFormat.
+type ShowFormat string
+
+const (
+	// TextFormat is the human-readable output of ShowAction.
+	TextFormat ShowFormat = "text"
+	// JSONFormat is a JSON object with the details of the action, its input tree and its result.
+	JSONFormat ShowFormat = "json"
+	// TextprotoFormat is the text format of the Action, Command, input Directory, ActionResult and
+	// output Tree protos, each preceded by a comment naming it.
+	TextprotoFormat ShowFormat = "textproto"
+)
+
+// ShowFormats are the supported values of ShowFormat.
+var ShowFormats = []ShowFormat{TextFormat, JSONFormat, TextprotoFormat}
+
+// actionJSON is the JSON output of ShowActionFormat. Digests are in <hash>/<size_bytes> format.
+type actionJSON struct {
+	ActionDigest         string            `json:"action_digest"`
+	CommandDigest        string            `json:"command_digest"`
+	InputRootDigest      string            `json:"input_root_digest"`
+	Timeout              string            `json:"timeout,omitempty"`
+	DoNotCache           bool              `json:"do_not_cache,omitempty"`
+	Arguments            []string          `json:"arguments"`
+	EnvironmentVariables []propertyJSON    `json:"environment_variables,omitempty"`
+	WorkingDirectory     string            `json:"working_directory,omitempty"`
+	OutputFiles          []string          `json:"output_files,omitempty"`
+	OutputDirectories    []string          `json:"output_directories,omitempty"`
+	Platform             []propertyJSON    `json:"platform,omitempty"`
+	InputRoot            *directoryJSON    `json:"input_root,omitempty"`
+	InputRootError       string            `json:"input_root_error,omitempty"`
+	ActionResult         *actionResultJSON `json:"action_result,omitempty"`
+}
+
+type propertyJSON struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// directoryJSON is a Directory with its subdirectories nested in it.
+type directoryJSON struct {
+	Name           string              `json:"name,omitempty"`
+	Digest         string              `json:"digest"`
+	Files          []fileJSON          `json:"files,omitempty"`
+	Symlinks       []symlinkJSON       `json:"symlinks,omitempty"`
+	Directories    []*directoryJSON    `json:"directories,omitempty"`
+	NodeProperties *nodePropertiesJSON `json:"node_properties,omitempty"`
+}
+
+// fileJSON is a file, named by its path for outputs, and by its name in directories.
+type fileJSON struct {
+	Name           string              `json:"name,omitempty"`
+	Path           string              `json:"path,omitempty"`
+	Digest         string              `json:"digest"`
+	IsExecutable   bool                `json:"is_executable,omitempty"`
+	NodeProperties *nodePropertiesJSON `json:"node_properties,omitempty"`
+}
+
+// symlinkJSON is a symlink, named by its path for outputs, and by its name in directories.
+type symlinkJSON struct {
+	Name           string              `json:"name,omitempty"`
+	Path           string              `json:"path,omitempty"`
+	Target         string              `json:"target"`
+	NodeProperties *nodePropertiesJSON `json:"node_properties,omitempty"`
+}
+
+// nodePropertiesJSON are the NodeProperties of a file, symlink or directory, which are part of the
+// digest of its parent directory. The modification time is in RFC 3339 format, and the mode in
+// octal.
+type nodePropertiesJSON struct {
+	Mtime      string         `json:"mtime,omitempty"`
+	UnixMode   string         `json:"unix_mode,omitempty"`
+	Properties []propertyJSON `json:"properties,omitempty"`
+}
+
+type actionResultJSON struct {
+	ExitCode          int32                 `json:"exit_code"`
+	StdoutDigest      string                `json:"stdout_digest,omitempty"`
+	StderrDigest      string                `json:"stderr_digest,omitempty"`
+	OutputFiles       []fileJSON            `json:"output_files,omitempty"`
+	OutputSymlinks    []symlinkJSON         `json:"output_symlinks,omitempty"`
+	OutputDirectories []outputDirectoryJSON `json:"output_directories,omitempty"`
+	Worker            string                `json:"worker,omitempty"`
+	Timing            []phaseJSON           `json:"timing,omitempty"`
+}
+
+type outputDirectoryJSON struct {
+	Path       string         `json:"path"`
+	TreeDigest string         `json:"tree_digest"`
+	Root       *directoryJSON `json:"root,omitempty"`
+}
+
+// ShowActionFormat displays an action with its corresponding command, input tree and cached result
+// in the given format. The text format is that of ShowAction.
+func (c *Client) ShowActionFormat(ctx context.Context, actionDigest string, format ShowFormat) (string, error) {
+	switch format {
+	case TextFormat, "":
+		return c.ShowAction(ctx, actionDigest)
+	case JSONFormat:
+		return c.showActionJSON(ctx, actionDigest)
+	case TextprotoFormat:
+		return c.showActionTextproto(ctx, actionDigest)
+	}
+	return "", fmt.Errorf("unsupported format %q, supported formats: %v", format, ShowFormats)
+}
+
+// readAction reads the action with the given digest and its command from the CAS.
+func (c *Client) readAction(ctx context.Context, actionDigest string) (digest.Digest, *repb.Action, *repb.Command, error) {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return digest.Digest{}, nil, nil, err
+	}
+	actionProto := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, acDg, actionProto); err != nil {
+		return digest.Digest{}, nil, nil, err
+	}
+	cmdDg, err := digest.NewFromProto(actionProto.GetCommandDigest())
+	if err != nil {
+		return digest.Digest{}, nil, nil, err
+	}
+	c.infof("Reading command from action digest..")
+	commandProto := &repb.Command{}
+	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
+		return digest.Digest{}, nil, nil, err
+	}
+	return acDg, actionProto, commandProto, nil
+}
+
+func (c *Client) showActionJSON(ctx context.Context, actionDigest string) (string, error) {
+	resPb, err := c.getActionResult(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	acDg, actionProto, commandProto, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	res := &actionJSON{
+		ActionDigest:      acDg.String(),
+		CommandDigest:     digestString(actionProto.GetCommandDigest()),
+		InputRootDigest:   digestString(actionProto.GetInputRootDigest()),
+		DoNotCache:        actionProto.DoNotCache,
+		Arguments:         commandProto.GetArguments(),
+		WorkingDirectory:  commandProto.GetWorkingDirectory(),
+		OutputFiles:       commandProto.GetOutputFiles(),
+		OutputDirectories: commandProto.GetOutputDirectories(),
+	}
+	if actionProto.Timeout != nil {
+		timeout, err := ptypes.Duration(actionProto.Timeout)
+		if err != nil {
+			return "", err
+		}
+		res.Timeout = timeout.String()
+	}
+	for _, ev := range commandProto.GetEnvironmentVariables() {
+		res.EnvironmentVariables = append(res.EnvironmentVariables, propertyJSON{Name: ev.Name, Value: ev.Value})
+	}
+	for _, p := range commandProto.GetPlatform().GetProperties() {
+		res.Platform = append(res.Platform, propertyJSON{Name: p.Name, Value: p.Value})
+	}
+
+	c.infof("Fetching input tree from input root digest..")
+	if dirs, err := c.GrpcClient.GetDirectoryTree(ctx, actionProto.GetInputRootDigest()); err != nil {
+		res.InputRootError = err.Error()
+	} else if len(dirs) == 0 {
+		res.InputRootError = fmt.Sprintf("empty directories returned by GetTree for %v", res.InputRootDigest)
+	} else if res.InputRoot, err = nestDirectories(dirs[0], dirs); err != nil {
+		return "", err
+	}
+
+	if resPb != nil {
+		c.infof("Fetching output trees from action result..")
+		if res.ActionResult, err = c.actionResultJSON(ctx, resPb); err != nil {
+			return "", err
+		}
+	}
+	out, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+func (c *Client) actionResultJSON(ctx context.Context, resPb *repb.ActionResult) (*actionResultJSON, error) {
+	res := &actionResultJSON{
+		ExitCode:     resPb.ExitCode,
+		StdoutDigest: digestString(resPb.StdoutDigest),
+		StderrDigest: digestString(resPb.StderrDigest),
+		Worker:       resPb.GetExecutionMetadata().GetWorker(),
+		Timing:       executionTimingJSON(resPb.GetExecutionMetadata()),
+	}
+	for _, of := range resPb.GetOutputFiles() {
+		res.OutputFiles = append(res.OutputFiles, fileJSON{Path: of.Path, Digest: digestString(of.Digest), IsExecutable: of.IsExecutable, NodeProperties: nodePropertiesToJSON(of.NodeProperties)})
+	}
+	for _, syms := range [][]*repb.OutputSymlink{resPb.GetOutputFileSymlinks(), resPb.GetOutputDirectorySymlinks(), resPb.GetOutputSymlinks()} {
+		for _, s := range syms {
+			res.OutputSymlinks = append(res.OutputSymlinks, symlinkJSON{Path: s.Path, Target: s.Target, NodeProperties: nodePropertiesToJSON(s.NodeProperties)})
+		}
+	}
+	for _, od := range resPb.GetOutputDirectories() {
+		dg, err := digest.NewFromProto(od.GetTreeDigest())
+		if err != nil {
+			return nil, err
+		}
+		tree := &repb.Tree{}
+		if _, err := c.GrpcClient.ReadProto(ctx, dg, tree); err != nil {
+			return nil, err
+		}
+		root, err := nestDirectories(tree.Root, tree.Children)
+		if err != nil {
+			return nil, err
+		}
+		res.OutputDirectories = append(res.OutputDirectories, outputDirectoryJSON{Path: od.Path, TreeDigest: dg.String(), Root: root})
+	}
+	return res, nil
+}
+
+// nestDirectories returns the JSON of the root directory, with its subdirectories taken from dirs.
+func nestDirectories(root *repb.Directory, dirs []*repb.Directory) (*directoryJSON, error) {
+	byDigest := make(map[digest.Digest]*repb.Directory, len(dirs))
+	for _, d := range dirs {
+		dg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return nil, err
+		}
+		byDigest[dg] = d
+	}
+	var nest func(name string, d *repb.Directory) (*directoryJSON, error)
+	nest = func(name string, d *repb.Directory) (*directoryJSON, error) {
+		dg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return nil, err
+		}
+		res := &directoryJSON{Name: name, Digest: dg.String(), NodeProperties: nodePropertiesToJSON(d.NodeProperties)}
+		for _, f := range d.Files {
+			res.Files = append(res.Files, fileJSON{Name: f.Name, Digest: digestString(f.Digest), IsExecutable: f.IsExecutable, NodeProperties: nodePropertiesToJSON(f.NodeProperties)})
+		}
+		for _, s := range d.Symlinks {
+			res.Symlinks = append(res.Symlinks, symlinkJSON{Name: s.Name, Target: s.Target, NodeProperties: nodePropertiesToJSON(s.NodeProperties)})
+		}
+		for _, sub := range d.Directories {
+			subDg, err := digest.NewFromProto(sub.Digest)
+			if err != nil {
+				return nil, err
+			}
+			subDir, ok := byDigest[subDg]
+			if !ok {
+				return nil, fmt.Errorf("directory %v of %v not found in the tree", subDg, dg)
+			}
+			child, err := nest(sub.Name, subDir)
+			if err != nil {
+				return nil, err
+			}
+			res.Directories = append(res.Directories, child)
+		}
+		return res, nil
+	}
+	return nest("", root)
+}
+
+func (c *Client) showActionTextproto(ctx context.Context, actionDigest string) (string, error) {
+	resPb, err := c.getActionResult(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	acDg, actionProto, commandProto, err := c.readAction(ctx, actionDigest)
+	if err != nil {
+		return "", err
+	}
+	var res bytes.Buffer
+	writeProto := func(kind string, dg string, m proto.Message) {
+		res.WriteString(fmt.Sprintf("# %s %s\n", kind, dg))
+		res.WriteString(proto.MarshalTextString(m))
+	}
+	writeProto("Action", acDg.String(), actionProto)
+	writeProto("Command", digestString(actionProto.GetCommandDigest()), commandProto)
+
+	c.infof("Fetching input tree from input root digest..")
+	dirs, err := c.GrpcClient.GetDirectoryTree(ctx, actionProto.GetInputRootDigest())
+	if err != nil {
+		return "", err
+	}
+	// GetTree may return a directory more than once.
+	seen := make(map[digest.Digest]bool)
+	for _, d := range dirs {
+		dg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return "", err
+		}
+		if seen[dg] {
+			continue
+		}
+		seen[dg] = true
+		writeProto("Directory", dg.String(), d)
+	}
+
+	if resPb == nil {
+		return res.String(), nil
+	}
+	writeProto("ActionResult", acDg.String(), resPb)
+	for _, od := range resPb.GetOutputDirectories() {
+		dg, err := digest.NewFromProto(od.GetTreeDigest())
+		if err != nil {
+			return "", err
+		}
+		tree := &repb.Tree{}
+		if _, err := c.GrpcClient.ReadProto(ctx, dg, tree); err != nil {
+			return "", err
+		}
+		writeProto("Tree", dg.String(), tree)
+	}
+	return res.String(), nil
+}
+
+// nodePropertiesToJSON returns the JSON of p, or nil if p is unset.
+func nodePropertiesToJSON(p *repb.NodeProperties) *nodePropertiesJSON {
+	if p == nil {
+		return nil
+	}
+	res := &nodePropertiesJSON{}
+	if p.Mtime != nil {
+		res.Mtime = ptypes.TimestampString(p.Mtime)
+	}
+	if p.UnixMode != nil {
+		res.UnixMode = fmt.Sprintf("%04o", p.UnixMode.Value)
+	}
+	for _, np := range p.Properties {
+		res.Properties = append(res.Properties, propertyJSON{Name: np.Name, Value: np.Value})
+	}
+	return res
+}
+
+// nodePropertiesString returns p as comma-separated fields for the text output, e.g.
+// "mtime: 2021-01-01T00:00:00Z, unix mode: 0644", or "" if p is unset or empty.
+func nodePropertiesString(p *repb.NodeProperties) string {
+	j := nodePropertiesToJSON(p)
+	if j == nil {
+		return ""
+	}
+	var fields []string
+	if j.Mtime != "" {
+		fields = append(fields, "mtime: "+j.Mtime)
+	}
+	if j.UnixMode != "" {
+		fields = append(fields, "unix mode: "+j.UnixMode)
+	}
+	for _, np := range j.Properties {
+		fields = append(fields, np.Name+"="+np.Value)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// digestString returns the <hash>/<size_bytes> form of dg, or "" if dg is nil.
+func digestString(dg *repb.Digest) string {
+	if dg == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", dg.Hash, dg.SizeBytes)
+}
@@ -0,0 +1,209 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func setShowAction(t *testing.T, e *fakes.TestEnv) string {
+	t.Helper()
+	cmd := &command.Command{
+		Args:     []string{"tool", "arg"},
+		ExecRoot: e.ExecRoot,
+		InputSpec: &command.InputSpec{
+			Inputs: []string{
+				"a/b/input.txt",
+			},
+		},
+		OutputFiles: []string{"a/b/out"},
+		Platform:    map[string]string{"OSFamily": "Linux"},
+	}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"},
+		fakes.StdOut("stdout"), &fakes.InputFile{Path: "a/b/input.txt", Contents: "input"})
+	return acDg.String()
+}
+
+func TestTool_ShowActionJSON(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	acDg := setShowAction(t, e)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.ShowActionFormat(context.Background(), acDg, JSONFormat)
+	if err != nil {
+		t.Fatalf("ShowActionFormat(%v, json) failed: %v", acDg, err)
+	}
+	res := &actionJSON{}
+	if err := json.Unmarshal([]byte(got), res); err != nil {
+		t.Fatalf("ShowActionFormat(%v, json) returned invalid JSON: %v\n%s", acDg, err, got)
+	}
+	if res.ActionDigest != acDg {
+		t.Errorf("ShowActionFormat(%v, json) returned action digest %v", acDg, res.ActionDigest)
+	}
+	if diff := cmp.Diff([]string{"tool", "arg"}, res.Arguments); diff != "" {
+		t.Errorf("ShowActionFormat(%v, json) returned diff in arguments (-want +got):\n%s", acDg, diff)
+	}
+	if diff := cmp.Diff([]propertyJSON{{Name: "OSFamily", Value: "Linux"}}, res.Platform); diff != "" {
+		t.Errorf("ShowActionFormat(%v, json) returned diff in platform (-want +got):\n%s", acDg, diff)
+	}
+	// The input file is nested under its directories.
+	in := res.InputRoot
+	for _, name := range []string{"a", "b"} {
+		if in == nil || len(in.Directories) != 1 || in.Directories[0].Name != name {
+			t.Fatalf("ShowActionFormat(%v, json) returned unexpected input root %s", acDg, got)
+		}
+		in = in.Directories[0]
+	}
+	if len(in.Files) != 1 || in.Files[0].Name != "input.txt" || !strings.Contains(in.Files[0].Digest, "/") {
+		t.Errorf("ShowActionFormat(%v, json) returned unexpected input files %v", acDg, in.Files)
+	}
+	ar := res.ActionResult
+	if ar == nil {
+		t.Fatalf("ShowActionFormat(%v, json) returned no action result", acDg)
+	}
+	if len(ar.OutputFiles) != 1 || ar.OutputFiles[0].Path != "a/b/out" {
+		t.Errorf("ShowActionFormat(%v, json) returned unexpected output files %v", acDg, ar.OutputFiles)
+	}
+	if ar.StdoutDigest == "" {
+		t.Errorf("ShowActionFormat(%v, json) returned no stdout digest", acDg)
+	}
+}
+
+func TestTool_ShowActionTextproto(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	acDg := setShowAction(t, e)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.ShowActionFormat(context.Background(), acDg, TextprotoFormat)
+	if err != nil {
+		t.Fatalf("ShowActionFormat(%v, textproto) failed: %v", acDg, err)
+	}
+	want := []string{
+		"# Action " + acDg + "\n",
+		"# Command ",
+		`arguments: "arg"`,
+		"# Directory ",
+		`name: "input.txt"`,
+		"# ActionResult " + acDg + "\n",
+		`path: "a/b/out"`,
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("ShowActionFormat(%v, textproto) = %s, want it to contain %q", acDg, got, w)
+		}
+	}
+}
+
+func TestTool_ShowActionUnsupportedFormat(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	acDg := setShowAction(t, e)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	if _, err := toolClient.ShowActionFormat(context.Background(), acDg, "yaml"); err == nil {
+		t.Errorf("ShowActionFormat(%v, yaml) succeeded, want an error", acDg)
+	}
+}
+
+// setNodePropertiesAction stores an action whose input root and result have symlinks and nodes
+// with properties, and returns its digest.
+func setNodePropertiesAction(t *testing.T, e *fakes.TestEnv) string {
+	t.Helper()
+	mtime, err := ptypes.TimestampProto(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ptypes.TimestampProto() failed: %v", err)
+	}
+	props := &repb.NodeProperties{
+		Mtime:      mtime,
+		UnixMode:   &wrappers.UInt32Value{Value: 0644},
+		Properties: []*repb.NodeProperty{{Name: "owner", Value: "root"}},
+	}
+	put := func(m proto.Message) *repb.Digest {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v) failed: %v", m, err)
+		}
+		return e.Server.CAS.Put(b).ToProto()
+	}
+	fileDg := e.Server.CAS.Put([]byte("input"))
+	root := &repb.Directory{
+		Files:    []*repb.FileNode{{Name: "input.txt", Digest: fileDg.ToProto(), NodeProperties: props}},
+		Symlinks: []*repb.SymlinkNode{{Name: "link", Target: "input.txt"}},
+	}
+	ac := &repb.Action{CommandDigest: put(&repb.Command{Arguments: []string{"tool"}}), InputRootDigest: put(root)}
+	acDg := digest.NewFromProtoUnvalidated(put(ac))
+	e.Server.ActionCache.Put(acDg, &repb.ActionResult{
+		OutputFiles:    []*repb.OutputFile{{Path: "out", Digest: fileDg.ToProto(), NodeProperties: props}},
+		OutputSymlinks: []*repb.OutputSymlink{{Path: "out_link", Target: "out"}},
+	})
+	return acDg.String()
+}
+
+func TestTool_ShowActionNodeProperties(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	acDg := setNodePropertiesAction(t, e)
+	fileDg := digest.NewFromBlob([]byte("input"))
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	got, err := toolClient.ShowAction(context.Background(), acDg)
+	if err != nil {
+		t.Fatalf("ShowAction(%v) failed: %v", acDg, err)
+	}
+	want := []string{
+		fmt.Sprintf("input.txt: [File digest: %v, mtime: 2021-01-02T03:04:05Z, unix mode: 0644, owner=root]\n", fileDg),
+		"link: [Symlink Target: input.txt]\n",
+		fmt.Sprintf("out, digest: %v, mtime: 2021-01-02T03:04:05Z, unix mode: 0644, owner=root\n", fileDg),
+		"Output Symlinks\n===============\nout_link -> out\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("ShowAction(%v) = %s, want it to contain %q", acDg, got, w)
+		}
+	}
+
+	got, err = toolClient.ShowActionFormat(context.Background(), acDg, JSONFormat)
+	if err != nil {
+		t.Fatalf("ShowActionFormat(%v, json) failed: %v", acDg, err)
+	}
+	res := &actionJSON{}
+	if err := json.Unmarshal([]byte(got), res); err != nil {
+		t.Fatalf("ShowActionFormat(%v, json) returned invalid JSON: %v\n%s", acDg, err, got)
+	}
+	wantProps := &nodePropertiesJSON{
+		Mtime:      "2021-01-02T03:04:05Z",
+		UnixMode:   "0644",
+		Properties: []propertyJSON{{Name: "owner", Value: "root"}},
+	}
+	wantRoot := &directoryJSON{
+		Digest:   res.InputRootDigest,
+		Files:    []fileJSON{{Name: "input.txt", Digest: fileDg.String(), NodeProperties: wantProps}},
+		Symlinks: []symlinkJSON{{Name: "link", Target: "input.txt"}},
+	}
+	if diff := cmp.Diff(wantRoot, res.InputRoot); diff != "" {
+		t.Errorf("ShowActionFormat(%v, json) returned diff in input root (-want +got):\n%s", acDg, diff)
+	}
+	wantResult := &actionResultJSON{
+		OutputFiles:    []fileJSON{{Path: "out", Digest: fileDg.String(), NodeProperties: wantProps}},
+		OutputSymlinks: []symlinkJSON{{Path: "out_link", Target: "out"}},
+	}
+	if diff := cmp.Diff(wantResult, res.ActionResult); diff != "" {
+		t.Errorf("ShowActionFormat(%v, json) returned diff in action result (-want +got):\n%s", acDg, diff)
+	}
+}
@@ -0,0 +1,105 @@
+package tool
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+// SessionStats summarize a remotetool session: the BuildStats of the client, with the transfers,
+// action cache hits and misses and retries of the session, and the statistics of its operations.
+type SessionStats struct {
+	*rc.BuildStats
+	// WallTime is the time elapsed since the client was created.
+	WallTime time.Duration `json:"wall_time"`
+	// Operations aggregates the durations of the operations recorded with RecordOperation, by
+	// operation name. Durations are written to stats files in nanoseconds.
+	Operations map[string]*rc.PhaseStats `json:"operations"`
+	// FailedOperations is the number of recorded operations that failed, by operation name.
+	FailedOperations map[string]int64 `json:"failed_operations"`
+}
+
+// operationStats accumulates the statistics of the operations of a Client. The zero value is
+// ready to use.
+type operationStats struct {
+	mu     sync.Mutex
+	byName map[string]*rc.PhaseStats
+	failed map[string]int64
+}
+
+// RecordOperation records that the operation with the given name took d, and failed if err is set.
+func (c *Client) RecordOperation(name string, d time.Duration, err error) {
+	s := &c.ops
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byName == nil {
+		s.byName = make(map[string]*rc.PhaseStats)
+		s.failed = make(map[string]int64)
+	}
+	ps := s.byName[name]
+	if ps == nil {
+		ps = &rc.PhaseStats{}
+		s.byName[name] = ps
+	}
+	ps.Count++
+	ps.Total += d
+	if d > ps.Max {
+		ps.Max = d
+	}
+	if err != nil {
+		s.failed[name]++
+	}
+}
+
+// SessionStats returns the statistics of the session so far.
+func (c *Client) SessionStats() *SessionStats {
+	bs := c.GrpcClient.BuildStats()
+	ss := &SessionStats{
+		BuildStats:       bs,
+		Operations:       make(map[string]*rc.PhaseStats),
+		FailedOperations: make(map[string]int64),
+	}
+	if !bs.StartTime.IsZero() {
+		ss.WallTime = bs.EndTime.Sub(bs.StartTime)
+	}
+	s := &c.ops
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, ps := range s.byName {
+		cp := *ps
+		ss.Operations[name] = &cp
+	}
+	for name, n := range s.failed {
+		ss.FailedOperations[name] = n
+	}
+	return ss
+}
+
+// WriteStatsFile writes the SessionStats as JSON to the file at path, through a temporary file so
+// that readers never see partial stats.
+func (c *Client) WriteStatsFile(path string) error {
+	blob, err := json.MarshalIndent(c.SessionStats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(blob)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,62 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_WriteStatsFile(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	if _, err := toolClient.UploadBlobFromReader(context.Background(), bytes.NewReader([]byte("blob"))); err != nil {
+		t.Fatalf("UploadBlobFromReader() failed: %v", err)
+	}
+	toolClient.RecordOperation("upload_blob", time.Second, nil)
+	toolClient.RecordOperation("upload_blob", 3*time.Second, errors.New("failed"))
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := toolClient.WriteStatsFile(path); err != nil {
+		t.Fatalf("WriteStatsFile(%v) failed: %v", path, err)
+	}
+
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading %v: %v", path, err)
+	}
+	var got struct {
+		BlobsUploaded int64 `json:"blobs_uploaded"`
+		BytesUploaded int64 `json:"bytes_uploaded"`
+		WallTime      int64 `json:"wall_time"`
+		Operations    map[string]struct {
+			Count int64 `json:"count"`
+			Total int64 `json:"total"`
+			Max   int64 `json:"max"`
+		} `json:"operations"`
+		FailedOperations map[string]int64 `json:"failed_operations"`
+	}
+	if err := json.Unmarshal(blob, &got); err != nil {
+		t.Fatalf("failed parsing %v: %v\n%s", path, err, blob)
+	}
+	if got.BlobsUploaded != 1 || got.BytesUploaded != 4 {
+		t.Errorf("stats file uploads = %d blobs (%d bytes), want 1 (4 bytes)", got.BlobsUploaded, got.BytesUploaded)
+	}
+	if got.WallTime <= 0 {
+		t.Errorf("stats file wall time = %d, want > 0", got.WallTime)
+	}
+	op := got.Operations["upload_blob"]
+	if op.Count != 2 || time.Duration(op.Total) != 4*time.Second || time.Duration(op.Max) != 3*time.Second {
+		t.Errorf("stats file upload_blob stats = %+v, want 2 operations totaling 4s, at most 3s", op)
+	}
+	if got.FailedOperations["upload_blob"] != 1 {
+		t.Errorf("stats file failed operations = %v, want 1 failed upload_blob", got.FailedOperations)
+	}
+}
@@ -0,0 +1,109 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// downloadActionOutputs downloads the outputs of the action result to outDir. Output symlinks are
+// recreated as symlinks or, if copySymlinks is set, e.g. on Windows where creating symlinks
+// requires privileges, replaced with copies of their targets.
+func (c *Client) downloadActionOutputs(ctx context.Context, ar *repb.ActionResult, outDir string, copySymlinks bool) error {
+	// We don't really need an in-memory filemetadata cache for debugging operations.
+	noopCache := filemetadata.NewNoopCache()
+	if !copySymlinks {
+		_, err := c.GrpcClient.DownloadActionOutputs(ctx, ar, outDir, noopCache)
+		return err
+	}
+	outs, err := c.GrpcClient.FlattenActionOutputs(ctx, ar)
+	if err != nil {
+		return err
+	}
+	var symlinks []*rc.TreeOutput
+	for p, out := range outs {
+		if out.SymlinkTarget != "" {
+			symlinks = append(symlinks, out)
+			delete(outs, p)
+		}
+	}
+	if _, err := c.GrpcClient.DownloadOutputs(ctx, outs, outDir, noopCache); err != nil {
+		return err
+	}
+	return c.copySymlinkTargets(outDir, symlinks)
+}
+
+// copySymlinkTargets writes a copy of the target of each symlink in outDir at the path of the
+// symlink. Symlinks to other symlinks are copied once their targets are. Symlinks with absolute
+// targets or targets outside outDir are skipped with a warning, like dangling ones.
+func (c *Client) copySymlinkTargets(outDir string, symlinks []*rc.TreeOutput) error {
+	for len(symlinks) > 0 {
+		var pending []*rc.TreeOutput
+		for _, sl := range symlinks {
+			if filepath.IsAbs(sl.SymlinkTarget) {
+				c.warningf("Not copying the target of symlink %v: %v is absolute.", sl.Path, sl.SymlinkTarget)
+				continue
+			}
+			src := filepath.Join(outDir, filepath.Dir(sl.Path), filepath.FromSlash(sl.SymlinkTarget))
+			if rel, err := filepath.Rel(outDir, src); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				c.warningf("Not copying the target of symlink %v: %v is outside of the outputs.", sl.Path, sl.SymlinkTarget)
+				continue
+			}
+			if _, err := os.Stat(src); os.IsNotExist(err) {
+				pending = append(pending, sl)
+				continue
+			}
+			if err := copyPath(src, filepath.Join(outDir, sl.Path)); err != nil {
+				return err
+			}
+		}
+		if len(pending) == len(symlinks) {
+			for _, sl := range pending {
+				c.warningf("Not copying the target of symlink %v: %v is not among the outputs.", sl.Path, sl.SymlinkTarget)
+			}
+			return nil
+		}
+		symlinks = pending
+	}
+	return nil
+}
+
+// copyPath copies the file or the directory tree at src to dst, preserving file modes.
+func copyPath(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
@@ -0,0 +1,74 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_DownloadActionResultSymlinks(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"tool"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{},
+		OutputFiles: []string{"out/a", "link", "dirlink", "chain", "dangling", "escape"},
+	}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus},
+		&fakes.OutputFile{Path: "out/a", Contents: "a"},
+		&fakes.OutputSymlink{Path: "link", Target: "out/a"},
+		&fakes.OutputSymlink{Path: "dirlink", Target: "out"},
+		&fakes.OutputSymlink{Path: "chain", Target: "link"},
+		&fakes.OutputSymlink{Path: "dangling", Target: "missing"},
+		&fakes.OutputSymlink{Path: "escape", Target: "../a"})
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+
+	t.Run("preserve", func(t *testing.T) {
+		outDir := t.TempDir()
+		if err := toolClient.DownloadActionResult(context.Background(), acDg.String(), outDir, false); err != nil {
+			t.Fatalf("DownloadActionResult(%v) failed: %v", acDg, err)
+		}
+		for path, want := range map[string]string{"link": "out/a", "dirlink": "out", "chain": "link", "dangling": "missing", "escape": "../a"} {
+			got, err := os.Readlink(filepath.Join(outDir, path))
+			if err != nil {
+				t.Fatalf("Readlink(%v) failed: %v", path, err)
+			}
+			if got != want {
+				t.Errorf("symlink %v points to %v, want %v", path, got, want)
+			}
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		outDir := t.TempDir()
+		if err := toolClient.DownloadActionResult(context.Background(), acDg.String(), outDir, true); err != nil {
+			t.Fatalf("DownloadActionResult(%v) failed: %v", acDg, err)
+		}
+		for _, path := range []string{"link", "dirlink/a", "chain"} {
+			fp := filepath.Join(outDir, path)
+			if fi, err := os.Lstat(fp); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("Lstat(%v) = %v, %v, want a regular file", path, fi, err)
+				continue
+			}
+			got, err := ioutil.ReadFile(fp)
+			if err != nil {
+				t.Fatalf("ReadFile(%v) failed: %v", path, err)
+			}
+			if string(got) != "a" {
+				t.Errorf("%v contains %q, want %q", path, got, "a")
+			}
+		}
+		for _, path := range []string{"dangling", "escape"} {
+			if _, err := os.Lstat(filepath.Join(outDir, path)); !os.IsNotExist(err) {
+				t.Errorf("Lstat(%v) = %v, want it not to exist", path, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// phaseTiming is a phase of the execution of an action, between two timestamps of its
+// ExecutedActionMetadata.
+type phaseTiming struct {
+	phase    string
+	start    time.Time
+	end      time.Time
+	duration time.Duration
+}
+
+// phaseJSON is a phaseTiming in the JSON output of ShowActionFormat, with the timestamps in
+// RFC 3339 format.
+type phaseJSON struct {
+	Phase    string `json:"phase"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Duration string `json:"duration"`
+}
+
+// executionPhases returns the phases of the execution described by md, from when the action was
+// queued to when the worker completed it. Phases missing either of their timestamps are skipped.
+// The last phase is the total.
+func executionPhases(md *repb.ExecutedActionMetadata) []*phaseTiming {
+	phases := []struct {
+		name       string
+		start, end *tspb.Timestamp
+	}{
+		{"Queue", md.GetQueuedTimestamp(), md.GetWorkerStartTimestamp()},
+		{"Worker setup", md.GetWorkerStartTimestamp(), md.GetInputFetchStartTimestamp()},
+		{"Input fetch", md.GetInputFetchStartTimestamp(), md.GetInputFetchCompletedTimestamp()},
+		{"Execution", md.GetExecutionStartTimestamp(), md.GetExecutionCompletedTimestamp()},
+		{"Output upload", md.GetOutputUploadStartTimestamp(), md.GetOutputUploadCompletedTimestamp()},
+		{"Total", md.GetQueuedTimestamp(), md.GetWorkerCompletedTimestamp()},
+	}
+	var res []*phaseTiming
+	for _, p := range phases {
+		if p.start == nil || p.end == nil {
+			continue
+		}
+		start, err := ptypes.Timestamp(p.start)
+		if err != nil {
+			continue
+		}
+		end, err := ptypes.Timestamp(p.end)
+		if err != nil {
+			continue
+		}
+		res = append(res, &phaseTiming{phase: p.name, start: start, end: end, duration: end.Sub(start)})
+	}
+	return res
+}
+
+// executionTimingText returns the phases of the execution described by md as a table, with the
+// share of the total duration taken by each, or an empty string if md has no timestamps.
+func executionTimingText(md *repb.ExecutedActionMetadata) string {
+	phases := executionPhases(md)
+	if len(phases) == 0 {
+		return ""
+	}
+	var total time.Duration
+	if last := phases[len(phases)-1]; last.phase == "Total" {
+		total = last.duration
+	}
+	var res bytes.Buffer
+	if md.GetWorker() != "" {
+		res.WriteString(fmt.Sprintf("Worker: %v\n", md.GetWorker()))
+	}
+	for _, p := range phases {
+		line := fmt.Sprintf("%-14s %s -> %s  %v", p.phase+":", p.start.UTC().Format(time.RFC3339Nano), p.end.UTC().Format(time.RFC3339Nano), p.duration)
+		if total > 0 && p.phase != "Total" {
+			line += fmt.Sprintf(" (%.1f%%)", 100*float64(p.duration)/float64(total))
+		}
+		res.WriteString(line + "\n")
+	}
+	return res.String()
+}
+
+func executionTimingJSON(md *repb.ExecutedActionMetadata) []phaseJSON {
+	var res []phaseJSON
+	for _, p := range executionPhases(md) {
+		res = append(res, phaseJSON{
+			Phase:    p.phase,
+			Start:    p.start.UTC().Format(time.RFC3339Nano),
+			End:      p.end.UTC().Format(time.RFC3339Nano),
+			Duration: p.duration.String(),
+		})
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package tool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/google/go-cmp/cmp"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestExecutionTiming(t *testing.T) {
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *tspb.Timestamp {
+		p, _ := ptypes.TimestampProto(start.Add(d))
+		return p
+	}
+	md := &repb.ExecutedActionMetadata{
+		Worker:                       "worker-1",
+		QueuedTimestamp:              at(0),
+		WorkerStartTimestamp:         at(2 * time.Second),
+		InputFetchStartTimestamp:     at(3 * time.Second),
+		InputFetchCompletedTimestamp: at(4 * time.Second),
+		ExecutionStartTimestamp:      at(4 * time.Second),
+		ExecutionCompletedTimestamp:  at(9 * time.Second),
+		// The output upload timestamps are missing.
+		WorkerCompletedTimestamp: at(10 * time.Second),
+	}
+	want := `Worker: worker-1
+Queue:         2021-05-01T12:00:00Z -> 2021-05-01T12:00:02Z  2s (20.0%)
+Worker setup:  2021-05-01T12:00:02Z -> 2021-05-01T12:00:03Z  1s (10.0%)
+Input fetch:   2021-05-01T12:00:03Z -> 2021-05-01T12:00:04Z  1s (10.0%)
+Execution:     2021-05-01T12:00:04Z -> 2021-05-01T12:00:09Z  5s (50.0%)
+Total:         2021-05-01T12:00:00Z -> 2021-05-01T12:00:10Z  10s
+`
+	if diff := cmp.Diff(want, executionTimingText(md)); diff != "" {
+		t.Errorf("executionTimingText(%v) returned diff (-want +got):\n%s", md, diff)
+	}
+	wantJSON := []phaseJSON{
+		{Phase: "Queue", Start: "2021-05-01T12:00:00Z", End: "2021-05-01T12:00:02Z", Duration: "2s"},
+		{Phase: "Worker setup", Start: "2021-05-01T12:00:02Z", End: "2021-05-01T12:00:03Z", Duration: "1s"},
+		{Phase: "Input fetch", Start: "2021-05-01T12:00:03Z", End: "2021-05-01T12:00:04Z", Duration: "1s"},
+		{Phase: "Execution", Start: "2021-05-01T12:00:04Z", End: "2021-05-01T12:00:09Z", Duration: "5s"},
+		{Phase: "Total", Start: "2021-05-01T12:00:00Z", End: "2021-05-01T12:00:10Z", Duration: "10s"},
+	}
+	if diff := cmp.Diff(wantJSON, executionTimingJSON(md)); diff != "" {
+		t.Errorf("executionTimingJSON(%v) returned diff (-want +got):\n%s", md, diff)
+	}
+	if got := executionTimingText(&repb.ExecutedActionMetadata{}); got != "" {
+		t.Errorf("executionTimingText() with no timestamps = %q, want empty", got)
+	}
+}
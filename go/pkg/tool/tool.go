@@ -3,14 +3,18 @@
 package tool
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/golang/glog"
@@ -18,6 +22,8 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
+	oppb "google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/grpc/status"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/cas"
 	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
@@ -41,45 +47,135 @@ var testOnlyStartDeterminismExec = func() {}
 // Client is a remote execution client.
 type Client struct {
 	GrpcClient *rc.Client
+	// Events, if set, receives the progress of operations as events, in addition to the logs.
+	Events *EventLog
+	// OnProgress, if set, is called periodically with the progress of long transfers, e.g. of
+	// DownloadDirectory and UploadDirectory, and once more when each of them completes. See
+	// ProgressWriter.
+	OnProgress func(Progress)
+
+	// ops are the statistics of the operations reported with RecordOperation.
+	ops operationStats
 }
 
 // CheckDeterminism executes the action the given number of times and compares
-// output digests, reporting failure if a mismatch is detected.
-func (c *Client) CheckDeterminism(ctx context.Context, actionDigest, actionRoot string, attempts int) error {
-	oe := outerr.SystemOutErr
+// output digests, reporting failure if a mismatch is detected. If parallel is
+// greater than 1, up to parallel executions run concurrently, and mismatches
+// are reported once all of them complete.
+// On a mismatch, a report of the output paths differing across executions is
+// printed. The divergent versions of the mismatching output files are
+// downloaded to outDir if set, and diffed if diff is set; see mismatchReport.
+// The outputs of the executions and the report are written to oe.
+func (c *Client) CheckDeterminism(ctx context.Context, actionDigest, actionRoot string, attempts, parallel int, outDir string, diff bool, oe outerr.OutErr) error {
+	if parallel > 1 {
+		return c.checkDeterminismParallel(ctx, actionDigest, actionRoot, attempts, parallel, outDir, diff, oe)
+	}
 	firstMd, firstRes := c.ExecuteAction(ctx, actionDigest, actionRoot, "", oe)
+	mds := []*command.Metadata{firstMd}
 	for i := 1; i < attempts; i++ {
 		testOnlyStartDeterminismExec()
 		md, res := c.ExecuteAction(ctx, actionDigest, actionRoot, "", oe)
-		gotErr := false
-		if (firstRes == nil) != (res == nil) {
-			log.Errorf("action does not produce a consistent result, got %v and %v from consecutive executions", res, firstRes)
-			gotErr = true
-		}
-		if len(md.OutputFileDigests) != len(firstMd.OutputFileDigests) {
-			log.Errorf("action does not produce a consistent number of outputs, got %v and %v from consecutive executions", len(md.OutputFileDigests), len(firstMd.OutputFileDigests))
-			gotErr = true
-		}
-		for p, d := range md.OutputFileDigests {
-			firstD, ok := firstMd.OutputFileDigests[p]
-			if !ok {
-				log.Errorf("action does not produce %v consistently", p)
-				gotErr = true
-				continue
-			}
-			if d != firstD {
-				log.Errorf("action does not produce a consistent digest for %v, got %v and %v", p, d, firstD)
-				gotErr = true
-				continue
-			}
+		mds = append(mds, md)
+		if !consistentExecutions(firstMd, firstRes, md, res) {
+			return c.reportMismatches(ctx, mds, outDir, diff, oe, 1, i)
 		}
-		if gotErr {
-			return fmt.Errorf("action is not deterministic, check error log for more details")
+	}
+	return nil
+}
+
+// reportMismatches writes the report of the outputs differing across the executions with the
+// given metadata to oe, and returns the error of CheckDeterminism.
+func (c *Client) reportMismatches(ctx context.Context, mds []*command.Metadata, outDir string, diff bool, oe outerr.OutErr, mismatches, compared int) error {
+	report, err := c.mismatchReport(ctx, mds, outDir, diff)
+	if err != nil {
+		c.errorf("error reporting mismatching outputs: %v", err)
+	}
+	oe.WriteOut([]byte(report))
+	return fmt.Errorf("action is not deterministic, %d of %d executions mismatched the first one, check error log for more details", mismatches, compared)
+}
+
+func (c *Client) checkDeterminismParallel(ctx context.Context, actionDigest, actionRoot string, attempts, parallel int, outDir string, diff bool, oe outerr.OutErr) error {
+	if actionRoot == "" {
+		// Download the action once, rather than its inputs for each execution.
+		dir, err := ioutil.TempDir("", "check_determinism")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := c.DownloadAction(ctx, actionDigest, dir); err != nil {
+			return err
+		}
+		actionRoot = dir
+	}
+	mds := make([]*command.Metadata, attempts)
+	errs := make([]error, attempts)
+	// Each execution writes its stdout and stderr to its own recorder, copied to oe in order once
+	// they are all done, so that concurrent executions neither race on oe nor interleave.
+	oes := make([]*outerr.RecordingOutErr, attempts)
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		i := i
+		oes[i] = outerr.NewRecordingOutErr()
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			mds[i], errs[i] = c.ExecuteAction(ctx, actionDigest, actionRoot, "", oes[i])
+		}()
+	}
+	wg.Wait()
+	for _, r := range oes {
+		oe.WriteOut(r.Stdout())
+		oe.WriteErr(r.Stderr())
+	}
+
+	mismatches := 0
+	for i := 1; i < attempts; i++ {
+		if !consistentExecutions(mds[0], errs[0], mds[i], errs[i]) {
+			c.errorf("execution %d does not match execution 0", i)
+			mismatches++
 		}
 	}
+	if mismatches > 0 {
+		return c.reportMismatches(ctx, mds, outDir, diff, oe, mismatches, attempts-1)
+	}
 	return nil
 }
 
+// consistentExecutions returns whether two executions of an action produced the same outputs,
+// logging the differences otherwise.
+func consistentExecutions(firstMd *command.Metadata, firstRes error, md *command.Metadata, res error) bool {
+	if (firstRes == nil) != (res == nil) {
+		log.Errorf("action does not produce a consistent result, got %v and %v from consecutive executions", res, firstRes)
+		return false
+	}
+	if md == nil || firstMd == nil {
+		return md == firstMd
+	}
+	consistent := true
+	if len(md.OutputFileDigests) != len(firstMd.OutputFileDigests) {
+		log.Errorf("action does not produce a consistent number of outputs, got %v and %v from consecutive executions", len(md.OutputFileDigests), len(firstMd.OutputFileDigests))
+		consistent = false
+	}
+	for p, d := range md.OutputFileDigests {
+		firstD, ok := firstMd.OutputFileDigests[p]
+		if !ok {
+			log.Errorf("action does not produce %v consistently", p)
+			consistent = false
+			continue
+		}
+		if d != firstD {
+			log.Errorf("action does not produce a consistent digest for %v, got %v and %v", p, d, firstD)
+			consistent = false
+		}
+	}
+	return consistent
+}
+
 func (c *Client) prepCommand(ctx context.Context, client *rexec.Client, actionDigest, inputRoot string) (*command.Command, error) {
 	acDg, err := digest.NewFromString(actionDigest)
 	if err != nil {
@@ -96,7 +192,7 @@ func (c *Client) prepCommand(ctx context.Context, client *rexec.Client, actionDi
 		return nil, err
 	}
 
-	log.Infof("Reading command from action digest..")
+	c.infof("Reading command from action digest..")
 	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
 		return nil, err
 	}
@@ -107,7 +203,7 @@ func (c *Client) prepCommand(ctx context.Context, client *rexec.Client, actionDi
 		if err != nil {
 			return nil, err
 		}
-		log.Infof("Fetching input tree from input root digest %s into %s", dg, inputRoot)
+		c.infof("Fetching input tree from input root digest %s into %s", dg, inputRoot)
 		_, _, err = c.GrpcClient.DownloadDirectory(ctx, dg, inputRoot, client.FileMetadataCache)
 		if err != nil {
 			return nil, err
@@ -157,9 +253,34 @@ func commandFromREProto(cmdPb *repb.Command) *command.Command {
 	return cmd
 }
 
+// actionEntries builds the Command proto of cmd and the Action executing it with the given input
+// root, as rexec.Context.ExecuteRemotely does, and returns the entries of their blobs.
+func (c *Client) actionEntries(cmd *command.Command, root digest.Digest) (cmdUe, acUe *uploadinfo.Entry, err error) {
+	cmdPb := cmd.ToREProto(c.GrpcClient.SupportsCommandOutputPaths())
+	if cmdUe, err = uploadinfo.EntryFromProto(cmdPb); err != nil {
+		return nil, nil, err
+	}
+	acPb := &repb.Action{
+		CommandDigest:   cmdUe.Digest.ToProto(),
+		InputRootDigest: root.ToProto(),
+	}
+	if c.GrpcClient.SupportsActionPlatformProperties() {
+		acPb.Platform = cmdPb.Platform
+	}
+	if cmd.Timeout > 0 {
+		acPb.Timeout = ptypes.DurationProto(cmd.Timeout)
+	}
+	if acUe, err = uploadinfo.EntryFromProto(acPb); err != nil {
+		return nil, nil, err
+	}
+	return cmdUe, acUe, nil
+}
+
 // DownloadActionResult downloads the action result of the given action digest
-// if it exists in the remote cache.
-func (c *Client) DownloadActionResult(ctx context.Context, actionDigest, pathPrefix string) error {
+// if it exists in the remote cache. Output symlinks are recreated as symlinks,
+// unless copySymlinks is set, in which case copies of their targets are written
+// instead, e.g. on Windows.
+func (c *Client) DownloadActionResult(ctx context.Context, actionDigest, pathPrefix string, copySymlinks bool) error {
 	acDg, err := digest.NewFromString(actionDigest)
 	if err != nil {
 		return err
@@ -173,7 +294,7 @@ func (c *Client) DownloadActionResult(ctx context.Context, actionDigest, pathPre
 	if err != nil {
 		return err
 	}
-	log.Infof("Reading command from action digest..")
+	c.infof("Reading command from action digest..")
 	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
 		return err
 	}
@@ -188,15 +309,13 @@ func (c *Client) DownloadActionResult(ctx context.Context, actionDigest, pathPre
 		return fmt.Errorf("action digest %v not found in cache", actionDigest)
 	}
 
-	log.Infof("Cleaning contents of %v.", pathPrefix)
+	c.infof("Cleaning contents of %v.", pathPrefix)
 	os.RemoveAll(pathPrefix)
 	os.Mkdir(pathPrefix, 0755)
 
-	log.Infof("Downloading action results of %v to %v.", actionDigest, pathPrefix)
-	// We don't really need an in-memory filemetadata cache for debugging operations.
-	noopCache := filemetadata.NewNoopCache()
-	if _, err := c.GrpcClient.DownloadActionOutputs(ctx, resPb, filepath.Join(pathPrefix, cmd.WorkingDir), noopCache); err != nil {
-		log.Errorf("Failed downloading action outputs: %v.", err)
+	c.infof("Downloading action results of %v to %v.", actionDigest, pathPrefix)
+	if err := c.downloadActionOutputs(ctx, resPb, filepath.Join(pathPrefix, cmd.WorkingDir), copySymlinks); err != nil {
+		c.errorf("Failed downloading action outputs: %v.", err)
 	}
 
 	// We have not requested for stdout/stderr to be inlined in GetActionResult, so the server
@@ -213,19 +332,141 @@ func (c *Client) DownloadActionResult(ctx context.Context, actionDigest, pathPre
 			Hash: reDg.GetHash(),
 			Size: reDg.GetSizeBytes(),
 		}
-		log.Infof("Downloading stdout/stderr to %v.", path)
+		c.infof("Downloading stdout/stderr to %v.", path)
 		bytes, _, err := c.GrpcClient.ReadBlob(ctx, *dg)
 		if err != nil {
-			log.Errorf("Unable to read blob for %v with digest %v.", path, dg)
+			c.errorf("Unable to read blob for %v with digest %v.", path, dg)
 		}
 		if err := ioutil.WriteFile(path, bytes, 0644); err != nil {
-			log.Errorf("Unable to write output of digest %v to file %v.", dg, path)
+			c.errorf("Unable to write output of digest %v to file %v.", dg, path)
 		}
 	}
-	log.Infof("Successfully downloaded results of %v to %v.", actionDigest, pathPrefix)
+	c.infof("Successfully downloaded results of %v to %v.", actionDigest, pathPrefix)
 	return nil
 }
 
+// DownloadStdErrOut fetches only the stdout and stderr of an action result, without its output
+// files. The given digest is either that of an action, whose result is looked up in the action
+// cache, or that of an ActionResult stored in the CAS. If pathPrefix is set, stdout and stderr are
+// written to files under it, otherwise they are written to oe.
+func (c *Client) DownloadStdErrOut(ctx context.Context, resultDigest, pathPrefix string, oe outerr.OutErr) error {
+	resPb, err := c.getActionResult(ctx, resultDigest)
+	if err != nil {
+		return err
+	}
+	if resPb == nil {
+		dg, err := digest.NewFromString(resultDigest)
+		if err != nil {
+			return err
+		}
+		c.infof("No action result for %v in cache, reading it as an ActionResult from the CAS.", dg)
+		resPb = &repb.ActionResult{}
+		if _, err := c.GrpcClient.ReadProto(ctx, dg, resPb); err != nil {
+			return errors.Wrapf(err, "no action result for %v in the action cache or the CAS", dg)
+		}
+	}
+
+	outs := []struct {
+		name  string
+		raw   []byte
+		dg    *repb.Digest
+		write func([]byte)
+	}{
+		{stdoutFile, resPb.StdoutRaw, resPb.StdoutDigest, oe.WriteOut},
+		{stderrFile, resPb.StderrRaw, resPb.StderrDigest, oe.WriteErr},
+	}
+	if pathPrefix != "" {
+		if err := os.MkdirAll(pathPrefix, 0755); err != nil {
+			return err
+		}
+	}
+	for _, out := range outs {
+		if pathPrefix == "" {
+			b, err := c.readOutErr(ctx, out.raw, out.dg)
+			if err != nil {
+				return err
+			}
+			out.write(b)
+			continue
+		}
+		path := filepath.Join(pathPrefix, out.name)
+		if out.dg == nil || out.raw != nil {
+			if err := ioutil.WriteFile(path, out.raw, 0644); err != nil {
+				return err
+			}
+			continue
+		}
+		dg, err := digest.NewFromProto(out.dg)
+		if err != nil {
+			return err
+		}
+		c.infof("Downloading %v to %v.", out.name, path)
+		if _, err := c.GrpcClient.ReadBlobToFile(ctx, dg, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadActionResultArchive writes the outputs of the action result of the given action digest
+// into a tar or zip archive at path, without extracting them. Output paths in the archive are
+// relative to the exec root, like the paths written by DownloadActionResult.
+func (c *Client) DownloadActionResultArchive(ctx context.Context, actionDigest, path, format string) error {
+	if format != "tar" && format != "zip" {
+		return fmt.Errorf("unsupported archive format %q, expected tar or zip", format)
+	}
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return err
+	}
+	actionProto := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, acDg, actionProto); err != nil {
+		return err
+	}
+	commandProto := &repb.Command{}
+	cmdDg, err := digest.NewFromProto(actionProto.GetCommandDigest())
+	if err != nil {
+		return err
+	}
+	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
+		return err
+	}
+	resPb, err := c.getActionResult(ctx, actionDigest)
+	if err != nil {
+		return err
+	}
+	if resPb == nil {
+		return fmt.Errorf("action digest %v not found in cache", actionDigest)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c.infof("Writing action results of %v to %v archive %v.", actionDigest, format, path)
+	prefix := commandProto.GetWorkingDirectory()
+	switch format {
+	case "tar":
+		tw := tar.NewWriter(f)
+		if err := c.GrpcClient.WriteActionOutputsTar(ctx, resPb, prefix, tw); err != nil {
+			return err
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+	case "zip":
+		zw := zip.NewWriter(f)
+		if err := c.GrpcClient.WriteActionOutputsZip(ctx, resPb, prefix, zw); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
 // DownloadBlob downloads a blob from the remote cache into the specified path.
 // If the path is empty, it writes the contents to stdout instead.
 func (c *Client) DownloadBlob(ctx context.Context, blobDigest, path string) (string, error) {
@@ -247,7 +488,7 @@ func (c *Client) DownloadBlob(ctx context.Context, blobDigest, path string) (str
 	if err != nil {
 		return "", err
 	}
-	log.Infof("Downloading blob of %v to %v.", dg, path)
+	c.infof("Downloading blob of %v to %v.", dg, path)
 	if _, err := c.GrpcClient.ReadBlobToFile(ctx, dg, path); err != nil {
 		return "", err
 	}
@@ -268,7 +509,7 @@ func (c *Client) UploadBlob(ctx context.Context, path string) error {
 		return err
 	}
 
-	log.Infof("Uploading blob of %v from %v.", dg, path)
+	c.infof("Uploading blob of %v from %v.", dg, path)
 	ue := uploadinfo.EntryFromFile(dg, path)
 	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, ue); err != nil {
 		return err
@@ -276,6 +517,64 @@ func (c *Client) UploadBlob(ctx context.Context, path string) error {
 	return nil
 }
 
+// StreamBlob writes the blob with the given digest from the remote cache to w as it is read, without
+// holding it in memory or in a temporary file, so that blobs of any size can be piped to stdout.
+// Reads interrupted by transient errors are resumed where they stopped. The size and hash of the
+// blob are verified once it is fully written.
+func (c *Client) StreamBlob(ctx context.Context, blobDigest string, w io.Writer) error {
+	dg, err := digest.NewFromString(blobDigest)
+	if err != nil {
+		return err
+	}
+	c.infof("Streaming blob of %v.", dg)
+	_, err = c.GrpcClient.ReadBlobToWriter(ctx, dg, w)
+	return err
+}
+
+// maxInMemoryBlobSize is the size of the largest blobs read by UploadBlobFromReader into memory.
+// Larger blobs are spooled to a temporary file.
+const maxInMemoryBlobSize = 4 * 1024 * 1024
+
+// UploadBlobFromReader uploads the blob read from r, e.g. the standard input, into the remote
+// cache, and returns its digest.
+func (c *Client) UploadBlobFromReader(ctx context.Context, r io.Reader) (digest.Digest, error) {
+	blob, err := ioutil.ReadAll(io.LimitReader(r, maxInMemoryBlobSize+1))
+	if err != nil {
+		return digest.Empty, err
+	}
+	if len(blob) <= maxInMemoryBlobSize {
+		ue := uploadinfo.EntryFromBlob(blob)
+		c.infof("Uploading blob of %v.", ue.Digest)
+		if _, _, err := c.GrpcClient.UploadIfMissing(ctx, ue); err != nil {
+			return digest.Empty, err
+		}
+		return ue.Digest, nil
+	}
+
+	f, err := ioutil.TempFile("", "upload_blob")
+	if err != nil {
+		return digest.Empty, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	c.infof("Spooling blob larger than %d bytes to %v.", maxInMemoryBlobSize, f.Name())
+	if _, err := io.Copy(f, io.MultiReader(bytes.NewReader(blob), r)); err != nil {
+		return digest.Empty, err
+	}
+	if err := f.Close(); err != nil {
+		return digest.Empty, err
+	}
+	dg, err := digest.NewFromFile(f.Name())
+	if err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Uploading blob of %v.", dg)
+	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, uploadinfo.EntryFromFile(dg, f.Name())); err != nil {
+		return digest.Empty, err
+	}
+	return dg, nil
+}
+
 // UploadBlobV2 uploads a blob from the specified path into the remote cache using newer cas implementation.
 func (c *Client) UploadBlobV2(ctx context.Context, path string) error {
 	casC, err := cas.NewClient(ctx, c.GrpcClient.Connection, c.GrpcClient.InstanceName)
@@ -302,21 +601,149 @@ func (c *Client) UploadBlobV2(ctx context.Context, path string) error {
 	return errors.WithStack(eg.Wait())
 }
 
+// UploadDirectory uploads the contents of the local directory at path into the remote cache, and
+// returns the digest of its root Directory.
+func (c *Client) UploadDirectory(ctx context.Context, path string) (digest.Digest, error) {
+	root, entries, _, err := c.localTree(path, nil)
+	if err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Uploading %d blobs of directory %v with root digest %v.", len(entries), path, root)
+	if c.OnProgress != nil {
+		// The progress only counts the blobs which are transferred.
+		if entries, err = c.missingEntries(ctx, entries); err != nil {
+			return digest.Empty, err
+		}
+	}
+	var size int64
+	for _, ue := range entries {
+		size += ue.Digest.Size
+	}
+	finish := c.trackProgress("Uploading", false, int64(len(entries)), size)
+	_, _, err = c.GrpcClient.UploadIfMissing(ctx, entries...)
+	finish(err)
+	if err != nil {
+		return digest.Empty, err
+	}
+	return root, nil
+}
+
+// missingEntries returns the entries whose blobs are missing from the CAS.
+func (c *Client) missingEntries(ctx context.Context, entries []*uploadinfo.Entry) ([]*uploadinfo.Entry, error) {
+	dgs := make([]digest.Digest, len(entries))
+	for i, ue := range entries {
+		dgs[i] = ue.Digest
+	}
+	missing, err := c.GrpcClient.MissingBlobs(ctx, dgs)
+	if err != nil {
+		return nil, err
+	}
+	isMissing := make(map[digest.Digest]bool, len(missing))
+	for _, dg := range missing {
+		isMissing[dg] = true
+	}
+	var res []*uploadinfo.Entry
+	for _, ue := range entries {
+		if isMissing[ue.Digest] {
+			res = append(res, ue)
+		}
+	}
+	return res, nil
+}
+
+// localTree computes the Merkle tree of the inputs of the local directory at path, and returns the
+// digest of its root Directory with the blobs of the tree and their stats. If is is nil, the inputs
+// are all the entries of the directory.
+func (c *Client) localTree(path string, is *command.InputSpec) (digest.Digest, []*uploadinfo.Entry, *rc.TreeStats, error) {
+	if is == nil {
+		contents, err := ioutil.ReadDir(path)
+		if err != nil {
+			return digest.Empty, nil, nil, err
+		}
+		is = &command.InputSpec{}
+		for _, f := range contents {
+			is.Inputs = append(is.Inputs, f.Name())
+		}
+	}
+	c.infof("Computing the Merkle tree of %v.", path)
+	return c.GrpcClient.ComputeMerkleTree(path, "", "", is, filemetadata.NewNoopCache())
+}
+
 // DownloadDirectory downloads a an input root from the remote cache into the specified path.
-func (c *Client) DownloadDirectory(ctx context.Context, rootDigest, path string) error {
-	log.Infof("Cleaning contents of %v.", path)
-	os.RemoveAll(path)
+// If include or exclude glob patterns are set, only the matching entries of the tree are
+// downloaded; see pathFilter. If resume is set, the existing contents of path are kept, and only
+// the entries missing from it or not matching the tree are downloaded, e.g. to resume a download
+// which failed midway.
+func (c *Client) DownloadDirectory(ctx context.Context, rootDigest, path string, include, exclude []string, resume bool) error {
+	f, err := newPathFilter(include, exclude)
+	if err != nil {
+		return err
+	}
+	dg, err := digest.NewFromString(rootDigest)
+	if err != nil {
+		return err
+	}
+	if !resume {
+		c.infof("Cleaning contents of %v.", path)
+		os.RemoveAll(path)
+	}
 	os.Mkdir(path, 0755)
 
-	dg, err := digest.NewFromString(rootDigest)
+	if f == nil && !resume && c.OnProgress == nil {
+		c.infof("Downloading input root %v to %v.", dg, path)
+		_, _, err = c.GrpcClient.DownloadDirectory(ctx, dg, path, filemetadata.NewNoopCache())
+		return err
+	}
+	if f == nil {
+		// Selects all the entries.
+		f = &pathFilter{}
+	}
+	c.infof("Fetching tree %v..", dg)
+	dirPbs, err := c.GrpcClient.GetDirectoryTree(ctx, dg.ToProto())
 	if err != nil {
 		return err
 	}
-	log.Infof("Downloading input root %v to %v.", dg, path)
-	_, _, err = c.GrpcClient.DownloadDirectory(ctx, dg, path, filemetadata.NewNoopCache())
+	dirs := make(map[digest.Digest]*repb.Directory, len(dirPbs))
+	for _, d := range dirPbs {
+		dirDg, err := digest.NewFromMessage(d)
+		if err != nil {
+			return err
+		}
+		dirs[dirDg] = d
+	}
+	outs := make(map[string]*rc.TreeOutput)
+	if err := f.walk(dg, "", false, dirs, outs); err != nil {
+		return err
+	}
+	if resume {
+		skipped, err := c.skipDownloaded(path, outs)
+		if err != nil {
+			return err
+		}
+		c.infof("Skipping %d entries of input root %v already in %v.", skipped, dg, path)
+	}
+	c.infof("Downloading %d matching entries of input root %v to %v.", len(outs), dg, path)
+	files, size := downloadSize(outs)
+	finish := c.trackProgress("Downloading", true, files, size)
+	_, err = c.GrpcClient.DownloadOutputs(ctx, outs, path, filemetadata.NewNoopCache())
+	finish(err)
 	return err
 }
 
+// downloadSize returns the number and the total size of the distinct blobs downloaded for outs.
+func downloadSize(outs map[string]*rc.TreeOutput) (files, size int64) {
+	seen := make(map[digest.Digest]bool)
+	for _, out := range outs {
+		if out.IsEmptyDirectory || out.SymlinkTarget != "" || out.Digest.Size == 0 || seen[out.Digest] {
+			continue
+		}
+		seen[out.Digest] = true
+		files++
+		size += out.Digest.Size
+	}
+	return files, size
+}
+
 func (c *Client) writeProto(m proto.Message, baseName string) error {
 	f, err := os.Create(baseName)
 	if err != nil {
@@ -338,7 +765,7 @@ func (c *Client) DownloadAction(ctx context.Context, actionDigest, outputPath st
 		return err
 	}
 	actionProto := &repb.Action{}
-	log.Infof("Reading action..")
+	c.infof("Reading action..")
 	if _, err := c.GrpcClient.ReadProto(ctx, acDg, actionProto); err != nil {
 		return err
 	}
@@ -350,7 +777,7 @@ func (c *Client) DownloadAction(ctx context.Context, actionDigest, outputPath st
 	if err != nil {
 		return err
 	}
-	log.Infof("Reading command from action..")
+	c.infof("Reading command from action..")
 	commandProto := &repb.Command{}
 	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
 		return err
@@ -359,7 +786,7 @@ func (c *Client) DownloadAction(ctx context.Context, actionDigest, outputPath st
 		return err
 	}
 
-	log.Infof("Fetching input tree from input root digest.. %v", actionProto.GetInputRootDigest())
+	c.infof("Fetching input tree from input root digest.. %v", actionProto.GetInputRootDigest())
 	rootPath := filepath.Join(outputPath, "input")
 	os.RemoveAll(rootPath)
 	os.Mkdir(rootPath, 0755)
@@ -372,40 +799,46 @@ func (c *Client) DownloadAction(ctx context.Context, actionDigest, outputPath st
 }
 
 func (c *Client) prepProtos(ctx context.Context, actionRoot string) (string, error) {
-	cmdTxt, err := ioutil.ReadFile(filepath.Join(actionRoot, "cmd.textproto"))
+	_, _, entries, err := readActionRoot(actionRoot)
 	if err != nil {
 		return "", err
 	}
+	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, entries...); err != nil {
+		return "", err
+	}
+	return entries[1].Digest.String(), nil
+}
+
+// readActionRoot reads the Command and the Action protos of the action spec at actionRoot, see
+// ExecuteAction, and returns them with the entries of their blobs, the Command first. The command
+// digest of the Action is set to that of the Command.
+func readActionRoot(actionRoot string) (*repb.Action, *repb.Command, []*uploadinfo.Entry, error) {
+	cmdTxt, err := ioutil.ReadFile(filepath.Join(actionRoot, "cmd.textproto"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	cmdProto := &repb.Command{}
 	if err := proto.UnmarshalText(string(cmdTxt), cmdProto); err != nil {
-		return "", err
+		return nil, nil, nil, err
 	}
 	cmdPb, err := proto.Marshal(cmdProto)
 	if err != nil {
-		return "", err
-	}
-	ue := uploadinfo.EntryFromBlob(cmdPb)
-	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, ue); err != nil {
-		return "", err
+		return nil, nil, nil, err
 	}
 	ac, err := ioutil.ReadFile(filepath.Join(actionRoot, "ac.textproto"))
 	if err != nil {
-		return "", err
+		return nil, nil, nil, err
 	}
 	actionProto := &repb.Action{}
 	if err := proto.UnmarshalText(string(ac), actionProto); err != nil {
-		return "", err
+		return nil, nil, nil, err
 	}
 	actionProto.CommandDigest = digest.NewFromBlob(cmdPb).ToProto()
 	acPb, err := proto.Marshal(actionProto)
 	if err != nil {
-		return "", err
-	}
-	ue = uploadinfo.EntryFromBlob(acPb)
-	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, ue); err != nil {
-		return "", err
+		return nil, nil, nil, err
 	}
-	return digest.NewFromBlob(acPb).String(), nil
+	return actionProto, cmdProto, []*uploadinfo.Entry{uploadinfo.EntryFromBlob(cmdPb), uploadinfo.EntryFromBlob(acPb)}, nil
 }
 
 // ExecuteAction executes an action in a cannonical structure remotely.
@@ -416,6 +849,56 @@ func (c *Client) prepProtos(ctx context.Context, actionRoot string) (string, err
 //           > input (Input root)
 //             > inputs...
 func (c *Client) ExecuteAction(ctx context.Context, actionDigest, actionRoot, outDir string, oe outerr.OutErr) (*command.Metadata, error) {
+	return c.ReexecuteAction(ctx, actionDigest, actionRoot, outDir, nil, oe)
+}
+
+// ExecuteOverrides are modifications applied to an action before it is re-executed.
+type ExecuteOverrides struct {
+	// Platform properties merged into those of the action. Properties with empty values are
+	// removed from the action.
+	Platform map[string]string
+	// Args, if set, replace the arguments of the command.
+	Args []string
+	// ExtraArgs are appended to the arguments of the command, e.g. to make a compiler verbose.
+	ExtraArgs []string
+	// Env are environment variables set in the command, replacing their values if already set.
+	Env map[string]string
+	// UnsetEnv are environment variables removed from the command.
+	UnsetEnv []string
+}
+
+func (o *ExecuteOverrides) apply(cmd *command.Command) {
+	if o == nil {
+		return
+	}
+	if o.Args != nil {
+		cmd.Args = o.Args
+	}
+	if len(o.ExtraArgs) > 0 {
+		cmd.Args = append(append([]string(nil), cmd.Args...), o.ExtraArgs...)
+	}
+	for name, val := range o.Platform {
+		if val == "" {
+			delete(cmd.Platform, name)
+			continue
+		}
+		cmd.Platform[name] = val
+	}
+	if len(o.Env) > 0 && cmd.InputSpec.EnvironmentVariables == nil {
+		cmd.InputSpec.EnvironmentVariables = make(map[string]string)
+	}
+	for name, val := range o.Env {
+		cmd.InputSpec.EnvironmentVariables[name] = val
+	}
+	for _, name := range o.UnsetEnv {
+		delete(cmd.InputSpec.EnvironmentVariables, name)
+	}
+}
+
+// ReexecuteAction executes an action like ExecuteAction, after modifying it with the given
+// overrides, if any. The modified Action and Command protos are uploaded, and the digest of the
+// executed action is that of the modified Action.
+func (c *Client) ReexecuteAction(ctx context.Context, actionDigest, actionRoot, outDir string, overrides *ExecuteOverrides, oe outerr.OutErr) (*command.Metadata, error) {
 	fmc := filemetadata.NewNoopCache()
 	client := &rexec.Client{
 		FileMetadataCache: fmc,
@@ -433,12 +916,24 @@ func (c *Client) ExecuteAction(ctx context.Context, actionDigest, actionRoot, ou
 	if err != nil {
 		return nil, err
 	}
+	overrides.apply(cmd)
 	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: true}
 	ec, err := client.NewContext(ctx, cmd, opt, oe)
 	if err != nil {
 		return nil, err
 	}
 	ec.ExecuteRemotely()
+	printExecution(ec, cmd, oe)
+	if ec.Result.Err == nil && outDir != "" {
+		ec.DownloadOutputs(outDir)
+		fmt.Printf("Output written to %v\n", outDir)
+	}
+	return ec.Metadata, ec.Result.Err
+}
+
+// printExecution prints a summary of the remote execution of cmd in ec, and writes the reason it
+// failed, if any, to oe.
+func printExecution(ec *rexec.Context, cmd *command.Command, oe outerr.OutErr) {
 	fmt.Printf("Action complete\n")
 	fmt.Printf("---------------\n")
 	fmt.Printf("Action digest: %v\n", ec.Metadata.ActionDigest.String())
@@ -459,11 +954,116 @@ func (c *Client) ExecuteAction(ctx context.Context, actionDigest, actionRoot, ou
 	case command.LocalErrorResultStatus:
 		oe.WriteErr([]byte(fmt.Sprintf("Local error: %v.\n", ec.Result.Err)))
 	}
-	if ec.Result.Err == nil && outDir != "" {
-		ec.DownloadOutputs(outDir)
-		fmt.Printf("Output written to %v\n", outDir)
+}
+
+// WaitOperation attaches to the remote execution with the given operation name, which may have
+// been started from another machine or by a process that has since exited, and prints its stage
+// updates until it completes. The stdout and stderr of the action are then written to oe, and its
+// outputs are downloaded to outDir, if set.
+func (c *Client) WaitOperation(ctx context.Context, name, outDir string, oe outerr.OutErr) error {
+	var stage repb.ExecutionStage_Value
+	var acDg *repb.Digest
+	op, err := c.GrpcClient.WaitOperation(ctx, name, func(md *repb.ExecuteOperationMetadata) {
+		if md.ActionDigest != nil {
+			acDg = md.ActionDigest
+		}
+		if md.Stage != stage {
+			stage = md.Stage
+			fmt.Printf("Operation %v: %v\n", name, stage)
+		}
+	})
+	if err != nil {
+		return err
 	}
-	return ec.Metadata, ec.Result.Err
+	return c.completeOperation(ctx, op, acDg, outDir, oe)
+}
+
+// completeOperation prints the result of the completed execution operation op of the action with
+// the given digest, if known, and writes the stdout and stderr of the action to oe. Its outputs
+// are downloaded to outDir, if set.
+func (c *Client) completeOperation(ctx context.Context, op *oppb.Operation, acDg *repb.Digest, outDir string, oe outerr.OutErr) error {
+	if e := op.GetError(); e != nil {
+		return rc.StatusDetailedError(status.FromProto(e))
+	}
+	if st := rc.OperationStatus(op); st != nil {
+		return rc.StatusDetailedError(st)
+	}
+	res := &repb.ExecuteResponse{}
+	if err := ptypes.UnmarshalAny(op.GetResponse(), res); err != nil {
+		return errors.Wrapf(err, "operation %v has no execute response", op.GetName())
+	}
+	ar := res.GetResult()
+	fmt.Printf("Operation complete\n")
+	fmt.Printf("------------------\n")
+	if acDg != nil {
+		fmt.Printf("Action digest: %v/%v\n", acDg.Hash, acDg.SizeBytes)
+	}
+	fmt.Printf("Cached result: %v\n", res.CachedResult)
+	fmt.Printf("Exit code: %d\n", ar.GetExitCode())
+
+	stdout, err := c.readOutErr(ctx, ar.GetStdoutRaw(), ar.GetStdoutDigest())
+	if err != nil {
+		return err
+	}
+	oe.WriteOut(stdout)
+	stderr, err := c.readOutErr(ctx, ar.GetStderrRaw(), ar.GetStderrDigest())
+	if err != nil {
+		return err
+	}
+	oe.WriteErr(stderr)
+	if ar.GetExitCode() != 0 {
+		oe.WriteErr([]byte(fmt.Sprintf("Remote action FAILED with exit code %d.\n", ar.GetExitCode())))
+	}
+
+	if outDir == "" || ar == nil {
+		return nil
+	}
+	// Outputs are relative to the working directory of the command, when it can be found.
+	wd := ""
+	if acDg != nil {
+		if wd, err = c.workingDir(ctx, acDg); err != nil {
+			c.warningf("Unable to read the working directory of action %v/%v, downloading outputs relative to %v: %v", acDg.Hash, acDg.SizeBytes, outDir, err)
+		}
+	}
+	if _, err := c.GrpcClient.DownloadActionOutputs(ctx, ar, filepath.Join(outDir, wd), filemetadata.NewNoopCache()); err != nil {
+		return err
+	}
+	fmt.Printf("Output written to %v\n", outDir)
+	return nil
+}
+
+// readOutErr returns the contents of stdout or stderr, inlined as raw or stored in the CAS under dg.
+func (c *Client) readOutErr(ctx context.Context, raw []byte, dg *repb.Digest) ([]byte, error) {
+	if raw != nil || dg == nil {
+		return raw, nil
+	}
+	d, err := digest.NewFromProto(dg)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := c.GrpcClient.ReadBlob(ctx, d)
+	return b, err
+}
+
+// workingDir returns the working directory of the command of the action with the given digest.
+func (c *Client) workingDir(ctx context.Context, acDg *repb.Digest) (string, error) {
+	dg, err := digest.NewFromProto(acDg)
+	if err != nil {
+		return "", err
+	}
+	actionProto := &repb.Action{}
+	if _, err := c.GrpcClient.ReadProto(ctx, dg, actionProto); err != nil {
+		return "", err
+	}
+	cmdDg, err := digest.NewFromProto(actionProto.GetCommandDigest())
+	if err != nil {
+		return "", err
+	}
+	commandProto := &repb.Command{}
+	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
+		return "", err
+	}
+	return commandProto.WorkingDirectory, nil
 }
 
 // ShowAction parses and displays an action with its corresponding command.
@@ -499,7 +1099,7 @@ func (c *Client) ShowAction(ctx context.Context, actionDigest string) (string, e
 	showActionRes.WriteString("Command\n=======\n")
 	showActionRes.WriteString(fmt.Sprintf("Command Digest: %v\n", cmdDg))
 
-	log.Infof("Reading command from action digest..")
+	c.infof("Reading command from action digest..")
 	if _, err := c.GrpcClient.ReadProto(ctx, cmdDg, commandProto); err != nil {
 		return "", err
 	}
@@ -515,7 +1115,7 @@ func (c *Client) ShowAction(ctx context.Context, actionDigest string) (string, e
 	}
 
 	showActionRes.WriteString("\nInputs\n======\n")
-	log.Infof("Fetching input tree from input root digest..")
+	c.infof("Fetching input tree from input root digest..")
 	inpTree, _, err := c.getInputTree(ctx, actionProto.GetInputRootDigest())
 	if err != nil {
 		showActionRes.WriteString("Failed to fetch input tree:\n")
@@ -528,7 +1128,7 @@ func (c *Client) ShowAction(ctx context.Context, actionDigest string) (string, e
 	if resPb == nil {
 		showActionRes.WriteString("\nNo action result in cache.\n")
 	} else {
-		log.Infof("Fetching output tree from action result..")
+		c.infof("Fetching output tree from action result..")
 		outs, err := c.getOutputs(ctx, resPb)
 		if err != nil {
 			return "", err
@@ -562,13 +1162,37 @@ func (c *Client) getOutputs(ctx context.Context, actionRes *repb.ActionResult) (
 		res.WriteString(fmt.Sprintf("stderr digest: %v\n", dg))
 	}
 
+	if timing := executionTimingText(actionRes.GetExecutionMetadata()); timing != "" {
+		res.WriteString("\nExecution Timing\n================\n")
+		res.WriteString(timing)
+	}
+
 	res.WriteString("\nOutput Files\n============\n")
 	for _, of := range actionRes.GetOutputFiles() {
 		dg, err := digest.NewFromProto(of.GetDigest())
 		if err != nil {
 			return "", err
 		}
-		res.WriteString(fmt.Sprintf("%v, digest: %v\n", of.GetPath(), dg))
+		if props := nodePropertiesString(of.GetNodeProperties()); props != "" {
+			res.WriteString(fmt.Sprintf("%v, digest: %v, %s\n", of.GetPath(), dg, props))
+		} else {
+			res.WriteString(fmt.Sprintf("%v, digest: %v\n", of.GetPath(), dg))
+		}
+	}
+
+	var symlinks []*repb.OutputSymlink
+	for _, syms := range [][]*repb.OutputSymlink{actionRes.GetOutputFileSymlinks(), actionRes.GetOutputDirectorySymlinks(), actionRes.GetOutputSymlinks()} {
+		symlinks = append(symlinks, syms...)
+	}
+	if len(symlinks) > 0 {
+		res.WriteString("\nOutput Symlinks\n===============\n")
+		for _, s := range symlinks {
+			if props := nodePropertiesString(s.GetNodeProperties()); props != "" {
+				res.WriteString(fmt.Sprintf("%v -> %v, %s\n", s.GetPath(), s.GetTarget(), props))
+			} else {
+				res.WriteString(fmt.Sprintf("%v -> %v\n", s.GetPath(), s.GetTarget()))
+			}
+		}
 	}
 
 	res.WriteString("\nOutput Files From Directories\n=============================\n")
@@ -642,12 +1266,16 @@ func (c *Client) flattenTree(ctx context.Context, t *repb.Tree) (string, []strin
 	sort.Strings(paths)
 	for _, path := range paths {
 		output := outputs[path]
+		props := nodePropertiesString(output.NodeProperties)
+		if props != "" {
+			props = ", " + props
+		}
 		if output.IsEmptyDirectory {
-			res.WriteString(fmt.Sprintf("%v: [Directory digest: %v]\n", path, output.Digest))
+			res.WriteString(fmt.Sprintf("%v: [Directory digest: %v%s]\n", path, output.Digest, props))
 		} else if output.SymlinkTarget != "" {
-			res.WriteString(fmt.Sprintf("%v: [Symlink digest: %v, Symlink Target: %v]\n", path, output.Digest, output.SymlinkTarget))
+			res.WriteString(fmt.Sprintf("%v: [Symlink Target: %v%s]\n", path, output.SymlinkTarget, props))
 		} else {
-			res.WriteString(fmt.Sprintf("%v: [File digest: %v]\n", path, output.Digest))
+			res.WriteString(fmt.Sprintf("%v: [File digest: %v%s]\n", path, output.Digest, props))
 		}
 	}
 	return res.String(), paths, nil
@@ -1,18 +1,23 @@
 package tool
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/outerr"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestTool_DownloadActionResult(t *testing.T) {
@@ -31,7 +36,7 @@ func TestTool_DownloadActionResult(t *testing.T) {
 
 	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
 	tmpDir := t.TempDir()
-	if err := toolClient.DownloadActionResult(context.Background(), acDg.String(), tmpDir); err != nil {
+	if err := toolClient.DownloadActionResult(context.Background(), acDg.String(), tmpDir, false); err != nil {
 		t.Fatalf("DownloadActionResult(%v,%v) failed: %v", acDg.String(), tmpDir, err)
 	}
 	verifyData := map[string]string{
@@ -51,6 +56,80 @@ func TestTool_DownloadActionResult(t *testing.T) {
 	}
 }
 
+func TestTool_DownloadActionResultArchiveInvalidFormat(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	path := filepath.Join(t.TempDir(), "out.rar")
+	if err := ioutil.WriteFile(path, []byte("previous"), 0644); err != nil {
+		t.Fatalf("WriteFile(%v) failed: %v", path, err)
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	if err := toolClient.DownloadActionResultArchive(context.Background(), digest.Empty.String(), path, "rar"); err == nil {
+		t.Errorf("DownloadActionResultArchive(rar) succeeded, want error")
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != "previous" {
+		t.Errorf("DownloadActionResultArchive(rar) modified %v: got %q, %v", path, got, err)
+	}
+}
+
+func TestTool_DownloadStdErrOut(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"tool"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{},
+		OutputFiles: []string{"a/b/out"},
+	}
+	opt := command.DefaultExecutionOptions()
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.CacheHitResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"},
+		fakes.StdOut("stdout"), fakes.StdErr("stderr"))
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	oe := outerr.NewRecordingOutErr()
+	if err := toolClient.DownloadStdErrOut(context.Background(), acDg.String(), "", oe); err != nil {
+		t.Fatalf("DownloadStdErrOut(%v) failed: %v", acDg, err)
+	}
+	if string(oe.Stdout()) != "stdout" {
+		t.Errorf("Incorrect stdout %v, expected \"stdout\"", oe.Stdout())
+	}
+	if string(oe.Stderr()) != "stderr" {
+		t.Errorf("Incorrect stderr %v, expected \"stderr\"", oe.Stderr())
+	}
+
+	// The action result may also be given by its own digest.
+	ar := e.Server.ActionCache.Get(acDg)
+	blob, err := proto.Marshal(ar)
+	if err != nil {
+		t.Fatalf("proto.Marshal(%v) failed: %v", ar, err)
+	}
+	arDg := e.Server.CAS.Put(blob)
+	tmpDir := filepath.Join(t.TempDir(), "stdio")
+	if err := toolClient.DownloadStdErrOut(context.Background(), arDg.String(), tmpDir, outerr.NewRecordingOutErr()); err != nil {
+		t.Fatalf("DownloadStdErrOut(%v) failed: %v", arDg, err)
+	}
+	verifyData := map[string]string{
+		filepath.Join(tmpDir, "stdout"): "stdout",
+		filepath.Join(tmpDir, "stderr"): "stderr",
+	}
+	for fp, want := range verifyData {
+		c, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatalf("Unable to read downloaded file %v: %v", fp, err)
+		}
+		if string(c) != want {
+			t.Errorf("Incorrect content in downloaded file %v, want %v, got %s", fp, want, c)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "a")); !os.IsNotExist(err) {
+		t.Errorf("DownloadStdErrOut(%v) downloaded output files", arDg)
+	}
+
+	if err := toolClient.DownloadStdErrOut(context.Background(), digest.NewFromBlob([]byte("unknown")).String(), "", outerr.NewRecordingOutErr()); err == nil {
+		t.Errorf("DownloadStdErrOut(unknown) succeeded, want an error")
+	}
+}
+
 func TestTool_ShowAction(t *testing.T) {
 	e, cleanup := fakes.NewTestEnv(t)
 	defer cleanup()
@@ -94,6 +173,15 @@ Exit code: 0
 stdout digest: 63d42d26156fcc761e57da4128e9881d5bdf3bf933f0f6e9c93d6e26b9b90ae7/6
 stderr digest: 7e6b710b765404cccbad9eedcff7615fc37b269d6db12cd81a58be541d93083c/6
 
+Execution Timing
+================
+Queue:         2006-01-02T15:04:05.001Z -> 2006-01-02T15:04:05.002Z  1ms (50.0%)
+Worker setup:  2006-01-02T15:04:05.002Z -> 2006-01-02T15:04:05.004Z  2ms (100.0%)
+Input fetch:   2006-01-02T15:04:05.004Z -> 2006-01-02T15:04:05.005Z  1ms (50.0%)
+Execution:     2006-01-02T15:04:05.006Z -> 2006-01-02T15:04:05.007Z  1ms (50.0%)
+Output upload: 2006-01-02T15:04:05.008Z -> 2006-01-02T15:04:05.009Z  1ms (50.0%)
+Total:         2006-01-02T15:04:05.001Z -> 2006-01-02T15:04:05.003Z  2ms
+
 Output Files
 ============
 a/b/out, digest: e0ee8bb50685e05fa0f47ed04203ae953fdfd055f5bd2892ea186504254f8c3a/6
@@ -126,7 +214,7 @@ func TestTool_CheckDeterminism(t *testing.T) {
 	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: out})
 
 	client := &Client{GrpcClient: e.Client.GrpcClient}
-	if err := client.CheckDeterminism(context.Background(), acDg.String(), "", 2); err != nil {
+	if err := client.CheckDeterminism(context.Background(), acDg.String(), "", 2, 1, "", false, outerr.SystemOutErr); err != nil {
 		t.Errorf("CheckDeterminism returned an error: %v", err)
 	}
 	// Now execute again with changed inputs.
@@ -135,11 +223,44 @@ func TestTool_CheckDeterminism(t *testing.T) {
 		e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: out})
 	}
 	defer func() { testOnlyStartDeterminismExec = func() {} }()
-	if err := client.CheckDeterminism(context.Background(), acDg.String(), "", 2); err == nil {
+	if err := client.CheckDeterminism(context.Background(), acDg.String(), "", 2, 1, "", false, outerr.SystemOutErr); err == nil {
 		t.Errorf("CheckDeterminism returned nil, want error")
 	}
 }
 
+func TestTool_CheckDeterminismParallel(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo bar baz"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1", "i2"}},
+		OutputFiles: []string{"a/b/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i2"), []byte("i2"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: true, DownloadOutErr: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: "output"},
+		fakes.StdOut("out;"), fakes.StdErr("err;"))
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	oe := outerr.NewRecordingOutErr()
+	if err := client.CheckDeterminism(context.Background(), acDg.String(), "", 5, 3, "", false, oe); err != nil {
+		t.Errorf("CheckDeterminism returned an error: %v", err)
+	}
+	// The outputs of every execution are kept whole.
+	if got, want := string(oe.Stdout()), strings.Repeat("out;", 5); got != want {
+		t.Errorf("CheckDeterminism wrote stdout %q, want %q", got, want)
+	}
+	if got, want := string(oe.Stderr()), strings.Repeat("err;", 5); got != want {
+		t.Errorf("CheckDeterminism wrote stderr %q, want %q", got, want)
+	}
+}
+
 func TestTool_ExecuteAction(t *testing.T) {
 	e, cleanup := fakes.NewTestEnv(t)
 	defer cleanup()
@@ -203,6 +324,173 @@ func TestTool_ExecuteAction(t *testing.T) {
 	}
 }
 
+func TestTool_WaitOperation(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo bar baz"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"a/b/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	out := "output"
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: false}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, &fakes.OutputFile{Path: "a/b/out", Contents: out},
+		fakes.StdOut("stdout"), fakes.StdErr("stderr"))
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	// Start the execution, as if from another process.
+	if _, err := client.ExecuteAction(context.Background(), acDg.String(), "", "", outerr.NewRecordingOutErr()); err != nil {
+		t.Fatalf("error executeAction: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	oe := outerr.NewRecordingOutErr()
+	if err := client.WaitOperation(context.Background(), "fake", tmpDir, oe); err != nil {
+		t.Fatalf("WaitOperation(fake) failed: %v", err)
+	}
+	if string(oe.Stdout()) != "stdout" {
+		t.Errorf("Incorrect stdout %v, expected \"stdout\"", oe.Stdout())
+	}
+	if string(oe.Stderr()) != "stderr" {
+		t.Errorf("Incorrect stderr %v, expected \"stderr\"", oe.Stderr())
+	}
+	fp := filepath.Join(tmpDir, "a/b/out")
+	c, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("Unable to read downloaded output %v: %v", fp, err)
+	}
+	if string(c) != out {
+		t.Errorf("Incorrect content in downloaded file %v, want %s, got %s", fp, out, c)
+	}
+
+	if err := client.WaitOperation(context.Background(), "unknown", "", outerr.NewRecordingOutErr()); err == nil {
+		t.Errorf("WaitOperation(unknown) succeeded, want an error")
+	}
+}
+
+func TestTool_ReexecuteActionPlatformOverrides(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:        []string{"foo"},
+		ExecRoot:    e.ExecRoot,
+		InputSpec:   &command.InputSpec{Inputs: []string{"i1"}},
+		OutputFiles: []string{"a/b/out"},
+		Platform:    map[string]string{"container-image": "old", "pool": "default", "dropped": "x"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, fakes.StdOut("old"))
+	// The fake only executes the action with the overridden platform.
+	cmd.Platform = map[string]string{"container-image": "new", "pool": "default", "extra": "y"}
+	_, wantDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus}, fakes.StdOut("new"))
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	oe := outerr.NewRecordingOutErr()
+	overrides := &ExecuteOverrides{Platform: map[string]string{"container-image": "new", "extra": "y", "dropped": ""}}
+	md, err := client.ReexecuteAction(context.Background(), acDg.String(), "", "", overrides, oe)
+	if err != nil {
+		t.Fatalf("ReexecuteAction(%v) failed: %v", acDg, err)
+	}
+	if md.ActionDigest != wantDg {
+		t.Errorf("ReexecuteAction(%v) executed action %v, want %v", acDg, md.ActionDigest, wantDg)
+	}
+	if string(oe.Stdout()) != "new" {
+		t.Errorf("Incorrect stdout %v, expected \"new\"", oe.Stdout())
+	}
+}
+
+func TestTool_ReexecuteActionArgsOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides *ExecuteOverrides
+		wantArgs  []string
+	}{
+		{
+			name:      "replace",
+			overrides: &ExecuteOverrides{Args: []string{"cc", "-c", "b.c"}},
+			wantArgs:  []string{"cc", "-c", "b.c"},
+		},
+		{
+			name:      "append",
+			overrides: &ExecuteOverrides{ExtraArgs: []string{"-v"}},
+			wantArgs:  []string{"cc", "-c", "a.c", "-v"},
+		},
+		{
+			name:      "replace and append",
+			overrides: &ExecuteOverrides{Args: []string{"cc", "-c", "b.c"}, ExtraArgs: []string{"-v"}},
+			wantArgs:  []string{"cc", "-c", "b.c", "-v"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			cmd := &command.Command{
+				Args:        []string{"cc", "-c", "a.c"},
+				ExecRoot:    e.ExecRoot,
+				InputSpec:   &command.InputSpec{Inputs: []string{"a.c"}},
+				OutputFiles: []string{"a.o"},
+			}
+			if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "a.c"), []byte("int a;"), 0644); err != nil {
+				t.Fatalf("failed creating input file: %v", err)
+			}
+			opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: true}
+			_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+			// The fake only executes the action with the overridden arguments.
+			cmd.Args = tc.wantArgs
+			_, wantDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+
+			client := &Client{GrpcClient: e.Client.GrpcClient}
+			md, err := client.ReexecuteAction(context.Background(), acDg.String(), "", "", tc.overrides, outerr.NewRecordingOutErr())
+			if err != nil {
+				t.Fatalf("ReexecuteAction(%v) failed: %v", acDg, err)
+			}
+			if md.ActionDigest != wantDg {
+				t.Errorf("ReexecuteAction(%v) executed action %v, want %v", acDg, md.ActionDigest, wantDg)
+			}
+		})
+	}
+}
+
+func TestTool_ReexecuteActionEnvOverrides(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cmd := &command.Command{
+		Args:     []string{"foo"},
+		ExecRoot: e.ExecRoot,
+		InputSpec: &command.InputSpec{
+			Inputs:               []string{"i1"},
+			EnvironmentVariables: map[string]string{"PATH": "/bin", "DEBUG": "0", "TMPDIR": "/tmp"},
+		},
+		OutputFiles: []string{"a/b/out"},
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.ExecRoot, "i1"), []byte("i1"), 0644); err != nil {
+		t.Fatalf("failed creating input file: %v", err)
+	}
+	opt := &command.ExecutionOptions{AcceptCached: false, DownloadOutputs: false, DownloadOutErr: true}
+	_, acDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+	// The fake only executes the action with the overridden environment.
+	cmd.InputSpec.EnvironmentVariables = map[string]string{"PATH": "/bin", "DEBUG": "1", "VERBOSE": "1"}
+	_, wantDg := e.Set(cmd, opt, &command.Result{Status: command.SuccessResultStatus})
+
+	client := &Client{GrpcClient: e.Client.GrpcClient}
+	overrides := &ExecuteOverrides{Env: map[string]string{"DEBUG": "1", "VERBOSE": "1"}, UnsetEnv: []string{"TMPDIR"}}
+	md, err := client.ReexecuteAction(context.Background(), acDg.String(), "", "", overrides, outerr.NewRecordingOutErr())
+	if err != nil {
+		t.Fatalf("ReexecuteAction(%v) failed: %v", acDg, err)
+	}
+	if md.ActionDigest != wantDg {
+		t.Errorf("ReexecuteAction(%v) executed action %v, want %v", acDg, md.ActionDigest, wantDg)
+	}
+}
+
 func TestTool_ExecuteActionFromRoot(t *testing.T) {
 	e, cleanup := fakes.NewTestEnv(t)
 	defer cleanup()
@@ -293,6 +581,28 @@ func TestTool_DownloadBlob(t *testing.T) {
 	}
 }
 
+func TestTool_StreamBlob(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	cas := e.Server.CAS
+	blob := bytes.Repeat([]byte("0123456789"), 1024*1024)
+	dg := cas.Put(blob)
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	var got bytes.Buffer
+	if err := toolClient.StreamBlob(context.Background(), dg.String(), &got); err != nil {
+		t.Fatalf("StreamBlob(%v) failed: %v", dg, err)
+	}
+	if !bytes.Equal(got.Bytes(), blob) {
+		t.Errorf("StreamBlob(%v) wrote %d bytes, want the %d bytes of the blob", dg, got.Len(), len(blob))
+	}
+
+	missing := digest.NewFromBlob([]byte("missing"))
+	if err := toolClient.StreamBlob(context.Background(), missing.String(), &got); status.Code(err) != codes.NotFound {
+		t.Errorf("StreamBlob(%v) = %v, want NotFound", missing, err)
+	}
+}
+
 func TestTool_UploadBlob(t *testing.T) {
 	e, cleanup := fakes.NewTestEnv(t)
 	defer cleanup()
@@ -326,3 +636,75 @@ func TestTool_UploadBlob(t *testing.T) {
 		t.Fatalf("Expected 1 write for blob '%v', got %v", dg.String(), cas.BlobWrites(dg))
 	}
 }
+
+func TestTool_UploadBlobFromReader(t *testing.T) {
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{name: "small", blob: []byte("Hello, World!")},
+		{name: "spooled", blob: bytes.Repeat([]byte("a"), maxInMemoryBlobSize+10)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, cleanup := fakes.NewTestEnv(t)
+			defer cleanup()
+			cas := e.Server.CAS
+
+			toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+			dg, err := toolClient.UploadBlobFromReader(context.Background(), bytes.NewReader(tc.blob))
+			if err != nil {
+				t.Fatalf("UploadBlobFromReader() failed: %v", err)
+			}
+			if want := digest.NewFromBlob(tc.blob); dg != want {
+				t.Errorf("UploadBlobFromReader() = %v, want %v", dg, want)
+			}
+			if cas.BlobWrites(dg) != 1 {
+				t.Errorf("Expected 1 write for blob '%v', got %v", dg.String(), cas.BlobWrites(dg))
+			}
+		})
+	}
+}
+
+func TestTool_UploadDirectory(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a":     "a",
+		"b/c":   "c",
+		"b/d/e": "e",
+	}
+	for p, contents := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(fp), err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(contents), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", fp, err)
+		}
+	}
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+
+	// The uploaded directory should be downloadable by its root digest.
+	outDir := t.TempDir()
+	if err := toolClient.DownloadDirectory(context.Background(), root.String(), outDir, nil, nil, false); err != nil {
+		t.Fatalf("DownloadDirectory(%v) failed: %v", root, err)
+	}
+	for p, want := range files {
+		fp := filepath.Join(outDir, p)
+		got, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatalf("Unable to read downloaded file %v: %v", fp, err)
+		}
+		if string(got) != want {
+			t.Errorf("Incorrect content in downloaded file %v, want %s, got %s", fp, want, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package tool
+
+import (
+	"fmt"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+// TransferOptions tune the concurrency and the batching of the CAS transfers of a Client, e.g. so
+// that large directory transfers saturate fast links, or to throttle them on constrained machines.
+// Zero values keep the settings of the underlying client.
+type TransferOptions struct {
+	// UploadConcurrency is the maximum number of files or batches of small files uploaded
+	// concurrently.
+	UploadConcurrency int
+	// DownloadConcurrency is the maximum number of files or batches of small files downloaded
+	// concurrently.
+	DownloadConcurrency int
+	// MaxBatchDigests is the maximum number of blobs in a batch request.
+	MaxBatchDigests int
+	// MaxBatchSize is the maximum total size in bytes of the blobs of a batch request. It takes
+	// precedence over the limit advertised by the server, which must not be exceeded.
+	MaxBatchSize int64
+	// ChunkSize is the size in bytes of the chunks in which large files are streamed.
+	ChunkSize int
+}
+
+// ApplyTransferOptions applies the options to the underlying client of c. It returns an error if
+// any of them is negative.
+func (c *Client) ApplyTransferOptions(o *TransferOptions) error {
+	for name, v := range map[string]int64{
+		"upload concurrency":   int64(o.UploadConcurrency),
+		"download concurrency": int64(o.DownloadConcurrency),
+		"max batch digests":    int64(o.MaxBatchDigests),
+		"max batch size":       o.MaxBatchSize,
+		"chunk size":           int64(o.ChunkSize),
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must be >= 0, got %d", name, v)
+		}
+	}
+	var opts []rc.Opt
+	if o.UploadConcurrency > 0 {
+		opts = append(opts, rc.CASUploadConcurrency(o.UploadConcurrency))
+	}
+	if o.DownloadConcurrency > 0 {
+		opts = append(opts, rc.CASDownloadConcurrency(o.DownloadConcurrency))
+	}
+	if o.MaxBatchDigests > 0 {
+		opts = append(opts, rc.MaxBatchDigests(o.MaxBatchDigests))
+	}
+	if o.MaxBatchSize > 0 {
+		opts = append(opts, rc.MaxBatchSize(o.MaxBatchSize))
+	}
+	if o.ChunkSize > 0 {
+		opts = append(opts, rc.ChunkMaxSize(o.ChunkSize))
+	}
+	for _, opt := range opts {
+		opt.Apply(c.GrpcClient)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package tool
+
+import (
+	"testing"
+
+	rc "github.com/bazelbuild/remote-apis-sdks/go/pkg/client"
+)
+
+func TestTool_ApplyTransferOptions(t *testing.T) {
+	grpcClient := &rc.Client{MaxBatchDigests: rc.DefaultMaxBatchDigests, MaxBatchSize: rc.DefaultMaxBatchSize, ChunkMaxSize: 1024}
+	toolClient := &Client{GrpcClient: grpcClient}
+	if err := toolClient.ApplyTransferOptions(&TransferOptions{MaxBatchDigests: 10, ChunkSize: 2048, UploadConcurrency: 4}); err != nil {
+		t.Fatalf("ApplyTransferOptions() failed: %v", err)
+	}
+	if grpcClient.MaxBatchDigests != 10 || grpcClient.ChunkMaxSize != 2048 {
+		t.Errorf("ApplyTransferOptions() set MaxBatchDigests = %v, ChunkMaxSize = %v, want 10, 2048", grpcClient.MaxBatchDigests, grpcClient.ChunkMaxSize)
+	}
+	// Unset options keep the settings of the client.
+	if grpcClient.MaxBatchSize != rc.DefaultMaxBatchSize {
+		t.Errorf("ApplyTransferOptions() set MaxBatchSize = %v, want %v", grpcClient.MaxBatchSize, rc.DefaultMaxBatchSize)
+	}
+
+	if err := toolClient.ApplyTransferOptions(&TransferOptions{DownloadConcurrency: -1}); err == nil {
+		t.Errorf("ApplyTransferOptions() with a negative download concurrency succeeded, want error")
+	}
+}
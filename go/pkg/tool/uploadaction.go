@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ActionSpec describes an action to construct from scratch.
+type ActionSpec struct {
+	// Args are the arguments of the command, the first one being the program to run.
+	Args []string
+	// Env are the environment variables of the command.
+	Env map[string]string
+	// Platform are the platform properties of the command.
+	Platform map[string]string
+	// InputRootDigest is the digest of the input root, in <digest/size_bytes> format. The input
+	// root is empty if unset.
+	InputRootDigest string
+	// OutputPaths are the paths of the outputs, relative to the working directory. Paths ending
+	// with a slash are output directories, the others output files.
+	OutputPaths []string
+}
+
+// UploadAction builds the Command and the Action described by spec, e.g. to construct synthetic
+// actions for testing a backend, uploads them to the CAS and returns the digest of the Action.
+// The inputs are not uploaded, the input root must already be in the CAS to execute the action.
+func (c *Client) UploadAction(ctx context.Context, spec *ActionSpec) (digest.Digest, error) {
+	root := digest.Empty
+	if spec.InputRootDigest != "" {
+		var err error
+		if root, err = digest.NewFromString(spec.InputRootDigest); err != nil {
+			return digest.Empty, err
+		}
+	}
+	cmd := &command.Command{
+		Args:      spec.Args,
+		InputSpec: &command.InputSpec{EnvironmentVariables: spec.Env},
+		Platform:  spec.Platform,
+	}
+	for _, p := range spec.OutputPaths {
+		if strings.HasSuffix(p, "/") {
+			cmd.OutputDirs = append(cmd.OutputDirs, strings.TrimSuffix(p, "/"))
+		} else {
+			cmd.OutputFiles = append(cmd.OutputFiles, p)
+		}
+	}
+	cmdPb := cmd.ToREProto(c.GrpcClient.SupportsCommandOutputPaths())
+	cmdUe, err := uploadinfo.EntryFromProto(cmdPb)
+	if err != nil {
+		return digest.Empty, err
+	}
+	acPb := &repb.Action{
+		CommandDigest:   cmdUe.Digest.ToProto(),
+		InputRootDigest: root.ToProto(),
+	}
+	if c.GrpcClient.SupportsActionPlatformProperties() {
+		acPb.Platform = cmdPb.Platform
+	}
+	acUe, err := uploadinfo.EntryFromProto(acPb)
+	if err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Uploading Command %v and Action %v.", cmdUe.Digest, acUe.Digest)
+	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, cmdUe, acUe); err != nil {
+		return digest.Empty, err
+	}
+	return acUe.Digest, nil
+}
@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_UploadAction(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	rootDg := digest.NewFromBlob([]byte("root"))
+	spec := &ActionSpec{
+		Args:            []string{"gcc", "-c", "a.c"},
+		Env:             map[string]string{"PATH": "/usr/bin", "LANG": "C"},
+		Platform:        map[string]string{"OSFamily": "Linux"},
+		InputRootDigest: rootDg.String(),
+		OutputPaths:     []string{"a.o", "logs/"},
+	}
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	acDg, err := toolClient.UploadAction(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("UploadAction(%+v) failed: %v", spec, err)
+	}
+	_, gotAc, gotCmd, err := toolClient.readAction(context.Background(), acDg.String())
+	if err != nil {
+		t.Fatalf("readAction(%v) failed: %v", acDg, err)
+	}
+	wantCmd := &repb.Command{
+		Arguments: []string{"gcc", "-c", "a.c"},
+		EnvironmentVariables: []*repb.Command_EnvironmentVariable{
+			{Name: "LANG", Value: "C"},
+			{Name: "PATH", Value: "/usr/bin"},
+		},
+		Platform:          &repb.Platform{Properties: []*repb.Platform_Property{{Name: "OSFamily", Value: "Linux"}}},
+		OutputFiles:       []string{"a.o"},
+		OutputDirectories: []string{"logs"},
+	}
+	if !proto.Equal(gotCmd, wantCmd) {
+		t.Errorf("UploadAction(%+v) uploaded Command %v, want %v", spec, gotCmd, wantCmd)
+	}
+	cmdBlob, err := proto.Marshal(wantCmd)
+	if err != nil {
+		t.Fatalf("proto.Marshal(%v) failed: %v", wantCmd, err)
+	}
+	wantAc := &repb.Action{
+		CommandDigest:   digest.NewFromBlob(cmdBlob).ToProto(),
+		InputRootDigest: rootDg.ToProto(),
+	}
+	if !proto.Equal(gotAc, wantAc) {
+		t.Errorf("UploadAction(%+v) uploaded Action %v, want %v", spec, gotAc, wantAc)
+	}
+
+	emptySpec := &ActionSpec{Args: []string{"true"}}
+	acDg, err = toolClient.UploadAction(context.Background(), emptySpec)
+	if err != nil {
+		t.Fatalf("UploadAction(%+v) failed: %v", emptySpec, err)
+	}
+	if _, gotAc, _, err = toolClient.readAction(context.Background(), acDg.String()); err != nil {
+		t.Fatalf("readAction(%v) failed: %v", acDg, err)
+	}
+	if got := digest.NewFromProtoUnvalidated(gotAc.InputRootDigest); got != digest.Empty {
+		t.Errorf("UploadAction(%+v) uploaded an Action with input root %v, want the empty root %v", emptySpec, got, digest.Empty)
+	}
+}
@@ -0,0 +1,91 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/command"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/filemetadata"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// ActionResultSpec describes an ActionResult to construct from local files.
+type ActionResultSpec struct {
+	// OutputRoot is the directory the output paths are relative to.
+	OutputRoot string
+	// OutputPaths are the paths of the output files and directories.
+	OutputPaths []string
+	// StdoutFile and StderrFile are the paths of the files with the stdout and stderr of the
+	// action, if any.
+	StdoutFile string
+	StderrFile string
+	ExitCode   int32
+}
+
+// UploadActionResult constructs the ActionResult described by spec, uploads the outputs and the
+// stdout and stderr missing from the CAS, and writes the result to the action cache for the action
+// with the given digest, e.g. to seed a cache or to test its eviction behavior. The action itself
+// does not need to be in the CAS. It returns the digest of the ActionResult.
+func (c *Client) UploadActionResult(ctx context.Context, actionDigest string, spec *ActionResultSpec) (digest.Digest, error) {
+	acDg, err := digest.NewFromString(actionDigest)
+	if err != nil {
+		return digest.Empty, err
+	}
+	return c.uploadActionResult(ctx, acDg, spec)
+}
+
+// uploadActionResult is UploadActionResult for the action with digest acDg, also uploading the
+// extra blobs, if missing.
+func (c *Client) uploadActionResult(ctx context.Context, acDg digest.Digest, spec *ActionResultSpec, extra ...*uploadinfo.Entry) (digest.Digest, error) {
+	for _, p := range spec.OutputPaths {
+		// Missing outputs would be silently left out of the result.
+		if _, err := os.Lstat(filepath.Join(spec.OutputRoot, p)); err != nil {
+			return digest.Empty, fmt.Errorf("output %v: %v", p, err)
+		}
+	}
+	blobs, resPb, err := c.GrpcClient.ComputeOutputsToUpload(spec.OutputRoot, "", spec.OutputPaths, filemetadata.NewNoopCache(), command.UnspecifiedSymlinkBehavior)
+	if err != nil {
+		return digest.Empty, err
+	}
+	resPb.ExitCode = spec.ExitCode
+	entries := extra
+	for _, ue := range blobs {
+		entries = append(entries, ue)
+	}
+	stdDigest := func(path string) (*repb.Digest, error) {
+		if path == "" {
+			return nil, nil
+		}
+		dg, err := digest.NewFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, uploadinfo.EntryFromFile(dg, path))
+		return dg.ToProto(), nil
+	}
+	if resPb.StdoutDigest, err = stdDigest(spec.StdoutFile); err != nil {
+		return digest.Empty, err
+	}
+	if resPb.StderrDigest, err = stdDigest(spec.StderrFile); err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Uploading %d blobs of the action result.", len(entries))
+	if _, _, err := c.GrpcClient.UploadIfMissing(ctx, entries...); err != nil {
+		return digest.Empty, err
+	}
+	c.infof("Updating the action cache for action %v.", acDg)
+	req := &repb.UpdateActionResultRequest{
+		InstanceName: c.GrpcClient.InstanceName,
+		ActionDigest: acDg.ToProto(),
+		ActionResult: resPb,
+	}
+	if _, err := c.GrpcClient.UpdateActionResult(ctx, req); err != nil {
+		return digest.Empty, err
+	}
+	return digest.NewFromMessage(resPb)
+}
@@ -0,0 +1,88 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+	"github.com/golang/protobuf/proto"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+func TestTool_UploadActionResult(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+	dir := t.TempDir()
+	files := map[string]string{
+		"out":       "out",
+		"dir/a":     "a",
+		"stdout":    "stdout",
+		"dir/sub/b": "b",
+	}
+	for p, contents := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("MkdirAll(%v) failed: %v", filepath.Dir(fp), err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(contents), 0644); err != nil {
+			t.Fatalf("WriteFile(%v) failed: %v", fp, err)
+		}
+	}
+	acDg := digest.NewFromBlob([]byte("action"))
+	spec := &ActionResultSpec{
+		OutputRoot:  dir,
+		OutputPaths: []string{"out", "dir"},
+		StdoutFile:  filepath.Join(dir, "stdout"),
+		ExitCode:    1,
+	}
+
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	resDg, err := toolClient.UploadActionResult(context.Background(), acDg.String(), spec)
+	if err != nil {
+		t.Fatalf("UploadActionResult(%v) failed: %v", acDg, err)
+	}
+	got := e.Server.ActionCache.Get(acDg)
+	if got == nil {
+		t.Fatalf("UploadActionResult(%v) wrote no action result", acDg)
+	}
+	if gotDg, err := digest.NewFromMessage(got); err != nil || gotDg != resDg {
+		t.Errorf("UploadActionResult(%v) = %v, want the digest of the cached result %v", acDg, resDg, gotDg)
+	}
+	want := &repb.ActionResult{
+		ExitCode:     1,
+		StdoutDigest: digest.NewFromBlob([]byte("stdout")).ToProto(),
+		OutputFiles:  []*repb.OutputFile{{Path: "out", Digest: digest.NewFromBlob([]byte("out")).ToProto()}},
+	}
+	gotDirs := got.OutputDirectories
+	got.OutputDirectories = nil
+	if !proto.Equal(got, want) {
+		t.Errorf("UploadActionResult(%v) cached %v, want %v", acDg, got, want)
+	}
+	if len(gotDirs) != 1 || gotDirs[0].Path != "dir" {
+		t.Fatalf("UploadActionResult(%v) cached output directories %v, want dir", acDg, gotDirs)
+	}
+	outs, err := e.Client.GrpcClient.FlattenActionOutputs(context.Background(), &repb.ActionResult{OutputDirectories: gotDirs})
+	if err != nil {
+		t.Fatalf("FlattenActionOutputs() failed: %v", err)
+	}
+	for _, p := range []string{"dir/a", "dir/sub/b"} {
+		o, ok := outs[p]
+		if !ok {
+			t.Errorf("output directory is missing %v", p)
+			continue
+		}
+		if blob, ok := e.Server.CAS.Get(o.Digest); !ok || string(blob) != files[p] {
+			t.Errorf("CAS has %q for %v, want %q", blob, p, files[p])
+		}
+	}
+
+	spec.OutputPaths = []string{"missing"}
+	if _, err := toolClient.UploadActionResult(context.Background(), acDg.String(), spec); err == nil {
+		t.Errorf("UploadActionResult(%v) with a missing output succeeded, want error", acDg)
+	}
+}
@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/uploadinfo"
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+)
+
+// VerifyDirectory compares the local directory at path with the tree with the given root
+// directory digest, e.g. to find out whether a download is incomplete or corrupted. If their root
+// digests differ, the tree is read from the remote cache and the files missing from the directory,
+// the extra files in it and the files with mismatching contents are reported. It returns whether
+// the directory matches the tree, with the report.
+func (c *Client) VerifyDirectory(ctx context.Context, rootDigest, path string) (bool, string, error) {
+	rootDg, err := digest.NewFromString(rootDigest)
+	if err != nil {
+		return false, "", err
+	}
+	localDg, entries, _, err := c.localTree(path, nil)
+	if err != nil {
+		return false, "", err
+	}
+	if localDg == rootDg {
+		return true, fmt.Sprintf("Directory %v matches tree %v.\n", path, rootDg), nil
+	}
+
+	c.infof("Fetching tree %v..", rootDg)
+	remote, err := c.flatInputs(ctx, rootDg.ToProto())
+	if err != nil {
+		return false, "", err
+	}
+	local, err := c.flatLocalTree(localDg, entries)
+	if err != nil {
+		return false, "", err
+	}
+	paths := make([]string, 0, len(remote)+len(local))
+	for p := range remote {
+		paths = append(paths, p)
+	}
+	for p := range local {
+		if _, ok := remote[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var res bytes.Buffer
+	res.WriteString(fmt.Sprintf("Directory %v does not match tree %v, its root digest is %v:\n", path, rootDg, localDg))
+	for _, p := range paths {
+		want, inRemote := remote[p]
+		got, inLocal := local[p]
+		switch {
+		case !inLocal:
+			res.WriteString(fmt.Sprintf("\tmissing %s: %s\n", p, want))
+		case !inRemote:
+			res.WriteString(fmt.Sprintf("\textra %s: %s\n", p, got))
+		case want != got:
+			res.WriteString(fmt.Sprintf("\tmismatched %s: %s, want %s\n", p, got, want))
+		}
+	}
+	return false, res.String(), nil
+}
+
+// flatLocalTree returns a description of each input of the tree with the given root computed from
+// a local directory, by path, in the format of flatInputs.
+func (c *Client) flatLocalTree(root digest.Digest, entries []*uploadinfo.Entry) (map[string]string, error) {
+	dirs := make(map[digest.Digest]*repb.Directory)
+	for _, ue := range entries {
+		if !ue.IsBlob() {
+			continue
+		}
+		// Only the Directory blobs are reachable from the root.
+		dir := &repb.Directory{}
+		if err := proto.Unmarshal(ue.Contents, dir); err == nil {
+			dirs[ue.Digest] = dir
+		}
+	}
+	rootDir, ok := dirs[root]
+	if !ok {
+		// An empty directory has no blobs.
+		rootDir = &repb.Directory{}
+	}
+	children := make([]*repb.Directory, 0, len(dirs))
+	for _, dir := range dirs {
+		children = append(children, dir)
+	}
+	outputs, err := c.GrpcClient.FlattenTree(&repb.Tree{Root: rootDir, Children: children}, "")
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(outputs))
+	for path, o := range outputs {
+		if path == "" {
+			path = "."
+		}
+		res[path] = describeTreeOutput(o)
+	}
+	return res, nil
+}
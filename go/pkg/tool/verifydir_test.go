@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
+	"github.com/bazelbuild/remote-apis-sdks/go/pkg/fakes"
+)
+
+func TestTool_VerifyDirectory(t *testing.T) {
+	e, cleanup := fakes.NewTestEnv(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a":     "a",
+		"b/c":   "c",
+		"b/d/e": "e",
+	}
+	for p, contents := range files {
+		path := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating directory for %v: %v", p, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed writing %v: %v", p, err)
+		}
+	}
+	toolClient := &Client{GrpcClient: e.Client.GrpcClient}
+	root, err := toolClient.UploadDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("UploadDirectory(%v) failed: %v", dir, err)
+	}
+
+	ok, report, err := toolClient.VerifyDirectory(context.Background(), root.String(), dir)
+	if err != nil {
+		t.Fatalf("VerifyDirectory(%v, %v) failed: %v", root, dir, err)
+	}
+	if !ok {
+		t.Errorf("VerifyDirectory(%v, %v) = false, want true:\n%s", root, dir, report)
+	}
+
+	// Corrupt the directory.
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("failed writing a: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "b/d/e")); err != nil {
+		t.Fatalf("failed removing b/d/e: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b/extra"), []byte("extra"), 0644); err != nil {
+		t.Fatalf("failed writing b/extra: %v", err)
+	}
+	ok, report, err = toolClient.VerifyDirectory(context.Background(), root.String(), dir)
+	if err != nil {
+		t.Fatalf("VerifyDirectory(%v, %v) failed: %v", root, dir, err)
+	}
+	if ok {
+		t.Errorf("VerifyDirectory(%v, %v) = true after corrupting the directory, want false", root, dir)
+	}
+	for _, want := range []string{
+		"\tmismatched a: [File digest: " + digest.NewFromBlob([]byte("corrupted")).String() + "], want [File digest: " + digest.NewFromBlob([]byte("a")).String() + "]\n",
+		"\tmissing b/d/e: [File digest: " + digest.NewFromBlob([]byte("e")).String() + "]\n",
+		"\textra b/extra: [File digest: " + digest.NewFromBlob([]byte("extra")).String() + "]\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("VerifyDirectory(%v, %v) = %q, want it to contain %q", root, dir, report, want)
+		}
+	}
+	if strings.Contains(report, "b/c") {
+		t.Errorf("VerifyDirectory(%v, %v) = %q, want it to not report the matching file b/c", root, dir, report)
+	}
+}
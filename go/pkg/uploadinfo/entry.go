@@ -3,6 +3,8 @@
 package uploadinfo
 
 import (
+	"io"
+
 	"github.com/bazelbuild/remote-apis-sdks/go/pkg/digest"
 	"github.com/golang/protobuf/proto"
 )
@@ -10,15 +12,25 @@ import (
 const (
 	ueBlob = iota
 	uePath
+	ueFileRange
+	ueReaderAt
 )
 
 // Entry should remain immutable upon creation.
-// Should be created using constructor. Only Contents or Path must be set.
+// Should be created using constructor. Only one of Contents, Path or ReaderAt must be set.
 // In case of a malformed entry, Contents takes precedence over Path.
 type Entry struct {
 	Digest   digest.Digest
 	Contents []byte
 	Path     string
+	// ReaderAt supplies the contents of entries created with EntryFromReaderAt.
+	ReaderAt io.ReaderAt
+	// Offset is where the contents start in the file at Path or in ReaderAt, for range entries.
+	// The length of the contents is Digest.Size.
+	Offset int64
+	// Compression, if set, overrides whether the contents are compressed when written with
+	// ByteStream, regardless of the settings of the client.
+	Compression *bool
 
 	ueType int
 }
@@ -28,9 +40,20 @@ func (ue *Entry) IsBlob() bool {
 	return ue.ueType == ueBlob
 }
 
-// IsFile returns whether this Entry is for a file in disk.
+// IsFile returns whether this Entry is for a file in disk, or a range of one.
 func (ue *Entry) IsFile() bool {
-	return ue.ueType == uePath
+	return ue.ueType == uePath || ue.ueType == ueFileRange
+}
+
+// IsReaderAt returns whether this Entry is for a range of an io.ReaderAt.
+func (ue *Entry) IsReaderAt() bool {
+	return ue.ueType == ueReaderAt
+}
+
+// IsRange returns whether the contents of this Entry are the Digest.Size bytes starting at Offset
+// of its file or io.ReaderAt, rather than the whole file.
+func (ue *Entry) IsRange() bool {
+	return ue.ueType == ueFileRange || ue.ueType == ueReaderAt
 }
 
 // EntryFromBlob creates an Entry from an in memory blob.
@@ -59,3 +82,26 @@ func EntryFromFile(dg digest.Digest, path string) *Entry {
 		ueType: uePath,
 	}
 }
+
+// EntryFromFileRange creates an entry from the dg.Size bytes starting at offset of a file in disk,
+// e.g. a member of an uncompressed archive.
+func EntryFromFileRange(dg digest.Digest, path string, offset int64) *Entry {
+	return &Entry{
+		Digest: dg,
+		Path:   path,
+		Offset: offset,
+		ueType: ueFileRange,
+	}
+}
+
+// EntryFromReaderAt creates an entry from the dg.Size bytes starting at offset of an io.ReaderAt.
+// The io.ReaderAt must remain readable until the upload is done, and may be read several times,
+// e.g. on retries.
+func EntryFromReaderAt(dg digest.Digest, ra io.ReaderAt, offset int64) *Entry {
+	return &Entry{
+		Digest:   dg,
+		ReaderAt: ra,
+		Offset:   offset,
+		ueType:   ueReaderAt,
+	}
+}
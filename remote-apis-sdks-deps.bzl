@@ -109,6 +109,12 @@ def remote_apis_sdks_go_deps():
         importpath = "github.com/golang/snappy",
         tag = "v0.0.3",
     )
+    _maybe(
+        go_repository,
+        name = "com_github_hanwen_go_fuse_v2",
+        importpath = "github.com/hanwen/go-fuse/v2",
+        tag = "v2.1.0",
+    )
     _maybe(
         go_repository,
         name = "com_github_klauspost_compress",